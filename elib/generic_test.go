@@ -0,0 +1,105 @@
+// Copyright 2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package elib
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestVec(t *testing.T) {
+	var v Vec[string]
+	if p := v.Validate(9); *p != "" || v.Len() != 10 {
+		t.Fatalf("validate: len %d", v.Len())
+	}
+	v[3] = "x"
+	v.ValidateInit(20, "z")
+	if v[3] != "x" || v[15] != "z" || v.Len() != 21 {
+		t.Fatalf("validate init: %q", v)
+	}
+	if cap(v) != int(NextResizeCap(21)) {
+		t.Errorf("cap %d", cap(v))
+	}
+	v.Resize(2)
+	if v.Len() != 23 {
+		t.Errorf("resize: len %d", v.Len())
+	}
+	v.ResetLen()
+	if v.Len() != 0 || v.ValidateLen(0) != nil {
+		t.Errorf("reset: len %d", v.Len())
+	}
+}
+
+func TestPoolOf(t *testing.T) {
+	var p PoolOf[int]
+	for i := 0; i < 10; i++ {
+		if x := p.GetIndex(); x != uint(i) {
+			t.Fatalf("get %d: %d", i, x)
+		}
+		p.Data[i] = i
+	}
+	p.PutIndex(3)
+	p.PutIndex(7)
+	if !p.IsFree(3) || p.IsFree(4) || !p.IsFree(10) || p.Elts() != 8 {
+		t.Fatalf("free: elts %d", p.Elts())
+	}
+	sum := 0
+	p.Foreach(func(x int) { sum += x })
+	if sum != 45-3-7 {
+		t.Errorf("foreach sum %d", sum)
+	}
+	if x := p.GetIndex(); x != 7 {
+		t.Errorf("reuse %d", x)
+	}
+	n := 0
+	p.ForeachIndex(func(i uint) { n++ })
+	if n != 9 || p.Len() != 10 {
+		t.Errorf("foreach index %d len %d", n, p.Len())
+	}
+	p.Reset()
+	if p.Len() != 0 || p.Elts() != 0 {
+		t.Errorf("reset: len %d", p.Len())
+	}
+}
+
+func TestHeapOf(t *testing.T) {
+	var h HeapOf[byte]
+	rand.Seed(1)
+	blocks := make(map[uint]byte)
+	for i := 0; i < 1000; i++ {
+		if len(blocks) > 0 && rand.Intn(3) == 0 {
+			for o, b := range blocks {
+				for _, x := range h.Slice(o) {
+					if x != b {
+						t.Fatalf("block %d: %d != %d", o, x, b)
+					}
+				}
+				h.Put(o)
+				delete(blocks, o)
+				break
+			}
+			continue
+		}
+		size, align := 1+uint(rand.Intn(32)), uint(rand.Intn(4))
+		o := h.GetAligned(size, align)
+		if o&(1<<align-1) != 0 {
+			t.Fatalf("offset %d not aligned %d", o, align)
+		}
+		s := h.Slice(o)
+		if uint(len(s)) != size {
+			t.Fatalf("slice len %d != %d", len(s), size)
+		}
+		for j := range s {
+			s[j] = byte(i)
+		}
+		blocks[o] = byte(i)
+	}
+	if err := h.validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package elib
+
+// HeapOf is the type parameterized equivalent of heaps generated from
+// heap.tmpl with Data=Data; it allocates variable sized blocks of Data.
+type HeapOf[T any] struct {
+	Heap
+	Data []T
+	ids  []Index
+}
+
+// GetAligned returns the offset within Data of a block of the given size
+// aligned to 1<<log2Alignment elements.
+func (p *HeapOf[T]) GetAligned(size, log2Alignment uint) (offset uint) {
+	l := uint(len(p.Data))
+	id, offset := p.Heap.GetAligned(size, log2Alignment)
+	if offset+size > l {
+		p.Validate(offset + size - 1)
+	}
+	for i := uint(0); i < size; i++ {
+		p.ids[offset+i] = id
+	}
+	return
+}
+
+func (p *HeapOf[T]) Get(size uint) uint { return p.GetAligned(size, 0) }
+
+// Put frees the block at the given offset.
+func (p *HeapOf[T]) Put(offset uint) {
+	p.Heap.Put(p.Id(offset))
+}
+
+func (p *HeapOf[T]) Validate(i uint) {
+	c := uint(cap(p.Data))
+	l := uint(i) + 1
+	if l > c {
+		c = NextResizeCap(l)
+		q := make([]T, l, c)
+		r := make([]Index, l, c)
+		copy(q, p.Data)
+		copy(r, p.ids)
+		p.Data = q
+		p.ids = r
+	}
+	if l > uint(len(p.Data)) {
+		p.Data = p.Data[:l]
+		p.ids = p.ids[:l]
+	}
+}
+
+func (p *HeapOf[T]) Id(offset uint) Index {
+	return p.ids[offset]
+}
+
+// Slice returns the block at the given offset.
+func (p *HeapOf[T]) Slice(offset uint) []T {
+	l := p.Len(p.Id(offset))
+	return p.Data[offset : offset+l]
+}
@@ -0,0 +1,231 @@
+// Copyright 2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mctree
+
+import (
+	"sort"
+)
+
+// Keys are identified by index; indices are stable while the key exists
+// and are re-used after the key is deleted.
+func (m *Main) key_index(o pair_offset) uint { return uint(o) / m.n_pairs_per_key }
+
+// AddDelPriority adds or deletes a key with the given priority and returns
+// its index.  Lookup of a value matching overlapping keys returns the key with
+// highest priority; ties go to the key with lowest index.  Adding an existing
+// key changes its priority.
+func (m *Main) AddDelPriority(p []Pair, priority uint, is_del bool) (ki uint) {
+	o, exists := m.pair_hash.get(p)
+	if is_del && !exists {
+		panic("key not found")
+	}
+	c := m.compiled
+	if exists {
+		ki = m.key_index(o)
+		if c != nil {
+			c.add_del(p, uint32(ki), m.priorities[ki], true)
+		}
+	}
+	if is_del || !exists {
+		m.add_del(p, is_del)
+	}
+	if !is_del {
+		if !exists {
+			o, _ = m.pair_hash.get(p)
+			ki = m.key_index(o)
+		}
+		m.priorities.Validate(ki)
+		m.priorities[ki] = uint32(priority)
+		if c != nil {
+			c.add_del(p, uint32(ki), uint32(priority), false)
+		}
+	}
+	return
+}
+
+// Returns true if key value v matches all pairs.
+func (p pair_vec) match(v []uint32) bool {
+	for i := range p {
+		if word(v[i])&p[i].Mask != p[i].Value {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Main) better(ki, kj uint) bool {
+	pi, pj := m.priorities[ki], m.priorities[kj]
+	return pi > pj || (pi == pj && ki < kj)
+}
+
+// Lookup returns the index of the highest priority key matching the value,
+// which has one uint32 per 32 bits of key, by walking the current lowest cost
+// tree.
+func (m *Main) Lookup(v []uint32) (ki uint, ok bool) {
+	if uint(len(v)) != m.n_pairs_per_key {
+		return
+	}
+	t := m.get_min_tree()
+	n := m.get_node(t.root_node_index)
+	for n.is_split() {
+		b0, b1 := n.split_bit/word_bits, n.split_bit%word_bits
+		n = m.get_node(n.sub_nodes[(v[b0]>>b1)&1])
+	}
+	for i, o := range n.pair_offsets.vec {
+		if o == pair_offset_invalid || n.index_is_free(uint(i)) {
+			continue
+		}
+		if !m.pair_hash.get_pairs_for_offset(o).match(v) {
+			continue
+		}
+		if k := m.key_index(o); !ok || m.better(k, ki) {
+			ki, ok = k, true
+		}
+	}
+	return
+}
+
+type compiled_key struct {
+	index, priority uint32
+}
+
+type compiled_leaf struct {
+	// Pairs of leaf's keys in lookup order: n_pairs_per_key pairs per key.
+	pairs pair_vec
+	keys  []compiled_key
+}
+
+type compiled_node struct {
+	split_bit uint32
+	// Index of sub node or, when negative, ^index of leaf.
+	sub_nodes [2]int32
+}
+
+// Compiled is a flat copy of the decision tree for fast lookups.  Leaf keys
+// are sorted by priority so the first match wins.  It is not safe for
+// concurrent add/del and lookup.
+type Compiled struct {
+	n_pairs_per_key uint
+	root            int32
+	nodes           []compiled_node
+	leaves          []compiled_leaf
+}
+
+// Compile returns the flat classifier of the current lowest cost tree.
+// Keys added or deleted afterwards with AddDelPriority update the classifier
+// in place; re-compile to pick up a lower cost tree found by Step.
+func (m *Main) Compile() *Compiled {
+	c := &Compiled{n_pairs_per_key: m.n_pairs_per_key}
+	c.root = c.compile_node(m, m.get_min_tree().root_node_index)
+	m.compiled = c
+	return c
+}
+
+func (c *Compiled) compile_node(m *Main, ni node_index) int32 {
+	n := m.get_node(ni)
+	if n.is_split() {
+		i := len(c.nodes)
+		c.nodes = append(c.nodes, compiled_node{split_bit: uint32(n.split_bit)})
+		sub_nodes := n.sub_nodes
+		for j := range sub_nodes {
+			s := c.compile_node(m, sub_nodes[j])
+			c.nodes[i].sub_nodes[j] = s
+		}
+		return int32(i)
+	}
+	i := len(c.leaves)
+	c.leaves = append(c.leaves, compiled_leaf{})
+	l := &c.leaves[i]
+	for j, o := range n.pair_offsets.vec {
+		if o == pair_offset_invalid || n.index_is_free(uint(j)) {
+			continue
+		}
+		ki := m.key_index(o)
+		l.keys = append(l.keys, compiled_key{
+			index:    uint32(ki),
+			priority: m.priorities[ki],
+		})
+		l.pairs = append(l.pairs, m.pair_hash.get_pairs_for_offset(o)...)
+	}
+	sort.Sort(l)
+	return ^int32(i)
+}
+
+func (a *compiled_key) before(b *compiled_key) bool {
+	return a.priority > b.priority ||
+		(a.priority == b.priority && a.index < b.index)
+}
+
+func (l *compiled_leaf) Len() int           { return len(l.keys) }
+func (l *compiled_leaf) Less(i, j int) bool { return l.keys[i].before(&l.keys[j]) }
+func (l *compiled_leaf) Swap(i, j int) {
+	n := len(l.pairs) / len(l.keys)
+	l.keys[i], l.keys[j] = l.keys[j], l.keys[i]
+	for k := 0; k < n; k++ {
+		l.pairs[i*n+k], l.pairs[j*n+k] = l.pairs[j*n+k], l.pairs[i*n+k]
+	}
+}
+
+// Lookup returns the index of the highest priority key matching the value.
+func (c *Compiled) Lookup(v []uint32) (ki uint, ok bool) {
+	if uint(len(v)) != c.n_pairs_per_key {
+		return
+	}
+	i := c.root
+	for i >= 0 {
+		n := &c.nodes[i]
+		i = n.sub_nodes[(v[n.split_bit/word_bits]>>(n.split_bit%word_bits))&1]
+	}
+	l := &c.leaves[^i]
+	n := c.n_pairs_per_key
+	for j := range l.keys {
+		if l.pairs[uint(j)*n : uint(j+1)*n].match(v) {
+			return uint(l.keys[j].index), true
+		}
+	}
+	return
+}
+
+// Add or delete key in all leafs it may match.
+func (c *Compiled) add_del(p []Pair, ki, priority uint32, is_del bool) {
+	c.add_del_helper(c.root, p, compiled_key{ki, priority}, is_del)
+}
+
+func (c *Compiled) add_del_helper(i int32, p []Pair, k compiled_key, is_del bool) {
+	if i >= 0 {
+		n := &c.nodes[i]
+		b0, b1 := n.split_bit/word_bits, n.split_bit%word_bits
+		unmasked := (p[b0].Mask>>b1)&1 == 0
+		v := (p[b0].Value>>b1)&1 != 0
+		sub_nodes := n.sub_nodes
+		if unmasked || !v {
+			c.add_del_helper(sub_nodes[0], p, k, is_del)
+		}
+		if unmasked || v {
+			c.add_del_helper(sub_nodes[1], p, k, is_del)
+		}
+		return
+	}
+	l := &c.leaves[^i]
+	n := int(c.n_pairs_per_key)
+	if is_del {
+		for j := range l.keys {
+			if l.keys[j].index == k.index {
+				l.keys = append(l.keys[:j], l.keys[j+1:]...)
+				l.pairs = append(l.pairs[:j*n], l.pairs[(j+1)*n:]...)
+				return
+			}
+		}
+		return
+	}
+	// Insert in priority order.
+	j := sort.Search(len(l.keys), func(j int) bool { return k.before(&l.keys[j]) })
+	l.keys = append(l.keys, compiled_key{})
+	copy(l.keys[j+1:], l.keys[j:])
+	l.keys[j] = k
+	l.pairs = append(l.pairs, p...)
+	copy(l.pairs[(j+1)*n:], l.pairs[j*n:])
+	copy(l.pairs[j*n:], p)
+}
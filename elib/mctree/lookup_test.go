@@ -0,0 +1,238 @@
+// Copyright 2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mctree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Linear scan of all keys as a TCAM would.
+type tcam struct {
+	keys map[uint]tcam_key
+}
+
+type tcam_key struct {
+	pairs    pair_vec
+	priority uint32
+}
+
+func (t *tcam) Lookup(v []uint32) (ki uint, ok bool) {
+	var priority uint32
+	for i, k := range t.keys {
+		if !k.pairs.match(v) {
+			continue
+		}
+		if !ok || k.priority > priority ||
+			(k.priority == priority && i < ki) {
+			ki, ok, priority = i, true, k.priority
+		}
+	}
+	return
+}
+
+type lookup_test struct {
+	m    Main
+	tcam tcam
+	// Keys by index of main.
+	keys map[uint][]Pair
+}
+
+const lookup_test_key_words = 2
+
+func new_lookup_test(n_keys int) *lookup_test {
+	t := &lookup_test{
+		tcam: tcam{keys: make(map[uint]tcam_key)},
+		keys: make(map[uint][]Pair),
+	}
+	t.m.Config = Config{
+		Key_bits:            32 * lookup_test_key_words,
+		Max_leafs:           uint(n_keys / 8),
+		Min_pairs_for_split: 4,
+		Temperature:         1e-6,
+	}
+	t.m.Init()
+	for i := 0; i < n_keys; i++ {
+		t.add_random()
+	}
+	return t
+}
+
+// Random ACL like key: a prefix of the first word and, sometimes, an
+// exact second word.
+func random_key() []Pair {
+	p := make([]Pair, lookup_test_key_words)
+	l := uint(8 + rand.Intn(25))
+	mask := ^uint(0) << (32 - l)
+	p[0].Set(uint(rand.Uint32())&mask&0xffffffff, mask&0xffffffff)
+	if rand.Intn(2) == 0 {
+		p[1].Set(uint(rand.Intn(16)), 0xffffffff)
+	}
+	return p
+}
+
+// Random value near keys so that most lookups match.
+func (t *lookup_test) random_value() []uint32 {
+	v := make([]uint32, lookup_test_key_words)
+	for _, p := range t.keys {
+		v[0] = uint32(p[0].Value) | rand.Uint32()&^uint32(p[0].Mask)
+		v[1] = uint32(rand.Intn(16))
+		break
+	}
+	return v
+}
+
+func (t *lookup_test) add(p []Pair, priority uint) {
+	ki := t.m.AddDelPriority(p, priority, false)
+	t.keys[ki] = p
+	t.tcam.keys[ki] = tcam_key{pairs: p, priority: uint32(priority)}
+}
+
+func (t *lookup_test) add_random() {
+	p := random_key()
+	if _, exists := t.m.pair_hash.get(p); exists {
+		return
+	}
+	t.add(p, uint(rand.Intn(4)))
+}
+
+func (t *lookup_test) del_random() {
+	for ki, p := range t.keys {
+		t.m.AddDelPriority(p, 0, true)
+		delete(t.keys, ki)
+		delete(t.tcam.keys, ki)
+		return
+	}
+}
+
+func (t *lookup_test) check(tb testing.TB, c *Compiled, n int) {
+	for i := 0; i < n; i++ {
+		v := t.random_value()
+		want, wantOk := t.tcam.Lookup(v)
+		if ki, ok := t.m.Lookup(v); ok != wantOk || ki != want {
+			tb.Fatalf("tree lookup %x: got %d %v want %d %v",
+				v, ki, ok, want, wantOk)
+		}
+		if c == nil {
+			continue
+		}
+		if ki, ok := c.Lookup(v); ok != wantOk || ki != want {
+			tb.Fatalf("compiled lookup %x: got %d %v want %d %v",
+				v, ki, ok, want, wantOk)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	rand.Seed(1)
+	x := new_lookup_test(1000)
+	x.check(t, x.m.Compile(), 1000)
+	for i := 0; i < 20000; i++ {
+		x.m.Step()
+	}
+	c := x.m.Compile()
+	if len(c.leaves) < 2 {
+		t.Fatalf("tree not split: %d leaves", len(c.leaves))
+	}
+	x.check(t, c, 1000)
+
+	// Incremental add/del and priority change.
+	for i := 0; i < 200; i++ {
+		if rand.Intn(2) == 0 {
+			x.add_random()
+		} else {
+			x.del_random()
+		}
+		x.check(t, c, 20)
+	}
+	for ki, p := range x.keys {
+		x.add(p, 100)
+		v := []uint32{uint32(p[0].Value), uint32(p[1].Value)}
+		if got, ok := c.Lookup(v); !ok || got != ki {
+			t.Fatalf("priority change: got %d want %d", got, ki)
+		}
+		break
+	}
+	x.check(t, c, 1000)
+	if _, ok := c.Lookup([]uint32{0}); ok {
+		t.Error("short value matched")
+	}
+}
+
+const bench_keys = 4000
+
+func bench_lookup_test(b *testing.B) *lookup_test {
+	rand.Seed(1)
+	x := new_lookup_test(bench_keys)
+	for i := 0; i < 100000; i++ {
+		x.m.Step()
+	}
+	b.ResetTimer()
+	return x
+}
+
+func bench_values(x *lookup_test) [][]uint32 {
+	v := make([][]uint32, 1024)
+	for i := range v {
+		v[i] = x.random_value()
+	}
+	return v
+}
+
+func BenchmarkLookupTcam(b *testing.B) {
+	x := bench_lookup_test(b)
+	v := bench_values(x)
+	keys := make([]tcam_key, 0, len(x.tcam.keys))
+	for _, k := range x.tcam.keys {
+		keys = append(keys, k)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		val := v[i%len(v)]
+		best := -1
+		for j := range keys {
+			if keys[j].pairs.match(val) &&
+				(best < 0 || keys[j].priority > keys[best].priority) {
+				best = j
+			}
+		}
+	}
+}
+
+func BenchmarkLookupTree(b *testing.B) {
+	x := bench_lookup_test(b)
+	v := bench_values(x)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x.m.Lookup(v[i%len(v)])
+	}
+}
+
+func BenchmarkLookupCompiled(b *testing.B) {
+	x := bench_lookup_test(b)
+	c := x.m.Compile()
+	v := bench_values(x)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Lookup(v[i%len(v)])
+	}
+}
+
+func BenchmarkAddDelCompiled(b *testing.B) {
+	x := bench_lookup_test(b)
+	x.m.Compile()
+	keys := make([][]Pair, b.N)
+	for i := range keys {
+		keys[i] = random_key()
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, exists := x.m.pair_hash.get(keys[i]); exists {
+			continue
+		}
+		x.m.AddDelPriority(keys[i], 0, false)
+		x.m.AddDelPriority(keys[i], 0, true)
+	}
+}
@@ -377,6 +377,12 @@ type Main struct {
 
 	pair_hash pair_hash
 
+	// Priority of each key by index.
+	priorities elib.Uint32Vec
+
+	// Classifier updated by add/del if non-nil.
+	compiled *Compiled
+
 	shared_pair_offsets_pool
 
 	// Temperature for simulated annealing.
@@ -762,7 +768,10 @@ func (m *Main) add_del_key_helper(t *tree, key []Pair, ni node_index, is_del boo
 	}
 }
 
-func (m *Main) AddDel(p []Pair, is_del bool) {
+// AddDel adds or deletes a key with priority 0.
+func (m *Main) AddDel(p []Pair, is_del bool) { m.AddDelPriority(p, 0, is_del) }
+
+func (m *Main) add_del(p []Pair, is_del bool) {
 	// Cancels any optimizing steps currently in progress.
 	m.restart()
 	t := m.get_tree_seq(m.tree_sequence - 1)
@@ -129,7 +129,7 @@ func (h *pair_hash) set(p []Pair) (o pair_offset, exists bool) {
 		exists = true
 		o = h.pair_offset_by_hash_index[i]
 	} else {
-		pi := h.pairs_pool.GetIndex(h.pairs.Len())
+		pi := h.pairs_pool.GetIndex(h.pairs.Len() / h.n_pairs_per_key)
 		o = pair_offset(pi * h.n_pairs_per_key)
 		h.pairs.Validate(uint(o) + h.n_pairs_per_key - 1)
 		h.pair_offset_by_hash_index[i] = o
@@ -0,0 +1,103 @@
+// Copyright 2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package elib
+
+// PoolOf is the type parameterized equivalent of pools generated from
+// pool.tmpl with Data=Data.  It's not named Pool since that's the free index
+// allocator embedded here.
+//
+//	var nodes elib.PoolOf[node]
+//	i := nodes.GetIndex()
+//	nodes.Data[i] = node{}
+//	...
+//	nodes.PutIndex(i)
+type PoolOf[T any] struct {
+	Pool
+	Data []T
+}
+
+// GetIndex returns the first free index, extending the pool if there are
+// none.
+func (p *PoolOf[T]) GetIndex() (i uint) {
+	l := uint(len(p.Data))
+	i = p.Pool.GetIndex(l)
+	if i >= l {
+		p.Validate(i)
+	}
+	return i
+}
+
+func (p *PoolOf[T]) PutIndex(i uint) (ok bool) {
+	return p.Pool.PutIndex(i)
+}
+
+func (p *PoolOf[T]) IsFree(i uint) (v bool) {
+	v = i >= uint(len(p.Data))
+	if !v {
+		v = p.Pool.IsFree(i)
+	}
+	return
+}
+
+func (p *PoolOf[T]) Resize(n uint) {
+	c := uint(cap(p.Data))
+	l := uint(len(p.Data) + int(n))
+	if l > c {
+		c = NextResizeCap(l)
+		q := make([]T, l, c)
+		copy(q, p.Data)
+		p.Data = q
+	}
+	p.Data = p.Data[:l]
+}
+
+func (p *PoolOf[T]) Validate(i uint) {
+	c := uint(cap(p.Data))
+	l := uint(i) + 1
+	if l > c {
+		c = NextResizeCap(l)
+		q := make([]T, l, c)
+		copy(q, p.Data)
+		p.Data = q
+	}
+	if l > uint(len(p.Data)) {
+		p.Data = p.Data[:l]
+	}
+}
+
+// Elts returns the number of allocated elements.
+func (p *PoolOf[T]) Elts() uint {
+	return uint(len(p.Data)) - p.FreeLen()
+}
+
+func (p *PoolOf[T]) Len() uint {
+	return uint(len(p.Data))
+}
+
+func (p *PoolOf[T]) Foreach(f func(x T)) {
+	for i := range p.Data {
+		if !p.Pool.IsFree(uint(i)) {
+			f(p.Data[i])
+		}
+	}
+}
+
+func (p *PoolOf[T]) ForeachIndex(f func(i uint)) {
+	for i := range p.Data {
+		if !p.Pool.IsFree(uint(i)) {
+			f(uint(i))
+		}
+	}
+}
+
+func (p *PoolOf[T]) Reset() {
+	p.Pool.Reset()
+	if len(p.Data) > 0 {
+		p.Data = p.Data[:0]
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package elib
+
+// Vec is the type parameterized equivalent of vectors generated from
+// vec.tmpl; e.g. Vec[uint32] has the same methods as Uint32Vec.
+type Vec[T any] []T
+
+func (p *Vec[T]) Resize(n uint) {
+	old_cap := uint(cap(*p))
+	new_len := uint(len(*p)) + n
+	if new_len > old_cap {
+		new_cap := NextResizeCap(new_len)
+		q := make([]T, new_len, new_cap)
+		copy(q, *p)
+		*p = q
+	}
+	*p = (*p)[:new_len]
+}
+
+func (p *Vec[T]) validate(new_len uint, zero T) *T {
+	old_cap := uint(cap(*p))
+	old_len := uint(len(*p))
+	if new_len <= old_cap {
+		// Need to reslice to larger length?
+		if new_len > old_len {
+			*p = (*p)[:new_len]
+			for i := old_len; i < new_len; i++ {
+				(*p)[i] = zero
+			}
+		}
+		return &(*p)[new_len-1]
+	}
+	return p.validateSlowPath(zero, old_cap, new_len, old_len)
+}
+
+func (p *Vec[T]) validateSlowPath(zero T, old_cap, new_len, old_len uint) *T {
+	if new_len > old_cap {
+		new_cap := NextResizeCap(new_len)
+		q := make([]T, new_cap, new_cap)
+		copy(q, *p)
+		for i := old_len; i < new_cap; i++ {
+			q[i] = zero
+		}
+		*p = q[:new_len]
+	}
+	if new_len > old_len {
+		*p = (*p)[:new_len]
+	}
+	return &(*p)[new_len-1]
+}
+
+// Validate extends the vector, if necessary, so that index i is valid and
+// returns a pointer to that element.
+func (p *Vec[T]) Validate(i uint) *T {
+	var zero T
+	return p.validate(i+1, zero)
+}
+
+// ValidateInit is Validate with new elements set to zero.
+func (p *Vec[T]) ValidateInit(i uint, zero T) *T {
+	return p.validate(i+1, zero)
+}
+
+func (p *Vec[T]) ValidateLen(l uint) (v *T) {
+	if l > 0 {
+		var zero T
+		v = p.validate(l, zero)
+	}
+	return
+}
+
+func (p *Vec[T]) ValidateLenInit(l uint, zero T) (v *T) {
+	if l > 0 {
+		v = p.validate(l, zero)
+	}
+	return
+}
+
+func (p *Vec[T]) ResetLen() {
+	if *p != nil {
+		*p = (*p)[:0]
+	}
+}
+
+func (p Vec[T]) Len() uint { return uint(len(p)) }
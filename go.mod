@@ -5,8 +5,6 @@ require (
 	github.com/Microsoft/go-winio v0.4.11 // indirect
 	github.com/cavaliercoder/go-cpio v0.0.0-20180626203310-925f9528c45e
 	github.com/cavaliercoder/grab v2.0.0+incompatible
-	github.com/d2g/dhcp4 v0.0.0-20170904100407-a1d1b6c41b1c
-	github.com/d2g/dhcp4client v0.0.0-20180622102533-b7a004ff1a09
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/docker/distribution v0.0.0-20170726174610-edc3ab29cdff // indirect
 	github.com/docker/go-connections v0.3.0 // indirect
@@ -31,6 +29,7 @@ require (
 	github.com/satori/go.uuid v1.2.0
 	github.com/stretchr/testify v1.2.2 // indirect
 	github.com/tatsushid/go-fastping v0.0.0-20160109021039-d7bb493dee3e
+	golang.org/x/crypto v0.0.0-20181009213950-7c1a557ab941
 	golang.org/x/net v0.0.0-20181004194319-68fc911561ed // indirect
 	golang.org/x/sys v0.0.0-20181004145325-8469e314837c // indirect
 	gopkg.in/yaml.v2 v2.2.1
@@ -23,13 +23,17 @@ func (g *Goes) Builtins() map[string]func(...string) error {
 		defer g.cache.Unlock()
 		g.cache.builtins = map[string]func(...string) error{
 			"apropos":   g.apropos,
+			"bg":        g.bg,
 			"complete":  g.complete,
 			"copyright": g.copyright,
+			"fg":        g.fg,
 			"help":      g.help,
+			"jobs":      g.listJobs,
 			"license":   g.license,
 			"man":       g.man,
 			"usage":     g.usage,
 			"version":   g.version,
+			"wait":      g.waitJobs,
 		}
 	}
 	return g.cache.builtins
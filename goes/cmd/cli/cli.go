@@ -158,7 +158,22 @@ PIPES
 
 		cat <<- EOF | wc -l > lines.txt
 			...
-		EOF`,
+		EOF
+
+JOBS
+	A pipeline, or and-or list, ending with '&' runs in the background;
+	'$!' is the process id of the last background job.
+
+		ping -c 3 localhost > /tmp/ping.out &
+
+	On a terminal, Ctrl-Z stops the foreground job. These builtins
+	manage the jobs of this cli.
+
+		jobs [-l | -p]	list background and stopped jobs
+		fg [%N]		continue the job in the foreground
+		bg [%N]		continue the stopped job in the background
+		wait [%N | PID]...
+				wait for the jobs to finish`,
 	}
 }
 
@@ -236,8 +251,14 @@ func (c *Command) Main(args ...string) error {
 		}
 	}
 	signal.Ignore(syscall.SIGINT)
+	if !isScript {
+		c.g.JobControl()
+	}
 readCommandLoop:
 	for {
+		if !isScript {
+			c.g.ReportJobs()
+		}
 		prompt := c.Prompt
 		if len(prompt) == 0 {
 			prompt = fmt.Sprint(c.g, "> ")
@@ -0,0 +1,467 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package dhcpcd
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+)
+
+// DHCPv4 message types (RFC 2132 §9.6)
+const (
+	dhcp4Discover = 1
+	dhcp4Offer    = 2
+	dhcp4Request  = 3
+	dhcp4Decline  = 4
+	dhcp4Ack      = 5
+	dhcp4Nak      = 6
+	dhcp4Release  = 7
+)
+
+// DHCPv4 option codes (RFC 2132, RFC 3397)
+const (
+	opt4Pad          = 0
+	opt4SubnetMask   = 1
+	opt4Router       = 3
+	opt4DNS          = 6
+	opt4Hostname     = 12
+	opt4DomainName   = 15
+	opt4NTP          = 42
+	opt4RequestedIP  = 50
+	opt4LeaseTime    = 51
+	opt4MsgType      = 53
+	opt4ServerID     = 54
+	opt4ParamList    = 55
+	opt4T1           = 58
+	opt4T2           = 59
+	opt4ClientID     = 61
+	opt4DomainSearch = 119
+	opt4End          = 255
+)
+
+const (
+	bootRequest  = 1
+	bootReply    = 2
+	dhcp4Magic   = 0x63825363
+	dhcp4Flags   = 0x8000 // broadcast
+	dhcp4MinSize = 300
+	dhcp4Server  = 67
+	dhcp4Client  = 68
+)
+
+var errNak = errors.New("NAK")
+
+var params4 = []byte{
+	opt4SubnetMask,
+	opt4Router,
+	opt4DNS,
+	opt4Hostname,
+	opt4DomainName,
+	opt4NTP,
+	opt4LeaseTime,
+	opt4ServerID,
+	opt4T1,
+	opt4T2,
+	opt4DomainSearch,
+}
+
+type client4 struct {
+	*Command
+	dev   string
+	mac   net.HardwareAddr
+	conn  *net.UDPConn
+	xid   uint32
+	begin time.Time
+}
+
+type reply4 struct {
+	yiaddr net.IP
+	opts   map[byte][]byte
+}
+
+func (r *reply4) msgType() byte {
+	if b := r.opts[opt4MsgType]; len(b) == 1 {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *reply4) server() net.IP {
+	if b := r.opts[opt4ServerID]; len(b) == net.IPv4len {
+		return net.IP(b)
+	}
+	return nil
+}
+
+// dhcp4 runs the RFC 2131 client state machine on the given interface until
+// the command is closed; then it releases the lease.
+func (c *Command) dhcp4(dev string, mac net.HardwareAddr) error {
+	conn, err := listenUDP("udp4", fmt.Sprint("0.0.0.0:", dhcp4Client), dev)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	cl := &client4{
+		Command: c,
+		dev:     dev,
+		mac:     mac,
+		conn:    conn,
+	}
+	fn := c.leaseFile(dev, 4)
+	lease := loadLease(fn)
+	for !c.stopped() {
+		var err error
+		var ack *reply4
+		if lease != nil {
+			// INIT-REBOOT
+			cl.newxid()
+			ack, err = cl.request(lease.IP(), nil, nil,
+				time.Now().Add(10*time.Second))
+		}
+		if ack == nil {
+			// INIT
+			ack, err = cl.discover()
+		}
+		if err != nil {
+			c.logf("%s: dhcp4: %v", dev, err)
+			lease = nil
+			c.wait(c.backoff4.Duration())
+			continue
+		}
+		c.backoff4.Reset()
+		lease = cl.lease(ack, nil)
+		c.bind(dev, 4, lease, fn)
+		lease = cl.bound(lease, fn)
+		if c.stopped() && lease != nil {
+			cl.release(lease)
+		}
+		c.bind(dev, 4, nil, fn)
+	}
+	return nil
+}
+
+// Select the first offer then request it.
+func (cl *client4) discover() (*reply4, error) {
+	cl.newxid()
+	offer, err := cl.exchange(cl.packet(dhcp4Discover, nil, nil, nil),
+		nil, time.Now().Add(64*time.Second), dhcp4Offer)
+	if err != nil {
+		return nil, err
+	}
+	return cl.request(offer.yiaddr, offer.server(), nil,
+		time.Now().Add(64*time.Second))
+}
+
+// Send a REQUEST for the given address with the current transaction.  A nil
+// ciaddr with a nil server identifier is INIT-REBOOT; with a server, it
+// selects an offer.  A non-nil ciaddr is RENEWING if unicast to server,
+// otherwise REBINDING.
+func (cl *client4) request(ip, server, ciaddr net.IP,
+	deadline time.Time) (*reply4, error) {
+	pkt := cl.packet(dhcp4Request, ip, server, ciaddr)
+	var dst net.IP
+	if ciaddr != nil {
+		dst = server
+	}
+	return cl.exchange(pkt, dst, deadline, dhcp4Ack, dhcp4Nak)
+}
+
+// Hold the lease through renewal at T1 and rebinding at T2 until it expires,
+// is NAK'd, or the command is stopped.  Returns the lease if it's still
+// bound.
+func (cl *client4) bound(lease *Lease, fn string) *Lease {
+	for {
+		if !cl.sleepUntil(lease.RenewAt()) {
+			return lease
+		}
+		cl.newxid()
+		ack, err := cl.extend(lease, lease.Server, lease.RebindAt())
+		if ack == nil && err != errNak && !cl.stopped() {
+			ack, err = cl.extend(lease, nil, lease.ExpireAt())
+		}
+		switch {
+		case cl.stopped():
+			return lease
+		case err == errNak:
+			cl.logf("%s: dhcp4: %s NAK'd", cl.dev, lease.Address)
+			return nil
+		case ack == nil:
+			cl.logf("%s: dhcp4: %s expired", cl.dev, lease.Address)
+			return nil
+		}
+		lease = cl.lease(ack, lease.Server)
+		cl.bind(cl.dev, 4, lease, fn)
+	}
+}
+
+// Repeat the REQUEST until the given time, waiting half the remaining time,
+// down to a minimum of 60 seconds, between tries (RFC 2131 §4.4.5).
+func (cl *client4) extend(lease *Lease, server net.IP,
+	until time.Time) (*reply4, error) {
+	for !cl.stopped() && time.Now().Before(until) {
+		wait := time.Until(until) / 2
+		if wait < 60*time.Second {
+			wait = 60 * time.Second
+		}
+		deadline := time.Now().Add(wait)
+		if deadline.After(until) {
+			deadline = until
+		}
+		ack, err := cl.request(nil, server, lease.IP(), deadline)
+		if ack != nil || err == errNak {
+			return ack, err
+		}
+	}
+	return nil, nil
+}
+
+func (cl *client4) release(lease *Lease) {
+	cl.newxid()
+	pkt := cl.packet(dhcp4Release, nil, lease.Server, lease.IP())
+	cl.send(pkt, lease.Server)
+}
+
+// Returns the lease described by the ACK.  Server identifies the server of
+// the previous lease, if any.
+func (cl *client4) lease(ack *reply4, server net.IP) *Lease {
+	l := &Lease{
+		Acquired: cl.begin,
+		Lifetime: 86400,
+		Server:   ack.server(),
+	}
+	if l.Server == nil {
+		l.Server = server
+	}
+	mask := net.IPMask(ack.opts[opt4SubnetMask])
+	if len(mask) != net.IPv4len {
+		mask = ack.yiaddr.DefaultMask()
+	}
+	l.Address = (&net.IPNet{IP: ack.yiaddr, Mask: mask}).String()
+	if b := ack.opts[opt4Router]; len(b) >= net.IPv4len {
+		if ip := net.IP(b[:4]); !ip.IsUnspecified() {
+			l.Router = ip
+		}
+	}
+	for b := ack.opts[opt4DNS]; len(b) >= net.IPv4len; b = b[4:] {
+		l.DNS = append(l.DNS, net.IP(b[:4]))
+	}
+	for b := ack.opts[opt4NTP]; len(b) >= net.IPv4len; b = b[4:] {
+		l.NTP = append(l.NTP, net.IP(b[:4]).String())
+	}
+	if b := ack.opts[opt4DomainName]; len(b) > 0 {
+		l.Search = append(l.Search, string(b))
+	}
+	for _, s := range decodeNames(ack.opts[opt4DomainSearch]) {
+		if len(l.Search) == 0 || s != l.Search[0] {
+			l.Search = append(l.Search, s)
+		}
+	}
+	if b := ack.opts[opt4Hostname]; len(b) > 0 {
+		l.Hostname = string(b)
+	}
+	if b := ack.opts[opt4LeaseTime]; len(b) == 4 {
+		l.Lifetime = binary.BigEndian.Uint32(b)
+	}
+	if b := ack.opts[opt4T1]; len(b) == 4 {
+		l.Renew = binary.BigEndian.Uint32(b)
+	}
+	if b := ack.opts[opt4T2]; len(b) == 4 {
+		l.Rebind = binary.BigEndian.Uint32(b)
+	}
+	l.defaultTimes()
+	return l
+}
+
+func (cl *client4) newxid() {
+	var b [4]byte
+	randomXid(b[:])
+	cl.xid = binary.BigEndian.Uint32(b[:])
+	cl.begin = time.Now()
+}
+
+// Returns a BOOTREQUEST of the given DHCP message type.
+func (cl *client4) packet(msgType byte, ip, server, ciaddr net.IP) []byte {
+	b := make([]byte, 240, 576)
+	b[0] = bootRequest
+	b[1] = 1 // ethernet
+	b[2] = byte(len(cl.mac))
+	binary.BigEndian.PutUint32(b[4:], cl.xid)
+	secs := time.Since(cl.begin) / time.Second
+	if secs > 0xffff {
+		secs = 0xffff
+	}
+	binary.BigEndian.PutUint16(b[8:], uint16(secs))
+	if ciaddr == nil && msgType != dhcp4Release {
+		binary.BigEndian.PutUint16(b[10:], dhcp4Flags)
+	}
+	if ciaddr != nil {
+		copy(b[12:16], ciaddr.To4())
+	}
+	copy(b[28:], cl.mac)
+	binary.BigEndian.PutUint32(b[236:], dhcp4Magic)
+
+	opt := func(code byte, v []byte) {
+		b = append(b, code, byte(len(v)))
+		b = append(b, v...)
+	}
+	opt(opt4MsgType, []byte{msgType})
+	opt(opt4ClientID, append([]byte{1}, cl.mac...))
+	if ip != nil {
+		opt(opt4RequestedIP, ip.To4())
+	}
+	if server != nil && (ciaddr == nil || msgType == dhcp4Release) {
+		opt(opt4ServerID, server.To4())
+	}
+	if msgType != dhcp4Release {
+		if s := hostname(); len(s) > 0 {
+			opt(opt4Hostname, []byte(s))
+		}
+		opt(opt4ParamList, params4)
+	}
+	b = append(b, opt4End)
+	for len(b) < dhcp4MinSize {
+		b = append(b, opt4Pad)
+	}
+	return b
+}
+
+// Send the packet to dst, or broadcast if nil, then retransmit with
+// exponential backoff (RFC 2131 §4.1) until a reply of the given types or
+// the deadline.
+func (cl *client4) exchange(pkt []byte, dst net.IP, deadline time.Time,
+	types ...byte) (*reply4, error) {
+	buf := make([]byte, 1500)
+	timeout := 4 * time.Second
+	for {
+		if err := cl.send(pkt, dst); err != nil {
+			return nil, err
+		}
+		retry := time.Now().Add(timeout +
+			time.Duration(rand.Int63n(int64(2*time.Second))) -
+			time.Second)
+		if retry.After(deadline) {
+			retry = deadline
+		}
+		for time.Now().Before(retry) {
+			if cl.stopped() {
+				return nil, errStopped
+			}
+			t := time.Now().Add(time.Second)
+			if t.After(retry) {
+				t = retry
+			}
+			cl.conn.SetReadDeadline(t)
+			n, _, err := cl.conn.ReadFromUDP(buf)
+			if err != nil {
+				if ne, ok := err.(net.Error); ok && ne.Timeout() {
+					continue
+				}
+				return nil, err
+			}
+			r := cl.parse(buf[:n])
+			if r == nil {
+				continue
+			}
+			for _, t := range types {
+				if r.msgType() == t {
+					if t == dhcp4Nak {
+						return nil, errNak
+					}
+					return r, nil
+				}
+			}
+		}
+		if !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("no reply")
+		}
+		if timeout < 64*time.Second {
+			timeout *= 2
+		}
+		binary.BigEndian.PutUint16(pkt[8:],
+			uint16(time.Since(cl.begin)/time.Second))
+	}
+}
+
+func (cl *client4) send(pkt []byte, dst net.IP) error {
+	if dst == nil {
+		dst = net.IPv4bcast
+	}
+	_, err := cl.conn.WriteToUDP(pkt, &net.UDPAddr{
+		IP:   dst,
+		Port: dhcp4Server,
+	})
+	return err
+}
+
+// Returns the parsed BOOTREPLY if it's for this client's transaction,
+// otherwise nil.
+func (cl *client4) parse(b []byte) *reply4 {
+	if len(b) < 240 || b[0] != bootReply ||
+		binary.BigEndian.Uint32(b[4:]) != cl.xid ||
+		binary.BigEndian.Uint32(b[236:]) != dhcp4Magic ||
+		string(b[28:28+len(cl.mac)]) != string(cl.mac) {
+		return nil
+	}
+	r := &reply4{
+		yiaddr: net.IP(append([]byte{}, b[16:20]...)),
+		opts:   make(map[byte][]byte),
+	}
+	for b = b[240:]; len(b) > 0; {
+		code := b[0]
+		if code == opt4End {
+			break
+		}
+		if code == opt4Pad {
+			b = b[1:]
+			continue
+		}
+		if len(b) < 2 || len(b) < 2+int(b[1]) {
+			return nil
+		}
+		n := 2 + int(b[1])
+		// RFC 3396 concatenates repeated options
+		r.opts[code] = append(r.opts[code], b[2:n]...)
+		b = b[n:]
+	}
+	if r.msgType() == 0 {
+		return nil
+	}
+	return r
+}
+
+// Listen on the given UDP address of the interface.
+func listenUDP(network, address, dev string) (*net.UDPConn, error) {
+	lc := net.ListenConfig{
+		Control: func(network, address string,
+			rc syscall.RawConn) error {
+			var err error
+			rc.Control(func(fd uintptr) {
+				s := int(fd)
+				err = syscall.SetsockoptInt(s, syscall.SOL_SOCKET,
+					syscall.SO_REUSEADDR, 1)
+				if err == nil {
+					err = syscall.SetsockoptInt(s,
+						syscall.SOL_SOCKET,
+						syscall.SO_BROADCAST, 1)
+				}
+				if err == nil {
+					err = syscall.BindToDevice(s, dev)
+				}
+			})
+			return err
+		},
+	}
+	pc, err := lc.ListenPacket(context.Background(), network, address)
+	if err != nil {
+		return nil, err
+	}
+	return pc.(*net.UDPConn), nil
+}
@@ -0,0 +1,98 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package dhcpcd
+
+import (
+	"encoding/binary"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// Returns a BOOTREPLY with the given xid, chaddr and raw options.
+func testReply4(xid uint32, mac net.HardwareAddr, opts ...byte) []byte {
+	b := make([]byte, 240)
+	b[0] = bootReply
+	binary.BigEndian.PutUint32(b[4:], xid)
+	copy(b[16:], []byte{192, 168, 1, 10})
+	copy(b[28:], mac)
+	binary.BigEndian.PutUint32(b[236:], dhcp4Magic)
+	return append(b, opts...)
+}
+
+func TestParse4(t *testing.T) {
+	mac := net.HardwareAddr{2, 0, 0, 0, 0, 1}
+	cl := &client4{mac: mac, xid: 0x12345678}
+	ack := []byte{opt4MsgType, 1, dhcp4Ack}
+	for _, x := range []struct {
+		name string
+		b    []byte
+		opts map[byte][]byte
+	}{
+		{
+			"ack",
+			testReply4(cl.xid, mac, append(ack, opt4End)...),
+			map[byte][]byte{opt4MsgType: {dhcp4Ack}},
+		},
+		{
+			"pad and no end",
+			testReply4(cl.xid, mac, append([]byte{opt4Pad}, ack...)...),
+			map[byte][]byte{opt4MsgType: {dhcp4Ack}},
+		},
+		{
+			"concatenated",
+			testReply4(cl.xid, mac, append(ack,
+				opt4DomainName, 3, 'f', 'o', 'o',
+				opt4DomainName, 4, '.', 'c', 'o', 'm',
+				opt4End)...),
+			map[byte][]byte{
+				opt4MsgType:    {dhcp4Ack},
+				opt4DomainName: []byte("foo.com"),
+			},
+		},
+		{"short", testReply4(cl.xid, mac)[:239], nil},
+		{"other xid", testReply4(cl.xid+1, mac, ack...), nil},
+		{
+			"other mac",
+			testReply4(cl.xid, net.HardwareAddr{2, 0, 0, 0, 0, 2},
+				ack...),
+			nil,
+		},
+		{"no message type", testReply4(cl.xid, mac, opt4End), nil},
+		{
+			"truncated length",
+			testReply4(cl.xid, mac, append(ack, opt4Hostname)...),
+			nil,
+		},
+		{
+			"truncated value",
+			testReply4(cl.xid, mac, append(ack,
+				opt4Hostname, 4, 's', 'w')...),
+			nil,
+		},
+	} {
+		r := cl.parse(x.b)
+		switch {
+		case x.opts == nil && r != nil:
+			t.Errorf("%s: parsed %v", x.name, r.opts)
+		case x.opts != nil && r == nil:
+			t.Errorf("%s: not parsed", x.name)
+		case r != nil && !reflect.DeepEqual(r.opts, x.opts):
+			t.Errorf("%s: got %v, want %v", x.name, r.opts, x.opts)
+		case r != nil && !r.yiaddr.Equal(net.IPv4(192, 168, 1, 10)):
+			t.Errorf("%s: yiaddr %v", x.name, r.yiaddr)
+		}
+	}
+}
+
+func TestNewxid(t *testing.T) {
+	cl := new(client4)
+	cl.newxid()
+	xid := cl.xid
+	cl.newxid()
+	if cl.xid == xid {
+		t.Fatal("repeated xid", xid)
+	}
+}
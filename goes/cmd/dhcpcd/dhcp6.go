@@ -0,0 +1,540 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package dhcpcd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+)
+
+// DHCPv6 message types (RFC 8415 §7.3)
+const (
+	dhcp6Solicit     = 1
+	dhcp6Advertise   = 2
+	dhcp6Request     = 3
+	dhcp6Renew       = 5
+	dhcp6Rebind      = 6
+	dhcp6Reply       = 7
+	dhcp6Release     = 8
+	dhcp6InfoRequest = 11
+)
+
+// DHCPv6 option codes (RFC 8415 §21, RFC 3646, RFC 4704, RFC 5908)
+const (
+	opt6ClientID    = 1
+	opt6ServerID    = 2
+	opt6IANA        = 3
+	opt6IAAddr      = 5
+	opt6ORO         = 6
+	opt6Preference  = 7
+	opt6ElapsedTime = 8
+	opt6StatusCode  = 13
+	opt6DNS         = 23
+	opt6DomainList  = 24
+	opt6ClientFQDN  = 39
+	opt6InfoRefresh = 32
+	opt6NTP         = 56
+)
+
+// NTP server suboptions (RFC 5908 §4)
+const (
+	ntpSubAddr  = 1
+	ntpSubMcast = 2
+	ntpSubFQDN  = 3
+)
+
+// DHCPv6 status codes (RFC 8415 §21.13)
+const (
+	status6Success = 0
+)
+
+const (
+	dhcp6Client = 546
+	dhcp6Server = 547
+
+	// RFC 8415 §21.23 IRT_DEFAULT and IRT_MINIMUM
+	infoRefreshDefault = 86400
+	infoRefreshMinimum = 600
+)
+
+var (
+	errNoAddrs   = errors.New("no addresses available")
+	errNoBinding = errors.New("no binding")
+	// All_DHCP_Relay_Agents_and_Servers
+	dhcp6Servers = net.ParseIP("ff02::1:2")
+)
+
+var params6 = []uint16{
+	opt6DNS,
+	opt6DomainList,
+	opt6ClientFQDN,
+	opt6InfoRefresh,
+	opt6NTP,
+}
+
+// Retransmission parameters (RFC 8415 §15, §7.6)
+type retrans struct {
+	irt, mrt time.Duration
+	mrc      int
+}
+
+var (
+	solicitRetrans = retrans{irt: time.Second, mrt: time.Hour}
+	requestRetrans = retrans{irt: time.Second, mrt: 30 * time.Second,
+		mrc: 10}
+	renewRetrans = retrans{irt: 10 * time.Second,
+		mrt: 600 * time.Second}
+	infoRetrans = retrans{irt: time.Second, mrt: time.Hour}
+)
+
+type client6 struct {
+	*Command
+	dev   string
+	duid  []byte
+	iaid  uint32
+	conn  *net.UDPConn
+	xid   [3]byte
+	begin time.Time
+}
+
+type reply6 struct {
+	msgType byte
+	server  []byte
+	status  uint16
+	opts    map[uint16][]byte
+
+	// IA_NA with the first non-zero lifetime address
+	ia       bool
+	iaStatus uint16
+	t1, t2   uint32
+	addr     net.IP
+	valid    uint32
+}
+
+// dhcp6 runs the RFC 8415 client on the given interface until the command is
+// closed; then it releases any bound address.  Stateless clients only
+// request the other configuration.
+func (c *Command) dhcp6(dev string, mac net.HardwareAddr,
+	stateless bool) error {
+	conn, err := listenUDP("udp6", fmt.Sprint("[::]:", dhcp6Client), dev)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	cl := &client6{
+		Command: c,
+		dev:     dev,
+		conn:    conn,
+	}
+	// DUID-LL (RFC 8415 §11.4) since there's no stable storage
+	// guaranteed for DUID-LLT.
+	cl.duid = []byte{0, 3, 0, 1}
+	cl.duid = append(cl.duid, mac...)
+	if len(mac) >= 4 {
+		cl.iaid = binary.BigEndian.Uint32(mac[len(mac)-4:])
+	}
+	fn := c.leaseFile(dev, 6)
+	if stateless {
+		cl.inform(fn)
+		return nil
+	}
+	lease := loadLease(fn)
+	for !c.stopped() {
+		var hint net.IP
+		if lease != nil && lease.IAID == cl.iaid {
+			hint = lease.IP()
+		}
+		r, err := cl.solicit(hint)
+		if err != nil {
+			if err != errStopped {
+				c.logf("%s: dhcp6: %v", dev, err)
+			}
+			lease = nil
+			c.wait(c.backoff6.Duration())
+			continue
+		}
+		c.backoff6.Reset()
+		lease = cl.lease(r)
+		c.bind(dev, 6, lease, fn)
+		lease = cl.bound(lease, fn)
+		if c.stopped() && lease != nil {
+			cl.release(lease)
+		}
+		c.bind(dev, 6, nil, fn)
+	}
+	return nil
+}
+
+// Repeat Information-request at the server's refresh interval.
+func (cl *client6) inform(fn string) {
+	for !cl.stopped() {
+		cl.newxid()
+		r, err := cl.exchange(func() []byte {
+			return cl.packet(dhcp6InfoRequest, nil, nil)
+		}, infoRetrans, time.Time{}, dhcp6Reply)
+		if err != nil {
+			if err != errStopped {
+				cl.logf("%s: dhcp6: %v", cl.dev, err)
+			}
+			cl.wait(cl.backoff6.Duration())
+			continue
+		}
+		cl.backoff6.Reset()
+		lease := cl.lease(r)
+		lease.Lifetime = infoRefreshDefault
+		if b := r.opts[opt6InfoRefresh]; len(b) == 4 {
+			lease.Lifetime = binary.BigEndian.Uint32(b)
+			if lease.Lifetime < infoRefreshMinimum {
+				lease.Lifetime = infoRefreshMinimum
+			}
+		}
+		lease.Renew, lease.Rebind = 0, 0
+		lease.defaultTimes()
+		cl.bind(cl.dev, 6, lease, fn)
+		cl.sleepUntil(lease.ExpireAt())
+	}
+	cl.bind(cl.dev, 6, nil, fn)
+}
+
+// Solicit an address, hinting the previous lease, then request it from the
+// first advertising server.
+func (cl *client6) solicit(hint net.IP) (*reply6, error) {
+	cl.newxid()
+	adv, err := cl.exchange(func() []byte {
+		return cl.packet(dhcp6Solicit, nil, hint)
+	}, solicitRetrans, time.Time{}, dhcp6Advertise)
+	if err != nil {
+		return nil, err
+	}
+	cl.newxid()
+	r, err := cl.exchange(func() []byte {
+		return cl.packet(dhcp6Request, adv.server, adv.addr)
+	}, requestRetrans, time.Time{}, dhcp6Reply)
+	if err != nil {
+		return nil, err
+	}
+	if r.addr == nil {
+		return nil, errNoAddrs
+	}
+	return r, nil
+}
+
+// Hold the lease through Renew at T1 and Rebind at T2 until it expires, the
+// server no longer has the binding, or the command is stopped.  Returns the
+// lease if it's still bound.
+func (cl *client6) bound(lease *Lease, fn string) *Lease {
+	for {
+		if !cl.sleepUntil(lease.RenewAt()) {
+			return lease
+		}
+		ip := lease.IP()
+		cl.newxid()
+		r, err := cl.exchange(func() []byte {
+			return cl.packet(dhcp6Renew, lease.Server, ip)
+		}, renewRetrans, lease.RebindAt(), dhcp6Reply)
+		if r == nil && err != errStopped {
+			cl.newxid()
+			r, err = cl.exchange(func() []byte {
+				return cl.packet(dhcp6Rebind, nil, ip)
+			}, renewRetrans, lease.ExpireAt(), dhcp6Reply)
+		}
+		if err == nil && r.addr == nil {
+			err = errNoBinding
+		}
+		switch {
+		case cl.stopped():
+			return lease
+		case err == errNoBinding:
+			cl.logf("%s: dhcp6: %s no binding", cl.dev,
+				lease.Address)
+			return nil
+		case err != nil:
+			cl.logf("%s: dhcp6: %s expired", cl.dev,
+				lease.Address)
+			return nil
+		}
+		lease = cl.lease(r)
+		cl.bind(cl.dev, 6, lease, fn)
+	}
+}
+
+// Send a Release without waiting for the Reply since the daemon is
+// stopping.
+func (cl *client6) release(lease *Lease) {
+	cl.newxid()
+	cl.send(cl.packet(dhcp6Release, lease.Server, lease.IP()))
+}
+
+func (cl *client6) lease(r *reply6) *Lease {
+	l := &Lease{
+		Acquired: cl.begin,
+		Server:   r.server,
+		IAID:     cl.iaid,
+		Renew:    r.t1,
+		Rebind:   r.t2,
+		Lifetime: r.valid,
+	}
+	if r.addr != nil {
+		l.Address = (&net.IPNet{
+			IP:   r.addr,
+			Mask: net.CIDRMask(128, 128),
+		}).String()
+	}
+	for b := r.opts[opt6DNS]; len(b) >= net.IPv6len; b = b[16:] {
+		l.DNS = append(l.DNS, net.IP(b[:16]))
+	}
+	l.Search = decodeNames(r.opts[opt6DomainList])
+	for b := r.opts[opt6NTP]; len(b) >= 4; {
+		code := binary.BigEndian.Uint16(b)
+		n := 4 + int(binary.BigEndian.Uint16(b[2:]))
+		if len(b) < n {
+			break
+		}
+		v := b[4:n]
+		switch {
+		case (code == ntpSubAddr || code == ntpSubMcast) &&
+			len(v) == net.IPv6len:
+			l.NTP = append(l.NTP, net.IP(v).String())
+		case code == ntpSubFQDN:
+			l.NTP = append(l.NTP, decodeNames(v)...)
+		}
+		b = b[n:]
+	}
+	if b := r.opts[opt6ClientFQDN]; len(b) > 1 {
+		if names := decodeNames(b[1:]); len(names) > 0 {
+			l.Hostname = strings.SplitN(names[0], ".", 2)[0]
+		}
+	}
+	l.defaultTimes()
+	return l
+}
+
+func (cl *client6) newxid() {
+	randomXid(cl.xid[:])
+	cl.begin = time.Now()
+}
+
+// Returns a message of the given type.  The server DUID and address are
+// included if non-nil.  The elapsed time option is always first so that
+// exchange may update it with each retransmission.
+func (cl *client6) packet(msgType byte, server []byte, addr net.IP) []byte {
+	b := []byte{msgType, cl.xid[0], cl.xid[1], cl.xid[2]}
+	opt := func(code uint16, v []byte) {
+		var hdr [4]byte
+		binary.BigEndian.PutUint16(hdr[:2], code)
+		binary.BigEndian.PutUint16(hdr[2:], uint16(len(v)))
+		b = append(b, hdr[:]...)
+		b = append(b, v...)
+	}
+	elapsed := time.Since(cl.begin) / (10 * time.Millisecond)
+	if elapsed > 0xffff {
+		elapsed = 0xffff
+	}
+	opt(opt6ElapsedTime, []byte{byte(elapsed >> 8), byte(elapsed)})
+	opt(opt6ClientID, cl.duid)
+	if server != nil {
+		opt(opt6ServerID, server)
+	}
+	if msgType != dhcp6InfoRequest {
+		ia := make([]byte, 12)
+		binary.BigEndian.PutUint32(ia, cl.iaid)
+		if addr != nil {
+			// zero lifetimes; the server chooses (RFC 8415 §21.6)
+			iaaddr := make([]byte, 4+16+8)
+			binary.BigEndian.PutUint16(iaaddr, opt6IAAddr)
+			binary.BigEndian.PutUint16(iaaddr[2:], 16+8)
+			copy(iaaddr[4:], addr.To16())
+			ia = append(ia, iaaddr...)
+		}
+		opt(opt6IANA, ia)
+	}
+	if msgType == dhcp6Release {
+		return b
+	}
+	if s := hostname(); len(s) > 0 {
+		// S bit set for the server to perform AAAA updates
+		opt(opt6ClientFQDN, append([]byte{1}, encodeNames(s)...))
+	}
+	oro := make([]byte, 2*len(params6))
+	for i, code := range params6 {
+		binary.BigEndian.PutUint16(oro[2*i:], code)
+	}
+	opt(opt6ORO, oro)
+	return b
+}
+
+// Send the message built by pkt then retransmit it as specified by rt until
+// a reply of the given types, the deadline, or the maximum retransmission
+// count.  A zero deadline retransmits until stopped or the count.
+func (cl *client6) exchange(pkt func() []byte, rt retrans, deadline time.Time,
+	types ...byte) (*reply6, error) {
+	buf := make([]byte, 1500)
+	// RAND in [-0.1, 0.1] (RFC 8415 §15)
+	jitter := func(d time.Duration) time.Duration {
+		return d + time.Duration(rand.Int63n(int64(d/5)+1)) - d/10
+	}
+	timeout := jitter(rt.irt)
+	if timeout < rt.irt && types[0] == dhcp6Advertise {
+		// the first Solicit RT must be greater than IRT
+		timeout = 2*rt.irt - timeout
+	}
+	for count := 1; ; count++ {
+		if err := cl.send(pkt()); err != nil {
+			return nil, err
+		}
+		retry := time.Now().Add(timeout)
+		if !deadline.IsZero() && retry.After(deadline) {
+			retry = deadline
+		}
+		for time.Now().Before(retry) {
+			if cl.stopped() {
+				return nil, errStopped
+			}
+			t := time.Now().Add(time.Second)
+			if t.After(retry) {
+				t = retry
+			}
+			cl.conn.SetReadDeadline(t)
+			n, _, err := cl.conn.ReadFromUDP(buf)
+			if err != nil {
+				if ne, ok := err.(net.Error); ok && ne.Timeout() {
+					continue
+				}
+				return nil, err
+			}
+			r := cl.parse(buf[:n])
+			if r == nil {
+				continue
+			}
+			for _, t := range types {
+				if r.msgType != t {
+					continue
+				}
+				// Ignore failures and advertisements without
+				// an address; RFC 8415 §18.2.1, §18.2.10
+				if r.status == status6Success &&
+					(r.addr != nil || t != dhcp6Advertise) {
+					return r, nil
+				}
+			}
+		}
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("no reply")
+		}
+		if rt.mrc > 0 && count >= rt.mrc {
+			return nil, fmt.Errorf("no reply after %d tries", count)
+		}
+		timeout = jitter(2 * timeout)
+		if rt.mrt > 0 && timeout > rt.mrt {
+			timeout = jitter(rt.mrt)
+		}
+	}
+}
+
+func (cl *client6) send(pkt []byte) error {
+	_, err := cl.conn.WriteToUDP(pkt, &net.UDPAddr{
+		IP:   dhcp6Servers,
+		Port: dhcp6Server,
+		Zone: cl.dev,
+	})
+	return err
+}
+
+// Returns the parsed server message if it's for this client's transaction,
+// otherwise nil.
+func (cl *client6) parse(b []byte) *reply6 {
+	if len(b) < 4 || !bytes.Equal(b[1:4], cl.xid[:]) {
+		return nil
+	}
+	r := &reply6{
+		msgType: b[0],
+		opts:    make(map[uint16][]byte),
+	}
+	opts, ok := options6(b[4:])
+	if !ok {
+		return nil
+	}
+	for _, o := range opts {
+		switch o.code {
+		case opt6ClientID:
+			if !bytes.Equal(o.v, cl.duid) {
+				return nil
+			}
+		case opt6ServerID:
+			r.server = append([]byte{}, o.v...)
+		case opt6StatusCode:
+			if len(o.v) >= 2 {
+				r.status = binary.BigEndian.Uint16(o.v)
+			}
+		case opt6IANA:
+			if !r.ia {
+				r.parseIA(o.v, cl.iaid)
+			}
+		default:
+			r.opts[o.code] = append(r.opts[o.code], o.v...)
+		}
+	}
+	if r.server == nil {
+		return nil
+	}
+	return r
+}
+
+func (r *reply6) parseIA(b []byte, iaid uint32) {
+	if len(b) < 12 || binary.BigEndian.Uint32(b) != iaid {
+		return
+	}
+	opts, ok := options6(b[12:])
+	if !ok {
+		return
+	}
+	r.ia = true
+	r.t1 = binary.BigEndian.Uint32(b[4:])
+	r.t2 = binary.BigEndian.Uint32(b[8:])
+	for _, o := range opts {
+		switch {
+		case o.code == opt6StatusCode && len(o.v) >= 2:
+			r.iaStatus = binary.BigEndian.Uint16(o.v)
+		case o.code == opt6IAAddr && len(o.v) >= 24 && r.addr == nil:
+			// skip the preferred lifetime
+			valid := binary.BigEndian.Uint32(o.v[20:])
+			if valid == 0 {
+				continue
+			}
+			r.addr = net.IP(append([]byte{}, o.v[:16]...))
+			r.valid = valid
+		}
+	}
+	if r.iaStatus != status6Success {
+		r.addr = nil
+	}
+}
+
+type option6 struct {
+	code uint16
+	v    []byte
+}
+
+func options6(b []byte) ([]option6, bool) {
+	var opts []option6
+	for len(b) > 0 {
+		if len(b) < 4 {
+			return nil, false
+		}
+		n := 4 + int(binary.BigEndian.Uint16(b[2:]))
+		if len(b) < n {
+			return nil, false
+		}
+		opts = append(opts, option6{binary.BigEndian.Uint16(b), b[4:n]})
+		b = b[n:]
+	}
+	return opts, true
+}
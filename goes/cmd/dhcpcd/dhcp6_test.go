@@ -0,0 +1,123 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package dhcpcd
+
+import (
+	"encoding/binary"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// Returns the encoded DHCPv6 option.
+func testOption6(code uint16, v ...byte) []byte {
+	b := make([]byte, 4, 4+len(v))
+	binary.BigEndian.PutUint16(b, code)
+	binary.BigEndian.PutUint16(b[2:], uint16(len(v)))
+	return append(b, v...)
+}
+
+func TestOptions6(t *testing.T) {
+	for _, x := range []struct {
+		name string
+		b    []byte
+		opts []option6
+		ok   bool
+	}{
+		{"empty", nil, nil, true},
+		{
+			"two",
+			append(testOption6(opt6ServerID, 1, 2),
+				testOption6(opt6ElapsedTime)...),
+			[]option6{
+				{opt6ServerID, []byte{1, 2}},
+				{opt6ElapsedTime, []byte{}},
+			},
+			true,
+		},
+		{"truncated header", []byte{0, 2, 0}, nil, false},
+		{"truncated value", []byte{0, 2, 0, 3, 1, 2}, nil, false},
+		{
+			"truncated second",
+			append(testOption6(opt6ServerID, 1), 0, 2),
+			nil, false,
+		},
+	} {
+		opts, ok := options6(x.b)
+		if ok != x.ok || !reflect.DeepEqual(opts, x.opts) {
+			t.Errorf("%s: got %v, %v; want %v, %v", x.name,
+				opts, ok, x.opts, x.ok)
+		}
+	}
+}
+
+func TestParse6(t *testing.T) {
+	cl := &client6{
+		duid: []byte{0, 3, 0, 1, 2, 0, 0, 0, 0, 1},
+		iaid: 7,
+		xid:  [3]byte{1, 2, 3},
+	}
+	header := append([]byte{dhcp6Reply}, cl.xid[:]...)
+	clientID := testOption6(opt6ClientID, cl.duid...)
+	serverID := testOption6(opt6ServerID, 0, 3, 0, 1, 2, 0, 0, 0, 0, 2)
+	addr := net.ParseIP("2001:db8::10")
+	iaaddr := make([]byte, 24)
+	copy(iaaddr, addr)
+	binary.BigEndian.PutUint32(iaaddr[16:], 1800)
+	binary.BigEndian.PutUint32(iaaddr[20:], 3600)
+	ia := make([]byte, 12)
+	binary.BigEndian.PutUint32(ia, cl.iaid)
+	binary.BigEndian.PutUint32(ia[4:], 900)
+	binary.BigEndian.PutUint32(ia[8:], 1440)
+	ia = append(ia, testOption6(opt6IAAddr, iaaddr...)...)
+	dns := testOption6(opt6DomainList, encodeNames("example.com")...)
+
+	cat := func(bs ...[]byte) []byte {
+		var b []byte
+		for _, x := range bs {
+			b = append(b, x...)
+		}
+		return b
+	}
+
+	r := cl.parse(cat(header, clientID, serverID,
+		testOption6(opt6IANA, ia...), dns))
+	if r == nil {
+		t.Fatal("reply not parsed")
+	}
+	if !r.ia || !r.addr.Equal(addr) || r.valid != 3600 ||
+		r.t1 != 900 || r.t2 != 1440 {
+		t.Fatalf("ia %v addr %v valid %d t1 %d t2 %d", r.ia, r.addr,
+			r.valid, r.t1, r.t2)
+	}
+	if names := decodeNames(r.opts[opt6DomainList]); len(names) != 1 ||
+		names[0] != "example.com" {
+		t.Fatalf("domain list %q", names)
+	}
+
+	otherIA := append([]byte{}, ia...)
+	otherIA[3]++
+	if r = cl.parse(cat(header, clientID, serverID,
+		testOption6(opt6IANA, otherIA...))); r == nil || r.ia {
+		t.Fatal("other IAID")
+	}
+
+	for _, x := range []struct {
+		name string
+		b    []byte
+	}{
+		{"short", header[:3]},
+		{"other xid", cat([]byte{dhcp6Reply, 1, 2, 4}, clientID,
+			serverID)},
+		{"other client", cat(header,
+			testOption6(opt6ClientID, 0, 3), serverID)},
+		{"no server", cat(header, clientID)},
+		{"truncated", cat(header, clientID, serverID, dns[:6])},
+	} {
+		if cl.parse(x.b) != nil {
+			t.Errorf("%s: parsed", x.name)
+		}
+	}
+}
@@ -2,40 +2,58 @@
 // Use of this source code is governed by the GPL-2 license described in the
 // LICENSE file.
 
-// Package dhcpcd is a simple dhcp client
+// Package dhcpcd is a DHCPv4 and DHCPv6 client daemon.
 package dhcpcd
 
 import (
-	"encoding/binary"
+	"bytes"
+	"crypto/rand"
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"math/bits"
 	"net"
 	"os"
-	"strconv"
+	"path/filepath"
+	"sync"
 	"syscall"
 	"time"
 	"unsafe"
 
-	"github.com/d2g/dhcp4"
-	"github.com/d2g/dhcp4client"
 	"github.com/jpillora/backoff"
 
 	"github.com/platinasystems/go/goes"
 	"github.com/platinasystems/go/goes/cmd"
 	"github.com/platinasystems/go/goes/lang"
+	"github.com/platinasystems/go/internal/flags"
+	"github.com/platinasystems/go/internal/log"
 	"github.com/platinasystems/go/internal/parms"
 )
 
+const DefaultLeaseDir = "/var/lib/dhcpcd"
+
+var errStopped = errors.New("stopped")
+
 type Command struct {
+	// Machines may keep leases in persistent storage.
+	// default: DefaultLeaseDir
+	LeaseDir string
+
 	g    *goes.Goes
 	done chan struct{}
+
+	ntp string
+
+	backoff4, backoff6 *backoff.Backoff
+
+	mutex  sync.Mutex
+	leases map[int]*Lease
 }
 
 func (*Command) String() string { return "dhcpcd" }
 
-func (*Command) Usage() string { return "dhcpcd" }
+func (*Command) Usage() string {
+	return "dhcpcd [-i INTERFACE] [-4 | -6 | -stateless] [-ntp FILE]"
+}
 
 func (*Command) Apropos() lang.Alt {
 	return lang.Alt{
@@ -43,6 +61,36 @@ func (*Command) Apropos() lang.Alt {
 	}
 }
 
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Configure the interface with addresses, default route, name servers,
+	domain search list, NTP servers and hostname from DHCPv4 (RFC 2131)
+	and DHCPv6 (RFC 8415) servers.
+
+	Leases are renewed with their server at T1, rebound with any server
+	at T2, and released when the daemon stops.  The last lease of each
+	family is saved in /var/lib/dhcpcd/INTERFACE.{4,6}.json so that,
+	after a reboot, the client first requests the same address.
+
+	The hostname is only set if it isn't already.
+
+OPTIONS
+	-i INTERFACE
+		default: eth0
+	-4	only run the DHCPv4 client
+	-6	only run the DHCPv6 client
+	-stateless
+		only run DHCPv6 Information-request for the name servers,
+		domain search list and NTP servers, leaving address
+		configuration to SLAAC
+	-ntp FILE
+		write "server ADDRESS iburst" lines for the leased NTP
+		servers to FILE`,
+	}
+}
+
 func (c *Command) Close() error {
 	close(c.done)
 	return nil
@@ -59,84 +107,12 @@ type ifreq struct {
 	ifrNewname [IFNAMSIZ]byte
 }
 
-func (c *Command) parseACK(ack dhcp4.Packet) (myIP string, rtrIP string, dnsIP string, lt uint32, err error) {
-	myIP = ack.YIAddr().String()
-	opt := ack.ParseOptions()
-	nm := opt[1]
-	fmt.Printf("Option[1] netmask: %v\n", nm)
-	if len(nm) == 4 {
-		myIP = myIP + "/" + strconv.Itoa(bits.LeadingZeros32(^binary.BigEndian.Uint32(nm)))
-	}
-	fmt.Printf("Got address %s\n", myIP)
-
-	rtr := opt[3]
-	fmt.Printf("Option[3] router: %v\n", rtr)
-	if len(rtr) == 4 {
-		ip := net.IP(rtr)
-		if !ip.Equal(net.IPv4(0, 0, 0, 0)) {
-			rtrIP = ip.String()
-		}
-	}
-
-	ltOpt := opt[51]
-	fmt.Printf("Got lease %v\n", ltOpt)
-	lt = uint32(86400)
-	if len(ltOpt) == 4 {
-		lt = binary.BigEndian.Uint32(ltOpt)
-		fmt.Printf("Lease time %d\n", lt)
-	}
-	dns := opt[6]
-	fmt.Printf("Got DNS %v\n", dns)
-	for i := 0; i < len(dns) && len(dns[i:]) >= 4; i += 4 {
-		dnsIP = dnsIP + "nameserver " + net.IP(dns[i:i+4]).String() + "\n"
-	}
-	fmt.Printf("DNS resolved to %v\n", dnsIP)
-
-	return
-}
-
-func (c *Command) updateParm(dev string, myIP string, myLastIP string, rtrIP string, rtrLastIP string, dnsIP string, dnsLastIP string) (err error) {
-	if myIP != myLastIP {
-		if myIP != "" {
-			err = c.g.Main("ip", "address", "add", myIP, "dev", dev)
-			if err != nil {
-				return err
-			}
-		}
-		if myLastIP != "" {
-			err = c.g.Main("ip", "address", "delete", myLastIP, "dev", dev)
-			if err != nil {
-				return err
-			}
-		}
-	}
-	if rtrIP != rtrLastIP {
-		if rtrIP != "" {
-			err := c.g.Main("ip", "route", "add", "0.0.0.0/0", "via", rtrIP)
-			if err != nil {
-				return err
-			}
-		}
-		if rtrLastIP != "" {
-			err := c.g.Main("ip", "route", "delete", "0.0.0.0/0", "via", rtrLastIP)
-			if err != nil {
-				return err
-			}
-		}
-	}
-	if dnsIP != dnsLastIP {
-		if dnsIP != "" {
-			err := ioutil.WriteFile("/etc/resolv.conf", []byte(dnsIP), 0644)
-			if err != nil {
-				return err
-			}
-		}
-	}
-	return nil
-}
-
 func (c *Command) Main(args ...string) error {
-	parm, args := parms.New(args, "-i")
+	flag, args := flags.New(args, "-4", "-6", "-stateless")
+	parm, args := parms.New(args, "-i", "-ntp")
+	if len(args) > 0 {
+		return fmt.Errorf("%v: unexpected", args)
+	}
 	i := "eth0"
 	if parm.ByName["-i"] != "" {
 		i = parm.ByName["-i"]
@@ -144,6 +120,12 @@ func (c *Command) Main(args ...string) error {
 	if len(i) > (IFNAMSIZ)-1 {
 		return errors.New("Interface name too long")
 	}
+	c.ntp = parm.ByName["-ntp"]
+	if len(c.LeaseDir) == 0 {
+		c.LeaseDir = DefaultLeaseDir
+	}
+	v4 := !flag.ByName["-6"] && !flag.ByName["-stateless"]
+	v6 := !flag.ByName["-4"]
 
 	var dev ifreq
 	copy(dev.ifrName[:], i)
@@ -159,11 +141,12 @@ func (c *Command) Main(args ...string) error {
 	if e != 0 {
 		return e
 	}
-	mac := net.HardwareAddr(dev.ifrNewname[2:7])
-
-	fmt.Printf("Got %s\n", mac)
+	mac := net.HardwareAddr(append([]byte{}, dev.ifrNewname[2:8]...))
 
 	c.done = make(chan struct{})
+	c.leases = make(map[int]*Lease)
+	c.backoff4 = newBackoff()
+	c.backoff6 = newBackoff()
 
 	err = c.g.Main("ip", "link", "change", i, "up")
 	if err != nil {
@@ -173,127 +156,250 @@ func (c *Command) Main(args ...string) error {
 		_ = c.g.Main("ip", "link", "change", i, "down")
 	}()
 
-	err = c.g.Main("ip", "route", "add", "255.255.255.255/32", "dev", i)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		_ = c.g.Main("ip", "route", "delete", "255.255.255.255/32", "dev", i)
-	}()
-	sock, err := dhcp4client.NewInetSock(dhcp4client.SetLocalAddr(net.UDPAddr{IP: net.IPv4(0, 0, 0, 0), Port: 68}), dhcp4client.SetRemoteAddr(net.UDPAddr{IP: net.IPv4bcast, Port: 67}))
-	if err != nil {
-		return err
+	var wg sync.WaitGroup
+	if v4 {
+		err = c.g.Main("ip", "route", "add", "255.255.255.255/32",
+			"dev", i)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = c.g.Main("ip", "route", "delete",
+				"255.255.255.255/32", "dev", i)
+		}()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.dhcp4(i, mac); err != nil {
+				c.logf("%s: dhcp4: %v", i, err)
+			}
+		}()
 	}
-	defer sock.Close()
-
-	cl, err := dhcp4client.New(dhcp4client.HardwareAddr(mac), dhcp4client.Connection(sock))
-	if err != nil {
-		return err
+	if v6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := c.dhcp6(i, mac, flag.ByName["-stateless"])
+			if err != nil {
+				c.logf("%s: dhcp6: %v", i, err)
+			}
+		}()
 	}
-	defer cl.Close()
+	wg.Wait()
+	return nil
+}
 
-	b := &backoff.Backoff{
+func newBackoff() *backoff.Backoff {
+	return &backoff.Backoff{
 		Min:    1 * time.Second,
 		Max:    60 * time.Second,
 		Factor: 2,
 		Jitter: false,
 	}
-	var myIP, rtrIP, dnsIP string
-	var lt uint32
-	var ack dhcp4.Packet
-	var success bool
-
-	for {
-		success, ack, err = cl.Request()
-		if err == nil {
-			if success {
-				myIP, rtrIP, dnsIP, lt, err = c.parseACK(ack)
-				if err == nil {
-					err := c.updateParm(i, myIP, "", rtrIP, "", dnsIP, "")
-					if err == nil {
-						break
-					} else {
-						fmt.Fprintf(os.Stderr, "Error in updateParm: %s\n", err)
-					}
-				} else {
-					fmt.Fprintf(os.Stderr, "Error in parseACK: %s\n", err)
-				}
-			}
-		} else {
-			fmt.Fprintf(os.Stderr, "Error in Request: %s\n", err)
-		}
+}
 
-		if !func() bool {
-			t := time.NewTicker(b.Duration())
-			defer t.Stop()
+func (c *Command) leaseFile(dev string, family int) string {
+	return filepath.Join(c.LeaseDir, fmt.Sprint(dev, ".", family, ".json"))
+}
+
+func (c *Command) stopped() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Wait for the given duration; returns false if stopped.
+func (c *Command) wait(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-c.done:
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+func (c *Command) sleepUntil(t time.Time) bool {
+	return c.wait(time.Until(t))
+}
+
+func (c *Command) logf(format string, args ...interface{}) {
+	log.Print("daemon", "info", fmt.Sprintf(format, args...))
+}
 
-			select {
-			case <-c.done:
-				return false
-			case <-t.C:
-				return true
+// Apply the lease of the given family to the interface, replacing any
+// previous lease; a nil lease removes the previous configuration.  The lease
+// file is kept when unbound by stop so that the next start may request the
+// same address.
+func (c *Command) bind(dev string, family int, lease *Lease, fn string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	old := c.leases[family]
+	c.leases[family] = lease
+	if lease == nil {
+		delete(c.leases, family)
+	}
+	var oldAddr, newAddr string
+	var oldRouter, newRouter net.IP
+	if old != nil {
+		oldAddr, oldRouter = old.Address, old.Router
+	}
+	if lease != nil {
+		newAddr, newRouter = lease.Address, lease.Router
+	}
+	if !oldRouter.Equal(newRouter) && oldRouter != nil {
+		c.ip("route", "delete", "0.0.0.0/0", "via", oldRouter.String())
+	}
+	if oldAddr != newAddr {
+		if len(oldAddr) > 0 {
+			c.ip("address", "delete", oldAddr, "dev", dev)
+		}
+		if len(newAddr) > 0 {
+			c.ip("address", "add", newAddr, "dev", dev)
+		}
+	}
+	if !oldRouter.Equal(newRouter) && newRouter != nil {
+		c.ip("route", "add", "0.0.0.0/0", "via", newRouter.String())
+	}
+	if lease != nil && old == nil {
+		c.logf("%s: bound %s", dev, leaseString(lease))
+	}
+	c.resolv()
+	c.ntpConf()
+	if lease != nil && len(lease.Hostname) > 0 && len(hostname()) == 0 {
+		if !validName(lease.Hostname) {
+			c.logf("hostname: %q: invalid", lease.Hostname)
+		} else {
+			err := syscall.Sethostname([]byte(lease.Hostname))
+			if err != nil {
+				c.logf("hostname: %v", err)
 			}
-		}() {
-			return nil
 		}
 	}
+	switch {
+	case lease != nil:
+		if err := lease.save(fn); err != nil {
+			c.logf("%s: %v", fn, err)
+		}
+	case !c.stopped():
+		os.Remove(fn)
+	}
+}
 
-	defer func() {
-		c.updateParm(i, "", myIP, "", rtrIP, "", dnsIP)
-	}()
+func (c *Command) ip(args ...string) {
+	err := c.g.Main(append([]string{"ip"}, args...)...)
+	if err != nil {
+		c.logf("ip %v: %v", args, err)
+	}
+}
+
+// Rewrite /etc/resolv.conf with the name servers and search domains of the
+// bound leases.
+func (c *Command) resolv() {
+	buf := new(bytes.Buffer)
+	var search []string
+	for _, family := range []int{4, 6} {
+		l := c.leases[family]
+		if l == nil {
+			continue
+		}
+		for _, ip := range l.DNS {
+			fmt.Fprintln(buf, "nameserver", ip)
+		}
+		search = appendNew(search, c.valid("search", validName,
+			l.Search)...)
+	}
+	if buf.Len() == 0 {
+		return
+	}
+	if len(search) > 0 {
+		fmt.Fprint(buf, "search")
+		for _, s := range search {
+			fmt.Fprint(buf, " ", s)
+		}
+		fmt.Fprintln(buf)
+	}
+	err := ioutil.WriteFile("/etc/resolv.conf", buf.Bytes(), 0644)
+	if err != nil {
+		c.logf("resolv.conf: %v", err)
+	}
+}
 
-	for {
-		if !func() bool {
-			t := time.NewTicker(time.Duration(lt) * time.Second)
-			defer t.Stop()
+func (c *Command) ntpConf() {
+	if len(c.ntp) == 0 {
+		return
+	}
+	var servers []string
+	for _, family := range []int{4, 6} {
+		if l := c.leases[family]; l != nil {
+			servers = appendNew(servers, c.valid("ntp",
+				validServer, l.NTP)...)
+		}
+	}
+	buf := new(bytes.Buffer)
+	for _, s := range servers {
+		fmt.Fprintln(buf, "server", s, "iburst")
+	}
+	if err := ioutil.WriteFile(c.ntp, buf.Bytes(), 0644); err != nil {
+		c.logf("%s: %v", c.ntp, err)
+	}
+}
 
-			select {
-			case <-c.done:
-				return false
-			case <-t.C:
-				return true
-			}
-		}() {
-			return nil
+// Returns the items that are valid, logging the others.
+func (c *Command) valid(what string, isValid func(string) bool,
+	items []string) []string {
+	valid := make([]string, 0, len(items))
+	for _, s := range items {
+		if isValid(s) {
+			valid = append(valid, s)
+		} else {
+			c.logf("%s: %q: invalid", what, s)
 		}
-		myLastIP := myIP
-		rtrLastIP := rtrIP
-		dnsLastIP := dnsIP
-
-		for {
-			success, ack, err = cl.Renew(ack)
-			if err == nil {
-				if success {
-					myIP, rtrIP, dnsIP, lt, err = c.parseACK(ack)
-					if err == nil {
-						err = c.updateParm(i, myIP, myLastIP, rtrIP, rtrLastIP, dnsIP, dnsLastIP)
-						if err == nil {
-							break
-						} else {
-							fmt.Fprintf(os.Stderr, "Error in updateParm: %s\n", err)
-						}
-					} else {
-						fmt.Fprintf(os.Stderr, "Error in parseACK: %s\n", err)
-					}
-				}
-			} else {
-				fmt.Fprintf(os.Stderr, "Error in Renew: %s\n", err)
-			}
-			if !func() bool {
-				t := time.NewTicker(b.Duration())
-				defer t.Stop()
-
-				select {
-				case <-c.done:
-					return false
-				case <-t.C:
-					return true
-				}
-			}() {
-				return nil
+	}
+	return valid
+}
+
+// Fill b with random bytes for a transaction id that an off-path attacker
+// can't predict.
+func randomXid(b []byte) {
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+}
+
+func appendNew(list []string, items ...string) []string {
+items:
+	for _, s := range items {
+		for _, x := range list {
+			if x == s {
+				continue items
 			}
 		}
+		list = append(list, s)
 	}
+	return list
+}
 
-	return nil
+func leaseString(l *Lease) string {
+	s := l.Address
+	if len(s) == 0 {
+		s = "information"
+	}
+	if l.Lifetime == infinity {
+		return s + " forever"
+	}
+	return fmt.Sprint(s, " for ", time.Duration(l.Lifetime)*time.Second)
+}
+
+// Returns the local hostname if it's been set.
+func hostname() string {
+	s, err := os.Hostname()
+	if err != nil || s == "(none)" || s == "localhost" {
+		return ""
+	}
+	return s
 }
@@ -0,0 +1,109 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package dhcpcd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// A Lease is the configuration bound to an interface by a DHCPv4 ACK or
+// DHCPv6 REPLY.  It's saved as JSON so that the client may request the same
+// address after a reboot.
+type Lease struct {
+	// Address in CIDR notation; empty with stateless DHCPv6.
+	Address string `json:",omitempty"`
+	Router  net.IP `json:",omitempty"`
+
+	// DHCPv4 server identifier or DHCPv6 server DUID
+	Server []byte `json:",omitempty"`
+	IAID   uint32 `json:",omitempty"`
+
+	DNS      []net.IP `json:",omitempty"`
+	Search   []string `json:",omitempty"`
+	NTP      []string `json:",omitempty"`
+	Hostname string   `json:",omitempty"`
+
+	Acquired time.Time
+	// Renew (T1), Rebind (T2) and Lifetime are in seconds from Acquired.
+	Renew, Rebind, Lifetime uint32
+}
+
+func (l *Lease) RenewAt() time.Time  { return l.at(l.Renew) }
+func (l *Lease) RebindAt() time.Time { return l.at(l.Rebind) }
+func (l *Lease) ExpireAt() time.Time { return l.at(l.Lifetime) }
+
+// Expired returns true if the lease is nil or has outlived its lifetime.
+func (l *Lease) Expired() bool {
+	return l == nil || !time.Now().Before(l.ExpireAt())
+}
+
+func (l *Lease) IP() net.IP {
+	if l == nil {
+		return nil
+	}
+	ip, _, _ := net.ParseCIDR(l.Address)
+	return ip
+}
+
+func (l *Lease) at(secs uint32) time.Time {
+	if secs == infinity {
+		return l.Acquired.Add(100 * 365 * 24 * time.Hour)
+	}
+	return l.Acquired.Add(time.Duration(secs) * time.Second)
+}
+
+// Fill the unset renew and rebind times with the RFC 2131 defaults of one
+// half and seven eighths of the lifetime.
+func (l *Lease) defaultTimes() {
+	if l.Lifetime == infinity {
+		if l.Renew == 0 {
+			l.Renew = infinity
+		}
+		if l.Rebind == 0 {
+			l.Rebind = infinity
+		}
+		return
+	}
+	if l.Renew == 0 || l.Renew > l.Lifetime {
+		l.Renew = l.Lifetime / 2
+	}
+	if l.Rebind == 0 || l.Rebind > l.Lifetime || l.Rebind < l.Renew {
+		l.Rebind = l.Lifetime / 8 * 7
+	}
+}
+
+const infinity = ^uint32(0)
+
+func loadLease(fn string) *Lease {
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil
+	}
+	l := new(Lease)
+	if json.Unmarshal(b, l) != nil || l.Expired() {
+		return nil
+	}
+	return l
+}
+
+func (l *Lease) save(fn string) error {
+	b, err := json.MarshalIndent(l, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+		return err
+	}
+	tmp := fn + ".tmp"
+	if err = ioutil.WriteFile(tmp, append(b, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fn)
+}
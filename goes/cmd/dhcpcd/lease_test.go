@@ -0,0 +1,72 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package dhcpcd
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLease(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dhcpcd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "leases", "eth0.4")
+
+	if loadLease(fn) != nil {
+		t.Fatal("loaded missing lease")
+	}
+	l := &Lease{
+		Address:  "192.168.1.10/24",
+		Router:   net.ParseIP("192.168.1.1"),
+		Server:   []byte{192, 168, 1, 1},
+		DNS:      []net.IP{net.ParseIP("192.168.1.2")},
+		Search:   []string{"example.com"},
+		NTP:      []string{"192.168.1.3"},
+		Hostname: "sw1",
+		Acquired: time.Now().Round(0).Truncate(time.Second),
+		Lifetime: 3600,
+	}
+	l.defaultTimes()
+	if err = l.save(fn); err != nil {
+		t.Fatal(err)
+	}
+	got := loadLease(fn)
+	if got == nil {
+		t.Fatal("lease not loaded")
+	}
+	if !got.Acquired.Equal(l.Acquired) {
+		t.Fatalf("acquired %v, want %v", got.Acquired, l.Acquired)
+	}
+	got.Acquired = l.Acquired
+	if !reflect.DeepEqual(got, l) {
+		t.Fatalf("got %+v\nwant %+v", got, l)
+	}
+	if got.Renew != 1800 || got.Rebind != 3150 {
+		t.Fatal("renew", got.Renew, "rebind", got.Rebind)
+	}
+
+	l.Acquired = l.Acquired.Add(-2 * time.Hour)
+	if err = l.save(fn); err != nil {
+		t.Fatal(err)
+	}
+	if loadLease(fn) != nil {
+		t.Fatal("loaded expired lease")
+	}
+
+	if err = ioutil.WriteFile(fn, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if loadLease(fn) != nil {
+		t.Fatal("loaded bad lease")
+	}
+}
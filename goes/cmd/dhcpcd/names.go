@@ -0,0 +1,112 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package dhcpcd
+
+import (
+	"net"
+	"strings"
+)
+
+// Encode domain names as RFC 1035 label sequences as used by the DHCPv4
+// domain search (RFC 3397) and DHCPv6 domain list and FQDN options.
+func encodeNames(names ...string) []byte {
+	var b []byte
+	for _, name := range names {
+		for _, label := range strings.Split(strings.Trim(name, "."),
+			".") {
+			if len(label) == 0 || len(label) > 63 {
+				continue
+			}
+			b = append(b, byte(len(label)))
+			b = append(b, label...)
+		}
+		b = append(b, 0)
+	}
+	return b
+}
+
+// Decode a sequence of RFC 1035 names, following compression pointers
+// within the given buffer.
+func decodeNames(b []byte) []string {
+	var names []string
+	for i := 0; i < len(b); {
+		name, next, ok := decodeName(b, i)
+		if !ok {
+			break
+		}
+		if len(name) > 0 {
+			names = append(names, name)
+		}
+		i = next
+	}
+	return names
+}
+
+// Returns the name at b[i:] and the index following it.
+func decodeName(b []byte, i int) (string, int, bool) {
+	var labels []string
+	next := -1
+	for hops := 0; i < len(b); hops++ {
+		if hops > len(b) {
+			return "", 0, false
+		}
+		n := int(b[i])
+		switch {
+		case n == 0:
+			if next < 0 {
+				next = i + 1
+			}
+			return strings.Join(labels, "."), next, true
+		case n&0xc0 == 0xc0:
+			if i+1 >= len(b) {
+				return "", 0, false
+			}
+			if next < 0 {
+				next = i + 2
+			}
+			i = (n&0x3f)<<8 | int(b[i+1])
+		case i+1+n > len(b):
+			return "", 0, false
+		default:
+			labels = append(labels, string(b[i+1:i+1+n]))
+			i += 1 + n
+		}
+	}
+	return "", 0, false
+}
+
+// Returns true if s is an RFC 1123 host or domain name: dot separated labels
+// of 1 to 63 letters, digits and hyphens that don't begin or end with a
+// hyphen, with an optional trailing dot.  Server supplied names are written
+// to /etc/resolv.conf and such, so anything else, e.g. a newline, must be
+// rejected.
+func validName(s string) bool {
+	s = strings.TrimSuffix(s, ".")
+	if len(s) == 0 || len(s) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if len(label) == 0 || len(label) > 63 ||
+			label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range []byte(label) {
+			switch {
+			case 'a' <= c && c <= 'z':
+			case 'A' <= c && c <= 'Z':
+			case '0' <= c && c <= '9':
+			case c == '-':
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Returns true if s is an ip address or valid name.
+func validServer(s string) bool {
+	return net.ParseIP(s) != nil || validName(s)
+}
@@ -0,0 +1,85 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package dhcpcd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNames(t *testing.T) {
+	names := []string{"example.com", "lab.example.com", "x"}
+	got := decodeNames(encodeNames(names...))
+	if !reflect.DeepEqual(got, names) {
+		t.Fatalf("got %q, want %q", got, names)
+	}
+}
+
+func TestDecodeName(t *testing.T) {
+	for _, x := range []struct {
+		name string
+		b    []byte
+		i    int
+		want string
+		next int
+		ok   bool
+	}{
+		{"root", []byte{0}, 0, "", 1, true},
+		{"labels", []byte("\x03foo\x03bar\x00"), 0, "foo.bar", 9, true},
+		{
+			"pointer",
+			[]byte("\x03bar\x00\x03foo\xc0\x00"),
+			5, "foo.bar", 11, true,
+		},
+		{"truncated label", []byte("\x05foo"), 0, "", 0, false},
+		{"unterminated", []byte("\x03foo"), 0, "", 0, false},
+		{"truncated pointer", []byte("\x03foo\xc0"), 0, "", 0, false},
+		{"pointer past end", []byte("\xc0\x10"), 0, "", 0, false},
+		{"pointer to self", []byte("\xc0\x00"), 0, "", 0, false},
+		{
+			"pointer loop",
+			[]byte("\x03foo\xc0\x06\x03bar\xc0\x00"),
+			0, "", 0, false,
+		},
+	} {
+		name, next, ok := decodeName(x.b, x.i)
+		if name != x.want || next != x.next || ok != x.ok {
+			t.Errorf("%s: got %q, %d, %v; want %q, %d, %v", x.name,
+				name, next, ok, x.want, x.next, x.ok)
+		}
+	}
+}
+
+func TestValidName(t *testing.T) {
+	for _, x := range []struct {
+		s  string
+		ok bool
+	}{
+		{"example.com", true},
+		{"example.com.", true},
+		{"sw-1", true},
+		{"1.2.3.4", true},
+		{"", false},
+		{".", false},
+		{"a..b", false},
+		{"-a.com", false},
+		{"a-.com", false},
+		{"a_b.com", false},
+		{"a b", false},
+		{"a.com\nnameserver 1.2.3.4", false},
+		{strings.Repeat("a", 63), true},
+		{strings.Repeat("a", 64), false},
+		{strings.Repeat("a.", 127), true},
+		{strings.Repeat("a.", 127) + "a", false},
+	} {
+		if ok := validName(x.s); ok != x.ok {
+			t.Errorf("%q: got %v", x.s, ok)
+		}
+	}
+	if !validServer("fe80::1") || validServer("ntp;reboot") {
+		t.Error("validServer")
+	}
+}
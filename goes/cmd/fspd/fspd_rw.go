@@ -9,6 +9,7 @@ import (
 	"time"
 	"unsafe"
 
+	"github.com/platinasystems/go/internal/atsock"
 	"github.com/platinasystems/go/internal/i2c"
 	"github.com/platinasystems/go/internal/log"
 )
@@ -197,7 +198,7 @@ func startI2c() error {
 
 func DoI2cRpc() error {
 	if dialed == 0 {
-		client, err := rpc.DialHTTP("tcp", "127.0.0.1"+":1233")
+		client, err := atsock.NewRpcClient(i2c.ServerName)
 		if err != nil {
 			log.Print("dialing:", err)
 			return err
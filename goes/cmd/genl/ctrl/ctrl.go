@@ -0,0 +1,46 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package ctrl
+
+import (
+	"github.com/platinasystems/go/goes"
+	"github.com/platinasystems/go/goes/cmd"
+	"github.com/platinasystems/go/goes/cmd/genl/ctrl/get"
+	"github.com/platinasystems/go/goes/cmd/genl/ctrl/list"
+	"github.com/platinasystems/go/goes/cmd/genl/ctrl/monitor"
+	"github.com/platinasystems/go/goes/lang"
+)
+
+var Goes = &goes.Goes{
+	NAME: "ctrl",
+	USAGE: `
+	genl ctrl [ list ] [ -d[etails] ]
+	genl ctrl get { name NAME | id ID } [ -d[etails] ]
+	genl ctrl monitor`,
+	APROPOS: lang.Alt{
+		lang.EnUS: "generic netlink controller",
+	},
+	MAN: lang.Alt{
+		lang.EnUS: `
+COMMANDS
+	list	print all families (default)
+	get	print the family with the given name or id
+	monitor	print family and multicast group changes
+
+OPTIONS
+	-d[etails]
+		also print command capabilities
+
+SEE ALSO
+	genl ctrl man COMMAND || genl ctrl COMMAND -man
+	man genl || genl -man`,
+	},
+	ByName: map[string]cmd.Cmd{
+		"":        list.Command(""),
+		"list":    list.Command("list"),
+		"get":     get.Command{},
+		"monitor": monitor.Command{},
+	},
+}
@@ -0,0 +1,87 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package get
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/platinasystems/go/goes/cmd/genl/internal/show"
+	"github.com/platinasystems/go/goes/lang"
+	"github.com/platinasystems/go/internal/flags"
+	"github.com/platinasystems/go/internal/nl"
+	"github.com/platinasystems/go/internal/nl/genl"
+	"github.com/platinasystems/go/internal/parms"
+)
+
+type Command struct{}
+
+func (Command) String() string { return "get" }
+
+func (Command) Usage() string {
+	return `genl ctrl get { name NAME | id ID } [ -d[etails] ]`
+}
+
+func (Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "print a generic netlink family",
+	}
+}
+
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+OPTIONS
+	name NAME
+		family name, e.g. "ethtool"
+
+	id ID	family message type, e.g. 0x10
+
+	-d[etails]
+		also print command capabilities
+
+SEE ALSO
+	genl man ctrl || genl ctrl -man`,
+	}
+}
+
+func (Command) Main(args ...string) error {
+	var f *genl.Family
+
+	flag, args := flags.New(args, []string{"-d", "-details"})
+	parm, args := parms.New(args, "name", "id")
+	if len(args) > 0 {
+		return fmt.Errorf("%v: unexpected", args)
+	}
+	name, sid := parm.ByName["name"], parm.ByName["id"]
+	if len(name) == 0 && len(sid) == 0 {
+		return fmt.Errorf("missing name or id")
+	}
+
+	sock, err := nl.NewSock(nl.NETLINK_GENERIC)
+	if err != nil {
+		return err
+	}
+	defer sock.Close()
+
+	sr := nl.NewSockReceiver(sock)
+
+	if len(name) > 0 {
+		f, err = genl.Lookup(sr, name)
+	} else {
+		var id uint64
+		id, err = strconv.ParseUint(sid, 0, 16)
+		if err != nil {
+			return fmt.Errorf("id: %q %v", sid, err)
+		}
+		f, err = genl.LookupId(sr, uint16(id))
+	}
+	if err != nil {
+		return err
+	}
+	show.Family(os.Stdout, f, flag.ByName["-d"])
+	return nil
+}
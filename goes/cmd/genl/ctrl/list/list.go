@@ -0,0 +1,72 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package list
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/platinasystems/go/goes/cmd/genl/internal/show"
+	"github.com/platinasystems/go/goes/lang"
+	"github.com/platinasystems/go/internal/flags"
+	"github.com/platinasystems/go/internal/nl"
+	"github.com/platinasystems/go/internal/nl/genl"
+)
+
+type Command string
+
+func (Command) Aka() string { return "list" }
+
+func (c Command) String() string { return string(c) }
+
+func (Command) Usage() string {
+	return `genl ctrl [ list ] [ -d[etails] ]`
+}
+
+func (c Command) Apropos() lang.Alt {
+	apropos := "list generic netlink families"
+	if c == "list" {
+		apropos += " (default)"
+	}
+	return lang.Alt{
+		lang.EnUS: apropos,
+	}
+}
+
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+OPTIONS
+	-d[etails]
+		also print command capabilities
+
+SEE ALSO
+	genl man ctrl || genl ctrl -man`,
+	}
+}
+
+func (Command) Main(args ...string) error {
+	flag, args := flags.New(args, []string{"-d", "-details"})
+	if len(args) > 0 {
+		return fmt.Errorf("%v: unexpected", args)
+	}
+
+	sock, err := nl.NewSock(nl.NETLINK_GENERIC)
+	if err != nil {
+		return err
+	}
+	defer sock.Close()
+
+	sr := nl.NewSockReceiver(sock)
+
+	families, err := genl.Families(sr)
+	if err != nil {
+		return err
+	}
+	for _, f := range families {
+		show.Family(os.Stdout, f, flag.ByName["-d"])
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/platinasystems/go/goes/cmd/genl/internal/show"
+	"github.com/platinasystems/go/goes/lang"
+	"github.com/platinasystems/go/internal/nl"
+	"github.com/platinasystems/go/internal/nl/genl"
+)
+
+type Command struct{}
+
+func (Command) String() string { return "monitor" }
+
+func (Command) Usage() string {
+	return `genl ctrl monitor`
+}
+
+func (Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "print generic netlink family changes",
+	}
+}
+
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Print the families and multicast groups that are registered or
+	unregistered until interrupted.
+
+SEE ALSO
+	genl man ctrl || genl ctrl -man`,
+	}
+}
+
+func (Command) Main(args ...string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("%v: unexpected", args)
+	}
+
+	sock, err := nl.NewSock(nl.NETLINK_GENERIC, 16)
+	if err != nil {
+		return err
+	}
+	defer sock.Close()
+
+	f, err := genl.Lookup(nl.NewSockReceiver(sock), "nlctrl")
+	if err != nil {
+		return err
+	}
+	grp, err := f.Group("notify")
+	if err != nil {
+		return err
+	}
+	if err = sock.Join(grp); err != nil {
+		return err
+	}
+
+	sigch := make(chan os.Signal, 1)
+	signal.Notify(sigch, os.Interrupt, os.Signal(syscall.SIGTERM))
+	defer signal.Stop(sigch)
+
+selectLoop:
+	for err == nil {
+		select {
+		case <-sigch:
+			break selectLoop
+		case b, opened := <-sock.RxCh:
+			if !opened {
+				break selectLoop
+			}
+			for err == nil && len(b) > nl.SizeofHdr {
+				var msg []byte
+				msg, b, err = nl.Pop(b)
+				handle(msg)
+			}
+		}
+	}
+	return err
+}
+
+func handle(b []byte) {
+	var what string
+	if nl.HdrPtr(b).Type != genl.GENL_ID_CTRL || genl.MsgPtr(b) == nil {
+		return
+	}
+	switch genl.MsgPtr(b).Cmd {
+	case genl.CTRL_CMD_NEWFAMILY:
+		what = "new family"
+	case genl.CTRL_CMD_DELFAMILY:
+		what = "deleted family"
+	case genl.CTRL_CMD_NEWMCAST_GRP:
+		what = "new multicast group"
+	case genl.CTRL_CMD_DELMCAST_GRP:
+		what = "deleted multicast group"
+	default:
+		return
+	}
+	var f genl.Family
+	f.Write(b)
+	fmt.Print("[", what, "] ")
+	show.Family(os.Stdout, &f, false)
+}
@@ -0,0 +1,42 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package genl
+
+import (
+	"github.com/platinasystems/go/goes"
+	"github.com/platinasystems/go/goes/cmd"
+	"github.com/platinasystems/go/goes/cmd/genl/ctrl"
+	"github.com/platinasystems/go/goes/lang"
+)
+
+var Goes = &goes.Goes{
+	NAME: "genl",
+	USAGE: `
+	genl OBJECT [ COMMAND [ OPTION... ] ]
+
+OBJECT := { ctrl }`,
+	APROPOS: lang.Alt{
+		lang.EnUS: "generic netlink utility",
+	},
+	MAN: lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Query the generic netlink controller for the registered families,
+	their commands and multicast groups.
+
+EXAMPLES
+	List all families
+		# genl ctrl list
+
+	Show the ethtool family
+		# genl ctrl get name ethtool
+
+SEE ALSO
+	genl man ctrl || genl ctrl -man`,
+	},
+	ByName: map[string]cmd.Cmd{
+		"ctrl": ctrl.Goes,
+	},
+}
@@ -0,0 +1,58 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package show prints generic netlink families like iproute2's genl.
+package show
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/platinasystems/go/internal/nl/genl"
+)
+
+func Family(w io.Writer, f *genl.Family, details bool) {
+	fmt.Fprintln(w, "Name:", f.Name)
+	fmt.Fprintf(w, "\tID: %#x  Version: %#x  header size: %d  max attribs: %d\n",
+		f.Id, f.Version, f.HdrSize, f.MaxAttr)
+	if len(f.Ops) > 0 {
+		fmt.Fprintln(w, "\tcommands supported:")
+		for i, op := range f.Ops {
+			fmt.Fprintf(w, "\t\t#%d:  ID-%#x", i+1, op.Id)
+			if details && op.Flags != 0 {
+				fmt.Fprintf(w, "\n\t\t  Capabilities (%#x): %s",
+					op.Flags, Caps(op.Flags))
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	if len(f.Groups) > 0 {
+		fmt.Fprintln(w, "\tmulticast groups:")
+		for i, g := range f.Groups {
+			fmt.Fprintf(w, "\t\t#%d:  ID-%#x  name: %s\n",
+				i+1, g.Id, g.Name)
+		}
+	}
+}
+
+// Caps formats the GENL_ADMIN_PERM and GENL_CMD_CAP_* flags of an op.
+func Caps(flags uint32) string {
+	var caps []string
+	for _, x := range []struct {
+		flag uint8
+		name string
+	}{
+		{genl.GENL_ADMIN_PERM, "requires admin permission"},
+		{genl.GENL_CMD_CAP_DO, "can doit"},
+		{genl.GENL_CMD_CAP_DUMP, "can dumpit"},
+		{genl.GENL_CMD_CAP_HASPOL, "has policy"},
+		{genl.GENL_UNS_ADMIN_PERM, "requires namespace admin permission"},
+	} {
+		if flags&uint32(x.flag) != 0 {
+			caps = append(caps, x.name)
+		}
+	}
+	return strings.Join(caps, "; ")
+}
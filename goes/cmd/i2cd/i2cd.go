@@ -2,36 +2,45 @@
 // Use of this source code is governed by the GPL-2 license described in the
 // LICENSE file.
 
-// Package ucd9090 provides access to the UCD9090 Power Sequencer/Monitor chip
+// Package i2cd serves i2c transactions to the hardware daemons.
 package i2cd
 
 import (
+	"fmt"
 	"net"
 	"net/http"
 	"net/rpc"
 	"sync"
-	"syscall"
 	"time"
 
 	"github.com/platinasystems/go/goes/cmd"
 	"github.com/platinasystems/go/goes/cmd/iocmd"
 	"github.com/platinasystems/go/goes/lang"
+	"github.com/platinasystems/go/internal/atsock"
 	"github.com/platinasystems/go/internal/gpio"
 	"github.com/platinasystems/go/internal/i2c"
+	"github.com/platinasystems/go/internal/i2c/sim"
 	"github.com/platinasystems/go/internal/log"
-	"github.com/platinasystems/go/internal/redis"
 	"github.com/platinasystems/go/internal/machine"
+	"github.com/platinasystems/go/internal/parms"
+	"github.com/platinasystems/go/internal/redis"
 )
 
 type Command struct {
 	Gpio func()
-	gpio sync.Once
-	done chan struct{}
+	// Backend, if non-nil, serves requests instead of /dev/i2c-X
+	Backend i2c.Backend
+	gpio    sync.Once
+	done    chan struct{}
+	locks   busLocks
+
+	mutex   sync.Mutex
+	stopped bool
 }
 
 func (*Command) String() string { return "i2cd" }
 
-func (*Command) Usage() string { return "i2cd" }
+func (*Command) Usage() string { return "i2cd [-sim FILE] [-tcp ADDRESS]" }
 
 func (*Command) Apropos() lang.Alt {
 	return lang.Alt{
@@ -39,6 +48,40 @@ func (*Command) Apropos() lang.Alt {
 	}
 }
 
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Serve i2c transactions of the hardware daemons through RPC on the
+	@MACHINE/i2cd socket.
+
+	Each transaction of version 1 of the I2c.Do method is a sequence of
+	mux selects, SMBus reads and writes, and block transfers of any
+	length with a result and error per operation.  The buses of a
+	transaction are locked from other clients until it's done, or
+	longer if the client asks to hold them.
+
+	The I2cReq.ReadWrite method of 30 operations is deprecated.
+
+OPTIONS
+	-sim FILE
+		Serve from simulated devices loaded from the FILE script
+		instead of /dev/i2c-X.  The script has lines of,
+
+		dev NAME MODEL BUS ADDRESS
+		dev NAME MODEL MUX.CHANNEL ADDRESS
+		set NAME [PAGE:]REG VALUE...
+
+		where MODEL is one of: regs, w83795, ucd9090, qsfp,
+		pca9534, pca9535, pca9539, pca9548, pca9554, or pca9555;
+		and MUX is the NAME of a pca9548.
+
+	-tcp ADDRESS
+		Also serve HTTP RPC on this TCP address, e.g.
+		127.0.0.1:1233, for remote clients.`,
+	}
+}
+
 func (c *Command) Close() error {
 	close(c.done)
 	return nil
@@ -46,36 +89,142 @@ func (c *Command) Close() error {
 
 func (*Command) Kind() cmd.Kind { return cmd.Daemon }
 
-func (c *Command) Main(...string) error {
-	var si syscall.Sysinfo_t
-	err := syscall.Sysinfo(&si)
+func (c *Command) Main(args ...string) error {
+	parm, args := parms.New(args, "-sim", "-tcp")
+	if len(args) > 0 {
+		return fmt.Errorf("%v: unexpected", args)
+	}
+
+	if fn := parm.ByName["-sim"]; len(fn) > 0 {
+		s := sim.New()
+		if err := s.LoadFile(fn); err != nil {
+			return err
+		}
+		c.Backend = s
+	}
+	if c.Backend != nil {
+		i2c.Use(c.Backend)
+		log.Print("i2c backend ", fmt.Sprintf("%T", c.Backend))
+	}
+
+	c.done = make(chan struct{})
+	rpc.Register(&I2c{c})
+	rpc.Register(&I2cReq{c})
+	srvr, err := atsock.NewRpcServer(i2c.ServerName)
 	if err != nil {
 		return err
 	}
+	defer srvr.Close()
 
-	c.done = make(chan struct{})
-	i2cReq := &I2cReq{c}
-	rpc.Register(i2cReq)
-	rpc.HandleHTTP()
-	l, e := net.Listen("tcp", ":1233")
-	if e != nil {
-		log.Print("listen error:", e)
-	}
-	log.Print("listen OKAY")
-	go http.Serve(l, nil)
-
-	t := time.NewTicker(20 * time.Millisecond)
-	defer t.Stop()
-	for {
-		select {
-		case <-c.done:
-			return nil
-		case <-t.C:
+	if addr := parm.ByName["-tcp"]; len(addr) > 0 {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+		defer l.Close()
+		rpc.HandleHTTP()
+		go http.Serve(l, nil)
+	}
+
+	<-c.done
+	return nil
+}
+
+func (c *Command) setStopped(stop bool) (prev bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	prev, c.stopped = c.stopped, stop
+	return
+}
+
+func (c *Command) isStopped() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.stopped
+}
+
+// Reset the muxes after a failed transfer that may have left a device
+// holding the bus.
+func (c *Command) reset() {
+	m, _ := redis.Hget(machine.Name, "machine")
+	switch m {
+	case "platina-mk1":
+		d, err := iocmd.Io_reg_rd(0x603, 1)
+		if err == nil {
+			iocmd.Io_reg_wr(0x603, uint64(d[0]&0xb0), 0x1)
+			time.Sleep(10 * time.Microsecond)
+			iocmd.Io_reg_wr(0x603, uint64(d[0]|0x40), 0x1)
+		}
+	case "platina-mk1-bmc":
+		c.gpio.Do(c.Gpio)
+		pin, found := gpio.Pins["FRU_I2C_MUX_RST_L"]
+		if found {
+			pin.SetValue(false)
+			time.Sleep(10 * time.Microsecond)
+			pin.SetValue(true)
 		}
+
+		pin, found = gpio.Pins["MAIN_I2C_MUX_RST_L"]
+		if found {
+			pin.SetValue(false)
+			time.Sleep(10 * time.Microsecond)
+			pin.SetValue(true)
+		}
+	default:
+	}
+}
+
+// I2c is the RPC service of transactions.
+type I2c struct {
+	c *Command
+}
+
+// Do the transaction while its buses are locked.
+func (s *I2c) Do(t *i2c.Txn, reply *i2c.Reply) error {
+	reply.Version = i2c.Version
+	if t.Version != i2c.Version {
+		return fmt.Errorf("version %d unsupported", t.Version)
+	}
+	if t.Hold > 0 && len(t.Client) == 0 {
+		return fmt.Errorf("hold without client")
+	}
+	timeout := t.Timeout
+	if timeout == 0 {
+		timeout = i2c.DefaultTimeout
+	}
+	buses := t.Buses()
+	if err := s.c.locks.acquire(t.Client, buses, timeout); err != nil {
+		return err
+	}
+	defer s.c.locks.release(t.Client, buses, t.Hold)
+	reply.Results = t.Do()
+	if err := i2c.Err(reply.Results); err != nil {
+		op := t.Ops[len(reply.Results)-1]
+		log.Printf("daemon", "err", "%s: bus 0x%x addr 0x%x: %v",
+			t.Client, op.Bus, op.Address, err)
+		s.c.reset()
 	}
 	return nil
 }
 
+// Release the buses held by the named client.
+func (s *I2c) Release(client *string, _ *struct{}) error {
+	s.c.locks.releaseAll(*client)
+	return nil
+}
+
+// Stop, or restart, polling of the hardware daemons; reply with the
+// previous state.
+func (s *I2c) Stop(stop *bool, prev *bool) error {
+	*prev = s.c.setStopped(*stop)
+	return nil
+}
+
+func (s *I2c) Stopped(_ *bool, stopped *bool) error {
+	*stopped = s.c.isStopped()
+	return nil
+}
+
 const MAXOPS = 30
 
 type I struct {
@@ -93,33 +242,39 @@ type R struct {
 	E error
 }
 
+// I2cReq is the deprecated RPC service of fixed operation arrays.
 type I2cReq struct {
 	c *Command
 }
 
-var b = [34]byte{0}
-var i = I{false, i2c.RW(0), 0, 0, b, 0, 0, 0}
-var j [MAXOPS]I
-var r = R{b, nil}
-var s [MAXOPS]R
-var x int
-var stopped byte = 0
-var mutex = &sync.Mutex{}
+const legacyClient = "I2cReq"
 
 func (t *I2cReq) ReadWrite(g *[MAXOPS]I, f *[MAXOPS]R) error {
-	mutex.Lock()
-	defer mutex.Unlock()
-
-	var bus i2c.Bus
-	var data i2c.SMBusData
 	if g[0].Bus == 0x99 {
-		stopped = byte(g[0].Addr)
+		t.c.setStopped(g[0].Addr != 0)
 		return nil
 	}
 	if g[0].Bus == 0x98 {
-		f[0].D[0] = stopped
+		if t.c.isStopped() {
+			f[0].D[0] = 1
+		}
 		return nil
 	}
+
+	var buses []int
+	for x := 0; x < MAXOPS; x++ {
+		if g[x].InUse {
+			buses = append(buses, g[x].Bus)
+		}
+	}
+	err := t.c.locks.acquire(legacyClient, buses, i2c.DefaultTimeout)
+	if err != nil {
+		return err
+	}
+	defer t.c.locks.release(legacyClient, buses, 0)
+
+	var bus i2c.Bus
+	var data i2c.SMBusData
 	for x := 0; x < MAXOPS; x++ {
 		if g[x].InUse == true {
 			err := bus.Open(g[x].Bus)
@@ -145,33 +300,7 @@ func (t *I2cReq) ReadWrite(g *[MAXOPS]I, f *[MAXOPS]R) error {
 					log.Printf("I2C R/W before Error: bus 0x%x addr 0x%x offset 0x%x data 0x%x RW %d BusSize %d delay %d", g[y].Bus, g[y].Addr, g[y].RegOffset, g[y].Data[0], g[y].RW, g[y].BusSize, g[y].Delay)
 				}
 				log.Printf("Error doing I2C R/W: bus 0x%x addr 0x%x offset 0x%x data 0x%x RW %d BusSize %d delay %d", g[x].Bus, g[x].Addr, g[x].RegOffset, data[0], g[x].RW, g[x].BusSize, g[x].Delay)
-				m, _ := redis.Hget(machine.Name, "machine")
-
-				switch m {
-				case "platina-mk1":
-					d, err := iocmd.Io_reg_rd(0x603, 1)
-					if err == nil {
-						iocmd.Io_reg_wr(0x603, uint64(d[0]&0xb0), 0x1)
-						time.Sleep(10 * time.Microsecond)
-						iocmd.Io_reg_wr(0x603, uint64(d[0]|0x40), 0x1)
-					}
-				case "platina-mk1-bmc":
-					t.c.gpio.Do(t.c.Gpio)
-					pin, found := gpio.Pins["FRU_I2C_MUX_RST_L"]
-					if found {
-						pin.SetValue(false)
-						time.Sleep(10 * time.Microsecond)
-						pin.SetValue(true)
-					}
-
-					pin, found = gpio.Pins["MAIN_I2C_MUX_RST_L"]
-					if found {
-						pin.SetValue(false)
-						time.Sleep(10 * time.Microsecond)
-						pin.SetValue(true)
-					}
-				default:
-				}
+				t.c.reset()
 				return err
 			}
 			f[x].D[0] = data[0]
@@ -189,11 +318,3 @@ func (t *I2cReq) ReadWrite(g *[MAXOPS]I, f *[MAXOPS]R) error {
 	}
 	return nil
 }
-
-func clearJS() {
-	x = 0
-	for k := 0; k < MAXOPS; k++ {
-		j[k] = i
-		s[k] = r
-	}
-}
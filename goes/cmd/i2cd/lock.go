@@ -0,0 +1,108 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package i2cd
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// busLocks serialize the transactions of each bus.  A client may hold its
+// buses between transactions until released or the hold expires.
+type busLocks struct {
+	mutex   sync.Mutex
+	byBus   map[int]*busLock
+	changed chan struct{}
+}
+
+type busLock struct {
+	busy   bool
+	client string
+	until  time.Time
+}
+
+func (l *busLocks) free(client string, bus int, now time.Time) bool {
+	bl := l.byBus[bus]
+	if bl == nil || bl.busy {
+		return bl == nil
+	}
+	return bl.client == client || len(bl.client) == 0 ||
+		now.After(bl.until)
+}
+
+// Acquire all of the given buses or none.
+func (l *busLocks) acquire(client string, buses []int,
+	timeout time.Duration) error {
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	for {
+		l.mutex.Lock()
+		if l.byBus == nil {
+			l.byBus = make(map[int]*busLock)
+			l.changed = make(chan struct{})
+		}
+		now := time.Now()
+		free := true
+		for _, bus := range buses {
+			if !l.free(client, bus, now) {
+				free = false
+				break
+			}
+		}
+		if free {
+			for _, bus := range buses {
+				l.byBus[bus] = &busLock{busy: true, client: client}
+			}
+			l.mutex.Unlock()
+			return nil
+		}
+		changed := l.changed
+		l.mutex.Unlock()
+		select {
+		case <-changed:
+		case <-t.C:
+			return fmt.Errorf("%v: busy", buses)
+		case <-time.After(10 * time.Millisecond):
+			// poll for expired holds
+		}
+	}
+}
+
+// Release the buses or hold them for the client.
+func (l *busLocks) release(client string, buses []int, hold time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for _, bus := range buses {
+		if hold > 0 {
+			l.byBus[bus] = &busLock{
+				client: client,
+				until:  time.Now().Add(hold),
+			}
+		} else {
+			delete(l.byBus, bus)
+		}
+	}
+	l.broadcast()
+}
+
+// Release the buses held by the client.
+func (l *busLocks) releaseAll(client string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for bus, bl := range l.byBus {
+		if !bl.busy && bl.client == client {
+			delete(l.byBus, bus)
+		}
+	}
+	l.broadcast()
+}
+
+func (l *busLocks) broadcast() {
+	if l.changed != nil {
+		close(l.changed)
+	}
+	l.changed = make(chan struct{})
+}
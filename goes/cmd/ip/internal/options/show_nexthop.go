@@ -0,0 +1,95 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package options
+
+import (
+	"net"
+
+	"github.com/platinasystems/go/internal/nl"
+	"github.com/platinasystems/go/internal/nl/rtnl"
+)
+
+func (opt *Options) ShowNexthop(b []byte) {
+	var nha rtnl.Nha
+	nha.Write(b)
+	msg := rtnl.NhMsgPtr(b)
+	detailed := opt.Flags.ByName["-d"]
+	opt.Print("id ", nl.Uint32(nha[rtnl.NHA_ID]))
+	if val := nha[rtnl.NHA_GROUP]; len(val) > 0 {
+		sep := " group "
+		for _, grp := range rtnl.NexthopGrps(val) {
+			opt.Print(sep, grp.Id)
+			weight := uint16(grp.WeightHigh)<<8 | uint16(grp.Weight)
+			if weight > 0 {
+				opt.Print(",", weight+1)
+			}
+			sep = "/"
+		}
+	}
+	if val := nha[rtnl.NHA_GROUP_TYPE]; len(val) > 0 &&
+		nl.Uint16(val) == rtnl.NEXTHOP_GRP_TYPE_RES {
+		opt.Print(" type resilient")
+	}
+	if val := nha[rtnl.NHA_RES_GROUP]; len(val) > 0 {
+		var res [rtnl.N_NHA_RES_GROUP][]byte
+		nl.IndexAttrByType(res[:], val)
+		opt.Print(" buckets ", nl.Uint16(res[rtnl.NHA_RES_GROUP_BUCKETS]))
+		for _, x := range []struct {
+			name string
+			t    uint16
+		}{
+			{"idle_timer", rtnl.NHA_RES_GROUP_IDLE_TIMER},
+			{"unbalanced_timer", rtnl.NHA_RES_GROUP_UNBALANCED_TIMER},
+		} {
+			opt.Print(" ", x.name, " ",
+				nl.Uint32(res[x.t])/rtnl.USER_HZ)
+		}
+		opt.Print(" unbalanced_time ",
+			nl.Uint64(res[rtnl.NHA_RES_GROUP_UNBALANCED_TIME])/
+				rtnl.USER_HZ)
+	}
+	if val := nha[rtnl.NHA_GATEWAY]; len(val) > 0 {
+		opt.Print(" via ", net.IP(val))
+	}
+	if val := nha[rtnl.NHA_OIF]; len(val) > 0 {
+		oif := nl.Int32(val)
+		if name, found := rtnl.If.NameByIndex[oif]; found {
+			opt.Print(" dev ", name)
+		} else {
+			opt.Print(" dev ", oif)
+		}
+	}
+	if msg.Scope != rtnl.RT_SCOPE_UNIVERSE || detailed {
+		opt.Print(" scope ", rtnl.RtScopeName[msg.Scope])
+	}
+	if val := nha[rtnl.NHA_BLACKHOLE]; val != nil {
+		opt.Print(" blackhole")
+	}
+	if msg.Protocol != rtnl.RTPROT_UNSPEC || detailed {
+		if name, found := rtnl.RtProtName[msg.Protocol]; found {
+			opt.Print(" proto ", name)
+		} else {
+			opt.Print(" proto ", msg.Protocol)
+		}
+	}
+	for _, x := range []struct {
+		name string
+		flag uint8
+	}{
+		{"dead", rtnl.RTNH_F_DEAD},
+		{"pervasive", rtnl.RTNH_F_PERVASIVE},
+		{"onlink", rtnl.RTNH_F_ONLINK},
+		{"offload", rtnl.RTNH_F_OFFLOAD},
+		{"linkdown", rtnl.RTNH_F_LINKDOWN},
+		{"unresolved", rtnl.RTNH_F_UNRESOLVED},
+	} {
+		if msg.Flags&uint32(x.flag) != 0 {
+			opt.Print(" ", x.name)
+		}
+	}
+	if val := nha[rtnl.NHA_FDB]; val != nil {
+		opt.Print(" fdb")
+	}
+}
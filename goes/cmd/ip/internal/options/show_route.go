@@ -41,6 +41,9 @@ func (opt *Options) ShowRoute(b []byte) {
 	if val := rta[rtnl.RTA_NEWDST]; len(val) > 0 {
 		opt.Print(" as to ", net.IP(val))
 	}
+	if val := rta[rtnl.RTA_NH_ID]; len(val) > 0 {
+		opt.Print(" nhid ", nl.Uint32(val))
+	}
 	if val := rta[rtnl.RTA_ENCAP]; len(val) > 0 {
 		opt.Print(" FIXME encap ", val)
 	}
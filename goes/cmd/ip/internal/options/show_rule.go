@@ -5,9 +5,11 @@
 package options
 
 import (
+	"encoding/binary"
 	"fmt"
 	"net"
 
+	"github.com/platinasystems/go/goes/cmd/ip/internal/group"
 	"github.com/platinasystems/go/internal/nl"
 	"github.com/platinasystems/go/internal/nl/rtnl"
 )
@@ -66,9 +68,9 @@ func (opt *Options) ShowRule(b []byte) {
 		mark := nl.Uint32(vmark)
 		mask := nl.Uint32(vmask)
 		if len(vmask) > 0 && mask != ^uint32(0) {
-			fmt.Printf("fwmark %#x/%#x ", mark, mask)
+			opt.Print(fmt.Sprintf("fwmark %#x/%#x ", mark, mask))
 		} else {
-			fmt.Printf("fwmark %#x ", mark)
+			opt.Print(fmt.Sprintf("fwmark %#x ", mark))
 		}
 	}
 
@@ -92,22 +94,100 @@ func (opt *Options) ShowRule(b []byte) {
 		}
 	}
 
-	if val := fra[rtnl.FRA_UID_RANGE]; len(val) > 0 {
-		r := rtnl.FibRuleUidRangePtr(val)
+	if r := rtnl.FibRuleUidRangeAttrPtr(fra[rtnl.FRA_UID_RANGE]); r != nil {
 		opt.Print("uidrange ", r.Start, "-", r.End, " ")
 	}
 
-	// FIXME table
+	if val := fra[rtnl.FRA_IP_PROTO]; len(val) > 0 {
+		opt.Print("ipproto ", nl.Uint8(val), " ")
+	}
+
+	for _, x := range []struct {
+		t    uint16
+		name string
+	}{
+		{rtnl.FRA_SPORT_RANGE, "sport "},
+		{rtnl.FRA_DPORT_RANGE, "dport "},
+	} {
+		r := rtnl.FibRulePortRangeAttrPtr(fra[x.t])
+		if r == nil {
+		} else if r.Start == r.End {
+			opt.Print(x.name, r.Start, " ")
+		} else {
+			opt.Print(x.name, r.Start, "-", r.End, " ")
+		}
+	}
+
+	if val := fra[rtnl.FRA_TUN_ID]; len(val) >= 8 {
+		opt.Print("tun_id ", binary.BigEndian.Uint64(val), " ")
+	}
+
+	table := uint32(msg.Table)
+	if val := fra[rtnl.FRA_TABLE]; len(val) > 0 {
+		table = nl.Uint32(val)
+	}
+
+	if msg.Action == rtnl.FR_ACT_TO_TBL {
+		if len(fra[rtnl.FRA_L3MDEV]) == 0 {
+			opt.Print("lookup ", rtnl.RtTableName(table), " ")
+		}
+		if val := fra[rtnl.FRA_SUPPRESS_PREFIXLEN]; len(val) > 0 {
+			if pl := nl.Int32(val); pl >= 0 {
+				opt.Print("suppress_prefixlength ", pl, " ")
+			}
+		}
+		if val := fra[rtnl.FRA_SUPPRESS_IFGROUP]; len(val) > 0 {
+			if g := nl.Uint32(val); g != ^uint32(0) {
+				opt.Print("suppress_ifgroup ", group.Name(g),
+					" ")
+			}
+		}
+	}
 
 	if val := fra[rtnl.FRA_FLOW]; len(val) > 0 {
 		to := nl.Uint32(val)
 		from := to >> 16
 		to &= 0xFFFF
+		opt.Print("realms ")
 		if from != 0 {
-			opt.Print("realms ", from, "/")
+			opt.Print(from, "/")
 		}
 		opt.Print(to, " ")
 	}
 
-	// FIXME RTN_NAT
+	switch msg.Action {
+	case rtnl.FR_ACT_TO_TBL:
+	case rtnl.FR_ACT_GOTO:
+		opt.Print("goto ")
+		if val := fra[rtnl.FRA_GOTO]; len(val) > 0 {
+			opt.Print(nl.Uint32(val), " ")
+		} else {
+			opt.Print("none ")
+		}
+		if (msg.Flags & rtnl.FIB_RULE_UNRESOLVED) != 0 {
+			opt.Print("[unresolved] ")
+		}
+	case rtnl.FR_ACT_NOP:
+		opt.Print("nop ")
+	case rtnl.FR_ACT_BLACKHOLE:
+		opt.Print("blackhole ")
+	case rtnl.FR_ACT_UNREACHABLE:
+		opt.Print("unreachable ")
+	case rtnl.FR_ACT_PROHIBIT:
+		opt.Print("prohibit ")
+	default:
+		opt.Print("action ", msg.Action, " ")
+	}
+
+	if val := fra[rtnl.FRA_PROTOCOL]; len(val) > 0 {
+		proto := nl.Uint8(val)
+		if (proto != rtnl.RTPROT_UNSPEC &&
+			proto != rtnl.RTPROT_KERNEL) || opt.Flags.ByName["-d"] {
+			if name, found := rtnl.RtProtName[proto]; found {
+				opt.Print("proto ", name, " ")
+			} else {
+				opt.Print("proto ", proto, " ")
+			}
+		}
+	}
 }
@@ -17,7 +17,9 @@ import (
 	"github.com/platinasystems/go/goes/cmd/ip/n"
 	"github.com/platinasystems/go/goes/cmd/ip/neighbor"
 	"github.com/platinasystems/go/goes/cmd/ip/netns"
+	"github.com/platinasystems/go/goes/cmd/ip/nexthop"
 	"github.com/platinasystems/go/goes/cmd/ip/route"
+	"github.com/platinasystems/go/goes/cmd/ip/rule"
 	"github.com/platinasystems/go/goes/lang"
 )
 
@@ -29,7 +31,8 @@ var Goes = &goes.Goes{
 	
 NETNS := { -a[ll] | -n[etns] NAME }
 
-OBJECT := { address | fou | link | monitor | neighbor | netns | route }
+OBJECT := { address | fou | link | monitor | neighbor | netns | nexthop |
+	route | rule }
 
 FAMILY := { -f[amily] { inet | inet6 | mpls | bridge | link } |
 	{ -4 | -6 | -B | -0 } }
@@ -58,6 +61,8 @@ OPTION := { -s[tat[isti]cs] | -d[etails] | -r[esolve] |
 		"netns":    netns.Goes,
 		"monitor":  monitor.Command{},
 		"neighbor": neighbor.Goes,
+		"nexthop":  nexthop.Goes,
 		"route":    route.Goes,
+		"rule":     rule.Goes,
 	},
 }
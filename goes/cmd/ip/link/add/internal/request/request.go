@@ -14,6 +14,16 @@ import (
 )
 
 func New(opt *options.Options, args []string) (*Add, error) {
+	return newAdd(opt, args, true)
+}
+
+// NewPeer is like New but, w/o a NAME, leaves the kernel to name the link,
+// e.g. the peer of a veth pair.
+func NewPeer(opt *options.Options, args []string) (*Add, error) {
+	return newAdd(opt, args, false)
+}
+
+func newAdd(opt *options.Options, args []string, needName bool) (*Add, error) {
 	err := opt.OnlyName(args)
 	if err != nil {
 		return nil, err
@@ -22,7 +32,7 @@ func New(opt *options.Options, args []string) (*Add, error) {
 	if len(args) == 1 {
 		ifname = args[0]
 	}
-	if len(ifname) == 0 {
+	if len(ifname) == 0 && needName {
 		return nil, fmt.Errorf("missing IFNAME")
 	}
 	if len(args) > 1 {
@@ -33,8 +43,10 @@ func New(opt *options.Options, args []string) (*Add, error) {
 	add.Hdr.Flags = nl.NLM_F_REQUEST | nl.NLM_F_ACK | nl.NLM_F_CREATE |
 		nl.NLM_F_EXCL
 	add.Msg.Family = rtnl.AF_UNSPEC
-	add.Attrs = append(add.Attrs, nl.Attr{rtnl.IFLA_IFNAME,
-		nl.KstringAttr(ifname)})
+	if len(ifname) > 0 {
+		add.Attrs = append(add.Attrs, nl.Attr{rtnl.IFLA_IFNAME,
+			nl.KstringAttr(ifname)})
+	}
 	for _, x := range []struct {
 		name string
 		t    uint16
@@ -35,7 +35,9 @@ func (Command) Man() lang.Alt {
 BASIC TYPES
 	dummy - Dummy network interface
 	ifb - Intermediate Functional Block device
+	team - Team device
 	vcan - Virtual Controller Area Network interface
+	wireguard - WireGuard tunnel
 
 SEE ALSO
 	ip link add type man TYPE || ip link add type TYPE -man
@@ -0,0 +1,277 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package bond
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/platinasystems/go/goes/cmd/ip/link/add/internal/options"
+	"github.com/platinasystems/go/goes/cmd/ip/link/add/internal/request"
+	"github.com/platinasystems/go/goes/lang"
+	"github.com/platinasystems/go/internal/nl"
+	"github.com/platinasystems/go/internal/nl/rtnl"
+)
+
+type Command struct{}
+
+func (Command) String() string { return "bond" }
+
+func (Command) Usage() string {
+	return `
+ip link add type bond [[ name ] NAME ] [ OPTION ]...
+	[ mode MODE ] [ miimon MSEC ] [ updelay MSEC ] [ downdelay MSEC ]
+	[ peer_notify_delay MSEC ] [ use_carrier { 0 | 1 } ]
+	[ arp_interval MSEC ] [ arp_ip_target ADDRESS[,ADDRESS]... ]
+	[ arp_validate VALIDATE ] [ arp_all_targets { any | all } ]
+	[ primary IFNAME ] [ primary_reselect RESELECT ]
+	[ fail_over_mac { none | active | follow } ]
+	[ xmit_hash_policy POLICY ] [ resend_igmp COUNT ]
+	[ num_grat_arp COUNT ] [ all_slaves_active { 0 | 1 } ]
+	[ min_links COUNT ] [ lp_interval SEC ] [ packets_per_slave COUNT ]
+	[ lacp_rate { slow | fast } ] [ ad_select { stable | bandwidth | count } ]
+	[ ad_actor_sys_prio PRIO ] [ ad_user_port_key KEY ]
+	[ ad_actor_system LLADDR ] [ tlb_dynamic_lb { 0 | 1 } ]`
+}
+
+func (Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "add a bonding device",
+	}
+}
+
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+MODES
+	balance-rr, active-backup, balance-xor, broadcast, 802.3ad,
+	balance-tlb, balance-alb
+
+POLICY
+	layer2, layer2+3, layer3+4, encap2+3, encap3+4, vlan+srcmac
+
+VALIDATE
+	none, active, backup, all, filter, filter_active, filter_backup
+
+RESELECT
+	always, better, failure
+
+DESCRIPTION
+	Slaves are added to the bond with,
+
+	ip link set IFNAME master BOND
+
+	and their per-slave options changed with,
+
+	ip link set IFNAME type bond_slave [ queue_id ID ] [ prio PRIO ]
+
+SEE ALSO
+	ip link add type man TYPE || ip link add type TYPE -man
+	ip link man add || ip link add -man
+	man ip || ip -man`,
+	}
+}
+
+func (Command) Main(args ...string) error {
+	var info nl.Attrs
+
+	opt, args := options.New(args)
+	args = opt.Parms.More(args,
+		"mode",
+		"miimon",
+		"updelay",
+		"downdelay",
+		"peer_notify_delay",
+		"use_carrier",
+		"arp_interval",
+		"arp_ip_target",
+		"arp_validate",
+		"arp_all_targets",
+		"primary",
+		"primary_reselect",
+		"fail_over_mac",
+		"xmit_hash_policy",
+		"resend_igmp",
+		[]string{"num_grat_arp", "num_unsol_na"},
+		"all_slaves_active",
+		"min_links",
+		"lp_interval",
+		"packets_per_slave",
+		"lacp_rate",
+		"ad_select",
+		"ad_actor_sys_prio",
+		"ad_user_port_key",
+		"ad_actor_system",
+		"tlb_dynamic_lb",
+	)
+
+	sock, err := nl.NewSock()
+	if err != nil {
+		return err
+	}
+	defer sock.Close()
+
+	sr := nl.NewSockReceiver(sock)
+
+	if err = rtnl.MakeIfMaps(sr); err != nil {
+		return err
+	}
+
+	add, err := request.New(opt, args)
+	if err != nil {
+		return err
+	}
+
+	for _, x := range []struct {
+		name   string
+		t      uint16
+		byname map[string]uint8
+	}{
+		{"mode", rtnl.IFLA_BOND_MODE, rtnl.BondModeByName},
+		{"primary_reselect", rtnl.IFLA_BOND_PRIMARY_RESELECT,
+			rtnl.BondPrimaryReselectByName},
+		{"fail_over_mac", rtnl.IFLA_BOND_FAIL_OVER_MAC,
+			rtnl.BondFailOverMacByName},
+		{"xmit_hash_policy", rtnl.IFLA_BOND_XMIT_HASH_POLICY,
+			rtnl.BondXmitHashPolicyByName},
+		{"lacp_rate", rtnl.IFLA_BOND_AD_LACP_RATE,
+			rtnl.BondLacpRateByName},
+		{"ad_select", rtnl.IFLA_BOND_AD_SELECT,
+			rtnl.BondAdSelectByName},
+	} {
+		s := opt.Parms.ByName[x.name]
+		if len(s) == 0 {
+			continue
+		}
+		u8, found := x.byname[s]
+		if !found {
+			if _, err := fmt.Sscan(s, &u8); err != nil {
+				return fmt.Errorf("%s: %q unknown", x.name, s)
+			}
+		}
+		info = append(info, nl.Attr{x.t, nl.Uint8Attr(u8)})
+	}
+	for _, x := range []struct {
+		name   string
+		t      uint16
+		byname map[string]uint32
+	}{
+		{"arp_validate", rtnl.IFLA_BOND_ARP_VALIDATE,
+			rtnl.BondArpValidateByName},
+		{"arp_all_targets", rtnl.IFLA_BOND_ARP_ALL_TARGETS,
+			rtnl.BondArpAllTargetsByName},
+	} {
+		s := opt.Parms.ByName[x.name]
+		if len(s) == 0 {
+			continue
+		}
+		u32, found := x.byname[s]
+		if !found {
+			return fmt.Errorf("%s: %q unknown", x.name, s)
+		}
+		info = append(info, nl.Attr{x.t, nl.Uint32Attr(u32)})
+	}
+	for _, x := range []struct {
+		name string
+		t    uint16
+	}{
+		{"use_carrier", rtnl.IFLA_BOND_USE_CARRIER},
+		{"num_grat_arp", rtnl.IFLA_BOND_NUM_PEER_NOTIF},
+		{"all_slaves_active", rtnl.IFLA_BOND_ALL_SLAVES_ACTIVE},
+		{"tlb_dynamic_lb", rtnl.IFLA_BOND_TLB_DYNAMIC_LB},
+	} {
+		var u8 uint8
+		s := opt.Parms.ByName[x.name]
+		if len(s) == 0 {
+			continue
+		}
+		if _, err := fmt.Sscan(s, &u8); err != nil {
+			return fmt.Errorf("%s: %q %v", x.name, s, err)
+		}
+		info = append(info, nl.Attr{x.t, nl.Uint8Attr(u8)})
+	}
+	for _, x := range []struct {
+		name string
+		t    uint16
+	}{
+		{"ad_actor_sys_prio", rtnl.IFLA_BOND_AD_ACTOR_SYS_PRIO},
+		{"ad_user_port_key", rtnl.IFLA_BOND_AD_USER_PORT_KEY},
+	} {
+		var u16 uint16
+		s := opt.Parms.ByName[x.name]
+		if len(s) == 0 {
+			continue
+		}
+		if _, err := fmt.Sscan(s, &u16); err != nil {
+			return fmt.Errorf("%s: %q %v", x.name, s, err)
+		}
+		info = append(info, nl.Attr{x.t, nl.Uint16Attr(u16)})
+	}
+	for _, x := range []struct {
+		name string
+		t    uint16
+	}{
+		{"miimon", rtnl.IFLA_BOND_MIIMON},
+		{"updelay", rtnl.IFLA_BOND_UPDELAY},
+		{"downdelay", rtnl.IFLA_BOND_DOWNDELAY},
+		{"peer_notify_delay", rtnl.IFLA_BOND_PEER_NOTIF_DELAY},
+		{"arp_interval", rtnl.IFLA_BOND_ARP_INTERVAL},
+		{"resend_igmp", rtnl.IFLA_BOND_RESEND_IGMP},
+		{"min_links", rtnl.IFLA_BOND_MIN_LINKS},
+		{"lp_interval", rtnl.IFLA_BOND_LP_INTERVAL},
+		{"packets_per_slave", rtnl.IFLA_BOND_PACKETS_PER_SLAVE},
+	} {
+		var u32 uint32
+		s := opt.Parms.ByName[x.name]
+		if len(s) == 0 {
+			continue
+		}
+		if _, err := fmt.Sscan(s, &u32); err != nil {
+			return fmt.Errorf("%s: %q %v", x.name, s, err)
+		}
+		info = append(info, nl.Attr{x.t, nl.Uint32Attr(u32)})
+	}
+	if s := opt.Parms.ByName["primary"]; len(s) > 0 {
+		ifindex, found := rtnl.If.IndexByName[s]
+		if !found {
+			return fmt.Errorf("primary: %q not found", s)
+		}
+		info = append(info, nl.Attr{rtnl.IFLA_BOND_PRIMARY,
+			nl.Uint32Attr(ifindex)})
+	}
+	if s := opt.Parms.ByName["arp_ip_target"]; len(s) > 0 {
+		var targets nl.Attrs
+		for i, sip := range strings.Split(s, ",") {
+			ip := net.ParseIP(sip).To4()
+			if ip == nil {
+				return fmt.Errorf("arp_ip_target: %q invalid",
+					sip)
+			}
+			targets = append(targets, nl.Attr{uint16(i),
+				nl.BytesAttr(ip)})
+		}
+		info = append(info, nl.Attr{rtnl.IFLA_BOND_ARP_IP_TARGET,
+			targets})
+	}
+	if s := opt.Parms.ByName["ad_actor_system"]; len(s) > 0 {
+		mac, err := net.ParseMAC(s)
+		if err != nil {
+			return fmt.Errorf("ad_actor_system: %q %v", s, err)
+		}
+		info = append(info, nl.Attr{rtnl.IFLA_BOND_AD_ACTOR_SYSTEM,
+			nl.BytesAttr(mac)})
+	}
+
+	add.Attrs = append(add.Attrs, nl.Attr{rtnl.IFLA_LINKINFO, nl.Attrs{
+		nl.Attr{rtnl.IFLA_INFO_KIND, nl.KstringAttr("bond")},
+		nl.Attr{rtnl.IFLA_INFO_DATA, info},
+	}})
+	req, err := add.Message()
+	if err == nil {
+		err = sr.UntilDone(req, nl.DoNothing)
+	}
+	return err
+}
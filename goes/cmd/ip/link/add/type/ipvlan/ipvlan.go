@@ -0,0 +1,122 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package ipvlan
+
+import (
+	"fmt"
+
+	"github.com/platinasystems/go/goes/cmd/ip/link/add/internal/options"
+	"github.com/platinasystems/go/goes/cmd/ip/link/add/internal/request"
+	"github.com/platinasystems/go/goes/lang"
+	"github.com/platinasystems/go/internal/nl"
+	"github.com/platinasystems/go/internal/nl/rtnl"
+)
+
+type Command string
+
+func (c Command) String() string { return string(c) }
+
+func (c Command) Usage() string {
+	return fmt.Sprint("ip link add type ", c,
+		` [[ name ] NAME ] link DEVICE [ OPTION ]...
+	[ mode { l2 | l3 | l3s } ] [ bridge | private | vepa ]`)
+}
+
+func (Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "add an ipvlan or ipvtap link",
+	}
+}
+
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+MODES
+	l2	the parent device handles link layer switching and
+		neighbor discovery; this is the default mode.
+
+	l3	the parent device routes packets to slaves by address;
+		there is no broadcast or multicast.
+
+	l3s	like l3 but also passes through netfilter of the slave's
+		namespace.
+
+FLAGS
+	bridge	slaves may communicate directly; this is the default.
+
+	private	don't allow communication between slaves.
+
+	vepa	send all slave traffic out the parent device.
+
+SEE ALSO
+	ip link add type man TYPE || ip link add type TYPE -man
+	ip link man add || ip link add -man
+	man ip || ip -man`,
+	}
+}
+
+func (c Command) Main(args ...string) error {
+	var info nl.Attrs
+
+	opt, args := options.New(args)
+	args = opt.Flags.More(args,
+		"bridge",
+		"private",
+		"vepa",
+	)
+	args = opt.Parms.More(args, "mode")
+
+	sock, err := nl.NewSock()
+	if err != nil {
+		return err
+	}
+	defer sock.Close()
+
+	sr := nl.NewSockReceiver(sock)
+
+	if err = rtnl.MakeIfMaps(sr); err != nil {
+		return err
+	}
+
+	add, err := request.New(opt, args)
+	if err != nil {
+		return err
+	}
+
+	if len(opt.Parms.ByName["link"]) == 0 {
+		return fmt.Errorf("missing link")
+	}
+
+	if s := opt.Parms.ByName["mode"]; len(s) > 0 {
+		mode, found := rtnl.IpvlanModeByName[s]
+		if !found {
+			return fmt.Errorf("mode: %q unknown", s)
+		}
+		info = append(info, nl.Attr{rtnl.IFLA_IPVLAN_MODE,
+			nl.Uint16Attr(mode)})
+	}
+
+	switch {
+	case opt.Flags.ByName["private"]:
+		info = append(info, nl.Attr{rtnl.IFLA_IPVLAN_FLAGS,
+			nl.Uint16Attr(rtnl.IPVLAN_F_PRIVATE)})
+	case opt.Flags.ByName["vepa"]:
+		info = append(info, nl.Attr{rtnl.IFLA_IPVLAN_FLAGS,
+			nl.Uint16Attr(rtnl.IPVLAN_F_VEPA)})
+	case opt.Flags.ByName["bridge"]:
+		info = append(info, nl.Attr{rtnl.IFLA_IPVLAN_FLAGS,
+			nl.Uint16Attr(0)})
+	}
+
+	add.Attrs = append(add.Attrs, nl.Attr{rtnl.IFLA_LINKINFO, nl.Attrs{
+		nl.Attr{rtnl.IFLA_INFO_KIND, nl.KstringAttr(c)},
+		nl.Attr{rtnl.IFLA_INFO_DATA, info},
+	}})
+	req, err := add.Message()
+	if err == nil {
+		err = sr.UntilDone(req, nl.DoNothing)
+	}
+	return err
+}
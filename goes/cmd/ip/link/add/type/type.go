@@ -8,6 +8,7 @@ import (
 	"github.com/platinasystems/go/goes"
 	"github.com/platinasystems/go/goes/cmd"
 	"github.com/platinasystems/go/goes/cmd/ip/link/add/type/basic"
+	"github.com/platinasystems/go/goes/cmd/ip/link/add/type/bond"
 	"github.com/platinasystems/go/goes/cmd/ip/link/add/type/bridge"
 	"github.com/platinasystems/go/goes/cmd/ip/link/add/type/geneve"
 	"github.com/platinasystems/go/goes/cmd/ip/link/add/type/gre"
@@ -15,10 +16,13 @@ import (
 	"github.com/platinasystems/go/goes/cmd/ip/link/add/type/ip6gre"
 	"github.com/platinasystems/go/goes/cmd/ip/link/add/type/ipip"
 	"github.com/platinasystems/go/goes/cmd/ip/link/add/type/ipoib"
+	"github.com/platinasystems/go/goes/cmd/ip/link/add/type/ipvlan"
 	"github.com/platinasystems/go/goes/cmd/ip/link/add/type/macsec"
 	"github.com/platinasystems/go/goes/cmd/ip/link/add/type/macvlan"
+	"github.com/platinasystems/go/goes/cmd/ip/link/add/type/veth"
 	"github.com/platinasystems/go/goes/cmd/ip/link/add/type/vlan"
 	"github.com/platinasystems/go/goes/cmd/ip/link/add/type/vrf"
+	"github.com/platinasystems/go/goes/cmd/ip/link/add/type/vti"
 	"github.com/platinasystems/go/goes/cmd/ip/link/add/type/vxlan"
 	"github.com/platinasystems/go/goes/cmd/ip/link/add/type/xeth"
 	"github.com/platinasystems/go/goes/lang"
@@ -46,15 +50,21 @@ TYPES
 	ip6tnl - Virtual tunnel interface IPv4|IPv6 over IPv6
 	ipip - Virtual tunnel interface IPv4 over IPv4
 	ipoib - IP over Infiniband device
+	ipvlan - Virtual interface based on network layer address (IP)
+	ipvtap - Virtual interface based on network layer address (IP) and TAP
 	macsec - 802.1AE MAC-level encryption
 	macvlan - Virtual interface base on link layer address (MAC)
 	macvtap - Virtual interface based on link layer address (MAC) and TAP
 	sit - Virtual tunnel interface IPv6 over IPv4
+	team - Team device
 	vcan - Virtual Controller Area Network interface
 	veth - Virtual point-to-point ethernet network interfaces
 	vlan - 802.1q tagged virtual LAN interface
 	vrf - Virtual Routing and Forwarding device
+	vti - Virtual tunnel interface IPv4 over IPsec
+	vti6 - Virtual tunnel interface IPv6 over IPsec
 	vxlan - Virtual eXtended LAN
+	wireguard - WireGuard tunnel
 	xeth - ethernet multiplexor
 
 SEE ALSO
@@ -63,6 +73,7 @@ SEE ALSO
 	man ip || ip -man`,
 	},
 	ByName: map[string]cmd.Cmd{
+		"bond":      bond.Command{},
 		"bridge":    bridge.Command{},
 		"dummy":     basic.Command("dummy"),
 		"geneve":    geneve.Command{},
@@ -74,13 +85,20 @@ SEE ALSO
 		"ip6gretap": ip6gre.Command("ip6gretap"),
 		"ipip":      ipip.Command{},
 		"ipoib":     ipoib.Command{},
+		"ipvlan":    ipvlan.Command("ipvlan"),
+		"ipvtap":    ipvlan.Command("ipvtap"),
 		"macsec":    macsec.Command{},
 		"macvlan":   macvlan.Command("macvlan"),
 		"macvtap":   macvlan.Command("macvtap"),
+		"team":      basic.Command("team"),
 		"vcan":      basic.Command("vcan"),
+		"veth":      veth.Command{},
 		"vlan":      vlan.Command{},
 		"vrf":       vrf.Command{},
+		"vti":       vti.Command("vti"),
+		"vti6":      vti.Command("vti6"),
 		"vxlan":     vxlan.Command{},
+		"wireguard": basic.Command("wireguard"),
 		"xeth":      xeth.Command{},
 	},
 }
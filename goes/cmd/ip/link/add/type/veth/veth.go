@@ -0,0 +1,128 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package veth
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/platinasystems/go/goes/cmd/ip/link/add/internal/options"
+	"github.com/platinasystems/go/goes/cmd/ip/link/add/internal/request"
+	"github.com/platinasystems/go/goes/lang"
+	"github.com/platinasystems/go/internal/nl"
+	"github.com/platinasystems/go/internal/nl/rtnl"
+)
+
+type Command struct{}
+
+func (Command) String() string { return "veth" }
+
+func (Command) Usage() string {
+	return `
+ip link add type veth [[ name ] NAME ] [ OPTION ]...
+	peer [[ name ] NAME ] [ netns { NAME | PID } ] [ OPTION ]...`
+}
+
+func (Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "add a virtual ethernet pair",
+	}
+}
+
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Veth devices are created in pairs; packets transmitted on one are
+	immediately received on the other.  The OPTIONs after "peer" apply
+	to the other end of the pair which may be placed in another network
+	namespace.
+
+OPTIONS
+	peer [ name ] NAME
+		name of the other end of the pair; without, the kernel
+		names the peer, e.g. "veth0"
+
+	netns { NAME | PID }
+		move the peer to the named or process network namespace
+
+SEE ALSO
+	ip link add type man TYPE || ip link add type TYPE -man
+	ip link man add || ip link add -man
+	man ip || ip -man`,
+	}
+}
+
+func (Command) Main(args ...string) error {
+	var pargs []string
+	var hasPeer bool
+	for i, arg := range args {
+		if arg == "peer" {
+			args, pargs = args[:i], args[i+1:]
+			hasPeer = true
+			break
+		}
+	}
+
+	opt, args := options.New(args)
+	popt, pargs := options.New(pargs)
+	pargs = popt.Parms.More(pargs, "netns")
+
+	sock, err := nl.NewSock()
+	if err != nil {
+		return err
+	}
+	defer sock.Close()
+
+	sr := nl.NewSockReceiver(sock)
+
+	if err = rtnl.MakeIfMaps(sr); err != nil {
+		return err
+	}
+
+	add, err := request.New(opt, args)
+	if err != nil {
+		return err
+	}
+
+	var info nl.Attrs
+
+	// Any peer option, even w/o a name, requires the peer info.
+	if hasPeer {
+		peer, err := request.NewPeer(popt, pargs)
+		if err != nil {
+			return fmt.Errorf("peer: %v", err)
+		}
+		if s := popt.Parms.ByName["netns"]; len(s) > 0 {
+			var id int32
+			var t uint16
+			f, err := os.Open(filepath.Join("/var/run/netns", s))
+			if err == nil {
+				defer f.Close()
+				t = rtnl.IFLA_NET_NS_FD
+				id = int32(f.Fd())
+			} else if _, err := fmt.Sscan(s, &id); err != nil {
+				return fmt.Errorf("netns: %q %v", s, err)
+			} else {
+				t = rtnl.IFLA_NET_NS_PID
+			}
+			peer.Attrs = append(peer.Attrs,
+				nl.Attr{t, nl.Int32Attr(id)})
+		}
+		info = append(info, nl.Attr{rtnl.VETH_INFO_PEER,
+			rtnl.VethPeer{peer.Msg, peer.Attrs}})
+	}
+
+	add.Attrs = append(add.Attrs, nl.Attr{rtnl.IFLA_LINKINFO, nl.Attrs{
+		nl.Attr{rtnl.IFLA_INFO_KIND, nl.KstringAttr("veth")},
+		nl.Attr{rtnl.IFLA_INFO_DATA, info},
+	}})
+	req, err := add.Message()
+	if err == nil {
+		err = sr.UntilDone(req, nl.DoNothing)
+	}
+	return err
+}
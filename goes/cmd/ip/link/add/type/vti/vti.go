@@ -0,0 +1,179 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package vti
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/platinasystems/go/goes/cmd/ip/link/add/internal/options"
+	"github.com/platinasystems/go/goes/cmd/ip/link/add/internal/request"
+	"github.com/platinasystems/go/goes/lang"
+	"github.com/platinasystems/go/internal/nl"
+	"github.com/platinasystems/go/internal/nl/rtnl"
+)
+
+type Command string
+
+func (c Command) String() string { return string(c) }
+
+func (c Command) Usage() string {
+	return fmt.Sprint("ip link add type ", c,
+		` [[ name ] NAME ] [ OPTION ]...
+	[ remote ADDR ] [ local ADDR ] [ dev PHYS_DEV ]
+	[ [i|o]key KEY ] [ fwmark MARK ]`)
+}
+
+func (Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "add a virtual IPsec tunnel interface",
+	}
+}
+
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+VTI TYPES
+	vti - Virtual tunnel interface IPv4 over IPsec
+	vti6 - Virtual tunnel interface IPv6 over IPsec
+
+OPTIONS
+	remote ADDR
+		the remote endpoint of the tunnel
+
+	local ADDR
+		the fixed local address for tunneled packets
+
+	dev PHYS_DEV
+		the physical device to use for tunnel endpoint communication
+
+	[i|o]key KEY
+		the input and/or output key that marks packets matching the
+		tunnel's IPsec policy; KEY is either a number or an IPv4
+		address-like dotted quad
+
+	fwmark MARK
+		the fwmark to use for routing tunneled packets
+
+SEE ALSO
+	ip link add type man TYPE || ip link add type TYPE -man
+	ip link man add || ip link add -man
+	man ip || ip -man`,
+	}
+}
+
+func (c Command) Main(args ...string) error {
+	var info nl.Attrs
+
+	opt, args := options.New(args)
+	args = opt.Parms.More(args,
+		"remote",
+		"local",
+		"dev",
+		"key",
+		"ikey",
+		"okey",
+		"fwmark",
+	)
+
+	sock, err := nl.NewSock()
+	if err != nil {
+		return err
+	}
+	defer sock.Close()
+
+	sr := nl.NewSockReceiver(sock)
+
+	if err = rtnl.MakeIfMaps(sr); err != nil {
+		return err
+	}
+
+	add, err := request.New(opt, args)
+	if err != nil {
+		return err
+	}
+
+	for _, x := range []struct {
+		name string
+		t    uint16
+	}{
+		{"local", rtnl.IFLA_VTI_LOCAL},
+		{"remote", rtnl.IFLA_VTI_REMOTE},
+	} {
+		s := opt.Parms.ByName[x.name]
+		if len(s) == 0 {
+			continue
+		}
+		ip := net.ParseIP(s)
+		if c == "vti" {
+			ip = ip.To4()
+		} else if ip.To4() != nil {
+			ip = nil
+		}
+		if ip == nil {
+			return fmt.Errorf("%s: %q invalid", x.name, s)
+		}
+		info = append(info, nl.Attr{x.t, nl.BytesAttr(ip)})
+	}
+	if s := opt.Parms.ByName["dev"]; len(s) > 0 {
+		dev, found := rtnl.If.IndexByName[s]
+		if !found {
+			return fmt.Errorf("dev: %q not found", s)
+		}
+		info = append(info, nl.Attr{rtnl.IFLA_VTI_LINK,
+			nl.Uint32Attr(dev)})
+	}
+	for _, x := range []struct {
+		names []string
+		t     uint16
+	}{
+		{[]string{"ikey", "key"}, rtnl.IFLA_VTI_IKEY},
+		{[]string{"okey", "key"}, rtnl.IFLA_VTI_OKEY},
+	} {
+		for _, name := range x.names {
+			s := opt.Parms.ByName[name]
+			if len(s) == 0 {
+				continue
+			}
+			key, err := parseKey(s)
+			if err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+			info = append(info, nl.Attr{x.t, nl.Be32Attr(key)})
+			break
+		}
+	}
+	if s := opt.Parms.ByName["fwmark"]; len(s) > 0 {
+		var u32 uint32
+		if _, err := fmt.Sscan(s, &u32); err != nil {
+			return fmt.Errorf("fwmark: %q %v", s, err)
+		}
+		info = append(info, nl.Attr{rtnl.IFLA_VTI_FWMARK,
+			nl.Uint32Attr(u32)})
+	}
+
+	add.Attrs = append(add.Attrs, nl.Attr{rtnl.IFLA_LINKINFO, nl.Attrs{
+		nl.Attr{rtnl.IFLA_INFO_KIND, nl.KstringAttr(c)},
+		nl.Attr{rtnl.IFLA_INFO_DATA, info},
+	}})
+	req, err := add.Message()
+	if err == nil {
+		err = sr.UntilDone(req, nl.DoNothing)
+	}
+	return err
+}
+
+// KEY is either a NUMBER or dotted quad
+func parseKey(s string) (uint32, error) {
+	var key uint32
+	if ip4 := net.ParseIP(s).To4(); ip4 != nil {
+		return binary.BigEndian.Uint32(ip4), nil
+	}
+	if _, err := fmt.Sscan(s, &key); err != nil {
+		return 0, fmt.Errorf("%q %v", s, err)
+	}
+	return key, nil
+}
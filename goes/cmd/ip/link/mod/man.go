@@ -146,6 +146,15 @@ XDP-ARGS
 
 	pinned FILE
 
+TYPE-ARGS
+	ip link set DEVICE type bond_slave [ queue_id ID ] [ prio PRIO ]
+
+	queue_id ID
+		transmit queue of the bond that this slave is mapped to
+
+	prio PRIO
+		priority of this slave in active-backup re-selection
+
 SEE ALSO
 	ip man link || ip link -man
 	man ip || ip -man
@@ -148,6 +148,17 @@ func (m *mod) parse() error {
 	var dev, link int32
 	var gid uint32
 
+	for i, arg := range m.args {
+		if arg == "type" {
+			targs := m.args[i+1:]
+			m.args = m.args[:i]
+			if err = m.parseType(targs); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
 	m.args = m.opt.Flags.More(m.args,
 		[]string{"up", "+up"},
 		[]string{"down", "no-up", "-up"},
@@ -429,6 +440,48 @@ func (m *mod) parse() error {
 	return nil
 }
 
+// TYPE [ ARGS ]...
+func (m *mod) parseType(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("type: missing TYPE")
+	}
+	switch args[0] {
+	case "bond_slave":
+		return m.parseBondSlave(args[1:])
+	}
+	return fmt.Errorf("type: %q unknown", args[0])
+}
+
+// bond_slave [ queue_id ID ] [ prio PRIO ]
+func (m *mod) parseBondSlave(args []string) error {
+	opt, args := options.New(args)
+	args = opt.Parms.More(args, "queue_id", "prio")
+	if len(args) > 0 {
+		return fmt.Errorf("bond_slave: %v: unexpected", args)
+	}
+	if s := opt.Parms.ByName["queue_id"]; len(s) > 0 {
+		var u16 uint16
+		if _, err := fmt.Sscan(s, &u16); err != nil {
+			return fmt.Errorf("queue_id: %q %v", s, err)
+		}
+		m.tinfo = append(m.tinfo, nl.Attr{rtnl.IFLA_BOND_SLAVE_QUEUE_ID,
+			nl.Uint16Attr(u16)})
+	}
+	if s := opt.Parms.ByName["prio"]; len(s) > 0 {
+		var i32 int32
+		if _, err := fmt.Sscan(s, &i32); err != nil {
+			return fmt.Errorf("prio: %q %v", s, err)
+		}
+		m.tinfo = append(m.tinfo, nl.Attr{rtnl.IFLA_BOND_SLAVE_PRIO,
+			nl.Int32Attr(i32)})
+	}
+	m.attrs = append(m.attrs, nl.Attr{rtnl.IFLA_LINKINFO, nl.Attrs{
+		nl.Attr{rtnl.IFLA_INFO_SLAVE_KIND, nl.KstringAttr("bond")},
+		nl.Attr{rtnl.IFLA_INFO_SLAVE_DATA, m.tinfo},
+	}})
+	return nil
+}
+
 func (m *mod) parseAddrGenMode(s string) error {
 	mode, found := rtnl.In6AddrGenModeByName[s]
 	if !found {
@@ -473,6 +526,7 @@ func (Command) Complete(args ...string) (list []string) {
 	cpv["link-netnsid"] = options.NoComplete
 	cpv["vf"] = options.NoComplete
 	cpv["xdp"] = options.NoComplete
+	cpv["type"] = completeType
 	if method, found := cpv[llarg]; found {
 		list = method(larg)
 	} else {
@@ -495,6 +549,7 @@ func (Command) Complete(args ...string) (list []string) {
 			"link-netnsid",
 			"vf",
 			"xdp",
+			"type",
 		) {
 			if len(larg) == 0 || strings.HasPrefix(name, larg) {
 				list = append(list, name)
@@ -503,3 +558,12 @@ func (Command) Complete(args ...string) (list []string) {
 	}
 	return
 }
+
+func completeType(s string) (list []string) {
+	for _, t := range []string{"bond_slave"} {
+		if len(s) == 0 || strings.HasPrefix(t, s) {
+			list = append(list, t)
+		}
+	}
+	return
+}
@@ -31,7 +31,7 @@ func (Command) Usage() string {
 	ip monitor [ all | OBJECT... ] [label] [all-nsid] [-t | -ts]
 
 OBJECT := link | address | route | mroute | prefix | neigh | netconf | rule |
-	nsid`
+	nexthop | nsid`
 }
 
 func (Command) Apropos() lang.Alt {
@@ -91,6 +91,7 @@ func (Command) Main(args ...string) error {
 		"neigh",
 		"netconf",
 		"rule",
+		"nexthop",
 		"nsid",
 		"all-nsid",
 		"label",
@@ -150,6 +151,13 @@ func (Command) Main(args ...string) error {
 	}
 	defer sock.Close()
 
+	if nexthops(show.opt) {
+		// beyond the reach of the NewSock groups bitmask
+		if err = sock.Join(uint32(rtnl.RTNLGRP_NEXTHOP)); err != nil {
+			return err
+		}
+	}
+
 	sigch := make(chan os.Signal)
 	signal.Notify(sigch, os.Interrupt, os.Signal(syscall.SIGTERM))
 
@@ -201,6 +209,7 @@ func (Command) Complete(args ...string) (list []string) {
 			"neigh",
 			"netconf",
 			"rule",
+			"nexthop",
 			"nsid",
 		) {
 			if len(larg) == 0 || strings.HasPrefix(name, larg) {
@@ -284,7 +293,8 @@ func groups(opt *options.Options) uint32 {
 			groups |= rtnl.RTNLGRP_IPV6_RULE.Bit()
 		}
 	}
-	if groups != 0 && !opt.Flags.ByName["all"] {
+	if (groups != 0 || opt.Flags.ByName["nexthop"]) &&
+		!opt.Flags.ByName["all"] {
 		return groups
 	}
 	groups |= rtnl.RTNLGRP_LINK.Bit()
@@ -321,6 +331,28 @@ func groups(opt *options.Options) uint32 {
 	return groups
 }
 
+func nexthops(opt *options.Options) bool {
+	if opt.Flags.ByName["nexthop"] || opt.Flags.ByName["all"] {
+		return true
+	}
+	for _, name := range []string{
+		"link",
+		"address",
+		"route",
+		"mroute",
+		"prefix",
+		"neigh",
+		"netconf",
+		"rule",
+		"nsid",
+	} {
+		if opt.Flags.ByName[name] {
+			return false
+		}
+	}
+	return true
+}
+
 type save struct {
 	*os.File
 	tsbuf []byte
@@ -429,6 +461,12 @@ func (show *show) Handle(b []byte) {
 	case rtnl.RTM_NEWRULE:
 		heading("RULE")
 		show.opt.ShowRule(b)
+	case rtnl.RTM_DELNEXTHOP:
+		deleted = true
+		fallthrough
+	case rtnl.RTM_NEWNEXTHOP:
+		heading("NEXTHOP")
+		show.opt.ShowNexthop(b)
 	case rtnl.RTM_NEWNETCONF:
 		heading("NETCONF")
 		show.opt.ShowNetconf(b)
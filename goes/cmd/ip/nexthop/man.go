@@ -0,0 +1,88 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nexthop
+
+const Man = `
+DESCRIPTION
+	ip nexthop manipulates nexthop objects that routes may reference
+	with "ip route add PREFIX nhid ID" instead of listing their own
+	gateways.  Changing a nexthop object changes the forwarding of all
+	routes using it.
+
+	A nexthop is either a single gateway and device, a blackhole, or a
+	group of other nexthops.  A group of type mpath hashes flows over
+	its members in proportion to their weights; a group of type
+	resilient maintains a table of buckets so that changing members
+	only disturbs the flows of the buckets that move.
+
+COMMANDS
+	ip nexthop add - add a new nexthop object
+	ip nexthop replace - add or change a nexthop object
+	ip nexthop delete - delete the nexthop object with the given id
+	ip nexthop get - show the nexthop object with the given id
+
+	id ID	the nexthop object's unique identifier.
+
+	via ADDRESS
+		the nexthop gateway address.
+
+	dev IFNAME
+		the output device.
+
+	onlink
+		pretend that the gateway is directly attached to the device
+		even if it doesn't match any interface prefix.
+
+	blackhole
+		silently discard packets.
+
+	group GROUP
+		a group of nexthop ids, separated by "/", each optionally
+		followed by ",WEIGHT" where the default weight is 1.
+
+	type { mpath | resilient }
+		the group type; mpath is the default.
+
+	buckets BUCKETS
+		number of resilient group hash buckets; default, 128.
+
+	idle_timer SECONDS
+		time after which an idle bucket may be moved to another
+		member; default, 120.
+
+	unbalanced_timer SECONDS
+		time after which busy buckets of an unbalanced group are
+		forcibly moved; default, 0, never.
+
+	fdb	the nexthop or group is used by VXLAN FDB entries rather than
+		routes.
+
+	protocol RTPROTO
+		the routing protocol identifier of this nexthop.
+
+	ip nexthop flush - delete all nexthops matching SELECTOR
+
+	ip nexthop show - list nexthops matching SELECTOR
+		list is a synonym of show.
+
+	groups	only match groups.
+
+	master IFNAME
+		only match nexthops with devices enslaved to IFNAME.
+
+EXAMPLES
+	ip nexthop add id 1 via 10.0.0.2 dev eth0
+	ip nexthop add id 2 via 10.0.1.2 dev eth1
+	ip nexthop add id 10 group 1/2,3
+	ip route add 10.2.0.0/24 nhid 10
+		Adds a route hashed over two gateways, three of every four
+		flows through 10.0.1.2.
+
+	ip nexthop add id 11 group 1/2 type resilient buckets 64
+		Adds a resilient group of two nexthops.
+
+SEE ALSO
+	ip man route || ip route -man
+	man ip || ip -man`
@@ -0,0 +1,347 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package mod
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/platinasystems/go/goes/cmd/ip/internal/options"
+	"github.com/platinasystems/go/goes/lang"
+	"github.com/platinasystems/go/internal/nl"
+	"github.com/platinasystems/go/internal/nl/rtnl"
+)
+
+type Command string
+
+type mod struct {
+	opt  *options.Options
+	args []string
+
+	sr *nl.SockReceiver
+
+	hdr   nl.Hdr
+	msg   rtnl.NhMsg
+	attrs nl.Attrs
+
+	hasId    bool
+	hasGroup bool
+	res      nl.Attrs
+}
+
+func (c Command) String() string { return string(c) }
+
+func (c Command) Usage() string {
+	if c == "delete" {
+		return "ip nexthop delete id ID"
+	}
+	return fmt.Sprint("ip nexthop ", c, ` id ID NH [ protocol RTPROTO ]
+
+NH := { blackhole | [ via ADDRESS ] [ dev IFNAME ] [ onlink ] |
+	group GROUP [ fdb ] [ type TYPE [ RES-ARGS ] ] }
+
+GROUP := ID[,WEIGHT][/ID[,WEIGHT]]...
+
+TYPE := { mpath | resilient }
+
+RES-ARGS := [ buckets BUCKETS ] [ idle_timer SECONDS ]
+	[ unbalanced_timer SECONDS ]`)
+}
+
+func (Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "nexthop object",
+	}
+}
+
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+SEE ALSO
+	ip man nexthop || ip nexthop -man
+	man ip || ip -man`,
+	}
+}
+
+func (c Command) Main(args ...string) error {
+	var m mod
+
+	m.opt, m.args = options.New(args)
+
+	sock, err := nl.NewSock()
+	if err != nil {
+		return err
+	}
+	defer sock.Close()
+
+	m.sr = nl.NewSockReceiver(sock)
+
+	if err = rtnl.MakeIfMaps(m.sr); err != nil {
+		return err
+	}
+
+	m.hdr.Flags = nl.NLM_F_REQUEST | nl.NLM_F_ACK
+
+	switch c {
+	case "add":
+		m.hdr.Type = rtnl.RTM_NEWNEXTHOP
+		m.hdr.Flags |= nl.NLM_F_CREATE | nl.NLM_F_EXCL
+	case "replace":
+		m.hdr.Type = rtnl.RTM_NEWNEXTHOP
+		m.hdr.Flags |= nl.NLM_F_CREATE | nl.NLM_F_REPLACE
+	case "delete":
+		m.hdr.Type = rtnl.RTM_DELNEXTHOP
+	default:
+		return fmt.Errorf("%s: unknown", c)
+	}
+
+	if err = m.parse(); err != nil {
+		return err
+	}
+
+	if !m.hasId {
+		return fmt.Errorf("missing id")
+	}
+
+	if len(m.res) > 0 {
+		m.append(rtnl.NHA_RES_GROUP|nl.NLA_F_NESTED, m.res)
+	}
+
+	// groups are family agnostic, other nexthops default to inet
+	if m.hasGroup {
+		m.msg.Family = rtnl.AF_UNSPEC
+	} else if c != "delete" && m.msg.Family == rtnl.AF_UNSPEC {
+		m.msg.Family = rtnl.AF_INET
+	}
+
+	req, err := nl.NewMessage(m.hdr, m.msg, m.attrs...)
+	if err == nil {
+		err = m.sr.UntilDone(req, nl.DoNothing)
+	}
+	return err
+}
+
+func (Command) Complete(args ...string) (list []string) {
+	var larg, llarg string
+	n := len(args)
+	if n > 0 {
+		larg = args[n-1]
+	}
+	if n > 1 {
+		llarg = args[n-2]
+	}
+	cpv := options.CompleteParmValue
+	cpv["id"] = options.NoComplete
+	cpv["via"] = options.NoComplete
+	cpv["dev"] = options.CompleteIfName
+	cpv["group"] = options.NoComplete
+	cpv["type"] = func(s string) (list []string) {
+		for name := range rtnl.NexthopGrpTypeByName {
+			if len(s) == 0 || strings.HasPrefix(name, s) {
+				list = append(list, name)
+			}
+		}
+		return
+	}
+	cpv["buckets"] = options.NoComplete
+	cpv["idle_timer"] = options.NoComplete
+	cpv["unbalanced_timer"] = options.NoComplete
+	cpv["protocol"] = rtnl.CompleteRtProt
+	if method, found := cpv[llarg]; found {
+		list = method(larg)
+	} else {
+		for _, name := range append(options.CompleteOptNames,
+			"id",
+			"via",
+			"dev",
+			"onlink",
+			"blackhole",
+			"group",
+			"fdb",
+			"type",
+			"buckets",
+			"idle_timer",
+			"unbalanced_timer",
+			"protocol",
+		) {
+			if len(larg) == 0 || strings.HasPrefix(name, larg) {
+				list = append(list, name)
+			}
+		}
+	}
+	return
+}
+
+func (m *mod) append(t uint16, v io.Reader) {
+	m.attrs = append(m.attrs, nl.Attr{t, v})
+}
+
+func (m *mod) parse() error {
+	var err error
+	if s := m.opt.Parms.ByName["-f"]; len(s) > 0 {
+		if v, ok := rtnl.AfByName[s]; ok {
+			m.msg.Family = v
+		} else {
+			return fmt.Errorf("family: %q unknown", s)
+		}
+	}
+	for err == nil && len(m.args) > 0 {
+		arg0 := m.args[0]
+		m.args = m.args[1:]
+		switch arg0 {
+		case "id":
+			if u32, e := m.parseUint32(); e == nil {
+				m.hasId = true
+				m.append(rtnl.NHA_ID, nl.Uint32Attr(u32))
+			} else {
+				err = e
+			}
+		case "via":
+			err = m.parseVia()
+		case "dev":
+			if s, e := m.parseString(); e != nil {
+				err = e
+			} else if ifindex, found := rtnl.If.IndexByName[s]; !found {
+				err = fmt.Errorf("%q not found", s)
+			} else {
+				m.append(rtnl.NHA_OIF, nl.Uint32Attr(ifindex))
+			}
+		case "onlink":
+			m.msg.Flags |= uint32(rtnl.RTNH_F_ONLINK)
+		case "blackhole":
+			m.append(rtnl.NHA_BLACKHOLE, nl.NilAttr{})
+		case "group":
+			err = m.parseGroup()
+		case "fdb":
+			m.append(rtnl.NHA_FDB, nl.NilAttr{})
+		case "type":
+			if s, e := m.parseString(); e != nil {
+				err = e
+			} else if v, ok := rtnl.NexthopGrpTypeByName[s]; ok {
+				m.append(rtnl.NHA_GROUP_TYPE, nl.Uint16Attr(v))
+			} else {
+				err = fmt.Errorf("%q unknown", s)
+			}
+		case "buckets":
+			if u32, e := m.parseUint32(); e != nil {
+				err = e
+			} else if u32 > 0xffff {
+				err = fmt.Errorf("%d: out of range", u32)
+			} else {
+				m.res = append(m.res, nl.Attr{
+					rtnl.NHA_RES_GROUP_BUCKETS,
+					nl.Uint16Attr(u32),
+				})
+			}
+		case "idle_timer":
+			err = m.parseTimer(rtnl.NHA_RES_GROUP_IDLE_TIMER)
+		case "unbalanced_timer":
+			err = m.parseTimer(rtnl.NHA_RES_GROUP_UNBALANCED_TIMER)
+		case "protocol", "proto":
+			err = m.parseProtocol()
+		default:
+			err = fmt.Errorf("unknown")
+		}
+		if err != nil {
+			err = fmt.Errorf("%s: %v", arg0, err)
+		}
+	}
+	return err
+}
+
+func (m *mod) parseString() (string, error) {
+	var v string
+	if len(m.args) == 0 {
+		return v, fmt.Errorf("missing STRING")
+	}
+	v = m.args[0]
+	m.args = m.args[1:]
+	return v, nil
+}
+
+func (m *mod) parseUint32() (uint32, error) {
+	var v uint32
+	if len(m.args) == 0 {
+		return v, fmt.Errorf("missing NUMBER")
+	}
+	if _, err := fmt.Sscan(m.args[0], &v); err != nil {
+		return v, fmt.Errorf("%q %v", m.args[0], err)
+	}
+	m.args = m.args[1:]
+	return v, nil
+}
+
+func (m *mod) parseVia() error {
+	if len(m.args) == 0 {
+		return fmt.Errorf("missing ADDRESS")
+	}
+	addr, err := rtnl.Address(m.args[0], m.msg.Family)
+	if err != nil {
+		return err
+	}
+	m.args = m.args[1:]
+	if m.msg.Family == rtnl.AF_UNSPEC {
+		m.msg.Family = addr.Family()
+	}
+	m.append(rtnl.NHA_GATEWAY, addr)
+	return nil
+}
+
+// ID[,WEIGHT][/ID[,WEIGHT]]...
+func (m *mod) parseGroup() error {
+	var grps rtnl.NexthopGrpList
+	if len(m.args) == 0 {
+		return fmt.Errorf("missing GROUP")
+	}
+	arg0 := m.args[0]
+	m.args = m.args[1:]
+	for _, s := range strings.Split(arg0, "/") {
+		var id, weight uint32
+		weight = 1
+		s = strings.Replace(s, ",", " ", 1)
+		if n, _ := fmt.Sscan(s, &id, &weight); n == 0 {
+			return fmt.Errorf("%q invalid", arg0)
+		}
+		if weight < 1 || weight > 1<<16 {
+			return fmt.Errorf("%q weight out of range", arg0)
+		}
+		weight--
+		grps = append(grps, rtnl.NexthopGrp{
+			Id:         id,
+			Weight:     uint8(weight),
+			WeightHigh: uint8(weight >> 8),
+		})
+	}
+	m.hasGroup = true
+	m.append(rtnl.NHA_GROUP, grps)
+	return nil
+}
+
+// SECONDS in clock_t
+func (m *mod) parseTimer(t uint16) error {
+	u32, err := m.parseUint32()
+	if err != nil {
+		return err
+	}
+	if u32 > ^uint32(0)/rtnl.USER_HZ {
+		return fmt.Errorf("%d: out of range", u32)
+	}
+	m.res = append(m.res, nl.Attr{t, nl.Uint32Attr(u32 * rtnl.USER_HZ)})
+	return nil
+}
+
+func (m *mod) parseProtocol() error {
+	if len(m.args) == 0 {
+		return fmt.Errorf("missing RTPROTO")
+	}
+	if v, ok := rtnl.RtProtByName[m.args[0]]; ok {
+		m.msg.Protocol = v
+	} else if _, err := fmt.Sscan(m.args[0], &m.msg.Protocol); err != nil {
+		return fmt.Errorf("%q %v", m.args[0], err)
+	}
+	m.args = m.args[1:]
+	return nil
+}
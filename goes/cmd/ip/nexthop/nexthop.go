@@ -0,0 +1,51 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package nexthop
+
+import (
+	"github.com/platinasystems/go/goes"
+	"github.com/platinasystems/go/goes/cmd"
+	"github.com/platinasystems/go/goes/cmd/ip/nexthop/mod"
+	"github.com/platinasystems/go/goes/cmd/ip/nexthop/show"
+	"github.com/platinasystems/go/goes/lang"
+)
+
+var Goes = &goes.Goes{
+	NAME: "nexthop",
+	USAGE: `
+	ip nexthop [ list | show ] [ SELECTOR ]
+	ip nexthop flush [ SELECTOR ]
+	ip nexthop { add | replace } id ID NH [ protocol RTPROTO ]
+	ip nexthop { get | delete } id ID
+
+SELECTOR := [ id ID ] [ dev IFNAME ] [ master IFNAME ] [ groups ] [ fdb ]
+	[ protocol RTPROTO ]
+
+NH := { blackhole | [ via ADDRESS ] [ dev IFNAME ] [ onlink ] |
+	group GROUP [ fdb ] [ type TYPE [ RES-ARGS ] ] }
+
+GROUP := ID[,WEIGHT][/ID[,WEIGHT]]...
+
+TYPE := { mpath | resilient }
+
+RES-ARGS := [ buckets BUCKETS ] [ idle_timer SECONDS ]
+	[ unbalanced_timer SECONDS ]`,
+	APROPOS: lang.Alt{
+		lang.EnUS: "nexthop object management",
+	},
+	MAN: lang.Alt{
+		lang.EnUS: Man,
+	},
+	ByName: map[string]cmd.Cmd{
+		"add":     mod.Command("add"),
+		"replace": mod.Command("replace"),
+		"delete":  mod.Command("delete"),
+		"":        show.Command(""),
+		"show":    show.Command("show"),
+		"list":    show.Command("list"),
+		"flush":   show.Command("flush"),
+		"get":     show.Command("get"),
+	},
+}
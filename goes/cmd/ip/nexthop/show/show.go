@@ -0,0 +1,269 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// ip nexthop show (default) | list | flush | get
+package show
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+
+	"github.com/platinasystems/go/goes/cmd/ip/internal/options"
+	"github.com/platinasystems/go/goes/lang"
+	"github.com/platinasystems/go/internal/nl"
+	"github.com/platinasystems/go/internal/nl/rtnl"
+)
+
+type Command string
+
+type selector struct {
+	family   uint8
+	id       *uint32
+	protocol *uint8
+	attrs    nl.Attrs
+}
+
+func (Command) Aka() string { return "show" }
+
+func (c Command) String() string { return string(c) }
+
+func (c Command) Usage() string {
+	if c == "get" {
+		return "ip nexthop get id ID"
+	}
+	return `
+	ip nexthop [ list | show ] [ SELECTOR ]
+	ip nexthop flush [ SELECTOR ]
+
+SELECTOR := [ id ID ] [ dev IFNAME ] [ master IFNAME ] [ groups ] [ fdb ]
+	[ protocol RTPROTO ]`
+}
+
+func (c Command) Apropos() lang.Alt {
+	apropos := "nexthop object"
+	if c == "show" {
+		apropos += " (default)"
+	}
+	return lang.Alt{
+		lang.EnUS: apropos,
+	}
+}
+
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+SEE ALSO
+	ip man nexthop || ip nexthop -man
+	man ip || ip -man`,
+	}
+}
+
+func (c Command) Main(args ...string) error {
+	var sel selector
+	var flush []uint32
+
+	opt, args := options.New(args)
+	args = opt.Flags.More(args, "groups", "fdb")
+	args = opt.Parms.More(args,
+		"id",
+		"dev",
+		"master",
+		[]string{"protocol", "proto"},
+	)
+	if len(args) > 0 {
+		return fmt.Errorf("%v: unexpected", args)
+	}
+
+	sock, err := nl.NewSock()
+	if err != nil {
+		return err
+	}
+	defer sock.Close()
+
+	sr := nl.NewSockReceiver(sock)
+
+	if err = rtnl.MakeIfMaps(sr); err != nil {
+		return err
+	}
+
+	if err = sel.parse(opt); err != nil {
+		return err
+	}
+
+	if c == "get" {
+		if sel.id == nil {
+			return fmt.Errorf("missing id")
+		}
+		req, err := nl.NewMessage(
+			nl.Hdr{
+				Type:  rtnl.RTM_GETNEXTHOP,
+				Flags: nl.NLM_F_REQUEST | nl.NLM_F_ACK,
+			},
+			rtnl.NhMsg{},
+			nl.Attr{rtnl.NHA_ID, nl.Uint32Attr(*sel.id)},
+		)
+		if err != nil {
+			return err
+		}
+		return sr.UntilDone(req, func(b []byte) {
+			if nl.HdrPtr(b).Type == rtnl.RTM_NEWNEXTHOP {
+				opt.ShowNexthop(b)
+				fmt.Println()
+			}
+		})
+	}
+
+	req, err := nl.NewMessage(
+		nl.Hdr{
+			Type:  rtnl.RTM_GETNEXTHOP,
+			Flags: nl.NLM_F_REQUEST | nl.NLM_F_DUMP,
+		},
+		rtnl.NhMsg{},
+		sel.attrs...,
+	)
+	if err != nil {
+		return err
+	}
+	if err = sr.UntilDone(req, func(b []byte) {
+		if nl.HdrPtr(b).Type != rtnl.RTM_NEWNEXTHOP {
+			return
+		}
+		if !sel.match(b) {
+			return
+		}
+		if c == "flush" {
+			var nha rtnl.Nha
+			nha.Write(b)
+			flush = append(flush, nl.Uint32(nha[rtnl.NHA_ID]))
+			return
+		}
+		opt.ShowNexthop(b)
+		fmt.Println()
+	}); err != nil {
+		return err
+	}
+
+	for _, id := range flush {
+		req, err := nl.NewMessage(
+			nl.Hdr{
+				Type:  rtnl.RTM_DELNEXTHOP,
+				Flags: nl.NLM_F_REQUEST | nl.NLM_F_ACK,
+			},
+			rtnl.NhMsg{},
+			nl.Attr{rtnl.NHA_ID, nl.Uint32Attr(id)},
+		)
+		if err != nil {
+			return err
+		}
+		// a group may have gone with its last member
+		err = sr.UntilDone(req, nl.DoNothing)
+		if err != nil && err != syscall.ENOENT {
+			return err
+		}
+	}
+	return nil
+}
+
+func (Command) Complete(args ...string) (list []string) {
+	var larg, llarg string
+	n := len(args)
+	if n > 0 {
+		larg = args[n-1]
+	}
+	if n > 1 {
+		llarg = args[n-2]
+	}
+	cpv := options.CompleteParmValue
+	cpv["id"] = options.NoComplete
+	cpv["dev"] = options.CompleteIfName
+	cpv["master"] = options.CompleteIfName
+	cpv["protocol"] = rtnl.CompleteRtProt
+	if method, found := cpv[llarg]; found {
+		list = method(larg)
+	} else {
+		for _, name := range append(options.CompleteOptNames,
+			"id",
+			"dev",
+			"master",
+			"groups",
+			"fdb",
+			"protocol",
+		) {
+			if len(larg) == 0 || strings.HasPrefix(name, larg) {
+				list = append(list, name)
+			}
+		}
+	}
+	return
+}
+
+// The kernel filters dumps by device, master, groups and fdb; family, id
+// and protocol are matched here.
+func (sel *selector) parse(opt *options.Options) error {
+	if s := opt.Parms.ByName["-f"]; len(s) > 0 {
+		family, found := rtnl.AfByName[s]
+		if !found {
+			return fmt.Errorf("family: %q unknown", s)
+		}
+		sel.family = family
+	}
+	if s := opt.Parms.ByName["id"]; len(s) > 0 {
+		var id uint32
+		if _, err := fmt.Sscan(s, &id); err != nil {
+			return fmt.Errorf("id: %q %v", s, err)
+		}
+		sel.id = &id
+	}
+	if s := opt.Parms.ByName["protocol"]; len(s) > 0 {
+		proto, found := rtnl.RtProtByName[s]
+		if !found {
+			if _, err := fmt.Sscan(s, &proto); err != nil {
+				return fmt.Errorf("protocol: %q %v", s, err)
+			}
+		}
+		sel.protocol = &proto
+	}
+	for _, x := range []struct {
+		name string
+		t    uint16
+	}{
+		{"dev", rtnl.NHA_OIF},
+		{"master", rtnl.NHA_MASTER},
+	} {
+		s := opt.Parms.ByName[x.name]
+		if len(s) == 0 {
+			continue
+		}
+		ifindex, found := rtnl.If.IndexByName[s]
+		if !found {
+			return fmt.Errorf("%s: %q not found", x.name, s)
+		}
+		sel.attrs = append(sel.attrs,
+			nl.Attr{x.t, nl.Uint32Attr(ifindex)})
+	}
+	if opt.Flags.ByName["groups"] {
+		sel.attrs = append(sel.attrs, nl.Attr{rtnl.NHA_GROUPS, nl.NilAttr{}})
+	}
+	if opt.Flags.ByName["fdb"] {
+		sel.attrs = append(sel.attrs, nl.Attr{rtnl.NHA_FDB, nl.NilAttr{}})
+	}
+	return nil
+}
+
+func (sel *selector) match(b []byte) bool {
+	var nha rtnl.Nha
+	nha.Write(b)
+	msg := rtnl.NhMsgPtr(b)
+	if sel.family != rtnl.AF_UNSPEC && sel.family != msg.Family {
+		return false
+	}
+	if sel.id != nil && *sel.id != nl.Uint32(nha[rtnl.NHA_ID]) {
+		return false
+	}
+	if sel.protocol != nil && *sel.protocol != msg.Protocol {
+		return false
+	}
+	return true
+}
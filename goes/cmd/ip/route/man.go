@@ -291,6 +291,11 @@ DESCRIPTION
 				route reflecting its relative bandwidth or
 				quality.
 
+		nhid ID
+			use the nexthop object with the given ID; the
+			route's nexthop and multipath attributes are then
+			managed with "ip nexthop".
+
 
 		scope SCOPE_VAL
 			the scope of the destinations covered by the route
@@ -558,6 +563,10 @@ EXAMPLES
 		Adds an ipv4 route with mpls encapsulation attributes attached
 		to it.
 
+	ip route add 10.2.0.0/24 nhid 10
+		Adds an ipv4 route through nexthop object 10, which may be a
+		group.
+
 SEE ALSO
 	man ip || ip -man
 
@@ -56,7 +56,7 @@ RTPROTO := { redirect | kernel | boot | static | gated | ra | mrt | zebra |
 
 RTSCOPE := { global | site | link | host | NUMBER }
 
-INFO-SPEC := NH OPTIONS [ nexthop NH ] ...
+INFO-SPEC := { NH | nhid ID } OPTIONS [ nexthop NH ] ...
 
 NH := [ encap ENCAP ] [ via [ FAMILY ] ADDRESS ] [ dev IFNAME ]
 	[ weight WEIGHT ] [ onlink | pervasive ]
@@ -186,6 +186,7 @@ func (Command) Complete(args ...string) (list []string) {
 	cpv["via"] = options.NoComplete
 	cpv["dev"] = options.CompleteIfName
 	cpv["weight"] = options.NoComplete
+	cpv["nhid"] = options.NoComplete
 	cpv["as"] = options.NoComplete
 	cpv["mtu"] = options.NoComplete
 	cpv["advmss"] = options.NoComplete
@@ -219,6 +220,7 @@ func (Command) Complete(args ...string) (list []string) {
 			"via",
 			"dev",
 			"weight",
+			"nhid",
 			"onlink",
 			"pervasive",
 			"as",
@@ -463,6 +465,12 @@ func (m *mod) parse() error {
 			}
 		case "onlink":
 			m.msg.Flags |= uint32(rtnl.RTNH_F_ONLINK)
+		case "nhid":
+			if v, e := m.parseNumber(); e == nil {
+				m.append(rtnl.RTA_NH_ID, nl.Uint32Attr(v))
+			} else {
+				err = e
+			}
 		case "nexthop":
 			if nhs, e := m.parseNextHops(); e == nil {
 				m.append(rtnl.RTA_MULTIPATH, nhs)
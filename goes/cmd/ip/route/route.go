@@ -32,7 +32,7 @@ ROUTE := NODE_SPEC [ INFO_SPEC ]
 NODE_SPEC := [ TYPE ] PREFIX [ tos TOS ] [ table TABLE_ID ]
 	[ proto RTPROTO ] [ scope SCOPE ] [ metric METRIC ]
 
-INFO_SPEC := NH OPTIONS [ nexthop NH ] ...
+INFO_SPEC := { NH | nhid ID } OPTIONS [ nexthop NH ] ...
 
 NH := [ encap ENCAP ] [ via ADDRESS ] [ dev STRING ] [ weight NUMBER ]
 	NHFLAGS
@@ -0,0 +1,131 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package rule
+
+const Man = `
+DESCRIPTION
+	ip rule manipulates rules in the routing policy database that
+	control the route selection algorithm.
+
+	Each policy routing rule consists of a selector and an action
+	predicate.  The RPDB is scanned in order of decreasing priority
+	(i.e. increasing pref number); the selector of each rule is
+	applied to {source address, destination address, incoming
+	interface, tos, fwmark} and, if the selector matches the packet,
+	the action is performed.
+
+	At startup the kernel configures the RPDB with three rules:
+
+	0:	from all lookup local
+	32766:	from all lookup main
+	32767:	from all lookup default
+
+	Rule types:
+
+	unicast
+		the rule prescribes to return the route found in the
+		routing table referenced by the rule.
+
+	blackhole
+		the rule prescribes to silently drop the packet.
+
+	unreachable
+		the rule prescribes to generate a 'Network is unreachable'
+		error.
+
+	prohibit
+		the rule prescribes to generate 'Communication is
+		administratively prohibited' error.
+
+	nop
+		the rule prescribes to do nothing.
+
+COMMANDS
+	ip rule add - insert a new rule
+	ip rule delete - delete a rule
+
+	not	invert the sense of the selector
+
+	from PREFIX
+		select the source prefix to match.
+
+	to PREFIX
+		select the destination prefix to match.
+
+	iif NAME
+		select the incoming device to match.  If the interface is
+		loopback, the rule only matches packets originating from
+		this host.
+
+	oif NAME
+		select the outgoing device to match.  The outgoing
+		interface is only available for packets originating from
+		local sockets that are bound to a device.
+
+	tos TOS, dsfield TOS
+		select the TOS value to match.
+
+	fwmark MARK[/MASK]
+		select the fwmark value to match.
+
+	uidrange NUMBER-NUMBER
+		select the uid value to match.
+
+	ipproto PROTOCOL
+		select the ip protocol value to match.
+
+	sport NUMBER | NUMBER-NUMBER
+		select the source port value to match.
+
+	dport NUMBER | NUMBER-NUMBER
+		select the destination port value to match.
+
+	priority PREFERENCE, preference PREFERENCE, order PREFERENCE
+		the priority of this rule.  PREFERENCE is an unsigned
+		integer value, higher number means lower priority.  Each
+		rule should have an explicitly set unique priority value.
+
+	table TABLEID, lookup TABLEID
+		the routing table identifier to lookup if the rule
+		selector matches.
+
+	protocol PROTO
+		the routing protocol who installed the rule in question.
+
+	l3mdev
+		lookup the table associated with the VRF device of the
+		incoming or outgoing interface.
+
+	suppress_prefixlength NUMBER
+		reject routing decisions that have a prefix length of
+		NUMBER or less.
+
+	suppress_ifgroup GROUP
+		reject routing decisions that use a device belonging to
+		the interface group GROUP.
+
+	realms FROM/TO
+		realms to select if the rule matched and the routing table
+		lookup succeeded.
+
+	goto NUMBER
+		jump to the rule with the given priority.
+
+	ip rule flush - delete all rules matching SELECTOR
+		Rules of priority 0 are never flushed.
+
+	ip rule show - list rules matching SELECTOR
+		list is a synonym of show.
+
+EXAMPLES
+	ip rule add from 10.1.1.0/24 table 100 pref 1000
+		Lookup table 100 for packets sourced from 10.1.1.0/24.
+
+	ip rule add l3mdev pref 1000
+		Lookup the table of the VRF master of the packet's device.
+
+SEE ALSO
+	ip man route || ip route -man
+	man ip || ip -man`
@@ -0,0 +1,517 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package mod
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/platinasystems/go/goes/cmd/ip/internal/group"
+	"github.com/platinasystems/go/goes/cmd/ip/internal/options"
+	"github.com/platinasystems/go/goes/lang"
+	"github.com/platinasystems/go/internal/nl"
+	"github.com/platinasystems/go/internal/nl/rtnl"
+)
+
+type Command string
+
+type mod struct {
+	opt  *options.Options
+	args []string
+
+	sr *nl.SockReceiver
+
+	hdr   nl.Hdr
+	msg   rtnl.FibRuleMsg
+	attrs nl.Attrs
+
+	hasTable  bool
+	hasL3mdev bool
+}
+
+var ipProtoByName = map[string]uint8{
+	"icmp": rtnl.IPPROTO_ICMP,
+	"tcp":  rtnl.IPPROTO_TCP,
+	"udp":  rtnl.IPPROTO_UDP,
+	"gre":  rtnl.IPPROTO_GRE,
+	"esp":  rtnl.IPPROTO_ESP,
+	"ah":   rtnl.IPPROTO_AH,
+	"sctp": rtnl.IPPROTO_SCTP,
+}
+
+func (c Command) String() string { return string(c) }
+
+func (c Command) Usage() string {
+	return fmt.Sprint("ip rule ", c, ` SELECTOR ACTION
+
+SELECTOR := [ not ] [ from PREFIX ] [ to PREFIX ] [ tos TOS ]
+	[ fwmark FWMARK[/MASK] ] [ iif STRING ] [ oif STRING ]
+	[ pref NUMBER ] [ l3mdev ] [ uidrange NUMBER-NUMBER ]
+	[ ipproto PROTOCOL ] [ sport [ NUMBER | NUMBER-NUMBER ] ]
+	[ dport [ NUMBER | NUMBER-NUMBER ] ] [ tun_id TUN_ID ]
+
+ACTION := [ table TABLE_ID ] [ protocol RTPROTO ] [ realms REALM ]
+	[ goto NUMBER ] [ suppress_prefixlength NUMBER ]
+	[ suppress_ifgroup GROUP ] TYPE
+
+TABLE_ID := [ local | main | default | NUMBER ]
+
+TYPE := [ unicast | blackhole | unreachable | prohibit | nop ]`)
+}
+
+func (Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "routing policy rule",
+	}
+}
+
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+SEE ALSO
+	ip man rule || ip rule -man
+	man ip || ip -man`,
+	}
+}
+
+func (c Command) Main(args ...string) error {
+	var m mod
+
+	m.opt, m.args = options.New(args)
+
+	sock, err := nl.NewSock()
+	if err != nil {
+		return err
+	}
+	defer sock.Close()
+
+	m.sr = nl.NewSockReceiver(sock)
+
+	m.hdr.Flags = nl.NLM_F_REQUEST | nl.NLM_F_ACK
+
+	switch c {
+	case "add":
+		m.hdr.Type = rtnl.RTM_NEWRULE
+		m.hdr.Flags |= nl.NLM_F_CREATE | nl.NLM_F_EXCL
+		m.msg.Action = rtnl.FR_ACT_TO_TBL
+	case "delete":
+		m.hdr.Type = rtnl.RTM_DELRULE
+	default:
+		return fmt.Errorf("%s: unknown", c)
+	}
+
+	if err = m.parse(); err != nil {
+		return err
+	}
+
+	if m.msg.Family == rtnl.AF_UNSPEC {
+		m.msg.Family = rtnl.AF_INET
+	}
+
+	if c == "add" && m.msg.Action == rtnl.FR_ACT_TO_TBL &&
+		!m.hasTable && !m.hasL3mdev {
+		m.msg.Table = uint8(rtnl.RT_TABLE_MAIN)
+	}
+
+	req, err := nl.NewMessage(m.hdr, m.msg, m.attrs...)
+	if err == nil {
+		err = m.sr.UntilDone(req, nl.DoNothing)
+	}
+	return err
+}
+
+func (Command) Complete(args ...string) (list []string) {
+	var larg, llarg string
+	n := len(args)
+	if n > 0 {
+		larg = args[n-1]
+	}
+	if n > 1 {
+		llarg = args[n-2]
+	}
+	cpv := options.CompleteParmValue
+	cpv["from"] = options.NoComplete
+	cpv["to"] = options.NoComplete
+	cpv["tos"] = options.NoComplete
+	cpv["fwmark"] = options.NoComplete
+	cpv["iif"] = options.CompleteIfName
+	cpv["oif"] = options.CompleteIfName
+	cpv["pref"] = options.NoComplete
+	cpv["uidrange"] = options.NoComplete
+	cpv["ipproto"] = options.NoComplete
+	cpv["sport"] = options.NoComplete
+	cpv["dport"] = options.NoComplete
+	cpv["tun_id"] = options.NoComplete
+	cpv["table"] = options.NoComplete
+	cpv["protocol"] = rtnl.CompleteRtProt
+	cpv["realms"] = options.NoComplete
+	cpv["goto"] = options.NoComplete
+	cpv["suppress_prefixlength"] = options.NoComplete
+	cpv["suppress_ifgroup"] = options.NoComplete
+	if method, found := cpv[llarg]; found {
+		list = method(larg)
+	} else {
+		for _, name := range append(options.CompleteOptNames,
+			"not",
+			"from",
+			"to",
+			"tos",
+			"fwmark",
+			"iif",
+			"oif",
+			"pref",
+			"l3mdev",
+			"uidrange",
+			"ipproto",
+			"sport",
+			"dport",
+			"tun_id",
+			"table",
+			"protocol",
+			"realms",
+			"goto",
+			"suppress_prefixlength",
+			"suppress_ifgroup",
+		) {
+			if len(larg) == 0 || strings.HasPrefix(name, larg) {
+				list = append(list, name)
+			}
+		}
+		list = append(list, rtnl.CompleteFrAct(larg)...)
+	}
+	return
+}
+
+func (m *mod) append(t uint16, v io.Reader) {
+	m.attrs = append(m.attrs, nl.Attr{t, v})
+}
+
+func (m *mod) parse() error {
+	var err error
+	if s := m.opt.Parms.ByName["-f"]; len(s) > 0 {
+		if v, ok := rtnl.AfByName[s]; ok {
+			m.msg.Family = v
+		} else {
+			return fmt.Errorf("family: %q unknown", s)
+		}
+	}
+	for err == nil && len(m.args) > 0 {
+		arg0 := m.args[0]
+		m.args = m.args[1:]
+		switch arg0 {
+		case "not":
+			m.msg.Flags |= rtnl.FIB_RULE_INVERT
+		case "from":
+			err = m.parsePrefix(rtnl.FRA_SRC, &m.msg.Src_len)
+		case "to":
+			err = m.parsePrefix(rtnl.FRA_DST, &m.msg.Dst_len)
+		case "tos", "dsfield":
+			err = m.parseTos()
+		case "fwmark":
+			err = m.parseFwmark()
+		case "iif", "dev":
+			if s, e := m.parseString(); e == nil {
+				m.append(rtnl.FRA_IIFNAME, nl.KstringAttr(s))
+			} else {
+				err = e
+			}
+		case "oif":
+			if s, e := m.parseString(); e == nil {
+				m.append(rtnl.FRA_OIFNAME, nl.KstringAttr(s))
+			} else {
+				err = e
+			}
+		case "pref", "preference", "priority", "order":
+			if u32, e := m.parseUint32(); e == nil {
+				m.append(rtnl.FRA_PRIORITY, nl.Uint32Attr(u32))
+			} else {
+				err = e
+			}
+		case "l3mdev":
+			m.hasL3mdev = true
+			m.append(rtnl.FRA_L3MDEV, nl.Uint8Attr(1))
+		case "uidrange":
+			if start, end, e := m.parseRange(); e != nil {
+				err = e
+			} else {
+				m.append(rtnl.FRA_UID_RANGE, rtnl.FibRuleUidRange{
+					Start: start,
+					End:   end,
+				})
+			}
+		case "ipproto":
+			err = m.parseIpProto()
+		case "sport":
+			err = m.parsePortRange(rtnl.FRA_SPORT_RANGE)
+		case "dport":
+			err = m.parsePortRange(rtnl.FRA_DPORT_RANGE)
+		case "tun_id":
+			if s, e := m.parseString(); e != nil {
+				err = e
+			} else if u64, e := strconv.ParseUint(s, 0, 64); e != nil {
+				err = fmt.Errorf("%q %v", s, e)
+			} else {
+				m.append(rtnl.FRA_TUN_ID, nl.Be64Attr(u64))
+			}
+		case "table", "lookup":
+			err = m.parseTable()
+		case "protocol", "proto":
+			err = m.parseProtocol()
+		case "realms", "flow":
+			if realm, e := m.parseRealm(); e == nil {
+				m.append(rtnl.FRA_FLOW, nl.Uint32Attr(realm))
+			} else {
+				err = e
+			}
+		case "goto":
+			if u32, e := m.parseUint32(); e == nil {
+				m.msg.Action = rtnl.FR_ACT_GOTO
+				m.append(rtnl.FRA_GOTO, nl.Uint32Attr(u32))
+			} else {
+				err = e
+			}
+		case "suppress_prefixlength", "sup_pl":
+			if u32, e := m.parseUint32(); e == nil {
+				m.append(rtnl.FRA_SUPPRESS_PREFIXLEN,
+					nl.Uint32Attr(u32))
+			} else {
+				err = e
+			}
+		case "suppress_ifgroup", "sup_group":
+			if s, e := m.parseString(); e != nil {
+				err = e
+			} else if id := group.Id(s); id == ^uint32(0) {
+				err = fmt.Errorf("%q invalid", s)
+			} else {
+				m.append(rtnl.FRA_SUPPRESS_IFGROUP,
+					nl.Uint32Attr(id))
+			}
+		case "type":
+			if len(m.args) == 0 {
+				err = fmt.Errorf("missing TYPE")
+			} else if v, ok := rtnl.FrActByName[m.args[0]]; ok {
+				m.msg.Action = v
+				m.args = m.args[1:]
+			} else {
+				err = fmt.Errorf("%q unknown", m.args[0])
+			}
+		default:
+			if v, ok := rtnl.FrActByName[arg0]; ok {
+				m.msg.Action = v
+			} else {
+				err = fmt.Errorf("unknown")
+			}
+		}
+		if err != nil {
+			err = fmt.Errorf("%s: %v", arg0, err)
+		}
+	}
+	return err
+}
+
+func (m *mod) parseString() (string, error) {
+	var v string
+	if len(m.args) == 0 {
+		return v, fmt.Errorf("missing STRING")
+	}
+	v = m.args[0]
+	m.args = m.args[1:]
+	return v, nil
+}
+
+func (m *mod) parseUint32() (uint32, error) {
+	var v uint32
+	if len(m.args) == 0 {
+		return v, fmt.Errorf("missing NUMBER")
+	}
+	if _, err := fmt.Sscan(m.args[0], &v); err != nil {
+		return v, fmt.Errorf("%q %v", m.args[0], err)
+	}
+	m.args = m.args[1:]
+	return v, nil
+}
+
+// PREFIX or ADDRESS, where "all", "any" and "default" match everything
+func (m *mod) parsePrefix(t uint16, plen *uint8) error {
+	if len(m.args) == 0 {
+		return fmt.Errorf("missing PREFIX")
+	}
+	arg0 := m.args[0]
+	m.args = m.args[1:]
+	switch arg0 {
+	case "all", "any", "default":
+		return nil
+	}
+	s := arg0
+	if !strings.Contains(s, "/") {
+		if ip := net.ParseIP(s); ip == nil {
+		} else if ip.To4() != nil {
+			s += "/32"
+		} else {
+			s += "/128"
+		}
+	}
+	prefix, err := rtnl.Prefix(s, m.msg.Family)
+	if err != nil {
+		return err
+	}
+	switch family := prefix.Family(); family {
+	case rtnl.AF_INET, rtnl.AF_INET6:
+		m.msg.Family = family
+	default:
+		return fmt.Errorf("%q invalid", arg0)
+	}
+	*plen = prefix.Len()
+	m.append(t, prefix)
+	return nil
+}
+
+func (m *mod) parseTos() error {
+	if len(m.args) == 0 {
+		return fmt.Errorf("missing TOS")
+	}
+	if _, err := fmt.Sscan(m.args[0], &m.msg.Tos); err != nil {
+		return fmt.Errorf("%q %v", m.args[0], err)
+	}
+	m.args = m.args[1:]
+	return nil
+}
+
+// MARK[/MASK]
+func (m *mod) parseFwmark() error {
+	if len(m.args) == 0 {
+		return fmt.Errorf("missing FWMARK")
+	}
+	arg0 := m.args[0]
+	m.args = m.args[1:]
+	smark, smask := arg0, ""
+	if slash := strings.Index(arg0, "/"); slash >= 0 {
+		smark, smask = arg0[:slash], arg0[slash+1:]
+	}
+	mark, err := strconv.ParseUint(smark, 0, 32)
+	if err != nil {
+		return fmt.Errorf("%q %v", arg0, err)
+	}
+	m.append(rtnl.FRA_FWMARK, nl.Uint32Attr(mark))
+	if len(smask) > 0 {
+		mask, err := strconv.ParseUint(smask, 0, 32)
+		if err != nil {
+			return fmt.Errorf("%q %v", arg0, err)
+		}
+		m.append(rtnl.FRA_FWMASK, nl.Uint32Attr(mask))
+	}
+	return nil
+}
+
+// NUMBER-NUMBER, or NUMBER for a range of one
+func (m *mod) parseRange() (start, end uint32, err error) {
+	if len(m.args) == 0 {
+		err = fmt.Errorf("missing NUMBER-NUMBER")
+		return
+	}
+	arg0 := m.args[0]
+	m.args = m.args[1:]
+	s := strings.Replace(arg0, "-", " ", 1)
+	if n, _ := fmt.Sscan(s, &start, &end); n == 1 {
+		end = start
+	} else if n != 2 {
+		err = fmt.Errorf("%q invalid", arg0)
+	} else if end < start {
+		err = fmt.Errorf("%q invalid range", arg0)
+	}
+	return
+}
+
+func (m *mod) parsePortRange(t uint16) error {
+	start, end, err := m.parseRange()
+	if err != nil {
+		return err
+	}
+	if end > 0xffff {
+		return fmt.Errorf("%d: out of range", end)
+	}
+	m.append(t, rtnl.FibRulePortRange{
+		Start: uint16(start),
+		End:   uint16(end),
+	})
+	return nil
+}
+
+func (m *mod) parseIpProto() error {
+	var proto uint8
+	if len(m.args) == 0 {
+		return fmt.Errorf("missing PROTOCOL")
+	}
+	if v, ok := ipProtoByName[m.args[0]]; ok {
+		proto = v
+	} else if _, err := fmt.Sscan(m.args[0], &proto); err != nil {
+		return fmt.Errorf("%q %v", m.args[0], err)
+	}
+	m.args = m.args[1:]
+	m.append(rtnl.FRA_IP_PROTO, nl.Uint8Attr(proto))
+	return nil
+}
+
+func (m *mod) parseTable() error {
+	var t uint32
+	if len(m.args) == 0 {
+		return fmt.Errorf("missing TABLE_ID")
+	}
+	if v, ok := rtnl.RtTableByName[m.args[0]]; ok {
+		t = v
+	} else if _, err := fmt.Sscan(m.args[0], &t); err != nil {
+		return fmt.Errorf("%q %v", m.args[0], err)
+	}
+	if t < 256 {
+		m.msg.Table = uint8(t)
+	} else {
+		m.msg.Table = uint8(rtnl.RT_TABLE_UNSPEC)
+		m.append(rtnl.FRA_TABLE, nl.Uint32Attr(t))
+	}
+	m.hasTable = true
+	m.args = m.args[1:]
+	return nil
+}
+
+func (m *mod) parseProtocol() error {
+	var proto uint8
+	if len(m.args) == 0 {
+		return fmt.Errorf("missing RTPROTO")
+	}
+	if v, ok := rtnl.RtProtByName[m.args[0]]; ok {
+		proto = v
+	} else if _, err := fmt.Sscan(m.args[0], &proto); err != nil {
+		return fmt.Errorf("%q %v", m.args[0], err)
+	}
+	m.args = m.args[1:]
+	m.append(rtnl.FRA_PROTOCOL, nl.Uint8Attr(proto))
+	return nil
+}
+
+// [FROM/]TO
+func (m *mod) parseRealm() (uint32, error) {
+	var from, to uint32
+	if len(m.args) == 0 {
+		return 0, fmt.Errorf("missing REALM")
+	}
+	arg0 := m.args[0]
+	m.args = m.args[1:]
+	sfrom, sto := "", arg0
+	if slash := strings.Index(arg0, "/"); slash >= 0 {
+		sfrom, sto = arg0[:slash], arg0[slash+1:]
+	}
+	if len(sfrom) > 0 {
+		if _, err := fmt.Sscan(sfrom, &from); err != nil {
+			return 0, fmt.Errorf("%q %v", arg0, err)
+		}
+	}
+	if _, err := fmt.Sscan(sto, &to); err != nil {
+		return 0, fmt.Errorf("%q %v", arg0, err)
+	}
+	return from<<16 | to, nil
+}
@@ -0,0 +1,49 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package rule
+
+import (
+	"github.com/platinasystems/go/goes"
+	"github.com/platinasystems/go/goes/cmd"
+	"github.com/platinasystems/go/goes/cmd/ip/rule/mod"
+	"github.com/platinasystems/go/goes/cmd/ip/rule/show"
+	"github.com/platinasystems/go/goes/lang"
+)
+
+var Goes = &goes.Goes{
+	NAME: "rule",
+	USAGE: `
+	ip rule [ list | show ] [ SELECTOR ]
+	ip rule flush [ SELECTOR ]
+	ip rule { add | del } SELECTOR ACTION
+
+SELECTOR := [ not ] [ from PREFIX ] [ to PREFIX ] [ tos TOS ]
+	[ fwmark FWMARK[/MASK] ] [ iif STRING ] [ oif STRING ]
+	[ pref NUMBER ] [ l3mdev ] [ uidrange NUMBER-NUMBER ]
+	[ ipproto PROTOCOL ] [ sport [ NUMBER | NUMBER-NUMBER ] ]
+	[ dport [ NUMBER | NUMBER-NUMBER ] ] [ tun_id TUN_ID ]
+
+ACTION := [ table TABLE_ID ] [ protocol RTPROTO ] [ realms REALM ]
+	[ goto NUMBER ] [ suppress_prefixlength NUMBER ]
+	[ suppress_ifgroup GROUP ] TYPE
+
+TABLE_ID := [ local | main | default | NUMBER ]
+
+TYPE := [ unicast | blackhole | unreachable | prohibit | nop ]`,
+	APROPOS: lang.Alt{
+		lang.EnUS: "routing policy database management",
+	},
+	MAN: lang.Alt{
+		lang.EnUS: Man,
+	},
+	ByName: map[string]cmd.Cmd{
+		"add":    mod.Command("add"),
+		"delete": mod.Command("delete"),
+		"":       show.Command(""),
+		"show":   show.Command("show"),
+		"list":   show.Command("list"),
+		"flush":  show.Command("flush"),
+	},
+}
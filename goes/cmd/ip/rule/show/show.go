@@ -0,0 +1,295 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// ip rule show (default) | list | flush
+package show
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/platinasystems/go/goes/cmd/ip/internal/options"
+	"github.com/platinasystems/go/goes/lang"
+	"github.com/platinasystems/go/internal/nl"
+	"github.com/platinasystems/go/internal/nl/rtnl"
+)
+
+type Command string
+
+type selector struct {
+	pref     *uint32
+	table    *uint32
+	iif, oif string
+	from, to *net.IPNet
+	fwmark   *uint32
+	l3mdev   bool
+}
+
+func (Command) Aka() string { return "show" }
+
+func (c Command) String() string { return string(c) }
+
+func (Command) Usage() string {
+	return `
+	ip rule [ list | show ] [ SELECTOR ]
+	ip rule flush [ SELECTOR ]
+
+SELECTOR := [ from PREFIX ] [ to PREFIX ] [ fwmark FWMARK ]
+	[ iif STRING ] [ oif STRING ] [ pref NUMBER ] [ l3mdev ]
+	[ table TABLE_ID ]`
+}
+
+func (c Command) Apropos() lang.Alt {
+	apropos := "routing policy rule"
+	if c == "show" {
+		apropos += " (default)"
+	}
+	return lang.Alt{
+		lang.EnUS: apropos,
+	}
+}
+
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+SEE ALSO
+	ip man rule || ip rule -man
+	man ip || ip -man`,
+	}
+}
+
+func (c Command) Main(args ...string) error {
+	var sel selector
+	var flush [][]byte
+
+	opt, args := options.New(args)
+	args = opt.Flags.More(args, "l3mdev")
+	args = opt.Parms.More(args,
+		"from",
+		"to",
+		"fwmark",
+		"iif",
+		"oif",
+		[]string{"pref", "preference", "priority", "order"},
+		[]string{"table", "lookup"},
+	)
+	if len(args) > 0 {
+		return fmt.Errorf("%v: unexpected", args)
+	}
+	if err := sel.parse(opt); err != nil {
+		return err
+	}
+
+	sock, err := nl.NewSock()
+	if err != nil {
+		return err
+	}
+	defer sock.Close()
+
+	sr := nl.NewSockReceiver(sock)
+
+	// Like rule add and delete, and iproute2, list and flush IPv4 rules
+	// unless -6 or -f inet6 so that flush keeps the IPv6 default rules.
+	afs := []uint8{rtnl.AF_INET}
+	if opt.Parms.ByName["-f"] == "inet6" {
+		afs = []uint8{rtnl.AF_INET6}
+	}
+
+	for _, af := range afs {
+		req, err := nl.NewMessage(
+			nl.Hdr{
+				Type:  rtnl.RTM_GETRULE,
+				Flags: nl.NLM_F_REQUEST | nl.NLM_F_DUMP,
+			},
+			rtnl.RtGenMsg{
+				Family: af,
+			},
+		)
+		if err != nil {
+			return err
+		}
+		if err = sr.UntilDone(req, func(b []byte) {
+			if nl.HdrPtr(b).Type != rtnl.RTM_NEWRULE {
+				return
+			}
+			if !sel.match(b) {
+				return
+			}
+			if c == "flush" {
+				var fra rtnl.Fra
+				fra.Write(b)
+				if nl.Uint32(fra[rtnl.FRA_PRIORITY]) != 0 {
+					flush = append(flush,
+						append([]byte{}, b...))
+				}
+				return
+			}
+			opt.ShowRule(b)
+			fmt.Println()
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, b := range flush {
+		h := nl.HdrPtr(b)
+		h.Type = rtnl.RTM_DELRULE
+		h.Flags = nl.NLM_F_REQUEST | nl.NLM_F_ACK
+		if err = sr.UntilDone(b, nl.DoNothing); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (Command) Complete(args ...string) (list []string) {
+	var larg, llarg string
+	n := len(args)
+	if n > 0 {
+		larg = args[n-1]
+	}
+	if n > 1 {
+		llarg = args[n-2]
+	}
+	cpv := options.CompleteParmValue
+	cpv["from"] = options.NoComplete
+	cpv["to"] = options.NoComplete
+	cpv["fwmark"] = options.NoComplete
+	cpv["iif"] = options.CompleteIfName
+	cpv["oif"] = options.CompleteIfName
+	cpv["pref"] = options.NoComplete
+	cpv["table"] = options.NoComplete
+	if method, found := cpv[llarg]; found {
+		list = method(larg)
+	} else {
+		for _, name := range append(options.CompleteOptNames,
+			"from",
+			"to",
+			"fwmark",
+			"iif",
+			"oif",
+			"pref",
+			"l3mdev",
+			"table",
+		) {
+			if len(larg) == 0 || strings.HasPrefix(name, larg) {
+				list = append(list, name)
+			}
+		}
+	}
+	return
+}
+
+func (sel *selector) parse(opt *options.Options) error {
+	for _, x := range []struct {
+		name string
+		p    **uint32
+	}{
+		{"pref", &sel.pref},
+		{"fwmark", &sel.fwmark},
+	} {
+		s := opt.Parms.ByName[x.name]
+		if len(s) == 0 {
+			continue
+		}
+		if slash := strings.Index(s, "/"); slash >= 0 {
+			s = s[:slash]
+		}
+		u64, err := strconv.ParseUint(s, 0, 32)
+		if err != nil {
+			return fmt.Errorf("%s: %q %v", x.name, s, err)
+		}
+		u32 := uint32(u64)
+		*x.p = &u32
+	}
+	if s := opt.Parms.ByName["table"]; len(s) > 0 {
+		t, found := rtnl.RtTableByName[s]
+		if !found {
+			if _, err := fmt.Sscan(s, &t); err != nil {
+				return fmt.Errorf("table: %q %v", s, err)
+			}
+		}
+		sel.table = &t
+	}
+	for _, x := range []struct {
+		name string
+		p    **net.IPNet
+	}{
+		{"from", &sel.from},
+		{"to", &sel.to},
+	} {
+		s := opt.Parms.ByName[x.name]
+		switch s {
+		case "", "all", "any", "default":
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			if ip := net.ParseIP(s); ip == nil {
+			} else if ip.To4() != nil {
+				s += "/32"
+			} else {
+				s += "/128"
+			}
+		}
+		_, ipnet, err := net.ParseCIDR(s)
+		if err != nil {
+			return fmt.Errorf("%s: %v", x.name, err)
+		}
+		*x.p = ipnet
+	}
+	sel.iif = opt.Parms.ByName["iif"]
+	sel.oif = opt.Parms.ByName["oif"]
+	sel.l3mdev = opt.Flags.ByName["l3mdev"]
+	return nil
+}
+
+func (sel *selector) match(b []byte) bool {
+	var fra rtnl.Fra
+	fra.Write(b)
+	msg := rtnl.FibRuleMsgPtr(b)
+	if sel.pref != nil && *sel.pref != nl.Uint32(fra[rtnl.FRA_PRIORITY]) {
+		return false
+	}
+	if sel.table != nil {
+		table := uint32(msg.Table)
+		if val := fra[rtnl.FRA_TABLE]; len(val) > 0 {
+			table = nl.Uint32(val)
+		}
+		if *sel.table != table {
+			return false
+		}
+	}
+	if sel.fwmark != nil && *sel.fwmark != nl.Uint32(fra[rtnl.FRA_FWMARK]) {
+		return false
+	}
+	if len(sel.iif) > 0 && sel.iif != nl.Kstring(fra[rtnl.FRA_IIFNAME]) {
+		return false
+	}
+	if len(sel.oif) > 0 && sel.oif != nl.Kstring(fra[rtnl.FRA_OIFNAME]) {
+		return false
+	}
+	if sel.l3mdev && len(fra[rtnl.FRA_L3MDEV]) == 0 {
+		return false
+	}
+	for _, x := range []struct {
+		ipnet *net.IPNet
+		val   []byte
+		plen  uint8
+	}{
+		{sel.from, fra[rtnl.FRA_SRC], msg.Src_len},
+		{sel.to, fra[rtnl.FRA_DST], msg.Dst_len},
+	} {
+		if x.ipnet == nil {
+			continue
+		}
+		ones, _ := x.ipnet.Mask.Size()
+		if len(x.val) == 0 || int(x.plen) != ones ||
+			!x.ipnet.IP.Equal(net.IP(x.val)) {
+			return false
+		}
+	}
+	return true
+}
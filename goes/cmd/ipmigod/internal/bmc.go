@@ -10,6 +10,8 @@ import (
 	"fmt"
 	"net"
 	"time"
+
+	"github.com/platinasystems/go/internal/redis"
 )
 
 // Used for artificial qemu environment
@@ -81,6 +83,9 @@ type selT struct {
 	reservation   uint16
 	nextEntry     uint16
 	lastEntry     uint16
+	timeOffset    int64  // of set SEL time from the system clock
+	file          string // persistent log, empty if in memory
+	wrap          bool   // replace the oldest entries when full
 }
 
 type mcT struct {
@@ -113,6 +118,7 @@ func bmcInit() {
 	mc.majorFwRev = 1
 	mc.minorFwRev = 1
 	mc.deviceSupport = IPMI_DEVID_SDR_REPOSITORY_DEV |
+		IPMI_DEVID_SEL_DEVICE |
 		IPMI_DEVID_SENSOR_DEV
 	mc.mfgId[0] = 0
 	mc.mfgId[1] = 0
@@ -124,8 +130,10 @@ func bmcInit() {
 	mc.mainSdrs.maxSdrCount = 2000
 	mc.mainSdrs.nextFreeEntryId = 1
 
-	mc.sel.maxCount = 1000
 	mc.sel.nextEntry = 1
+	if err := selLoad(); err != nil {
+		fmt.Println("SEL:", err)
+	}
 
 	// Initially this is a simulated set of sensors.
 	// In production, a similar scheme could be used or
@@ -190,19 +198,21 @@ func bmcInit() {
 			0, 0, 0, 0, 0, 0, 0, 0xCA,
 			sensorName4)
 
-		// Add an event log to sel for sensor 17
+		// Add event logs to an empty sel for sensor 17
 		selRecord := []uint8{0x01, 0x00, 0x02, 0x00, 0x00, 0x00,
 			0x00, 0x20, 0x00, 0x04, 0x01, 0x11, 0x01, 0x00,
 			0x00, 0x00}
-		addToSel(2, selRecord)
+		if mc.sel.count == 0 {
+			addToSel(2, selRecord)
 
-		// Add a 2nd event log to sel for sensor 17
-		selRecord[12] = 0x02
-		addToSel(2, selRecord)
+			// Add a 2nd event log to sel for sensor 17
+			selRecord[12] = 0x02
+			addToSel(2, selRecord)
 
-		// Add a 3rd event log to sel for sensor 17
-		selRecord[12] = 0x03
-		addToSel(2, selRecord)
+			// Add a 3rd event log to sel for sensor 17
+			selRecord[12] = 0x03
+			addToSel(2, selRecord)
+		}
 
 	} else {
 		// Do a dynamic discovery of sensors based on sysclass
@@ -213,6 +223,20 @@ func bmcInit() {
 		//lm75 (temp monitor)
 	}
 
+	// Record the alarm transitions of the hardware daemons and publish
+	// the alerts of the platform event filters.
+	if len(EventChannels) > 0 {
+		alerts, err := redis.Publish(PEF_ALERT_CHANNEL)
+		if err != nil {
+			fmt.Println("PEF alerts:", err)
+		} else {
+			pef.alerts = alerts
+		}
+	}
+	for _, channel := range EventChannels {
+		go selWatch(channel)
+	}
+
 	// Start ticker for sensor polling in a gofunc
 	ticker := time.NewTicker(time.Second * 3)
 	go func() {
@@ -366,8 +390,14 @@ func findSelEventByRecid(recordId uint16) *selEntryT {
 
 func addToSel(recordType uint8, recordData []uint8) (err, recordId uint16) {
 	if mc.sel.count >= mc.sel.maxCount {
-		mc.sel.flags |= 0x80
-		return IPMI_OUT_OF_SPACE_CC, 0
+		mc.sel.flags |= SEL_OVERFLOW
+		if !mc.sel.wrap || mc.sel.count == 0 {
+			return IPMI_OUT_OF_SPACE_CC, 0
+		}
+		// Replace the oldest entry
+		mc.sel.entries = append(mc.sel.entries[:0],
+			mc.sel.entries[1:]...)
+		mc.sel.count--
 	}
 
 	e := new(selEntryT)
@@ -378,24 +408,24 @@ func addToSel(recordType uint8, recordData []uint8) (err, recordId uint16) {
 	// Find a new unique record-id - take care of the
 	// case where the log has wrapped and record_ids are out of order
 	// (from deletes) and so nextEntry may not be unique anymore
-	// NB: We jump index 0 since it's invalid
-	e.recordId = mc.sel.nextEntry
-	mc.sel.nextEntry++
-	startRecordId := e.recordId
-	for mc.sel.nextEntry == 0 ||
-		findSelEventByRecid(e.recordId) != nil {
+	// NB: We jump index 0 and 0xffff since they're invalid
+	startRecordId := mc.sel.nextEntry
+	for {
 		e.recordId = mc.sel.nextEntry
 		mc.sel.nextEntry++
-		if e.recordId == startRecordId {
+		if e.recordId != 0 && e.recordId != 0xffff &&
+			findSelEventByRecid(e.recordId) == nil {
+			break
+		}
+		if mc.sel.nextEntry == startRecordId {
 			return IPMI_OUT_OF_SPACE_CC, 0
 		}
 	}
 
-	now := time.Now()
+	nowUnix := selNow()
 	if debug {
-		fmt.Println("Time now:", now, "Unix time", now.Unix())
+		fmt.Println("Time now:", time.Now(), "SEL time", nowUnix)
 	}
-	nowUnix := uint32(now.Unix())
 
 	binary.LittleEndian.PutUint16(e.data[0:2], e.recordId)
 	e.data[2] = recordType
@@ -420,5 +450,7 @@ func addToSel(recordType uint8, recordData []uint8) (err, recordId uint16) {
 			fmt.Printf("sel[%d]: record_id: %d\n", i, e.recordId)
 		}
 	}
+	selSave()
+	pefProcess(e)
 	return 0, e.recordId
 }
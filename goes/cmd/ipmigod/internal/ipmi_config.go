@@ -0,0 +1,266 @@
+// Copyright 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package contains IPMI 2.0 spec protocol definitions
+package internal
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+const DefaultConfigFile = "/etc/goes/ipmigod.yaml"
+
+// DefaultConfig is used without DefaultConfigFile; it has the users that
+// were once statically initialized and, without cipher suites, RMCP+ is
+// disabled until a config file supplies them with its own credentials.
+const DefaultConfig = `
+guid: a123456789abcdefa123456789abcdef
+sessionTimeout: 30
+users:
+- id: 1
+  name: ""
+  password: test
+  privilege: user
+  auths: [none, straight]
+- id: 2
+  name: ipmiusr
+  password: test
+  privilege: admin
+  auths: [none]
+`
+
+// Config of the LAN channel and its users.
+type Config struct {
+	// System GUID in hex.
+	Guid string `yaml:"guid"`
+	// Seconds, default 30.
+	SessionTimeout uint32 `yaml:"sessionTimeout"`
+	// RMCP+ cipher suite IDs, e.g. 3 (RAKP-HMAC-SHA1, HMAC-SHA1-96,
+	// AES-CBC-128) or 17 (RAKP-HMAC-SHA256, HMAC-SHA256-128,
+	// AES-CBC-128); without, RMCP+ is disabled.
+	CipherSuites []uint8      `yaml:"cipherSuites"`
+	Users        []UserConfig `yaml:"users"`
+	Sol          SolConfig    `yaml:"sol"`
+	Sel          SelConfig    `yaml:"sel"`
+	Pef          PefConfig    `yaml:"pef"`
+}
+
+// System Event Log
+type SelConfig struct {
+	// Persistent log, default /var/lib/ipmigod/sel; or "none" to keep
+	// it in memory.
+	File string `yaml:"file"`
+	// Default 1000.
+	Entries uint16 `yaml:"entries"`
+	// When full: stop, the default, or wrap to replace the oldest.
+	Overflow string `yaml:"overflow"`
+}
+
+// Platform Event Filtering
+type PefConfig struct {
+	// Filters of the events to alert; later filters may be set with
+	// "ipmitool pef" or "ipmitool raw 4 0x12 6 ...".
+	Filters []PefFilterConfig `yaml:"filters"`
+}
+
+type PefFilterConfig struct {
+	// Sensor type, e.g. 4 (fan) or 8 (power supply), default any.
+	SensorType *uint8 `yaml:"sensorType"`
+	// Sensor number, default any.
+	Sensor *uint8 `yaml:"sensor"`
+	// information, ok, warning, critical, or non-recoverable
+	Severity string `yaml:"severity"`
+}
+
+// Serial-over-LAN
+type SolConfig struct {
+	// The host console tty, i.e. the DEVICE of femtocom; without,
+	// SOL is disabled.
+	Device string `yaml:"device"`
+	// 9600, 19200, 38400, 57600, or default, 115200.
+	Baud int `yaml:"baud"`
+}
+
+type UserConfig struct {
+	// 1 through 64
+	Id uint8 `yaml:"id"`
+	// Up to 16 characters; empty is the null user.
+	Name string `yaml:"name"`
+	// Up to 20 characters; IPMI 1.5 sessions use the first 16.
+	Password string `yaml:"password"`
+	// Maximum: callback, user, operator, admin, or oem.
+	Privilege string `yaml:"privilege"`
+	// IPMI 1.5 session authentication: none, md2, md5, straight, oem
+	Auths []string `yaml:"auths"`
+}
+
+var privileges = map[string]uint8{
+	"callback": IPMI_PRIVILEGE_CALLBACK,
+	"user":     IPMI_PRIVILEGE_USER,
+	"operator": IPMI_PRIVILEGE_OPERATOR,
+	"admin":    IPMI_PRIVILEGE_ADMIN,
+	"oem":      IPMI_PRIVILEGE_OEM,
+}
+
+var severities = map[string]uint8{
+	"":                0x00,
+	"information":     0x02,
+	"ok":              0x04,
+	"warning":         0x08,
+	"critical":        0x10,
+	"non-recoverable": 0x20,
+}
+
+var authtypes = map[string]uint8{
+	"none":     IPMI_AUTHTYPE_NONE,
+	"md2":      IPMI_AUTHTYPE_MD2,
+	"md5":      IPMI_AUTHTYPE_MD5,
+	"straight": IPMI_AUTHTYPE_STRAIGHT,
+	"oem":      IPMI_AUTHTYPE_OEM,
+}
+
+// LoadConfig reads and applies the named config file or, if it doesn't
+// exist, DefaultConfig.
+func LoadConfig(fn string) error {
+	b, err := ioutil.ReadFile(fn)
+	if os.IsNotExist(err) {
+		return ParseConfig([]byte(DefaultConfig))
+	}
+	if err != nil {
+		return err
+	}
+	if fi, err := os.Stat(fn); err == nil && fi.Mode().Perm()&077 != 0 {
+		fmt.Println("warning:", fn, "with passwords is accessible to others")
+	}
+	if err = ParseConfig(b); err != nil {
+		return fmt.Errorf("%s: %v", fn, err)
+	}
+	return nil
+}
+
+// ParseConfig validates then applies the YAML config.
+func ParseConfig(b []byte) error {
+	var cfg Config
+	if err := yaml.UnmarshalStrict(b, &cfg); err != nil {
+		return err
+	}
+	var (
+		users  [MAX_USERS + 1]userT
+		guid   [16]uint8
+		suites []uint8
+	)
+	if cfg.Guid != "" {
+		g, err := hex.DecodeString(cfg.Guid)
+		if err != nil || len(g) != len(guid) {
+			return fmt.Errorf("guid: %s: invalid", cfg.Guid)
+		}
+		copy(guid[:], g)
+	}
+	for _, id := range cfg.CipherSuites {
+		if findCipherSuite(id) == nil {
+			return fmt.Errorf("cipherSuites: %d: unsupported", id)
+		}
+		suites = append(suites, id)
+	}
+	for _, u := range cfg.Users {
+		if u.Id == 0 || u.Id > MAX_USERS {
+			return fmt.Errorf("users: id %d: invalid", u.Id)
+		}
+		if users[u.Id].valid {
+			return fmt.Errorf("users: id %d: duplicate", u.Id)
+		}
+		if len(u.Name) > 16 {
+			return fmt.Errorf("users: %s: name too long", u.Name)
+		}
+		if len(u.Password) > 20 {
+			return fmt.Errorf("users: %s: password too long", u.Name)
+		}
+		priv, found := privileges[u.Privilege]
+		if !found {
+			return fmt.Errorf("users: %s: privilege: %q: invalid",
+				u.Name, u.Privilege)
+		}
+		user := &users[u.Id]
+		user.idx = u.Id
+		user.username = make([]uint8, 16)
+		copy(user.username, u.Name)
+		user.pw = make([]uint8, 20)
+		copy(user.pw, u.Password)
+		user.maxPriv = priv
+		for _, s := range u.Auths {
+			a, found := authtypes[s]
+			if !found {
+				return fmt.Errorf("users: %s: auth: %q: invalid",
+					u.Name, s)
+			}
+			user.allowedAuths |= 1 << a
+		}
+		user.valid = true
+	}
+	if cfg.Sol.Baud == 0 {
+		cfg.Sol.Baud = 115200
+	}
+	bitRate, found := solBitRates[cfg.Sol.Baud]
+	if !found {
+		return fmt.Errorf("sol: baud: %d: unsupported", cfg.Sol.Baud)
+	}
+	switch cfg.Sel.File {
+	case "":
+		cfg.Sel.File = DefaultSelFile
+	case "none":
+		cfg.Sel.File = ""
+	}
+	if cfg.Sel.Entries == 0 {
+		cfg.Sel.Entries = SEL_MAX_COUNT
+	}
+	if cfg.Sel.Overflow != "" && cfg.Sel.Overflow != "stop" &&
+		cfg.Sel.Overflow != "wrap" {
+		return fmt.Errorf("sel: overflow: %q: invalid",
+			cfg.Sel.Overflow)
+	}
+	var filters [PEF_FILTERS]pefFilterT
+	if len(cfg.Pef.Filters) > PEF_FILTERS {
+		return fmt.Errorf("pef: filters: more than %d", PEF_FILTERS)
+	}
+	for i, f := range cfg.Pef.Filters {
+		severity, found := severities[f.Severity]
+		if !found {
+			return fmt.Errorf("pef: filters: severity: %q: invalid",
+				f.Severity)
+		}
+		filter := &filters[i]
+		filter[0] = PEF_FILTER_ENABLE
+		filter[1] = PEF_ACTION_ALERT
+		filter[3] = severity
+		filter[4], filter[5] = 0xff, 0xff
+		filter[6], filter[7], filter[8] = 0xff, 0xff, 0xff
+		if f.SensorType != nil {
+			filter[6] = *f.SensorType
+		}
+		if f.Sensor != nil {
+			filter[7] = *f.Sensor
+		}
+		filter[9], filter[10] = 0xff, 0xff
+	}
+	lanserv.users = users
+	lanserv.guid = guid
+	lanserv.cipherSuites = suites
+	lanserv.defaultSessionTimeout = cfg.SessionTimeout
+	if lanserv.defaultSessionTimeout == 0 {
+		lanserv.defaultSessionTimeout = 30
+	}
+	sol.device = cfg.Sol.Device
+	sol.enable = sol.device != ""
+	sol.nvBitRate = bitRate
+	mc.sel.file = cfg.Sel.File
+	mc.sel.maxCount = cfg.Sel.Entries
+	mc.sel.wrap = cfg.Sel.Overflow == "wrap"
+	pef.filters = filters
+	return nil
+}
@@ -77,15 +77,21 @@ func getBtInterfaceCapabilties(msg *msgT) {
 }
 
 func getSystemGuid(msg *msgT) {
+	var data [17]uint8
+
 	// no session only allowed with authtype_none
 	if msg.rmcp.session.sid == 0 {
-		if msg.rmcp.session.authType != IPMI_AUTHTYPE_NONE {
+		if msg.rmcp.session.authType != IPMI_AUTHTYPE_NONE &&
+			msg.rmcp.session.authType != IPMI_AUTHTYPE_RMCP_PLUS {
 			fmt.Println("systemGuid - no session with authtype",
 				msg.rmcp.session.authType)
 			return
 		}
 	}
 
+	data[0] = 0
+	copy(data[1:], lanserv.guid[:])
+	msg.returnRspData(nil, data[0:17], 17)
 }
 
 func getChannelAuthCapabilties(msg *msgT) {
@@ -97,7 +103,8 @@ func getChannelAuthCapabilties(msg *msgT) {
 	// no session only allowed with authtype_none
 	if msg.rmcp.session.sid == 0 {
 
-		if msg.rmcp.session.authType != IPMI_AUTHTYPE_NONE {
+		if msg.rmcp.session.authType != IPMI_AUTHTYPE_NONE &&
+			msg.rmcp.session.authType != IPMI_AUTHTYPE_RMCP_PLUS {
 			fmt.Println("systemGuid - no session with authtype",
 				msg.rmcp.session.authType)
 			return
@@ -106,10 +113,6 @@ func getChannelAuthCapabilties(msg *msgT) {
 
 	dataStart := msg.dataStart
 	do_rmcpp := (msg.data[dataStart] >> 7) & 1
-	if do_rmcpp > 0 {
-		fmt.Println("get chan auth caps: rmcpp requested")
-		return
-	}
 
 	channel := msg.data[dataStart] & 0xf
 	priv := msg.data[msg.dataStart+1] & 0xf
@@ -129,11 +132,14 @@ func getChannelAuthCapabilties(msg *msgT) {
 		data[1] = channel
 		data[2] = 0x17 //HACK lanserv.chan_priv_allowed_auths[priv-1]
 		data[3] = 0x6  // HACK per-message authentication is on,
-		// Only RMCP for now (no RMCPP)
 		// user-level authenitcation is on,
 		// non-null user names disabled,
 		// no anonymous support.
 		data[4] = 0
+		if do_rmcpp > 0 && len(lanserv.cipherSuites) > 0 {
+			data[2] |= 0x80 // IPMI v2.0+ extended capabilities
+			data[4] = 0x3   // IPMI v1.5 and v2.0 connections
+		}
 		data[5] = 0
 		data[6] = 0
 		data[7] = 0
@@ -456,6 +462,9 @@ func closeSession(msg *msgT) {
 	msg.returnErr(session, 0)
 
 	// Cleanup the target session (which could be the same or not)
+	if sol.session == targetSess {
+		solDeactivate(session)
+	}
 	targetSess.active = false
 	lanserv.activeSessions--
 
@@ -502,15 +511,92 @@ func setUserPassword(msg *msgT) {
 }
 
 func activatePayload(msg *msgT) {
-	fmt.Println("appNetfn not supported", msg.rmcp.message.cmd)
+	var data [13]uint8
+
+	session := sidToSession(msg.sid)
+	if session == nil || !session.rmcpplus {
+		fmt.Printf("Activate payload - no session %x\n", msg.sid)
+		return
+	}
+	if msg.dataLen < msg.dataStart+6 {
+		msg.returnErr(session, IPMI_REQUEST_DATA_LENGTH_INVALID_CC)
+		return
+	}
+	dataStart := msg.dataStart
+	payload := msg.data[dataStart] & 0x3f
+	instance := msg.data[dataStart+1] & 0xf
+	encrypt := msg.data[dataStart+2]&0x80 != 0
+	switch {
+	case payload != RMCPP_PAYLOAD_SOL || instance != 1:
+		msg.returnErr(session, IPMI_INVALID_DATA_FIELD_CC)
+	case !sol.enable:
+		msg.returnErr(session, 0x81) // payload type disabled
+	case session.priv < sol.auth&0xf:
+		msg.returnErr(session, IPMI_INSUFFICIENT_PRIVILEGE_CC)
+	case sol.session != nil:
+		msg.returnErr(session, 0x80) // already active
+	case encrypt && session.conf == RMCPP_CONF_NONE:
+		msg.returnErr(session, 0x83) // can't with encryption
+	case (sol.auth&0x80 != 0 && !encrypt) ||
+		(sol.auth&0x40 != 0 && session.integ == RMCPP_INTEG_NONE):
+		msg.returnErr(session, 0x84) // can't without encryption
+	default:
+		if err := msg.solActivate(session); err != nil {
+			fmt.Println("Activate payload:", err)
+			msg.returnErr(session, IPMI_DESTINATION_UNAVAILABLE_CC)
+			return
+		}
+		data[0] = 0
+		binary.LittleEndian.PutUint16(data[5:7], SOL_PAYLOAD_SIZE)
+		binary.LittleEndian.PutUint16(data[7:9], SOL_PAYLOAD_SIZE)
+		binary.LittleEndian.PutUint16(data[9:11], 623)
+		binary.LittleEndian.PutUint16(data[11:13], 0xffff) // no VLAN
+		msg.returnRspData(session, data[0:13], 13)
+	}
 }
 
 func deavtivatePayload(msg *msgT) {
-	fmt.Println("appNetfn not supported", msg.rmcp.message.cmd)
+	session := sidToSession(msg.sid)
+	if session == nil || !session.rmcpplus {
+		fmt.Printf("Deactivate payload - no session %x\n", msg.sid)
+		return
+	}
+	if msg.dataLen < msg.dataStart+2 {
+		msg.returnErr(session, IPMI_REQUEST_DATA_LENGTH_INVALID_CC)
+		return
+	}
+	payload := msg.data[msg.dataStart] & 0x3f
+	instance := msg.data[msg.dataStart+1] & 0xf
+	if payload != RMCPP_PAYLOAD_SOL || instance != 1 {
+		msg.returnErr(session, IPMI_INVALID_DATA_FIELD_CC)
+	} else if sol.session == nil {
+		msg.returnErr(session, 0x80) // already deactivated
+	} else if sol.session != session &&
+		session.priv != IPMI_PRIVILEGE_ADMIN {
+		msg.returnErr(session, IPMI_INSUFFICIENT_PRIVILEGE_CC)
+	} else {
+		solDeactivate(session)
+		msg.returnErr(session, 0)
+	}
 }
 
 func getPayloadActivationStatus(msg *msgT) {
-	fmt.Println("appNetfn not supported", msg.rmcp.message.cmd)
+	var data [4]uint8
+
+	if msg.dataLen < msg.dataStart+1 {
+		msg.returnErr(nil, IPMI_REQUEST_DATA_LENGTH_INVALID_CC)
+		return
+	}
+	if msg.data[msg.dataStart]&0x3f != RMCPP_PAYLOAD_SOL {
+		msg.returnErr(nil, IPMI_INVALID_DATA_FIELD_CC)
+		return
+	}
+	data[0] = 0
+	data[1] = 1 // instance capacity
+	if sol.session != nil {
+		data[2] = 1 // instance 1 active
+	}
+	msg.returnRspData(nil, data[0:4], 4)
 }
 
 func getPayloadInstanceInfo(msg *msgT) {
@@ -542,16 +628,55 @@ func masterReadWrite(msg *msgT) {
 }
 
 func getChannelCipherSuites(msg *msgT) {
+	var (
+		data    [18]uint8
+		records []uint8
+	)
+
 	// no session only allowed with authtype_none
 	if msg.rmcp.session.sid == 0 {
 
-		if msg.rmcp.session.authType != IPMI_AUTHTYPE_NONE {
+		if msg.rmcp.session.authType != IPMI_AUTHTYPE_NONE &&
+			msg.rmcp.session.authType != IPMI_AUTHTYPE_RMCP_PLUS {
 			fmt.Println("system_guid - no session with authtype",
 				msg.rmcp.session.authType)
 			return
 		}
 	}
 
+	if msg.dataLen < msg.dataStart+3 {
+		msg.returnErr(nil, IPMI_REQUEST_DATA_LENGTH_INVALID_CC)
+		return
+	}
+	dataStart := msg.dataStart
+	channel := msg.data[dataStart] & 0xf
+	payload := msg.data[dataStart+1] & 0x3f
+	bySuite := (msg.data[dataStart+2] >> 7) & 1
+	index := int(msg.data[dataStart+2] & 0x3f)
+	if channel == 0xe { // means use "this channel"
+		channel = lanserv.chanNum
+	}
+	if channel != lanserv.chanNum || payload != RMCPP_PAYLOAD_IPMI {
+		msg.returnErr(nil, IPMI_INVALID_DATA_FIELD_CC)
+		return
+	}
+
+	// Standard cipher suite records, or the tagged algorithms of the
+	// cipher suites in 16 byte pages.
+	for _, id := range lanserv.cipherSuites {
+		cs := findCipherSuite(id)
+		if bySuite > 0 {
+			records = append(records, 0xc0, cs.id)
+		}
+		records = append(records, cs.auth, 0x40|cs.integ, 0x80|cs.conf)
+	}
+	data[0] = 0
+	data[1] = channel
+	n := 2
+	if index*16 < len(records) {
+		n += copy(data[2:], records[index*16:])
+	}
+	msg.returnRspData(nil, data[0:n], uint(n))
 }
 
 func suspendResumePayloadEncryption(msg *msgT) {
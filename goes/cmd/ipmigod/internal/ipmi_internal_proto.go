@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"fmt"
 	"net"
+	"time"
 )
 
 //
@@ -55,6 +56,8 @@ type lanservT struct {
 	nextChallSeq          uint32
 	sidSeq                uint32
 	defaultSessionTimeout uint32
+	guid                  [16]uint8
+	cipherSuites          []uint8
 	users                 [MAX_USERS + 1]userT
 	sessions              [MAX_SESSIONS + 1]sessionT
 }
@@ -124,9 +127,24 @@ type sessionT struct {
 	remSid        uint32
 	auth          uint8
 	conf          uint8
-	integ         uint
+	integ         uint8
 	priv          uint8
 	maxPriv       uint8
+
+	// sliding windows of sequence numbers received behind the last
+	recvWindow       uint32
+	unauthRecvWindow uint32
+
+	/* RAKP data */
+	started  time.Time
+	tag      uint8
+	role     uint8
+	username []uint8
+	rm       [16]uint8
+	rc       [16]uint8
+	sik      []uint8
+	k1       []uint8
+	k2       []uint8
 }
 
 type msgT struct {
@@ -171,7 +189,6 @@ type msgT struct {
 			cmd    uint8
 		}
 	}
-	// Not yet supported
 	rmcpp struct {
 		/* RMCP+ parms */
 		payload       uint8
@@ -179,6 +196,7 @@ type msgT struct {
 		authenticated uint8
 		iana          [3]uint8
 		payloadId     uint16
+		payloadLen    uint
 		authdata      *uint8
 		authdataLen   uint
 	}
@@ -227,7 +245,7 @@ func sidToSession(sid uint32) *sessionT {
 		return nil
 	}
 	session = &lanserv.sessions[idx]
-	if !session.active {
+	if !session.active || session.inStartup {
 		return nil
 	}
 	if session.sid != sid {
@@ -41,6 +41,96 @@ type sensorT struct {
 	eventStatus uint16
 }
 
+const (
+	PEF_FILTERS     = 16
+	PEF_FILTER_SIZE = 20
+
+	// PEF configuration parameters
+	PEF_PARM_SET_IN_PROGRESS = 0
+	PEF_PARM_CONTROL         = 1
+	PEF_PARM_ACTION_CONTROL  = 2
+	PEF_PARM_STARTUP_DELAY   = 3
+	PEF_PARM_ALERT_DELAY     = 4
+	PEF_PARM_FILTERS         = 5
+	PEF_PARM_FILTER          = 6
+	PEF_PARM_FILTER_DATA1    = 7
+
+	PEF_CONTROL_ENABLE       = 0x01
+	PEF_ACTION_ALERT         = 0x01
+	PEF_FILTER_ENABLE        = 0x80
+	PEF_ALERT_CHANNEL        = "ipmigod.alerts"
+	PEF_SOFTWARE_PROCESSED   = 0
+	PEF_BMC_PROCESSED        = 1
+	PEF_LAST_PROCESSED_NONE  = 0xffff
+	PEF_CONFIG_PARM_REVISION = 0x11
+)
+
+// Event filter table entry: config, action, alert policy, severity,
+// generator id 1 and 2, sensor type, sensor number, event trigger, event
+// data 1 offset mask, then the unsupported event data 1, 2, and 3 masks
+// and compares; 0xff generator, sensor, and trigger match any.
+type pefFilterT [PEF_FILTER_SIZE]uint8
+
+// Platform event filtering
+type pefT struct {
+	setInProgress uint8
+	control       uint8
+	actionControl uint8
+	filters       [PEF_FILTERS]pefFilterT
+	// Last record ids processed by software and the BMC.
+	lastProcessed    uint16
+	lastBmcProcessed uint16
+	// Published alerts or nil.
+	alerts chan<- string
+}
+
+var pef = pefT{
+	control:          PEF_CONTROL_ENABLE,
+	actionControl:    PEF_ACTION_ALERT,
+	lastProcessed:    PEF_LAST_PROCESSED_NONE,
+	lastBmcProcessed: PEF_LAST_PROCESSED_NONE,
+}
+
+// Returns true if the enabled filter matches the system event record.
+func (f *pefFilterT) match(e *selEntryT) bool {
+	any := func(want, have uint8) bool {
+		return want == 0xff || want == have
+	}
+	offsetMask := binary.LittleEndian.Uint16(f[9:11])
+	return f[0]&PEF_FILTER_ENABLE != 0 &&
+		e.data[2] == SEL_SYSTEM_EVENT_RECORD &&
+		any(f[4], e.data[7]) &&
+		any(f[5], e.data[8]) &&
+		any(f[6], e.data[10]) &&
+		any(f[7], e.data[11]) &&
+		any(f[8], e.data[12]&^SEL_DEASSERTION) &&
+		offsetMask&(1<<(e.data[13]&0xf)) != 0
+}
+
+// Alert the new SEL entry if it matches an alert filter.
+func pefProcess(e *selEntryT) {
+	if pef.control&PEF_CONTROL_ENABLE == 0 {
+		return
+	}
+	for i := range pef.filters {
+		f := &pef.filters[i]
+		if f[1]&PEF_ACTION_ALERT == 0 || !f.match(e) {
+			continue
+		}
+		if pef.actionControl&PEF_ACTION_ALERT != 0 {
+			s := fmt.Sprint("filter ", i+1, " severity ",
+				f[3], ": ", selString(e))
+			fmt.Println("PEF alert:", s)
+			select {
+			case pef.alerts <- s:
+			default:
+			}
+		}
+		break
+	}
+	pef.lastBmcProcessed = e.recordId
+}
+
 func setEventReceiver(msg *msgT) {
 	fmt.Println("sensorEventNetfn not supported",
 		msg.rmcp.message.cmd)
@@ -52,13 +142,31 @@ func getEventReceiver(msg *msgT) {
 }
 
 func platformEvent(msg *msgT) {
-	fmt.Println("sensorEventNetfn not supported",
-		msg.rmcp.message.cmd)
+	var (
+		data [1]uint8
+		rec  [SEL_ENTRY_SIZE]uint8
+	)
+
+	// The generator is the requester.
+	if msg.dataLen < msg.dataStart+7 {
+		msg.returnErr(nil, IPMI_REQUEST_DATA_LENGTH_INVALID_CC)
+		return
+	}
+	req := msg.data[msg.dataStart : msg.dataStart+7]
+	rec[7] = msg.rmcp.message.rqAddr
+	rec[8] = msg.rmcp.message.rqLun
+	copy(rec[9:], req)
+	if err, _ := addToSel(SEL_SYSTEM_EVENT_RECORD, rec[:]); err != 0 {
+		msg.returnErr(nil, uint8(err))
+		return
+	}
+	msg.returnRspData(nil, data[0:1], 1)
 }
 
 func getPefCapabilities(msg *msgT) {
-	fmt.Println("sensorEventNetfn not supported",
-		msg.rmcp.message.cmd)
+	data := [4]uint8{0, 0x51, PEF_ACTION_ALERT, PEF_FILTERS}
+
+	msg.returnRspData(nil, data[0:4], 4)
 }
 
 func armPefPostponeTimer(msg *msgT) {
@@ -67,23 +175,124 @@ func armPefPostponeTimer(msg *msgT) {
 }
 
 func setPefConfigParms(msg *msgT) {
-	fmt.Println("sensorEventNetfn not supported",
-		msg.rmcp.message.cmd)
+	var data [1]uint8
+
+	session := msg.privileged(IPMI_PRIVILEGE_ADMIN)
+	if session == nil {
+		return
+	}
+	req := msg.data[msg.dataStart:msg.dataLen]
+	if len(req) < 2 {
+		msg.returnErr(session, IPMI_REQUEST_DATA_LENGTH_INVALID_CC)
+		return
+	}
+	parm, val := req[0]&0x7f, req[1:]
+	switch parm {
+	case PEF_PARM_SET_IN_PROGRESS:
+		pef.setInProgress = val[0] & 0x3
+	case PEF_PARM_CONTROL:
+		pef.control = val[0] & 0xf
+	case PEF_PARM_ACTION_CONTROL:
+		pef.actionControl = val[0] & 0x3f
+	case PEF_PARM_FILTERS:
+		msg.returnErr(session, 0x82) // read-only parameter
+		return
+	case PEF_PARM_FILTER, PEF_PARM_FILTER_DATA1:
+		n := int(val[0] & 0x7f)
+		if n == 0 || n > PEF_FILTERS {
+			msg.returnErr(session, IPMI_PARAMETER_OUT_OF_RANGE_CC)
+			return
+		}
+		size := 1 + PEF_FILTER_SIZE
+		if parm == PEF_PARM_FILTER_DATA1 {
+			size = 2
+		}
+		if len(val) < size {
+			msg.returnErr(session,
+				IPMI_REQUEST_DATA_LENGTH_INVALID_CC)
+			return
+		}
+		copy(pef.filters[n-1][:], val[1:size])
+	default:
+		msg.returnErr(session, 0x80) // parameter not supported
+		return
+	}
+	msg.returnRspData(session, data[0:1], 1)
 }
 
 func getPefConfigParms(msg *msgT) {
-	fmt.Println("sensorEventNetfn not supported",
-		msg.rmcp.message.cmd)
+	if msg.dataLen < msg.dataStart+3 {
+		msg.returnErr(nil, IPMI_REQUEST_DATA_LENGTH_INVALID_CC)
+		return
+	}
+	parm := msg.data[msg.dataStart]
+	set := msg.data[msg.dataStart+1] & 0x7f
+	data := []uint8{0, PEF_CONFIG_PARM_REVISION}
+	if parm&0x80 != 0 {
+		// revision only
+		msg.returnRspData(nil, data, uint(len(data)))
+		return
+	}
+	switch parm {
+	case PEF_PARM_SET_IN_PROGRESS:
+		data = append(data, pef.setInProgress)
+	case PEF_PARM_CONTROL:
+		data = append(data, pef.control)
+	case PEF_PARM_ACTION_CONTROL:
+		data = append(data, pef.actionControl)
+	case PEF_PARM_FILTERS:
+		data = append(data, PEF_FILTERS)
+	case PEF_PARM_FILTER, PEF_PARM_FILTER_DATA1:
+		if set == 0 || set > PEF_FILTERS {
+			msg.returnErr(nil, IPMI_PARAMETER_OUT_OF_RANGE_CC)
+			return
+		}
+		f := pef.filters[set-1][:]
+		if parm == PEF_PARM_FILTER_DATA1 {
+			f = f[:1]
+		}
+		data = append(append(data, set), f...)
+	default:
+		msg.returnErr(nil, 0x80) // parameter not supported
+		return
+	}
+	msg.returnRspData(nil, data, uint(len(data)))
 }
 
 func setLastProcessedEventId(msg *msgT) {
-	fmt.Println("sensorEventNetfn not supported",
-		msg.rmcp.message.cmd)
+	var data [1]uint8
+
+	session := msg.privileged(IPMI_PRIVILEGE_ADMIN)
+	if session == nil {
+		return
+	}
+	if msg.dataLen < msg.dataStart+3 {
+		msg.returnErr(session, IPMI_REQUEST_DATA_LENGTH_INVALID_CC)
+		return
+	}
+	dataStart := msg.dataStart
+	recordId :=
+		binary.LittleEndian.Uint16(msg.data[dataStart+1 : dataStart+3])
+	if msg.data[dataStart]&1 == PEF_BMC_PROCESSED {
+		pef.lastBmcProcessed = recordId
+	} else {
+		pef.lastProcessed = recordId
+	}
+	msg.returnRspData(session, data[0:1], 1)
 }
 
 func getLastProcessedEventId(msg *msgT) {
-	fmt.Println("sensorEventNetfn not supported",
-		msg.rmcp.message.cmd)
+	var data [11]uint8
+
+	lastRecordId := uint16(0xffff)
+	if mc.sel.count > 0 {
+		lastRecordId = mc.sel.entries[mc.sel.count-1].recordId
+	}
+	binary.LittleEndian.PutUint32(data[1:5], mc.sel.lastAddTime)
+	binary.LittleEndian.PutUint16(data[5:7], lastRecordId)
+	binary.LittleEndian.PutUint16(data[7:9], pef.lastProcessed)
+	binary.LittleEndian.PutUint16(data[9:11], pef.lastBmcProcessed)
+	msg.returnRspData(nil, data[0:11], 11)
 }
 
 func alertImmediate(msg *msgT) {
@@ -332,16 +332,26 @@ func getSelInfo(msg *msgT) {
 	data[1] = 0x51
 	binary.LittleEndian.PutUint16(data[2:4], mc.sel.count)
 	binary.LittleEndian.PutUint16(data[4:6],
-		(mc.sel.maxCount-mc.sel.count)*16)
+		(mc.sel.maxCount-mc.sel.count)*SEL_ENTRY_SIZE)
 	binary.LittleEndian.PutUint32(data[6:10], mc.sel.lastAddTime)
 	binary.LittleEndian.PutUint32(data[10:14], mc.sel.lastEraseTime)
-	data[14] = mc.sel.flags
+	data[14] = mc.sel.flags | SEL_DELETE_SUPPORTED |
+		SEL_RESERVE_SUPPORTED | SEL_ALLOC_INFO_SUPPORTED
 
 	msg.returnRspData(nil, data[0:15], 15)
 }
 
 func getSelAllocationInfo(msg *msgT) {
-	fmt.Println("storageNetfn not supported", msg.rmcp.message.cmd)
+	var data [10]uint8
+
+	free := mc.sel.maxCount - mc.sel.count
+	binary.LittleEndian.PutUint16(data[1:3], mc.sel.maxCount)
+	binary.LittleEndian.PutUint16(data[3:5], SEL_ENTRY_SIZE)
+	binary.LittleEndian.PutUint16(data[5:7], free)
+	binary.LittleEndian.PutUint16(data[7:9], free)
+	data[9] = 1 // maximum record size in allocation units
+
+	msg.returnRspData(nil, data[0:10], 10)
 }
 
 func reserveSel(msg *msgT) {
@@ -359,10 +369,28 @@ func reserveSel(msg *msgT) {
 	msg.returnRspData(nil, data[0:3], 3)
 }
 
+// Returns the index of the SEL entry with the record id, where 0 is the
+// first and 0xffff the last, or -1 if not present.
+func selIndex(recordId uint16) int {
+	switch {
+	case mc.sel.count == 0:
+		return -1
+	case recordId == 0:
+		return 0
+	case recordId == 0xffff:
+		return int(mc.sel.count) - 1
+	}
+	for i := range mc.sel.entries {
+		if mc.sel.entries[i].recordId == recordId {
+			return i
+		}
+	}
+	return -1
+}
+
 func getSelEntry(msg *msgT) {
 	var (
 		nextRecordId uint16
-		entry        selEntryT
 		data         [19]uint8
 	)
 
@@ -379,52 +407,28 @@ func getSelEntry(msg *msgT) {
 	offset := msg.data[dataStart+4]
 	count := msg.data[dataStart+5]
 
-	if offset >= 16 {
+	if offset >= SEL_ENTRY_SIZE {
 		msg.returnErr(nil, IPMI_INVALID_DATA_FIELD_CC)
 		return
 	}
 
-	if mc.sel.count == 0 {
+	i := selIndex(recordId)
+	if i < 0 {
 		msg.returnErr(nil, IPMI_NOT_PRESENT_CC)
 		return
 	}
-
-	// record id of 0 means 1st one in sel; 0xFF means last.
-	if recordId == 0 {
-		entry = mc.sel.entries[0] // 0th entry is valid
-		if mc.sel.count-1 > 0 {
-			nextRecordId = 2
-		} else {
-			nextRecordId = 0xffff
-		}
-	} else if recordId == 0xffff {
-		entry = mc.sel.entries[mc.sel.count-1]
-		nextRecordId = 0xffff
+	entry := mc.sel.entries[i]
+	if i+1 < int(mc.sel.count) {
+		nextRecordId = mc.sel.entries[i+1].recordId
 	} else {
-		for i := range mc.sel.entries {
-			if mc.sel.entries[i].recordId == recordId {
-				entry = mc.sel.entries[i]
-				if i+1 >= int(mc.sel.count) {
-					nextRecordId = 0xffff
-				} else {
-					nextRecordId = uint16(i + 2)
-				}
-				break
-			}
-		}
-	}
-
-	// Nothing found ?
-	if entry.recordId == 0 {
-		msg.returnErr(nil, IPMI_NOT_PRESENT_CC)
-		return
+		nextRecordId = 0xffff
 	}
 
 	data[0] = 0
 	binary.LittleEndian.PutUint16(data[1:3], nextRecordId)
 
-	if (offset + count) > 16 {
-		count = 16 - offset
+	if (offset + count) > SEL_ENTRY_SIZE {
+		count = SEL_ENTRY_SIZE - offset
 	}
 	copy(data[3:], entry.data[offset:offset+count])
 	retLen := count + 3
@@ -435,9 +439,13 @@ func getSelEntry(msg *msgT) {
 func addSelEntry(msg *msgT) {
 	var data [19]uint8
 
+	if msg.dataLen < msg.dataStart+SEL_ENTRY_SIZE {
+		msg.returnErr(nil, IPMI_REQUEST_DATA_LENGTH_INVALID_CC)
+		return
+	}
 	dataStart := msg.dataStart
 	err, r := addToSel(msg.data[dataStart+2],
-		msg.data[dataStart+3:dataStart+16])
+		msg.data[dataStart:dataStart+SEL_ENTRY_SIZE])
 	if err != 0 {
 		msg.returnErr(nil, uint8(err))
 		return
@@ -453,56 +461,110 @@ func partialAddSelEntry(msg *msgT) {
 }
 
 func deleteSelEntry(msg *msgT) {
-	fmt.Println("storageNetfn not supported", msg.rmcp.message.cmd)
+	var data [3]uint8
+
+	session := msg.privileged(IPMI_PRIVILEGE_OPERATOR)
+	if session == nil {
+		return
+	}
+	if msg.dataLen < msg.dataStart+4 {
+		msg.returnErr(session, IPMI_REQUEST_DATA_LENGTH_INVALID_CC)
+		return
+	}
+	dataStart := msg.dataStart
+	reservation :=
+		binary.LittleEndian.Uint16(msg.data[dataStart : dataStart+2])
+	if reservation != mc.sel.reservation {
+		msg.returnErr(session, IPMI_INVALID_RESERVATION_CC)
+		return
+	}
+	recordId :=
+		binary.LittleEndian.Uint16(msg.data[dataStart+2 : dataStart+4])
+	i := selIndex(recordId)
+	if i < 0 {
+		msg.returnErr(session, IPMI_NOT_PRESENT_CC)
+		return
+	}
+	recordId = mc.sel.entries[i].recordId
+	mc.sel.entries = append(mc.sel.entries[:i], mc.sel.entries[i+1:]...)
+	mc.sel.count--
+	mc.sel.lastEraseTime = selNow()
+	selSave()
+
+	data[0] = 0
+	binary.LittleEndian.PutUint16(data[1:3], recordId)
+	msg.returnRspData(session, data[0:3], 3)
 }
 
 func clearSel(msg *msgT) {
 
 	var data [2]uint8
+
+	session := msg.privileged(IPMI_PRIVILEGE_OPERATOR)
+	if session == nil {
+		return
+	}
+	if msg.dataLen < msg.dataStart+6 {
+		msg.returnErr(session, IPMI_REQUEST_DATA_LENGTH_INVALID_CC)
+		return
+	}
 	dataStart := msg.dataStart
 	reservation :=
 		binary.LittleEndian.Uint16(msg.data[dataStart : dataStart+2])
 	if (reservation != 0) && (reservation != mc.sel.reservation) {
-		msg.returnErr(nil, IPMI_INVALID_RESERVATION_CC)
+		msg.returnErr(session, IPMI_INVALID_RESERVATION_CC)
 		return
 	}
 
 	if (msg.data[dataStart+2] != 'C') ||
 		(msg.data[dataStart+3] != 'L') ||
 		(msg.data[dataStart+4] != 'R') {
-		msg.returnErr(nil, IPMI_INVALID_DATA_FIELD_CC)
+		msg.returnErr(session, IPMI_INVALID_DATA_FIELD_CC)
 		return
 	}
 
 	op := msg.data[dataStart+5]
 	if op != 0 && op != 0xaa {
-		msg.returnErr(nil, IPMI_INVALID_DATA_FIELD_CC)
+		msg.returnErr(session, IPMI_INVALID_DATA_FIELD_CC)
 		return
 	}
 
 	data[0] = 0
-	data[1] = 1
+	data[1] = SEL_ERASE_COMPLETED
 	if op == 0xaa {
-		mc.sel.entries = nil
-		// nil'ing causes capacity to go to 0 so remake
-		mc.sel.entries = make([]selEntryT, mc.sel.maxCount)
-
-		now := time.Now()
-		nowUnix := uint32(now.Unix())
-		mc.sel.lastEraseTime = nowUnix
+		mc.sel.entries = make([]selEntryT, 0, mc.sel.maxCount)
+		mc.sel.count = 0
+		mc.sel.lastEraseTime = selNow()
 		// Clear the overflow flag.
-		mc.sel.flags &^= 0x80
+		mc.sel.flags &^= SEL_OVERFLOW
+		selSave()
 	}
 
-	msg.returnRspData(nil, data[0:2], 2)
+	msg.returnRspData(session, data[0:2], 2)
 }
 
 func getSelTime(msg *msgT) {
-	fmt.Println("storageNetfn not supported", msg.rmcp.message.cmd)
+	var data [5]uint8
+
+	binary.LittleEndian.PutUint32(data[1:5], selNow())
+	msg.returnRspData(nil, data[0:5], 5)
 }
 
 func setSelTime(msg *msgT) {
-	fmt.Println("storageNetfn not supported", msg.rmcp.message.cmd)
+	var data [1]uint8
+
+	session := msg.privileged(IPMI_PRIVILEGE_OPERATOR)
+	if session == nil {
+		return
+	}
+	if msg.dataLen < msg.dataStart+4 {
+		msg.returnErr(session, IPMI_REQUEST_DATA_LENGTH_INVALID_CC)
+		return
+	}
+	dataStart := msg.dataStart
+	t := binary.LittleEndian.Uint32(msg.data[dataStart : dataStart+4])
+	mc.sel.timeOffset = int64(t) - time.Now().Unix()
+	msg.returnRspData(session, data[0:1], 1)
 }
 
 func getAuxiliaryLogStatus(msg *msgT) {
@@ -5,6 +5,11 @@
 // Package contains IPMI 2.0 spec protocol definitions
 package internal
 
+import (
+	"encoding/binary"
+	"fmt"
+)
+
 func setLanConfigParms(msg *msgT) {
 }
 
@@ -57,7 +62,138 @@ func solActivating(msg *msgT) {
 }
 
 func setSolConfigurationParameters(msg *msgT) {
+	session := sidToSession(msg.sid)
+	if session == nil {
+		fmt.Printf("Set SOL config - no session %x\n", msg.sid)
+		return
+	}
+	if session.priv < IPMI_PRIVILEGE_ADMIN {
+		msg.returnErr(session, IPMI_INSUFFICIENT_PRIVILEGE_CC)
+		return
+	}
+	if msg.dataLen < msg.dataStart+3 {
+		msg.returnErr(session, IPMI_REQUEST_DATA_LENGTH_INVALID_CC)
+		return
+	}
+	dataStart := msg.dataStart
+	channel := msg.data[dataStart] & 0xf
+	if channel == 0xe { // means use "this channel"
+		channel = lanserv.chanNum
+	}
+	if channel != lanserv.chanNum {
+		msg.returnErr(session, IPMI_INVALID_DATA_FIELD_CC)
+		return
+	}
+	parm := msg.data[dataStart+1]
+	data := msg.data[dataStart+2 : msg.dataLen]
+	cc := uint8(0)
+	switch parm {
+	case SOL_PARM_SET_IN_PROGRESS:
+		if data[0]&0x3 == 1 && sol.setInProgress == 1 {
+			cc = 0x81 // set in progress
+		} else if data[0]&0x3 > 1 {
+			cc = IPMI_INVALID_DATA_FIELD_CC
+		} else {
+			sol.setInProgress = data[0] & 0x3
+		}
+	case SOL_PARM_ENABLE:
+		sol.enable = data[0]&1 == 1 && sol.device != ""
+	case SOL_PARM_AUTH:
+		priv := data[0] & 0xf
+		if priv < IPMI_PRIVILEGE_USER || priv > IPMI_PRIVILEGE_OEM {
+			cc = IPMI_INVALID_DATA_FIELD_CC
+		} else {
+			sol.auth = data[0] & 0xcf
+		}
+	case SOL_PARM_ACCUMULATE:
+		if len(data) < 2 || data[0] == 0 {
+			cc = IPMI_INVALID_DATA_FIELD_CC
+		} else {
+			sol.accumulateInterval = data[0]
+			sol.sendThreshold = data[1]
+		}
+	case SOL_PARM_RETRY:
+		if len(data) < 2 {
+			cc = IPMI_REQUEST_DATA_LENGTH_INVALID_CC
+		} else {
+			sol.retryCount = data[0] & 0x7
+			sol.retryInterval = data[1]
+		}
+	case SOL_PARM_NV_BIT_RATE, SOL_PARM_BIT_RATE:
+		rate := data[0] & 0xf
+		if _, found := solBauds[rate]; !found &&
+			(rate != 0 || parm == SOL_PARM_NV_BIT_RATE) {
+			cc = IPMI_INVALID_DATA_FIELD_CC
+		} else if parm == SOL_PARM_NV_BIT_RATE {
+			sol.nvBitRate = rate
+		} else {
+			sol.bitRate = rate
+		}
+	case SOL_PARM_PAYLOAD_CHANNEL, SOL_PARM_PAYLOAD_PORT:
+		cc = 0x82 // read-only
+	default:
+		cc = 0x80 // parameter not supported
+	}
+	msg.returnErr(session, cc)
 }
 
 func getSolConfigurationParameters(msg *msgT) {
+	var data [4]uint8
+
+	if msg.dataLen < msg.dataStart+4 {
+		msg.returnErr(nil, IPMI_REQUEST_DATA_LENGTH_INVALID_CC)
+		return
+	}
+	dataStart := msg.dataStart
+	revOnly := msg.data[dataStart]&0x80 != 0
+	channel := msg.data[dataStart] & 0xf
+	if channel == 0xe { // means use "this channel"
+		channel = lanserv.chanNum
+	}
+	if channel != lanserv.chanNum {
+		msg.returnErr(nil, IPMI_INVALID_DATA_FIELD_CC)
+		return
+	}
+	data[0] = 0
+	data[1] = 0x11 // parameter revision
+	n := 2
+	if !revOnly {
+		switch msg.data[dataStart+1] {
+		case SOL_PARM_SET_IN_PROGRESS:
+			data[n] = sol.setInProgress
+			n++
+		case SOL_PARM_ENABLE:
+			if sol.enable {
+				data[n] = 1
+			}
+			n++
+		case SOL_PARM_AUTH:
+			data[n] = sol.auth
+			n++
+		case SOL_PARM_ACCUMULATE:
+			data[n] = sol.accumulateInterval
+			data[n+1] = sol.sendThreshold
+			n += 2
+		case SOL_PARM_RETRY:
+			data[n] = sol.retryCount
+			data[n+1] = sol.retryInterval
+			n += 2
+		case SOL_PARM_NV_BIT_RATE:
+			data[n] = sol.nvBitRate
+			n++
+		case SOL_PARM_BIT_RATE:
+			data[n] = sol.bitRate
+			n++
+		case SOL_PARM_PAYLOAD_CHANNEL:
+			data[n] = lanserv.chanNum
+			n++
+		case SOL_PARM_PAYLOAD_PORT:
+			binary.LittleEndian.PutUint16(data[n:n+2], 623)
+			n += 2
+		default:
+			msg.returnErr(nil, 0x80) // parameter not supported
+			return
+		}
+	}
+	msg.returnRspData(nil, data[0:n], uint(n))
 }
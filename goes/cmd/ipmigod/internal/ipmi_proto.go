@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"sync"
 )
 
 const (
@@ -20,6 +21,9 @@ var Signaled = func() bool { return false }
 var service string = "10.0.0.3:623"
 var chassisCardNum uint8
 
+// Held while handling a message or SOL event.
+var lanMutex sync.Mutex
+
 // Open a UDP/IPMI connection as a client to configured MM-BMC IP-addr
 // and run through a sequence of well-known setup calls
 // This connection is cached for further use by SDR
@@ -50,32 +54,7 @@ func ipmiMMConnect() error {
 
 func ipmiLanInit() {
 
-	// Initialize user database for straight authentication
-	lanserv.users[1].idx = 1
-	lanserv.users[1].username = make([]uint8, 16)
-	copy(lanserv.users[1].username[0:], "")
-	lanserv.users[1].pw = make([]uint8, 16)
-	copy(lanserv.users[1].pw[0:], "test")
-	lanserv.users[1].maxPriv = IPMI_PRIVILEGE_USER
-	lanserv.users[1].allowedAuths = (1 << IPMI_AUTHTYPE_NONE) |
-		//(1 << AUTHTYPE_MD2) |
-		//(1 << AUTHTYPE_MD5) |
-		(1 << IPMI_AUTHTYPE_STRAIGHT)
-	lanserv.users[1].valid = true
-	lanserv.users[2].idx = 2
-	lanserv.users[2].username = make([]uint8, 16)
-	copy(lanserv.users[2].username[0:], "ipmiusr")
-	lanserv.users[2].pw = make([]uint8, 16)
-	copy(lanserv.users[2].pw[0:], "test")
-	lanserv.users[2].maxPriv = IPMI_PRIVILEGE_ADMIN
-	lanserv.users[2].allowedAuths = (1 << IPMI_AUTHTYPE_NONE) //|
-	//(1 << AUTHTYPE_MD2) |
-	//(1 << AUTHTYPE_MD5) |
-	//(1 << IPMI_AUTHTYPE_STRAIGHT)
-	lanserv.users[2].valid = true
-
 	lanserv.chanNum = 1
-	lanserv.defaultSessionTimeout = 30
 	lanserv.sidSeq = 0
 	lanserv.nextChallSeq = 0
 	lanserv.chanPrivLimit = IPMI_PRIVILEGE_ADMIN
@@ -108,8 +87,11 @@ func init() {
 	// allowed_auths_operator none md2 md5 straight
 	// allowed_auths_admin none md2 md5 straight
 	// guid a123456789abcdefa123456789abcdef
-	//  user 2 true  "ipmiusr" "test" admin    10 none md2 md5 straight
+	// and users, cipher suites, etc. from DefaultConfig until LoadConfig
 	ipmiLanInit()
+	if err := ParseConfig([]byte(DefaultConfig)); err != nil {
+		panic(err)
+	}
 }
 
 func Ipmigod(mmCardMode bool, cardNum int) {
@@ -179,6 +161,9 @@ func Ipmigod(mmCardMode bool, cardNum int) {
 
 func (msg *msgT) ipmiHandleMsg() {
 
+	lanMutex.Lock()
+	defer lanMutex.Unlock()
+
 	if msg.dataLen < 5 {
 		fmt.Printf("LAN msg failure: message too short %d",
 			msg.dataLen)
@@ -188,40 +173,61 @@ func (msg *msgT) ipmiHandleMsg() {
 
 	// Parse incoming IPMI packet (including error checks)
 	// and load up msg struct
-	msg.ipmiParseMsg()
+	if !msg.ipmiParseMsg() {
+		return
+	}
 
 	if msg.authtype == IPMI_AUTHTYPE_RMCP_PLUS {
-		//ipmi_handle_rmcpp_msg(lan, &msg);
-		fmt.Println("Received RMCP+ message!")
+		if len(lanserv.cipherSuites) == 0 {
+			if debug {
+				fmt.Println("RMCP+ disabled")
+			}
+			return
+		}
+		if debug {
+			fmt.Println("Received RMCP+ message!")
+		}
+		msg.ipmiHandleRmcppMsg()
 	} else {
 		if debug {
 			fmt.Println("Received RMCP message!")
 		}
-		(netfuncProcessors[msg.rmcp.message.netfn])(msg)
+		msg.ipmiDispatch()
 	}
 }
 
-func (msg *msgT) ipmiParseMsg() {
+func (msg *msgT) ipmiDispatch() {
+	processor, found := netfuncProcessors[msg.rmcp.message.netfn]
+	if !found {
+		fmt.Println("netfn not supported", msg.rmcp.message.netfn)
+		return
+	}
+	processor(msg)
+}
+
+func (msg *msgT) ipmiParseMsg() bool {
 	dataStart := msg.dataStart
 
 	if msg.data[dataStart+3] == 6 {
 		// Handle ASF ping message
 		asfPing(msg)
 	} else if msg.data[dataStart+3] == 7 {
+		if !msg.ipmiParseRmcpHdr() {
+			return false
+		}
 		// Peek ahead to see if we have an RMCP or RMCP+ message
-		if msg.data[dataStart+4] == IPMI_AUTHTYPE_RMCP_PLUS {
-			fmt.Println("LAN msg not supported RMCP+")
-			//ipmi_parse_rmcpp_msg(msg)
-		} else {
-			msg.ipmiParseRmcpMsg()
+		if msg.data[msg.dataStart] == IPMI_AUTHTYPE_RMCP_PLUS {
+			return msg.ipmiParseRmcppMsg()
 		}
+		return msg.ipmiParseRmcpMsg()
 	} else {
 		fmt.Println("LAN msg has unsupported class",
 			msg.data[dataStart+3])
 	}
+	return false
 }
 
-func (msg *msgT) ipmiParseRmcpMsg() {
+func (msg *msgT) ipmiParseRmcpHdr() bool {
 	dataStart := msg.dataStart
 
 	// Load RMCP header
@@ -229,11 +235,20 @@ func (msg *msgT) ipmiParseRmcpMsg() {
 	msg.rmcp.hdr.rmcpSeq = msg.data[dataStart+2]
 	msg.rmcp.hdr.class = msg.data[dataStart+3]
 	msg.dataStart += 4
-	dataStart = msg.dataStart
 
 	if msg.rmcp.hdr.rmcpSeq != 0xff {
 		fmt.Println("LAN msg failure: seq not ff")
-		return /* Sequence # must be ff (no ack) */
+		return false /* Sequence # must be ff (no ack) */
+	}
+	return true
+}
+
+func (msg *msgT) ipmiParseRmcpMsg() bool {
+	dataStart := msg.dataStart
+
+	if msg.dataLen < dataStart+10 {
+		fmt.Println("LAN msg failure: message too short", msg.dataLen)
+		return false
 	}
 
 	// Load IPMI Session fields
@@ -256,7 +271,16 @@ func (msg *msgT) ipmiParseRmcpMsg() {
 	} else {
 		msg.dataStart += 10
 	}
-	dataStart = msg.dataStart
+	if msg.dataLen < msg.dataStart+7 {
+		fmt.Println("LAN msg failure: message too short", msg.dataLen)
+		return false
+	}
+	msg.ipmiParseIpmiMsg()
+	return true
+}
+
+func (msg *msgT) ipmiParseIpmiMsg() {
+	dataStart := msg.dataStart
 
 	// Load IPMI Message fields
 	msg.rmcp.message.rsAddr = msg.data[dataStart]
@@ -280,9 +304,9 @@ func (msg *msgT) returnRsp(session *sessionT, rsp *rspMsgDataT) {
 	if session == nil {
 		session = sidToSession(msg.sid)
 	}
-	if session != nil && session.rmcpplus {
-		//rmcp plus not currently supported
-		fmt.Println("RMCP+ returnRsp not supported!")
+	if (session != nil && session.rmcpplus) ||
+		msg.authtype == IPMI_AUTHTYPE_RMCP_PLUS {
+		msg.rmcppReturnRsp(session, rsp)
 		return
 	} else if msg.sid == 0 {
 		session = &dummySession
@@ -631,8 +655,13 @@ var transportProcessors = map[uint8]transportProcessor{
 }
 
 func transportNetfn(msg *msgT) {
-	fmt.Println("transportNetfn not supported",
-		msg.rmcp.message.cmd)
+	processor, found := transportProcessors[msg.rmcp.message.cmd]
+	if !found {
+		fmt.Println("transportNetfn not supported",
+			msg.rmcp.message.cmd)
+		return
+	}
+	processor(msg)
 }
 
 func groupExtensionNetfn(msg *msgT) {
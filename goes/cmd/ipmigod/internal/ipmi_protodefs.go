@@ -257,3 +257,51 @@ const (
 	IPMI_PRIVILEGE_ADMIN    = 4
 	IPMI_PRIVILEGE_OEM      = 5
 )
+
+// RMCP+ payload types
+const (
+	RMCPP_PAYLOAD_IPMI             = 0x00
+	RMCPP_PAYLOAD_SOL              = 0x01
+	RMCPP_PAYLOAD_OEM              = 0x02
+	RMCPP_PAYLOAD_OPEN_SESSION_REQ = 0x10
+	RMCPP_PAYLOAD_OPEN_SESSION_RSP = 0x11
+	RMCPP_PAYLOAD_RAKP1            = 0x12
+	RMCPP_PAYLOAD_RAKP2            = 0x13
+	RMCPP_PAYLOAD_RAKP3            = 0x14
+	RMCPP_PAYLOAD_RAKP4            = 0x15
+
+	RMCPP_PAYLOAD_TYPE_MASK     = 0x3f
+	RMCPP_PAYLOAD_AUTHENTICATED = 0x40
+	RMCPP_PAYLOAD_ENCRYPTED     = 0x80
+)
+
+// RMCP+ authentication, integrity, and confidentiality algorithms
+const (
+	RMCPP_AUTH_NONE             = 0
+	RMCPP_AUTH_HMAC_SHA1        = 1
+	RMCPP_AUTH_HMAC_SHA256      = 3
+	RMCPP_INTEG_NONE            = 0
+	RMCPP_INTEG_HMAC_SHA1_96    = 1
+	RMCPP_INTEG_HMAC_SHA256_128 = 4
+	RMCPP_CONF_NONE             = 0
+	RMCPP_CONF_AES_CBC_128      = 1
+)
+
+// RMCP+ and RAKP message status codes
+const (
+	RMCPP_STATUS_OK                      = 0x00
+	RMCPP_STATUS_INSUFFICIENT_RESOURCES  = 0x01
+	RMCPP_STATUS_INVALID_SESSION_ID      = 0x02
+	RMCPP_STATUS_INVALID_PAYLOAD_TYPE    = 0x03
+	RMCPP_STATUS_INVALID_AUTH_ALGORITHM  = 0x04
+	RMCPP_STATUS_INVALID_INTEG_ALGORITHM = 0x05
+	RMCPP_STATUS_INACTIVE_SESSION_ID     = 0x08
+	RMCPP_STATUS_INVALID_ROLE            = 0x09
+	RMCPP_STATUS_UNAUTHORIZED_ROLE       = 0x0a
+	RMCPP_STATUS_INVALID_NAME_LENGTH     = 0x0c
+	RMCPP_STATUS_UNAUTHORIZED_NAME       = 0x0d
+	RMCPP_STATUS_INVALID_INTEGRITY_CHECK = 0x0f
+	RMCPP_STATUS_INVALID_CONF_ALGORITHM  = 0x10
+	RMCPP_STATUS_NO_CIPHER_SUITE_MATCH   = 0x11
+	RMCPP_STATUS_ILLEGAL_PARAMETER       = 0x12
+)
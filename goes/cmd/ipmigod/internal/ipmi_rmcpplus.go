@@ -0,0 +1,680 @@
+// Copyright 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package contains IPMI 2.0 spec implementation
+package internal
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"time"
+)
+
+const (
+	RMCPP_HDR_LEN     = 12 // authtype through payload length
+	RMCPP_NEXT_HEADER = 0x07
+	// Sequence numbers may be this far ahead of the last received...
+	RMCPP_SEQ_AHEAD = 16
+	// ...or, if not yet received, this far behind.
+	RMCPP_SEQ_BEHIND = 8
+)
+
+type cipherSuiteT struct {
+	id    uint8
+	auth  uint8
+	integ uint8
+	conf  uint8
+}
+
+// Cipher suites with authenticated key exchange; those without (0) are
+// not supported.
+var cipherSuites = []cipherSuiteT{
+	{1, RMCPP_AUTH_HMAC_SHA1, RMCPP_INTEG_NONE, RMCPP_CONF_NONE},
+	{2, RMCPP_AUTH_HMAC_SHA1, RMCPP_INTEG_HMAC_SHA1_96, RMCPP_CONF_NONE},
+	{3, RMCPP_AUTH_HMAC_SHA1, RMCPP_INTEG_HMAC_SHA1_96,
+		RMCPP_CONF_AES_CBC_128},
+	{15, RMCPP_AUTH_HMAC_SHA256, RMCPP_INTEG_NONE, RMCPP_CONF_NONE},
+	{16, RMCPP_AUTH_HMAC_SHA256, RMCPP_INTEG_HMAC_SHA256_128,
+		RMCPP_CONF_NONE},
+	{17, RMCPP_AUTH_HMAC_SHA256, RMCPP_INTEG_HMAC_SHA256_128,
+		RMCPP_CONF_AES_CBC_128},
+}
+
+func findCipherSuite(id uint8) *cipherSuiteT {
+	for i := range cipherSuites {
+		if cipherSuites[i].id == id {
+			return &cipherSuites[i]
+		}
+	}
+	return nil
+}
+
+// Returns the first enabled cipher suite with the proposed algorithms; an
+// algorithm payload of zero length proposes any.
+func matchCipherSuite(auth, integ, conf []uint8) (*cipherSuiteT, uint8) {
+	for i, p := range [][]uint8{auth, integ, conf} {
+		if p[0] != uint8(i) || (p[3] != 0 && p[3] != 8) {
+			return nil, RMCPP_STATUS_ILLEGAL_PARAMETER
+		}
+	}
+	for _, id := range lanserv.cipherSuites {
+		cs := findCipherSuite(id)
+		if (auth[3] == 0 || auth[4]&0x3f == cs.auth) &&
+			(integ[3] == 0 || integ[4]&0x3f == cs.integ) &&
+			(conf[3] == 0 || conf[4]&0x3f == cs.conf) {
+			return cs, RMCPP_STATUS_OK
+		}
+	}
+	return nil, RMCPP_STATUS_NO_CIPHER_SUITE_MATCH
+}
+
+// Commands allowed outside of a session.
+var rmcppSessionless = map[uint8]bool{
+	GET_CHANNEL_AUTH_CAPABILITIES_CMD: true,
+	GET_CHANNEL_CIPHER_SUITES_CMD:     true,
+	GET_SYSTEM_GUID_CMD:               true,
+}
+
+func rmcppHmac(auth uint8, key []uint8, data ...[]uint8) []uint8 {
+	var h hash.Hash
+	if auth == RMCPP_AUTH_HMAC_SHA256 {
+		h = hmac.New(sha256.New, key)
+	} else {
+		h = hmac.New(sha1.New, key)
+	}
+	for _, b := range data {
+		h.Write(b)
+	}
+	return h.Sum(nil)
+}
+
+// Length of the RAKP4 integrity check value.
+func rmcppIcvLen(auth uint8) int {
+	if auth == RMCPP_AUTH_HMAC_SHA256 {
+		return 16
+	}
+	return 12
+}
+
+// Length of the packet AuthCode.
+func (session *sessionT) authcodeLen() int {
+	switch session.integ {
+	case RMCPP_INTEG_HMAC_SHA1_96:
+		return 12
+	case RMCPP_INTEG_HMAC_SHA256_128:
+		return 16
+	}
+	return 0
+}
+
+func (session *sessionT) authcode(data []uint8) []uint8 {
+	switch session.integ {
+	case RMCPP_INTEG_HMAC_SHA1_96:
+		return rmcppHmac(RMCPP_AUTH_HMAC_SHA1, session.k1, data)[:12]
+	case RMCPP_INTEG_HMAC_SHA256_128:
+		return rmcppHmac(RMCPP_AUTH_HMAC_SHA256, session.k1, data)[:16]
+	}
+	return nil
+}
+
+// Returns the IV prefixed AES-CBC-128 ciphertext of the payload with its
+// confidentiality trailer: pad bytes 1, 2, ..., then the pad length.
+func (session *sessionT) encrypt(payload []uint8) []uint8 {
+	block, _ := aes.NewCipher(session.k2[:16])
+	n := aes.BlockSize - (len(payload)+1)%aes.BlockSize
+	if n == aes.BlockSize {
+		n = 0
+	}
+	data := make([]uint8, aes.BlockSize, aes.BlockSize+len(payload)+n+1)
+	rand.Read(data[:aes.BlockSize])
+	data = append(data, payload...)
+	for i := 1; i <= n; i++ {
+		data = append(data, uint8(i))
+	}
+	data = append(data, uint8(n))
+	cipher.NewCBCEncrypter(block, data[:aes.BlockSize]).
+		CryptBlocks(data[aes.BlockSize:], data[aes.BlockSize:])
+	return data
+}
+
+func (session *sessionT) decrypt(data []uint8) ([]uint8, bool) {
+	if len(data) < 2*aes.BlockSize || len(data)%aes.BlockSize != 0 {
+		return nil, false
+	}
+	block, _ := aes.NewCipher(session.k2[:16])
+	plain := make([]uint8, len(data)-aes.BlockSize)
+	cipher.NewCBCDecrypter(block, data[:aes.BlockSize]).
+		CryptBlocks(plain, data[aes.BlockSize:])
+	n := int(plain[len(plain)-1])
+	if n >= aes.BlockSize {
+		return nil, false
+	}
+	plain = plain[:len(plain)-1]
+	for i := 1; i <= n; i++ {
+		if plain[len(plain)-n+i-1] != uint8(i) {
+			return nil, false
+		}
+	}
+	return plain[:len(plain)-n], true
+}
+
+// Check the sequence number with the given last received and the window of
+// those received behind it.
+func checkSeq(last, window *uint32, seq uint32) bool {
+	if seq == 0 {
+		return false
+	}
+	d := int32(seq - *last)
+	switch {
+	case d > 0 && d <= RMCPP_SEQ_AHEAD:
+		*window = (*window << uint(d)) | (1 << uint(d-1))
+		*last = seq
+	case d < 0 && d >= -RMCPP_SEQ_BEHIND:
+		bit := uint32(1) << uint(-d-1)
+		if *window&bit != 0 {
+			return false
+		}
+		*window |= bit
+	default:
+		return false
+	}
+	return true
+}
+
+func (msg *msgT) ipmiParseRmcppMsg() bool {
+	dataStart := msg.dataStart
+
+	if msg.dataLen < dataStart+RMCPP_HDR_LEN {
+		fmt.Println("RMCP+ msg failure: message too short",
+			msg.dataLen)
+		return false
+	}
+
+	// Load RMCP+ Session fields
+	msg.rmcp.session.authType = msg.data[dataStart]
+	msg.authtype = msg.rmcp.session.authType
+	msg.rmcpp.payload = msg.data[dataStart+1] & RMCPP_PAYLOAD_TYPE_MASK
+	msg.rmcpp.encrypted = (msg.data[dataStart+1] >> 7) & 1
+	msg.rmcpp.authenticated = (msg.data[dataStart+1] >> 6) & 1
+	if msg.rmcpp.payload == RMCPP_PAYLOAD_OEM {
+		fmt.Println("RMCP+ msg failure: OEM payload not supported")
+		return false
+	}
+	msg.rmcp.session.sid =
+		binary.LittleEndian.Uint32(msg.data[dataStart+2 : dataStart+6])
+	msg.rmcp.session.seq =
+		binary.LittleEndian.Uint32(msg.data[dataStart+6 : dataStart+10])
+	msg.sid = msg.rmcp.session.sid
+	msg.rmcpp.payloadLen = uint(binary.LittleEndian.Uint16(
+		msg.data[dataStart+10 : dataStart+12]))
+	msg.dataStart += RMCPP_HDR_LEN
+	if msg.dataLen < msg.dataStart+msg.rmcpp.payloadLen {
+		fmt.Println("RMCP+ msg failure: payload length",
+			msg.rmcpp.payloadLen)
+		return false
+	}
+	return true
+}
+
+// Check the integrity, sequence number, and confidentiality of the
+// session's message; then replace its payload with the decrypted one.
+func (msg *msgT) rmcppCheck(session *sessionT) bool {
+	authStart := msg.dataStart - RMCPP_HDR_LEN
+	payloadEnd := msg.dataStart + msg.rmcpp.payloadLen
+
+	if session.integ != RMCPP_INTEG_NONE {
+		n := uint(session.authcodeLen())
+		if msg.rmcpp.authenticated == 0 {
+			fmt.Println("RMCP+ msg failure: not authenticated")
+			return false
+		}
+		if msg.dataLen < payloadEnd+2+n ||
+			(msg.dataLen-n-authStart)%4 != 0 {
+			fmt.Println("RMCP+ msg failure: invalid trailer")
+			return false
+		}
+		authcodeStart := msg.dataLen - n
+		padLen := uint(msg.data[authcodeStart-2])
+		if msg.data[authcodeStart-1] != RMCPP_NEXT_HEADER ||
+			payloadEnd+padLen+2 != authcodeStart {
+			fmt.Println("RMCP+ msg failure: invalid trailer")
+			return false
+		}
+		if !hmac.Equal(msg.data[authcodeStart:msg.dataLen],
+			session.authcode(msg.data[authStart:authcodeStart])) {
+			fmt.Println("RMCP+ msg failure: invalid authcode")
+			return false
+		}
+		if !checkSeq(&session.recvSeq, &session.recvWindow,
+			msg.rmcp.session.seq) {
+			fmt.Println("RMCP+ msg failure: sequence",
+				msg.rmcp.session.seq)
+			return false
+		}
+	} else if msg.rmcpp.authenticated != 0 {
+		fmt.Println("RMCP+ msg failure: unexpected authcode")
+		return false
+	} else if !checkSeq(&session.unauthRecvSeq, &session.unauthRecvWindow,
+		msg.rmcp.session.seq) {
+		fmt.Println("RMCP+ msg failure: sequence",
+			msg.rmcp.session.seq)
+		return false
+	}
+
+	if session.conf != RMCPP_CONF_NONE {
+		if msg.rmcpp.encrypted == 0 {
+			fmt.Println("RMCP+ msg failure: not encrypted")
+			return false
+		}
+		plain, ok := session.decrypt(msg.data[msg.dataStart:payloadEnd])
+		if !ok {
+			fmt.Println("RMCP+ msg failure: invalid ciphertext")
+			return false
+		}
+		copy(msg.data[msg.dataStart:], plain)
+		msg.rmcpp.payloadLen = uint(len(plain))
+	} else if msg.rmcpp.encrypted != 0 {
+		fmt.Println("RMCP+ msg failure: unexpected encryption")
+		return false
+	}
+	msg.dataLen = msg.dataStart + msg.rmcpp.payloadLen
+	session.timeLeft = lanserv.defaultSessionTimeout
+	return true
+}
+
+func (msg *msgT) ipmiHandleRmcppMsg() {
+	var session *sessionT
+
+	switch msg.rmcpp.payload {
+	case RMCPP_PAYLOAD_OPEN_SESSION_REQ:
+		msg.rmcppOpenSession()
+		return
+	case RMCPP_PAYLOAD_RAKP1:
+		msg.rmcppRakp1()
+		return
+	case RMCPP_PAYLOAD_RAKP3:
+		msg.rmcppRakp3()
+		return
+	case RMCPP_PAYLOAD_IPMI, RMCPP_PAYLOAD_SOL:
+	default:
+		fmt.Println("RMCP+ payload not supported", msg.rmcpp.payload)
+		return
+	}
+
+	if msg.sid != 0 {
+		session = sidToSession(msg.sid)
+		if session == nil || !session.rmcpplus {
+			fmt.Printf("RMCP+ msg - no session %x\n", msg.sid)
+			return
+		}
+		if !msg.rmcppCheck(session) {
+			return
+		}
+	} else if msg.rmcpp.encrypted != 0 || msg.rmcpp.authenticated != 0 {
+		fmt.Println("RMCP+ msg failure: sessionless authcode")
+		return
+	} else {
+		msg.dataLen = msg.dataStart + msg.rmcpp.payloadLen
+	}
+
+	if msg.rmcpp.payload == RMCPP_PAYLOAD_SOL {
+		if session == nil {
+			fmt.Println("SOL msg failure: no session")
+			return
+		}
+		msg.solHandle(session)
+		return
+	}
+	if msg.rmcpp.payloadLen < 7 {
+		fmt.Println("RMCP+ msg failure: IPMI message too short",
+			msg.rmcpp.payloadLen)
+		return
+	}
+	msg.ipmiParseIpmiMsg()
+	if session == nil && (msg.rmcp.message.netfn != APP_NETFN ||
+		!rmcppSessionless[msg.rmcp.message.cmd]) {
+		fmt.Println("RMCP+ msg failure: command requires session",
+			msg.rmcp.message.netfn, msg.rmcp.message.cmd)
+		return
+	}
+	msg.ipmiDispatch()
+}
+
+// Send the payload in an RMCP+ packet of the session, or outside of any
+// session if nil.
+func (msg *msgT) rmcppSend(session *sessionT, payloadType uint8,
+	payload []uint8) {
+	var sid, seq uint32
+
+	if session != nil {
+		sid = session.remSid
+		if session.conf != RMCPP_CONF_NONE {
+			payload = session.encrypt(payload)
+			payloadType |= RMCPP_PAYLOAD_ENCRYPTED
+		}
+		if session.integ != RMCPP_INTEG_NONE {
+			payloadType |= RMCPP_PAYLOAD_AUTHENTICATED
+			session.xmitSeq++
+			if session.xmitSeq == 0 {
+				session.xmitSeq++
+			}
+			seq = session.xmitSeq
+		} else {
+			session.unauthXmitSeq++
+			if session.unauthXmitSeq == 0 {
+				session.unauthXmitSeq++
+			}
+			seq = session.unauthXmitSeq
+		}
+	}
+
+	data := make([]uint8, 4+RMCPP_HDR_LEN, 4+RMCPP_HDR_LEN+len(payload)+20)
+	data[0] = 6    /* RMCP version. */
+	data[2] = 0xff /* No seq num */
+	data[3] = 7    /* IPMI msg class */
+	data[4] = IPMI_AUTHTYPE_RMCP_PLUS
+	data[5] = payloadType
+	binary.LittleEndian.PutUint32(data[6:10], sid)
+	binary.LittleEndian.PutUint32(data[10:14], seq)
+	binary.LittleEndian.PutUint16(data[14:16], uint16(len(payload)))
+	data = append(data, payload...)
+	if payloadType&RMCPP_PAYLOAD_AUTHENTICATED != 0 {
+		padLen := 0
+		for (len(data)-4+2)%4 != 0 {
+			data = append(data, 0xff)
+			padLen++
+		}
+		data = append(data, uint8(padLen), RMCPP_NEXT_HEADER)
+		data = append(data, session.authcode(data[4:])...)
+	}
+	if debug {
+		fmt.Println("Sending", len(data), " bytes to", msg.remoteAddr)
+	}
+	msg.conn.WriteToUDP(data, msg.remoteAddr)
+}
+
+func (msg *msgT) rmcppReturnRsp(session *sessionT, rsp *rspMsgDataT) {
+	data := make([]uint8, 6, 7+int(rsp.dataLen))
+	data[0] = msg.rmcp.message.rqAddr
+	data[1] = (rsp.netfn << 2) | msg.rmcp.message.rqLun
+	data[2] = uint8(ipmiChecksum(data[0:2], 2, 0))
+	data[3] = msg.rmcp.message.rsAddr
+	data[4] = (msg.rmcp.message.rqSeq << 2) | msg.rmcp.message.rsLun
+	data[5] = rsp.cmd
+	data = append(data, rsp.data[0:rsp.dataLen]...)
+	data = append(data, uint8(ipmiChecksum(data[3:], len(data)-3, 0)))
+	msg.rmcppSend(session, RMCPP_PAYLOAD_IPMI, data)
+}
+
+func (msg *msgT) rmcppPayload() []uint8 {
+	return msg.data[msg.dataStart : msg.dataStart+msg.rmcpp.payloadLen]
+}
+
+// Find the session of the ID in the RAKP message.
+func rmcppStartupSession(sid uint32) *sessionT {
+	if sid&1 == 1 {
+		return nil
+	}
+	idx := (sid >> 1) & SESSION_MASK
+	if idx == 0 || idx > MAX_SESSIONS {
+		return nil
+	}
+	session := &lanserv.sessions[idx]
+	if !session.active || !session.inStartup || session.sid != sid {
+		return nil
+	}
+	return session
+}
+
+func (session *sessionT) free() {
+	if sol.session == session {
+		solDeactivate(nil)
+	}
+	session.active = false
+	session.sik, session.k1, session.k2 = nil, nil, nil
+	lanserv.activeSessions--
+}
+
+// Free the sessions abandoned in key exchange.
+func rmcppReap(now time.Time) {
+	timeout := time.Duration(lanserv.defaultSessionTimeout) * time.Second
+	for i := 1; i <= MAX_SESSIONS; i++ {
+		session := &lanserv.sessions[i]
+		if session.active && session.inStartup &&
+			now.Sub(session.started) > timeout {
+			fmt.Printf("Session %d closed: key exchange timeout\n",
+				session.handle)
+			session.free()
+		}
+	}
+}
+
+func (msg *msgT) rmcppOpenSession() {
+	var data [36]uint8
+
+	req := msg.rmcppPayload()
+	if len(req) < 32 {
+		fmt.Println("Open session failure: message too short",
+			len(req))
+		return
+	}
+	data[0] = req[0] // message tag
+	copy(data[4:8], req[4:8])
+	reject := func(status uint8) {
+		fmt.Println("Open session failure: status", status)
+		data[1] = status
+		msg.rmcppSend(nil, RMCPP_PAYLOAD_OPEN_SESSION_RSP, data[0:8])
+	}
+
+	remSid := binary.LittleEndian.Uint32(req[4:8])
+	if remSid == 0 {
+		reject(RMCPP_STATUS_ILLEGAL_PARAMETER)
+		return
+	}
+	maxPriv := req[1] & 0xf
+	if maxPriv == 0 {
+		maxPriv = lanserv.chanPrivLimit
+	}
+	if maxPriv > IPMI_PRIVILEGE_OEM {
+		reject(RMCPP_STATUS_INVALID_ROLE)
+		return
+	}
+	if maxPriv > lanserv.chanPrivLimit {
+		reject(RMCPP_STATUS_UNAUTHORIZED_ROLE)
+		return
+	}
+	cs, status := matchCipherSuite(req[8:16], req[16:24], req[24:32])
+	if cs == nil {
+		reject(status)
+		return
+	}
+
+	now := time.Now()
+	rmcppReap(now)
+	session := findFreeSession()
+	if lanserv.activeSessions >= MAX_SESSIONS || session == nil {
+		reject(RMCPP_STATUS_INSUFFICIENT_RESOURCES)
+		return
+	}
+	*session = sessionT{
+		handle:    session.handle,
+		active:    true,
+		inStartup: true,
+		rmcpplus:  true,
+		authtype:  IPMI_AUTHTYPE_RMCP_PLUS,
+		remSid:    remSid,
+		auth:      cs.auth,
+		integ:     cs.integ,
+		conf:      cs.conf,
+		maxPriv:   maxPriv,
+		timeLeft:  lanserv.defaultSessionTimeout,
+		started:   now,
+		tag:       req[0],
+	}
+	if lanserv.sidSeq == 0 {
+		lanserv.sidSeq++
+	}
+	session.sid = uint32((lanserv.sidSeq << (SESSION_BITS_REQ + 1)) |
+		(session.handle << 1))
+	lanserv.sidSeq++
+	lanserv.activeSessions++
+
+	data[2] = maxPriv
+	binary.LittleEndian.PutUint32(data[8:12], session.sid)
+	for i, alg := range []uint8{cs.auth, cs.integ, cs.conf} {
+		data[12+8*i] = uint8(i) // payload type
+		data[12+8*i+3] = 8      // payload length
+		data[12+8*i+4] = alg
+	}
+	if debug {
+		fmt.Printf("Open session: %x cipher suite %d\n",
+			session.sid, cs.id)
+	}
+	msg.rmcppSend(nil, RMCPP_PAYLOAD_OPEN_SESSION_RSP, data[0:36])
+}
+
+func (msg *msgT) rmcppRakp1() {
+	var data [8]uint8
+
+	req := msg.rmcppPayload()
+	if len(req) < 28 {
+		fmt.Println("RAKP1 failure: message too short", len(req))
+		return
+	}
+	data[0] = req[0] // message tag
+	session := rmcppStartupSession(binary.LittleEndian.Uint32(req[4:8]))
+	reject := func(status uint8) {
+		fmt.Println("RAKP1 failure: status", status)
+		data[1] = status
+		msg.rmcppSend(nil, RMCPP_PAYLOAD_RAKP2, data[0:8])
+		if session != nil {
+			session.free()
+		}
+	}
+	if session == nil {
+		reject(RMCPP_STATUS_INVALID_SESSION_ID)
+		return
+	}
+	binary.LittleEndian.PutUint32(data[4:8], session.remSid)
+	if session.username != nil {
+		reject(RMCPP_STATUS_ILLEGAL_PARAMETER)
+		return
+	}
+
+	role := req[24]
+	priv := role & 0xf
+	ulen := int(req[27])
+	if role&0xe0 != 0 || priv == 0 || priv > IPMI_PRIVILEGE_OEM {
+		reject(RMCPP_STATUS_INVALID_ROLE)
+		return
+	}
+	if ulen > 16 || len(req) < 28+ulen {
+		reject(RMCPP_STATUS_INVALID_NAME_LENGTH)
+		return
+	}
+	username := make([]uint8, 16)
+	copy(username, req[28:28+ulen])
+	user := findUser(username, true, priv)
+	if user == nil || !user.valid {
+		reject(RMCPP_STATUS_UNAUTHORIZED_NAME)
+		return
+	}
+	if priv > user.maxPriv || priv > session.maxPriv {
+		reject(RMCPP_STATUS_UNAUTHORIZED_ROLE)
+		return
+	}
+
+	session.userid = user.idx
+	session.role = role
+	session.username = append([]uint8{}, req[28:28+ulen]...)
+	session.maxPriv = priv
+	copy(session.rm[:], req[8:24])
+	if _, err := rand.Read(session.rc[:]); err != nil {
+		reject(RMCPP_STATUS_INSUFFICIENT_RESOURCES)
+		return
+	}
+
+	var sidm, sidc [4]uint8
+	binary.LittleEndian.PutUint32(sidm[:], session.remSid)
+	binary.LittleEndian.PutUint32(sidc[:], session.sid)
+	rsp := append(data[0:8:8], session.rc[:]...)
+	rsp = append(rsp, lanserv.guid[:]...)
+	rsp = append(rsp, rmcppHmac(session.auth, user.pw,
+		sidm[:], sidc[:], session.rm[:], session.rc[:],
+		lanserv.guid[:], []uint8{role, uint8(ulen)},
+		session.username)...)
+	msg.rmcppSend(nil, RMCPP_PAYLOAD_RAKP2, rsp)
+}
+
+func (msg *msgT) rmcppRakp3() {
+	var data [8]uint8
+
+	req := msg.rmcppPayload()
+	if len(req) < 8 {
+		fmt.Println("RAKP3 failure: message too short", len(req))
+		return
+	}
+	data[0] = req[0] // message tag
+	session := rmcppStartupSession(binary.LittleEndian.Uint32(req[4:8]))
+	reject := func(status uint8) {
+		fmt.Println("RAKP3 failure: status", status)
+		data[1] = status
+		msg.rmcppSend(nil, RMCPP_PAYLOAD_RAKP4, data[0:8])
+		if session != nil {
+			session.free()
+		}
+	}
+	if session == nil {
+		reject(RMCPP_STATUS_INVALID_SESSION_ID)
+		return
+	}
+	binary.LittleEndian.PutUint32(data[4:8], session.remSid)
+	if session.username == nil {
+		reject(RMCPP_STATUS_ILLEGAL_PARAMETER)
+		return
+	}
+	if req[1] != RMCPP_STATUS_OK {
+		// the remote console gave up on the session
+		fmt.Println("RAKP3 failure: remote status", req[1])
+		session.free()
+		return
+	}
+
+	var sidm, sidc [4]uint8
+	binary.LittleEndian.PutUint32(sidm[:], session.remSid)
+	binary.LittleEndian.PutUint32(sidc[:], session.sid)
+	kuid := lanserv.users[session.userid].pw
+	name := []uint8{session.role, uint8(len(session.username))}
+	if !hmac.Equal(req[8:], rmcppHmac(session.auth, kuid,
+		session.rc[:], sidm[:], name, session.username)) {
+		reject(RMCPP_STATUS_INVALID_INTEGRITY_CHECK)
+		return
+	}
+
+	session.sik = rmcppHmac(session.auth, kuid,
+		session.rm[:], session.rc[:], name, session.username)
+	session.k1 = rmcppHmac(session.auth, session.sik,
+		bytes.Repeat([]uint8{1}, 20))
+	session.k2 = rmcppHmac(session.auth, session.sik,
+		bytes.Repeat([]uint8{2}, 20))
+	icv := rmcppHmac(session.auth, session.sik,
+		session.rm[:], sidc[:], lanserv.guid[:])
+	session.inStartup = false
+	session.priv = IPMI_PRIVILEGE_USER // Start at user privilege
+	if session.priv > session.maxPriv {
+		session.priv = session.maxPriv
+	}
+
+	fmt.Printf("\nSession %d activated\n", session.handle)
+	msg.rmcppSend(nil, RMCPP_PAYLOAD_RAKP4,
+		append(data[0:8:8], icv[:rmcppIcvLen(session.auth)]...))
+}
@@ -0,0 +1,394 @@
+// Copyright 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package internal
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"net"
+	"testing"
+	"time"
+)
+
+// A remote console of one RMCP+ session.
+type console struct {
+	t      *testing.T
+	conn   *net.UDPConn
+	h      func() hash.Hash
+	icvLen int
+	conf   bool
+
+	remSid, sid uint32
+	seq         uint32
+	rqSeq       uint8
+	rm, rc      [16]uint8
+	k1, k2      []uint8
+}
+
+// DefaultConfig with RMCP+ enabled.
+const testConfig = DefaultConfig + "cipherSuites: [1, 2, 3, 15, 16, 17]\n"
+
+func serve(t *testing.T) *net.UDPAddr {
+	lanMutex.Lock()
+	err := ParseConfig([]byte(testConfig))
+	lanMutex.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			msg := new(msgT)
+			n, remoteAddr, err := conn.ReadFromUDP(msg.data[0:])
+			if err != nil {
+				return
+			}
+			msg.remoteAddr = remoteAddr
+			msg.dataLen = uint(n)
+			msg.conn = conn
+			msg.ipmiHandleMsg()
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr)
+}
+
+func newConsole(t *testing.T, addr *net.UDPAddr, suite uint8) *console {
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &console{t: t, conn: conn, remSid: 0xa0a1a2a3}
+	cs := findCipherSuite(suite)
+	c.h, c.icvLen = sha1.New, 12
+	if cs.auth == RMCPP_AUTH_HMAC_SHA256 {
+		c.h, c.icvLen = sha256.New, 16
+	}
+	c.conf = cs.conf == RMCPP_CONF_AES_CBC_128
+	return c
+}
+
+func (c *console) hmac(key []uint8, data ...[]uint8) []uint8 {
+	h := hmac.New(c.h, key)
+	for _, b := range data {
+		h.Write(b)
+	}
+	return h.Sum(nil)
+}
+
+func (c *console) send(ptype uint8, sid uint32, payload []uint8) {
+	authenticated := sid != 0 && c.k1 != nil
+	if sid != 0 && c.conf {
+		block, _ := aes.NewCipher(c.k2[:16])
+		iv := make([]uint8, 16)
+		rand.Read(iv)
+		n := 15 - len(payload)%16
+		plain := append([]uint8{}, payload...)
+		for i := 1; i <= n; i++ {
+			plain = append(plain, uint8(i))
+		}
+		plain = append(plain, uint8(n))
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(plain, plain)
+		payload = append(iv, plain...)
+		ptype |= 0x80
+	}
+	if authenticated {
+		ptype |= 0x40
+		c.seq++
+	}
+	b := []uint8{6, 0, 0xff, 7, 6, ptype, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(b[6:], sid)
+	binary.LittleEndian.PutUint32(b[10:], c.seq)
+	binary.LittleEndian.PutUint16(b[14:], uint16(len(payload)))
+	b = append(b, payload...)
+	if authenticated {
+		n := 0
+		for (len(b)-4+2)%4 != 0 {
+			b = append(b, 0xff)
+			n++
+		}
+		b = append(b, uint8(n), 7)
+		b = append(b, c.hmac(c.k1, b[4:])[:c.icvLen]...)
+	}
+	if _, err := c.conn.Write(b); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *console) recv(ptype uint8) []uint8 {
+	b := make([]uint8, 1024)
+	c.conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := c.conn.Read(b)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	b = b[:n]
+	if n < 16 || b[4] != 6 || b[5]&0x3f != ptype {
+		c.t.Fatalf("unexpected response: %x", b)
+	}
+	l := int(binary.LittleEndian.Uint16(b[14:16]))
+	payload := b[16 : 16+l]
+	if b[5]&0x40 != 0 {
+		if binary.LittleEndian.Uint32(b[6:10]) != c.remSid {
+			c.t.Fatalf("sid %x", b[6:10])
+		}
+		authcode := b[n-c.icvLen:]
+		if !hmac.Equal(authcode, c.hmac(c.k1, b[4:n-c.icvLen])[:c.icvLen]) {
+			c.t.Fatal("invalid authcode")
+		}
+	}
+	if b[5]&0x80 != 0 {
+		block, _ := aes.NewCipher(c.k2[:16])
+		plain := make([]uint8, len(payload)-16)
+		cipher.NewCBCDecrypter(block, payload[:16]).
+			CryptBlocks(plain, payload[16:])
+		payload = plain[:len(plain)-1-int(plain[len(plain)-1])]
+	}
+	return payload
+}
+
+// Send a request of the session, or sessionless if 0, and return the
+// response data.
+func (c *console) ipmi(sid uint32, netfn, cmd uint8, data ...uint8) []uint8 {
+	c.rqSeq++
+	b := []uint8{0x20, netfn << 2, 0, 0x81, c.rqSeq << 2, cmd}
+	b[2] = uint8(ipmiChecksum(b[0:2], 2, 0))
+	b = append(b, data...)
+	b = append(b, uint8(ipmiChecksum(b[3:], len(b)-3, 0)))
+	c.send(RMCPP_PAYLOAD_IPMI, sid, b)
+	rsp := c.recv(RMCPP_PAYLOAD_IPMI)
+	if len(rsp) < 8 || rsp[1] != (netfn|1)<<2 || rsp[4]>>2 != c.rqSeq ||
+		rsp[5] != cmd {
+		c.t.Fatalf("unexpected response: %x", rsp)
+	}
+	return rsp[6 : len(rsp)-1]
+}
+
+// Returns the server's active sessions after prior requests.
+func (c *console) activeSessions() uint8 {
+	if rsp := c.ipmi(0, APP_NETFN, GET_SYSTEM_GUID_CMD); len(rsp) != 17 {
+		c.t.Fatalf("system guid: %x", rsp)
+	}
+	lanMutex.Lock()
+	defer lanMutex.Unlock()
+	return lanserv.activeSessions
+}
+
+// Open a session and return the RAKP2 response.
+func (c *console) rakp1(suite uint8, role uint8, name string) []uint8 {
+	cs := findCipherSuite(suite)
+	req := make([]uint8, 32)
+	req[0] = 1
+	req[1] = role & 0xf
+	binary.LittleEndian.PutUint32(req[4:8], c.remSid)
+	for i, alg := range []uint8{cs.auth, cs.integ, cs.conf} {
+		req[8+8*i] = uint8(i)
+		req[8+8*i+3] = 8
+		req[8+8*i+4] = alg
+	}
+	c.send(RMCPP_PAYLOAD_OPEN_SESSION_REQ, 0, req)
+	rsp := c.recv(RMCPP_PAYLOAD_OPEN_SESSION_RSP)
+	if len(rsp) != 36 || rsp[1] != 0 ||
+		binary.LittleEndian.Uint32(rsp[4:8]) != c.remSid ||
+		rsp[16] != cs.auth || rsp[24] != cs.integ || rsp[32] != cs.conf {
+		c.t.Fatalf("open session: %x", rsp)
+	}
+	c.sid = binary.LittleEndian.Uint32(rsp[8:12])
+
+	req = make([]uint8, 28)
+	req[0] = 2
+	binary.LittleEndian.PutUint32(req[4:8], c.sid)
+	rand.Read(c.rm[:])
+	copy(req[8:24], c.rm[:])
+	req[24] = role
+	req[27] = uint8(len(name))
+	req = append(req, name...)
+	c.send(RMCPP_PAYLOAD_RAKP1, 0, req)
+	return c.recv(RMCPP_PAYLOAD_RAKP2)
+}
+
+func (c *console) handshake(suite uint8, role uint8, name, pw string) {
+	kuid := make([]uint8, 20)
+	copy(kuid, pw)
+	rsp := c.rakp1(suite, role, name)
+	if rsp[1] != 0 {
+		c.t.Fatalf("RAKP2 status %#x", rsp[1])
+	}
+	copy(c.rc[:], rsp[8:24])
+	guid := rsp[24:40]
+	var sidm, sidc [4]uint8
+	binary.LittleEndian.PutUint32(sidm[:], c.remSid)
+	binary.LittleEndian.PutUint32(sidc[:], c.sid)
+	lname := []uint8{role, uint8(len(name))}
+	if !hmac.Equal(rsp[40:], c.hmac(kuid, sidm[:], sidc[:], c.rm[:],
+		c.rc[:], guid, lname, []uint8(name))) {
+		c.t.Fatal("invalid RAKP2 authcode")
+	}
+
+	req := make([]uint8, 8)
+	req[0] = 3
+	binary.LittleEndian.PutUint32(req[4:8], c.sid)
+	req = append(req, c.hmac(kuid, c.rc[:], sidm[:], lname,
+		[]uint8(name))...)
+	c.send(RMCPP_PAYLOAD_RAKP3, 0, req)
+	rsp = c.recv(RMCPP_PAYLOAD_RAKP4)
+	if rsp[1] != 0 {
+		c.t.Fatalf("RAKP4 status %#x", rsp[1])
+	}
+	sik := c.hmac(kuid, c.rm[:], c.rc[:], lname, []uint8(name))
+	if !hmac.Equal(rsp[8:], c.hmac(sik, c.rm[:], sidc[:],
+		guid)[:c.icvLen]) {
+		c.t.Fatal("invalid RAKP4 integrity check value")
+	}
+	c.k1 = c.hmac(sik, bytes.Repeat([]uint8{1}, 20))
+	c.k2 = c.hmac(sik, bytes.Repeat([]uint8{2}, 20))
+}
+
+func TestRmcppSession(t *testing.T) {
+	addr := serve(t)
+	for _, suite := range []uint8{3, 17} {
+		c := newConsole(t, addr, suite)
+		c.handshake(suite, 0x10|IPMI_PRIVILEGE_ADMIN, "ipmiusr", "test")
+		rsp := c.ipmi(c.sid, APP_NETFN, SET_SESSION_PRIVILEGE_CMD,
+			IPMI_PRIVILEGE_ADMIN)
+		if !bytes.Equal(rsp, []uint8{0, IPMI_PRIVILEGE_ADMIN}) {
+			t.Errorf("suite %d: set session privilege: %x",
+				suite, rsp)
+		}
+		var sid [4]uint8
+		binary.LittleEndian.PutUint32(sid[:], c.sid)
+		rsp = c.ipmi(c.sid, APP_NETFN, CLOSE_SESSION_CMD, sid[:]...)
+		if !bytes.Equal(rsp, []uint8{0}) {
+			t.Errorf("suite %d: close session: %x", suite, rsp)
+		}
+	}
+	c := newConsole(t, addr, 3)
+	if n := c.activeSessions(); n != 0 {
+		t.Error("active sessions:", n)
+	}
+}
+
+func TestRmcppSessionless(t *testing.T) {
+	c := newConsole(t, serve(t), 17)
+	rsp := c.ipmi(0, APP_NETFN, GET_CHANNEL_CIPHER_SUITES_CMD,
+		0xe, RMCPP_PAYLOAD_IPMI, 0x80)
+	if !bytes.Equal(rsp, []uint8{0, 1,
+		0xc0, 1, 1, 0x40, 0x80,
+		0xc0, 2, 1, 0x41, 0x80,
+		0xc0, 3, 1, 0x41, 0x81,
+		0xc0}) {
+		t.Errorf("cipher suites: %x", rsp)
+	}
+	rsp = c.ipmi(0, APP_NETFN, GET_CHANNEL_CIPHER_SUITES_CMD,
+		0xe, RMCPP_PAYLOAD_IPMI, 0x81)
+	if !bytes.Equal(rsp, []uint8{0, 1,
+		15, 3, 0x40, 0x80,
+		0xc0, 16, 3, 0x44, 0x80,
+		0xc0, 17, 3, 0x44, 0x81}) {
+		t.Errorf("cipher suites: %x", rsp)
+	}
+	rsp = c.ipmi(0, APP_NETFN, GET_CHANNEL_AUTH_CAPABILITIES_CMD,
+		0x8e, IPMI_PRIVILEGE_ADMIN)
+	if len(rsp) != 9 || rsp[2]&0x80 == 0 || rsp[4] != 3 {
+		t.Errorf("channel auth capabilities: %x", rsp)
+	}
+}
+
+func TestRmcppReject(t *testing.T) {
+	addr := serve(t)
+	c := newConsole(t, addr, 3)
+	if rsp := c.rakp1(3, IPMI_PRIVILEGE_ADMIN, "nobody"); rsp[1] !=
+		RMCPP_STATUS_UNAUTHORIZED_NAME {
+		t.Errorf("RAKP2 status %#x", rsp[1])
+	}
+	// the null user may only be user
+	if rsp := c.rakp1(3, IPMI_PRIVILEGE_ADMIN, ""); rsp[1] !=
+		RMCPP_STATUS_UNAUTHORIZED_ROLE {
+		t.Errorf("RAKP2 status %#x", rsp[1])
+	}
+
+	c.rakp1(3, IPMI_PRIVILEGE_ADMIN, "ipmiusr")
+	req := make([]uint8, 28)
+	req[0] = 3
+	binary.LittleEndian.PutUint32(req[4:8], c.sid)
+	c.send(RMCPP_PAYLOAD_RAKP3, 0, req)
+	if rsp := c.recv(RMCPP_PAYLOAD_RAKP4); rsp[1] !=
+		RMCPP_STATUS_INVALID_INTEGRITY_CHECK {
+		t.Errorf("RAKP4 status %#x", rsp[1])
+	}
+	if n := c.activeSessions(); n != 0 {
+		t.Error("active sessions:", n)
+	}
+}
+
+func TestRmcppDisabled(t *testing.T) {
+	c := newConsole(t, serve(t), 3)
+	lanMutex.Lock()
+	err := ParseConfig([]byte(DefaultConfig))
+	lanMutex.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.send(RMCPP_PAYLOAD_OPEN_SESSION_REQ, 0, make([]uint8, 32))
+	b := make([]uint8, 1024)
+	c.conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if n, err := c.conn.Read(b); err == nil {
+		t.Errorf("unexpected response: %x", b[:n])
+	}
+}
+
+func TestSeqWindow(t *testing.T) {
+	var last, window uint32
+	for _, x := range []struct {
+		seq uint32
+		ok  bool
+	}{
+		{1, true}, {1, false}, {3, true}, {2, true}, {2, false},
+		{20, false}, {19, true}, {11, true}, {10, false}, {0, false},
+	} {
+		if ok := checkSeq(&last, &window, x.seq); ok != x.ok {
+			t.Errorf("seq %d: %v", x.seq, ok)
+		}
+	}
+}
+
+func TestConfig(t *testing.T) {
+	lanMutex.Lock()
+	defer lanMutex.Unlock()
+	defer ParseConfig([]byte(DefaultConfig))
+	for _, s := range []string{
+		"cipherSuites: [0]\n",
+		"users:\n- {id: 1, name: a, password: b, privilege: root}\n",
+		"users:\n- {id: 65, name: a, password: b, privilege: user}\n",
+		"users:\n- {id: 1, privilege: user, auths: [sha1]}\n",
+		"guid: 1234\n",
+	} {
+		if err := ParseConfig([]byte(s)); err == nil {
+			t.Errorf("%q: not invalid", s)
+		}
+	}
+	if err := ParseConfig([]byte(`
+cipherSuites: [17]
+users:
+- {id: 3, name: admin, password: secret, privilege: admin}
+`)); err != nil {
+		t.Fatal(err)
+	}
+	u := &lanserv.users[3]
+	if !u.valid || u.maxPriv != IPMI_PRIVILEGE_ADMIN ||
+		string(bytes.TrimRight(u.pw, "\x00")) != "secret" ||
+		lanserv.users[2].valid || len(lanserv.cipherSuites) != 1 {
+		t.Error(lanserv.users[1:4], lanserv.cipherSuites)
+	}
+}
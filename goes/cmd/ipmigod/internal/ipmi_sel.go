@@ -0,0 +1,388 @@
+// Copyright 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package contains IPMI 2.0 spec implementation
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	redigo "github.com/garyburd/redigo/redis"
+	"github.com/platinasystems/go/internal/opticsalarm"
+	"github.com/platinasystems/go/internal/redis"
+)
+
+const (
+	DefaultSelFile = "/var/lib/ipmigod/sel"
+
+	SEL_ENTRY_SIZE      = 16
+	SEL_MAX_COUNT       = 1000
+	SEL_ERASE_COMPLETED = 1
+
+	// SEL info operation support
+	SEL_OVERFLOW             = 0x80
+	SEL_DELETE_SUPPORTED     = 0x08
+	SEL_RESERVE_SUPPORTED    = 0x02
+	SEL_ALLOC_INFO_SUPPORTED = 0x01
+
+	SEL_SYSTEM_EVENT_RECORD = 0x02
+	SEL_EVM_REV             = 0x04
+	SEL_DEASSERTION         = 0x80
+)
+
+// Sensor types
+const (
+	SENSOR_TYPE_TEMPERATURE  = 0x01
+	SENSOR_TYPE_VOLTAGE      = 0x02
+	SENSOR_TYPE_FAN          = 0x04
+	SENSOR_TYPE_POWER_SUPPLY = 0x08
+	SENSOR_TYPE_POWER_UNIT   = 0x09
+	SENSOR_TYPE_OTHER_UNITS  = 0x0b
+	SENSOR_TYPE_CABLE        = 0x1b
+)
+
+// Event/reading types
+const (
+	EVENT_TYPE_THRESHOLD        = 0x01
+	EVENT_TYPE_DIGITAL_DISCRETE = 0x03
+	EVENT_TYPE_PRESENCE         = 0x08
+	EVENT_TYPE_SENSOR_SPECIFIC  = 0x6f
+)
+
+// Sensor numbers of the events recorded from the hardware daemons; the
+// psu, fan tray, and port numbers are added to their base.
+const (
+	SEL_SENSOR_PSU        = 0x50
+	SEL_SENSOR_FAN_TRAY   = 0x60
+	SEL_SENSOR_POWER_UNIT = 0x70
+	SEL_SENSOR_PORT       = 0x80
+	SEL_SENSOR_MGMT_PORT  = 0xe0
+)
+
+// EventChannels are the redis channels with the alarm transitions of the
+// hardware daemons, e.g. the machine hash and qsfp.events.
+var EventChannels []string
+
+// The last published value of the fields recorded in the SEL.
+var selLast = make(map[string]string)
+
+// Seconds since the epoch offset by set SEL time.
+func selNow() uint32 {
+	return uint32(time.Now().Unix() + mc.sel.timeOffset)
+}
+
+// The SEL file has the last add and erase times, flags, then the entries.
+func selLoad() error {
+	if mc.sel.file == "" {
+		return nil
+	}
+	b, err := ioutil.ReadFile(mc.sel.file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(b) < 9 || (len(b)-9)%SEL_ENTRY_SIZE != 0 {
+		return fmt.Errorf("%s: corrupt", mc.sel.file)
+	}
+	mc.sel.lastAddTime = binary.LittleEndian.Uint32(b[0:4])
+	mc.sel.lastEraseTime = binary.LittleEndian.Uint32(b[4:8])
+	mc.sel.flags = b[8]
+	mc.sel.entries = mc.sel.entries[:0]
+	for b = b[9:]; len(b) > 0; b = b[SEL_ENTRY_SIZE:] {
+		var e selEntryT
+		copy(e.data[:], b)
+		e.recordId = binary.LittleEndian.Uint16(e.data[0:2])
+		mc.sel.entries = append(mc.sel.entries, e)
+		mc.sel.nextEntry = e.recordId + 1
+	}
+	mc.sel.count = uint16(len(mc.sel.entries))
+	return nil
+}
+
+func selSave() {
+	if mc.sel.file == "" {
+		return
+	}
+	b := make([]uint8, 9, 9+len(mc.sel.entries)*SEL_ENTRY_SIZE)
+	binary.LittleEndian.PutUint32(b[0:4], mc.sel.lastAddTime)
+	binary.LittleEndian.PutUint32(b[4:8], mc.sel.lastEraseTime)
+	b[8] = mc.sel.flags
+	for _, e := range mc.sel.entries {
+		b = append(b, e.data[:]...)
+	}
+	err := os.MkdirAll(filepath.Dir(mc.sel.file), 0755)
+	if err == nil {
+		tmp := mc.sel.file + ".tmp"
+		if err = ioutil.WriteFile(tmp, b, 0644); err == nil {
+			err = os.Rename(tmp, mc.sel.file)
+		}
+	}
+	if err != nil {
+		fmt.Println("SEL save:", err)
+	}
+}
+
+// Returns the message's session if it has the privilege; otherwise, returns
+// the error response and nil.
+func (msg *msgT) privileged(priv uint8) *sessionT {
+	session := sidToSession(msg.sid)
+	if session == nil {
+		msg.returnErr(nil, IPMI_INSUFFICIENT_PRIVILEGE_CC)
+	} else if session.priv < priv {
+		msg.returnErr(session, IPMI_INSUFFICIENT_PRIVILEGE_CC)
+		session = nil
+	}
+	return session
+}
+
+// Add a system event record generated by the BMC.
+func selAddEvent(sensorType, sensorNum, eventType, offset uint8,
+	deassert bool) {
+	var rec [SEL_ENTRY_SIZE]uint8
+
+	rec[7] = mc.bmcIpmb
+	rec[9] = SEL_EVM_REV
+	rec[10] = sensorType
+	rec[11] = sensorNum
+	rec[12] = eventType
+	if deassert {
+		rec[12] |= SEL_DEASSERTION
+	}
+	rec[13] = offset
+	rec[14] = 0xff // unspecified event data 2 and 3
+	rec[15] = 0xff
+	if err, _ := addToSel(SEL_SYSTEM_EVENT_RECORD, rec[:]); err != 0 {
+		fmt.Printf("SEL full: sensor %#x event %#x dropped\n",
+			sensorNum, offset)
+	}
+}
+
+// Subscribe to the channel and record its alarm transitions until
+// signaled.
+func selWatch(channel string) {
+	for !Signaled() {
+		psc, err := redis.Subscribe(channel)
+		if err != nil {
+			fmt.Println("SEL subscribe:", channel, err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		for !Signaled() {
+			v := psc.Receive()
+			if m, ok := v.(redigo.Message); ok {
+				lanMutex.Lock()
+				selFeed(m.Channel, string(m.Data))
+				lanMutex.Unlock()
+			} else if err, ok = v.(error); ok {
+				fmt.Println("SEL subscription:", channel, err)
+				break
+			}
+		}
+		psc.Close()
+	}
+}
+
+// Record the events of the channel message; either a qsfp alarm event or
+// a "FIELD: VALUE" of the machine hash.
+func selFeed(channel, s string) {
+	if channel == opticsalarm.Channel {
+		selQsfpEvent(s)
+		return
+	}
+	x := strings.SplitN(s, ": ", 2)
+	if len(x) != 2 {
+		return
+	}
+	k, v := x[0], x[1]
+	if k == "delete" {
+		delete(selLast, v)
+		return
+	}
+	var n int
+	switch {
+	case sscan(k, "psu%d.status", &n):
+		selPsuStatus(k, uint8(n), v)
+	case sscan(k, "fan_tray.%d.status", &n):
+		selFanTrayStatus(k, uint8(n), v)
+	case strings.HasSuffix(k, "poweroff.events"):
+		selPowerOffEvents(k, v)
+	}
+}
+
+func sscan(s, format string, n *int) bool {
+	_, err := fmt.Sscanf(s, format, n)
+	return err == nil && fmt.Sprintf(format, *n) == s
+}
+
+// Update the last field value and return the previous value, or, if
+// unknown, the nominal.
+func selUpdate(k, v, nominal string) string {
+	old, found := selLast[k]
+	if !found {
+		old = nominal
+	}
+	selLast[k] = v
+	return old
+}
+
+// fspd status: powered_on, powered_off, not_installed, not_found, or
+// undetermined.
+func selPsuStatus(k string, n uint8, v string) {
+	state := func(s string) (present, failed, known bool) {
+		switch s {
+		case "powered_on":
+			return true, false, true
+		case "powered_off":
+			return true, true, true
+		case "not_installed", "not_found":
+			return false, false, true
+		}
+		return
+	}
+	newPresent, newFailed, known := state(v)
+	if !known {
+		return
+	}
+	oldPresent, oldFailed, _ := state(selUpdate(k, v, "powered_on"))
+	if newPresent != oldPresent {
+		selAddEvent(SENSOR_TYPE_POWER_SUPPLY, SEL_SENSOR_PSU+n,
+			EVENT_TYPE_SENSOR_SPECIFIC, 0, !newPresent)
+	}
+	if newFailed != oldFailed {
+		selAddEvent(SENSOR_TYPE_POWER_SUPPLY, SEL_SENSOR_PSU+n,
+			EVENT_TYPE_SENSOR_SPECIFIC, 1, !newFailed)
+	}
+}
+
+// fantrayd status: ok.DIRECTION, warning low rpm detected, or not
+// installed.
+func selFanTrayStatus(k string, n uint8, v string) {
+	state := func(s string) (present, low bool) {
+		present = s != "not installed"
+		low = strings.HasPrefix(s, "warning")
+		return
+	}
+	newPresent, newLow := state(v)
+	oldPresent, oldLow := state(selUpdate(k, v, "ok"))
+	if newPresent != oldPresent {
+		// device removed/absent or inserted/present
+		offset := uint8(0)
+		if newPresent {
+			offset = 1
+		}
+		selAddEvent(SENSOR_TYPE_FAN, SEL_SENSOR_FAN_TRAY+n,
+			EVENT_TYPE_PRESENCE, offset, false)
+	}
+	if newLow != oldLow {
+		// lower critical going low
+		selAddEvent(SENSOR_TYPE_FAN, SEL_SENSOR_FAN_TRAY+n,
+			EVENT_TYPE_THRESHOLD, 2, !newLow)
+	}
+}
+
+// ucd9090d power off events are period separated timestamps; record a
+// power down for each that's new since the first seen.
+func selPowerOffEvents(k, v string) {
+	old, found := selLast[k]
+	selLast[k] = v
+	if !found {
+		return
+	}
+	seen := make(map[string]bool)
+	for _, t := range strings.Split(old, ".") {
+		seen[t] = true
+	}
+	for _, t := range strings.Split(v, ".") {
+		if t != "" && !seen[t] {
+			selAddEvent(SENSOR_TYPE_POWER_UNIT,
+				SEL_SENSOR_POWER_UNIT,
+				EVENT_TYPE_SENSOR_SPECIFIC, 0, false)
+		}
+	}
+}
+
+// The threshold event offsets of the optics alarm and warning flags.
+var selQsfpThresholds = map[string]uint8{
+	"LowWarn":   0x00, // lower non-critical going low
+	"LowAlarm":  0x02, // lower critical going low
+	"HighWarn":  0x07, // upper non-critical going high
+	"HighAlarm": 0x09, // upper critical going high
+}
+
+// Record the optics alarm event formatted like this:
+//
+//	2018-06-01T12:00:00Z port-5.qsfp.rx1.alarms: RxLos raised
+//	2018-06-01T12:00:01Z port-5.qsfp.alarms: TempHighWarn raised at 66.0
+func selQsfpEvent(s string) {
+	var sensorNum uint8
+
+	f := strings.Fields(s)
+	if len(f) < 4 || !strings.HasPrefix(f[1], "port-") {
+		return
+	}
+	port := strings.SplitN(strings.TrimPrefix(f[1], "port-"), ".", 2)[0]
+	if strings.HasPrefix(port, "M") {
+		n, err := strconv.ParseUint(port[1:], 10, 8)
+		if err != nil {
+			return
+		}
+		sensorNum = SEL_SENSOR_MGMT_PORT + uint8(n)
+	} else {
+		n, err := strconv.ParseUint(port, 10, 8)
+		if err != nil || n >= SEL_SENSOR_MGMT_PORT-SEL_SENSOR_PORT {
+			return
+		}
+		sensorNum = SEL_SENSOR_PORT + uint8(n)
+	}
+	alarm, deassert := f[2], f[3] != "raised"
+	for suffix, offset := range selQsfpThresholds {
+		if !strings.HasSuffix(alarm, suffix) {
+			continue
+		}
+		sensorType := uint8(SENSOR_TYPE_OTHER_UNITS)
+		if strings.HasPrefix(alarm, "Temp") {
+			sensorType = SENSOR_TYPE_TEMPERATURE
+		} else if strings.HasPrefix(alarm, "Vcc") {
+			sensorType = SENSOR_TYPE_VOLTAGE
+		}
+		selAddEvent(sensorType, sensorNum, EVENT_TYPE_THRESHOLD,
+			offset, deassert)
+		return
+	}
+	// Other flags, e.g. RxLos or TxFault, assert the cable state.
+	selAddEvent(SENSOR_TYPE_CABLE, sensorNum, EVENT_TYPE_DIGITAL_DISCRETE,
+		1, deassert)
+}
+
+// Format the SEL entry like this:
+//
+//	12 2018-06-01T12:00:00Z sensor 0x08 #0x51 event 0x6f 0x01 asserted
+func selString(e *selEntryT) string {
+	var buf bytes.Buffer
+	t := time.Unix(int64(binary.LittleEndian.Uint32(e.data[3:7])), 0)
+	fmt.Fprint(&buf, e.recordId, " ", t.UTC().Format(time.RFC3339))
+	if e.data[2] != SEL_SYSTEM_EVENT_RECORD {
+		fmt.Fprintf(&buf, " type 0x%02x % x", e.data[2], e.data[7:])
+		return buf.String()
+	}
+	fmt.Fprintf(&buf, " sensor 0x%02x #0x%02x event 0x%02x 0x%02x",
+		e.data[10], e.data[11], e.data[12]&^SEL_DEASSERTION,
+		e.data[13]&0xf)
+	if e.data[12]&SEL_DEASSERTION != 0 {
+		buf.WriteString(" deasserted")
+	} else {
+		buf.WriteString(" asserted")
+	}
+	return buf.String()
+}
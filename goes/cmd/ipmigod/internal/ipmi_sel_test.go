@@ -0,0 +1,213 @@
+// Copyright 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Returns the record ids and data of all entries.
+func (c *console) selList() (ids []uint16, records [][]uint8) {
+	for id := uint16(0); id != 0xffff; {
+		var req [6]uint8
+		binary.LittleEndian.PutUint16(req[2:4], id)
+		req[5] = 0xff
+		rsp := c.ipmi(c.sid, STORAGE_NETFN, GET_SEL_ENTRY_CMD, req[:]...)
+		if rsp[0] == IPMI_NOT_PRESENT_CC && id == 0 {
+			break
+		}
+		if len(rsp) != 3+SEL_ENTRY_SIZE || rsp[0] != 0 {
+			c.t.Fatalf("get SEL entry %d: %x", id, rsp)
+		}
+		ids = append(ids, binary.LittleEndian.Uint16(rsp[3:5]))
+		records = append(records, rsp[3:])
+		id = binary.LittleEndian.Uint16(rsp[1:3])
+	}
+	return
+}
+
+func (c *console) selReserve() []uint8 {
+	rsp := c.ipmi(c.sid, STORAGE_NETFN, RESERVE_SEL_CMD)
+	if len(rsp) != 3 || rsp[0] != 0 {
+		c.t.Fatalf("reserve SEL: %x", rsp)
+	}
+	return rsp[1:3]
+}
+
+func feed(channel string, messages ...string) {
+	lanMutex.Lock()
+	defer lanMutex.Unlock()
+	for _, s := range messages {
+		selFeed(channel, s)
+	}
+}
+
+func TestSel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	addr := serve(t)
+	alerts := make(chan string, 4)
+	lanMutex.Lock()
+	err = ParseConfig([]byte(testConfig + `
+sel:
+  file: ` + filepath.Join(dir, "sel") + `
+  entries: 4
+pef:
+  filters:
+  - sensorType: 8
+    severity: critical
+`))
+	mc.sel.entries, mc.sel.count, mc.sel.flags = nil, 0, 0
+	mc.sel.nextEntry = 1
+	selLast = make(map[string]string)
+	pef.alerts = alerts
+	lanMutex.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := newConsole(t, addr, 3)
+	c.handshake(3, 0x10|IPMI_PRIVILEGE_ADMIN, "ipmiusr", "test")
+	c.ipmi(c.sid, APP_NETFN, SET_SESSION_PRIVILEGE_CMD,
+		IPMI_PRIVILEGE_ADMIN)
+
+	feed("platform",
+		"psu1.status: powered_on",
+		"psu1.status: powered_off",
+		"fan_tray.2.status: ok.front->back",
+		"fan_tray.2.status: warning low rpm detected",
+		"vmon.poweroff.events: 1528000000",
+		"vmon.poweroff.events: 1528000000.1528000100")
+	feed("qsfp.events",
+		"2018-06-01T12:00:01Z port-5.qsfp.alarms: TempHighWarn raised at 66.0")
+
+	ids, records := c.selList()
+	if len(ids) != 4 {
+		t.Fatalf("SEL entries: %v", ids)
+	}
+	for i, want := range [][]uint8{
+		{0x08, 0x51, 0x6f, 1},    // psu1 failure
+		{0x04, 0x62, 0x01, 2},    // fan tray 2 lower critical
+		{0x09, 0x70, 0x6f, 0},    // power down
+		{0x01, 0x85, 0x01, 0x07}, // port 5 upper non-critical
+	} {
+		if !bytes.Equal(records[i][10:14], want) {
+			t.Errorf("SEL entry %d: %x", ids[i], records[i])
+		}
+	}
+	select {
+	case s := <-alerts:
+		if !strings.Contains(s, "sensor 0x08 #0x51 event 0x6f 0x01") {
+			t.Errorf("PEF alert: %s", s)
+		}
+	default:
+		t.Error("no PEF alert")
+	}
+	select {
+	case s := <-alerts:
+		t.Errorf("unfiltered PEF alert: %s", s)
+	default:
+	}
+
+	// the SEL is full
+	feed("platform", "psu1.status: powered_on")
+	rsp := c.ipmi(c.sid, STORAGE_NETFN, GET_SEL_INFO_CMD)
+	if len(rsp) != 15 || binary.LittleEndian.Uint16(rsp[2:4]) != 4 ||
+		rsp[14] != SEL_OVERFLOW|0xb {
+		t.Errorf("SEL info: %x", rsp)
+	}
+
+	reservation := c.selReserve()
+	req := append(append([]uint8{}, reservation...), 0, 0)
+	binary.LittleEndian.PutUint16(req[2:4], ids[1])
+	rsp = c.ipmi(c.sid, STORAGE_NETFN, DELETE_SEL_ENTRY_CMD, req...)
+	if len(rsp) != 3 || rsp[0] != 0 {
+		t.Errorf("delete SEL entry: %x", rsp)
+	}
+	c.selReserve()
+	rsp = c.ipmi(c.sid, STORAGE_NETFN, DELETE_SEL_ENTRY_CMD, req...)
+	if !bytes.Equal(rsp, []uint8{IPMI_INVALID_RESERVATION_CC}) {
+		t.Errorf("delete SEL entry with cancelled reservation: %x", rsp)
+	}
+	if ids, _ = c.selList(); len(ids) != 3 || ids[1] != 3 {
+		t.Errorf("SEL entries after delete: %v", ids)
+	}
+
+	// the log persists
+	lanMutex.Lock()
+	mc.sel.entries, mc.sel.count = nil, 0
+	err = selLoad()
+	lanMutex.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded, _ := c.selList(); len(reloaded) != 3 {
+		t.Errorf("SEL entries reloaded: %v", reloaded)
+	}
+
+	when := uint32(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).Unix())
+	var tm [4]uint8
+	binary.LittleEndian.PutUint32(tm[:], when)
+	c.ipmi(c.sid, STORAGE_NETFN, SET_SEL_TIME_CMD, tm[:]...)
+	rsp = c.ipmi(c.sid, STORAGE_NETFN, GET_SEL_TIME_CMD)
+	if len(rsp) != 5 || binary.LittleEndian.Uint32(rsp[1:])-when > 2 {
+		t.Errorf("SEL time: %x", rsp)
+	}
+	lanMutex.Lock()
+	mc.sel.timeOffset = 0
+	lanMutex.Unlock()
+
+	req = append(c.selReserve(), 'C', 'L', 'R', 0xaa)
+	rsp = c.ipmi(c.sid, STORAGE_NETFN, CLEAR_SEL_CMD, req...)
+	if !bytes.Equal(rsp, []uint8{0, SEL_ERASE_COMPLETED}) {
+		t.Errorf("clear SEL: %x", rsp)
+	}
+	rsp = c.ipmi(c.sid, STORAGE_NETFN, GET_SEL_INFO_CMD)
+	if len(rsp) != 15 || binary.LittleEndian.Uint16(rsp[2:4]) != 0 ||
+		rsp[14] != 0xb {
+		t.Errorf("SEL info after clear: %x", rsp)
+	}
+
+	var sid [4]uint8
+	binary.LittleEndian.PutUint32(sid[:], c.sid)
+	c.ipmi(c.sid, APP_NETFN, CLOSE_SESSION_CMD, sid[:]...)
+}
+
+func TestSelWrap(t *testing.T) {
+	lanMutex.Lock()
+	defer lanMutex.Unlock()
+	err := ParseConfig([]byte(testConfig + `
+sel:
+  file: none
+  entries: 2
+  overflow: wrap
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc.sel.entries, mc.sel.count, mc.sel.flags = nil, 0, 0
+	mc.sel.nextEntry = 0xfffe
+	for i := uint8(0); i < 3; i++ {
+		selAddEvent(SENSOR_TYPE_FAN, SEL_SENSOR_FAN_TRAY+i,
+			EVENT_TYPE_PRESENCE, 0, false)
+	}
+	// 0 and 0xffff aren't record ids
+	if mc.sel.count != 2 || mc.sel.flags&SEL_OVERFLOW == 0 ||
+		mc.sel.entries[0].recordId != 1 ||
+		mc.sel.entries[1].recordId != 2 ||
+		mc.sel.entries[1].data[11] != SEL_SENSOR_FAN_TRAY+2 {
+		t.Errorf("wrapped SEL: %+v", mc.sel)
+	}
+}
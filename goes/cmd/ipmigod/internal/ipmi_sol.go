@@ -0,0 +1,378 @@
+// Copyright 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package contains IPMI 2.0 spec implementation
+package internal
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	SOL_PAYLOAD_SIZE = 252 // inbound and outbound, including header
+	SOL_HDR_LEN      = 4
+	SOL_MAX_CHARS    = SOL_PAYLOAD_SIZE - SOL_HDR_LEN
+	SOL_BUFFER_SIZE  = 4096 // console output held while unacknowledged
+
+	// BMC to remote console status
+	SOL_STATUS_NACK         = 0x40
+	SOL_STATUS_UNAVAILABLE  = 0x20
+	SOL_STATUS_DEACTIVATING = 0x10
+	SOL_STATUS_OVERRUN      = 0x08
+	SOL_STATUS_BREAK        = 0x04
+
+	// Remote console to BMC operation
+	SOL_OP_NACK           = 0x40
+	SOL_OP_BREAK          = 0x10
+	SOL_OP_FLUSH_INBOUND  = 0x02
+	SOL_OP_FLUSH_OUTBOUND = 0x01
+
+	// missing from syscall
+	TCSBRK   = 0x5409
+	TCFLSH   = 0x540b
+	TCIFLUSH = 0
+)
+
+// SOL configuration parameters
+const (
+	SOL_PARM_SET_IN_PROGRESS = iota
+	SOL_PARM_ENABLE
+	SOL_PARM_AUTH
+	SOL_PARM_ACCUMULATE
+	SOL_PARM_RETRY
+	SOL_PARM_NV_BIT_RATE
+	SOL_PARM_BIT_RATE
+	SOL_PARM_PAYLOAD_CHANNEL
+	SOL_PARM_PAYLOAD_PORT
+)
+
+var solBitRates = map[int]uint8{
+	9600:   0x6,
+	19200:  0x7,
+	38400:  0x8,
+	57600:  0x9,
+	115200: 0xa,
+}
+
+var solBauds = map[uint8]uint32{
+	0x6: syscall.B9600,
+	0x7: syscall.B19200,
+	0x8: syscall.B38400,
+	0x9: syscall.B57600,
+	0xa: syscall.B115200,
+}
+
+type solT struct {
+	// configuration parameters
+	device             string
+	enable             bool
+	setInProgress      uint8
+	auth               uint8 // force encryption, authentication, and priv
+	accumulateInterval uint8 // 5ms units
+	sendThreshold      uint8
+	retryCount         uint8
+	retryInterval      uint8 // 10ms units
+	nvBitRate          uint8
+	bitRate            uint8 // 0 is nvBitRate
+
+	// active payload instance
+	session  *sessionT
+	conn     *net.UDPConn
+	addr     *net.UDPAddr
+	tty      *os.File
+	gen      int // of activation, to ignore stale timers
+	buf      []uint8
+	overrun  bool
+	inflight []uint8
+	seq      uint8 // of the inflight packet
+	retries  uint8
+	recvSeq  uint8 // of the last packet from the remote console
+	accepted uint8
+	timer    *time.Timer
+	pending  bool // accumulating characters
+}
+
+var sol = solT{
+	auth:               IPMI_PRIVILEGE_USER,
+	accumulateInterval: 12,
+	sendThreshold:      96,
+	retryCount:         7,
+	retryInterval:      50,
+	nvBitRate:          0xa,
+}
+
+func ioctl(fd uintptr, req, arg uintptr) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, arg)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Open the host console tty for exclusive, raw use like femtocom.
+func solOpen(device string, bitRate uint8) (*os.File, error) {
+	var t syscall.Termios
+
+	tty, err := os.OpenFile(device, os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err = ioctl(tty.Fd(), syscall.TIOCEXCL, 0); err != nil {
+		tty.Close()
+		return nil, fmt.Errorf("TIOCEXCL: %s: %v", device, err)
+	}
+	err = ioctl(tty.Fd(), syscall.TCGETS, uintptr(unsafe.Pointer(&t)))
+	if err != nil {
+		tty.Close()
+		return nil, fmt.Errorf("TCGETS: %s: %v", device, err)
+	}
+	t.Iflag &^= syscall.IGNBRK |
+		syscall.BRKINT |
+		syscall.PARMRK |
+		syscall.ISTRIP |
+		syscall.INLCR |
+		syscall.IGNCR |
+		syscall.ICRNL |
+		syscall.IXON
+	t.Oflag &^= syscall.OPOST
+	t.Lflag &^= syscall.ECHO |
+		syscall.ECHONL |
+		syscall.ICANON |
+		syscall.ISIG |
+		syscall.IEXTEN
+	t.Cflag = solBauds[bitRate] |
+		syscall.CS8 |
+		syscall.HUPCL |
+		syscall.CREAD |
+		syscall.CLOCAL
+	t.Ispeed = solBauds[bitRate]
+	t.Ospeed = solBauds[bitRate]
+	err = ioctl(tty.Fd(), syscall.TCSETS, uintptr(unsafe.Pointer(&t)))
+	if err != nil {
+		tty.Close()
+		return nil, fmt.Errorf("TCSETS: %s: %v", device, err)
+	}
+	return tty, nil
+}
+
+// Activate the SOL payload instance on the session of the message.
+func (msg *msgT) solActivate(session *sessionT) error {
+	bitRate := sol.bitRate
+	if bitRate == 0 {
+		bitRate = sol.nvBitRate
+	}
+	tty, err := solOpen(sol.device, bitRate)
+	if err != nil {
+		return err
+	}
+	sol.session = session
+	sol.conn = msg.conn
+	sol.addr = msg.remoteAddr
+	sol.tty = tty
+	sol.gen++
+	sol.buf = sol.buf[:0]
+	sol.overrun = false
+	sol.inflight = nil
+	sol.seq = 0
+	sol.recvSeq = 0
+	sol.pending = false
+	fmt.Printf("Session %d activated SOL on %s\n", session.handle,
+		sol.device)
+	go solRead(tty, sol.gen)
+	return nil
+}
+
+// Deactivate the SOL payload instance; if by another session, notify the
+// remote console.
+func solDeactivate(by *sessionT) {
+	if sol.session == nil {
+		return
+	}
+	if by != sol.session && sol.session.active {
+		solSend([]uint8{0, 0, 0, SOL_STATUS_DEACTIVATING})
+	}
+	fmt.Printf("Session %d deactivated SOL\n", sol.session.handle)
+	if sol.timer != nil {
+		sol.timer.Stop()
+		sol.timer = nil
+	}
+	ioctl(sol.tty.Fd(), syscall.TIOCNXCL, 0)
+	sol.tty.Close()
+	sol.tty = nil
+	sol.session = nil
+	sol.inflight = nil
+	sol.gen++
+}
+
+// Copy host console output to the buffer to send after the character
+// accumulate interval or once there are send threshold characters.
+func solRead(tty *os.File, gen int) {
+	rx := make([]uint8, SOL_MAX_CHARS)
+	for {
+		n, err := tty.Read(rx)
+		if err != nil {
+			return
+		}
+		lanMutex.Lock()
+		if sol.gen != gen {
+			lanMutex.Unlock()
+			return
+		}
+		if len(sol.buf)+n > SOL_BUFFER_SIZE {
+			n = SOL_BUFFER_SIZE - len(sol.buf)
+			sol.overrun = true
+		}
+		sol.buf = append(sol.buf, rx[:n]...)
+		if sol.inflight == nil {
+			if len(sol.buf) >= int(sol.sendThreshold) {
+				solFlush()
+			} else if !sol.pending {
+				sol.pending = true
+				solAfter(time.Duration(sol.accumulateInterval)*
+					5*time.Millisecond, solFlush)
+			}
+		}
+		lanMutex.Unlock()
+	}
+}
+
+// Run f after d unless SOL is deactivated or the timer replaced.
+func solAfter(d time.Duration, f func()) {
+	gen := sol.gen
+	if sol.timer != nil {
+		sol.timer.Stop()
+	}
+	var t *time.Timer
+	t = time.AfterFunc(d, func() {
+		lanMutex.Lock()
+		defer lanMutex.Unlock()
+		if sol.gen == gen && sol.timer == t {
+			sol.timer = nil
+			f()
+		}
+	})
+	sol.timer = t
+}
+
+// Send the next packet of buffered console output.
+func solFlush() {
+	sol.pending = false
+	if sol.inflight != nil || len(sol.buf) == 0 {
+		return
+	}
+	n := len(sol.buf)
+	if n > SOL_MAX_CHARS {
+		n = SOL_MAX_CHARS
+	}
+	sol.inflight = append([]uint8{}, sol.buf[:n]...)
+	sol.buf = append(sol.buf[:0], sol.buf[n:]...)
+	sol.seq = sol.seq%15 + 1
+	sol.retries = 0
+	solTransmit()
+}
+
+func solTransmit() {
+	var status uint8
+
+	if sol.overrun {
+		status |= SOL_STATUS_OVERRUN
+		sol.overrun = false
+	}
+	solSend(append([]uint8{sol.seq, 0, 0, status}, sol.inflight...))
+	solAfter(time.Duration(sol.retryInterval)*10*time.Millisecond,
+		solRetry)
+}
+
+// Retransmit the unacknowledged packet up to retry count times before
+// dropping it.
+func solRetry() {
+	if sol.inflight == nil {
+		return
+	}
+	if sol.retries < sol.retryCount&0x7 {
+		sol.retries++
+		solTransmit()
+		return
+	}
+	fmt.Println("SOL packet dropped: seq", sol.seq)
+	sol.inflight = nil
+	solFlush()
+}
+
+func solSend(payload []uint8) {
+	msg := &msgT{conn: sol.conn, remoteAddr: sol.addr}
+	msg.rmcppSend(sol.session, RMCPP_PAYLOAD_SOL, payload)
+}
+
+// Handle the SOL packet from the remote console: acknowledge console
+// output, and write its characters to the host console.
+func (msg *msgT) solHandle(session *sessionT) {
+	if session != sol.session {
+		fmt.Printf("SOL msg - payload not active on session %d\n",
+			session.handle)
+		return
+	}
+	pkt := msg.rmcppPayload()
+	if len(pkt) < SOL_HDR_LEN {
+		fmt.Println("SOL msg failure: message too short", len(pkt))
+		return
+	}
+	seq, ackSeq, count, op := pkt[0]&0xf, pkt[1]&0xf, int(pkt[2]), pkt[3]
+	data := pkt[SOL_HDR_LEN:]
+
+	if ackSeq != 0 && ackSeq == sol.seq && sol.inflight != nil {
+		if count > len(sol.inflight) {
+			count = len(sol.inflight)
+		}
+		rest := sol.inflight[count:]
+		sol.inflight = nil
+		if sol.timer != nil {
+			sol.timer.Stop()
+			sol.timer = nil
+		}
+		// requeue characters not accepted
+		sol.buf = append(rest, sol.buf...)
+		if op&SOL_OP_NACK != 0 {
+			// the remote console isn't ready; retry later
+			sol.pending = true
+			solAfter(time.Duration(sol.retryInterval)*
+				10*time.Millisecond, solFlush)
+		} else {
+			solFlush()
+		}
+	}
+
+	if op&SOL_OP_FLUSH_OUTBOUND != 0 {
+		sol.buf = sol.buf[:0]
+	}
+	if op&SOL_OP_FLUSH_INBOUND != 0 {
+		ioctl(sol.tty.Fd(), TCFLSH, TCIFLUSH)
+	}
+	if seq == 0 {
+		return // ack only
+	}
+	if seq != sol.recvSeq {
+		// a new, rather than retransmitted, packet
+		sol.recvSeq = seq
+		sol.accepted = 0
+		if op&SOL_OP_BREAK != 0 {
+			ioctl(sol.tty.Fd(), TCSBRK, 0)
+		}
+		for len(data) > 0 {
+			n, err := sol.tty.Write(data)
+			sol.accepted += uint8(n)
+			if err != nil {
+				fmt.Println("SOL write:", err)
+				break
+			}
+			data = data[n:]
+		}
+	}
+	solSend([]uint8{0, seq, sol.accepted, 0})
+}
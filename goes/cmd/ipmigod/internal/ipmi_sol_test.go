@@ -0,0 +1,145 @@
+// Copyright 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// Returns the master and slave name of a new pty, the stand-in host
+// console UART.
+func openPty(t *testing.T) (*os.File, string) {
+	var n, unlock uint32
+
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		t.Skip(err)
+	}
+	err = ioctl(master.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n)))
+	if err != nil {
+		t.Fatal("TIOCGPTN:", err)
+	}
+	err = ioctl(master.Fd(), syscall.TIOCSPTLCK,
+		uintptr(unsafe.Pointer(&unlock)))
+	if err != nil {
+		t.Fatal("TIOCSPTLCK:", err)
+	}
+	return master, fmt.Sprintf("/dev/pts/%d", n)
+}
+
+func readPty(t *testing.T, master *os.File, want string) {
+	b := make([]uint8, 64)
+	master.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := master.Read(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b[:n]); s != want {
+		t.Errorf("host console: %q", s)
+	}
+}
+
+func TestSol(t *testing.T) {
+	addr := serve(t)
+	master, pts := openPty(t)
+	defer master.Close()
+	lanMutex.Lock()
+	sol.device, sol.enable = pts, true
+	lanMutex.Unlock()
+
+	c := newConsole(t, addr, 17)
+	c.handshake(17, 0x10|IPMI_PRIVILEGE_ADMIN, "ipmiusr", "test")
+	c.ipmi(c.sid, APP_NETFN, SET_SESSION_PRIVILEGE_CMD,
+		IPMI_PRIVILEGE_ADMIN)
+
+	// send after 5ms and retry once after 50ms
+	for _, parm := range [][]uint8{
+		{0xe, SOL_PARM_ACCUMULATE, 1, 200},
+		{0xe, SOL_PARM_RETRY, 1, 5},
+	} {
+		rsp := c.ipmi(c.sid, TRANSPORT_NETFN,
+			SET_SOL_CONFIGURATION_PARAMETERS, parm...)
+		if !bytes.Equal(rsp, []uint8{0}) {
+			t.Fatalf("set SOL parameter %d: %x", parm[1], rsp)
+		}
+	}
+	rsp := c.ipmi(c.sid, TRANSPORT_NETFN, GET_SOL_CONFIGURATION_PARAMETERS,
+		0xe, SOL_PARM_RETRY, 0, 0)
+	if !bytes.Equal(rsp, []uint8{0, 0x11, 1, 5}) {
+		t.Errorf("get SOL retry: %x", rsp)
+	}
+	rsp = c.ipmi(c.sid, TRANSPORT_NETFN, SET_SOL_CONFIGURATION_PARAMETERS,
+		0xe, SOL_PARM_PAYLOAD_PORT, 0, 1)
+	if !bytes.Equal(rsp, []uint8{0x82}) {
+		t.Errorf("set SOL payload port: %x", rsp)
+	}
+
+	rsp = c.ipmi(c.sid, APP_NETFN, ACTIVATE_PAYLOAD_CMD,
+		RMCPP_PAYLOAD_SOL, 1, 0xc0, 0, 0, 0)
+	if len(rsp) != 13 || rsp[0] != 0 || rsp[9] != 0x6f || rsp[10] != 2 {
+		t.Fatalf("activate payload: %x", rsp)
+	}
+	rsp = c.ipmi(c.sid, APP_NETFN, ACTIVATE_PAYLOAD_CMD,
+		RMCPP_PAYLOAD_SOL, 1, 0xc0, 0, 0, 0)
+	if !bytes.Equal(rsp, []uint8{0x80}) {
+		t.Errorf("activate active payload: %x", rsp)
+	}
+
+	// host console output is retried then dropped if unacknowledged
+	master.Write([]uint8("login: "))
+	pkt := c.recv(RMCPP_PAYLOAD_SOL)
+	if pkt[0] != 1 || string(pkt[4:]) != "login: " {
+		t.Errorf("SOL packet: %x", pkt)
+	}
+	if retry := c.recv(RMCPP_PAYLOAD_SOL); !bytes.Equal(retry, pkt) {
+		t.Errorf("SOL retry: %x", retry)
+	}
+	master.Write([]uint8("x"))
+	pkt = c.recv(RMCPP_PAYLOAD_SOL)
+	if pkt[0] != 2 || string(pkt[4:]) != "x" {
+		t.Errorf("SOL packet: %x", pkt)
+	}
+	c.send(RMCPP_PAYLOAD_SOL, c.sid, []uint8{0, 2, 1, 0})
+
+	// remote console input is acknowledged and written once
+	in := append([]uint8{1, 0, 0, 0}, "root\r"...)
+	for i := 0; i < 2; i++ {
+		c.send(RMCPP_PAYLOAD_SOL, c.sid, in)
+		if ack := c.recv(RMCPP_PAYLOAD_SOL); !bytes.Equal(ack,
+			[]uint8{0, 1, 5, 0}) {
+			t.Errorf("SOL ack: %x", ack)
+		}
+	}
+	readPty(t, master, "root\r")
+	c.send(RMCPP_PAYLOAD_SOL, c.sid, append([]uint8{2, 0, 0, 0}, "ls\r"...))
+	c.recv(RMCPP_PAYLOAD_SOL)
+	readPty(t, master, "ls\r")
+
+	rsp = c.ipmi(c.sid, APP_NETFN, GET_PAYLOAD_ACTIVATION_STATUS_CMD,
+		RMCPP_PAYLOAD_SOL)
+	if !bytes.Equal(rsp, []uint8{0, 1, 1, 0}) {
+		t.Errorf("payload activation status: %x", rsp)
+	}
+	rsp = c.ipmi(c.sid, APP_NETFN, DEACTIVATE_PAYLOAD_CMD,
+		RMCPP_PAYLOAD_SOL, 1, 0, 0, 0, 0)
+	if !bytes.Equal(rsp, []uint8{0}) {
+		t.Errorf("deactivate payload: %x", rsp)
+	}
+	rsp = c.ipmi(c.sid, APP_NETFN, GET_PAYLOAD_ACTIVATION_STATUS_CMD,
+		RMCPP_PAYLOAD_SOL)
+	if !bytes.Equal(rsp, []uint8{0, 1, 0, 0}) {
+		t.Errorf("payload activation status: %x", rsp)
+	}
+	var sid [4]uint8
+	binary.LittleEndian.PutUint32(sid[:], c.sid)
+	c.ipmi(c.sid, APP_NETFN, CLOSE_SESSION_CMD, sid[:]...)
+}
@@ -8,40 +8,113 @@ package ipmigod
 import (
 	"strconv"
 
+	"github.com/platinasystems/go/goes/cmd"
+	"github.com/platinasystems/go/goes/cmd/ipmigod/internal"
+	"github.com/platinasystems/go/goes/lang"
 	"github.com/platinasystems/go/internal/flags"
-	"github.com/platinasystems/go/goes"
+	"github.com/platinasystems/go/internal/machine"
+	"github.com/platinasystems/go/internal/opticsalarm"
 	"github.com/platinasystems/go/internal/parms"
 	"github.com/platinasystems/go/internal/redis"
 )
 
-const Name = "ipmigod"
+type Command struct {
+	done chan struct{}
+}
+
+func (*Command) String() string { return "ipmigod" }
+
+func (*Command) Usage() string {
+	return "ipmigod [-mm] [-lc NUMBER] [-config FILE]"
+}
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "ipmigod daemon",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Serve IPMI 1.5 and 2.0 (RMCP+) sessions on UDP port 623.
+
+	RMCP+ sessions are established with RAKP key exchange then
+	authenticated with HMAC-SHA1-96 or HMAC-SHA256-128 and encrypted with
+	AES-CBC-128 per the negotiated cipher suite.
 
-type cmd struct{}
+	With a configured SOL device, RMCP+ sessions may activate the
+	Serial-over-LAN payload to reach the host console instead of running
+	femtocom on the BMC, e.g. "ipmitool -I lanplus ... sol activate".
 
-func New() cmd { return cmd{} }
+	The System Event Log records the PSU, fan tray, power off, and optics
+	alarm transitions published by fspd, fantrayd, ucd9090d, and qsfp,
+	e.g. "ipmitool ... sel list".  Events matching the platform event
+	filters are alerted on the ` + internal.PEF_ALERT_CHANNEL + ` redis channel.
 
-func (cmd) String() string { return Name }
-func (cmd) Usage() string  { return Name + " [OPTIONS]..." }
+OPTIONS
+	-mm	management card; default if cmdline card/type is management
+	-lc NUMBER
+		line card number; default: cmdline card/slot
+	-config FILE
+		YAML users and cipher suites; default: ` +
+			internal.DefaultConfigFile + `
 
-func (cmd) Apropos() map[string]string {
-	return map[string]string{
-		"en_US.UTF-8": "ipmigod daemon",
+CONFIG
+	guid: a123456789abcdefa123456789abcdef
+	sessionTimeout: 30
+	cipherSuites: [3, 17]
+	users:
+	- id: 2
+	  name: ipmiusr
+	  password: test
+	  privilege: admin	# callback, user, operator, admin, or oem
+	  auths: [none]		# IPMI 1.5: none, md2, md5, straight, oem
+	sol:
+	  device: /dev/ttyS1	# the host console DEVICE of femtocom
+	  baud: 115200
+	sel:
+	  file: /var/lib/ipmigod/sel	# or none to keep in memory
+	  entries: 1000
+	  overflow: stop	# or wrap to replace the oldest entries
+	pef:
+	  filters:
+	  - sensorType: 8	# power supply; default any
+	    sensor: 0x51	# default any
+	    severity: critical
+
+	Without FILE, the IPMI 1.5 users are the null user and ipmiusr, both
+	with password test, and RMCP+ (lanplus) is disabled; it's enabled by
+	the cipherSuites of FILE: 1, 2, 3, 15, 16, or 17.  Since FILE has
+	passwords, it shouldn't be readable by others.`,
 	}
 }
 
-func (cmd) Kind() goes.Kind { return goes.Daemon }
+func (c *Command) Close() error {
+	close(c.done)
+	return nil
+}
 
-func (cmd) Main(args ...string) error {
+func (*Command) Kind() cmd.Kind { return cmd.Daemon }
+
+func (c *Command) Main(args ...string) error {
+	c.done = make(chan struct{})
 	flag, args := flags.New(args, "-mm")
-	parm, args := parms.New(args, "-lc")
+	parm, args := parms.New(args, "-lc", "-config")
 	for k, v := range map[string]string{
-		"-lc": "0",
+		"-lc":     "0",
+		"-config": internal.DefaultConfigFile,
 	} {
-		if len(parm[k]) == 0 {
-			parm[k] = v
+		if len(parm.ByName[k]) == 0 {
+			parm.ByName[k] = v
 		}
 	}
 
+	if err := internal.LoadConfig(parm.ByName["-config"]); err != nil {
+		return err
+	}
+
 	cardSlot, err := redis.Hget("cmdline", "card/slot")
 	if err != nil {
 		return err
@@ -51,9 +124,9 @@ func (cmd) Main(args ...string) error {
 		return err
 	}
 
-	mmCard := flag["-mm"] || cardType == "management"
+	mmCard := flag.ByName["-mm"] || cardType == "management"
 
-	cardNum, err := strconv.ParseInt(parm["-lc"], 0, 0)
+	cardNum, err := strconv.ParseInt(parm.ByName["-lc"], 0, 0)
 	if err != nil {
 		return err
 	}
@@ -65,6 +138,15 @@ func (cmd) Main(args ...string) error {
 		}
 	}
 
-	// FIXME internal.Ipmigod(mmCard, int(cardNum))
+	internal.Signaled = func() bool {
+		select {
+		case <-c.done:
+			return true
+		default:
+			return false
+		}
+	}
+	internal.EventChannels = []string{machine.Name, opticsalarm.Channel}
+	internal.Ipmigod(mmCard, int(cardNum))
 	return nil
 }
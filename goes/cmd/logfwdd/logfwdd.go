@@ -0,0 +1,299 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package logfwdd forwards the kernel log, including goes daemon messages,
+// to remote syslog collectors in RFC 5424 format.
+package logfwdd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/platinasystems/go/goes/cmd"
+	"github.com/platinasystems/go/goes/lang"
+	"github.com/platinasystems/go/internal/log"
+	"github.com/platinasystems/go/internal/machine"
+	"github.com/platinasystems/go/internal/parms"
+	"gopkg.in/yaml.v2"
+)
+
+const DefaultConfigFile = "/etc/goes/logfwdd.yaml"
+
+// Config of the collectors.
+type Config struct {
+	Remotes []RemoteConfig `yaml:"remotes"`
+	// Messages buffered per remote while it's unreachable, default 10000.
+	Buffer int `yaml:"buffer"`
+	// Forward messages of this priority or more urgent, default debug.
+	Priority string `yaml:"priority"`
+	// Default: the host name.
+	Hostname string `yaml:"hostname"`
+}
+
+type RemoteConfig struct {
+	// udp|tcp|tls://HOST[:PORT]
+	Url string `yaml:"url"`
+	// PEM file of the TLS collector's certificate authorities; default,
+	// the system's.
+	Ca string `yaml:"ca"`
+	// PEM files of the TLS client certificate and key.
+	Cert     string `yaml:"cert"`
+	Key      string `yaml:"key"`
+	Insecure bool   `yaml:"insecure"`
+}
+
+type Command struct {
+	mutex sync.Mutex
+	kmsg  *os.File
+	done  chan struct{}
+}
+
+// The sink of forwarded messages; this is a *log.Remote other than test.
+type sender interface {
+	Send(*log.Message)
+}
+
+func (*Command) String() string { return "logfwdd" }
+
+func (*Command) Usage() string { return "logfwdd [-config FILE]" }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "forward log messages to remote syslog collectors",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Forward /dev/kmsg, kernel and goes daemon messages, to the configured
+	syslog collectors in RFC 5424 format over UDP, TCP, or TLS.  The
+	structured data of goes messages has the daemon id and machine name,
+	e.g.:
+
+	<30>1 2018-06-01T12:00:01.500000Z switch1 redisd 123 - [goes@32473
+	id="goes-platina-mk1.redisd[123\]" daemon="redisd"
+	machine="platina-mk1"] ready
+
+	Messages are buffered while a collector is unreachable. Without
+	remotes, the daemon is idle.
+
+OPTIONS
+	-config FILE
+		YAML collectors; default: ` + DefaultConfigFile + `
+
+CONFIG
+	remotes:
+	- url: udp://10.0.0.1		# default port 514
+	- url: tcp://logs.example.com	# default port 601
+	- url: tls://logs.example.com	# default port 6514
+	  ca: /etc/goes/syslog-ca.pem
+	  cert: /etc/goes/syslog.crt	# client authentication
+	  key: /etc/goes/syslog.key
+	buffer: 10000
+	priority: info			# and more urgent
+	hostname: switch1		# default: host name`,
+	}
+}
+
+func (c *Command) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.kmsg != nil {
+		c.kmsg.Close()
+	}
+	close(c.done)
+	return nil
+}
+
+func (*Command) Kind() cmd.Kind { return cmd.Daemon }
+
+func (c *Command) Main(args ...string) error {
+	c.done = make(chan struct{})
+	parm, args := parms.New(args, "-config")
+	if len(args) > 0 {
+		return fmt.Errorf("%v: unexpected", args)
+	}
+	if len(parm.ByName["-config"]) == 0 {
+		parm.ByName["-config"] = DefaultConfigFile
+	}
+	cfg, err := LoadConfig(parm.ByName["-config"])
+	if err != nil {
+		return err
+	}
+	if len(cfg.Remotes) == 0 {
+		<-c.done
+		return nil
+	}
+	var sinks []sender
+	for _, rc := range cfg.Remotes {
+		r, err := rc.remote(cfg.Buffer)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		sinks = append(sinks, r)
+	}
+	boot, err := bootTime()
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(log.DevKmsg)
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	select {
+	case <-c.done:
+		f.Close()
+		c.mutex.Unlock()
+		return nil
+	default:
+		c.kmsg = f
+	}
+	c.mutex.Unlock()
+	err = forward(f, boot, cfg, sinks)
+	select {
+	case <-c.done:
+		return nil
+	default:
+		return err
+	}
+}
+
+// LoadConfig reads the named config file, if it exists, and sets the
+// defaults.
+func LoadConfig(fn string) (*Config, error) {
+	b, err := ioutil.ReadFile(fn)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	cfg, err := ParseConfig(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fn, err)
+	}
+	return cfg, nil
+}
+
+// ParseConfig validates the YAML config and sets its defaults.
+func ParseConfig(b []byte) (*Config, error) {
+	cfg := new(Config)
+	if err := yaml.UnmarshalStrict(b, cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Buffer == 0 {
+		cfg.Buffer = log.DefaultRemoteBuffer
+	}
+	if len(cfg.Priority) == 0 {
+		cfg.Priority = "debug"
+	}
+	if _, found := log.PriorityByName[cfg.Priority]; !found {
+		return nil, fmt.Errorf("priority: %q: invalid", cfg.Priority)
+	}
+	if len(cfg.Hostname) == 0 {
+		cfg.Hostname, _ = os.Hostname()
+	}
+	for _, rc := range cfg.Remotes {
+		if !strings.HasPrefix(rc.Url, "tls://") &&
+			(len(rc.Ca) > 0 || len(rc.Cert) > 0 || rc.Insecure) {
+			return nil, fmt.Errorf("%s: TLS options of non-TLS url",
+				rc.Url)
+		}
+		if (len(rc.Cert) > 0) != (len(rc.Key) > 0) {
+			return nil, fmt.Errorf("%s: need both cert and key",
+				rc.Url)
+		}
+	}
+	return cfg, nil
+}
+
+func (rc *RemoteConfig) remote(n int) (*log.Remote, error) {
+	var config *tls.Config
+	if strings.HasPrefix(rc.Url, "tls://") {
+		config = &tls.Config{InsecureSkipVerify: rc.Insecure}
+		if len(rc.Ca) > 0 {
+			b, err := ioutil.ReadFile(rc.Ca)
+			if err != nil {
+				return nil, err
+			}
+			config.RootCAs = x509.NewCertPool()
+			if !config.RootCAs.AppendCertsFromPEM(b) {
+				return nil, fmt.Errorf("%s: no certificates",
+					rc.Ca)
+			}
+		}
+		if len(rc.Cert) > 0 {
+			cert, err := tls.LoadX509KeyPair(rc.Cert, rc.Key)
+			if err != nil {
+				return nil, err
+			}
+			config.Certificates = []tls.Certificate{cert}
+		}
+	}
+	return log.NewRemote(rc.Url, config, n)
+}
+
+// Returns the time of the kernel log's zero timestamp.
+func bootTime() (time.Time, error) {
+	b, err := ioutil.ReadFile("/proc/uptime")
+	if err != nil {
+		return time.Time{}, err
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) == 0 {
+		return time.Time{}, fmt.Errorf("/proc/uptime: empty")
+	}
+	uptime, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().Add(-time.Duration(uptime * float64(time.Second))),
+		nil
+}
+
+// Forward the kmsg records, one per read, until EOF or error.
+func forward(r io.Reader, boot time.Time, cfg *Config, sinks []sender) error {
+	var kmsg log.Kmsg
+	buf := make([]byte, 8192)
+	limit := log.PriorityByName[cfg.Priority]
+	for {
+		n, err := r.Read(buf)
+		if pe, ok := err.(*os.PathError); ok && pe.Err == syscall.EPIPE {
+			// records were overwritten before they were read
+			continue
+		}
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return err
+		}
+		kmsg = log.Kmsg{}
+		kmsg.Parse(buf[:n])
+		if len(kmsg.Msg) == 0 ||
+			kmsg.Pri&log.PriorityMask > limit&log.PriorityMask {
+			continue
+		}
+		m := kmsg.Message(boot)
+		m.Hostname = cfg.Hostname
+		if len(m.Data) == 0 {
+			m.Data = []log.Element{{Id: log.SdId}}
+		}
+		m.Data[0].Params = append(m.Data[0].Params,
+			log.Param{Name: "machine", Value: machine.Name})
+		for _, s := range sinks {
+			s.Send(m)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package logfwdd
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/platinasystems/go/internal/log"
+)
+
+// Returns a record, like /dev/kmsg, per read.
+type records []string
+
+func (r *records) Read(b []byte) (int, error) {
+	if len(*r) == 0 {
+		return 0, io.EOF
+	}
+	s := (*r)[0]
+	*r = (*r)[1:]
+	if len(s) == 0 {
+		return 0, &os.PathError{Op: "read", Path: log.DevKmsg,
+			Err: syscall.EPIPE}
+	}
+	return copy(b, s), nil
+}
+
+type sent []*log.Message
+
+func (s *sent) Send(m *log.Message) { *s = append(*s, m) }
+
+func TestForward(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`
+remotes:
+- url: udp://localhost
+priority: info
+hostname: switch1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &records{
+		"6,1,1000000,-;eth0: link up\n",
+		"",
+		"31,2,2000000,-;goes.redisd[42]: debug message\n",
+		"27,3,3000000,-;goes.redisd[42]: failed\n",
+	}
+	var s sent
+	boot := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	if err = forward(r, boot, cfg, []sender{&s}); err != nil {
+		t.Fatal(err)
+	}
+	if len(s) != 2 {
+		t.Fatalf("sent %d messages", len(s))
+	}
+	for i, want := range []string{
+		`<6>1 2018-06-01T12:00:01.000000Z switch1 kernel - - [goes@32473 machine="goes"] eth0: link up`,
+		`<27>1 2018-06-01T12:00:03.000000Z switch1 redisd 42 - [goes@32473 id="goes.redisd[42\]" daemon="redisd" machine="goes"] failed`,
+	} {
+		if got := string(s[i].Format()); got != want {
+			t.Errorf("got: %s\nwant: %s", got, want)
+		}
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	for _, s := range []string{
+		"priority: loud",
+		"remotes:\n- url: udp://localhost\n  ca: ca.pem",
+		"remotes:\n- url: tls://localhost\n  cert: client.crt",
+		"unknown: true",
+	} {
+		if _, err := ParseConfig([]byte(s)); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
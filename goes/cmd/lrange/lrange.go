@@ -0,0 +1,71 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package lrange
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/platinasystems/go/goes/lang"
+	"github.com/platinasystems/go/internal/redis"
+)
+
+type Command struct{}
+
+func (Command) String() string { return "lrange" }
+
+func (Command) Usage() string { return "lrange KEY [START [STOP]]" }
+
+func (Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "print the elements of a redis list",
+	}
+}
+
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Print the list elements from START through STOP inclusive; default,
+	the whole list.  Negative indices are offsets from the tail.
+
+EXAMPLES
+	lrange port-5.qsfp.events
+	lrange port-5.qsfp.events 0 9`,
+	}
+}
+
+func (Command) Main(args ...string) error {
+	start, stop := 0, -1
+	switch len(args) {
+	case 0:
+		return fmt.Errorf("KEY: missing")
+	case 1, 2, 3:
+	default:
+		return fmt.Errorf("%v: unexpected", args[3:])
+	}
+	for i, p := range []*int{&start, &stop} {
+		if len(args) > i+1 {
+			v, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return fmt.Errorf("%s: %v", args[i+1], err)
+			}
+			*p = v
+		}
+	}
+	l, err := redis.Lrange(args[0], start, stop)
+	if err != nil {
+		return err
+	}
+	for _, s := range l {
+		fmt.Fprintln(os.Stdout, s)
+	}
+	return nil
+}
+
+func (Command) Complete(args ...string) []string {
+	return redis.Complete(args...)
+}
@@ -36,7 +36,7 @@ type Command struct {
 func (*Command) String() string { return "upgrade" }
 
 func (*Command) Usage() string {
-	return "upgrade [-v VER] [-s SERVER[/dir]] [-r] [-l] [-c] [-t] [-f]"
+	return "upgrade [-v VER] [-s SERVER[/dir]] [-r] [-l] [-c] [-t] [-f] [-insecure]"
 }
 
 func (*Command) Apropos() lang.Alt {
@@ -64,6 +64,12 @@ DESCRIPTION
 	Upgrade proceeds only if the selected version number is newer,
 	unless overridden with the "-f" force flag.
 
+	The archive is only installed if listed in the server's MANIFEST of
+	SHA-256 hashes and its detached ed25519 signature, MANIFEST.sig, is
+	valid for a key embedded in goes.  Goes built without a key refuses
+	every image.  The "-insecure" flag installs unsigned or
+	tampered archives with a warning.
+
 OPTIONS
 	-v [VER]          version [YYYYMMDD] or LATEST (default)
 	-s [SERVER[/dir]] IP4 or URL, default downloads.platinasystems.com 
@@ -72,14 +78,16 @@ OPTIONS
 	-r                report QSPI installed versions, QSPI booted from
 	-c                check SHA-1's of flash
 	-1                upgrade QSPI1(recovery QSPI), default is QSPI0
-	-f                force upgrade (ignore version check)`,
+	-f                force upgrade (ignore version check)
+	-insecure         install images that fail signature verification`,
 	}
 }
 
 func (c *Command) Main(args ...string) error {
 	command = c
 	initQfmt()
-	flag, args := flags.New(args, "-t", "-l", "-f", "-r", "-c", "-1")
+	flag, args := flags.New(args, "-t", "-l", "-f", "-r", "-c", "-1",
+		"-insecure")
 	parm, args := parms.New(args, "-v", "-s")
 	if len(parm.ByName["-v"]) == 0 {
 		parm.ByName["-v"] = DfltVer
@@ -113,7 +121,7 @@ func (c *Command) Main(args ...string) error {
 
 	if err := doUpgrade(parm.ByName["-s"], parm.ByName["-v"],
 		flag.ByName["-t"], flag.ByName["-f"],
-		flag.ByName["-1"]); err != nil {
+		flag.ByName["-1"], flag.ByName["-insecure"]); err != nil {
 		return err
 	}
 	return nil
@@ -180,9 +188,15 @@ func compareChecksums() (err error) {
 	return nil
 }
 
-func doUpgrade(s string, v string, t bool, f bool, q bool) (err error) {
+func doUpgrade(s string, v string, t bool, f bool, q bool,
+	insecure bool) (err error) {
 	fmt.Print("\n")
 
+	m, err := getManifest(s, v, t, insecure)
+	if err != nil {
+		return err
+	}
+	defer rmManifest()
 	n, err := getFile(s, v, t, ArchiveName)
 	if err != nil {
 		return fmt.Errorf("Server unreachable\n")
@@ -190,6 +204,9 @@ func doUpgrade(s string, v string, t bool, f bool, q bool) (err error) {
 	if n < 1000 {
 		return fmt.Errorf("Server unreachable\n")
 	}
+	if err = verify(m, ArchiveName); err != nil {
+		return err
+	}
 	if err = unzip(); err != nil {
 		return fmt.Errorf("Server error: unzipping file: %v\n", err)
 	}
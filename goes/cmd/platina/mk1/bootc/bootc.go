@@ -36,7 +36,8 @@ func (Command) Usage() string {
 	bootc [vers] [readcfg] [initcfg] [wipe] [setsda1] [setsda6] [clrsda1]
 	[clrsda6] [setinstall] [clrinstall]	[setip] [setnetmask] [setgateway]
 	[setkernel] [setinitrd] [setpost] [clrpost] [checkfiles] [getfiles]
-	[setdisable] [clrdisable] [wipedryrun]
+	[setdisable] [clrdisable] [setinsecure] [clrinsecure] [wipedryrun]
+	[confirm] [cancel]
 	[pccinitfile] [setpccenb] [clrpccenb] [setpccip] [setpccport] [setpccsn]
 	[pcc1a] [pcc1b] [pcc1c] [pcc2] [pcc3] [pcc4]`
 }
@@ -51,7 +52,21 @@ func (Command) Man() lang.Alt {
 	return lang.Alt{
 		lang.EnUS: `
 description
-	bootc provides wipe and access to bootc.cfg.`,
+	bootc provides wipe and access to bootc.cfg.
+
+	Before kexec, bootc verifies the kernel with the signed MANIFEST in its
+	directory, as installed by upgrade.  "setinsecure" allows booting
+	unverified kernels.  Goes without trusted keys refuses every kernel,
+	and kernels without a MANIFEST boot unverified with a warning until a
+	signed slot is confirmed.
+
+	sda6 has two image slots, /boot/slot/a and /boot/slot/b, each with a
+	kernel, initrd and goes.  Upgrade installs images to the inactive
+	slot and marks it to try once.  If the new slot isn't confirmed by
+	the next boot, bootc boots the active slot again.  Before kexec,
+	bootc points /usr/bin/goes at the goes of the booted slot.
+	"confirm" makes the tried slot active; "cancel" discards an untried
+	slot.`,
 	}
 }
 
@@ -109,6 +124,22 @@ func (c *Command) Main(args ...string) (err error) {
 		if err = clrDisable(); err != nil {
 			return err
 		}
+	case "confirm":
+		if err = Confirm(); err != nil {
+			return err
+		}
+	case "cancel":
+		if err = Cancel(); err != nil {
+			return err
+		}
+	case "setinsecure":
+		if err = setInsecure(); err != nil {
+			return err
+		}
+	case "clrinsecure":
+		if err = clrInsecure(); err != nil {
+			return err
+		}
 	case "readcfg":
 		if err := readCfg(); err != nil {
 			return err
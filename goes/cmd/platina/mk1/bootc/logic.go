@@ -22,12 +22,14 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/platinasystems/go/internal/machine"
+	"github.com/platinasystems/go/internal/sign"
 )
 
 const (
@@ -120,6 +122,9 @@ func Bootc() []string {
 
 		// sda1 utility mode
 		if Cfg.BootSda1 && strings.Contains(mounts, "sda1") {
+			if !verifyKexec(Cfg.Sda1K, Cfg.Sda1I) {
+				return []string{""}
+			}
 			if err := formKexec1(); err != nil {
 				fmt.Println("Error: can't form kexec string, drop into grub...")
 				return []string{""}
@@ -135,6 +140,9 @@ func Bootc() []string {
 
 		// install
 		if Cfg.Install && strings.Contains(mounts, "sda1") && !strings.Contains(parts, "sda6") {
+			if !verifyKexec(Cfg.ReInstallK, Cfg.ReInstallI) {
+				return []string{""}
+			}
 			if err := formKexec1(); err != nil {
 				fmt.Println("Error: can't form install kexec, drop into grub...")
 				return []string{""}
@@ -229,6 +237,14 @@ func Bootc() []string {
 				fmt.Println("Error: can't fix paths, drop into grub...")
 				return []string{""}
 			}
+			k, i, err := sda6Slot()
+			if err != nil {
+				fmt.Println("Error: can't select sda6 slot, drop into grub...")
+				return []string{""}
+			}
+			if !verifyKexec(k, i) {
+				return []string{""}
+			}
 			if err := formKexec6(); err != nil {
 				fmt.Println("Error: can't form sda6 kexec, drop into grub...")
 				return []string{""}
@@ -240,12 +256,15 @@ func Bootc() []string {
 				}
 			}
 			// boot sda6
-			return []string{"kexec", "-k", Cfg.Sda6K,
-				"-i", Cfg.Sda6I, "-c", kexec6, "-e"}
+			return []string{"kexec", "-k", k,
+				"-i", i, "-c", kexec6, "-e"}
 		}
 
 		// non-partitioned
 		if !strings.Contains(parts, sda6) && strings.Contains(mounts, sda1) {
+			if !verifyKexec(Cfg.Sda1K, Cfg.Sda1I) {
+				return []string{""}
+			}
 			if err := formKexec1(); err != nil {
 				fmt.Println("Error: can't form kexec string, drop into grub...")
 				return []string{""}
@@ -264,6 +283,38 @@ func Bootc() []string {
 	return []string{""}
 }
 
+// Verify the kernel, and the initrd if listed, with the signed manifest in
+// the kernel's directory unless bootc.cfg allows insecure boot.  Goes
+// without trusted keys refuses every kernel.  The initrd is usually generated
+// on the target so it may not be listed.  Kernels without a manifest, e.g.
+// installed before images were signed, boot with a warning until a signed
+// slot is confirmed.
+func verifyKexec(k, i string) bool {
+	if Cfg.Insecure {
+		return true
+	}
+	if !sign.Enabled() {
+		fmt.Printf("Error: %s: %v, drop into grub...\n", k, sign.ErrNoKeys)
+		return false
+	}
+	m, err := sign.Load(filepath.Dir(k))
+	if err == sign.ErrUnsigned && !Cfg.Signed {
+		fmt.Printf("Warning: %s: %v, booting unverified...\n", k, err)
+		return true
+	}
+	if err == nil {
+		err = m.Check(k)
+	}
+	if err == nil && m[filepath.Base(i)] != nil {
+		err = m.Check(i)
+	}
+	if err != nil {
+		fmt.Printf("Error: %s: %v, drop into grub...\n", k, err)
+		return false
+	}
+	return true
+}
+
 func checkFiles() bool {
 	context, err := getContext()
 	if err != nil {
@@ -380,6 +431,8 @@ func initCfg() error {
 		PccIP:           "",
 		PccPort:         "",
 		PccSN:           "",
+		Insecure:        false,
+		Signed:          false,
 	}
 	if err := writeCfg(); err != nil {
 		return err
@@ -682,6 +735,49 @@ func setDisable() error {
 	return nil
 }
 
+func setInsecure() error {
+	if err := readCfg(); err != nil {
+		return err
+	}
+	Cfg.Insecure = true
+	jsonInfo, err := json.Marshal(Cfg)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(BootcCfgFile, jsonInfo, 0644)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func clrInsecure() error {
+	if err := readCfg(); err != nil {
+		return err
+	}
+	Cfg.Insecure = false
+	jsonInfo, err := json.Marshal(Cfg)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(BootcCfgFile, jsonInfo, 0644)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetSigned records whether the active sda6 slot has a signed MANIFEST so
+// that bootc won't kexec a kernel after its manifest is removed.  Confirm
+// sets it for signed slots; insecure upgrades clear it.
+func SetSigned(signed bool) error {
+	if err := readCfg(); err != nil {
+		return err
+	}
+	Cfg.Signed = signed
+	return writeCfg()
+}
+
 func clrDisable() error {
 	if err := readCfg(); err != nil {
 		return err
@@ -1057,6 +1153,9 @@ func Wipe(dryrun bool) error {
 }
 
 func fixPaths() error { //FIXME Temporary remove by 9/30/2018
+	if len(slotOf(Cfg.Sda6K)) > 0 {
+		return nil
+	}
 	files, err := ioutil.ReadDir(cbSda6 + "boot")
 	if err != nil {
 		return err
@@ -0,0 +1,89 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package bootc
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/platinasystems/go/internal/sign"
+	"golang.org/x/crypto/ed25519"
+)
+
+func TestVerifyKexec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bootc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	k := filepath.Join(dir, "vmlinuz-4.13.0-platina-mk1")
+	i := filepath.Join(dir, "initrd.img-4.13.0-platina-mk1")
+	for _, fn := range []string{k, i} {
+		if err = ioutil.WriteFile(fn, []byte(fn), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func(cfg BootcConfig, keys []string) {
+		Cfg, sign.Keys = cfg, keys
+	}(Cfg, sign.Keys)
+	Cfg = BootcConfig{}
+	sign.Keys = nil
+
+	if verifyKexec(k, i) {
+		t.Fatal("boot without trusted keys succeeded")
+	}
+	Cfg.Insecure = true
+	if !verifyKexec(k, i) {
+		t.Fatal("insecure boot without trusted keys failed")
+	}
+	Cfg.Insecure = false
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign.Keys = []string{base64.StdEncoding.EncodeToString(pub)}
+	if !verifyKexec(k, i) {
+		t.Fatal("unsigned boot before signed upgrade failed")
+	}
+	Cfg.Signed = true
+	if verifyKexec(k, i) {
+		t.Fatal("unsigned boot after signed upgrade succeeded")
+	}
+	Cfg.Insecure = true
+	if !verifyKexec(k, i) {
+		t.Fatal("insecure boot failed")
+	}
+	Cfg.Insecure = false
+
+	m := make(sign.Manifest)
+	if err = m.Add(k); err != nil {
+		t.Fatal(err)
+	}
+	b := m.Bytes()
+	err = ioutil.WriteFile(filepath.Join(dir, sign.ManifestName), b, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, sign.SignatureName),
+		sign.Sign(priv, b), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verifyKexec(k, i) {
+		t.Fatal("signed boot failed")
+	}
+	Cfg.Signed = false
+	if err = ioutil.WriteFile(k, []byte("rootkit"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if verifyKexec(k, i) {
+		t.Fatal("tampered boot succeeded")
+	}
+}
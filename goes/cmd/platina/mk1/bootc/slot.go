@@ -0,0 +1,299 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// slot.go - A/B images of sda6
+//
+// sda6 has two image slots, /boot/slot/a and /boot/slot/b, each with a
+// kernel, initrd, goes and the MANIFEST that verifies them.  Sda6K and Sda6I
+// are the kernel and initrd of the active slot.  Upgrade copies the active
+// slot to the inactive one, installs the new images there, then marks it
+// try-once with TryK and TryI.  bootc boots the untried slot once; if it
+// isn't confirmed by the next boot, bootc boots the active slot again.
+// Before kexec, bootc points /usr/bin/goes at the goes of the booted slot.
+//
+// The first upgrade of an install without slots adopts its /boot kernel,
+// initrd, MANIFEST and /usr/bin/goes as slot a.
+
+package bootc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/platinasystems/go/internal/sign"
+)
+
+const (
+	slotDir = "boot/slot/"
+	goesBin = "usr/bin/goes"
+	tryCnt  = 1
+)
+
+var slotNames = []string{"a", "b"}
+
+// Roots of sda6 from goes-boot, where bootc kexecs, and from sda6 itself,
+// where upgrade and slotd run.  bootc.cfg paths are relative to bootRoot.
+var (
+	bootRoot = cbSda6
+	sda6Root = "/"
+)
+
+// Returns the name of the slot with the given image, or "" if it isn't in
+// one.
+func slotOf(fn string) string {
+	i := strings.Index(fn, "/"+slotDir)
+	if i < 0 {
+		return ""
+	}
+	name := fn[i+1+len(slotDir):]
+	if i = strings.Index(name, "/"); i < 0 {
+		return ""
+	}
+	return name[:i]
+}
+
+// Returns the slot that isn't active.
+func (c *BootcConfig) inactive() string {
+	if slotOf(c.Sda6K) == slotNames[0] {
+		return slotNames[1]
+	}
+	return slotNames[0]
+}
+
+// Mark the given kernel and initrd as the untried slot.
+func (c *BootcConfig) try(k, i string) {
+	c.TryK, c.TryI, c.TryCnt = k, i, tryCnt
+}
+
+// Make the tried slot active so that the previous becomes the inactive slot
+// for the next upgrade.  Returns false without a tried slot.
+func (c *BootcConfig) confirm() bool {
+	if len(c.TryK) == 0 {
+		return false
+	}
+	c.Sda6K, c.Sda6I = c.TryK, c.TryI
+	c.cancel()
+	return true
+}
+
+func (c *BootcConfig) cancel() {
+	c.TryK, c.TryI, c.TryCnt = "", "", 0
+}
+
+// Returns the kernel and initrd to boot and whether the config changed.
+// This counts the boot of an untried slot and rolls back an unconfirmed one.
+func (c *BootcConfig) next() (k, i string, changed bool) {
+	if len(c.TryK) == 0 {
+		return c.Sda6K, c.Sda6I, false
+	}
+	if c.TryCnt > 0 {
+		c.TryCnt--
+		return c.TryK, c.TryI, true
+	}
+	fmt.Println("Rolling back unconfirmed", c.TryK, "to", c.Sda6K)
+	c.cancel()
+	return c.Sda6K, c.Sda6I, true
+}
+
+// Returns the bootc.cfg path of the named file in sda6 root.
+func toBoot(root, fn string) string {
+	fn = strings.TrimPrefix(strings.TrimPrefix(fn, root), "/")
+	return bootRoot + fn
+}
+
+// Returns the named bootc.cfg path in the given sda6 root.
+func fromBoot(root, fn string) string {
+	return filepath.Join(root, strings.TrimPrefix(fn, bootRoot))
+}
+
+// Point root's /usr/bin/goes at the goes in the kernel's slot.  Images that
+// aren't in a slot use /usr/bin/goes as is.
+func linkGoes(root, k string) error {
+	if len(slotOf(k)) == 0 {
+		return nil
+	}
+	goes := filepath.Join(filepath.Dir(strings.TrimPrefix(k, bootRoot)),
+		"goes")
+	if _, err := os.Stat(filepath.Join(root, goes)); err != nil {
+		return err
+	}
+	// relative to resolve from either root
+	target, err := filepath.Rel(filepath.Dir(goesBin), goes)
+	if err != nil {
+		return err
+	}
+	fn := filepath.Join(root, goesBin)
+	if t, err := os.Readlink(fn); err == nil && t == target {
+		return nil
+	}
+	tmp := fn + ".slot"
+	os.Remove(tmp)
+	if err = os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fn)
+}
+
+// Copy the active images in the given sda6 root to the named slot, replacing
+// whatever was there.  Returns the bootc.cfg paths of the copied kernel and
+// initrd.
+func (c *BootcConfig) copySlot(root, name string) (k, i string, err error) {
+	dir := filepath.Join(root, slotDir, name)
+	if err = os.RemoveAll(dir); err != nil {
+		return
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	srcK := fromBoot(root, c.Sda6K)
+	srcI := fromBoot(root, c.Sda6I)
+	goes := filepath.Join(root, goesBin)
+	if len(slotOf(c.Sda6K)) > 0 {
+		goes = filepath.Join(filepath.Dir(srcK), "goes")
+	}
+	for _, fn := range []string{
+		srcK,
+		srcI,
+		goes,
+		filepath.Join(filepath.Dir(srcK), sign.ManifestName),
+		filepath.Join(filepath.Dir(srcK), sign.SignatureName),
+	} {
+		fi, err := os.Stat(fn)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", "", err
+		}
+		dst := filepath.Join(dir, filepath.Base(fn))
+		if err = Copy(fn, dst); err != nil {
+			return "", "", err
+		}
+		if err = os.Chmod(dst, fi.Mode()); err != nil {
+			return "", "", err
+		}
+	}
+	k = toBoot(root, filepath.Join(dir, filepath.Base(srcK)))
+	i = toBoot(root, filepath.Join(dir, filepath.Base(srcI)))
+	return
+}
+
+// Copy the active images to the inactive slot of the given sda6 root and
+// return its directory.  An install without slots is first adopted as slot
+// a.
+func (c *BootcConfig) stage(root string) (string, error) {
+	if len(c.Sda6K) == 0 {
+		return "", fmt.Errorf("no active sda6 kernel")
+	}
+	c.cancel()
+	if len(slotOf(c.Sda6K)) == 0 {
+		k, i, err := c.copySlot(root, slotNames[0])
+		if err != nil {
+			return "", err
+		}
+		c.Sda6K, c.Sda6I = k, i
+		if err = linkGoes(root, k); err != nil {
+			return "", err
+		}
+	}
+	name := c.inactive()
+	if _, _, err := c.copySlot(root, name); err != nil {
+		return "", err
+	}
+	return filepath.Join(root, slotDir, name), nil
+}
+
+// Stage copies the running images to the inactive slot for upgrade to
+// replace, then returns its directory.
+func Stage() (string, error) {
+	if err := readCfg(); err != nil {
+		return "", err
+	}
+	dir, err := Cfg.stage(sda6Root)
+	if err != nil {
+		return "", err
+	}
+	return dir, writeCfg()
+}
+
+// Try marks the staged slot in the given directory as untried.
+func Try(dir string) error {
+	if err := readCfg(); err != nil {
+		return err
+	}
+	k, i, err := slotImages(dir)
+	if err != nil {
+		return err
+	}
+	Cfg.try(toBoot(sda6Root, k), toBoot(sda6Root, i))
+	return writeCfg()
+}
+
+// Returns the kernel and initrd in the slot directory.
+func slotImages(dir string) (k, i string, err error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, f := range files {
+		switch {
+		case strings.HasPrefix(f.Name(), "vmlinuz"):
+			k = filepath.Join(dir, f.Name())
+		case strings.HasPrefix(f.Name(), "initrd"):
+			i = filepath.Join(dir, f.Name())
+		}
+	}
+	if len(k) == 0 || len(i) == 0 {
+		err = fmt.Errorf("%s: missing kernel or initrd", dir)
+	}
+	return
+}
+
+// Trying returns true if this is the unconfirmed boot of a new slot.
+func Trying() (bool, error) {
+	if err := readCfg(); err != nil {
+		return false, err
+	}
+	return len(Cfg.TryK) > 0 && Cfg.TryCnt == 0, nil
+}
+
+// Confirm makes the tried slot active.  A slot whose kernel verifies with its
+// signed manifest also stops bootc from booting unsigned kernels.
+func Confirm() error {
+	if err := readCfg(); err != nil {
+		return err
+	}
+	if !Cfg.confirm() {
+		return nil
+	}
+	if sign.VerifyFile(fromBoot(sda6Root, Cfg.Sda6K)) == nil {
+		Cfg.Signed = true
+	}
+	return writeCfg()
+}
+
+// Cancel the untried slot.
+func Cancel() error {
+	if err := readCfg(); err != nil {
+		return err
+	}
+	Cfg.cancel()
+	return writeCfg()
+}
+
+// Returns the kernel and initrd of the sda6 slot to boot after pointing
+// /usr/bin/goes at the slot's goes.
+func sda6Slot() (k, i string, err error) {
+	k, i, changed := Cfg.next()
+	if err = linkGoes(bootRoot, k); err != nil {
+		return
+	}
+	if changed {
+		err = writeCfg()
+	}
+	return
+}
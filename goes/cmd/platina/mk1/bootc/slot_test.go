@@ -0,0 +1,143 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package bootc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSlotOf(t *testing.T) {
+	for _, x := range []struct {
+		fn, slot string
+	}{
+		{"/mountd/sda6/boot/vmlinuz-4.13.0", ""},
+		{"/mountd/sda6/boot/slot/a/vmlinuz-4.13.0", "a"},
+		{"/boot/slot/b/goes", "b"},
+		{"/boot/slot/b", ""},
+		{"", ""},
+	} {
+		if slot := slotOf(x.fn); slot != x.slot {
+			t.Errorf("%q: got %q, want %q", x.fn, slot, x.slot)
+		}
+	}
+}
+
+func TestSlot(t *testing.T) {
+	root, err := ioutil.TempDir("", "sda6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	write := func(fn, s string) {
+		fn = filepath.Join(root, fn)
+		if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fn, []byte(s), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(fn string) string {
+		b, err := ioutil.ReadFile(filepath.Join(root, fn))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	expect := func(what, got, want string) {
+		if got != want {
+			t.Fatalf("%s: got %q, want %q", what, got, want)
+		}
+	}
+	// boot the next slot and return its kernel
+	boot := func(c *BootcConfig) string {
+		k, _, _ := c.next()
+		if err := linkGoes(root, k); err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	// replace the staged goes and kernel, then mark the slot to try
+	upgrade := func(c *BootcConfig, dir, ver string) {
+		for _, fn := range []string{"vmlinuz", "initrd.img"} {
+			k, _ := filepath.Glob(filepath.Join(dir, fn+"-*"))
+			for _, fn := range k {
+				os.Remove(fn)
+			}
+			write(filepath.Join(dir[len(root):], fn+"-"+ver), ver)
+		}
+		write(filepath.Join(dir[len(root):], "goes"), "goes-"+ver)
+		k, i, err := slotImages(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.try(toBoot(root, k), toBoot(root, i))
+	}
+
+	// an install without slots
+	write("boot/vmlinuz-4.13.0", "4.13.0")
+	write("boot/initrd.img-4.13.0", "4.13.0")
+	write(goesBin, "goes-4.13.0")
+	c := &BootcConfig{
+		Sda6K: bootRoot + "boot/vmlinuz-4.13.0",
+		Sda6I: bootRoot + "boot/initrd.img-4.13.0",
+	}
+	expect("legacy boot", boot(c), bootRoot+"boot/vmlinuz-4.13.0")
+
+	dir, err := c.stage(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect("staged", dir, filepath.Join(root, slotDir, "b"))
+	expect("adopted", c.Sda6K, bootRoot+slotDir+"a/vmlinuz-4.13.0")
+	expect("adopted goes", read(goesBin), "goes-4.13.0")
+	expect("staged goes", read(slotDir+"b/goes"), "goes-4.13.0")
+	expect("staged kernel", read(slotDir+"b/vmlinuz-4.13.0"), "4.13.0")
+
+	// a failed upgrade boots once then rolls back
+	upgrade(c, dir, "4.14.0")
+	expect("untried", boot(c), bootRoot+slotDir+"b/vmlinuz-4.14.0")
+	expect("untried goes", read(goesBin), "goes-4.14.0")
+	expect("running kernel", read(slotDir+"a/vmlinuz-4.13.0"), "4.13.0")
+	expect("rollback", boot(c), bootRoot+slotDir+"a/vmlinuz-4.13.0")
+	expect("rollback goes", read(goesBin), "goes-4.13.0")
+	if len(c.TryK) > 0 {
+		t.Fatal("try pending after rollback")
+	}
+	expect("after rollback", boot(c), bootRoot+slotDir+"a/vmlinuz-4.13.0")
+	if c.confirm() {
+		t.Fatal("confirmed without a tried slot")
+	}
+
+	// a confirmed upgrade becomes the active slot
+	if dir, err = c.stage(root); err != nil {
+		t.Fatal(err)
+	}
+	expect("restaged", dir, filepath.Join(root, slotDir, "b"))
+	upgrade(c, dir, "4.14.0")
+	expect("retried", boot(c), bootRoot+slotDir+"b/vmlinuz-4.14.0")
+	if !c.confirm() {
+		t.Fatal("not confirmed")
+	}
+	expect("confirmed", boot(c), bootRoot+slotDir+"b/vmlinuz-4.14.0")
+	expect("confirmed goes", read(goesBin), "goes-4.14.0")
+
+	// the next upgrade replaces the previous slot
+	if dir, err = c.stage(root); err != nil {
+		t.Fatal(err)
+	}
+	expect("next staged", dir, filepath.Join(root, slotDir, "a"))
+	expect("next staged goes", read(slotDir+"a/goes"), "goes-4.14.0")
+	if _, err = os.Stat(filepath.Join(dir, "vmlinuz-4.13.0")); err == nil {
+		t.Fatal("previous kernel remains in staged slot")
+	}
+	upgrade(c, dir, "4.15.0")
+	c.cancel()
+	expect("canceled", boot(c), bootRoot+slotDir+"b/vmlinuz-4.14.0")
+}
@@ -82,6 +82,11 @@ type BootcConfig struct { // stored as bootc.cfg, updated by server
 	PccIP           string
 	PccPort         string
 	PccSN           string
+	Insecure        bool // kexec unverified kernels
+	Signed          bool // sda6 /boot has a signed MANIFEST
+	TryK            string
+	TryI            string
+	TryCnt          int
 }
 
 type Client struct { // not stored, populated from server
@@ -0,0 +1,116 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package slotd confirms the health of a newly upgraded image slot.  If
+// bootc booted an untried slot, this waits for redis and vnet to be ready
+// then confirms it as the active slot.  Otherwise, it restarts the system
+// through coreboot so that bootc rolls back to the previous slot.
+package slotd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/platinasystems/go/goes/cmd"
+	"github.com/platinasystems/go/goes/cmd/platina/mk1/bootc"
+	"github.com/platinasystems/go/goes/cmd/reboot"
+	"github.com/platinasystems/go/goes/lang"
+	"github.com/platinasystems/go/internal/log"
+	"github.com/platinasystems/go/internal/machine"
+	"github.com/platinasystems/go/internal/redis"
+)
+
+const DefaultTimeout = 5 * time.Minute
+
+type Command struct {
+	// Timeout to confirm the new slot, default 5 minutes
+	Timeout time.Duration
+
+	done chan struct{}
+}
+
+func (*Command) String() string { return "slotd" }
+
+func (*Command) Usage() string { return "slotd" }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "confirm or roll back an upgraded image slot",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	After an upgrade, bootc boots the new kernel and goes once.  This
+	daemon confirms the new slot once redis and vnet are ready.  If
+	they aren't ready within the timeout, it restarts the system and
+	bootc rolls back to the previous kernel and goes.
+
+	Without an untried slot, this daemon exits.
+
+SEE ALSO
+	bootc, upgrade`,
+	}
+}
+
+func (*Command) Kind() cmd.Kind { return cmd.Daemon }
+
+func (c *Command) Close() error {
+	if c.done != nil {
+		close(c.done)
+	}
+	return nil
+}
+
+func (c *Command) Main(...string) error {
+	trying, err := bootc.Trying()
+	if err != nil || !trying {
+		return err
+	}
+	if c.Timeout == 0 {
+		c.Timeout = DefaultTimeout
+	}
+	c.done = make(chan struct{})
+	err = c.wait()
+	if err == nil {
+		if err = bootc.Confirm(); err == nil {
+			log.Print("daemon", "note", "confirmed upgraded slot")
+		}
+		return err
+	}
+	select {
+	case <-c.done:
+		return nil
+	default:
+	}
+	log.Print("daemon", "crit", "upgraded slot ", err,
+		", rolling back")
+	// bootc rolls back as the tried slot has used its boot
+	return reboot.Command{}.Main()
+}
+
+func (c *Command) wait() error {
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	end := time.Now().Add(c.Timeout)
+	for _, field := range []string{"redis.ready", "vnet.ready"} {
+		for {
+			s, _ := redis.Hget(machine.Name, field)
+			if s == "true" {
+				break
+			}
+			if time.Now().After(end) {
+				return fmt.Errorf("%s timeout", field)
+			}
+			select {
+			case <-c.done:
+				return fmt.Errorf("stopped")
+			case <-t.C:
+			}
+		}
+	}
+	return nil
+}
@@ -2,7 +2,6 @@
 // Use of this source code is governed by the GPL-2 license described in the
 // LICENSE file.
 
-//TODO AUTHENTICATE,  CERT OR EQUIV
 //TODO UPGRADE AUTOMATICALLY IF ENABLED, contact boot server
 
 package upgrade
@@ -11,6 +10,7 @@ import (
 	"fmt"
 	"io/ioutil"
 
+	"github.com/platinasystems/go/goes/cmd/platina/mk1/bootc"
 	"github.com/platinasystems/go/goes/lang"
 	"github.com/platinasystems/go/internal/flags"
 	"github.com/platinasystems/go/internal/parms"
@@ -36,7 +36,8 @@ func (Command) String() string { return "upgrade" }
 
 func (Command) Usage() string {
 	return `
-upgrade [-v VER] [-s SERVER[/dir]] [-r] [-l] [-t] [-a | -g -k -c] [-f]`
+upgrade [-v VER] [-s SERVER[/dir]] [-r] [-l] [-t] [-a | -g -k -c] [-f]
+	[-insecure]`
 }
 
 func (Command) Apropos() lang.Alt {
@@ -64,6 +65,20 @@ DESCRIPTION
 	Upgrade proceeds only if the selected version number is newer,
 	unless overridden with the "-f" force flag.
 
+	Images are only installed if listed in the server's MANIFEST of
+	SHA-256 hashes and its detached ed25519 signature, MANIFEST.sig, is
+	valid for a key embedded in goes.  Goes built without a key refuses
+	every image.  The kernel's MANIFEST also lists its vmlinuz, and is
+	copied to the slot for bootc to verify before kexec.
+	The "-insecure" flag installs unsigned or tampered images with a
+	warning.
+
+	Goes and the kernel are installed to a copy of the running images in
+	the inactive slot, /boot/slot/a or /boot/slot/b, and bootc boots the
+	new slot once after the next reboot.  If the new slot doesn't confirm
+	that redis and vnet are ready, the following boot rolls back to the
+	running slot.  Coreboot has a single slot and is not rolled back.
+
 OPTIONS
 	-v [VER]          version [YYYYMMDD] or LATEST (default)
 	-s [SERVER[/dir]] IP4 or URL, default downloads.platinasystems.com
@@ -74,13 +89,14 @@ OPTIONS
 	-k                upgrade kernel
 	-c                upgrade coreboot
 	-a                upgrade all
-	-f                force upgrade (ignore version check)`,
+	-f                force upgrade (ignore version check)
+	-insecure         install images that fail signature verification`,
 	}
 }
 
 func (Command) Main(args ...string) error {
 	flag, args := flags.New(args, "-t", "-l", "-f", "-r",
-		"-g", "-c", "-k", "-a")
+		"-g", "-c", "-k", "-a", "-insecure")
 	parm, args := parms.New(args, "-v", "-s")
 	if len(parm.ByName["-v"]) == 0 {
 		parm.ByName["-v"] = DfltVer
@@ -115,7 +131,8 @@ func (Command) Main(args ...string) error {
 	}
 	if err := doUpgrade(parm.ByName["-s"], parm.ByName["-v"],
 		flag.ByName["-t"], flag.ByName["-g"], flag.ByName["-k"],
-		flag.ByName["-c"], flag.ByName["-f"]); err != nil {
+		flag.ByName["-c"], flag.ByName["-f"],
+		flag.ByName["-insecure"]); err != nil {
 		return err
 	}
 	return nil
@@ -146,25 +163,36 @@ func reportVersions() error {
 }
 
 func doUpgrade(s string, v string, t bool, g bool, k bool,
-	c bool, f bool) error {
+	c bool, f bool, insecure bool) error {
 	fmt.Print("\n")
+	m, err := getManifest(s, v, t, insecure)
+	if err != nil {
+		return err
+	}
+	defer rmManifest()
+	var dir string
+	if g || k {
+		if dir, err = bootc.Stage(); err != nil {
+			return err
+		}
+	}
 	if g {
-		if err := upgradeGoes(s, v, t, f); err != nil {
+		if err := upgradeGoes(s, v, t, f, m, dir); err != nil {
 			return err
 		}
 	}
 	if k {
-		if err := upgradeKernel(s, v, t, f); err != nil {
+		if err := upgradeKernel(s, v, t, f, m, dir); err != nil {
 			return err
 		}
 	}
 	if c {
-		if err := upgradeCoreboot(s, v, t, f); err != nil {
+		if err := upgradeCoreboot(s, v, t, f, m); err != nil {
 			return err
 		}
 	}
 	if Install_flag {
-		if err := activateGoes(); err != nil {
+		if err := trySlot(dir); err != nil {
 			return err
 		}
 		return nil
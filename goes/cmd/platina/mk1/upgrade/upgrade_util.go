@@ -10,12 +10,14 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"syscall"
 
 	. "github.com/platinasystems/go"
 	"github.com/platinasystems/go/goes/cmd/platina/mk1/bootc"
+	"github.com/platinasystems/go/internal/sign"
 	"github.com/platinasystems/go/internal/url"
 )
 
@@ -169,7 +171,8 @@ func getCorebootInfo() (im IMGINFO, err error) {
 	return im, nil
 }
 
-func upgradeGoes(s string, v string, t bool, f bool) error {
+func upgradeGoes(s string, v string, t bool, f bool, m sign.Manifest,
+	dir string) error {
 	fmt.Printf("Update Goes\n")
 	if !f {
 		g := getGoesVal("tag", "/go")
@@ -189,13 +192,14 @@ func upgradeGoes(s string, v string, t bool, f bool) error {
 		}
 	}
 
-	if err := installGoes(s, v, t); err != nil {
+	if err := installGoes(s, v, t, m, dir); err != nil {
 		return err
 	}
 	return nil
 }
 
-func upgradeKernel(s string, v string, t bool, f bool) error {
+func upgradeKernel(s string, v string, t bool, f bool,
+	m sign.Manifest, dir string) error {
 	fmt.Printf("Update Kernel\n")
 	kr, fn, err := getSrvKernelVer(s, v, t)
 	if err != nil {
@@ -214,13 +218,14 @@ func upgradeKernel(s string, v string, t bool, f bool) error {
 		}
 	}
 
-	if err := installKernel(s, v, t, fn); err != nil {
+	if err := installKernel(s, v, t, fn, m, dir); err != nil {
 		return err
 	}
 	return nil
 }
 
-func upgradeCoreboot(s string, v string, t bool, f bool) error {
+func upgradeCoreboot(s string, v string, t bool, f bool,
+	m sign.Manifest) error {
 	fmt.Printf("Update Coreboot\n")
 	c, err := getCorebootVer()
 	if err != nil {
@@ -238,7 +243,7 @@ func upgradeCoreboot(s string, v string, t bool, f bool) error {
 	}
 
 	fmt.Printf("Please wait...installing Coreboot into flash\n")
-	if err := installCoreboot(s, v, t); err != nil {
+	if err := installCoreboot(s, v, t, m); err != nil {
 		return err
 	}
 	return nil
@@ -375,7 +380,9 @@ func getSrvCorebootVer(s string, v string, t bool) (string, error) {
 	return tag, nil
 }
 
-func installGoes(s string, v string, t bool) error {
+// Install the goes installer as the goes of the staged slot.
+func installGoes(s string, v string, t bool, m sign.Manifest,
+	dir string) error {
 	fn := GoesInstaller
 	n, err := getFile(s, v, t, fn)
 	if err != nil {
@@ -384,12 +391,26 @@ func installGoes(s string, v string, t bool) error {
 	if n < 1000 {
 		return fmt.Errorf("    Error file too small: %v", err)
 	}
+	if err = verify(m, fn); err != nil {
+		return err
+	}
 
+	dst := filepath.Join(dir, "goes")
+	if err = bootc.Copy(fn, dst); err != nil {
+		return err
+	}
+	if err = os.Chmod(dst, 0755); err != nil {
+		return err
+	}
 	Install_flag = true
 	return nil
 }
 
-func installKernel(s string, v string, t bool, fn string) error {
+// Install the kernel package then copy its kernel and initrd, along with the
+// manifest that verifies them, to the staged slot.  /boot keeps the new and
+// running kernels for grub.
+func installKernel(s string, v string, t bool, fn string,
+	m sign.Manifest, dir string) error {
 	n, err := getFile(s, v, t, fn)
 	if err != nil {
 		return fmt.Errorf("    Error downloading: %v", err)
@@ -397,13 +418,21 @@ func installKernel(s string, v string, t bool, fn string) error {
 	if n < 1000 {
 		return fmt.Errorf("    Error file too small: %v", err)
 	}
+	if err = verify(m, fn); err != nil {
+		return err
+	}
+
+	x, err := kernelVersion(fn)
+	if err != nil {
+		return err
+	}
 
 	_, err = exec.Command("dpkg", "-i", fn).Output()
 	if err != nil {
 		return err
 	}
 
-	err = cleanupBootDir(fn)
+	err = cleanupBootDir(x)
 	if err != nil {
 		return err
 	}
@@ -413,31 +442,46 @@ func installKernel(s string, v string, t bool, fn string) error {
 		return err
 	}
 
-	files, err := ioutil.ReadDir("/boot")
+	files, err := ioutil.ReadDir(dir)
 	if err != nil {
 		return err
 	}
-	k := ""
-	i := ""
 	for _, f := range files {
-		if strings.Contains(f.Name(), "vmlinuz") {
-			k = f.Name()
+		if strings.HasPrefix(f.Name(), "vmlinuz") ||
+			strings.HasPrefix(f.Name(), "initrd") {
+			if err = rmFile(filepath.Join(dir, f.Name())); err != nil {
+				return err
+			}
 		}
 	}
+	files, err = ioutil.ReadDir("/boot")
+	if err != nil {
+		return err
+	}
 	for _, f := range files {
-		if strings.Contains(f.Name(), "initrd") {
-			i = f.Name()
+		if !f.IsDir() && strings.Contains(f.Name(), x) &&
+			(strings.HasPrefix(f.Name(), "vmlinuz") ||
+				strings.HasPrefix(f.Name(), "initrd")) {
+			err = bootc.Copy(filepath.Join("/boot", f.Name()),
+				filepath.Join(dir, f.Name()))
+			if err != nil {
+				return err
+			}
 		}
 	}
 
-	if err := bootc.UpdateBootcCfg(k, i); err != nil {
+	err = installManifest(dir, m)
+	if err != nil {
 		return err
 	}
-
+	Install_flag = true
 	return nil
 }
 
-func installCoreboot(s string, v string, t bool) error {
+func installCoreboot(s string, v string, t bool, m sign.Manifest) error {
+	if err := verify(m, CorebootName); err != nil {
+		return err
+	}
 	_, err := exec.Command("/usr/local/sbin/flashrom", "-p",
 		"internal:boardmismatch=force", "-l",
 		"/usr/local/share/flashrom/layouts/platina-mk1.xml",
@@ -448,16 +492,29 @@ func installCoreboot(s string, v string, t bool) error {
 	return nil
 }
 
-func cleanupBootDir(fn string) error {
+func kernelVersion(fn string) (string, error) {
 	ref := regexp.MustCompile("([0-9]+)[.]([0-9]+)[.]([0-9]+)")
 	x := ref.FindString(fn)
 	if len(x) == 0 {
-		return fmt.Errorf("Error: version number not found. %v", fn)
+		return "", fmt.Errorf("Error: version number not found. %v", fn)
+	}
+	return x, nil
+}
+
+// Remove all but the new and running kernels from /boot.
+func cleanupBootDir(x string) error {
+	r, err := getKernelVer()
+	if err != nil {
+		return err
+	}
+	if r, err = kernelVersion(r); err != nil {
+		return err
 	}
 	files, _ := ioutil.ReadDir("/boot")
 	for _, f := range files {
 		if !f.IsDir() {
-			if !strings.Contains(f.Name(), x) {
+			if !strings.Contains(f.Name(), x) &&
+				!strings.Contains(f.Name(), r) {
 				err := rmFile("/boot/" + f.Name())
 				if err != nil {
 					return err
@@ -501,9 +558,87 @@ func rmFile(f string) error {
 	return nil
 }
 
-func activateGoes() error {
-	fmt.Print("\nACTIVATING GOES, WILL EXIT... type reset, goes\n")
-	cmd := exec.Command("./" + GoesInstaller)
-	cmd.Start()
+// Mark the staged slot to boot once.
+func trySlot(dir string) error {
+	if err := bootc.Try(dir); err != nil {
+		return err
+	}
+	fmt.Printf("\nINSTALLED TO %s, reboot to try it\n", dir)
+	return nil
+}
+
+// Download and verify the server's signed manifest.  Without trusted keys
+// every manifest is refused.  If insecure, a missing or invalid manifest
+// only warns and returns nil to install unverified images.
+func getManifest(s string, v string, t bool,
+	insecure bool) (sign.Manifest, error) {
+	var m sign.Manifest
+	err := sign.ErrNoKeys
+	if sign.Enabled() {
+		m, err = getSignedManifest(s, v, t)
+	}
+	if err != nil {
+		if insecure {
+			fmt.Printf("    WARNING: %v, images are NOT verified\n\n",
+				err)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%v, use -insecure to override", err)
+	}
+	return m, nil
+}
+
+func getSignedManifest(s string, v string, t bool) (sign.Manifest, error) {
+	for _, fn := range []string{sign.ManifestName, sign.SignatureName} {
+		if _, err := getFile(s, v, t, fn); err != nil {
+			return nil, sign.ErrUnsigned
+		}
+	}
+	b, err := ioutil.ReadFile(sign.ManifestName)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := ioutil.ReadFile(sign.SignatureName)
+	if err != nil {
+		return nil, err
+	}
+	return sign.Verify(b, sig)
+}
+
+// Verify the downloaded image unless installing insecurely with a nil
+// manifest.
+func verify(m sign.Manifest, fn string) error {
+	if m == nil {
+		return nil
+	}
+	if err := m.Check(fn); err != nil {
+		rmFile(fn)
+		return fmt.Errorf("    Error verifying: %v", err)
+	}
+	fmt.Printf("    Verified %s\n", fn)
 	return nil
 }
+
+// Copy the verified manifest and signature into dir, otherwise remove any
+// stale ones and allow bootc to kexec the unsigned kernel.
+func installManifest(dir string, m sign.Manifest) error {
+	for _, fn := range []string{sign.ManifestName, sign.SignatureName} {
+		dst := filepath.Join(dir, fn)
+		if m == nil {
+			os.Remove(dst)
+			continue
+		}
+		if err := bootc.Copy(fn, dst); err != nil {
+			return err
+		}
+	}
+	if m == nil {
+		return bootc.SetSigned(false)
+	}
+	return nil
+}
+
+func rmManifest() {
+	rmFile(sign.ManifestName)
+	rmFile(sign.SignatureName)
+}
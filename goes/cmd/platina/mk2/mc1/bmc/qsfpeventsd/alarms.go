@@ -0,0 +1,39 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qsfpeventsd
+
+import (
+	"strconv"
+
+	"github.com/platinasystems/go/internal/opticsalarm"
+)
+
+// Publish the alarm fields, relative to port-M<slot>.qsfp., with the flags
+// of configured thresholds in place of the module's.
+func (c *Command) publishAlarms(fields map[string]string) {
+	name := "M" + strconv.Itoa(Slotid)
+	prefix := "port-" + name + ".qsfp."
+	for _, k := range opticsalarm.Monitors(4) {
+		fields[k] = c.lasts[prefix+k]
+	}
+	for k, v := range c.alarms.Update(name, fields) {
+		if v != c.lasts[prefix+k] {
+			c.pub.Print(prefix+k, ": ", v)
+			c.lasts[prefix+k] = v
+		}
+	}
+}
+
+// Publish the configured thresholds over those of the module.
+func (c *Command) publishThresholds() {
+	name := "M" + strconv.Itoa(Slotid)
+	prefix := "port-" + name + ".qsfp."
+	for k, v := range c.alarms.Thresholds(name) {
+		if v != c.lasts[prefix+k] {
+			c.pub.Print(prefix+k, ": ", v)
+			c.lasts[prefix+k] = v
+		}
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/platinasystems/go/goes/lang"
 	"github.com/platinasystems/go/internal/atsock"
 	"github.com/platinasystems/go/internal/log"
+	"github.com/platinasystems/go/internal/opticsalarm"
 	"github.com/platinasystems/go/internal/redis"
 	"github.com/platinasystems/go/internal/redis/publisher"
 )
@@ -38,12 +39,12 @@ type Command struct {
 }
 
 type Info struct {
-	mutex sync.Mutex
-	rpc   *atsock.RpcServer
-	pub   *publisher.Publisher
-	stop  chan struct{}
-	last  map[string]uint16
-	lasts map[string]string
+	rpc    *atsock.RpcServer
+	pub    *publisher.Publisher
+	stop   chan struct{}
+	last   map[string]uint16
+	lasts  map[string]string
+	alarms *opticsalarm.Monitor
 }
 
 type I2cDev struct {
@@ -121,6 +122,15 @@ func (c *Command) Main(...string) error {
 	if err = syscall.Sysinfo(&si); err != nil {
 		return err
 	}
+	if c.alarms, err = opticsalarm.NewMonitor(); err != nil {
+		return err
+	}
+	ticking := false
+	defer func() {
+		if !ticking {
+			c.alarms.Close()
+		}
+	}()
 
 	// Setup UIO device
 	x, err := uiodevs.GetIndex("qsfpeventsd")
@@ -171,8 +181,12 @@ func (c *Command) Main(...string) error {
 		return err
 	}
 
-	// Dynamic data handler
-	go qsfpioTicker(c)
+	// Dynamic data handler, which sends the alarm events until stopped
+	ticking = true
+	go func() {
+		qsfpioTicker(c)
+		c.alarms.Close()
+	}()
 
 	// Event loop
 	data := make([]byte, 4)
@@ -518,6 +532,9 @@ func (c *Command) update() error {
 					c.lasts[k] = v
 				}
 			}
+			if !PortIsCopper {
+				c.publishThresholds()
+			}
 			log.Printf("QSFP detected in MC%d port: %s", Slotid, typeString)
 			if portConfig != "" {
 				log.Print("MC Port ", Slotid, " setting changed to ", portConfig)
@@ -529,6 +546,7 @@ func (c *Command) update() error {
 				c.pub.Print("delete: ", k)
 				c.lasts[k] = ""
 			}
+			c.alarms.Remove("M" + strconv.Itoa(Slotid))
 			log.Printf("QSFP removed from MC%d port", Slotid)
 			PortIsCopper = true
 		}
@@ -591,28 +609,16 @@ func (c *Command) updateDynamic() error {
 						c.lasts[k] = va[x]
 					}
 				}
+				alarms := map[string]string{
+					"alarms": FreeSideAlarms(portLpage0.freeMonitorInterruptFlags),
+				}
 				vs := ChannelAlarms(portLpage0.channelStatusInterrupt,
 					portLpage0.channelMonitorInterruptFlags)
 				for x := 0; x < 4; x++ {
-					k = "port-" + "M" + strconv.Itoa(Slotid) + ".qsfp.rx" + strconv.Itoa(x+1) + ".alarms"
-					if vs[x] != c.lasts[k] {
-						c.pub.Print(k, ": ", vs[x])
-						c.lasts[k] = vs[x]
-					}
-				}
-				for x := 4; x < 8; x++ {
-					k = "port-" + "M" + strconv.Itoa(Slotid) + ".qsfp.tx" + strconv.Itoa(x-3) + ".alarms"
-					if vs[x] != c.lasts[k] {
-						c.pub.Print(k, ": ", vs[x])
-						c.lasts[k] = vs[x]
-					}
-				}
-				vs[0] = FreeSideAlarms(portLpage0.freeMonitorInterruptFlags)
-				k = "port-" + "M" + strconv.Itoa(Slotid) + ".qsfp.alarms"
-				if vs[0] != c.lasts[k] {
-					c.pub.Print(k, ": ", vs[0])
-					c.lasts[k] = vs[0]
+					alarms["rx"+strconv.Itoa(x+1)+".alarms"] = vs[x]
+					alarms["tx"+strconv.Itoa(x+1)+".alarms"] = vs[x+4]
 				}
+				c.publishAlarms(alarms)
 			}
 		}
 	}
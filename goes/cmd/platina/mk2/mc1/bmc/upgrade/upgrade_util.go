@@ -18,6 +18,7 @@ import (
 	"syscall"
 
 	"github.com/platinasystems/go/internal/kexec"
+	"github.com/platinasystems/go/internal/sign"
 	"github.com/platinasystems/go/internal/url"
 )
 
@@ -340,3 +341,59 @@ func cmpSums(q bool) (err error) {
 	}
 	return nil
 }
+
+// Download and verify the server's signed manifest.  Without trusted keys
+// every manifest is refused.  If insecure, a missing or invalid manifest
+// only warns and returns nil to install unverified images.
+func getManifest(s string, v string, t bool,
+	insecure bool) (sign.Manifest, error) {
+	var m sign.Manifest
+	err := sign.ErrNoKeys
+	if sign.Enabled() {
+		m, err = getSignedManifest(s, v, t)
+	}
+	if err != nil {
+		if insecure {
+			fmt.Printf("WARNING: %v, images are NOT verified\n\n", err)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%v, use -insecure to override", err)
+	}
+	return m, nil
+}
+
+func getSignedManifest(s string, v string, t bool) (sign.Manifest, error) {
+	for _, fn := range []string{sign.ManifestName, sign.SignatureName} {
+		if n, err := getFile(s, v, t, fn); err != nil || n == 0 {
+			return nil, sign.ErrUnsigned
+		}
+	}
+	b, err := ioutil.ReadFile(sign.ManifestName)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := ioutil.ReadFile(sign.SignatureName)
+	if err != nil {
+		return nil, err
+	}
+	return sign.Verify(b, sig)
+}
+
+// Verify the downloaded image unless installing insecurely with a nil
+// manifest.
+func verify(m sign.Manifest, fn string) error {
+	if m == nil {
+		return nil
+	}
+	if err := m.Check(fn); err != nil {
+		rmFile(fn)
+		return fmt.Errorf("Error verifying: %v\n", err)
+	}
+	fmt.Printf("Verified %s\n", fn)
+	return nil
+}
+
+func rmManifest() {
+	rmFile(sign.ManifestName)
+	rmFile(sign.SignatureName)
+}
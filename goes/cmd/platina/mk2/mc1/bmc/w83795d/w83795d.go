@@ -9,6 +9,7 @@ package w83795d
 import (
 	"fmt"
 	"net/rpc"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -19,6 +20,7 @@ import (
 	"github.com/platinasystems/go/goes/lang"
 	"github.com/platinasystems/go/internal/atsock"
 	"github.com/platinasystems/go/internal/eeprom"
+	"github.com/platinasystems/go/internal/fanpolicy"
 	"github.com/platinasystems/go/internal/log"
 	"github.com/platinasystems/go/internal/redis"
 	"github.com/platinasystems/go/internal/redis/publisher"
@@ -69,6 +71,15 @@ var (
 	hostCtrl bool
 	thCtrl   bool
 
+	// PolicyFile configures the "policy" fan speed, the default if
+	// present.
+	PolicyFile = fanpolicy.DefaultFile
+
+	policyCtrl   bool
+	policy       *fanpolicy.Engine
+	policyTime   time.Time
+	policyReason string
+
 	Vdev   [6]I2cDev
 	VdevIo [6]I2cDev
 
@@ -215,6 +226,12 @@ func (c *Command) update() error {
 		first = 0
 	}
 
+	if policyCtrl {
+		if err := c.policyStep(); err != nil {
+			log.Print("warning: fan policy: ", err)
+		}
+	}
+
 	for j := 0; j <= MaxFanTrays; j++ {
 		for m := 0; m < MaxFansPerTray; m++ {
 			k := "fan_tray." + strconv.Itoa((j + 1)) + "." + strconv.Itoa((m + 1)) + ".speed.units.rpm"
@@ -371,8 +388,11 @@ func (h *I2cDev) FanCount(t uint8, i uint8) (uint16, error) {
 }
 
 func (h *I2cDev) FanInit() error {
-	//default auto mode
+	//default auto mode, or policy if configured
 	lastSpeed = "auto"
+	if _, err := os.Stat(PolicyFile); err == nil {
+		lastSpeed = "policy"
+	}
 
 	//reset hwm to default values
 	r0 := getRegsBank0()
@@ -397,8 +417,8 @@ func (h *I2cDev) FanInit() error {
 		return err
 	}
 
-	//set default speed to auto
-	h.SetFanSpeed("auto", true)
+	//set default speed
+	h.SetFanSpeed(lastSpeed, true)
 
 	//enable temperature monitoring
 	r0.BankSelect.set(h, 0x80)
@@ -451,7 +471,11 @@ func (h *I2cDev) SetFanDuty(d uint8) error {
 			break
 		}
 	}
+	return h.fanDuty(d)
+}
 
+// Set manual mode and the duty of both fan outputs.
+func (h *I2cDev) fanDuty(d uint8) error {
 	r2 := getRegsBank2()
 	r2.BankSelect.set(h, 0x82)
 	r2.TempToFanMap1.set(h, 0x0)
@@ -466,6 +490,40 @@ func (h *I2cDev) SetFanDuty(d uint8) error {
 	return nil
 }
 
+// Run the fan policy engine with the temperatures in redis then publish
+// its decision and set the duty of each fan tray.  The engine sets the
+// failsafe duty with failed fans.
+func (c *Command) policyStep() error {
+	fields, err := redis.Hgetall(machine.Name)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var dt time.Duration
+	if !policyTime.IsZero() {
+		dt = now.Sub(policyTime)
+	}
+	policyTime = now
+	d := policy.Step(fields, dt)
+	for k, v := range d.Fields("fan_policy.") {
+		if v != c.lasts[k] {
+			c.pub.Print(k, ": ", v)
+			c.lasts[k] = v
+		}
+	}
+	if d.Reason != policyReason {
+		log.Print("notice: fan policy duty ", d.Duty, "% by ",
+			d.Reason)
+		policyReason = d.Reason
+	}
+	for j := 0; j < MaxFanTrays; j++ {
+		if err := Vdev[j].fanDuty(d.PWM()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (h *I2cDev) SetFanControl(w string) error {
 	r2 := getRegsIo()
 	switch w {
@@ -504,7 +562,26 @@ func (h *I2cDev) SetFanSpeed(w string, l bool) error {
 		w = "high"
 	}
 
+	if w != "policy" {
+		policyCtrl = false
+	}
+
 	switch w {
+	case "policy":
+		p, err := fanpolicy.Load(PolicyFile)
+		if err != nil {
+			log.Print("warning: ", err)
+			return err
+		}
+		policy = fanpolicy.New(p)
+		policyTime = time.Time{}
+		policyReason = ""
+		policyCtrl = true
+		hostCtrl = false
+		thCtrl = false
+		if l {
+			log.Print("notice: fan speed set to ", w)
+		}
 	case "auto":
 		if !hostCtrl {
 			r2.BankSelect.set(h, 0x82)
@@ -652,6 +729,10 @@ func (h *I2cDev) GetFanSpeed() (string, error) {
 	var duty uint8
 	r2 := getRegsBank2()
 
+	if policyCtrl {
+		return "policy", nil
+	}
+
 	if !hostCtrl {
 		r2.BankSelect.set(h, 0x82)
 		r2.TempToFanMap1.get(h)
@@ -838,7 +919,7 @@ func writeRegs() error {
 	for k, v := range WrRegVal {
 		switch WrRegFn[k] {
 		case "speed":
-			if v == "auto" || v == "high" || v == "med" || v == "low" || v == "max" {
+			if v == "auto" || v == "high" || v == "med" || v == "low" || v == "max" || v == "policy" {
 				for i := 0; i < MaxFanTrays; i++ {
 					Vdev[i].SetFanSpeed(v, true)
 				}
@@ -19,7 +19,7 @@ import (
 const (
 	Name    = "upgrade"
 	Apropos = "upgrade images"
-	Usage   = "upgrade [-v VER] [-s SERVER[/dir]] [-r] [-l] [-t] [-a | -g -k -c] [-f]"
+	Usage   = "upgrade [-v VER] [-s SERVER[/dir]] [-r] [-l] [-t] [-a | -g -k -c] [-f] [-insecure]"
 	Man     = `
 DESCRIPTION
 	The upgrade command updates firmware images.
@@ -37,6 +37,12 @@ DESCRIPTION
 	Upgrade proceeds only if the selected version number is newer,
 	unless overridden with the "-f" force flag.
 
+	Images are only installed if listed in the server's MANIFEST of
+	SHA-256 hashes and its detached ed25519 signature, MANIFEST.sig, is
+	valid for a key embedded in goes.  Goes built without a key refuses
+	every image.  The "-insecure" flag installs unsigned or
+	tampered images with a warning.
+
 OPTIONS
 	-v [VER]          version [YYYYMMDD] or LATEST (default)
 	-s [SERVER[/dir]] IP4 or URL, default is downloads.platina.com
@@ -47,7 +53,8 @@ OPTIONS
 	-k                upgrade kernel
 	-c                upgrade coreboot
 	-a                upgrade all
-	-f                force upgrade (ignore version check)`
+	-f                force upgrade (ignore version check)
+	-insecure         install images that fail signature verification`
 
 	DfltMod = 0755
 	DfltSrv = "downloads.platinasystems.com"
@@ -78,7 +85,7 @@ func (cmd) Apropos() lang.Alt { return apropos }
 
 func (cmd) Main(args ...string) error {
 	flag, args := flags.New(args, "-t", "-l", "-f", "-r",
-		"-g", "-c", "-k", "-a")
+		"-g", "-c", "-k", "-a", "-insecure")
 	parm, args := parms.New(args, "-v", "-s")
 	if len(parm.ByName["-v"]) == 0 {
 		parm.ByName["-v"] = DfltVer
@@ -113,7 +120,8 @@ func (cmd) Main(args ...string) error {
 	}
 	if err := doUpgrade(parm.ByName["-s"], parm.ByName["-v"],
 		flag.ByName["-t"], flag.ByName["-g"], flag.ByName["-k"],
-		flag.ByName["-c"], flag.ByName["-f"]); err != nil {
+		flag.ByName["-c"], flag.ByName["-f"],
+		flag.ByName["-insecure"]); err != nil {
 		return err
 	}
 	return nil
@@ -157,20 +165,25 @@ func reportVersions() error {
 }
 
 func doUpgrade(s string, v string, t bool, g bool, k bool,
-	c bool, f bool) error {
+	c bool, f bool, insecure bool) error {
 	fmt.Print("\n")
+	m, err := getManifest(s, v, t, insecure)
+	if err != nil {
+		return err
+	}
+	defer rmManifest()
 	if g {
-		if err := upgradeGoes(s, v, t, f); err != nil {
+		if err := upgradeGoes(s, v, t, f, m); err != nil {
 			return err
 		}
 	}
 	if k {
-		if err := upgradeKernel(s, v, t, f); err != nil {
+		if err := upgradeKernel(s, v, t, f, m); err != nil {
 			return err
 		}
 	}
 	if c {
-		if err := upgradeCoreboot(s, v, t, f); err != nil {
+		if err := upgradeCoreboot(s, v, t, f, m); err != nil {
 			return err
 		}
 	}
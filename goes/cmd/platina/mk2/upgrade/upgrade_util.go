@@ -15,6 +15,7 @@ import (
 	"syscall"
 
 	. "github.com/platinasystems/go"
+	"github.com/platinasystems/go/internal/sign"
 	"github.com/platinasystems/go/internal/url"
 )
 
@@ -132,7 +133,7 @@ func getCorebootInfo() (im IMGINFO, err error) {
 	return im, nil
 }
 
-func upgradeGoes(s string, v string, t bool, f bool) error {
+func upgradeGoes(s string, v string, t bool, f bool, m sign.Manifest) error {
 	fmt.Printf("Update Goes\n")
 	if !f {
 		g := getGoesVer()
@@ -152,13 +153,14 @@ func upgradeGoes(s string, v string, t bool, f bool) error {
 		}
 	}
 
-	if err := installGoes(s, v, t); err != nil {
+	if err := installGoes(s, v, t, m); err != nil {
 		return err
 	}
 	return nil
 }
 
-func upgradeKernel(s string, v string, t bool, f bool) error {
+func upgradeKernel(s string, v string, t bool, f bool,
+	m sign.Manifest) error {
 	fmt.Printf("Update Kernel\n")
 	kr, fn, err := getSrvKernelVer(s, v, t)
 	if err != nil {
@@ -177,13 +179,14 @@ func upgradeKernel(s string, v string, t bool, f bool) error {
 		}
 	}
 
-	if err := installKernel(s, v, t, fn); err != nil {
+	if err := installKernel(s, v, t, fn, m); err != nil {
 		return err
 	}
 	return nil
 }
 
-func upgradeCoreboot(s string, v string, t bool, f bool) error {
+func upgradeCoreboot(s string, v string, t bool, f bool,
+	m sign.Manifest) error {
 	fmt.Printf("Update Coreboot\n")
 	if !f {
 		c, err := getCorebootVer()
@@ -201,7 +204,7 @@ func upgradeCoreboot(s string, v string, t bool, f bool) error {
 		}
 	}
 
-	if err := installCoreboot(s, v, t); err != nil {
+	if err := installCoreboot(s, v, t, m); err != nil {
 		return err
 	}
 	return nil
@@ -311,7 +314,7 @@ func getSrvCorebootVer(s string, v string, t bool) (string, error) { //TODO
 	return "no_tag", nil
 }
 
-func installGoes(s string, v string, t bool) error {
+func installGoes(s string, v string, t bool, m sign.Manifest) error {
 	fn := GoesInstaller
 	n, err := getFile(s, v, t, fn)
 	if err != nil {
@@ -320,12 +323,16 @@ func installGoes(s string, v string, t bool) error {
 	if n < 1000 {
 		return fmt.Errorf("    Error file too small: %v", err)
 	}
+	if err = verify(m, fn); err != nil {
+		return err
+	}
 
 	Install_flag = true
 	return nil
 }
 
-func installKernel(s string, v string, t bool, fn string) error {
+func installKernel(s string, v string, t bool, fn string,
+	m sign.Manifest) error {
 	n, err := getFile(s, v, t, fn)
 	if err != nil {
 		return fmt.Errorf("    Error downloading: %v", err)
@@ -333,6 +340,9 @@ func installKernel(s string, v string, t bool, fn string) error {
 	if n < 1000 {
 		return fmt.Errorf("    Error file too small: %v", err)
 	}
+	if err = verify(m, fn); err != nil {
+		return err
+	}
 
 	_, err = exec.Command("dpkg", "-i", fn).Output()
 	if err != nil {
@@ -353,7 +363,10 @@ func installKernel(s string, v string, t bool, fn string) error {
 	return nil
 }
 
-func installCoreboot(s string, v string, t bool) error {
+func installCoreboot(s string, v string, t bool, m sign.Manifest) error {
+	if err := verify(m, "coreboot.rom"); err != nil {
+		return err
+	}
 	_, err := exec.Command("/usr/local/sbin/flashrom", "-p",
 		"internal:boardmismatch=force", "-l",
 		"/usr/local/share/flashrom/layouts/platina-mk1.xml",
@@ -434,3 +447,60 @@ func activateGoes() error {
 	cmd.Start()
 	return nil
 }
+
+// Download and verify the server's signed manifest.  Without trusted keys
+// every manifest is refused.  If insecure, a missing or invalid manifest
+// only warns and returns nil to install unverified images.
+func getManifest(s string, v string, t bool,
+	insecure bool) (sign.Manifest, error) {
+	var m sign.Manifest
+	err := sign.ErrNoKeys
+	if sign.Enabled() {
+		m, err = getSignedManifest(s, v, t)
+	}
+	if err != nil {
+		if insecure {
+			fmt.Printf("    WARNING: %v, images are NOT verified\n\n",
+				err)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%v, use -insecure to override", err)
+	}
+	return m, nil
+}
+
+func getSignedManifest(s string, v string, t bool) (sign.Manifest, error) {
+	for _, fn := range []string{sign.ManifestName, sign.SignatureName} {
+		if _, err := getFile(s, v, t, fn); err != nil {
+			return nil, sign.ErrUnsigned
+		}
+	}
+	b, err := ioutil.ReadFile(sign.ManifestName)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := ioutil.ReadFile(sign.SignatureName)
+	if err != nil {
+		return nil, err
+	}
+	return sign.Verify(b, sig)
+}
+
+// Verify the downloaded image unless installing insecurely with a nil
+// manifest.
+func verify(m sign.Manifest, fn string) error {
+	if m == nil {
+		return nil
+	}
+	if err := m.Check(fn); err != nil {
+		rmFile(fn)
+		return fmt.Errorf("    Error verifying: %v", err)
+	}
+	fmt.Printf("    Verified %s\n", fn)
+	return nil
+}
+
+func rmManifest() {
+	rmFile(sign.ManifestName)
+	rmFile(sign.SignatureName)
+}
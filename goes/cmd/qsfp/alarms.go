@@ -0,0 +1,31 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qsfp
+
+import (
+	"strconv"
+
+	"github.com/platinasystems/go/internal/opticsalarm"
+)
+
+// Publish the port's alarm fields, relative to port-N.qsfp., with the flags
+// of configured thresholds in place of the module's.
+func (c *Command) publishAlarms(port, lanes int, fields map[string]string) {
+	prefix := "port-" + strconv.Itoa(port) + ".qsfp."
+	for _, k := range opticsalarm.Monitors(lanes) {
+		fields[k] = c.lasts[prefix+k]
+	}
+	for k, v := range c.alarms.Update(strconv.Itoa(port), fields) {
+		c.publish(prefix+k, v)
+	}
+}
+
+// Publish the configured thresholds of the port over those of its module.
+func (c *Command) publishThresholds(port int) {
+	prefix := "port-" + strconv.Itoa(port) + ".qsfp."
+	for k, v := range c.alarms.Thresholds(strconv.Itoa(port)) {
+		c.publish(prefix+k, v)
+	}
+}
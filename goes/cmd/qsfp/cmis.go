@@ -0,0 +1,275 @@
+// Copyright 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qsfp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/platinasystems/go/internal/i2c"
+	"github.com/platinasystems/go/internal/log"
+	"github.com/platinasystems/go/internal/machine"
+	"github.com/platinasystems/go/internal/redis"
+	"github.com/platinasystems/go/internal/redis/rpc/args"
+	"github.com/platinasystems/go/internal/redis/rpc/reply"
+	"github.com/platinasystems/go/vnet/devices/optics/sfp"
+)
+
+// QSFP-DD and OSFP modules with the CMIS memory map; nil otherwise.
+var portCmis [32]*sfp.CmisMemory
+
+var i2cClient *i2c.Client
+
+// Access CMIS memory through i2cd transactions that select the module's
+// muxes since page select and the following transfers must not be
+// interleaved with other clients.
+func (h *I2cDev) cmisReadWrite(offset uint, p []uint8, isWrite bool) bool {
+	if i2cClient == nil {
+		cl, err := i2c.Dial("qsfp")
+		if err != nil {
+			log.Print("dialing: ", err)
+			return false
+		}
+		i2cClient = cl
+	}
+	t := i2c.NewTxn("").
+		Mux(h.MuxBus, h.MuxAddr, byte(h.MuxValue)).
+		Mux(h.MuxBus2, h.MuxAddr2, byte(h.MuxValue2))
+	if isWrite {
+		t.Add(i2c.Op{
+			Bus:     h.Bus,
+			Address: h.Addr,
+			RW:      i2c.Write,
+			Command: uint8(offset),
+			Size:    i2c.I2CBlockData,
+			Data:    p,
+		})
+	} else {
+		t.ReadBlock(h.Bus, h.Addr, uint8(offset), len(p))
+	}
+	t.Mux(h.MuxBus2, h.MuxAddr2, 0).Mux(h.MuxBus, h.MuxAddr, 0)
+	results, err := i2cClient.Do(t)
+	if err != nil {
+		log.Print("qsfp cmis: ", err)
+		return false
+	}
+	if !isWrite {
+		copy(p, results[2].Data)
+	}
+	return true
+}
+
+// IsCmis checks the SFF-8024 identifier of the module.
+func (h *I2cDev) IsCmis() bool {
+	id := []uint8{0}
+	return h.cmisReadWrite(sfp.CmisId, id, false) && sfp.IsCmis(sfp.Id(id[0]))
+}
+
+// Publish the thresholds, state, and applications of a CMIS module.
+func (c *Command) cmisStatic(lp int, cm *sfp.CmisMemory) {
+	prefix := "port-" + strconv.Itoa(lp) + ".qsfp."
+	th := cm.Thresholds()
+	for _, x := range []struct {
+		k    string
+		t    *sfp.QsfpThreshold
+		prec int
+	}{
+		{"temperature.%sThreshold.units.C", &th.TemperatureInCelsius, 1},
+		{"vcc.%sThreshold.units.V", &th.SupplyVoltageInVolts, 3},
+		{"rx.power.%sThreshold.units.mW", &th.RxPowerInWatts, 3},
+		{"tx.power.%sThreshold.units.mW", &th.TxPowerInWatts, 3},
+		{"tx.bias%sThreshold.units.mA", &th.TxBiasCurrentInAmps, 3},
+	} {
+		for _, y := range []struct {
+			name string
+			v    float64
+		}{
+			{"highAlarm", x.t.Alarm.Hi},
+			{"lowAlarm", x.t.Alarm.Lo},
+			{"highWarn", x.t.Warning.Hi},
+			{"lowWarn", x.t.Warning.Lo},
+		} {
+			name := y.name
+			if strings.HasPrefix(x.k, "tx.bias") {
+				// tx.biasHighAlarmThreshold
+				name = strings.ToUpper(name[:1]) + name[1:]
+			}
+			c.publish(prefix+fmt.Sprintf(x.k, name),
+				strconv.FormatFloat(y.v, 'f', x.prec, 64))
+		}
+	}
+	for i, a := range cm.Applications() {
+		c.publish(prefix+"application"+strconv.Itoa(i+1), a.String())
+	}
+	c.publish(prefix+"revision", cm.Revision())
+	c.publish(prefix+"txDisable", strconv.FormatBool(cm.TxDisabled() != 0))
+}
+
+// Refresh and publish the monitors, flags, and state of a CMIS module.
+func (c *Command) cmisMonitor(i, port int) {
+	cm := portCmis[i]
+	if !cm.ReadCmisDynamic(Vdev[i].cmisReadWrite) {
+		return
+	}
+	prefix := "port-" + strconv.Itoa(port) + ".qsfp."
+	mon := cm.Monitoring()
+	t := uint16(cm.Lower[sfp.CmisTemperature])<<8 |
+		uint16(cm.Lower[sfp.CmisTemperature+1])
+	c.publish(prefix+"temperature.units.C", CheckTemp(t, strconv.Itoa(port)))
+	c.publish(prefix+"vcc.units.V", mon.Voltage)
+	for x := range mon.RxPower {
+		lane := strconv.Itoa(x + 1)
+		c.publish(prefix+"rx"+lane+".power.units.mW", mon.RxPower[x])
+		c.publish(prefix+"tx"+lane+".power.units.mW", mon.TxPower[x])
+		c.publish(prefix+"tx"+lane+".bias.units.mA", mon.TxBias[x])
+	}
+	alarms := make(map[string]string)
+	f := cm.LaneFlags()
+	for x := range mon.RxPower {
+		lane := strconv.Itoa(x + 1)
+		rx, tx := f.LaneAlarms(x, false)
+		alarms["rx"+lane+".alarms"] = strings.Join(rx, ",")
+		alarms["tx"+lane+".alarms"] = strings.Join(tx, ",")
+	}
+	var module []string
+	for _, s := range cm.ModuleAlarms() {
+		// L-Temp High Alarm to TempHighAlarm as FreeSideAlarms
+		module = append(module, strings.Replace(s[2:], " ", "", -1))
+	}
+	alarms["alarms"] = strings.Join(module, ",")
+	c.publishAlarms(port, len(mon.RxPower), alarms)
+	var states []string
+	for _, s := range cm.DataPathStates(cm.Lanes()) {
+		states = append(states, s.String())
+	}
+	c.publish(prefix+"dataPathState", strings.Join(states, ","))
+	c.publish(prefix+"lowPower", strconv.FormatBool(cm.LowPower()))
+	k := prefix + "moduleState"
+	if s := cm.ModuleState().String(); c.lasts[k] != "" && s != c.lasts[k] {
+		log.Print("port ", port, " module state ", c.lasts[k], " to ", s)
+	}
+	c.publish(k, cm.ModuleState().String())
+}
+
+func (c *Command) publish(k, v string) {
+	if v != c.lasts[k] {
+		c.pub.Print(k, ": ", v)
+		c.lasts[k] = v
+	}
+}
+
+// Enable the output of all lanes.
+func (h *I2cDev) cmisTxEnable(cm *sfp.CmisMemory, p int) {
+	if !sfp.ReadCmisPage(h.cmisReadWrite, 0x10, &cm.Page10) {
+		return
+	}
+	if h.cmisReadWrite(sfp.CmisTxDisable, []uint8{0}, true) {
+		cm.Page10[sfp.CmisTxDisable-sfp.CmisUpper] = 0
+		log.Print("Port ", p, " Tx Disable set to false")
+	}
+	sfp.ReadCmisPage(h.cmisReadWrite, 0, &cm.Page00)
+}
+
+// Delete the fields of a removed CMIS module.
+func (c *Command) cmisRemoved(lp int) {
+	for _, v := range sfp.CmisRedisFields {
+		k := "port-" + strconv.Itoa(lp) + "." + v
+		if c.lasts[k] != "" {
+			c.pub.Print("delete: ", k)
+			c.lasts[k] = ""
+		}
+	}
+	for x := 5; x <= sfp.CmisNLane; x++ {
+		for _, d := range []string{"rx", "tx"} {
+			k := "port-" + strconv.Itoa(lp) + ".qsfp." + d +
+				strconv.Itoa(x) + ".alarms"
+			if c.lasts[k] != "" {
+				c.pub.Print("delete: ", k)
+				c.lasts[k] = ""
+			}
+		}
+	}
+}
+
+// Info serves hset of the CMIS module controls: port-N.qsfp.lowPower, true
+// or false, and port-N.qsfp.reset, true.
+type Info struct {
+	c *Command
+}
+
+var cmisControls = []string{"lowPower", "reset"}
+
+func (i *Info) Hset(args args.Hset, reply *reply.Hset) error {
+	var lp int
+	var field string
+	_, err := fmt.Sscanf(strings.Replace(args.Field, ".", " ", -1),
+		"port-%d qsfp %s", &lp, &field)
+	if err != nil {
+		return fmt.Errorf("cannot hset: %s", args.Field)
+	}
+	//logical to physical port translation
+	x := lp - porto
+	if x%2 == 0 {
+		x -= 2
+	}
+	if x < 0 || x >= len(Vdev) {
+		return fmt.Errorf("%s: invalid port", args.Field)
+	}
+	v, err := strconv.ParseBool(string(args.Value))
+	if err != nil {
+		return err
+	}
+	i.c.mutex.Lock()
+	defer i.c.mutex.Unlock()
+	cm := portCmis[x]
+	if cm == nil {
+		return fmt.Errorf("port %d: not a CMIS module", lp)
+	}
+	h := &Vdev[x]
+	b := []uint8{0}
+	if !h.cmisReadWrite(sfp.CmisModuleControl, b, false) {
+		return fmt.Errorf("port %d: read failed", lp)
+	}
+	switch field {
+	case "lowPower":
+		b[0] = sfp.CmisModuleControlValue(b[0], v)
+	case "reset":
+		if !v {
+			return fmt.Errorf("%s: may only be true", args.Field)
+		}
+		b[0] |= sfp.CmisSoftwareReset
+	default:
+		return fmt.Errorf("cannot hset: %s", args.Field)
+	}
+	if !h.cmisReadWrite(sfp.CmisModuleControl, b, true) {
+		return fmt.Errorf("port %d: write failed", lp)
+	}
+	log.Print("port ", lp, " ", field, " set to ", v)
+	if field == "reset" {
+		// republish the module once it's out of reset
+		present[x/16] |= 1 << uint(x%16)
+	} else {
+		cm.Lower[sfp.CmisModuleControl] = b[0]
+		i.c.publish("port-"+strconv.Itoa(lp)+".qsfp.lowPower",
+			strconv.FormatBool(v))
+	}
+	*reply = 1
+	return nil
+}
+
+// Assign hset of the controls of all ports, 0 or 1 based, to Info.
+func assignCmisControls() error {
+	for lp := 0; lp <= len(Vdev); lp++ {
+		for _, k := range cmisControls {
+			err := redis.Assign(machine.Name+":port-"+strconv.Itoa(lp)+
+				".qsfp."+k, "qsfp", "Info")
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
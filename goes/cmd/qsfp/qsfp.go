@@ -6,6 +6,7 @@ package qsfp
 
 import (
 	"encoding/hex"
+	"net/rpc"
 	"strconv"
 	"strings"
 	"sync"
@@ -15,10 +16,13 @@ import (
 	redigo "github.com/garyburd/redigo/redis"
 	"github.com/platinasystems/go/goes/cmd"
 	"github.com/platinasystems/go/goes/lang"
+	"github.com/platinasystems/go/internal/atsock"
 	"github.com/platinasystems/go/internal/log"
 	"github.com/platinasystems/go/internal/machine"
+	"github.com/platinasystems/go/internal/opticsalarm"
 	"github.com/platinasystems/go/internal/redis"
 	"github.com/platinasystems/go/internal/redis/publisher"
+	"github.com/platinasystems/go/vnet/devices/optics/sfp"
 	"github.com/platinasystems/go/vnet/platforms/mk1"
 )
 
@@ -30,6 +34,9 @@ type Command struct {
 
 	stop    chan struct{}
 	pub     *publisher.Publisher
+	rpc     *atsock.RpcServer
+	mutex   sync.Mutex
+	alarms  *opticsalarm.Monitor
 	last    map[string]float64
 	lasts   map[string]string
 	lastu   map[string]uint8
@@ -63,6 +70,39 @@ func (*Command) String() string { return "qsfp" }
 
 func (*Command) Usage() string { return "qsfp" }
 
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Publish the presence, identity, thresholds, and monitoring data of
+	QSFP modules to redis.
+
+	QSFP-DD and OSFP modules are decoded with the CMIS memory map and
+	publish up to eight lanes, their applications, module state, and
+	data path states. These accept:
+
+	hset platina port-N.qsfp.lowPower true|false
+	hset platina port-N.qsfp.reset true
+
+ALARMS
+	The raising and clearing of module and lane alarms and warnings are
+	logged, published to the qsfp.events channel, and pushed to a list of
+	the port's recent events.
+
+	goes subscribe qsfp.events
+	goes lrange port-N.qsfp.events
+
+	The optional /etc/goes/optics-alarms.yaml configures the number of
+	events listed and thresholds that override those of the module.
+
+	history: 64
+	thresholds:
+	- temperature: {highAlarm: 70, highWarn: 65}
+	- ports: [1, 2]
+	  rx.power: {lowAlarm: 0.05, lowWarn: 0.1}`,
+	}
+}
+
 func (*Command) Apropos() lang.Alt {
 	return lang.Alt{
 		lang.EnUS: "qsfp monitoring daemon, publishes to redis",
@@ -71,6 +111,9 @@ func (*Command) Apropos() lang.Alt {
 
 func (c *Command) Close() error {
 	close(c.stop)
+	if c.rpc != nil {
+		return c.rpc.Close()
+	}
 	return nil
 }
 
@@ -105,6 +148,18 @@ func (c *Command) Main(...string) error {
 	if err = syscall.Sysinfo(&si); err != nil {
 		return err
 	}
+	if c.rpc, err = atsock.NewRpcServer("qsfp"); err != nil {
+		return err
+	}
+	rpc.Register(&Info{c})
+	if err = assignCmisControls(); err != nil {
+		return err
+	}
+	if c.alarms, err = opticsalarm.NewMonitor(); err != nil {
+		return err
+	}
+	// updateMonitor sends the alarm events from this loop
+	defer c.alarms.Close()
 
 	go qsfpioTicker(c)
 	t := time.NewTicker(3 * time.Second)
@@ -141,6 +196,9 @@ func (c *Command) updatePresence() error {
 		return nil
 	}
 
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	var buffPresent = [2]uint16{0xffff, 0xffff}
 
 	for j := 0; j < 2; j++ {
@@ -158,8 +216,19 @@ func (c *Command) updatePresence() error {
 					var typeString string
 					if ((1 << uint(i)) & (buffPresent[j] ^ 0xffff)) != 0 {
 						//when qsfp is installed publish static data
+						var cm *sfp.CmisMemory
+						if Vdev[i+j*16].IsCmis() {
+							// QSFP-DD or OSFP
+							cm, _ = sfp.ReadCmis(Vdev[i+j*16].cmisReadWrite)
+						}
+						portCmis[i+j*16] = cm
 						k := "port-" + strconv.Itoa(lp) + ".qsfp.compliance"
-						v := Vdev[i+j*16].Compliance()
+						var v string
+						if cm != nil {
+							v = cm.Compliance()
+						} else {
+							v = Vdev[i+j*16].Compliance()
+						}
 						var portConfig string
 
 						//identify copper vs optic and set media and speed
@@ -190,6 +259,17 @@ func (c *Command) updatePresence() error {
 									portConfig += "cl91 "
 								}
 							}
+						} else if cm != nil {
+							portIsCopper[i+j*16] = false
+							Vdev[i+j*16].cmisTxEnable(cm, lp)
+							if media != "fiber" {
+								ret, err := redis.Hset(machine.Name, "vnet."+mk1.IfnameOf(lp, (1+porto))+".media", "fiber")
+								if err != nil || ret != 1 {
+									log.Print("qsfp hset error:", err, " ", ret)
+								} else {
+									portConfig += "fiber "
+								}
+							}
 						} else if strings.Contains(v, "40G") {
 							portIsCopper[i+j*16] = false
 							Vdev[i+j*16].TxDisableSet(false, lp)
@@ -235,27 +315,43 @@ func (c *Command) updatePresence() error {
 							c.lasts[k] = v
 						}
 
+						var id sfp.QsfpIdFields
+						if cm != nil {
+							id = cm.Ident()
+						}
 						k = "port-" + strconv.Itoa(lp) + ".qsfp.vendor"
-						v = Vdev[i+j*16].Vendor()
+						v = id.Vendor
+						if cm == nil {
+							v = Vdev[i+j*16].Vendor()
+						}
 						typeString += strings.Trim(v, " ") + ", "
 						if v != c.lasts[k] {
 							c.pub.Print(k, ": ", v)
 							c.lasts[k] = v
 						}
 						k = "port-" + strconv.Itoa(lp) + ".qsfp.partnumber"
-						v = Vdev[i+j*16].PN()
+						v = id.PartNumber
+						if cm == nil {
+							v = Vdev[i+j*16].PN()
+						}
 						typeString += strings.Trim(v, " ") + ", "
 						if v != c.lasts[k] {
 							c.pub.Print(k, ": ", v)
 							c.lasts[k] = v
 						}
 						k = "port-" + strconv.Itoa(lp) + ".qsfp.serialnumber"
-						v = Vdev[i+j*16].SN()
+						v = id.SerialNumber
+						if cm == nil {
+							v = Vdev[i+j*16].SN()
+						}
 						typeString += strings.Trim(v, " ")
 						if v != c.lasts[k] {
 							c.pub.Print(k, ": ", v)
 						}
-						if !portIsCopper[i+j*16] {
+						if cm != nil {
+							c.cmisStatic(lp, cm)
+							c.publishThresholds(lp)
+						} else if !portIsCopper[i+j*16] {
 							// optics need delay from power on to make thresholds readable
 							time.Sleep(10 * time.Millisecond)
 							// get monitoring thresholds if qsfp is not a cable
@@ -387,6 +483,9 @@ func (c *Command) updatePresence() error {
 								c.lasts[k] = v
 							}
 						}
+						if !portIsCopper[i+j*16] {
+							c.publishThresholds(lp)
+						}
 						log.Print("QSFP detected in port ", lp, ": ", typeString)
 						if portConfig != "" {
 							log.Print("Port ", lp, " setting changed to ", portConfig)
@@ -398,6 +497,11 @@ func (c *Command) updatePresence() error {
 							c.pub.Print("delete: ", k)
 							c.lasts[k] = ""
 						}
+						if portCmis[i+j*16] != nil {
+							c.cmisRemoved(lp)
+							portCmis[i+j*16] = nil
+						}
+						c.alarms.Remove(strconv.Itoa(lp))
 						log.Print("QSFP removed from port ", lp)
 						portIsCopper[i+j*16] = true
 						if maxTempPort == strconv.Itoa(lp) {
@@ -423,6 +527,9 @@ func (c *Command) updateMonitor() error {
 		return nil
 	}
 
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	for i := 0; i < 32; i++ {
 		//publish dynamic monitoring data
 		var port int
@@ -433,7 +540,9 @@ func (c *Command) updateMonitor() error {
 		}
 		port += porto
 		if present[i/16]&(1<<uint(i%16)) == 0 {
-			if !portIsCopper[i] {
+			if !portIsCopper[i] && portCmis[i] != nil {
+				c.cmisMonitor(i, port)
+			} else if !portIsCopper[i] {
 				// get monitoring data only if qsfp is present and not a cable
 				if Vdev[i].DataReady() {
 					Vdev[i].DynamicBlocks(i)
@@ -473,27 +582,15 @@ func (c *Command) updateMonitor() error {
 							c.lasts[k] = va[x]
 						}
 					}
+					alarms := map[string]string{
+						"alarms": FreeSideAlarms(portLpage0[i].freeMonitorInterruptFlags),
+					}
 					vs := ChannelAlarms(portLpage0[i].channelStatusInterrupt, portLpage0[i].channelMonitorInterruptFlags)
 					for x := 0; x < 4; x++ {
-						k = "port-" + strconv.Itoa(port) + ".qsfp.rx" + strconv.Itoa(x+1) + ".alarms"
-						if vs[x] != c.lasts[k] {
-							c.pub.Print(k, ": ", vs[x])
-							c.lasts[k] = vs[x]
-						}
-					}
-					for x := 4; x < 8; x++ {
-						k = "port-" + strconv.Itoa(port) + ".qsfp.tx" + strconv.Itoa(x-3) + ".alarms"
-						if vs[x] != c.lasts[k] {
-							c.pub.Print(k, ": ", vs[x])
-							c.lasts[k] = vs[x]
-						}
-					}
-					vs[0] = FreeSideAlarms(portLpage0[i].freeMonitorInterruptFlags)
-					k = "port-" + strconv.Itoa(port) + ".qsfp.alarms"
-					if vs[0] != c.lasts[k] {
-						c.pub.Print(k, ": ", vs[0])
-						c.lasts[k] = vs[0]
+						alarms["rx"+strconv.Itoa(x+1)+".alarms"] = vs[x]
+						alarms["tx"+strconv.Itoa(x+1)+".alarms"] = vs[x+4]
 					}
+					c.publishAlarms(port, 4, alarms)
 				}
 			}
 		}
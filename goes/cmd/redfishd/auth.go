@@ -0,0 +1,151 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package redfishd
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Redfish privileges of the roles
+const (
+	login = 1 << iota
+	configureComponents
+	configureManager
+)
+
+var privileges = map[string]int{
+	"ReadOnly":      login,
+	"Operator":      login | configureComponents,
+	"Administrator": login | configureComponents | configureManager,
+}
+
+type user struct {
+	name, password, role string
+}
+
+type session struct {
+	id, token string
+	user      *user
+	last      time.Time
+}
+
+type auth struct {
+	mutex    sync.Mutex
+	users    map[string]*user
+	sessions map[string]*session // by token
+	timeout  time.Duration
+	lastId   int
+}
+
+func newAuth(cfg *Config) *auth {
+	a := &auth{
+		users:    make(map[string]*user),
+		sessions: make(map[string]*session),
+		timeout:  time.Duration(cfg.SessionTimeout) * time.Second,
+	}
+	for _, u := range cfg.Users {
+		a.users[u.Name] = &user{u.Name, u.Password, u.Role}
+	}
+	return a
+}
+
+// Returns the named user if the password is valid.
+func (a *auth) login(name, password string) *user {
+	u, found := a.users[name]
+	if !found || subtle.ConstantTimeCompare([]byte(u.password),
+		[]byte(password)) != 1 {
+		return nil
+	}
+	return u
+}
+
+// Returns the user of the request's session token or basic authorization.
+func (a *auth) user(r *http.Request) *user {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.expire()
+	if token := r.Header.Get("X-Auth-Token"); len(token) > 0 {
+		if s, found := a.sessions[token]; found {
+			s.last = time.Now()
+			return s.user
+		}
+		return nil
+	}
+	if name, password, ok := r.BasicAuth(); ok {
+		return a.login(name, password)
+	}
+	return nil
+}
+
+func (a *auth) expire() {
+	for token, s := range a.sessions {
+		if time.Since(s.last) > a.timeout {
+			delete(a.sessions, token)
+		}
+	}
+}
+
+func (a *auth) newSession(name, password string) *session {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	u := a.login(name, password)
+	if u == nil {
+		return nil
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil
+	}
+	a.lastId++
+	s := &session{
+		id:    strconv.Itoa(a.lastId),
+		token: hex.EncodeToString(b),
+		user:  u,
+		last:  time.Now(),
+	}
+	a.sessions[s.token] = s
+	return s
+}
+
+func (a *auth) session(id string) *session {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.expire()
+	for _, s := range a.sessions {
+		if s.id == id {
+			return s
+		}
+	}
+	return nil
+}
+
+func (a *auth) sessionIds() []string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.expire()
+	ids := make([]string, 0, len(a.sessions))
+	for _, s := range a.sessions {
+		ids = append(ids, s.id)
+	}
+	return ids
+}
+
+func (a *auth) deleteSession(id string) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	for token, s := range a.sessions {
+		if s.id == id {
+			delete(a.sessions, token)
+			return true
+		}
+	}
+	return false
+}
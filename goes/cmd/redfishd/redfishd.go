@@ -0,0 +1,302 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package redfishd provides a DMTF Redfish service of the machine's redis
+// hash and its reset and upgrade commands.
+package redfishd
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/platinasystems/go/goes"
+	"github.com/platinasystems/go/goes/cmd"
+	"github.com/platinasystems/go/goes/lang"
+	"github.com/platinasystems/go/internal/machine"
+	"github.com/platinasystems/go/internal/parms"
+	"github.com/platinasystems/go/internal/redis"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	DefaultConfigFile = "/etc/goes/redfishd.yaml"
+	// The self-signed certificate and key without those of the config.
+	DefaultCertFile = "/etc/goes/redfishd.crt"
+	DefaultKeyFile  = "/etc/goes/redfishd.key"
+)
+
+// Config of the service and its users.
+type Config struct {
+	// Address, default :443.
+	Listen string `yaml:"listen"`
+	// PEM files of the TLS certificate and key; without, the service
+	// generates a self-signed DefaultCertFile and DefaultKeyFile.
+	Cert string `yaml:"cert"`
+	Key  string `yaml:"key"`
+	// Seconds of session inactivity, default 1800.
+	SessionTimeout int          `yaml:"sessionTimeout"`
+	Users          []UserConfig `yaml:"users"`
+}
+
+type UserConfig struct {
+	Name     string `yaml:"name"`
+	Password string `yaml:"password"`
+	// Administrator, Operator, or ReadOnly
+	Role string `yaml:"role"`
+}
+
+type Command struct {
+	g    *goes.Goes
+	done chan struct{}
+}
+
+func (*Command) String() string { return "redfishd" }
+
+func (*Command) Usage() string { return "redfishd [-config FILE]" }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "redfish server",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Serve the DMTF Redfish API at /redfish/v1/:
+
+	Chassis/1/Thermal	temperature (*.units.C) and fan (*.units.rpm)
+				fields of the machine's redis hash
+	Chassis/1/Power		voltage (*.units.V) and power supply (psuN.*)
+				fields
+	Managers/bmc		Manager.Reset runs "reboot"
+	Systems/1		ComputerSystem.Reset sets host.reset
+	UpdateService		SimpleUpdate of ImageURI, e.g.
+				http://SERVER[/dir]/VERSION, runs "upgrade"
+	SessionService		POST Sessions with UserName and Password
+				returns the X-Auth-Token of the session
+
+	Other than the service root, requests are authorized with basic
+	authentication or a session token.  ReadOnly users may only GET.
+
+	The service is HTTPS.  Without a configured cert and key, it uses a
+	self-signed ` + DefaultCertFile + ` and ` + DefaultKeyFile + `,
+	generated if they don't exist.
+
+OPTIONS
+	-config FILE
+		YAML users and listener; default: ` + DefaultConfigFile + `
+
+CONFIG
+	listen: :443
+	cert: /etc/goes/redfishd.crt
+	key: /etc/goes/redfishd.key
+	sessionTimeout: 1800
+	users:
+	- name: admin
+	  password: secret
+	  role: Administrator	# Administrator, Operator, or ReadOnly
+
+	Since FILE has passwords, it shouldn't be readable by others.`,
+	}
+}
+
+func (c *Command) Close() error {
+	close(c.done)
+	return nil
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.Daemon }
+
+func (c *Command) Main(args ...string) error {
+	c.done = make(chan struct{})
+	parm, args := parms.New(args, "-config")
+	if len(args) > 0 {
+		return fmt.Errorf("%v: unexpected", args)
+	}
+	if len(parm.ByName["-config"]) == 0 {
+		parm.ByName["-config"] = DefaultConfigFile
+	}
+	cfg, err := LoadConfig(parm.ByName["-config"])
+	if err != nil {
+		return err
+	}
+	if len(cfg.Cert) == 0 {
+		cfg.Cert, cfg.Key = DefaultCertFile, DefaultKeyFile
+		if err = selfSigned(cfg.Cert, cfg.Key); err != nil {
+			return err
+		}
+	}
+	s, err := newServer(cfg, &redisPlatform{c.g})
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Addr:    cfg.Listen,
+		Handler: s,
+	}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.ListenAndServeTLS(cfg.Cert, cfg.Key)
+	}()
+	select {
+	case err = <-errs:
+		return err
+	case <-c.done:
+		ctx, cancel := context.WithTimeout(context.Background(),
+			5*time.Second)
+		defer cancel()
+		return srv.Shutdown(ctx)
+	}
+}
+
+// LoadConfig reads the named config file, if it exists, and sets the
+// defaults.
+func LoadConfig(fn string) (*Config, error) {
+	b, err := ioutil.ReadFile(fn)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if fi, err := os.Stat(fn); err == nil && fi.Mode().Perm()&077 != 0 {
+		fmt.Println("warning:", fn, "with passwords is accessible to others")
+	}
+	cfg, err := ParseConfig(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fn, err)
+	}
+	if len(cfg.Users) == 0 {
+		fmt.Println("warning:", fn, "has no users")
+	}
+	return cfg, nil
+}
+
+// ParseConfig validates the YAML config and sets its defaults.
+func ParseConfig(b []byte) (*Config, error) {
+	cfg := new(Config)
+	if err := yaml.UnmarshalStrict(b, cfg); err != nil {
+		return nil, err
+	}
+	if (len(cfg.Cert) > 0) != (len(cfg.Key) > 0) {
+		return nil, fmt.Errorf("need both cert and key")
+	}
+	if len(cfg.Listen) == 0 {
+		cfg.Listen = ":443"
+	}
+	if cfg.SessionTimeout == 0 {
+		cfg.SessionTimeout = 1800
+	}
+	for _, u := range cfg.Users {
+		if len(u.Name) == 0 {
+			return nil, fmt.Errorf("users: missing name")
+		}
+		if _, found := privileges[u.Role]; !found {
+			return nil, fmt.Errorf("users: %s: role: %q: invalid",
+				u.Name, u.Role)
+		}
+	}
+	return cfg, nil
+}
+
+// Generate a self-signed certificate and key in the given files unless the
+// certificate exists.
+func selfSigned(certfn, keyfn string) error {
+	if _, err := os.Stat(certfn); err == nil {
+		return nil
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+	hostname, _ := os.Hostname()
+	if len(hostname) == 0 {
+		hostname = machine.Name
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: hostname},
+		DNSNames:              []string{hostname},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl,
+		&key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	for _, x := range []struct {
+		fn   string
+		t    string
+		b    []byte
+		mode os.FileMode
+	}{
+		{keyfn, "EC PRIVATE KEY", der, 0600},
+		{certfn, "CERTIFICATE", cert, 0644},
+	} {
+		if err = os.MkdirAll(filepath.Dir(x.fn), 0755); err != nil {
+			return err
+		}
+		b := pem.EncodeToMemory(&pem.Block{Type: x.t, Bytes: x.b})
+		if err = ioutil.WriteFile(x.fn, b, x.mode); err != nil {
+			return err
+		}
+	}
+	fmt.Println("generated", certfn)
+	return nil
+}
+
+// The machine's redis hash and goes commands.
+type platform interface {
+	Hgetall() (map[string]string, error)
+	Hset(field, value string) error
+	// Run the goes command in a child process.
+	Run(args ...string) error
+}
+
+type redisPlatform struct {
+	g *goes.Goes
+}
+
+func (*redisPlatform) Hgetall() (map[string]string, error) {
+	return redis.Hgetall(machine.Name)
+}
+
+func (*redisPlatform) Hset(field, value string) error {
+	_, err := redis.Hset(machine.Name, field, value)
+	return err
+}
+
+func (p *redisPlatform) Run(args ...string) error {
+	x := p.g.Fork(args...)
+	x.Stdout = os.Stdout
+	x.Stderr = os.Stderr
+	x.Dir = "/"
+	return x.Run()
+}
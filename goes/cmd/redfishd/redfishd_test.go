@@ -0,0 +1,343 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package redfishd
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A fake of the redis hash and goes commands.
+type fakePlatform struct {
+	mutex sync.Mutex
+	hash  map[string]string
+	runs  [][]string
+}
+
+func (p *fakePlatform) Hgetall() (map[string]string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	h := make(map[string]string)
+	for k, v := range p.hash {
+		h[k] = v
+	}
+	return h, nil
+}
+
+func (p *fakePlatform) Hset(field, value string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.hash[field] = value
+	return nil
+}
+
+func (p *fakePlatform) Run(args ...string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.runs = append(p.runs, args)
+	return nil
+}
+
+func (p *fakePlatform) ran() [][]string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.runs
+}
+
+const testConfig = `
+sessionTimeout: 60
+users:
+- name: admin
+  password: secret
+  role: Administrator
+- name: guest
+  password: guest
+  role: ReadOnly
+`
+
+type client struct {
+	t   *testing.T
+	url string
+}
+
+// Returns the status code, headers, and decoded body of the request.
+func (c *client) do(method, path, user, token, body string) (int,
+	http.Header, map[string]interface{}) {
+	req, err := http.NewRequest(method, c.url+path, strings.NewReader(body))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if len(user) > 0 {
+		req.SetBasicAuth(user, map[string]string{
+			"admin": "secret",
+			"guest": "guest",
+		}[user])
+	}
+	if len(token) > 0 {
+		req.Header.Set("X-Auth-Token", token)
+	}
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+	v := make(map[string]interface{})
+	json.NewDecoder(rsp.Body).Decode(&v)
+	return rsp.StatusCode, rsp.Header, v
+}
+
+func newTest(t *testing.T) (*client, *fakePlatform, func()) {
+	cfg, err := ParseConfig([]byte(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakePlatform{
+		hash: map[string]string{
+			"eeprom.ProductName":           "PS-3001-32C",
+			"eeprom.SerialNumber":          "1234",
+			"sys.cpu.temp.units.C":         "44.5",
+			"fan_tray.1.1.speed.units.rpm": "7000",
+			"fan_tray.1.status":            "ok.front->back",
+			"fan_tray.2.1.speed.units.rpm": "0",
+			"fan_tray.2.status":            "not installed",
+			"vmon.5v.sb.units.V":           "5.02",
+			"psu1.status":                  "powered_on",
+			"psu1.mfg_model":               "DPS-750",
+			"psu1.p_out.units.W":           "210",
+			"psu1.v_in.units.V":            "230",
+			"psu2.status":                  "not_installed",
+		},
+	}
+	s, err := newServer(cfg, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	return &client{t, ts.URL}, p, ts.Close
+}
+
+func TestRedfish(t *testing.T) {
+	c, _, done := newTest(t)
+	defer done()
+
+	code, _, v := c.do("GET", "/redfish/v1/", "", "", "")
+	if code != http.StatusOK || v["RedfishVersion"] != version {
+		t.Errorf("service root: %d %v", code, v)
+	}
+	if code, h, _ := c.do("GET", "/redfish/v1/Chassis", "", "", ""); code !=
+		http.StatusUnauthorized || len(h.Get("WWW-Authenticate")) == 0 {
+		t.Errorf("unauthorized: %d", code)
+	}
+	code, _, v = c.do("GET", "/redfish/v1/Chassis/1", "guest", "", "")
+	if code != http.StatusOK || v["Model"] != "PS-3001-32C" {
+		t.Errorf("chassis: %d %v", code, v)
+	}
+
+	code, _, v = c.do("GET", "/redfish/v1/Chassis/1/Thermal", "guest", "",
+		"")
+	temps, _ := v["Temperatures"].([]interface{})
+	fans, _ := v["Fans"].([]interface{})
+	if code != http.StatusOK || len(temps) != 1 || len(fans) != 2 {
+		t.Fatalf("thermal: %d %v", code, v)
+	}
+	if temp := temps[0].(map[string]interface{}); temp["Name"] !=
+		"sys.cpu.temp" || temp["ReadingCelsius"] != 44.5 {
+		t.Errorf("temperature: %v", temp)
+	}
+	want := map[string]interface{}{"State": "Absent", "Health": "OK"}
+	if fan := fans[1].(map[string]interface{}); !reflect.DeepEqual(
+		fan["Status"], want) {
+		t.Errorf("fan: %v", fan)
+	}
+
+	code, _, v = c.do("GET", "/redfish/v1/Chassis/1/Power", "guest", "", "")
+	volts, _ := v["Voltages"].([]interface{})
+	psus, _ := v["PowerSupplies"].([]interface{})
+	if code != http.StatusOK || len(volts) != 1 || len(psus) != 2 {
+		t.Fatalf("power: %d %v", code, v)
+	}
+	if psu := psus[0].(map[string]interface{}); psu["Model"] != "DPS-750" ||
+		psu["PowerOutputWatts"] != 210.0 ||
+		psu["LineInputVoltage"] != 230.0 {
+		t.Errorf("psu1: %v", psu)
+	}
+	if psu := psus[1].(map[string]interface{}); !reflect.DeepEqual(
+		psu["Status"], want) {
+		t.Errorf("psu2: %v", psu)
+	}
+
+	if code, _, _ = c.do("GET", "/redfish/v1/Nowhere", "guest", "",
+		""); code != http.StatusNotFound {
+		t.Errorf("not found: %d", code)
+	}
+	if code, _, _ = c.do("DELETE", "/redfish/v1/Chassis/1", "admin", "",
+		""); code != http.StatusMethodNotAllowed {
+		t.Errorf("not allowed: %d", code)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	c, _, done := newTest(t)
+	defer done()
+
+	code, _, _ := c.do("POST", "/redfish/v1/SessionService/Sessions", "",
+		"", `{"UserName":"admin","Password":"wrong"}`)
+	if code != http.StatusUnauthorized {
+		t.Errorf("invalid login: %d", code)
+	}
+	code, h, v := c.do("POST", "/redfish/v1/SessionService/Sessions", "",
+		"", `{"UserName":"admin","Password":"secret"}`)
+	token := h.Get("X-Auth-Token")
+	location := h.Get("Location")
+	if code != http.StatusCreated || len(token) == 0 ||
+		location != "/redfish/v1/SessionService/Sessions/1" ||
+		v["UserName"] != "admin" {
+		t.Fatalf("login: %d %v %v", code, h, v)
+	}
+	code, _, v = c.do("GET", "/redfish/v1/SessionService/Sessions", "",
+		token, "")
+	if code != http.StatusOK || v["Members@odata.count"] != 1.0 {
+		t.Errorf("sessions: %d %v", code, v)
+	}
+	if code, _, _ = c.do("GET", "/redfish/v1/Systems/1", "", "bogus",
+		""); code != http.StatusUnauthorized {
+		t.Errorf("bogus token: %d", code)
+	}
+	if code, _, _ = c.do("DELETE", location, "", token, ""); code !=
+		http.StatusNoContent {
+		t.Errorf("logout: %d", code)
+	}
+	if code, _, _ = c.do("GET", "/redfish/v1/Systems/1", "", token,
+		""); code != http.StatusUnauthorized {
+		t.Errorf("deleted session: %d", code)
+	}
+}
+
+func TestActions(t *testing.T) {
+	c, p, done := newTest(t)
+	defer done()
+
+	reset := "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset"
+	if code, _, _ := c.do("POST", reset, "guest", "",
+		`{"ResetType":"ForceRestart"}`); code != http.StatusForbidden {
+		t.Errorf("read only reset: %d", code)
+	}
+	if code, _, _ := c.do("POST", reset, "admin", "",
+		`{"ResetType":"On"}`); code != http.StatusBadRequest {
+		t.Errorf("invalid reset: %d", code)
+	}
+	if code, _, _ := c.do("POST", reset, "admin", "",
+		`{"ResetType":"ForceRestart"}`); code != http.StatusNoContent {
+		t.Errorf("reset: %d", code)
+	}
+	if h, _ := p.Hgetall(); h["host.reset"] != "true" {
+		t.Error("host.reset wasn't set")
+	}
+
+	update := "/redfish/v1/UpdateService/Actions/UpdateService.SimpleUpdate"
+	if code, _, _ := c.do("POST", update, "admin", "",
+		`{"ImageURI":"ftp://example.com/20180601"}`); code !=
+		http.StatusBadRequest {
+		t.Errorf("invalid update: %d", code)
+	}
+	if code, _, _ := c.do("POST", update, "admin", "",
+		`{"ImageURI":"http://example.com/images/20180601"}`); code !=
+		http.StatusAccepted {
+		t.Errorf("update: %d", code)
+	}
+	code, _, _ := c.do("POST",
+		"/redfish/v1/Managers/bmc/Actions/Manager.Reset", "admin", "",
+		`{"ResetType":"GracefulRestart"}`)
+	if code != http.StatusNoContent {
+		t.Errorf("manager reset: %d", code)
+	}
+	for i := 0; i < 100 && len(p.ran()) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	runs := p.ran()
+	if len(runs) != 2 {
+		t.Fatalf("runs: %v", runs)
+	}
+	if runs[0][0] == "reboot" {
+		runs[0], runs[1] = runs[1], runs[0]
+	}
+	if !reflect.DeepEqual(runs, [][]string{
+		{"upgrade", "-v", "20180601", "-s", "example.com/images"},
+		{"reboot"},
+	}) {
+		t.Errorf("runs: %v", runs)
+	}
+}
+
+func TestUpgradeArgs(t *testing.T) {
+	for _, x := range []struct {
+		uri, protocol string
+		want          []string
+	}{
+		{"http://example.com", "",
+			[]string{"upgrade", "-s", "example.com"}},
+		{"http://example.com/LATEST/", "",
+			[]string{"upgrade", "-v", "LATEST", "-s", "example.com"}},
+		{"tftp://10.0.0.1/goes/20180601", "",
+			[]string{"upgrade", "-t", "-v", "20180601", "-s",
+				"10.0.0.1/goes"}},
+		{"http://example.com/goes", "TFTP",
+			[]string{"upgrade", "-t", "-s", "example.com/goes"}},
+		{"example.com", "", nil},
+	} {
+		got, err := upgradeArgs(x.uri, x.protocol)
+		if x.want == nil {
+			if err == nil {
+				t.Errorf("%s: %v", x.uri, got)
+			}
+		} else if !reflect.DeepEqual(got, x.want) {
+			t.Errorf("%s: %v %v", x.uri, got, err)
+		}
+	}
+}
+
+func TestSelfSigned(t *testing.T) {
+	dir, err := ioutil.TempDir("", "redfishd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certfn := filepath.Join(dir, "etc/redfishd.crt")
+	keyfn := filepath.Join(dir, "etc/redfishd.key")
+	if err = selfSigned(certfn, keyfn); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.LoadX509KeyPair(certfn, keyfn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(keyfn); err != nil || fi.Mode().Perm() != 0600 {
+		t.Error("key mode:", fi.Mode())
+	}
+	// an existing certificate is kept
+	if err = selfSigned(certfn, keyfn); err != nil {
+		t.Fatal(err)
+	}
+	again, err := tls.LoadX509KeyPair(certfn, keyfn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cert.Certificate, again.Certificate) {
+		t.Error("regenerated")
+	}
+	if cfg, err := ParseConfig(nil); err != nil || cfg.Listen != ":443" {
+		t.Error(cfg, err)
+	}
+}
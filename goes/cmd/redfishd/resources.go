@@ -0,0 +1,692 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package redfishd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	root    = "/redfish/v1/"
+	version = "1.4.0"
+)
+
+type object map[string]interface{}
+
+type server struct {
+	auth     *auth
+	platform platform
+	mutex    sync.Mutex
+	// of the last SimpleUpdate
+	updating  bool
+	updateErr error
+}
+
+func newServer(cfg *Config, p platform) (*server, error) {
+	return &server{
+		auth:     newAuth(cfg),
+		platform: p,
+	}, nil
+}
+
+func link(path string) object { return object{"@odata.id": root + path} }
+
+func links(paths ...string) []object {
+	l := make([]object, 0, len(paths))
+	for _, path := range paths {
+		l = append(l, link(path))
+	}
+	return l
+}
+
+func resource(path, odataType, id, name string) object {
+	return object{
+		"@odata.id":   root + path,
+		"@odata.type": "#" + odataType,
+		"Id":          id,
+		"Name":        name,
+	}
+}
+
+func collection(path, odataType, name string, members ...string) object {
+	return object{
+		"@odata.id":           root + path,
+		"@odata.type":         "#" + odataType,
+		"Name":                name,
+		"Members":             links(members...),
+		"Members@odata.count": len(members),
+	}
+}
+
+func status(state, health string) object {
+	return object{"State": state, "Health": health}
+}
+
+func action(path, name string, values ...string) object {
+	return object{
+		"#" + name: object{
+			"target":                            root + path + "/Actions/" + name,
+			"ResetType@Redfish.AllowableValues": values,
+		},
+	}
+}
+
+// Write the Redfish error response of the HTTP status.
+func fail(w http.ResponseWriter, code int, message string) {
+	reason := map[int]string{
+		http.StatusBadRequest:       "Base.1.0.PropertyValueNotInList",
+		http.StatusUnauthorized:     "Base.1.0.NoValidSession",
+		http.StatusForbidden:        "Base.1.0.InsufficientPrivilege",
+		http.StatusNotFound:         "Base.1.0.ResourceMissingAtURI",
+		http.StatusMethodNotAllowed: "Base.1.0.ActionNotSupported",
+		http.StatusConflict:         "Base.1.0.ResourceInUse",
+	}[code]
+	if len(reason) == 0 {
+		reason = "Base.1.0.GeneralError"
+	}
+	if code == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="redfish"`)
+	}
+	reply(w, code, object{
+		"error": object{
+			"code":    reason,
+			"message": message,
+		},
+	})
+}
+
+func reply(w http.ResponseWriter, code int, v object) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("OData-Version", "4.0")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case path == "/redfish":
+		reply(w, http.StatusOK, object{"v1": root})
+		return
+	case path+"/" == root:
+		s.serviceRoot(w, r)
+		return
+	case !strings.HasPrefix(path, root):
+		fail(w, http.StatusNotFound, r.URL.Path)
+		return
+	}
+	path = strings.TrimPrefix(path, root)
+	if path == "SessionService/Sessions" && r.Method == http.MethodPost {
+		s.login(w, r)
+		return
+	}
+	u := s.auth.user(r)
+	if u == nil {
+		fail(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead &&
+		privileges[u.role]&configureComponents == 0 {
+		fail(w, http.StatusForbidden, u.name+" is "+u.role)
+		return
+	}
+	for _, route := range routes {
+		m := route.re.FindStringSubmatch(path)
+		if m == nil {
+			continue
+		}
+		f, found := route.methods[r.Method]
+		if !found && r.Method == http.MethodHead {
+			f, found = route.methods[http.MethodGet]
+		}
+		if !found {
+			fail(w, http.StatusMethodNotAllowed, r.Method+" "+path)
+			return
+		}
+		f(s, w, r, u, m[1:])
+		return
+	}
+	fail(w, http.StatusNotFound, r.URL.Path)
+}
+
+type handler func(s *server, w http.ResponseWriter, r *http.Request,
+	u *user, args []string)
+
+var routes = []struct {
+	re      *regexp.Regexp
+	methods map[string]handler
+}{
+	{regexp.MustCompile(`^Chassis$`), map[string]handler{
+		http.MethodGet: (*server).chassisCollection,
+	}},
+	{regexp.MustCompile(`^Chassis/1$`), map[string]handler{
+		http.MethodGet: (*server).chassis,
+	}},
+	{regexp.MustCompile(`^Chassis/1/Thermal$`), map[string]handler{
+		http.MethodGet: (*server).thermal,
+	}},
+	{regexp.MustCompile(`^Chassis/1/Power$`), map[string]handler{
+		http.MethodGet: (*server).power,
+	}},
+	{regexp.MustCompile(`^Managers$`), map[string]handler{
+		http.MethodGet: (*server).managerCollection,
+	}},
+	{regexp.MustCompile(`^Managers/bmc$`), map[string]handler{
+		http.MethodGet: (*server).manager,
+	}},
+	{regexp.MustCompile(`^Managers/bmc/Actions/Manager\.Reset$`),
+		map[string]handler{
+			http.MethodPost: (*server).managerReset,
+		}},
+	{regexp.MustCompile(`^Systems$`), map[string]handler{
+		http.MethodGet: (*server).systemCollection,
+	}},
+	{regexp.MustCompile(`^Systems/1$`), map[string]handler{
+		http.MethodGet: (*server).system,
+	}},
+	{regexp.MustCompile(`^Systems/1/Actions/ComputerSystem\.Reset$`),
+		map[string]handler{
+			http.MethodPost: (*server).systemReset,
+		}},
+	{regexp.MustCompile(`^UpdateService$`), map[string]handler{
+		http.MethodGet: (*server).updateService,
+	}},
+	{regexp.MustCompile(
+		`^UpdateService/Actions/UpdateService\.SimpleUpdate$`),
+		map[string]handler{
+			http.MethodPost: (*server).simpleUpdate,
+		}},
+	{regexp.MustCompile(`^SessionService$`), map[string]handler{
+		http.MethodGet: (*server).sessionService,
+	}},
+	{regexp.MustCompile(`^SessionService/Sessions$`), map[string]handler{
+		http.MethodGet: (*server).sessionCollection,
+	}},
+	{regexp.MustCompile(`^SessionService/Sessions/(\d+)$`),
+		map[string]handler{
+			http.MethodGet:    (*server).session,
+			http.MethodDelete: (*server).logout,
+		}},
+}
+
+func (s *server) serviceRoot(w http.ResponseWriter, r *http.Request) {
+	v := resource("", "ServiceRoot.v1_2_0.ServiceRoot", "RootService",
+		"Root Service")
+	v["RedfishVersion"] = version
+	v["Chassis"] = link("Chassis")
+	v["Managers"] = link("Managers")
+	v["Systems"] = link("Systems")
+	v["SessionService"] = link("SessionService")
+	v["UpdateService"] = link("UpdateService")
+	v["Links"] = object{"Sessions": link("SessionService/Sessions")}
+	reply(w, http.StatusOK, v)
+}
+
+// Returns the machine's redis hash or, after replying with the error, nil.
+func (s *server) hash(w http.ResponseWriter) map[string]string {
+	h, err := s.platform.Hgetall()
+	if err != nil {
+		fail(w, http.StatusInternalServerError, err.Error())
+		return nil
+	}
+	return h
+}
+
+// Returns the sorted fields with the suffix.
+func fields(h map[string]string, suffix string) []string {
+	var keys []string
+	for k := range h {
+		if strings.HasSuffix(k, suffix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func reading(s string) interface{} {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil
+	}
+	return f
+}
+
+func (s *server) chassisCollection(w http.ResponseWriter, r *http.Request,
+	u *user, args []string) {
+	reply(w, http.StatusOK, collection("Chassis",
+		"ChassisCollection.ChassisCollection", "Chassis Collection",
+		"Chassis/1"))
+}
+
+func (s *server) chassis(w http.ResponseWriter, r *http.Request,
+	u *user, args []string) {
+	h := s.hash(w)
+	if h == nil {
+		return
+	}
+	v := resource("Chassis/1", "Chassis.v1_5_0.Chassis", "1", "Chassis")
+	v["ChassisType"] = "RackMount"
+	v["Manufacturer"] = h["eeprom.Manufacturer"]
+	v["Model"] = h["eeprom.ProductName"]
+	v["PartNumber"] = h["eeprom.PartNumber"]
+	v["SerialNumber"] = h["eeprom.SerialNumber"]
+	v["Status"] = status("Enabled", "OK")
+	v["Thermal"] = link("Chassis/1/Thermal")
+	v["Power"] = link("Chassis/1/Power")
+	v["Links"] = object{
+		"ComputerSystems": links("Systems/1"),
+		"ManagedBy":       links("Managers/bmc"),
+	}
+	reply(w, http.StatusOK, v)
+}
+
+// Temperatures are *.units.C fields and fans *.units.rpm fields; the
+// fans of fan_tray.N are unavailable if the tray's status is "not
+// installed" and warn with low rpm.
+func (s *server) thermal(w http.ResponseWriter, r *http.Request,
+	u *user, args []string) {
+	h := s.hash(w)
+	if h == nil {
+		return
+	}
+	v := resource("Chassis/1/Thermal", "Thermal.v1_4_0.Thermal",
+		"Thermal", "Thermal")
+	temperatures := []object{}
+	for i, k := range fields(h, ".units.C") {
+		if strings.Contains(k, ".target.") {
+			continue
+		}
+		temperatures = append(temperatures, object{
+			"@odata.id": fmt.Sprint(root,
+				"Chassis/1/Thermal#/Temperatures/", i),
+			"MemberId":       strconv.Itoa(i),
+			"Name":           strings.TrimSuffix(k, ".units.C"),
+			"ReadingCelsius": reading(h[k]),
+			"Status":         status("Enabled", "OK"),
+		})
+	}
+	fans := []object{}
+	for i, k := range fields(h, ".units.rpm") {
+		st := status("Enabled", "OK")
+		if strings.HasPrefix(k, "fan_tray.") {
+			tray := strings.SplitN(k, ".", 3)[:2]
+			traySt := h[strings.Join(tray, ".")+".status"]
+			if traySt == "not installed" {
+				st = status("Absent", "OK")
+			} else if strings.HasPrefix(traySt, "warning") {
+				st = status("Enabled", "Warning")
+			}
+		}
+		fans = append(fans, object{
+			"@odata.id": fmt.Sprint(root, "Chassis/1/Thermal#/Fans/",
+				i),
+			"MemberId":     strconv.Itoa(i),
+			"Name":         strings.TrimSuffix(k, ".units.rpm"),
+			"Reading":      reading(h[k]),
+			"ReadingUnits": "RPM",
+			"Status":       st,
+		})
+	}
+	v["Temperatures"] = temperatures
+	v["Fans"] = fans
+	reply(w, http.StatusOK, v)
+}
+
+// Voltages are *.units.V fields other than those of the power supplies,
+// psuN.*.
+func (s *server) power(w http.ResponseWriter, r *http.Request,
+	u *user, args []string) {
+	h := s.hash(w)
+	if h == nil {
+		return
+	}
+	psu := regexp.MustCompile(`^psu(\d+)\.status$`)
+	v := resource("Chassis/1/Power", "Power.v1_5_0.Power", "Power",
+		"Power")
+	voltages := []object{}
+	for _, k := range fields(h, ".units.V") {
+		if strings.HasPrefix(k, "psu") {
+			continue
+		}
+		i := len(voltages)
+		voltages = append(voltages, object{
+			"@odata.id": fmt.Sprint(root,
+				"Chassis/1/Power#/Voltages/", i),
+			"MemberId":     strconv.Itoa(i),
+			"Name":         strings.TrimSuffix(k, ".units.V"),
+			"ReadingVolts": reading(h[k]),
+			"Status":       status("Enabled", "OK"),
+		})
+	}
+	supplies := []object{}
+	for _, k := range fields(h, ".status") {
+		m := psu.FindStringSubmatch(k)
+		if m == nil {
+			continue
+		}
+		p := "psu" + m[1] + "."
+		var st object
+		switch h[k] {
+		case "powered_on":
+			st = status("Enabled", "OK")
+		case "powered_off":
+			st = status("Enabled", "Critical")
+		case "not_installed", "not_found":
+			st = status("Absent", "OK")
+		default:
+			st = status("Enabled", "Warning")
+		}
+		i := len(supplies)
+		supplies = append(supplies, object{
+			"@odata.id": fmt.Sprint(root,
+				"Chassis/1/Power#/PowerSupplies/", i),
+			"MemberId":             strconv.Itoa(i),
+			"Name":                 "PSU" + m[1],
+			"Manufacturer":         h[p+"mfg_id"],
+			"Model":                h[p+"mfg_model"],
+			"SerialNumber":         h[p+"sn"],
+			"LineInputVoltage":     reading(h[p+"v_in.units.V"]),
+			"PowerInputWatts":      reading(h[p+"p_in.units.W"]),
+			"PowerOutputWatts":     reading(h[p+"p_out.units.W"]),
+			"LastPowerOutputWatts": reading(h[p+"p_out.units.W"]),
+			"Status":               st,
+		})
+	}
+	v["Voltages"] = voltages
+	v["PowerSupplies"] = supplies
+	reply(w, http.StatusOK, v)
+}
+
+func (s *server) managerCollection(w http.ResponseWriter, r *http.Request,
+	u *user, args []string) {
+	reply(w, http.StatusOK, collection("Managers",
+		"ManagerCollection.ManagerCollection", "Manager Collection",
+		"Managers/bmc"))
+}
+
+func (s *server) manager(w http.ResponseWriter, r *http.Request,
+	u *user, args []string) {
+	v := resource("Managers/bmc", "Manager.v1_3_0.Manager", "bmc",
+		"Manager")
+	v["ManagerType"] = "BMC"
+	v["Status"] = status("Enabled", "OK")
+	v["Actions"] = action("Managers/bmc", "Manager.Reset",
+		"GracefulRestart", "ForceRestart")
+	v["Links"] = object{
+		"ManagerForChassis": links("Chassis/1"),
+		"ManagerForServers": links("Systems/1"),
+	}
+	reply(w, http.StatusOK, v)
+}
+
+// Decode the POST body or, after replying with the error, return false.
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		fail(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	return true
+}
+
+func resetType(w http.ResponseWriter, r *http.Request,
+	values ...string) bool {
+	var body struct{ ResetType string }
+	if !decode(w, r, &body) {
+		return false
+	}
+	for _, v := range values {
+		if body.ResetType == v {
+			return true
+		}
+	}
+	fail(w, http.StatusBadRequest, fmt.Sprint("ResetType: ",
+		body.ResetType, ": not in ", values))
+	return false
+}
+
+// The BMC is rebooted after the response.
+func (s *server) managerReset(w http.ResponseWriter, r *http.Request,
+	u *user, args []string) {
+	if privileges[u.role]&configureManager == 0 {
+		fail(w, http.StatusForbidden, u.name+" is "+u.role)
+		return
+	}
+	if !resetType(w, r, "GracefulRestart", "ForceRestart") {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+	go func() {
+		if err := s.platform.Run("reboot"); err != nil {
+			fmt.Println("reboot:", err)
+		}
+	}()
+}
+
+func (s *server) systemCollection(w http.ResponseWriter, r *http.Request,
+	u *user, args []string) {
+	reply(w, http.StatusOK, collection("Systems",
+		"ComputerSystemCollection.ComputerSystemCollection",
+		"Computer System Collection", "Systems/1"))
+}
+
+func (s *server) system(w http.ResponseWriter, r *http.Request,
+	u *user, args []string) {
+	h := s.hash(w)
+	if h == nil {
+		return
+	}
+	v := resource("Systems/1", "ComputerSystem.v1_4_0.ComputerSystem",
+		"1", "Host")
+	v["SystemType"] = "Physical"
+	v["Manufacturer"] = h["eeprom.Manufacturer"]
+	v["Model"] = h["eeprom.ProductName"]
+	v["SerialNumber"] = h["eeprom.SerialNumber"]
+	v["Status"] = status("Enabled", "OK")
+	v["Actions"] = action("Systems/1", "ComputerSystem.Reset",
+		"ForceRestart")
+	v["Links"] = object{
+		"Chassis":   links("Chassis/1"),
+		"ManagedBy": links("Managers/bmc"),
+	}
+	reply(w, http.StatusOK, v)
+}
+
+// The host is reset like "hset platina host.reset true".
+func (s *server) systemReset(w http.ResponseWriter, r *http.Request,
+	u *user, args []string) {
+	if !resetType(w, r, "ForceRestart") {
+		return
+	}
+	if err := s.platform.Hset("host.reset", "true"); err != nil {
+		fail(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) updateService(w http.ResponseWriter, r *http.Request,
+	u *user, args []string) {
+	s.mutex.Lock()
+	st := status("Enabled", "OK")
+	if s.updating {
+		st = status("Updating", "OK")
+	} else if s.updateErr != nil {
+		st = status("Enabled", "Warning")
+	}
+	s.mutex.Unlock()
+	v := resource("UpdateService", "UpdateService.v1_2_0.UpdateService",
+		"UpdateService", "Update Service")
+	v["ServiceEnabled"] = true
+	v["Status"] = st
+	v["Actions"] = object{
+		"#UpdateService.SimpleUpdate": object{
+			"target": root +
+				"UpdateService/Actions/UpdateService.SimpleUpdate",
+			"TransferProtocol@Redfish.AllowableValues": []string{
+				"HTTP", "TFTP",
+			},
+		},
+	}
+	reply(w, http.StatusOK, v)
+}
+
+// Returns the upgrade command of the image URI, scheme://SERVER[/dir]/VER
+// where VER is YYYYMMDD or LATEST, or scheme://SERVER[/dir] of the latest.
+func upgradeArgs(imageURI, protocol string) ([]string, error) {
+	uri, err := url.Parse(imageURI)
+	if err != nil || len(uri.Host) == 0 {
+		return nil, fmt.Errorf("ImageURI: %q: invalid", imageURI)
+	}
+	if len(protocol) == 0 {
+		protocol = strings.ToUpper(uri.Scheme)
+	}
+	args := []string{"upgrade"}
+	switch protocol {
+	case "HTTP":
+	case "TFTP":
+		args = append(args, "-t")
+	default:
+		return nil, fmt.Errorf("TransferProtocol: %q: unsupported",
+			protocol)
+	}
+	server := uri.Host + strings.TrimSuffix(uri.Path, "/")
+	if i := strings.LastIndex(server, "/"); i > 0 {
+		ver := server[i+1:]
+		if _, err := strconv.Atoi(ver); (err == nil && len(ver) == 8) ||
+			ver == "LATEST" {
+			server = server[:i]
+			args = append(args, "-v", ver)
+		}
+	}
+	return append(args, "-s", server), nil
+}
+
+func (s *server) simpleUpdate(w http.ResponseWriter, r *http.Request,
+	u *user, args []string) {
+	var body struct {
+		ImageURI         string
+		TransferProtocol string
+	}
+	if privileges[u.role]&configureManager == 0 {
+		fail(w, http.StatusForbidden, u.name+" is "+u.role)
+		return
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+	upgrade, err := upgradeArgs(body.ImageURI, body.TransferProtocol)
+	if err != nil {
+		fail(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.updating {
+		fail(w, http.StatusConflict, "update in progress")
+		return
+	}
+	s.updating = true
+	go func() {
+		err := s.platform.Run(upgrade...)
+		if err != nil {
+			fmt.Println(strings.Join(upgrade, " "), err)
+		}
+		s.mutex.Lock()
+		s.updating = false
+		s.updateErr = err
+		s.mutex.Unlock()
+	}()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *server) sessionService(w http.ResponseWriter, r *http.Request,
+	u *user, args []string) {
+	v := resource("SessionService",
+		"SessionService.v1_1_3.SessionService", "SessionService",
+		"Session Service")
+	v["ServiceEnabled"] = true
+	v["SessionTimeout"] = int(s.auth.timeout.Seconds())
+	v["Status"] = status("Enabled", "OK")
+	v["Sessions"] = link("SessionService/Sessions")
+	reply(w, http.StatusOK, v)
+}
+
+func (s *server) sessionCollection(w http.ResponseWriter, r *http.Request,
+	u *user, args []string) {
+	ids := s.auth.sessionIds()
+	sort.Slice(ids, func(i, j int) bool {
+		a, _ := strconv.Atoi(ids[i])
+		b, _ := strconv.Atoi(ids[j])
+		return a < b
+	})
+	for i, id := range ids {
+		ids[i] = "SessionService/Sessions/" + id
+	}
+	reply(w, http.StatusOK, collection("SessionService/Sessions",
+		"SessionCollection.SessionCollection", "Session Collection",
+		ids...))
+}
+
+func sessionResource(ses *session) object {
+	v := resource("SessionService/Sessions/"+ses.id,
+		"Session.v1_1_0.Session", ses.id, "User Session")
+	v["UserName"] = ses.user.name
+	return v
+}
+
+// Create a session without other authorization; reply with its token.
+func (s *server) login(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		UserName, Password string
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+	ses := s.auth.newSession(body.UserName, body.Password)
+	if ses == nil {
+		fail(w, http.StatusUnauthorized, "invalid UserName or Password")
+		return
+	}
+	w.Header().Set("X-Auth-Token", ses.token)
+	w.Header().Set("Location", root+"SessionService/Sessions/"+ses.id)
+	reply(w, http.StatusCreated, sessionResource(ses))
+}
+
+func (s *server) session(w http.ResponseWriter, r *http.Request,
+	u *user, args []string) {
+	ses := s.auth.session(args[0])
+	if ses == nil {
+		fail(w, http.StatusNotFound, r.URL.Path)
+		return
+	}
+	reply(w, http.StatusOK, sessionResource(ses))
+}
+
+// Users may delete their own sessions; administrators, any.
+func (s *server) logout(w http.ResponseWriter, r *http.Request,
+	u *user, args []string) {
+	ses := s.auth.session(args[0])
+	if ses == nil {
+		fail(w, http.StatusNotFound, r.URL.Path)
+		return
+	}
+	if ses.user != u && privileges[u.role]&configureManager == 0 {
+		fail(w, http.StatusForbidden, u.name+" is "+u.role)
+		return
+	}
+	s.auth.deleteSession(args[0])
+	w.WriteHeader(http.StatusNoContent)
+}
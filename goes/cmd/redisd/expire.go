@@ -0,0 +1,208 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package redisd
+
+import (
+	"fmt"
+	"time"
+)
+
+// Del removes the given lists, sorted sets and published hashes.  It refuses
+// to remove the machine's published hashes.
+func (redisd *Redisd) Del(keys ...string) (int, error) {
+	redisd.mutex.Lock()
+	defer redisd.mutex.Unlock()
+	for _, key := range keys {
+		if _, found := redisd.pinned[key]; found {
+			return 0, fmt.Errorf("ERR %s: can't delete", key)
+		}
+	}
+	redisd.expire()
+	n := 0
+	for _, key := range keys {
+		if redisd.del(key) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Expire sets the time to live, in seconds, of a list, sorted set or
+// published hash other than those of the machine.  A non-positive timeout
+// removes the key.
+func (redisd *Redisd) Expire(key string, seconds int) (int, error) {
+	redisd.mutex.Lock()
+	defer redisd.mutex.Unlock()
+	if _, found := redisd.pinned[key]; found {
+		return 0, fmt.Errorf("ERR %s: can't expire", key)
+	}
+	redisd.expire()
+	if !redisd.exists(key) {
+		return 0, nil
+	}
+	if seconds <= 0 {
+		redisd.del(key)
+	} else {
+		redisd.expires[key] = deadline(seconds)
+	}
+	return 1, nil
+}
+
+// Hexpire sets the time to live, in seconds, of the given fields of a
+// published hash; this returns the number of fields found.
+//
+//	HEXPIRE KEY SECONDS FIELD...
+func (redisd *Redisd) Hexpire(key string, seconds int, fields ...string) (int,
+	error) {
+	redisd.mutex.Lock()
+	defer redisd.mutex.Unlock()
+	redisd.expire()
+	hv, found := redisd.published[key]
+	if !found {
+		return 0, fmt.Errorf("%s: not found", key)
+	}
+	n := 0
+	for _, field := range fields {
+		if _, found := hv[field]; !found {
+			continue
+		}
+		n++
+		if seconds <= 0 {
+			redisd.hdel(key, field)
+			continue
+		}
+		fe, found := redisd.fieldExpires[key]
+		if !found {
+			fe = make(map[string]time.Time)
+			redisd.fieldExpires[key] = fe
+		}
+		fe[field] = deadline(seconds)
+	}
+	return n, nil
+}
+
+// Httl returns the remaining seconds to live of the published hash field,
+// -1 if it doesn't expire, or -2 if it doesn't exist.
+func (redisd *Redisd) Httl(key, field string) (int, error) {
+	redisd.mutex.Lock()
+	defer redisd.mutex.Unlock()
+	redisd.expire()
+	hv, found := redisd.published[key]
+	if !found {
+		return -2, nil
+	}
+	if _, found = hv[field]; !found {
+		return -2, nil
+	}
+	if t, found := redisd.fieldExpires[key][field]; found {
+		return remaining(t), nil
+	}
+	if t, found := redisd.expires[key]; found {
+		return remaining(t), nil
+	}
+	return -1, nil
+}
+
+// Persist removes the time to live of the given key and its hash fields.
+func (redisd *Redisd) Persist(key string) (int, error) {
+	redisd.mutex.Lock()
+	defer redisd.mutex.Unlock()
+	redisd.expire()
+	_, found := redisd.expires[key]
+	if _, hasFields := redisd.fieldExpires[key]; hasFields {
+		delete(redisd.fieldExpires, key)
+		found = true
+	}
+	if !found {
+		return 0, nil
+	}
+	delete(redisd.expires, key)
+	return 1, nil
+}
+
+// Ttl returns the remaining seconds to live of the given key, -1 if it
+// doesn't expire, or -2 if it doesn't exist.
+func (redisd *Redisd) Ttl(key string) (int, error) {
+	redisd.mutex.Lock()
+	defer redisd.mutex.Unlock()
+	redisd.expire()
+	if !redisd.exists(key) {
+		return -2, nil
+	}
+	if t, found := redisd.expires[key]; found {
+		return remaining(t), nil
+	}
+	return -1, nil
+}
+
+// Remove all keys and hash fields that have outlived their deadline.  Rather
+// than running a timer, this is called by each command that accesses the
+// stores.  The caller must hold the mutex.
+func (redisd *Redisd) expire() {
+	now := time.Now()
+	for key, t := range redisd.expires {
+		if now.After(t) {
+			redisd.del(key)
+		}
+	}
+	for key, fe := range redisd.fieldExpires {
+		for field, t := range fe {
+			if now.After(t) {
+				redisd.hdel(key, field)
+			}
+		}
+	}
+}
+
+// The caller must hold the mutex.
+func (redisd *Redisd) exists(key string) bool {
+	if _, found := redisd.published[key]; found {
+		return true
+	}
+	if _, found := redisd.lists[key]; found {
+		return true
+	}
+	_, found := redisd.zsets[key]
+	return found
+}
+
+// Remove the list, sorted set or published hash along with any deadlines.
+// The caller must hold the mutex.
+func (redisd *Redisd) del(key string) bool {
+	found := redisd.exists(key)
+	delete(redisd.published, key)
+	delete(redisd.lists, key)
+	delete(redisd.zsets, key)
+	delete(redisd.expires, key)
+	delete(redisd.fieldExpires, key)
+	if found {
+		redisd.flushKeyCache()
+		redisd.flushSubkeyCache(key)
+	}
+	return found
+}
+
+// Remove a published hash field and its deadline.  The caller must hold the
+// mutex.
+func (redisd *Redisd) hdel(key, field string) {
+	if hv, found := redisd.published[key]; found {
+		delete(hv, field)
+		redisd.flushSubkeyCache(key)
+	}
+	if fe, found := redisd.fieldExpires[key]; found {
+		delete(fe, field)
+		if len(fe) == 0 {
+			delete(redisd.fieldExpires, key)
+		}
+	}
+}
+
+func deadline(seconds int) time.Time {
+	return time.Now().Add(time.Duration(seconds) * time.Second)
+}
+
+func remaining(t time.Time) int {
+	return int((time.Until(t) + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,148 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package redisd
+
+import grs "github.com/platinasystems/go-redis-server"
+
+// Daemons may keep a bounded event history in a list like this:
+//
+//	LPUSH qsfp.events "port-3: inserted"
+//	LTRIM qsfp.events 0 99
+//	LRANGE qsfp.events 0 -1
+
+// Llen returns the length of the list or 0 if it doesn't exist.
+func (redisd *Redisd) Llen(key string) (int, error) {
+	redisd.mutex.Lock()
+	defer redisd.mutex.Unlock()
+	redisd.expire()
+	l, err := redisd.list(key)
+	return len(l), err
+}
+
+// Lpush inserts the values at the head of the list, creating it as needed;
+// this returns the resulting length.
+func (redisd *Redisd) Lpush(key string, values ...[]byte) (int, error) {
+	redisd.mutex.Lock()
+	defer redisd.mutex.Unlock()
+	redisd.expire()
+	l, err := redisd.list(key)
+	if err != nil {
+		return 0, err
+	}
+	nl := make([][]byte, 0, len(values)+len(l))
+	for i := len(values) - 1; i >= 0; i-- {
+		nl = append(nl, clone(values[i]))
+	}
+	nl = append(nl, l...)
+	redisd.setList(key, nl)
+	return len(nl), nil
+}
+
+// Lrange returns the list elements from start through stop inclusive.
+// Negative indices are offsets from the tail, so "0 -1" is the whole list.
+func (redisd *Redisd) Lrange(key string, start, stop int) ([][]byte, error) {
+	redisd.mutex.Lock()
+	defer redisd.mutex.Unlock()
+	redisd.expire()
+	l, err := redisd.list(key)
+	if err != nil {
+		return nil, err
+	}
+	start, stop = span(len(l), start, stop)
+	bs := make([][]byte, 0, stop-start)
+	bs = append(bs, l[start:stop]...)
+	return bs, nil
+}
+
+// Ltrim retains the list elements from start through stop inclusive,
+// removing the list if that leaves it empty.
+func (redisd *Redisd) Ltrim(key string, start, stop int) (*grs.StatusReply,
+	error) {
+	redisd.mutex.Lock()
+	defer redisd.mutex.Unlock()
+	redisd.expire()
+	l, err := redisd.list(key)
+	if err != nil {
+		return nil, err
+	}
+	start, stop = span(len(l), start, stop)
+	for i := range l[:start] {
+		l[i] = nil
+	}
+	for i := range l[stop:] {
+		l[stop+i] = nil
+	}
+	redisd.setList(key, l[start:stop])
+	return grs.NewStatusReply("OK"), nil
+}
+
+// Rpush appends the values to the tail of the list, creating it as needed;
+// this returns the resulting length.
+func (redisd *Redisd) Rpush(key string, values ...[]byte) (int, error) {
+	redisd.mutex.Lock()
+	defer redisd.mutex.Unlock()
+	redisd.expire()
+	l, err := redisd.list(key)
+	if err != nil {
+		return 0, err
+	}
+	for _, v := range values {
+		l = append(l, clone(v))
+	}
+	redisd.setList(key, l)
+	return len(l), nil
+}
+
+// Returns the named list, nil if it doesn't exist, or an error if the key
+// holds another type.  The caller must hold the mutex.
+func (redisd *Redisd) list(key string) ([][]byte, error) {
+	if l, found := redisd.lists[key]; found {
+		return l, nil
+	}
+	if redisd.exists(key) {
+		return nil, errWrongType
+	}
+	return nil, nil
+}
+
+// The caller must hold the mutex.
+func (redisd *Redisd) setList(key string, l [][]byte) {
+	if len(l) == 0 {
+		redisd.del(key)
+		return
+	}
+	if _, found := redisd.lists[key]; !found {
+		redisd.flushKeyCache()
+	}
+	redisd.lists[key] = l
+}
+
+// Convert redis style inclusive start and stop indices, that may be negative
+// offsets from the end, to a slice range of a sequence with n elements.
+func span(n, start, stop int) (int, int) {
+	if start < 0 {
+		start += n
+		if start < 0 {
+			start = 0
+		}
+	}
+	if stop < 0 {
+		stop += n
+	}
+	stop++
+	if stop > n {
+		stop = n
+	}
+	if start >= stop {
+		return 0, 0
+	}
+	return start, stop
+}
+
+func clone(b []byte) []byte {
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
+}
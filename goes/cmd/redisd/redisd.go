@@ -8,13 +8,16 @@ package redisd
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	info "github.com/platinasystems/go"
 	grs "github.com/platinasystems/go-redis-server"
@@ -148,11 +151,17 @@ func (c *Command) Main(args ...string) (err error) {
 	c.redisd.devs = make(map[string][]*grs.Server)
 	c.redisd.sub = make(map[string]*grs.MultiChannelWriter)
 	c.redisd.published = make(grs.HashHash)
+	c.redisd.lists = make(map[string][][]byte)
+	c.redisd.zsets = make(map[string]*zset)
+	c.redisd.expires = make(map[string]time.Time)
+	c.redisd.fieldExpires = make(map[string]map[string]time.Time)
 	if len(c.PublishedKeys) == 0 {
 		c.PublishedKeys = []string{machine.Name}
 	}
+	c.redisd.pinned = make(map[string]struct{})
 	for _, k := range c.PublishedKeys {
 		c.redisd.published[k] = make(grs.HashValue)
+		c.redisd.pinned[k] = struct{}{}
 	}
 
 	b, err := info.Marshal()
@@ -243,30 +252,7 @@ func (c *Command) gopub() {
 				hv[field] = hv[field][:0]
 			}
 			hv[field] = append(hv[field], value...)
-			if sub, found := c.redisd.sub[key]; found {
-				mb := make([]byte, len(fv))
-				copy(mb, fv)
-				msg := make([]interface{}, 3)
-				msg[0] = "message"
-				msg[1] = key
-				msg[2] = mb
-				for i := 0; i < len(sub.Chans); {
-					select {
-					case sub.Chans[i].Channel <- msg:
-						i++
-					default:
-						// cull this subscriber
-						close(sub.Chans[i].Channel)
-						n := len(sub.Chans) - 1
-						if i != n {
-							copy(sub.Chans[i:],
-								sub.Chans[i+1:])
-						}
-						sub.Chans[n] = nil
-						sub.Chans = sub.Chans[:n]
-					}
-				}
-			}
+			c.redisd.notify(key, fv)
 		}
 		c.redisd.flushSubkeyCache(key)
 		c.redisd.mutex.Unlock()
@@ -317,6 +303,9 @@ func (c *Command) pubinit(fieldEqValues ...string) error {
 	return pub.Error()
 }
 
+var errWrongType = errors.New(
+	"WRONGTYPE Operation against a key holding the wrong kind of value")
+
 type Redisd struct {
 	mutex sync.Mutex
 	devs  map[string][]*grs.Server
@@ -327,6 +316,14 @@ type Redisd struct {
 	assignments Assignments
 
 	published grs.HashHash
+	// The machine's published hashes outlive DEL and EXPIRE.
+	pinned map[string]struct{}
+
+	lists map[string][][]byte
+	zsets map[string]*zset
+
+	expires      map[string]time.Time
+	fieldExpires map[string]map[string]time.Time
 
 	cachedKeys    []string
 	cachedSubkeys map[string][]string
@@ -434,9 +431,40 @@ func (redisd *Redisd) flushSubkeyCache(key string) {
 	}
 }
 
+// Send "field: value" to the subscribers of key; culling those that aren't
+// keeping up.  The caller must hold the mutex.
+func (redisd *Redisd) notify(key string, fv []byte) {
+	sub, found := redisd.sub[key]
+	if !found {
+		return
+	}
+	mb := make([]byte, len(fv))
+	copy(mb, fv)
+	msg := make([]interface{}, 3)
+	msg[0] = "message"
+	msg[1] = key
+	msg[2] = mb
+	for i := 0; i < len(sub.Chans); {
+		select {
+		case sub.Chans[i].Channel <- msg:
+			i++
+		default:
+			// cull this subscriber
+			close(sub.Chans[i].Channel)
+			n := len(sub.Chans) - 1
+			if i != n {
+				copy(sub.Chans[i:], sub.Chans[i+1:])
+			}
+			sub.Chans[n] = nil
+			sub.Chans = sub.Chans[:n]
+		}
+	}
+}
+
 func (redisd *Redisd) Hexists(key, field string) (int, error) {
 	redisd.mutex.Lock()
 	defer redisd.mutex.Unlock()
+	redisd.expire()
 	hv, found := redisd.published[key]
 	if !found {
 		return 0, fmt.Errorf("%s: not found", key)
@@ -453,6 +481,7 @@ func (redisd *Redisd) Hget(key, field string) ([]byte, error) {
 
 	redisd.mutex.Lock()
 	defer redisd.mutex.Unlock()
+	redisd.expire()
 
 	hv, found := redisd.published[key]
 	if !found {
@@ -499,6 +528,7 @@ func (redisd *Redisd) Hgetall(key string) ([][]byte, error) {
 	var bs [][]byte
 	redisd.mutex.Lock()
 	defer redisd.mutex.Unlock()
+	redisd.expire()
 	hv, found := redisd.published[key]
 	if !found {
 		return bs, fmt.Errorf("%s: not found", key)
@@ -511,10 +541,64 @@ func (redisd *Redisd) Hgetall(key string) ([][]byte, error) {
 	return bs, nil
 }
 
+// Hincrby adds incr to the integer value of the published hash field,
+// creating the hash and field as needed, then notifies the key's subscribers
+// of the new value.  Like Hset, this passes the new value of a key or field
+// assigned to another daemon to that daemon to publish.
+func (redisd *Redisd) Hincrby(key, field string, incr int) (int, error) {
+	type t interface {
+		Hset(string, string, []byte) (int, error)
+	}
+	var hset func(string, string, []byte) (int, error)
+	hashkey := fmt.Sprint(key, ":", field)
+	redisd.mutex.Lock()
+	if method, found := redisd.assignments.Find(hashkey).(t); found {
+		hset = method.Hset
+	} else if method, found := redisd.assignments.Find(key).(t); found {
+		hset = method.Hset
+	}
+	redisd.expire()
+	if redisd.exists(key) {
+		if _, found := redisd.published[key]; !found {
+			redisd.mutex.Unlock()
+			return 0, errWrongType
+		}
+	}
+	i := 0
+	if b, found := redisd.published[key][field]; found {
+		var err error
+		if i, err = strconv.Atoi(string(b)); err != nil {
+			redisd.mutex.Unlock()
+			return 0, fmt.Errorf("%s: %s: not an integer", key, field)
+		}
+	}
+	i += incr
+	if hset != nil {
+		// Like Hset, the assigned daemon publishes the new value.
+		redisd.mutex.Unlock()
+		if _, err := hset(key, field, []byte(strconv.Itoa(i))); err != nil {
+			return 0, err
+		}
+		return i, nil
+	}
+	defer redisd.mutex.Unlock()
+	hv, found := redisd.published[key]
+	if !found {
+		hv = make(grs.HashValue)
+		redisd.published[key] = hv
+		redisd.flushKeyCache()
+	}
+	hv[field] = []byte(strconv.Itoa(i))
+	redisd.notify(key, []byte(fmt.Sprint(field, ": ", i)))
+	redisd.flushSubkeyCache(key)
+	return i, nil
+}
+
 func (redisd *Redisd) Hkeys(key string) ([][]byte, error) {
 	var bs [][]byte
 	redisd.mutex.Lock()
 	defer redisd.mutex.Unlock()
+	redisd.expire()
 	hv, found := redisd.published[key]
 	if !found {
 		return bs, fmt.Errorf("%s: not found", key)
@@ -587,6 +671,7 @@ func (redisd *Redisd) Keys(pattern string) ([][]byte, error) {
 func (redisd *Redisd) keys() []string {
 	redisd.mutex.Lock()
 	defer redisd.mutex.Unlock()
+	redisd.expire()
 	if len(redisd.cachedKeys) == 0 {
 		for _, a := range redisd.assignments {
 			k := a.prefix
@@ -598,6 +683,12 @@ func (redisd *Redisd) keys() []string {
 		for k := range redisd.published {
 			redisd.cachedKeys = append(redisd.cachedKeys, k)
 		}
+		for k := range redisd.lists {
+			redisd.cachedKeys = append(redisd.cachedKeys, k)
+		}
+		for k := range redisd.zsets {
+			redisd.cachedKeys = append(redisd.cachedKeys, k)
+		}
 		sort.Strings(redisd.cachedKeys)
 	}
 	return redisd.cachedKeys
@@ -612,6 +703,18 @@ func (redisd *Redisd) Ping() (*grs.StatusReply, error) {
 	return grs.NewStatusReply("PONG"), nil
 }
 
+// Publish sends the message to the subscribers of the channel, e.g.
+// qsfp.events, returning the number of subscribers.
+func (redisd *Redisd) Publish(channel string, msg []byte) (int, error) {
+	redisd.mutex.Lock()
+	defer redisd.mutex.Unlock()
+	redisd.notify(channel, msg)
+	if sub, found := redisd.sub[channel]; found {
+		return len(sub.Chans), nil
+	}
+	return 0, nil
+}
+
 func (redisd *Redisd) Subscribe(channels ...[]byte) (*grs.MultiChannelWriter,
 	error) {
 	mcw := &grs.MultiChannelWriter{
@@ -0,0 +1,287 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package redisd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	grs "github.com/platinasystems/go-redis-server"
+)
+
+const testMachine = "platina"
+
+func newTestRedisd() *Redisd {
+	redisd := &Redisd{
+		sub:          make(map[string]*grs.MultiChannelWriter),
+		published:    make(grs.HashHash),
+		pinned:       make(map[string]struct{}),
+		lists:        make(map[string][][]byte),
+		zsets:        make(map[string]*zset),
+		expires:      make(map[string]time.Time),
+		fieldExpires: make(map[string]map[string]time.Time),
+	}
+	redisd.published[testMachine] = grs.HashValue{
+		"hostname": []byte("test"),
+	}
+	redisd.pinned[testMachine] = struct{}{}
+	return redisd
+}
+
+func strs(bs [][]byte) string {
+	s := make([]string, len(bs))
+	for i, b := range bs {
+		s[i] = string(b)
+	}
+	return strings.Join(s, " ")
+}
+
+func bytess(ss ...string) [][]byte {
+	bs := make([][]byte, len(ss))
+	for i, s := range ss {
+		bs[i] = []byte(s)
+	}
+	return bs
+}
+
+func TestList(t *testing.T) {
+	redisd := newTestRedisd()
+	if n, err := redisd.Rpush("l", bytess("b", "c")...); err != nil ||
+		n != 2 {
+		t.Fatal("rpush:", n, err)
+	}
+	if n, err := redisd.Lpush("l", bytess("a", "z")...); err != nil ||
+		n != 4 {
+		t.Fatal("lpush:", n, err)
+	}
+	for _, x := range []struct {
+		start, stop int
+		want        string
+	}{
+		{0, -1, "z a b c"},
+		{1, 2, "a b"},
+		{-2, -1, "b c"},
+		{-100, 100, "z a b c"},
+		{3, 1, ""},
+		{5, 10, ""},
+	} {
+		bs, err := redisd.Lrange("l", x.start, x.stop)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strs(bs); got != x.want {
+			t.Errorf("lrange %d %d: got %q, want %q",
+				x.start, x.stop, got, x.want)
+		}
+	}
+	if _, err := redisd.Ltrim("l", 1, -2); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := redisd.Llen("l"); n != 2 {
+		t.Error("llen after ltrim:", n)
+	}
+	if _, err := redisd.Ltrim("l", 5, 10); err != nil {
+		t.Fatal(err)
+	}
+	if redisd.exists("l") {
+		t.Error("empty list wasn't removed")
+	}
+	if _, err := redisd.Lpush(testMachine, []byte("x")); err != errWrongType {
+		t.Error("lpush hash:", err)
+	}
+}
+
+func TestZset(t *testing.T) {
+	redisd := newTestRedisd()
+	n, err := redisd.Zadd("z", "3", "c", "1", "a", "2", "b", "1", "aa")
+	if err != nil || n != 4 {
+		t.Fatal("zadd:", n, err)
+	}
+	// a rescored member isn't new
+	if n, err = redisd.Zadd("z", "0.5", "c"); err != nil || n != 0 {
+		t.Fatal("zadd rescore:", n, err)
+	}
+	for _, x := range []struct {
+		reverse     bool
+		start, stop int
+		opts        []string
+		want        string
+	}{
+		{false, 0, -1, nil, "c a aa b"},
+		{true, 0, -1, nil, "b aa a c"},
+		{false, 0, 1, []string{"withscores"}, "c 0.5 a 1"},
+		{false, -1, -1, nil, "b"},
+	} {
+		bs, err := redisd.zrange(x.reverse, "z", x.start, x.stop,
+			x.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strs(bs); got != x.want {
+			t.Errorf("zrange %v %d %d: got %q, want %q",
+				x.reverse, x.start, x.stop, got, x.want)
+		}
+	}
+	if b, _ := redisd.Zscore("z", "c"); string(b) != "0.5" {
+		t.Errorf("zscore: %q", b)
+	}
+	if n, _ = redisd.Zrem("z", "a", "x"); n != 1 {
+		t.Error("zrem:", n)
+	}
+	if n, _ = redisd.Zcard("z"); n != 3 {
+		t.Error("zcard:", n)
+	}
+	if n, _ = redisd.Zrem("z", "aa", "b", "c"); n != 3 ||
+		redisd.exists("z") {
+		t.Error("empty set wasn't removed")
+	}
+	for _, score := range []string{"nan", "NaN", "-nan", "x", ""} {
+		if _, err = redisd.Zadd("z", score, "a"); err != errNotFloat {
+			t.Errorf("zadd %q: %v", score, err)
+		}
+	}
+	if redisd.exists("z") {
+		t.Error("invalid zadd created the set")
+	}
+	for _, score := range []string{"inf", "-inf", "1e300"} {
+		if _, err = redisd.Zadd("z", score, score); err != nil {
+			t.Errorf("zadd %q: %v", score, err)
+		}
+	}
+	if bs, _ := redisd.Zrange("z", 0, -1); strs(bs) != "-inf 1e300 inf" {
+		t.Errorf("zrange infinities: %q", strs(bs))
+	}
+}
+
+func TestExpire(t *testing.T) {
+	redisd := newTestRedisd()
+	redisd.Rpush("l", []byte("a"))
+	redisd.Zadd("z", "1", "a")
+	redisd.published["h"] = grs.HashValue{
+		"a": []byte("1"),
+		"b": []byte("2"),
+	}
+	if n, _ := redisd.Ttl("l"); n != -1 {
+		t.Error("ttl persistent:", n)
+	}
+	if n, _ := redisd.Ttl("x"); n != -2 {
+		t.Error("ttl missing:", n)
+	}
+	if n, _ := redisd.Expire("l", 100); n != 1 {
+		t.Error("expire:", n)
+	}
+	if n, _ := redisd.Ttl("l"); n != 100 {
+		t.Error("ttl:", n)
+	}
+	if n, _ := redisd.Persist("l"); n != 1 {
+		t.Error("persist:", n)
+	}
+	if n, _ := redisd.Ttl("l"); n != -1 {
+		t.Error("ttl after persist:", n)
+	}
+	if n, _ := redisd.Hexpire("h", 100, "a", "x"); n != 1 {
+		t.Error("hexpire:", n)
+	}
+	if n, _ := redisd.Httl("h", "a"); n != 100 {
+		t.Error("httl:", n)
+	}
+	if n, _ := redisd.Httl("h", "b"); n != -1 {
+		t.Error("httl persistent:", n)
+	}
+
+	// rather than wait, move the deadlines into the past
+	past := time.Now().Add(-time.Second)
+	redisd.expires["l"] = past
+	redisd.fieldExpires["h"]["a"] = past
+	if n, _ := redisd.Llen("l"); n != 0 || redisd.exists("l") {
+		t.Error("list didn't expire")
+	}
+	if b, _ := redisd.Hget("h", "b"); string(b) != "2" {
+		t.Errorf("hget unexpired field: %q", b)
+	}
+	if n, _ := redisd.Hexists("h", "a"); n != 0 {
+		t.Error("field didn't expire")
+	}
+
+	if n, _ := redisd.Expire("z", 0); n != 1 || redisd.exists("z") {
+		t.Error("expire 0 didn't remove set")
+	}
+	if n, _ := redisd.Del("h", "x"); n != 1 || redisd.exists("h") {
+		t.Error("del:", n)
+	}
+}
+
+func TestMachineKey(t *testing.T) {
+	redisd := newTestRedisd()
+	redisd.Rpush("l", []byte("a"))
+	if _, err := redisd.Del("l", testMachine); err == nil {
+		t.Error("del machine key")
+	}
+	if !redisd.exists("l") {
+		t.Error("refused del removed other keys")
+	}
+	if _, err := redisd.Expire(testMachine, 0); err == nil {
+		t.Error("expire machine key")
+	}
+	if _, err := redisd.Expire(testMachine, 10); err == nil {
+		t.Error("expire machine key")
+	}
+	if b, _ := redisd.Hget(testMachine, "hostname"); string(b) != "test" {
+		t.Errorf("machine key lost: %q", b)
+	}
+}
+
+// An assigned daemon publishes what it's given.
+type testDaemon struct {
+	redisd *Redisd
+	hsets  []string
+}
+
+func (d *testDaemon) Hset(key, field string, value []byte) (int, error) {
+	d.hsets = append(d.hsets, fmt.Sprint(key, ":", field, "=",
+		string(value)))
+	d.redisd.mutex.Lock()
+	defer d.redisd.mutex.Unlock()
+	d.redisd.published[key][field] = value
+	return 1, nil
+}
+
+func TestHincrby(t *testing.T) {
+	redisd := newTestRedisd()
+	d := &testDaemon{redisd: redisd}
+	redisd.published["d"] = grs.HashValue{"n": []byte("1")}
+	redisd.assign("d", d)
+
+	if i, err := redisd.Hincrby("d", "n", 2); err != nil || i != 3 {
+		t.Fatal("hincrby assigned:", i, err)
+	}
+	if i, err := redisd.Hincrby("d", "m", -1); err != nil || i != -1 {
+		t.Fatal("hincrby assigned new field:", i, err)
+	}
+	if got := strings.Join(d.hsets, " "); got != "d:n=3 d:m=-1" {
+		t.Errorf("daemon got %q", got)
+	}
+
+	if i, err := redisd.Hincrby("c", "n", 5); err != nil || i != 5 {
+		t.Fatal("hincrby unassigned:", i, err)
+	}
+	if i, _ := redisd.Hincrby("c", "n", 5); i != 10 {
+		t.Error("hincrby unassigned:", i)
+	}
+	if len(d.hsets) != 2 {
+		t.Error("unassigned key reached daemon")
+	}
+
+	redisd.published["c"]["s"] = []byte("x")
+	if _, err := redisd.Hincrby("c", "s", 1); err == nil {
+		t.Error("hincrby non-integer")
+	}
+	redisd.Rpush("l", []byte("a"))
+	if _, err := redisd.Hincrby("l", "n", 1); err != errWrongType {
+		t.Error("hincrby list:", err)
+	}
+}
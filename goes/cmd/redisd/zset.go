@@ -0,0 +1,211 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package redisd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var errNotFloat = errors.New("ERR value is not a valid float")
+
+// A sorted set keeps its members ordered by score then name.
+type zset struct {
+	score   map[string]float64
+	members []string
+}
+
+func newZset() *zset {
+	return &zset{score: make(map[string]float64)}
+}
+
+func (z *zset) less(i int, score float64, member string) bool {
+	zi := z.score[z.members[i]]
+	return zi < score || (zi == score && z.members[i] < member)
+}
+
+func (z *zset) index(member string) int {
+	score := z.score[member]
+	return sort.Search(len(z.members), func(i int) bool {
+		return !z.less(i, score, member)
+	})
+}
+
+func (z *zset) remove(member string) bool {
+	if _, found := z.score[member]; !found {
+		return false
+	}
+	i := z.index(member)
+	copy(z.members[i:], z.members[i+1:])
+	z.members = z.members[:len(z.members)-1]
+	delete(z.score, member)
+	return true
+}
+
+// Returns true if member is new to the set.
+func (z *zset) add(score float64, member string) bool {
+	isNew := !z.remove(member)
+	z.score[member] = score
+	i := z.index(member)
+	z.members = append(z.members, "")
+	copy(z.members[i+1:], z.members[i:])
+	z.members[i] = member
+	return isNew
+}
+
+// Zadd inserts or updates the scored members of the set, creating it as
+// needed; this returns the number of new members.
+//
+//	ZADD KEY SCORE MEMBER [SCORE MEMBER]...
+func (redisd *Redisd) Zadd(key string, scoreMembers ...string) (int, error) {
+	if len(scoreMembers) == 0 || len(scoreMembers)%2 != 0 {
+		return 0, fmt.Errorf("ZADD: SCORE MEMBER: missing")
+	}
+	scores := make([]float64, len(scoreMembers)/2)
+	for i := range scores {
+		var err error
+		scores[i], err = strconv.ParseFloat(scoreMembers[2*i], 64)
+		// NaN doesn't order so would break the set's search
+		if err != nil || math.IsNaN(scores[i]) {
+			return 0, errNotFloat
+		}
+	}
+	redisd.mutex.Lock()
+	defer redisd.mutex.Unlock()
+	redisd.expire()
+	z, err := redisd.zset(key)
+	if err != nil {
+		return 0, err
+	}
+	if z == nil {
+		z = newZset()
+		redisd.zsets[key] = z
+		redisd.flushKeyCache()
+	}
+	n := 0
+	for i, score := range scores {
+		if z.add(score, scoreMembers[2*i+1]) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Zcard returns the number of members in the set.
+func (redisd *Redisd) Zcard(key string) (int, error) {
+	redisd.mutex.Lock()
+	defer redisd.mutex.Unlock()
+	redisd.expire()
+	z, err := redisd.zset(key)
+	if z == nil {
+		return 0, err
+	}
+	return len(z.members), nil
+}
+
+// Zrange returns the set members from start through stop inclusive in
+// ascending score order.
+//
+//	ZRANGE KEY START STOP [WITHSCORES]
+func (redisd *Redisd) Zrange(key string, start, stop int,
+	opts ...string) ([][]byte, error) {
+	return redisd.zrange(false, key, start, stop, opts...)
+}
+
+// Zrevrange is like Zrange but in descending score order.
+func (redisd *Redisd) Zrevrange(key string, start, stop int,
+	opts ...string) ([][]byte, error) {
+	return redisd.zrange(true, key, start, stop, opts...)
+}
+
+// Zrem removes the given members from the set, and the set itself if that
+// leaves it empty; this returns the number of members removed.
+func (redisd *Redisd) Zrem(key string, members ...string) (int, error) {
+	redisd.mutex.Lock()
+	defer redisd.mutex.Unlock()
+	redisd.expire()
+	z, err := redisd.zset(key)
+	if z == nil {
+		return 0, err
+	}
+	n := 0
+	for _, member := range members {
+		if z.remove(member) {
+			n++
+		}
+	}
+	if len(z.members) == 0 {
+		redisd.del(key)
+	}
+	return n, nil
+}
+
+// Zscore returns the score of the set member.
+func (redisd *Redisd) Zscore(key, member string) ([]byte, error) {
+	redisd.mutex.Lock()
+	defer redisd.mutex.Unlock()
+	redisd.expire()
+	z, err := redisd.zset(key)
+	if z == nil {
+		return nil, err
+	}
+	score, found := z.score[member]
+	if !found {
+		return nil, nil
+	}
+	return formatScore(score), nil
+}
+
+func (redisd *Redisd) zrange(reverse bool, key string, start, stop int,
+	opts ...string) ([][]byte, error) {
+	withScores := false
+	for _, opt := range opts {
+		if strings.ToUpper(opt) != "WITHSCORES" {
+			return nil, fmt.Errorf("%s: unexpected", opt)
+		}
+		withScores = true
+	}
+	redisd.mutex.Lock()
+	defer redisd.mutex.Unlock()
+	redisd.expire()
+	z, err := redisd.zset(key)
+	if z == nil {
+		return [][]byte{}, err
+	}
+	n := len(z.members)
+	start, stop = span(n, start, stop)
+	bs := make([][]byte, 0, 2*(stop-start))
+	for i := start; i < stop; i++ {
+		member := z.members[i]
+		if reverse {
+			member = z.members[n-1-i]
+		}
+		bs = append(bs, []byte(member))
+		if withScores {
+			bs = append(bs, formatScore(z.score[member]))
+		}
+	}
+	return bs, nil
+}
+
+// Returns the named sorted set, nil if it doesn't exist, or an error if the
+// key holds another type.  The caller must hold the mutex.
+func (redisd *Redisd) zset(key string) (*zset, error) {
+	if z, found := redisd.zsets[key]; found {
+		return z, nil
+	}
+	if redisd.exists(key) {
+		return nil, errWrongType
+	}
+	return nil, nil
+}
+
+func formatScore(score float64) []byte {
+	return []byte(strconv.FormatFloat(score, 'g', -1, 64))
+}
@@ -0,0 +1,268 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package sshd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/kr/pty"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/platinasystems/go/internal/fields"
+	"github.com/platinasystems/go/internal/log"
+	"github.com/platinasystems/go/internal/prog"
+	"github.com/platinasystems/go/internal/sftp"
+)
+
+type session struct {
+	user string
+	ch   ssh.Channel
+	env  []string
+	term string
+
+	pty, tty *os.File
+
+	once sync.Once
+}
+
+type ptyReq struct {
+	Term   string
+	Cols   uint32
+	Rows   uint32
+	Width  uint32
+	Height uint32
+	Modes  string
+}
+
+type windowChange struct {
+	Cols   uint32
+	Rows   uint32
+	Width  uint32
+	Height uint32
+}
+
+type envReq struct {
+	Name, Value string
+}
+
+type stringReq struct {
+	S string
+}
+
+type exitStatus struct {
+	Status uint32
+}
+
+func (s *session) serve(reqs <-chan *ssh.Request) {
+	defer s.closePty()
+	for req := range reqs {
+		var run func()
+		ok := false
+		switch req.Type {
+		case "pty-req":
+			var msg ptyReq
+			if ssh.Unmarshal(req.Payload, &msg) == nil {
+				ok = s.openPty(&msg) == nil
+			}
+		case "window-change":
+			var msg windowChange
+			if ssh.Unmarshal(req.Payload, &msg) == nil &&
+				s.pty != nil {
+				ok = setsize(s.pty, msg.Cols, msg.Rows) == nil
+			}
+		case "env":
+			var msg envReq
+			if ssh.Unmarshal(req.Payload, &msg) == nil &&
+				allowEnv(msg.Name) {
+				s.env = append(s.env, msg.Name+"="+msg.Value)
+				ok = true
+			}
+		case "shell":
+			run, ok = s.command(), true
+		case "exec":
+			var msg stringReq
+			if ssh.Unmarshal(req.Payload, &msg) == nil {
+				run = s.command(fields.New(msg.S)...)
+				ok = true
+			}
+		case "subsystem":
+			var msg stringReq
+			if ssh.Unmarshal(req.Payload, &msg) == nil &&
+				msg.S == "sftp" {
+				run, ok = s.sftp()
+			}
+		}
+		if req.WantReply {
+			req.Reply(ok, nil)
+		}
+		if run != nil {
+			s.once.Do(func() { go run() })
+		}
+	}
+}
+
+func (s *session) openPty(msg *ptyReq) error {
+	if s.pty != nil {
+		return fmt.Errorf("pty: already allocated")
+	}
+	var err error
+	s.pty, s.tty, err = pty.Open()
+	if err != nil {
+		return err
+	}
+	s.term = msg.Term
+	return setsize(s.pty, msg.Cols, msg.Rows)
+}
+
+func (s *session) closePty() {
+	if s.tty != nil {
+		s.tty.Close()
+	}
+	if s.pty != nil {
+		s.pty.Close()
+	}
+}
+
+// Clients may only set the locale and terminal type, not, for example,
+// PATH or LD_PRELOAD of the goes shell.
+func allowEnv(name string) bool {
+	return name == "LANG" || name == "TERM" ||
+		strings.HasPrefix(name, "LC_")
+}
+
+// Returns a func to run the goes shell or command with the session's pty or
+// channel as its standard input and output.
+func (s *session) command(args ...string) func() {
+	return func() {
+		code := uint32(0)
+		if err := s.run(args...); err != nil {
+			code = 1
+			if e, ok := err.(*exec.ExitError); ok {
+				if ws, ok := e.Sys().(syscall.WaitStatus); ok {
+					code = uint32(ws.ExitStatus())
+				}
+			} else {
+				fmt.Fprintln(s.ch.Stderr(), err)
+				code = 127
+			}
+		}
+		s.exit(code)
+	}
+}
+
+func (s *session) run(args ...string) error {
+	uid, gid, same, err := credential(s.user)
+	if err != nil {
+		return err
+	}
+	u, err := lookup(s.user)
+	if err != nil {
+		return err
+	}
+	dir := "/"
+	if fi, err := os.Stat(u.HomeDir); err == nil && fi.IsDir() {
+		dir = u.HomeDir
+	}
+	term := s.term
+	if len(term) == 0 {
+		term = "dumb"
+	}
+	x := exec.Command(prog.Name(), args...)
+	x.Args[0] = "goes"
+	x.Dir = dir
+	x.Env = append([]string{
+		"PATH=" + prog.Path(),
+		"TERM=" + term,
+		"HOME=" + u.HomeDir,
+		"USER=" + s.user,
+		"LOGNAME=" + s.user,
+	}, s.env...)
+	x.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if !same {
+		x.SysProcAttr.Credential = &syscall.Credential{
+			Uid: uid,
+			Gid: gid,
+		}
+	}
+	if s.pty == nil {
+		stdin, err := x.StdinPipe()
+		if err != nil {
+			return err
+		}
+		x.Stdout = s.ch
+		x.Stderr = s.ch.Stderr()
+		if err = x.Start(); err != nil {
+			return err
+		}
+		go func() {
+			io.Copy(stdin, s.ch)
+			stdin.Close()
+		}()
+		return x.Wait()
+	}
+	x.Stdin = s.tty
+	x.Stdout = s.tty
+	x.Stderr = s.tty
+	x.SysProcAttr.Setctty = true
+	x.SysProcAttr.Ctty = 0
+	err = x.Start()
+	// The child has its own tty so close ours for the master to read EOF
+	// once the child exits; keep the master for the session's I/O.
+	s.tty.Close()
+	if err != nil {
+		return err
+	}
+	go io.Copy(s.pty, s.ch)
+	done := make(chan struct{})
+	go func() {
+		io.Copy(s.ch, s.pty)
+		close(done)
+	}()
+	err = x.Wait()
+	<-done
+	s.pty.Close()
+	return err
+}
+
+// Returns a func to serve SFTP from the user's home directory.  This runs
+// within the daemon so it's only available to users with the same
+// credentials.
+func (s *session) sftp() (func(), bool) {
+	_, _, same, err := credential(s.user)
+	if err != nil || !same {
+		return nil, false
+	}
+	u, err := lookup(s.user)
+	if err != nil {
+		return nil, false
+	}
+	return func() {
+		code := uint32(0)
+		if err := sftp.Serve(s.ch, u.HomeDir); err != nil {
+			log.Print("daemon", "err", s.user, ": sftp: ", err)
+			code = 1
+		}
+		s.exit(code)
+	}, true
+}
+
+func (s *session) exit(code uint32) {
+	s.ch.SendRequest("exit-status", false,
+		ssh.Marshal(&exitStatus{code}))
+	s.ch.Close()
+}
+
+func setsize(f *os.File, cols, rows uint32) error {
+	return pty.Setsize(f, &pty.Winsize{
+		Rows: uint16(rows),
+		Cols: uint16(cols),
+	})
+}
@@ -0,0 +1,131 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package sshd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Sessions run this test binary in place of goes; clients may set LC_*
+// variables so this one has it act as the shell instead of run the tests.
+const testShellEnv = "LC_SSHD_TEST_SHELL"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testShellEnv) != "" {
+		os.Exit(testShell())
+	}
+	os.Exit(m.Run())
+}
+
+// Greet the line read from a terminal stdin.
+func testShell() int {
+	var termios syscall.Termios
+	if _, _, e := syscall.Syscall(syscall.SYS_IOCTL, 0,
+		uintptr(syscall.TCGETS),
+		uintptr(unsafe.Pointer(&termios))); e != 0 {
+		fmt.Println("stdin:", e)
+		return 1
+	}
+	fmt.Println("ready")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	fmt.Printf("hello %s\n", strings.TrimSpace(line))
+	return 3
+}
+
+func TestPtySession(t *testing.T) {
+	u, err := user.Current()
+	if err != nil {
+		t.Skip(err)
+	}
+	dir, err := ioutil.TempDir("", "sshd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	signer, err := hostKey(filepath.Join(dir, "key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn, config)
+		}
+	}()
+
+	client, err := ssh.Dial("tcp", ln.Addr().String(), &ssh.ClientConfig{
+		User:            u.Username,
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	sess, err := client.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close()
+	if err = sess.Setenv(testShellEnv, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if err = sess.RequestPty("xterm", 24, 80, ssh.TerminalModes{}); err != nil {
+		t.Fatal(err)
+	}
+	stdin, err := sess.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	sess.Stdout = &out
+	if err = sess.Shell(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stdin.Write([]byte("world\n")); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- sess.Wait() }()
+	select {
+	case err = <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("shell didn't exit")
+	}
+	if e, ok := err.(*ssh.ExitError); !ok || e.ExitStatus() != 3 {
+		t.Errorf("exit: %v; output %q", err, out.String())
+	}
+	if s := out.String(); !strings.Contains(s, "hello world") {
+		t.Errorf("output %q", s)
+	}
+}
@@ -0,0 +1,324 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package sshd provides a secure shell server daemon that runs the goes shell
+// or a single goes command for each session and serves SFTP to copy images.
+package sshd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/platinasystems/go/goes/cmd"
+	"github.com/platinasystems/go/goes/lang"
+	"github.com/platinasystems/go/internal/crypt"
+	"github.com/platinasystems/go/internal/log"
+	"github.com/platinasystems/go/internal/parms"
+)
+
+const (
+	DefaultHostKey = "/etc/ssh/ssh_host_ecdsa_key"
+	DefaultShadow  = "/etc/shadow"
+)
+
+type Command struct {
+	// Machines may restrict sshd listening to this address.
+	// default: ":22"
+	Addr string
+
+	// Machines may move the host key to persistent storage.
+	// default: DefaultHostKey
+	HostKey string
+
+	mutex sync.Mutex
+	ln    net.Listener
+	done  bool
+}
+
+func (*Command) String() string { return "sshd" }
+
+func (*Command) Usage() string {
+	return "sshd [-addr ADDRESS] [-key FILE] [-shadow FILE]"
+}
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "secure shell server daemon",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Run a secure shell server that authenticates users by password or
+	public key then runs either the goes shell on a pseudo-terminal, the
+	requested goes command, or the "sftp" subsystem.
+
+	Passwords are SHA-256 ($5$) or SHA-512 ($6$) crypt(3) hashes from the
+	shadow file.  Public keys are read from the user's
+	~/.ssh/authorized_keys.
+
+	If the host key doesn't exist, sshd generates a new ECDSA P-256 key.
+
+OPTIONS
+	-addr ADDRESS
+		listening address, default: ":22"
+	-key FILE
+		host key, default: /etc/ssh/ssh_host_ecdsa_key
+	-shadow FILE
+		password file, default: /etc/shadow
+
+SEE ALSO
+	telnetd`,
+	}
+}
+
+func (*Command) Kind() cmd.Kind { return cmd.Daemon }
+
+func (c *Command) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.done = true
+	if c.ln != nil {
+		return c.ln.Close()
+	}
+	return nil
+}
+
+func (c *Command) Main(args ...string) error {
+	parm, args := parms.New(args, "-addr", "-key", "-shadow")
+	if len(args) > 0 {
+		return fmt.Errorf("%v: unexpected", args)
+	}
+	addr := parm.ByName["-addr"]
+	if len(addr) == 0 {
+		addr = c.Addr
+	}
+	if len(addr) == 0 {
+		addr = ":22"
+	}
+	keyfn := parm.ByName["-key"]
+	if len(keyfn) == 0 {
+		keyfn = c.HostKey
+	}
+	if len(keyfn) == 0 {
+		keyfn = DefaultHostKey
+	}
+	shadow := parm.ByName["-shadow"]
+	if len(shadow) == 0 {
+		shadow = DefaultShadow
+	}
+
+	signer, err := hostKey(keyfn)
+	if err != nil {
+		return err
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(meta ssh.ConnMetadata,
+			password []byte) (*ssh.Permissions, error) {
+			return passwordAuth(shadow, meta, password)
+		},
+		PublicKeyCallback: publicKeyAuth,
+	}
+	config.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	c.ln = ln
+	done := c.done
+	c.mutex.Unlock()
+	if done {
+		return ln.Close()
+	}
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			c.mutex.Lock()
+			done = c.done
+			c.mutex.Unlock()
+			if done {
+				err = nil
+			}
+			return err
+		}
+		go serve(conn, config)
+	}
+}
+
+func serve(conn net.Conn, config *ssh.ServerConfig) {
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		log.Print("daemon", "info", conn.RemoteAddr(), ": ", err)
+		conn.Close()
+		return
+	}
+	defer sconn.Close()
+	log.Print("auth", "info", sconn.User(), "@", sconn.RemoteAddr(),
+		": logged in")
+	go ssh.DiscardRequests(reqs)
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			nc.Reject(ssh.UnknownChannelType, nc.ChannelType())
+			continue
+		}
+		ch, creqs, err := nc.Accept()
+		if err != nil {
+			log.Print("daemon", "err", sconn.User(), "@",
+				sconn.RemoteAddr(), ": ", err)
+			continue
+		}
+		s := &session{
+			user: sconn.User(),
+			ch:   ch,
+		}
+		go s.serve(creqs)
+	}
+}
+
+// Load the host key from the given file, generating it if it doesn't exist.
+func hostKey(fn string) (ssh.Signer, error) {
+	b, err := ioutil.ReadFile(fn)
+	if os.IsNotExist(err) {
+		b, err = newHostKey(fn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.ParsePrivateKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fn, err)
+	}
+	return signer, nil
+}
+
+func newHostKey(fn string) ([]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	b := pem.EncodeToMemory(&pem.Block{
+		Type:  "EC PRIVATE KEY",
+		Bytes: der,
+	})
+	if err = os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+		return nil, err
+	}
+	if err = ioutil.WriteFile(fn, b, 0600); err != nil {
+		return nil, err
+	}
+	log.Print("daemon", "note", "generated ", fn)
+	return b, nil
+}
+
+func passwordAuth(shadow string, meta ssh.ConnMetadata,
+	password []byte) (*ssh.Permissions, error) {
+	f, err := os.Open(shadow)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	prefix := meta.User() + ":"
+	for scan := bufio.NewScanner(f); scan.Scan(); {
+		line := scan.Text()
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		hash := strings.SplitN(line[len(prefix):], ":", 2)[0]
+		if crypt.Verify(string(password), hash) {
+			return nil, nil
+		}
+	}
+	log.Print("auth", "warn", meta.User(), "@", meta.RemoteAddr(),
+		": password rejected")
+	return nil, fmt.Errorf("%s: password rejected", meta.User())
+}
+
+func publicKeyAuth(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions,
+	error) {
+	u, err := lookup(meta.User())
+	if err != nil {
+		return nil, err
+	}
+	fn := filepath.Join(u.HomeDir, ".ssh", "authorized_keys")
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	want := key.Marshal()
+	// skip, rather than stop at, lines that don't parse
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		pub, _, _, _, err := ssh.ParseAuthorizedKey(line)
+		if err != nil {
+			continue
+		}
+		if bytes.Equal(pub.Marshal(), want) {
+			return &ssh.Permissions{
+				Extensions: map[string]string{
+					"pubkey-fp": ssh.FingerprintSHA256(key),
+				},
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: public key rejected", meta.User())
+}
+
+// Lookup the named user in /etc/passwd; machines without that may only
+// login as root.
+func lookup(name string) (*user.User, error) {
+	u, err := user.Lookup(name)
+	if err != nil && name == "root" {
+		u, err = &user.User{
+			Uid:      "0",
+			Gid:      "0",
+			Username: "root",
+			HomeDir:  "/root",
+		}, nil
+	}
+	return u, err
+}
+
+// Returns the user's credentials and whether they're the same as this
+// daemon's.
+func credential(name string) (uid, gid uint32, same bool, err error) {
+	u, err := lookup(name)
+	if err != nil {
+		return
+	}
+	id, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return
+	}
+	uid = uint32(id)
+	id, err = strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return
+	}
+	gid = uint32(id)
+	same = uid == uint32(os.Getuid()) && gid == uint32(os.Getgid())
+	return
+}
@@ -9,6 +9,7 @@ package w83795d
 import (
 	"fmt"
 	"net/rpc"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -18,6 +19,7 @@ import (
 	"github.com/platinasystems/go/goes/cmd"
 	"github.com/platinasystems/go/goes/lang"
 	"github.com/platinasystems/go/internal/atsock"
+	"github.com/platinasystems/go/internal/fanpolicy"
 	"github.com/platinasystems/go/internal/gpio"
 	"github.com/platinasystems/go/internal/log"
 	"github.com/platinasystems/go/internal/redis"
@@ -45,6 +47,15 @@ var (
 	hostCtrl bool
 	thCtrl   bool
 
+	// PolicyFile configures the "policy" fan speed, the default if
+	// present.
+	PolicyFile = fanpolicy.DefaultFile
+
+	policyCtrl   bool
+	policy       *fanpolicy.Engine
+	policyTime   time.Time
+	policyReason string
+
 	Vdev I2cDev
 
 	VpageByKey map[string]uint8
@@ -88,6 +99,22 @@ func (*Command) Apropos() lang.Alt {
 	}
 }
 
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Publish the fan tray and temperature sensors of the w83795 hardware
+	monitor and control fan speed by fan_tray.speed: auto, high, med,
+	low, or policy.
+
+	The policy speed runs the closed-loop controller of ` + fanpolicy.DefaultFile + `
+	with zones of temperatures from redis and publishes its inputs and
+	decisions as fan_policy.* fields.  It's the default speed if the
+	file exists and is reloaded each time fan_tray.speed is set to
+	policy.`,
+	}
+}
+
 func (*Command) Kind() cmd.Kind { return cmd.Daemon }
 
 func (c *Command) Main(...string) error {
@@ -173,6 +200,12 @@ func (c *Command) update() error {
 		first = 0
 	}
 
+	if policyCtrl {
+		if err := c.policyStep(); err != nil {
+			log.Print("warning: fan policy: ", err)
+		}
+	}
+
 	for k, i := range VpageByKey {
 		if strings.Contains(k, "rpm") {
 			v, err := Vdev.FanCount(i)
@@ -382,8 +415,11 @@ func (h *I2cDev) FanCount(i uint8) (uint16, error) {
 }
 
 func (h *I2cDev) FanInit() error {
-	//default auto mode
+	//default auto mode, or policy if configured
 	lastSpeed = "auto"
+	if _, err := os.Stat(PolicyFile); err == nil {
+		lastSpeed = "policy"
+	}
 
 	//reset hwm to default values
 	r0 := getRegsBank0()
@@ -408,8 +444,8 @@ func (h *I2cDev) FanInit() error {
 		return err
 	}
 
-	//set default speed to auto
-	h.SetFanSpeed("auto", true)
+	//set default speed
+	h.SetFanSpeed(lastSpeed, true)
 
 	//enable temperature monitoring
 	r0.BankSelect.set(h, 0x80)
@@ -448,7 +484,11 @@ func (h *I2cDev) SetFanDuty(d uint8) error {
 			break
 		}
 	}
+	return h.fanDuty(d)
+}
 
+// Set manual mode and the duty of both fan outputs.
+func (h *I2cDev) fanDuty(d uint8) error {
 	r2 := getRegsBank2()
 	r2.BankSelect.set(h, 0x82)
 	r2.TempToFanMap1.set(h, 0x0)
@@ -463,6 +503,35 @@ func (h *I2cDev) SetFanDuty(d uint8) error {
 	return nil
 }
 
+// Run the fan policy engine with the temperatures in redis then publish
+// its decision and set the duty.  The engine sets the failsafe duty with
+// failed fans.
+func (c *Command) policyStep() error {
+	fields, err := redis.Hgetall(machine.Name)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var dt time.Duration
+	if !policyTime.IsZero() {
+		dt = now.Sub(policyTime)
+	}
+	policyTime = now
+	d := policy.Step(fields, dt)
+	for k, v := range d.Fields("fan_policy.") {
+		if v != c.lasts[k] {
+			c.pub.Print(k, ": ", v)
+			c.lasts[k] = v
+		}
+	}
+	if d.Reason != policyReason {
+		log.Print("notice: fan policy duty ", d.Duty, "% by ",
+			d.Reason)
+		policyReason = d.Reason
+	}
+	return Vdev.fanDuty(d.PWM())
+}
+
 func (h *I2cDev) SetFanSpeed(w string, l bool) error {
 	r2 := getRegsBank2()
 
@@ -482,7 +551,26 @@ func (h *I2cDev) SetFanSpeed(w string, l bool) error {
 		w = "high"
 	}
 
+	if w != "policy" {
+		policyCtrl = false
+	}
+
 	switch w {
+	case "policy":
+		p, err := fanpolicy.Load(PolicyFile)
+		if err != nil {
+			log.Print("warning: ", err)
+			return err
+		}
+		policy = fanpolicy.New(p)
+		policyTime = time.Time{}
+		policyReason = ""
+		policyCtrl = true
+		hostCtrl = false
+		thCtrl = false
+		if l {
+			log.Print("notice: fan speed set to ", w)
+		}
 	case "auto":
 		if !hostCtrl {
 			r2.BankSelect.set(h, 0x82)
@@ -606,6 +694,10 @@ func (h *I2cDev) GetFanSpeed() (string, error) {
 	var duty uint8
 	r2 := getRegsBank2()
 
+	if policyCtrl {
+		return "policy", nil
+	}
+
 	if !hostCtrl {
 		r2.BankSelect.set(h, 0x82)
 		r2.TempToFanMap1.get(h)
@@ -806,7 +898,7 @@ func writeRegs() error {
 	for k, v := range WrRegVal {
 		switch WrRegFn[k] {
 		case "speed":
-			if v == "auto" || v == "high" || v == "med" || v == "low" || v == "max" {
+			if v == "auto" || v == "high" || v == "med" || v == "low" || v == "max" || v == "policy" {
 				Vdev.SetFanSpeed(v, true)
 			}
 		case "host.temp.units.C":
@@ -64,6 +64,10 @@ type Goes struct {
 	cache  cache
 	parent *Goes
 
+	jobs jobTable
+	// of the running foreground pipeline
+	job *Job
+
 	EnvMap map[string]string
 
 	FunctionMap map[string]Function
@@ -275,12 +279,24 @@ func (g *Goes) ProcessCommand(cl shellutils.Cmdline, closers *[]io.Closer) (func
 		x.Stdout = out
 		x.Stderr = stderr
 
+		job := g.job
+		if job != nil {
+			job.fork(x)
+		}
 		if err := x.Start(); err != nil {
 			err = fmt.Errorf("child: %v: %v", x.Args, err)
 			return err
 		}
+		if job != nil {
+			job.started(x)
+		}
 		if isLast {
-			err := x.Wait()
+			var err error
+			if job != nil {
+				err = g.waitForeground(job, x)
+			} else {
+				err = x.Wait()
+			}
 			g.Status = err
 		} else {
 			go func(x *exec.Cmd) {
@@ -318,6 +334,16 @@ func (g *Goes) MakePipefun(pipeline []func(io.Reader, io.Writer, io.Writer, bool
 				c.Close()
 			}
 		}()
+		if g.job == nil {
+			// each foreground pipeline is a process group that may
+			// be suspended from the terminal
+			if g.job = g.newJob(); g.job != nil {
+				defer func() {
+					g.terminal(nil)
+					g.job = nil
+				}()
+			}
+		}
 		in := stdin
 		end := len(pipeline) - 1
 		for i, runfun := range pipeline {
@@ -647,13 +673,17 @@ func (g *Goes) ProcessList(ls shellutils.List) (*shellutils.List, *shellutils.Wo
 		return nil, nil, nil, err
 	}
 	ls = *newls
+	if i, amp := backgroundList(ls); amp {
+		return g.processBackground(ls, i)
+	}
 	for len(ls.Cmds) != 0 {
-		nextls, term, runner, err := g.ProcessPipeline(ls)
+		nextls, pterm, runner, err := g.ProcessPipeline(ls)
 		if err != nil {
 			return nil, nil, nil, err
 		}
 		ls = *nextls
-		pipeline = append(pipeline, piperun{f: runner, t: *term})
+		term = *pterm
+		pipeline = append(pipeline, piperun{f: runner, t: term})
 		if term.String() != "&&" && term.String() != "||" {
 			break
 		}
@@ -664,6 +694,42 @@ func (g *Goes) ProcessList(ls shellutils.List) (*shellutils.List, *shellutils.Wo
 	return &ls, &term, listfun, nil
 }
 
+// processBackground returns the remaining list after the i'th command line
+// and a function to start the preceding list, as text, in a child shell.
+func (g *Goes) processBackground(ls shellutils.List, i int) (*shellutils.List, *shellutils.Word, func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error, error) {
+	getenv := func(k string) string {
+		v, def := g.EnvMap[k]
+		if def {
+			return v
+		}
+		return os.Getenv(k)
+	}
+	var text []string
+	for _, cl := range ls.Cmds[:i+1] {
+		name := cl.Cmds[0].String()
+		if v := g.ByName[name]; v != nil {
+			if _, found := v.(Blocker); found {
+				return nil, nil, nil,
+					fmt.Errorf("%s: can't background", name)
+			}
+			if cmd.WhatKind(v).IsDaemon() {
+				return nil, nil, nil, fmt.Errorf(
+					"use `goes-daemons start %s`", name)
+			}
+		}
+		text = append(text, cl.Quote(getenv))
+		if term := cl.Term.String(); term != "&" {
+			text = append(text, term)
+		}
+	}
+	term := ls.Cmds[i].Term
+	rest := &shellutils.List{Cmds: ls.Cmds[i+1:]}
+	runner := func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+		return g.background(strings.Join(text, " "))
+	}
+	return rest, &term, runner, nil
+}
+
 func (g *Goes) MakeListFunc(pipeline []piperun) (func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error, error) {
 	listfun := func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
 		var err error
@@ -0,0 +1,556 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// +build linux
+
+package goes
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/platinasystems/go/internal/shellutils"
+)
+
+// ErrStopped is the status of a foreground pipeline suspended with the
+// terminal's stop character, ^Z.
+var ErrStopped = errors.New("stopped")
+
+const (
+	jobRunning = iota
+	jobStopped
+	jobDone
+)
+
+// Job is a pipeline that's running in the background or suspended.
+type Job struct {
+	Id      int
+	Cmdline string
+	// Pid of the process that ends the wait for the job.
+	Pid  int
+	pgid int
+
+	state int
+	err   error
+	done  chan struct{}
+}
+
+type jobTable struct {
+	sync.Mutex
+	list []*Job
+	// with ^Z, the shell's stdin is its controlling terminal
+	control bool
+	// of the shell
+	pgrp int
+}
+
+// JobControl enables the terminal suspension of foreground pipelines if the
+// stdin of this shell is its controlling terminal and the shell is in its
+// foreground.
+func (g *Goes) JobControl() bool {
+	pgrp := syscall.Getpgrp()
+	if fg, err := tcgetpgrp(os.Stdin); err != nil || fg != pgrp {
+		return false
+	}
+	// Ignore SIGTTOU to retake the terminal but catch SIGTSTP and
+	// SIGTTIN so that children have the default disposition.
+	signal.Ignore(syscall.SIGTTOU)
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTSTP, syscall.SIGTTIN)
+	go func() {
+		for range sig {
+		}
+	}()
+	g.jobs.Lock()
+	defer g.jobs.Unlock()
+	g.jobs.control = true
+	g.jobs.pgrp = pgrp
+	return true
+}
+
+func (j *Job) String() string {
+	return fmt.Sprint("[", j.Id, "]")
+}
+
+func (j *Job) finish(g *Goes, err error) {
+	g.jobs.Lock()
+	j.state = jobDone
+	j.err = err
+	g.jobs.Unlock()
+	close(j.done)
+}
+
+// Start the forked command of the given list in the background.
+func (g *Goes) background(cmdline string) error {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	x := g.Fork("cli", "/dev/fd/3")
+	x.ExtraFiles = []*os.File{r}
+	x.Stdout = os.Stdout
+	x.Stderr = os.Stderr
+	g.jobs.Lock()
+	control := g.jobs.control
+	g.jobs.Unlock()
+	if control {
+		// reads of the terminal stop the job
+		x.Stdin = os.Stdin
+		x.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	}
+	if err = x.Start(); err != nil {
+		w.Close()
+		return fmt.Errorf("child: %v: %v", x.Args, err)
+	}
+	go func() {
+		fmt.Fprintln(w, cmdline)
+		w.Close()
+	}()
+	j := &Job{
+		Cmdline: cmdline,
+		Pid:     x.Process.Pid,
+		pgid:    x.Process.Pid,
+		done:    make(chan struct{}),
+	}
+	if !control {
+		j.pgid = 0
+	}
+	g.jobs.add(j)
+	if g.EnvMap == nil {
+		g.EnvMap = make(map[string]string)
+	}
+	g.EnvMap["!"] = strconv.Itoa(j.Pid)
+	if control {
+		fmt.Fprintln(os.Stderr, j, j.Pid)
+	}
+	go func() {
+		j.finish(g, x.Wait())
+	}()
+	g.Status = nil
+	return nil
+}
+
+func (t *jobTable) add(j *Job) {
+	t.Lock()
+	defer t.Unlock()
+	for _, p := range t.list {
+		if p == j {
+			return
+		}
+	}
+	j.Id = 1
+	if n := len(t.list); n > 0 {
+		j.Id = t.list[n-1].Id + 1
+	}
+	t.list = append(t.list, j)
+}
+
+func (t *jobTable) remove(j *Job) {
+	t.Lock()
+	defer t.Unlock()
+	for i, p := range t.list {
+		if p == j {
+			copy(t.list[i:], t.list[i+1:])
+			t.list[len(t.list)-1] = nil
+			t.list = t.list[:len(t.list)-1]
+			return
+		}
+	}
+}
+
+// Returns the current, "+", and previous, "-", jobs; the current is the
+// most recently suspended or, without any suspended, started.
+func (t *jobTable) current() (cur, prev *Job) {
+	for i := len(t.list) - 1; i >= 0; i-- {
+		j := t.list[i]
+		if j.state == jobStopped && cur == nil {
+			cur = j
+		}
+	}
+	for i := len(t.list) - 1; i >= 0; i-- {
+		j := t.list[i]
+		if cur == nil {
+			cur = j
+		} else if j != cur && prev == nil {
+			prev = j
+		}
+	}
+	return
+}
+
+// Returns the job of the spec: %N, %+, %%, %-, or PID; default, current.
+func (t *jobTable) find(spec string) (*Job, error) {
+	t.Lock()
+	defer t.Unlock()
+	cur, prev := t.current()
+	switch spec {
+	case "", "%", "%+", "%%":
+		if cur == nil {
+			return nil, errors.New("no current job")
+		}
+		return cur, nil
+	case "%-":
+		if prev == nil {
+			return nil, errors.New("no previous job")
+		}
+		return prev, nil
+	}
+	if strings.HasPrefix(spec, "%") {
+		id, err := strconv.Atoi(spec[1:])
+		if err == nil {
+			for _, j := range t.list {
+				if j.Id == id {
+					return j, nil
+				}
+			}
+		}
+	} else if pid, err := strconv.Atoi(spec); err == nil {
+		for _, j := range t.list {
+			if j.Pid == pid {
+				return j, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s: no such job", spec)
+}
+
+// Update the state of running jobs suspended by a signal, e.g. SIGTTIN.
+func (t *jobTable) poll() {
+	t.Lock()
+	defer t.Unlock()
+	for _, j := range t.list {
+		if j.state == jobRunning {
+			if stopped, err := waitStop(j.Pid, false); err == nil &&
+				stopped {
+				j.state = jobStopped
+			}
+		}
+	}
+}
+
+func (t *jobTable) format(j *Job, long bool) string {
+	cur, prev := t.current()
+	mark := " "
+	if j == cur {
+		mark = "+"
+	} else if j == prev {
+		mark = "-"
+	}
+	var state string
+	switch j.state {
+	case jobRunning:
+		state = "Running"
+	case jobStopped:
+		state = "Stopped"
+	case jobDone:
+		state = "Done"
+		if e, ok := j.err.(*exec.ExitError); ok {
+			ws, _ := e.Sys().(syscall.WaitStatus)
+			if ws.Signaled() {
+				state = ws.Signal().String()
+				state = strings.ToUpper(state[:1]) + state[1:]
+			} else {
+				state = fmt.Sprint("Exit ", ws.ExitStatus())
+			}
+		} else if j.err != nil {
+			state = "Exit"
+		}
+	}
+	pid := ""
+	if long {
+		pid = fmt.Sprint(j.Pid, " ")
+	}
+	amp := ""
+	if j.state == jobRunning {
+		amp = " &"
+	}
+	return fmt.Sprintf("%s%s  %s%-24s%s%s", j, mark, pid, state,
+		j.Cmdline, amp)
+}
+
+// ReportJobs prints and forgets the jobs that have finished since the last
+// report.
+func (g *Goes) ReportJobs() {
+	g.jobs.poll()
+	g.jobs.Lock()
+	var done []*Job
+	for _, j := range g.jobs.list {
+		if j.state == jobDone {
+			fmt.Fprintln(os.Stderr, g.jobs.format(j, false))
+			done = append(done, j)
+		}
+	}
+	g.jobs.Unlock()
+	for _, j := range done {
+		g.jobs.remove(j)
+	}
+}
+
+// Wait for the exit or stop of the foreground job's process; a stopped job
+// is added to the table.
+func (g *Goes) foreground(j *Job) error {
+	stopped, err := waitStop(j.Pid, true)
+	if err == nil && stopped {
+		g.jobs.add(j)
+		g.jobs.Lock()
+		j.state = jobStopped
+		s := g.jobs.format(j, false)
+		g.jobs.Unlock()
+		fmt.Fprint(os.Stderr, "\n", s, "\n")
+		return ErrStopped
+	}
+	<-j.done
+	g.jobs.remove(j)
+	return j.err
+}
+
+// Wait for the forked, last command of a foreground pipeline.
+func (g *Goes) waitForeground(j *Job, x *exec.Cmd) error {
+	j.Pid = x.Process.Pid
+	j.done = make(chan struct{})
+	go func() {
+		j.finish(g, x.Wait())
+	}()
+	return g.foreground(j)
+}
+
+// Give the terminal to the job's process group or, if nil, the shell.
+func (g *Goes) terminal(j *Job) {
+	g.jobs.Lock()
+	control, pgrp := g.jobs.control, g.jobs.pgrp
+	g.jobs.Unlock()
+	if !control {
+		return
+	}
+	if j != nil && j.pgid != 0 {
+		pgrp = j.pgid
+	}
+	tcsetpgrp(os.Stdin, pgrp)
+}
+
+// Returns the foreground job of a pipeline if this shell has job control.
+func (g *Goes) newJob() *Job {
+	g.jobs.Lock()
+	defer g.jobs.Unlock()
+	if !g.jobs.control {
+		return nil
+	}
+	return &Job{}
+}
+
+// Add the forked command to the job; the first is its process group leader.
+func (j *Job) fork(x *exec.Cmd) {
+	x.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid: true,
+		Pgid:    j.pgid,
+	}
+	if j.pgid == 0 {
+		x.SysProcAttr.Foreground = true
+		x.SysProcAttr.Ctty = int(os.Stdin.Fd())
+	}
+}
+
+func (j *Job) started(x *exec.Cmd) {
+	if j.pgid == 0 {
+		j.pgid = x.Process.Pid
+	}
+	if len(j.Cmdline) > 0 {
+		j.Cmdline += " | "
+	}
+	j.Cmdline += strings.Join(x.Args, " ")
+}
+
+// Returns the index of the terminator of the first list and whether that's
+// an ampersand.
+func backgroundList(ls shellutils.List) (int, bool) {
+	for i, cl := range ls.Cmds {
+		switch cl.Term.String() {
+		case "|", "&&", "||":
+		default:
+			return i, cl.Term.String() == "&"
+		}
+	}
+	return -1, false
+}
+
+func (g *Goes) listJobs(args ...string) error {
+	var long, pids bool
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		switch args[0] {
+		case "-l":
+			long = true
+		case "-p":
+			pids = true
+		default:
+			return fmt.Errorf("%s: invalid option", args[0])
+		}
+		args = args[1:]
+	}
+	g.jobs.poll()
+	var list []*Job
+	if len(args) == 0 {
+		g.jobs.Lock()
+		list = append(list, g.jobs.list...)
+		g.jobs.Unlock()
+	}
+	for _, spec := range args {
+		j, err := g.jobs.find(spec)
+		if err != nil {
+			return err
+		}
+		list = append(list, j)
+	}
+	g.jobs.Lock()
+	defer g.jobs.Unlock()
+	for _, j := range list {
+		if pids {
+			fmt.Println(j.Pid)
+		} else {
+			fmt.Println(g.jobs.format(j, long))
+		}
+	}
+	return nil
+}
+
+func (g *Goes) continueJob(j *Job) error {
+	g.jobs.Lock()
+	j.state = jobRunning
+	pgid := j.pgid
+	g.jobs.Unlock()
+	if pgid == 0 {
+		return nil
+	}
+	return syscall.Kill(-pgid, syscall.SIGCONT)
+}
+
+func (g *Goes) fg(args ...string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("%v: unexpected", args[1:])
+	}
+	spec := ""
+	if len(args) > 0 {
+		spec = args[0]
+	}
+	j, err := g.jobs.find(spec)
+	if err != nil {
+		return err
+	}
+	fmt.Println(j.Cmdline)
+	g.terminal(j)
+	defer g.terminal(nil)
+	if err = g.continueJob(j); err != nil {
+		return err
+	}
+	g.Status = g.foreground(j)
+	if g.Status == ErrStopped {
+		return nil
+	}
+	return g.Status
+}
+
+func (g *Goes) bg(args ...string) error {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	for _, spec := range args {
+		j, err := g.jobs.find(spec)
+		if err != nil {
+			return err
+		}
+		if err = g.continueJob(j); err != nil {
+			return err
+		}
+		fmt.Println(j, j.Cmdline, "&")
+	}
+	return nil
+}
+
+// Wait for all background jobs or those given; the status is that of the
+// last.
+func (g *Goes) waitJobs(args ...string) error {
+	var list []*Job
+	if len(args) == 0 {
+		g.jobs.Lock()
+		for _, j := range g.jobs.list {
+			if j.state == jobRunning {
+				list = append(list, j)
+			}
+		}
+		g.jobs.Unlock()
+	}
+	for _, spec := range args {
+		j, err := g.jobs.find(spec)
+		if err != nil {
+			return err
+		}
+		list = append(list, j)
+	}
+	var err error
+	for _, j := range list {
+		<-j.done
+		g.jobs.Lock()
+		err = j.err
+		g.jobs.Unlock()
+		g.jobs.remove(j)
+	}
+	g.Status = err
+	return err
+}
+
+func tcgetpgrp(f *os.File) (int, error) {
+	var pgrp int32
+	_, _, e := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(),
+		syscall.TIOCGPGRP, uintptr(unsafe.Pointer(&pgrp)))
+	if e != 0 {
+		return 0, e
+	}
+	return int(pgrp), nil
+}
+
+func tcsetpgrp(f *os.File, pgrp int) error {
+	v := int32(pgrp)
+	_, _, e := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(),
+		syscall.TIOCSPGRP, uintptr(unsafe.Pointer(&v)))
+	if e != 0 {
+		return e
+	}
+	return nil
+}
+
+const (
+	pPid       = 1
+	cldStopped = 5
+)
+
+// Returns true if the process is stopped; otherwise, it has exited and may
+// be reaped.  Without hang, this returns false for a running process.
+func waitStop(pid int, hang bool) (bool, error) {
+	// siginfo_t is 128 bytes with si_code at offset 8
+	var info [32]int32
+	options := syscall.WEXITED | syscall.WSTOPPED | syscall.WNOWAIT
+	if !hang {
+		options |= syscall.WNOHANG
+	}
+	for {
+		_, _, e := syscall.Syscall6(syscall.SYS_WAITID, pPid,
+			uintptr(pid), uintptr(unsafe.Pointer(&info[0])),
+			uintptr(options), 0, 0)
+		if e == syscall.EINTR {
+			continue
+		}
+		if e != 0 {
+			return false, e
+		}
+		return info[2] == cldStopped, nil
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// +build linux
+
+package goes_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/platinasystems/go/goes"
+	"github.com/platinasystems/go/goes/cmd"
+	"github.com/platinasystems/go/goes/cmd/cli"
+	"github.com/platinasystems/go/goes/cmd/echo"
+	"github.com/platinasystems/go/goes/cmd/sleep"
+)
+
+// The shell forks background jobs from this test program; with this
+// variable, it and the jobs act as goes rather than run the tests.
+const testGoesEnv = "GOES_TEST_GOES"
+
+var testGoes = &goes.Goes{
+	NAME: "goes-test",
+	ByName: map[string]cmd.Cmd{
+		"cli":   &cli.Command{},
+		"echo":  echo.Command{},
+		"sleep": sleep.Command{},
+	},
+}
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testGoesEnv) != "" {
+		if err := testGoes.Main(os.Args...); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// Run the script with a goes of this test program and return its output
+// and exit status.
+func runScript(t *testing.T, script string) (string, int) {
+	dir, err := ioutil.TempDir("", "goes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "script")
+	err = ioutil.WriteFile(fn, []byte("#!/usr/bin/goes\n"+script), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := exec.Command(os.Args[0], fn)
+	x.Args[0] = "goes"
+	x.Env = append(os.Environ(), testGoesEnv+"=1")
+	out, err := x.Output()
+	if e, ok := err.(*exec.ExitError); ok {
+		return string(out), e.Sys().(syscall.WaitStatus).ExitStatus()
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return string(out), 0
+}
+
+func TestJobs(t *testing.T) {
+	// the job is gone after wait so the second jobs lists nothing
+	out, status := runScript(t, "sleep 1 &\necho $!\njobs\nwait\njobs\n")
+	if status != 0 {
+		t.Errorf("exit %d; output %q", status, out)
+	}
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("output %q", out)
+	}
+	if pid, err := strconv.Atoi(lines[0]); err != nil || pid <= 0 {
+		t.Errorf("$! %q", lines[0])
+	}
+	if want := "[1]+  Running                 sleep 1 &"; lines[1] != want {
+		t.Errorf("jobs %q, want %q", lines[1], want)
+	}
+}
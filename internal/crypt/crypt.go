@@ -0,0 +1,199 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package crypt implements the SHA-256 ("$5$") and SHA-512 ("$6$") methods of
+// crypt(3) used by /etc/shadow.
+//
+// See: https://www.akkadia.org/drepper/SHA-crypt.txt
+package crypt
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+)
+
+const (
+	RoundsDefault = 5000
+	RoundsMin     = 1000
+	RoundsMax     = 999999999
+	SaltMax       = 16
+)
+
+const b64 = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// Byte triplets of the final digest in their encoded order.
+var (
+	sha256Order = [][3]int{
+		{0, 10, 20}, {21, 1, 11}, {12, 22, 2}, {3, 13, 23},
+		{24, 4, 14}, {15, 25, 5}, {6, 16, 26}, {27, 7, 17},
+		{18, 28, 8}, {9, 19, 29},
+	}
+	sha512Order = [][3]int{
+		{0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45},
+		{25, 46, 4}, {47, 5, 26}, {6, 27, 48}, {28, 49, 7},
+		{50, 8, 29}, {9, 30, 51}, {31, 52, 10}, {53, 11, 32},
+		{12, 33, 54}, {34, 55, 13}, {56, 14, 35}, {15, 36, 57},
+		{37, 58, 16}, {59, 17, 38}, {18, 39, 60}, {40, 61, 19},
+		{62, 20, 41},
+	}
+)
+
+// Crypt returns the key hashed with the method, rounds and salt of the given
+// setting, "$ID$[rounds=N$]SALT[$...]", in the same format.
+func Crypt(key, setting string) (string, error) {
+	var newHash func() hash.Hash
+	var id string
+	switch {
+	case strings.HasPrefix(setting, "$5$"):
+		newHash, id = sha256.New, "$5$"
+	case strings.HasPrefix(setting, "$6$"):
+		newHash, id = sha512.New, "$6$"
+	default:
+		return "", fmt.Errorf("%.3s: unsupported method", setting)
+	}
+	s := setting[len(id):]
+	rounds, customRounds := RoundsDefault, false
+	if strings.HasPrefix(s, "rounds=") {
+		i := strings.IndexByte(s, '$')
+		if i < 0 {
+			return "", fmt.Errorf("%s: missing salt", setting)
+		}
+		n, err := strconv.ParseUint(s[len("rounds="):i], 10, 32)
+		if err != nil {
+			return "", fmt.Errorf("%s: rounds: %v", setting, err)
+		}
+		switch {
+		case n < RoundsMin:
+			rounds = RoundsMin
+		case n > RoundsMax:
+			rounds = RoundsMax
+		default:
+			rounds = int(n)
+		}
+		customRounds = true
+		s = s[i+1:]
+	}
+	if i := strings.IndexByte(s, '$'); i >= 0 {
+		s = s[:i]
+	}
+	if len(s) > SaltMax {
+		s = s[:SaltMax]
+	}
+	salt := []byte(s)
+	digest := shacrypt(newHash, []byte(key), salt, rounds)
+
+	buf := make([]byte, 0, 128)
+	buf = append(buf, id...)
+	if customRounds {
+		buf = append(buf, "rounds="...)
+		buf = strconv.AppendInt(buf, int64(rounds), 10)
+		buf = append(buf, '$')
+	}
+	buf = append(buf, salt...)
+	buf = append(buf, '$')
+	if len(digest) == sha256.Size {
+		for _, t := range sha256Order {
+			buf = encode(buf, digest[t[0]], digest[t[1]],
+				digest[t[2]], 4)
+		}
+		buf = encode(buf, 0, digest[31], digest[30], 3)
+	} else {
+		for _, t := range sha512Order {
+			buf = encode(buf, digest[t[0]], digest[t[1]],
+				digest[t[2]], 4)
+		}
+		buf = encode(buf, 0, 0, digest[63], 2)
+	}
+	return string(buf), nil
+}
+
+// Verify returns true if the key hashes to the given crypt(3) string.
+func Verify(key, hashed string) bool {
+	s, err := Crypt(key, hashed)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(s), []byte(hashed)) == 1
+}
+
+func shacrypt(newHash func() hash.Hash, key, salt []byte, rounds int) []byte {
+	h := newHash()
+	h.Write(key)
+	h.Write(salt)
+	h.Write(key)
+	b := h.Sum(nil)
+
+	h.Reset()
+	h.Write(key)
+	h.Write(salt)
+	h.Write(repeat(b, len(key)))
+	for n := len(key); n > 0; n >>= 1 {
+		if n&1 != 0 {
+			h.Write(b)
+		} else {
+			h.Write(key)
+		}
+	}
+	a := h.Sum(nil)
+
+	h.Reset()
+	for range key {
+		h.Write(key)
+	}
+	p := repeat(h.Sum(nil), len(key))
+
+	h.Reset()
+	for i := 0; i < 16+int(a[0]); i++ {
+		h.Write(salt)
+	}
+	s := repeat(h.Sum(nil), len(salt))
+
+	for i := 0; i < rounds; i++ {
+		h.Reset()
+		if i&1 != 0 {
+			h.Write(p)
+		} else {
+			h.Write(a)
+		}
+		if i%3 != 0 {
+			h.Write(s)
+		}
+		if i%7 != 0 {
+			h.Write(p)
+		}
+		if i&1 != 0 {
+			h.Write(a)
+		} else {
+			h.Write(p)
+		}
+		a = h.Sum(a[:0])
+	}
+	return a
+}
+
+// Returns n bytes of b repeated.
+func repeat(b []byte, n int) []byte {
+	r := make([]byte, 0, n)
+	for len(r) < n {
+		if n-len(r) < len(b) {
+			b = b[:n-len(r)]
+		}
+		r = append(r, b...)
+	}
+	return r
+}
+
+func encode(buf []byte, b2, b1, b0 byte, n int) []byte {
+	w := uint(b2)<<16 | uint(b1)<<8 | uint(b0)
+	for ; n > 0; n-- {
+		buf = append(buf, b64[w&0x3f])
+		w >>= 6
+	}
+	return buf
+}
@@ -0,0 +1,67 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package crypt
+
+import "testing"
+
+var vectors = []struct {
+	setting, key, expect string
+}{
+	{
+		"$5$saltstring",
+		"Hello world!",
+		"$5$saltstring$5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZaBBGWEc5",
+	},
+	{
+		"$5$rounds=10000$saltstringsaltstring",
+		"Hello world!",
+		"$5$rounds=10000$saltstringsaltst$3xv.VbSHBb41AL9AvLeujZkZRBAwqFMz2.opqey6IcA",
+	},
+	{
+		"$5$",
+		"",
+		"$5$$3c2QQ0KjIU1OLtB29cl8Fplc2WN7X89bnoEjaR7tWu.",
+	},
+	{
+		"$6$saltstring",
+		"Hello world!",
+		"$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1",
+	},
+	{
+		"$6$rounds=1400$anotherlongsaltstring",
+		"a very much longer text to encrypt.  This one even stretches over morethan one line.",
+		"$6$rounds=1400$anotherlongsalts$POfYwTEok97VWcjxIiSOjiykti.o/pQs.wPvMxQ6Fm7I6IoYN3CmLs66x9t0oSwbtEW7o7UmJEiDwGqd8p4ur1",
+	},
+	{
+		"$6$rounds=10$roundstoolow",
+		"the minimum number is still observed",
+		"$6$rounds=1000$roundstoolow$kUMsbe306n21p9R.FRkW3IGn.S9NPN0x50YhH1xhLsPuWGsUSklZt58jaTfF4ZEQpyUNGc0dqbpBYYBaHHrsX.",
+	},
+}
+
+func TestCrypt(t *testing.T) {
+	for _, v := range vectors {
+		s, err := Crypt(v.key, v.setting)
+		if err != nil {
+			t.Error(v.setting, err)
+		} else if s != v.expect {
+			t.Errorf("%s: %q\n\texpected: %q", v.setting, s, v.expect)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	for _, v := range vectors {
+		if !Verify(v.key, v.expect) {
+			t.Error(v.expect, "didn't verify")
+		}
+		if Verify(v.key+"x", v.expect) {
+			t.Error(v.expect, "verified the wrong key")
+		}
+	}
+	if Verify("", "!") {
+		t.Error("verified locked account")
+	}
+}
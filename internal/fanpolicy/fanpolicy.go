@@ -0,0 +1,373 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package fanpolicy is a closed-loop fan controller of thermal zones
+// configured by a YAML policy rather than goes build.
+//
+// Each zone has the temperature of its hottest input, a redis field or
+// pattern of fields, and a piecewise-linear curve or PID controller of fan
+// duty.  The fans run at the duty of the hottest zone limited to the
+// policy's min and max; or at its failsafe duty if any failure field, e.g.
+// fan_tray.*.status, isn't "ok".
+//
+//	failsafe: 100
+//	min: 30
+//	failures: [fan_tray.*.status]
+//	zones:
+//	- name: board
+//	  inputs: [hwmon.front.temp.units.C, hwmon.rear.temp.units.C]
+//	  curve: [[35, 30], [50, 60], [60, 100]]
+//	  hysteresis: 3
+//	- name: qsfp
+//	  inputs: [port-*.qsfp.temperature.units.C]
+//	  pid: {setpoint: 55, kp: 5, ki: 0.1}
+//	  missing: 0
+package fanpolicy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const DefaultFile = "/etc/goes/fan-policy.yaml"
+
+type Policy struct {
+	// Duty, in percent, with failed fans or zones without inputs;
+	// default 100.
+	Failsafe float64 `yaml:"failsafe"`
+	// Limits of the duty, default 0 and 100.
+	Min float64 `yaml:"min"`
+	Max float64 `yaml:"max"`
+	// Fields, or patterns, that are failures if not "ok".
+	Failures []string `yaml:"failures"`
+	Zones    []Zone   `yaml:"zones"`
+}
+
+type Zone struct {
+	Name string `yaml:"name"`
+	// Fields, or patterns, of temperatures in °C.
+	Inputs []string `yaml:"inputs"`
+	// Curve of [temperature, duty] points in increasing temperature.
+	// The duty is that of the first point below it and of the last
+	// point above.
+	Curve [][]float64 `yaml:"curve"`
+	PID   *PID        `yaml:"pid"`
+	// Degrees that the temperature must fall below that of the last
+	// duty increase before it may decrease.
+	Hysteresis float64 `yaml:"hysteresis"`
+	// Duty without inputs, default Failsafe.
+	Missing *float64 `yaml:"missing"`
+}
+
+// PID controls the duty, in percent, by the error of the temperature over
+// its setpoint.
+type PID struct {
+	Setpoint float64 `yaml:"setpoint"`
+	Kp       float64 `yaml:"kp"`
+	Ki       float64 `yaml:"ki"`
+	Kd       float64 `yaml:"kd"`
+}
+
+// Load the named policy file.
+func Load(fn string) (*Policy, error) {
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	p, err := Parse(b)
+	if err != nil {
+		err = fmt.Errorf("%s: %v", fn, err)
+	}
+	return p, err
+}
+
+// Parse and validate a YAML policy.
+func Parse(b []byte) (*Policy, error) {
+	p := new(Policy)
+	if err := yaml.UnmarshalStrict(b, p); err != nil {
+		return nil, err
+	}
+	if p.Failsafe == 0 {
+		p.Failsafe = 100
+	}
+	if p.Max == 0 {
+		p.Max = 100
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Policy) validate() error {
+	if err := duty(p.Failsafe, "failsafe"); err != nil {
+		return err
+	}
+	if err := duty(p.Min, "min"); err != nil {
+		return err
+	}
+	if err := duty(p.Max, "max"); err != nil {
+		return err
+	}
+	if p.Min > p.Max {
+		return fmt.Errorf("min exceeds max")
+	}
+	if len(p.Zones) == 0 {
+		return fmt.Errorf("no zones")
+	}
+	names := make(map[string]bool)
+	for _, z := range p.Zones {
+		if len(z.Name) == 0 {
+			return fmt.Errorf("zone without name")
+		}
+		if names[z.Name] {
+			return fmt.Errorf("%s: duplicate zone", z.Name)
+		}
+		names[z.Name] = true
+		if err := z.validate(); err != nil {
+			return fmt.Errorf("%s: %v", z.Name, err)
+		}
+	}
+	return nil
+}
+
+func (z *Zone) validate() error {
+	if len(z.Inputs) == 0 {
+		return fmt.Errorf("no inputs")
+	}
+	if (len(z.Curve) > 0) == (z.PID != nil) {
+		return fmt.Errorf("need either curve or pid")
+	}
+	for i, pt := range z.Curve {
+		if len(pt) != 2 {
+			return fmt.Errorf("curve point %d isn't [temp, duty]", i)
+		}
+		if err := duty(pt[1], "curve duty"); err != nil {
+			return err
+		}
+		if i > 0 && pt[0] <= z.Curve[i-1][0] {
+			return fmt.Errorf("curve temperatures must increase")
+		}
+	}
+	if z.Hysteresis < 0 {
+		return fmt.Errorf("negative hysteresis")
+	}
+	if z.Missing != nil {
+		return duty(*z.Missing, "missing")
+	}
+	return nil
+}
+
+func duty(v float64, name string) error {
+	if v < 0 || v > 100 {
+		return fmt.Errorf("%s: %v%% out of range", name, v)
+	}
+	return nil
+}
+
+// An Engine runs the policy with the state of its zones.
+type Engine struct {
+	Policy *Policy
+	zones  []zoneState
+}
+
+type zoneState struct {
+	primed   bool
+	duty     float64
+	peak     float64
+	last     float64
+	integral float64
+}
+
+// A Decision has the fan duty, in percent, its reason, and the inputs and
+// duty of each zone.
+type Decision struct {
+	Duty   float64
+	Reason string
+	Zones  []ZoneDecision
+}
+
+type ZoneDecision struct {
+	Name  string
+	Valid bool
+	Temp  float64
+	Duty  float64
+}
+
+func New(p *Policy) *Engine {
+	return &Engine{
+		Policy: p,
+		zones:  make([]zoneState, len(p.Zones)),
+	}
+}
+
+// Step the engine with the current redis fields and the time since the
+// last step.
+func (e *Engine) Step(fields map[string]string, dt time.Duration) *Decision {
+	p := e.Policy
+	d := &Decision{
+		Reason: "min",
+		Zones:  make([]ZoneDecision, len(p.Zones)),
+	}
+	for i := range p.Zones {
+		z := &p.Zones[i]
+		zd := &d.Zones[i]
+		zd.Name = z.Name
+		zd.Temp, zd.Valid = z.input(fields)
+		if zd.Valid {
+			zd.Duty = e.zones[i].step(z, zd.Temp, dt)
+		} else if z.Missing != nil {
+			zd.Duty = *z.Missing
+		} else {
+			zd.Duty = p.Failsafe
+		}
+		if zd.Duty > d.Duty {
+			d.Duty = zd.Duty
+			d.Reason = z.Name
+			if !zd.Valid {
+				d.Reason += " missing"
+			}
+		}
+	}
+	if d.Duty < p.Min {
+		d.Duty = p.Min
+		d.Reason = "min"
+	} else if d.Duty > p.Max {
+		d.Duty = p.Max
+		d.Reason = "max"
+	}
+	if failure := p.failure(fields); len(failure) > 0 {
+		d.Duty = p.Failsafe
+		d.Reason = "failsafe " + failure
+	}
+	return d
+}
+
+// PWM returns the duty scaled to 0 through 0xff.
+func (d *Decision) PWM() uint8 {
+	return uint8(d.Duty*0xff/100 + 0.5)
+}
+
+// Fields returns the decision in redis fields with the given prefix.
+func (d *Decision) Fields(prefix string) map[string]string {
+	m := map[string]string{
+		prefix + "duty.units.percent": format(d.Duty),
+		prefix + "reason":             d.Reason,
+	}
+	for _, zd := range d.Zones {
+		zp := prefix + "zone." + zd.Name + "."
+		m[zp+"duty.units.percent"] = format(zd.Duty)
+		if zd.Valid {
+			m[zp+"temp.units.C"] = format(zd.Temp)
+		} else {
+			m[zp+"temp.units.C"] = "missing"
+		}
+	}
+	return m
+}
+
+func format(f float64) string {
+	return strconv.FormatFloat(f, 'f', 2, 64)
+}
+
+// Returns the hottest input.
+func (z *Zone) input(fields map[string]string) (t float64, valid bool) {
+	for _, k := range match(fields, z.Inputs) {
+		v, err := strconv.ParseFloat(strings.TrimSpace(fields[k]), 64)
+		if err != nil {
+			continue
+		}
+		if !valid || v > t {
+			t, valid = v, true
+		}
+	}
+	return
+}
+
+// Returns the first failure field and value; a working status begins with
+// "ok", e.g. "ok.front->back".
+func (p *Policy) failure(fields map[string]string) string {
+	for _, k := range match(fields, p.Failures) {
+		v := fields[k]
+		if len(v) > 0 && !strings.HasPrefix(v, "ok") {
+			return k + ": " + v
+		}
+	}
+	return ""
+}
+
+// Returns the sorted fields matching any of the patterns.
+func match(fields map[string]string, patterns []string) []string {
+	var keys []string
+	for k := range fields {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, k); ok {
+				keys = append(keys, k)
+				break
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (st *zoneState) step(z *Zone, t float64, dt time.Duration) float64 {
+	var duty float64
+	if z.PID != nil {
+		duty = st.pid(z.PID, t, dt)
+	} else {
+		duty = curve(z.Curve, t)
+	}
+	// hold the duty until the temperature falls by the hysteresis
+	switch {
+	case !st.primed, duty >= st.duty, t <= st.peak-z.Hysteresis:
+		st.primed = true
+		st.duty = duty
+		st.peak = t
+	}
+	return st.duty
+}
+
+func curve(pts [][]float64, t float64) float64 {
+	if t <= pts[0][0] {
+		return pts[0][1]
+	}
+	for i := 1; i < len(pts); i++ {
+		t0, d0 := pts[i-1][0], pts[i-1][1]
+		t1, d1 := pts[i][0], pts[i][1]
+		if t <= t1 {
+			return d0 + (d1-d0)*(t-t0)/(t1-t0)
+		}
+	}
+	return pts[len(pts)-1][1]
+}
+
+func (st *zoneState) pid(pid *PID, t float64, dt time.Duration) float64 {
+	secs := dt.Seconds()
+	e := t - pid.Setpoint
+	deriv := 0.0
+	if st.primed && secs > 0 {
+		deriv = (t - st.last) / secs
+	}
+	st.last = t
+	integral := st.integral + e*secs
+	out := pid.Kp*e + pid.Ki*integral + pid.Kd*deriv
+	// only integrate while unsaturated to avoid windup
+	switch {
+	case out > 100:
+		out = 100
+	case out < 0:
+		out = 0
+	default:
+		st.integral = integral
+	}
+	return out
+}
@@ -0,0 +1,147 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package fanpolicy
+
+import (
+	"testing"
+	"time"
+)
+
+const policy = `
+min: 30
+failures: [fan_tray.*.status]
+zones:
+- name: board
+  inputs: [hwmon.front.temp.units.C, hwmon.rear.temp.units.C]
+  curve: [[35, 30], [55, 70], [65, 100]]
+  hysteresis: 3
+- name: qsfp
+  inputs: [port-*.qsfp.temperature.units.C]
+  pid: {setpoint: 55, kp: 10, ki: 2}
+  missing: 0
+`
+
+func TestCurve(t *testing.T) {
+	p, err := Parse([]byte(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := New(p)
+	fields := map[string]string{
+		"hwmon.front.temp.units.C": "20.0",
+		"hwmon.rear.temp.units.C":  "45.0",
+		"fan_tray.1.status":        "ok.front->back",
+	}
+	for _, x := range []struct {
+		rear   string
+		duty   float64
+		reason string
+	}{
+		{"45.0", 50, "board"},
+		{"25.0", 30, "board"},
+		{"55.0", 70, "board"},
+		{"53.0", 70, "board"}, // hysteresis holds 70%
+		{"52.0", 64, "board"},
+		{"70.0", 100, "board"},
+	} {
+		fields["hwmon.rear.temp.units.C"] = x.rear
+		d := e.Step(fields, 10*time.Second)
+		if d.Duty != x.duty || d.Reason != x.reason {
+			t.Errorf("rear %s: duty %v %q, expected %v %q",
+				x.rear, d.Duty, d.Reason, x.duty, x.reason)
+		}
+	}
+}
+
+func TestFailure(t *testing.T) {
+	p := &Policy{Failures: []string{"fan_tray.*.status"}}
+	for _, x := range []struct {
+		status, failure string
+	}{
+		{"ok.front->back", ""},
+		{"ok", ""},
+		{"", ""},
+		{"not installed", "fan_tray.3.status: not installed"},
+		{"not ok", "fan_tray.3.status: not ok"},
+		{"broken", "fan_tray.3.status: broken"},
+	} {
+		fields := map[string]string{
+			"fan_tray.1.status": "ok.back->front",
+			"fan_tray.3.status": x.status,
+		}
+		if s := p.failure(fields); s != x.failure {
+			t.Errorf("%q: got %q, expected %q", x.status, s,
+				x.failure)
+		}
+	}
+}
+
+func TestPID(t *testing.T) {
+	p, err := Parse([]byte(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := New(p)
+	fields := map[string]string{
+		"hwmon.front.temp.units.C":        "20",
+		"port-1.qsfp.temperature.units.C": "50",
+		"port-7.qsfp.temperature.units.C": "60",
+	}
+	d := e.Step(fields, 10*time.Second)
+	// kp*5 + ki*5*10 saturates
+	if d.Duty != 100 || d.Reason != "qsfp" {
+		t.Errorf("hot: duty %v %q", d.Duty, d.Reason)
+	}
+	if e.zones[1].integral != 0 {
+		t.Error("integral wound up while saturated")
+	}
+	fields["port-7.qsfp.temperature.units.C"] = "57"
+	d = e.Step(fields, 10*time.Second)
+	// kp*2 + ki*2*10
+	if d.Zones[1].Duty != 60 {
+		t.Errorf("warm: zone duty %v", d.Zones[1].Duty)
+	}
+	delete(fields, "port-1.qsfp.temperature.units.C")
+	delete(fields, "port-7.qsfp.temperature.units.C")
+	d = e.Step(fields, 10*time.Second)
+	if d.Zones[1].Valid || d.Zones[1].Duty != 0 || d.Duty != 30 {
+		t.Errorf("missing: %+v", d)
+	}
+	if f := d.Fields("fan_policy."); f["fan_policy.zone.qsfp.temp.units.C"] != "missing" ||
+		f["fan_policy.duty.units.percent"] != "30.00" {
+		t.Error("fields:", f)
+	}
+}
+
+func TestMissing(t *testing.T) {
+	p, err := Parse([]byte(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := New(p).Step(map[string]string{}, time.Second)
+	if d.Duty != 100 || d.Reason != "board missing" {
+		t.Errorf("duty %v %q", d.Duty, d.Reason)
+	}
+	if d.PWM() != 0xff {
+		t.Errorf("pwm %#x", d.PWM())
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	for _, bad := range []string{
+		"zones: []",
+		"zones: [{name: a, inputs: [x]}]",
+		"zones: [{name: a, inputs: [x], curve: [[1, 2]], pid: {kp: 1}}]",
+		"zones: [{name: a, inputs: [x], curve: [[2, 2], [1, 3]]}]",
+		"zones: [{name: a, inputs: [x], curve: [[1, 200]]}]",
+		"zones: [{name: a, curve: [[1, 2]]}]",
+		"min: 80\nmax: 50\nzones: [{name: a, inputs: [x], curve: [[1, 2]]}]",
+		"bogus: 1\nzones: [{name: a, inputs: [x], curve: [[1, 2]]}]",
+	} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Errorf("%q: parsed", bad)
+		}
+	}
+}
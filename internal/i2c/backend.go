@@ -0,0 +1,23 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package i2c
+
+// A Backend performs the SMBus transfers of a Bus in place of the Linux
+// /dev/i2c-X devices, e.g. the simulator of package i2c/sim.
+type Backend interface {
+	// Features returns the functionality of the indexed bus or an error
+	// if it doesn't exist.
+	Features(index int) (FeatureFlag, error)
+	// ReadWrite performs an SMBus transfer with the addressed slave
+	// with the same data format as the I2C_SMBUS ioctl.
+	ReadWrite(index, address int, rw RW, command uint8, size SMBusSize,
+		data *SMBusData) error
+}
+
+var backend Backend
+
+// Use the given backend for subsequently opened buses; nil restores the
+// Linux devices.
+func Use(b Backend) { backend = b }
@@ -0,0 +1,70 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package i2c
+
+import (
+	"fmt"
+	"net/rpc"
+
+	"github.com/platinasystems/go/internal/atsock"
+)
+
+// Name of the i2cd socket, "@MACHINE/i2cd"
+const ServerName = "i2cd"
+
+// A Client performs transactions with i2cd.
+type Client struct {
+	*rpc.Client
+	name string
+}
+
+// Dial i2cd as the named client, the holder of any bus locks.
+func Dial(name string) (*Client, error) {
+	c, err := atsock.NewRpcClient(ServerName)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{c, name}, nil
+}
+
+// Do the transaction and return its results.  The error is that of the
+// failed operation, the last result, or of the call itself.
+func (c *Client) Do(t *Txn) ([]Result, error) {
+	var reply Reply
+	if len(t.Client) == 0 {
+		t.Client = c.name
+	}
+	if t.Version == 0 {
+		t.Version = Version
+	}
+	if err := c.Call("I2c.Do", t, &reply); err != nil {
+		return nil, err
+	}
+	if reply.Version != Version {
+		return nil, fmt.Errorf("i2cd version %d, expected %d",
+			reply.Version, Version)
+	}
+	return reply.Results, Err(reply.Results)
+}
+
+// Release the buses held by this client.
+func (c *Client) Release() error {
+	var reply struct{}
+	return c.Call("I2c.Release", &c.name, &reply)
+}
+
+// Stop, or restart, polling of the hardware daemons, e.g. during an
+// upgrade.
+func (c *Client) Stop(stop bool) error {
+	var prev bool
+	return c.Call("I2c.Stop", &stop, &prev)
+}
+
+// Stopped returns true while polling is stopped.
+func (c *Client) Stopped() (bool, error) {
+	var stopped bool
+	err := c.Call("I2c.Stopped", &stopped, &stopped)
+	return stopped, err
+}
@@ -34,6 +34,12 @@ type Bus struct {
 	fd int
 
 	features FeatureFlag
+
+	// Non-nil if opened with a Backend other than /dev/i2c-INDEX
+	backend Backend
+
+	// Slave address of backend transfers
+	address int
 }
 
 func New(index, address int) (*Bus, error) {
@@ -46,6 +52,12 @@ func New(index, address int) (*Bus, error) {
 }
 
 func (b *Bus) Open(index int) (err error) {
+	if backend != nil {
+		b.backend = backend
+		b.index = index
+		b.features, err = backend.Features(index)
+		return
+	}
 	path := fmt.Sprintf("/dev/i2c-%d", index)
 	fd, err := syscall.Open(path, syscall.O_RDWR, 0)
 	if err != nil {
@@ -70,6 +82,9 @@ func (b *Bus) Open(index int) (err error) {
 }
 
 func (b *Bus) Close() (err error) {
+	if b.backend != nil {
+		return
+	}
 	err = syscall.Close(b.fd)
 	return
 }
@@ -88,6 +103,12 @@ func Do(index, slave int, f func(bus *Bus) error) (err error) {
 }
 
 func ioctlInt(b *Bus, op IoctlOp, arg int) (err error) {
+	if b.backend != nil {
+		if op == I2C_SLAVE || op == I2C_SLAVE_FORCE {
+			b.address = arg
+		}
+		return
+	}
 	_, _, e := syscall.RawSyscall(syscall.SYS_IOCTL, uintptr(b.fd), uintptr(op), uintptr(arg))
 	if e != 0 {
 		err = e
@@ -116,6 +137,9 @@ func (b *Bus) Set7BitAddressing() error {
 }
 
 func (b *Bus) GetFeatures() (mask FeatureFlag, err error) {
+	if b.backend != nil {
+		return b.backend.Features(b.index)
+	}
 	var flags [1]uintptr
 	_, _, e := syscall.RawSyscall(syscall.SYS_IOCTL, uintptr(b.fd), uintptr(I2C_FUNCS), uintptr(unsafe.Pointer(&flags[0])))
 	if e != 0 {
@@ -257,6 +281,10 @@ func (b *Bus) ReadWrite(rw RW, command uint8, size SMBusSize, data *SMBusData) (
 	if rw == Read {
 		cmd.isRead = 1
 	}
+	if b.backend != nil {
+		return b.backend.ReadWrite(b.index, b.address, rw, command,
+			size, data)
+	}
 	_, _, e := syscall.RawSyscall(syscall.SYS_IOCTL, uintptr(b.fd), uintptr(I2C_SMBUS), uintptr(unsafe.Pointer(&cmd)))
 	if e != 0 {
 		err = e
@@ -327,6 +355,9 @@ func (b *Bus) Send(messages []Message) (err error) {
 	if l > len(ms) {
 		return fmt.Errorf("too many messages: max %d", len(ms))
 	}
+	if b.backend != nil {
+		return fmt.Errorf("send: unsupported by %T", b.backend)
+	}
 
 	for i := 0; i < l; i++ {
 		ms[i].address = messages[i].Address
@@ -0,0 +1,452 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package sim
+
+import (
+	"syscall"
+
+	"github.com/platinasystems/go/internal/i2c"
+)
+
+// Regs is a file of 256 byte registers.  Word and block transfers access
+// consecutive registers as do most sensor chips.
+type Regs struct {
+	R [256]byte
+
+	// OnRead, if non-nil, is called before a register is read so that
+	// tests may model changing values, e.g. fan tachometers.
+	OnRead func(reg uint8)
+	// OnWrite, if non-nil, is called after a register is written.
+	OnWrite func(reg uint8, v byte)
+
+	ptr uint8
+}
+
+func NewRegs() *Regs { return new(Regs) }
+
+func (r *Regs) get(reg uint8) byte {
+	if r.OnRead != nil {
+		r.OnRead(reg)
+	}
+	return r.R[reg]
+}
+
+func (r *Regs) set(reg uint8, v byte) {
+	r.R[reg] = v
+	if r.OnWrite != nil {
+		r.OnWrite(reg, v)
+	}
+}
+
+func (r *Regs) ReadWrite(rw i2c.RW, command uint8, size i2c.SMBusSize,
+	data *i2c.SMBusData) error {
+	return readWrite(r.get, r.set, &r.ptr, rw, command, size, data)
+}
+
+func (r *Regs) Set(page int, reg uint8, b ...byte) error {
+	if page != 0 || int(reg)+len(b) > len(r.R) {
+		return syscall.EINVAL
+	}
+	copy(r.R[reg:], b)
+	return nil
+}
+
+// Transfer with a device of consecutive byte registers.
+func readWrite(get func(uint8) byte, set func(uint8, byte), ptr *uint8,
+	rw i2c.RW, command uint8, size i2c.SMBusSize,
+	data *i2c.SMBusData) error {
+	switch size {
+	case i2c.Quick:
+	case i2c.Byte:
+		if rw == i2c.Write {
+			*ptr = command
+		} else {
+			data[0] = get(*ptr)
+			*ptr++
+		}
+	case i2c.ByteData:
+		if rw == i2c.Write {
+			set(command, data[0])
+		} else {
+			data[0] = get(command)
+		}
+		*ptr = command + 1
+	case i2c.WordData:
+		for i := uint8(0); i < 2; i++ {
+			if rw == i2c.Write {
+				set(command+i, data[i])
+			} else {
+				data[i] = get(command + i)
+			}
+		}
+		*ptr = command + 2
+	case i2c.I2CBlockData:
+		n := int(data[0])
+		if n > i2c.BlockMax {
+			return syscall.EINVAL
+		}
+		for i := 0; i < n; i++ {
+			if rw == i2c.Write {
+				set(command+uint8(i), data[1+i])
+			} else {
+				data[1+i] = get(command + uint8(i))
+			}
+		}
+		*ptr = command + uint8(n)
+	default:
+		return syscall.EOPNOTSUPP
+	}
+	return nil
+}
+
+// Banked registers are selected by the low bits of the Sel register, e.g.
+// the BANKSEL register of the W83795.
+type Banked struct {
+	Sel   uint8
+	Mask  byte
+	Banks []*Regs
+
+	sel byte
+}
+
+// NewW83795 returns a W83795 hardware monitor with its vendor and chip ids.
+func NewW83795() *Banked {
+	b := &Banked{Sel: 0, Mask: 0x07, Banks: make([]*Regs, 8)}
+	for i := range b.Banks {
+		b.Banks[i] = NewRegs()
+	}
+	b.Banks[0].R[0xfd] = 0xa3 // vendor id low byte, HBACS clear
+	b.Banks[0].R[0xfe] = 0x79 // chip id
+	return b
+}
+
+func (b *Banked) ReadWrite(rw i2c.RW, command uint8, size i2c.SMBusSize,
+	data *i2c.SMBusData) error {
+	if command == b.Sel && size == i2c.ByteData {
+		if rw == i2c.Write {
+			b.sel = data[0]
+		} else {
+			data[0] = b.sel
+		}
+		return nil
+	}
+	bank := int(b.sel & b.Mask)
+	if bank >= len(b.Banks) {
+		return syscall.EINVAL
+	}
+	return b.Banks[bank].ReadWrite(rw, command, size, data)
+}
+
+func (b *Banked) Set(page int, reg uint8, v ...byte) error {
+	if page < 0 || page >= len(b.Banks) {
+		return syscall.EINVAL
+	}
+	return b.Banks[page].Set(0, reg, v...)
+}
+
+// PMBus devices have paged byte, word and block commands.  Command 0 is the
+// PAGE select.  Unset commands read zero.
+type PMBus struct {
+	Pages []PMBusPage
+
+	page byte
+}
+
+type PMBusPage struct {
+	Byte  map[uint8]byte
+	Word  map[uint8]uint16
+	Block map[uint8][]byte
+}
+
+const pmbusPage = 0x00
+
+// NewPMBus returns a device with the given number of pages.
+func NewPMBus(pages int) *PMBus {
+	p := &PMBus{Pages: make([]PMBusPage, pages)}
+	for i := range p.Pages {
+		p.Pages[i] = PMBusPage{
+			Byte:  make(map[uint8]byte),
+			Word:  make(map[uint8]uint16),
+			Block: make(map[uint8][]byte),
+		}
+	}
+	return p
+}
+
+// NewUCD9090 returns a UCD9090 power sequencer with 10 rails.
+func NewUCD9090() *PMBus {
+	p := NewPMBus(10)
+	for i := range p.Pages {
+		p.Pages[i].Byte[0x20] = 0x17 // VOUT_MODE, exponent -9
+		p.Pages[i].Block[0x99] = []byte("TI")
+		p.Pages[i].Block[0x9a] = []byte("UCD9090")
+	}
+	return p
+}
+
+func (p *PMBus) ReadWrite(rw i2c.RW, command uint8, size i2c.SMBusSize,
+	data *i2c.SMBusData) error {
+	if command == pmbusPage && size == i2c.ByteData {
+		if rw == i2c.Write {
+			p.page = data[0]
+		} else {
+			data[0] = p.page
+		}
+		return nil
+	}
+	if int(p.page) >= len(p.Pages) {
+		return syscall.EINVAL
+	}
+	pg := &p.Pages[p.page]
+	switch size {
+	case i2c.Quick, i2c.Byte:
+	case i2c.ByteData:
+		if rw == i2c.Write {
+			pg.Byte[command] = data[0]
+		} else {
+			data[0] = pg.Byte[command]
+		}
+	case i2c.WordData:
+		if rw == i2c.Write {
+			pg.Word[command] = uint16(data[0]) | uint16(data[1])<<8
+		} else {
+			w := pg.Word[command]
+			data[0], data[1] = byte(w), byte(w>>8)
+		}
+	case i2c.BlockData:
+		if rw == i2c.Write {
+			n := int(data[0])
+			if n > i2c.BlockMax {
+				return syscall.EINVAL
+			}
+			pg.Block[command] = append([]byte{}, data[1:1+n]...)
+		} else {
+			b := pg.Block[command]
+			data[0] = byte(copy(data[1:1+i2c.BlockMax], b))
+		}
+	default:
+		return syscall.EOPNOTSUPP
+	}
+	return nil
+}
+
+// Set stores a byte, little endian word, or block command by the length of
+// the given value.
+func (p *PMBus) Set(page int, reg uint8, b ...byte) error {
+	if page < 0 || page >= len(p.Pages) {
+		return syscall.EINVAL
+	}
+	pg := &p.Pages[page]
+	switch len(b) {
+	case 1:
+		pg.Byte[reg] = b[0]
+	case 2:
+		pg.Word[reg] = uint16(b[0]) | uint16(b[1])<<8
+	default:
+		pg.Block[reg] = append([]byte{}, b...)
+	}
+	return nil
+}
+
+// EEPROM models the SFF-8636 memory map of QSFP modules with 128 lower
+// bytes and the upper pages selected by byte 127.  Absent pages read 0xff.
+type EEPROM struct {
+	Lower [128]byte
+	Upper map[byte]*[128]byte
+
+	ptr uint8
+}
+
+const eepromPageSel = 127
+
+// NewQSFP returns a module with upper page 0 and a QSFP identifier.
+func NewQSFP() *EEPROM {
+	e := &EEPROM{Upper: make(map[byte]*[128]byte)}
+	e.Upper[0] = new([128]byte)
+	e.Lower[0] = 0x0d    // QSFP+ or later
+	e.Upper[0][0] = 0x0d // identifier copy
+	return e
+}
+
+func (e *EEPROM) get(off uint8) byte {
+	if off < 128 {
+		return e.Lower[off]
+	}
+	page := e.Upper[e.Lower[eepromPageSel]]
+	if page == nil {
+		return 0xff
+	}
+	return page[off-128]
+}
+
+func (e *EEPROM) set(off uint8, v byte) {
+	if off < 128 {
+		e.Lower[off] = v
+	} else if page := e.Upper[e.Lower[eepromPageSel]]; page != nil {
+		page[off-128] = v
+	}
+}
+
+func (e *EEPROM) ReadWrite(rw i2c.RW, command uint8, size i2c.SMBusSize,
+	data *i2c.SMBusData) error {
+	return readWrite(e.get, e.set, &e.ptr, rw, command, size, data)
+}
+
+// Set the lower bytes if reg is less than 128, otherwise those of the
+// given upper page, adding it if absent.
+func (e *EEPROM) Set(page int, reg uint8, b ...byte) error {
+	if page < 0 || page > 0xff {
+		return syscall.EINVAL
+	}
+	for _, v := range b {
+		if reg < 128 {
+			e.Lower[reg] = v
+		} else {
+			p := e.Upper[byte(page)]
+			if p == nil {
+				p = new([128]byte)
+				e.Upper[byte(page)] = p
+			}
+			p[reg-128] = v
+		}
+		reg++
+		if reg == 0 {
+			break
+		}
+	}
+	return nil
+}
+
+// GPIO models the PCA95xx expanders with 8 bit input, output, polarity
+// inversion and configuration registers per port.  Pins configured as
+// outputs read their output value.
+type GPIO struct {
+	In, Out, Pol, Cfg []byte
+}
+
+const (
+	gpioIn = iota
+	gpioOut
+	gpioPol
+	gpioCfg
+)
+
+// NewPCA9534 returns an 8 bit expander, also the PCA9554.
+func NewPCA9534() *GPIO { return newGPIO(1) }
+
+// NewPCA9535 returns a 16 bit expander, also the PCA9539 and PCA9555.
+func NewPCA9535() *GPIO { return newGPIO(2) }
+
+func newGPIO(ports int) *GPIO {
+	g := &GPIO{
+		In:  make([]byte, ports),
+		Out: make([]byte, ports),
+		Pol: make([]byte, ports),
+		Cfg: make([]byte, ports),
+	}
+	for i := 0; i < ports; i++ {
+		g.Out[i] = 0xff
+		g.Cfg[i] = 0xff
+	}
+	return g
+}
+
+func (g *GPIO) reg(reg uint8) (port int, r []byte) {
+	n := len(g.In)
+	port = int(reg) % n
+	switch int(reg) / n {
+	case gpioIn:
+		r = g.In
+	case gpioOut:
+		r = g.Out
+	case gpioPol:
+		r = g.Pol
+	case gpioCfg:
+		r = g.Cfg
+	}
+	return
+}
+
+func (g *GPIO) get(reg uint8) byte {
+	port, r := g.reg(reg)
+	switch {
+	case r == nil:
+		return 0xff
+	case int(reg)/len(g.In) == gpioIn:
+		in := (g.In[port] ^ g.Pol[port]) & g.Cfg[port]
+		return in | g.Out[port]&^g.Cfg[port]
+	default:
+		return r[port]
+	}
+}
+
+func (g *GPIO) set(reg uint8, v byte) {
+	port, r := g.reg(reg)
+	if r != nil && int(reg)/len(g.In) != gpioIn {
+		r[port] = v
+	}
+}
+
+func (g *GPIO) ReadWrite(rw i2c.RW, command uint8, size i2c.SMBusSize,
+	data *i2c.SMBusData) error {
+	var ptr uint8
+	return readWrite(g.get, g.set, &ptr, rw, command, size, data)
+}
+
+// Set registers including the input pins of register 0, and 1 with 16 bits.
+func (g *GPIO) Set(page int, reg uint8, b ...byte) error {
+	for _, v := range b {
+		port, r := g.reg(reg)
+		if page != 0 || r == nil {
+			return syscall.EINVAL
+		}
+		r[port] = v
+		reg++
+	}
+	return nil
+}
+
+// Mux models the PCA954x switches with a control register of the enabled
+// channels.
+type Mux struct {
+	Mask     byte
+	Channels [8]map[int]Device
+}
+
+func NewPCA9548() *Mux { return new(Mux) }
+
+// Add the device behind the given channel.
+func (m *Mux) Add(channel, address int, d Device) {
+	if m.Channels[channel] == nil {
+		m.Channels[channel] = make(map[int]Device)
+	}
+	m.Channels[channel][address] = d
+}
+
+func (m *Mux) ReadWrite(rw i2c.RW, command uint8, size i2c.SMBusSize,
+	data *i2c.SMBusData) error {
+	switch {
+	case size == i2c.Byte && rw == i2c.Write:
+		m.Mask = command
+	case size == i2c.Byte || size == i2c.ByteData:
+		if rw == i2c.Write {
+			m.Mask = data[0]
+		} else {
+			data[0] = m.Mask
+		}
+	case size == i2c.Quick:
+	default:
+		return syscall.EOPNOTSUPP
+	}
+	return nil
+}
+
+func (m *Mux) Set(page int, reg uint8, b ...byte) error {
+	if page != 0 || len(b) != 1 {
+		return syscall.EINVAL
+	}
+	m.Mask = b[0]
+	return nil
+}
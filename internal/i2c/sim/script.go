@@ -0,0 +1,161 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package sim
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Models by script name.
+var Models = map[string]func() Device{
+	"regs":    func() Device { return NewRegs() },
+	"w83795":  func() Device { return NewW83795() },
+	"ucd9090": func() Device { return NewUCD9090() },
+	"qsfp":    func() Device { return NewQSFP() },
+	"pca9534": func() Device { return NewPCA9534() },
+	"pca9554": func() Device { return NewPCA9534() },
+	"pca9535": func() Device { return NewPCA9535() },
+	"pca9539": func() Device { return NewPCA9535() },
+	"pca9555": func() Device { return NewPCA9535() },
+	"pca9548": func() Device { return NewPCA9548() },
+}
+
+// LoadFile loads the named script.
+func (s *Sim) LoadFile(fn string) error {
+	f, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err = s.Load(f); err != nil {
+		err = fmt.Errorf("%s:%v", fn, err)
+	}
+	return err
+}
+
+// Load a script of device and register lines.
+//
+//	# comment
+//	dev NAME MODEL BUS ADDRESS
+//	dev NAME MODEL MUX.CHANNEL ADDRESS
+//	set NAME [PAGE:]REG VALUE...
+//
+// Where BUS is the /dev/i2c-BUS index and MUX is the NAME of a pca9548
+// device.  Numbers may be decimal or 0x prefixed hexadecimal.
+func (s *Sim) Load(r io.Reader) error {
+	line := 0
+	for scan := bufio.NewScanner(r); scan.Scan(); {
+		line++
+		text := scan.Text()
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		var err error
+		switch fields[0] {
+		case "dev":
+			err = s.dev(fields[1:])
+		case "set":
+			err = s.set(fields[1:])
+		default:
+			err = fmt.Errorf("%s: unknown", fields[0])
+		}
+		if err != nil {
+			return fmt.Errorf("%d: %v", line, err)
+		}
+	}
+	return nil
+}
+
+func (s *Sim) dev(args []string) error {
+	if len(args) != 4 {
+		return fmt.Errorf("dev: expected NAME MODEL BUS ADDRESS")
+	}
+	name, model, at := args[0], args[1], args[2]
+	newDevice, found := Models[model]
+	if !found {
+		return fmt.Errorf("%s: unknown model", model)
+	}
+	address, err := number(args[3], 0x7f)
+	if err != nil {
+		return err
+	}
+	if s.Device(name) != nil {
+		return fmt.Errorf("%s: duplicate", name)
+	}
+	d := newDevice()
+	if i := strings.Index(at, "."); i > 0 {
+		mux, ok := s.Device(at[:i]).(*Mux)
+		if !ok {
+			return fmt.Errorf("%s: not a mux", at[:i])
+		}
+		ch, err := number(at[i+1:], len(mux.Channels)-1)
+		if err != nil {
+			return err
+		}
+		s.Lock()
+		mux.Add(ch, address, d)
+		s.Unlock()
+	} else {
+		bus, err := number(at, 255)
+		if err != nil {
+			return err
+		}
+		s.Add(bus, address, d)
+	}
+	s.Lock()
+	s.byName[name] = d
+	s.Unlock()
+	return nil
+}
+
+func (s *Sim) set(args []string) error {
+	if len(args) < 3 {
+		return fmt.Errorf("set: expected NAME [PAGE:]REG VALUE...")
+	}
+	d := s.Device(args[0])
+	if d == nil {
+		return fmt.Errorf("%s: not found", args[0])
+	}
+	page, reg := 0, args[1]
+	if i := strings.Index(reg, ":"); i > 0 {
+		var err error
+		if page, err = number(reg[:i], 255); err != nil {
+			return err
+		}
+		reg = reg[i+1:]
+	}
+	r, err := number(reg, 255)
+	if err != nil {
+		return err
+	}
+	b := make([]byte, 0, len(args)-2)
+	for _, arg := range args[2:] {
+		v, err := number(arg, 255)
+		if err != nil {
+			return err
+		}
+		b = append(b, byte(v))
+	}
+	s.Lock()
+	defer s.Unlock()
+	return d.Set(page, uint8(r), b...)
+}
+
+func number(s string, max int) (int, error) {
+	u, err := strconv.ParseUint(s, 0, 32)
+	if err != nil || int(u) > max {
+		return 0, fmt.Errorf("%s: invalid", s)
+	}
+	return int(u), nil
+}
@@ -0,0 +1,124 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package sim is an in-memory i2c.Backend of scripted device models for
+// testing hardware daemons without hardware.
+//
+//	s := sim.New()
+//	mux := sim.NewPCA9548()
+//	s.Add(0, 0x70, mux)
+//	mux.Add(2, 0x50, sim.NewQSFP())
+//	i2c.Use(s)
+//
+// Devices behind a mux are only addressable while their channel is
+// selected.  Transfers with an absent device fail with ENXIO as a NAK
+// would with /dev/i2c-X.
+package sim
+
+import (
+	"fmt"
+	"sync"
+	"syscall"
+
+	"github.com/platinasystems/go/internal/i2c"
+)
+
+const features = i2c.I2C | i2c.SMBUS_Quick |
+	i2c.SMBUS_Read_Byte | i2c.SMBUS_Write_Byte |
+	i2c.SMBUS_Read_Byte_Data | i2c.SMBUS_Write_Byte_Data |
+	i2c.SMBUS_Read_Word_Data | i2c.SMBUS_Write_Word_Data |
+	i2c.SMBUS_Read_Block_Data | i2c.SMBUS_Write_Block_Data |
+	i2c.SMBUS_Read_I2C_Block | i2c.SMBUS_Write_I2C_Block
+
+// A Device models an i2c slave.
+type Device interface {
+	// ReadWrite performs an SMBus transfer with the same data format
+	// as the I2C_SMBUS ioctl.
+	ReadWrite(rw i2c.RW, command uint8, size i2c.SMBusSize,
+		data *i2c.SMBusData) error
+	// Set the raw values of the given page starting at reg without
+	// side effects, e.g. the input pins of a GPIO expander.
+	Set(page int, reg uint8, b ...byte) error
+}
+
+type Sim struct {
+	// Lock to change device models while in use.
+	sync.Mutex
+
+	buses  map[int]map[int]Device
+	byName map[string]Device
+}
+
+func New() *Sim {
+	return &Sim{
+		buses:  make(map[int]map[int]Device),
+		byName: make(map[string]Device),
+	}
+}
+
+// Add the device at the given bus index and slave address.
+func (s *Sim) Add(bus, address int, d Device) {
+	s.Lock()
+	defer s.Unlock()
+	if s.buses[bus] == nil {
+		s.buses[bus] = make(map[int]Device)
+	}
+	s.buses[bus][address] = d
+}
+
+// Remove the device at the given bus index and slave address, e.g. to
+// simulate an unplugged module.
+func (s *Sim) Remove(bus, address int) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.buses[bus], address)
+}
+
+// Device returns the named device of a loaded script.
+func (s *Sim) Device(name string) Device {
+	s.Lock()
+	defer s.Unlock()
+	return s.byName[name]
+}
+
+func (s *Sim) Features(index int) (i2c.FeatureFlag, error) {
+	s.Lock()
+	defer s.Unlock()
+	if _, found := s.buses[index]; !found {
+		return 0, fmt.Errorf("i2c-%d: %v", index, syscall.ENOENT)
+	}
+	return features, nil
+}
+
+func (s *Sim) ReadWrite(index, address int, rw i2c.RW, command uint8,
+	size i2c.SMBusSize, data *i2c.SMBusData) error {
+	s.Lock()
+	defer s.Unlock()
+	d := lookup(s.buses[index], address)
+	if d == nil {
+		return syscall.ENXIO
+	}
+	return d.ReadWrite(rw, command, size, data)
+}
+
+func lookup(devs map[int]Device, address int) Device {
+	if d, found := devs[address]; found {
+		return d
+	}
+	for _, d := range devs {
+		mux, ok := d.(*Mux)
+		if !ok {
+			continue
+		}
+		for ch := range mux.Channels {
+			if mux.Mask&(1<<uint(ch)) == 0 {
+				continue
+			}
+			if d := lookup(mux.Channels[ch], address); d != nil {
+				return d
+			}
+		}
+	}
+	return nil
+}
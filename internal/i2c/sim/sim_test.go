@@ -0,0 +1,202 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package sim
+
+import (
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/platinasystems/go/internal/i2c"
+)
+
+const script = `
+dev mux0 pca9548 0 0x70
+dev hwmon w83795 0 0x2f
+dev psu ucd9090 0 0x7e
+dev port1 qsfp mux0.2 0x50
+dev gpio pca9535 mux0.7 0x20
+set hwmon 2:0x10 0x55 0xaa	# bank 2
+set psu 3:0x8b 0x00 0x18	# READ_VOUT of rail 3
+set port1 3:0x80 0x42		# upper page 3
+set gpio 0 0x0f 0xf0		# input pins
+`
+
+func load(t *testing.T) *Sim {
+	s := New()
+	if err := s.Load(strings.NewReader(script)); err != nil {
+		t.Fatal(err)
+	}
+	i2c.Use(s)
+	return s
+}
+
+func do(bus, address int, rw i2c.RW, command uint8,
+	size i2c.SMBusSize, data *i2c.SMBusData) error {
+	return i2c.Do(bus, address, func(b *i2c.Bus) error {
+		return b.ReadWrite(rw, command, size, data)
+	})
+}
+
+func TestBanked(t *testing.T) {
+	load(t)
+	defer i2c.Use(nil)
+	var data i2c.SMBusData
+	if err := do(0, 0x2f, i2c.Read, 0xfe, i2c.ByteData,
+		&data); err != nil {
+		t.Fatal(err)
+	}
+	if data[0] != 0x79 {
+		t.Errorf("chip id %#x", data[0])
+	}
+	data[0] = 2
+	if err := do(0, 0x2f, i2c.Write, 0, i2c.ByteData,
+		&data); err != nil {
+		t.Fatal(err)
+	}
+	if err := do(0, 0x2f, i2c.Read, 0x10, i2c.WordData,
+		&data); err != nil {
+		t.Fatal(err)
+	}
+	if data[0] != 0x55 || data[1] != 0xaa {
+		t.Errorf("bank 2 word %#x %#x", data[0], data[1])
+	}
+}
+
+func TestPMBus(t *testing.T) {
+	load(t)
+	defer i2c.Use(nil)
+	var data i2c.SMBusData
+	data[0] = 3
+	if err := do(0, 0x7e, i2c.Write, 0, i2c.ByteData,
+		&data); err != nil {
+		t.Fatal(err)
+	}
+	if err := do(0, 0x7e, i2c.Read, 0x8b, i2c.WordData,
+		&data); err != nil {
+		t.Fatal(err)
+	}
+	if v := uint16(data[0]) | uint16(data[1])<<8; v != 0x1800 {
+		t.Errorf("READ_VOUT %#x", v)
+	}
+	if err := do(0, 0x7e, i2c.Read, 0x9a, i2c.BlockData,
+		&data); err != nil {
+		t.Fatal(err)
+	}
+	if s := string(data[1 : 1+data[0]]); s != "UCD9090" {
+		t.Errorf("MFR_MODEL %q", s)
+	}
+}
+
+func TestMux(t *testing.T) {
+	load(t)
+	defer i2c.Use(nil)
+	var data i2c.SMBusData
+	err := do(0, 0x50, i2c.Read, 0, i2c.ByteData, &data)
+	if err != syscall.ENXIO {
+		t.Fatal("expected ENXIO with closed mux, got", err)
+	}
+	data[0] = 1 << 2
+	if err = do(0, 0x70, i2c.Write, 0, i2c.ByteData,
+		&data); err != nil {
+		t.Fatal(err)
+	}
+	if err = do(0, 0x50, i2c.Read, 0, i2c.ByteData,
+		&data); err != nil {
+		t.Fatal(err)
+	}
+	if data[0] != 0x0d {
+		t.Errorf("identifier %#x", data[0])
+	}
+	if err = do(0, 0x20, i2c.Read, 0, i2c.ByteData,
+		&data); err != syscall.ENXIO {
+		t.Error("expected ENXIO on other channel, got", err)
+	}
+}
+
+func TestQSFPPage(t *testing.T) {
+	s := load(t)
+	defer i2c.Use(nil)
+	s.Device("mux0").Set(0, 0, 1<<2)
+	var data i2c.SMBusData
+	data[0] = 3
+	if err := do(0, 0x50, i2c.Write, 127, i2c.ByteData,
+		&data); err != nil {
+		t.Fatal(err)
+	}
+	data[0] = 4
+	if err := do(0, 0x50, i2c.Read, 0x80, i2c.I2CBlockData,
+		&data); err != nil {
+		t.Fatal(err)
+	}
+	if data[1] != 0x42 {
+		t.Errorf("page 3 byte 128 %#x", data[1])
+	}
+	data[0] = 9
+	if err := do(0, 0x50, i2c.Write, 127, i2c.ByteData,
+		&data); err != nil {
+		t.Fatal(err)
+	}
+	if err := do(0, 0x50, i2c.Read, 0x80, i2c.ByteData,
+		&data); err != nil {
+		t.Fatal(err)
+	}
+	if data[0] != 0xff {
+		t.Errorf("absent page read %#x", data[0])
+	}
+}
+
+func TestGPIO(t *testing.T) {
+	s := load(t)
+	defer i2c.Use(nil)
+	s.Device("mux0").Set(0, 0, 1<<7)
+	var data i2c.SMBusData
+	if err := do(0, 0x20, i2c.Read, 0, i2c.WordData,
+		&data); err != nil {
+		t.Fatal(err)
+	}
+	if data[0] != 0x0f || data[1] != 0xf0 {
+		t.Errorf("input %#x %#x", data[0], data[1])
+	}
+	// drive the low nibble of port 0 as outputs low
+	data[0] = 0xf0
+	if err := do(0, 0x20, i2c.Write, 2, i2c.ByteData,
+		&data); err != nil {
+		t.Fatal(err)
+	}
+	if err := do(0, 0x20, i2c.Write, 6, i2c.ByteData,
+		&data); err != nil {
+		t.Fatal(err)
+	}
+	if err := do(0, 0x20, i2c.Read, 0, i2c.ByteData,
+		&data); err != nil {
+		t.Fatal(err)
+	}
+	if data[0] != 0x00 {
+		t.Errorf("input with outputs %#x", data[0])
+	}
+}
+
+func TestNoBus(t *testing.T) {
+	load(t)
+	defer i2c.Use(nil)
+	if err := i2c.Do(5, 0x50, func(*i2c.Bus) error { return nil }); err == nil {
+		t.Error("opened absent bus")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for _, bad := range []string{
+		"dev x nosuch 0 0x50",
+		"dev x qsfp 0 0x80",
+		"dev x qsfp nomux.1 0x50",
+		"set nodev 0 1",
+		"frob",
+	} {
+		if err := New().Load(strings.NewReader(bad)); err == nil {
+			t.Errorf("%q: loaded", bad)
+		}
+	}
+}
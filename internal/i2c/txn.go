@@ -0,0 +1,241 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package i2c
+
+import (
+	"fmt"
+	"time"
+)
+
+// Version of the i2cd transaction API.
+const Version = 1
+
+// A Txn is a sequence of operations that i2cd performs while its buses are
+// locked from other clients; e.g. a mux select followed by the reads of
+// the device behind it.
+type Txn struct {
+	Version int
+	// Client names the holder of the bus locks.
+	Client string
+	Ops    []Op
+	// Timeout waiting for busy buses, default DefaultTimeout.
+	Timeout time.Duration
+	// Hold the buses for more transactions of the same Client until
+	// released or this duration expires.
+	Hold time.Duration
+}
+
+const DefaultTimeout = time.Second
+
+// An Op is an SMBus transfer.
+type Op struct {
+	Bus     int
+	Address int
+	RW      RW
+	Command uint8
+	Size    SMBusSize
+	// Data to write.  Words are little endian as on the bus.
+	// I2CBlockData writes of more than BlockMax bytes are split into
+	// as many transfers with an incrementing command.
+	Data []byte
+	// Number of bytes to read with I2CBlockData, which may also be
+	// more than BlockMax.
+	N int
+	// Delay after the transfer.
+	Delay time.Duration
+}
+
+// A Result has the data read by the corresponding Op or its error.
+type Result struct {
+	Data []byte
+	Err  string
+}
+
+type Reply struct {
+	Version int
+	Results []Result
+}
+
+func NewTxn(client string) *Txn {
+	return &Txn{Version: Version, Client: client}
+}
+
+// Mux enables the given channels of a PCA954x switch.
+func (t *Txn) Mux(bus, address int, channels byte) *Txn {
+	return t.Add(Op{
+		Bus:     bus,
+		Address: address,
+		RW:      Write,
+		Command: channels,
+		Size:    Byte,
+	})
+}
+
+func (t *Txn) Read(bus, address int, command uint8, size SMBusSize) *Txn {
+	return t.Add(Op{
+		Bus:     bus,
+		Address: address,
+		RW:      Read,
+		Command: command,
+		Size:    size,
+	})
+}
+
+func (t *Txn) Write(bus, address int, command uint8, size SMBusSize,
+	data ...byte) *Txn {
+	return t.Add(Op{
+		Bus:     bus,
+		Address: address,
+		RW:      Write,
+		Command: command,
+		Size:    size,
+		Data:    data,
+	})
+}
+
+// ReadBlock reads n bytes starting with the given command.
+func (t *Txn) ReadBlock(bus, address int, command uint8, n int) *Txn {
+	return t.Add(Op{
+		Bus:     bus,
+		Address: address,
+		RW:      Read,
+		Command: command,
+		Size:    I2CBlockData,
+		N:       n,
+	})
+}
+
+// Sleep after the last operation.
+func (t *Txn) Sleep(d time.Duration) *Txn {
+	if len(t.Ops) > 0 {
+		t.Ops[len(t.Ops)-1].Delay = d
+	}
+	return t
+}
+
+func (t *Txn) Add(op Op) *Txn {
+	t.Ops = append(t.Ops, op)
+	return t
+}
+
+// Buses returns the indices of the buses used by the transaction.
+func (t *Txn) Buses() []int {
+	var buses []int
+	for _, op := range t.Ops {
+		found := false
+		for _, bus := range buses {
+			if bus == op.Bus {
+				found = true
+				break
+			}
+		}
+		if !found {
+			buses = append(buses, op.Bus)
+		}
+	}
+	return buses
+}
+
+// Do performs the operations with the local buses, stopping at the first
+// error, which is the last result.
+func (t *Txn) Do() []Result {
+	results := make([]Result, 0, len(t.Ops))
+	for _, op := range t.Ops {
+		var r Result
+		err := Do(op.Bus, op.Address, func(bus *Bus) (err error) {
+			r.Data, err = op.do(bus)
+			return
+		})
+		if err != nil {
+			r.Err = err.Error()
+		}
+		results = append(results, r)
+		if err != nil {
+			break
+		}
+		if op.Delay > 0 {
+			time.Sleep(op.Delay)
+		}
+	}
+	return results
+}
+
+// Err returns the error of the last result, if any.
+func Err(results []Result) error {
+	if n := len(results); n > 0 && len(results[n-1].Err) > 0 {
+		return fmt.Errorf("op %d: %s", n-1, results[n-1].Err)
+	}
+	return nil
+}
+
+func (op *Op) do(bus *Bus) ([]byte, error) {
+	var data SMBusData
+	switch op.Size {
+	case Quick:
+		return nil, bus.Do(op.RW, op.Command, op.Size, &data)
+	case Byte, ByteData, WordData:
+		n := 1
+		if op.Size == WordData {
+			n = 2
+		}
+		if op.RW == Write {
+			if op.Size != Byte && len(op.Data) != n {
+				return nil, fmt.Errorf("expected %d bytes", n)
+			}
+			copy(data[:], op.Data)
+			return nil, bus.Do(op.RW, op.Command, op.Size, &data)
+		}
+		err := bus.Do(op.RW, op.Command, op.Size, &data)
+		return append([]byte{}, data[:n]...), err
+	case BlockData:
+		if op.RW == Write {
+			if len(op.Data) > BlockMax {
+				return nil, fmt.Errorf("block exceeds %d bytes",
+					BlockMax)
+			}
+			data[0] = byte(len(op.Data))
+			copy(data[1:], op.Data)
+			return nil, bus.Do(op.RW, op.Command, op.Size, &data)
+		}
+		if err := bus.Do(op.RW, op.Command, op.Size, &data); err != nil {
+			return nil, err
+		}
+		n := int(data[0])
+		if n > BlockMax {
+			n = BlockMax
+		}
+		return append([]byte{}, data[1:1+n]...), nil
+	case I2CBlockData:
+		return op.block(bus)
+	}
+	return nil, fmt.Errorf("size %d unsupported", op.Size)
+}
+
+func (op *Op) block(bus *Bus) ([]byte, error) {
+	var buf []byte
+	n := op.N
+	if op.RW == Write {
+		n = len(op.Data)
+	}
+	for off := 0; off < n; off += BlockMax {
+		var data SMBusData
+		l := n - off
+		if l > BlockMax {
+			l = BlockMax
+		}
+		data[0] = byte(l)
+		if op.RW == Write {
+			copy(data[1:], op.Data[off:off+l])
+		}
+		err := bus.Do(op.RW, op.Command+uint8(off), op.Size, &data)
+		if err != nil {
+			return buf, err
+		}
+		if op.RW == Read {
+			buf = append(buf, data[1:1+l]...)
+		}
+	}
+	return buf, nil
+}
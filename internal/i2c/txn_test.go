@@ -0,0 +1,61 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package i2c_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/platinasystems/go/internal/i2c"
+	"github.com/platinasystems/go/internal/i2c/sim"
+)
+
+func TestTxn(t *testing.T) {
+	s := sim.New()
+	err := s.Load(strings.NewReader(`
+dev mux pca9548 0 0x70
+dev port qsfp mux.3 0x50
+dev hwmon w83795 0 0x2f
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := make([]byte, 48)
+	for i := range name {
+		name[i] = byte('A' + i%26)
+	}
+	s.Device("port").Set(0, 148, name...)
+	i2c.Use(s)
+	defer i2c.Use(nil)
+
+	results := i2c.NewTxn("test").
+		Mux(0, 0x70, 1<<3).
+		ReadBlock(0, 0x50, 148, len(name)).
+		Write(0, 0x2f, 0, i2c.ByteData, 0).
+		Read(0, 0x2f, 0xfe, i2c.ByteData).
+		Do()
+	if err = i2c.Err(results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 {
+		t.Fatal("results:", len(results))
+	}
+	if !bytes.Equal(results[1].Data, name) {
+		t.Errorf("block %q", results[1].Data)
+	}
+	if !bytes.Equal(results[3].Data, []byte{0x79}) {
+		t.Errorf("chip id %x", results[3].Data)
+	}
+
+	results = i2c.NewTxn("test").
+		Mux(0, 0x70, 0).
+		Read(0, 0x50, 0, i2c.ByteData).
+		Read(0, 0x2f, 0xfe, i2c.ByteData).
+		Do()
+	if len(results) != 2 || i2c.Err(results) == nil {
+		t.Error("expected error of second op, got", results)
+	}
+}
@@ -10,7 +10,7 @@ import (
 )
 
 func setup() {
-	pid = 6789
+	id_ = "log.test[6789]"
 	Writer = os.Stdout
 }
 
@@ -48,7 +48,7 @@ line message with default fac/pri`[1:])
 	// <15>log.test[6789]: line message with default fac/pri
 }
 
-func ExampleLimitedPrint() {
+func ExampleLimited_Print() {
 	setup()
 	l := NewLimited(3)
 	l.Print("first message")
@@ -61,7 +61,7 @@ func ExampleLimitedPrint() {
 	// <15>log.test[6789]: third message
 }
 
-func ExampleRateLimitedPrint() {
+func ExampleRateLimited_Print() {
 	setup()
 	rl := NewRateLimited(3, 500*time.Millisecond)
 	defer rl.Close()
@@ -0,0 +1,223 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package log
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Default ports of the remote syslog transports.
+var RemotePorts = map[string]string{
+	"udp": "514",
+	"tcp": "601",
+	"tls": "6514",
+}
+
+const (
+	DefaultRemoteBuffer = 10000
+	remoteTimeout       = 10 * time.Second
+	maxRemoteBackoff    = 30 * time.Second
+)
+
+// Remote sends RFC 5424 messages to a syslog collector over UDP, TCP, or
+// TLS; the stream transports use the octet-counting framing of RFC 6587 and
+// RFC 5425.  Messages are buffered, dropping the oldest when full, while the
+// collector is unreachable.  This must be created with NewRemote and
+// destroyed with (*Remote).Close().
+type Remote struct {
+	Network string // udp, tcp, or tls
+	Addr    string // HOST:PORT
+	TLS     *tls.Config
+
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	queue   [][]byte
+	max     int
+	dropped uint64
+	// of dropped and sent messages
+	popped uint64
+	closed bool
+	conn   net.Conn
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewRemote returns a forwarder to the collector of the given URL,
+// udp|tcp|tls://HOST[:PORT], with a buffer of n messages.
+func NewRemote(rawurl string, config *tls.Config, n int) (*Remote, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	port, found := RemotePorts[u.Scheme]
+	if !found {
+		return nil, fmt.Errorf("%s: unsupported scheme", rawurl)
+	}
+	if len(u.Hostname()) == 0 {
+		return nil, fmt.Errorf("%s: missing host", rawurl)
+	}
+	if len(u.Port()) > 0 {
+		port = u.Port()
+	}
+	if n <= 0 {
+		n = DefaultRemoteBuffer
+	}
+	r := &Remote{
+		Network: u.Scheme,
+		Addr:    net.JoinHostPort(u.Hostname(), port),
+		TLS:     config,
+		max:     n,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if r.Network == "tls" {
+		if r.TLS == nil {
+			r.TLS = new(tls.Config)
+		}
+		if len(r.TLS.ServerName) == 0 {
+			r.TLS = r.TLS.Clone()
+			r.TLS.ServerName = u.Hostname()
+		}
+	}
+	r.cond = sync.NewCond(&r.mutex)
+	go r.forward()
+	return r, nil
+}
+
+func (r *Remote) String() string { return r.Network + "://" + r.Addr }
+
+// Send queues the message to the collector.
+func (r *Remote) Send(m *Message) {
+	b := m.Format()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.closed {
+		return
+	}
+	if len(r.queue) >= r.max {
+		r.pop()
+		r.dropped++
+	}
+	r.queue = append(r.queue, b)
+	r.cond.Signal()
+}
+
+// Returns the number of queued and dropped messages.
+func (r *Remote) Stats() (queued int, dropped uint64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.queue), r.dropped
+}
+
+func (r *Remote) pop() {
+	r.queue[0] = nil
+	r.queue = r.queue[1:]
+	r.popped++
+}
+
+// Close stops the forwarder after one attempt, of up to ten seconds, to
+// send the queued messages.
+func (r *Remote) Close() error {
+	r.mutex.Lock()
+	if r.closed {
+		r.mutex.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.stop)
+	r.cond.Signal()
+	r.mutex.Unlock()
+	select {
+	case <-r.done:
+	case <-time.After(remoteTimeout):
+		r.mutex.Lock()
+		if r.conn != nil {
+			r.conn.Close()
+		}
+		r.mutex.Unlock()
+		<-r.done
+	}
+	return nil
+}
+
+func (r *Remote) dial() (net.Conn, error) {
+	d := &net.Dialer{Timeout: remoteTimeout}
+	if r.Network == "tls" {
+		return tls.DialWithDialer(d, "tcp", r.Addr, r.TLS)
+	}
+	return d.Dial(r.Network, r.Addr)
+}
+
+func (r *Remote) write(conn net.Conn, b []byte) error {
+	conn.SetWriteDeadline(time.Now().Add(remoteTimeout))
+	if r.Network != "udp" {
+		b = append([]byte(fmt.Sprint(len(b), " ")), b...)
+	}
+	_, err := conn.Write(b)
+	return err
+}
+
+func (r *Remote) forward() {
+	defer close(r.done)
+	backoff := time.Second
+	for {
+		r.mutex.Lock()
+		for len(r.queue) == 0 && !r.closed {
+			r.cond.Wait()
+		}
+		if len(r.queue) == 0 {
+			if r.conn != nil {
+				r.conn.Close()
+			}
+			r.mutex.Unlock()
+			return
+		}
+		b, conn, closed, seq := r.queue[0], r.conn, r.closed, r.popped
+		r.mutex.Unlock()
+
+		var err error
+		if conn == nil {
+			conn, err = r.dial()
+			if err == nil {
+				r.mutex.Lock()
+				r.conn = conn
+				r.mutex.Unlock()
+			}
+		}
+		if err == nil {
+			err = r.write(conn, b)
+		}
+		if err == nil {
+			backoff = time.Second
+			r.mutex.Lock()
+			if r.popped == seq {
+				r.pop()
+			}
+			r.mutex.Unlock()
+			continue
+		}
+		if conn != nil {
+			conn.Close()
+		}
+		r.mutex.Lock()
+		r.conn = nil
+		r.mutex.Unlock()
+		if closed {
+			return
+		}
+		select {
+		case <-time.After(backoff):
+		case <-r.stop:
+		}
+		if backoff *= 2; backoff > maxRemoteBackoff {
+			backoff = maxRemoteBackoff
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package log
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/syslog"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func ExampleMessage_Format() {
+	var kmsg Kmsg
+	boot := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	kmsg.Parse([]byte("30,42,1500000,-;goes-platina-mk1.redisd[123]: ready"))
+	m := kmsg.Message(boot)
+	m.Hostname = "switch1"
+	m.Data[0].Params = append(m.Data[0].Params,
+		Param{"machine", "platina-mk1"})
+	fmt.Println(string(m.Format()))
+	kmsg.Parse([]byte("6,43,2000000,-;eth0: link up"))
+	fmt.Println(string(kmsg.Message(boot).Format()))
+	// Output:
+	// <30>1 2018-06-01T12:00:01.500000Z switch1 redisd 123 - [goes@32473 id="goes-platina-mk1.redisd[123\]" daemon="redisd" machine="platina-mk1"] ready
+	// <6>1 2018-06-01T12:00:02.000000Z - kernel - - - eth0: link up
+}
+
+func TestMessageEscape(t *testing.T) {
+	m := &Message{
+		Pri:     syslog.LOG_DAEMON | syslog.LOG_ERR,
+		AppName: "bad app",
+		Data: []Element{{SdId, []Param{
+			{"a=b", `say "hi" [x] \ y`},
+		}}},
+	}
+	want := `<27>1 - - bad_app - - [goes@32473 a_b="say \"hi\" [x\] \\ y"]`
+	if s := string(m.Format()); s != want {
+		t.Errorf("got: %s\nwant: %s", s, want)
+	}
+}
+
+func message(s string) *Message {
+	return &Message{Pri: syslog.LOG_USER | syslog.LOG_INFO, Msg: s}
+}
+
+// Read octet-counted messages from the connection.
+func readFrames(t *testing.T, conn net.Conn, n int) []string {
+	var msgs []string
+	r := bufio.NewReader(conn)
+	for len(msgs) < n {
+		s, err := r.ReadString(' ')
+		if err != nil {
+			t.Fatal(err)
+		}
+		l, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := make([]byte, l)
+		if _, err = io.ReadFull(r, b); err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, string(b))
+	}
+	return msgs
+}
+
+func TestRemoteTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	r, err := NewRemote("tcp://"+addr, nil, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	r.Send(message("first"))
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgs := readFrames(t, conn, 1); !strings.HasSuffix(msgs[0],
+		" first") {
+		t.Errorf("first: %q", msgs[0])
+	}
+	// the collector is down; the oldest message is dropped
+	conn.Close()
+	ln.Close()
+	for i := 0; i < 100; i++ {
+		r.Send(message(fmt.Sprint("down ", i)))
+		time.Sleep(10 * time.Millisecond)
+		if r.mutex.Lock(); r.conn == nil {
+			r.mutex.Unlock()
+			break
+		}
+		r.mutex.Unlock()
+	}
+	r.Send(message("second"))
+	r.Send(message("third"))
+	if queued, dropped := r.Stats(); queued != 2 || dropped == 0 {
+		t.Fatalf("queued %d, dropped %d", queued, dropped)
+	}
+
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	r.Close()
+	if conn, err = ln.Accept(); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	msgs := readFrames(t, conn, 2)
+	if !strings.HasSuffix(msgs[0], " second") ||
+		!strings.HasSuffix(msgs[1], " third") {
+		t.Errorf("buffered: %q", msgs)
+	}
+}
+
+func TestRemoteUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	r, err := NewRemote("udp://"+pc.LocalAddr().String(), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.Send(message("hello"))
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	b := make([]byte, 1024)
+	n, _, err := pc.ReadFrom(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b[:n]); s != "<14>1 - - - - - - hello" {
+		t.Errorf("datagram: %q", s)
+	}
+}
+
+func TestNewRemote(t *testing.T) {
+	for _, x := range []struct {
+		url, addr string
+	}{
+		{"udp://collector", "collector:514"},
+		{"tcp://collector:1514", "collector:1514"},
+		{"tls://[::1]", "[::1]:6514"},
+		{"http://collector", ""},
+		{"udp://", ""},
+	} {
+		r, err := NewRemote(x.url, nil, 0)
+		if err != nil {
+			if len(x.addr) > 0 {
+				t.Error(err)
+			}
+			continue
+		}
+		if r.Addr != x.addr {
+			t.Errorf("%s: %s", x.url, r.Addr)
+		}
+		r.Close()
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"log/syslog"
+	"strings"
+	"time"
+)
+
+// SdId identifies the structured data element of goes messages; 32473 is
+// the private enterprise number reserved for documentation by RFC 5612.
+const SdId = "goes@32473"
+
+const Rfc5424Time = "2006-01-02T15:04:05.000000Z07:00"
+
+// Message is a RFC 5424 syslog message.
+type Message struct {
+	Pri      syslog.Priority
+	Time     time.Time
+	Hostname string
+	AppName  string
+	ProcId   string
+	MsgId    string
+	Data     []Element
+	Msg      string
+}
+
+// Element of structured data.
+type Element struct {
+	Id     string
+	Params []Param
+}
+
+type Param struct {
+	Name, Value string
+}
+
+// Returns the RFC 5424 header field of printable US-ASCII truncated to the
+// given length, or the NILVALUE, "-", if empty.
+func header(s string, n int) string {
+	if len(s) == 0 {
+		return "-"
+	}
+	if len(s) > n {
+		s = s[:n]
+	}
+	return strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// SD-NAME is printable US-ASCII other than '=', ' ', ']', and '"'.
+func sdName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, header(s, 32))
+}
+
+var sdEscaper = strings.NewReplacer(`"`, `\"`, `\`, `\\`, `]`, `\]`)
+
+// Format the message as:
+//
+//	<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]
+func (m *Message) Format() []byte {
+	buf := new(bytes.Buffer)
+	timestamp := "-"
+	if !m.Time.IsZero() {
+		timestamp = m.Time.Format(Rfc5424Time)
+	}
+	fmt.Fprintf(buf, "<%d>1 %s %s %s %s %s ", m.Pri, timestamp,
+		header(m.Hostname, 255), header(m.AppName, 48),
+		header(m.ProcId, 128), header(m.MsgId, 32))
+	if len(m.Data) == 0 {
+		buf.WriteString("-")
+	}
+	for _, e := range m.Data {
+		fmt.Fprint(buf, "[", sdName(e.Id))
+		for _, p := range e.Params {
+			fmt.Fprintf(buf, " %s=\"%s\"", sdName(p.Name),
+				sdEscaper.Replace(p.Value))
+		}
+		buf.WriteString("]")
+	}
+	if len(m.Msg) > 0 {
+		buf.WriteString(" ")
+		buf.WriteString(m.Msg)
+	}
+	return buf.Bytes()
+}
+
+// Message returns the RFC 5424 message of the kernel log record with the
+// given boot time. Records other than the kernel's are expected to have
+// the PROG[PID] id of the goes logger.
+func (p *Kmsg) Message(boot time.Time) *Message {
+	m := &Message{
+		Pri:     p.Pri,
+		Time:    boot.Add(time.Duration(p.Stamp) * time.Microsecond),
+		AppName: "kernel",
+		Msg:     p.Msg,
+	}
+	if p.IsKern() {
+		return m
+	}
+	id, msg := p.Msg, ""
+	if i := strings.Index(p.Msg, ": "); i > 0 {
+		id, msg = p.Msg[:i], p.Msg[i+2:]
+	}
+	prog, pid := id, ""
+	if i := strings.LastIndex(id, "["); i > 0 && strings.HasSuffix(id, "]") {
+		prog, pid = id[:i], id[i+1:len(id)-1]
+	}
+	if strings.ContainsAny(prog, " \t") {
+		// not a goes id
+		m.AppName = ""
+		return m
+	}
+	m.AppName, m.ProcId, m.Msg = prog, pid, msg
+	e := Element{Id: SdId, Params: []Param{{"id", id}}}
+	if i := strings.Index(prog, "."); i > 0 {
+		m.AppName = prog[i+1:]
+		e.Params = append(e.Params, Param{"daemon", prog[i+1:]})
+	}
+	m.Data = append(m.Data, e)
+	return m
+}
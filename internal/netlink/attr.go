@@ -7,9 +7,11 @@ package netlink
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net"
+	"syscall"
 	"unsafe"
 
 	"github.com/platinasystems/go/elib"
@@ -119,8 +121,58 @@ func fprintAttrs(w io.Writer, names []string, attrs []Attr) (int64,
 	return acc.Tuple()
 }
 
+// Panic of attribute decoders given a truncated or otherwise malformed
+// message; see recoverMalformed.
+var errMalformed = errors.New("malformed attribute")
+
+// Message decoders return errMalformed panics as EINVAL, as gorx does
+// other decode errors, rather than exit the receive routine.  Any other
+// panic, e.g. a runtime index error, is a decoder bug so re-panic.
+func recoverMalformed(err *error) {
+	if e := recover(); e != nil {
+		if e != errMalformed {
+			panic(e)
+		}
+		*err = syscall.EINVAL
+	}
+}
+
+// Copy an attribute's bytes into the given fixed size value; shorter
+// attributes, e.g. from an older kernel, leave the remainder zero.
+func copyAttr(p unsafe.Pointer, size uintptr, b []byte) {
+	if len(b) == 0 {
+		panic(errMalformed)
+	}
+	v := (*[1 << 16]byte)(p)[:size:size]
+	for i := copy(v, b); i < len(v); i++ {
+		v[i] = 0
+	}
+}
+
+// Value of a one byte attribute.
+func attrUint8(b []byte) uint8 {
+	if len(b) == 0 {
+		panic(errMalformed)
+	}
+	return b[0]
+}
+
+// Bytes of a nul terminated string attribute without the nul.
+func attrCString(b []byte) []byte {
+	if len(b) == 0 {
+		panic(errMalformed)
+	}
+	return b[:len(b)-1]
+}
+
 func nextAttr(b []byte, i int) (n *NlAttr, v []byte, j int) {
+	if i+SizeofNlAttr > len(b) {
+		panic(errMalformed)
+	}
 	n = (*NlAttr)(unsafe.Pointer(&b[i]))
+	if int(n.Len) < SizeofNlAttr || i+int(n.Len) > len(b) {
+		panic(errMalformed)
+	}
 	v = b[i+SizeofNlAttr : i+int(n.Len)]
 	j = i + attrAlignLen(int(n.Len))
 	return
@@ -325,7 +377,7 @@ func parse_link_info(b []byte) *AttrArray {
 		case IFLA_INFO_DATA, IFLA_INFO_SLAVE_DATA:
 			as.X[kind] = StringAttrBytes(v)
 		default:
-			panic("unknown link info attribute kind " + kind.String())
+			panic(errMalformed)
 		}
 	}
 	switch linkKind {
@@ -394,7 +446,7 @@ func parse_vlan_info(b []byte) (as *AttrArray) {
 		case IFLA_VLAN_FLAGS:
 			as.X[kind] = NewVlanFlagsBytes(v)
 		default:
-			panic("unknown vlan link data attribute kind " + kind.String())
+			panic(errMalformed)
 		}
 	}
 	return as
@@ -403,6 +455,9 @@ func parse_vlan_info(b []byte) (as *AttrArray) {
 type VlanProtocolAttr uint16
 
 func VlanProtocolAttrBytes(b []byte) VlanProtocolAttr {
+	if len(b) < 2 {
+		panic(errMalformed)
+	}
 	return VlanProtocolAttr(uint16(b[0])<<8 | uint16(b[1]))
 }
 
@@ -455,7 +510,7 @@ func (a *VlanFlags) String() string {
 	return StringOf(a)
 }
 func (a *VlanFlags) Parse(b []byte) {
-	*a = *(*VlanFlags)(unsafe.Pointer(&b[0]))
+	copyAttr(unsafe.Pointer(a), unsafe.Sizeof(*a), b)
 }
 func (a *VlanFlags) WriteTo(w io.Writer) (int64, error) {
 	acc := accumulate.New(w)
@@ -553,7 +608,7 @@ func parse_iptun_info(b []byte, linkKind InterfaceKind) (as *AttrArray) {
 		case IFLA_IPTUN_6RD_RELAY_PREFIX:
 			as.X[kind] = NewIp4AddressBytes(v)
 		case IFLA_IPTUN_TTL, IFLA_IPTUN_TOS, IFLA_IPTUN_PROTO, IFLA_IPTUN_PMTUDISC, IFLA_IPTUN_ENCAP_LIMIT, IFLA_IPTUN_FWMARK, IFLA_IPTUN_FLAGS:
-			as.X[kind] = Uint8Attr(v[0])
+			as.X[kind] = Uint8Attr(attrUint8(v))
 		case IFLA_IPTUN_ENCAP_TYPE, IFLA_IPTUN_ENCAP_FLAGS, IFLA_IPTUN_ENCAP_SPORT, IFLA_IPTUN_ENCAP_DPORT, IFLA_IPTUN_6RD_PREFIXLEN, IFLA_IPTUN_6RD_RELAY_PREFIXLEN:
 			as.X[kind] = Uint16AttrBytes(v)
 		case IFLA_IPTUN_LINK, IFLA_IPTUN_FLOWINFO:
@@ -561,7 +616,7 @@ func parse_iptun_info(b []byte, linkKind InterfaceKind) (as *AttrArray) {
 		case IFLA_IPTUN_COLLECT_METADATA:
 			as.X[kind] = Uint8Attr(0)
 		default:
-			panic("unknown iptun link data attribute kind " + kind.String())
+			panic(errMalformed)
 		}
 	}
 	return as
@@ -661,11 +716,11 @@ func parse_gre_info(b []byte, linkKind InterfaceKind) (as *AttrArray) {
 			as.X[kind] = Uint16AttrBytes(v)
 		case IFLA_GRE_TTL, IFLA_GRE_TOS, IFLA_GRE_ENCAP_LIMIT,
 			IFLA_GRE_PMTUDISC, IFLA_GRE_IGNORE_DF:
-			as.X[kind] = Uint8Attr(v[0])
+			as.X[kind] = Uint8Attr(attrUint8(v))
 		case IFLA_GRE_COLLECT_METADATA:
 			as.X[kind] = Uint8Attr(0)
 		default:
-			panic("unknown GRE link data attribute kind " + kind.String())
+			panic(errMalformed)
 		}
 	}
 	return as
@@ -774,7 +829,7 @@ func (a *EthernetAddress) Close() error {
 	return nil
 }
 func (a *EthernetAddress) Parse(b []byte) {
-	copy(a[:], b[:6])
+	copyAttr(unsafe.Pointer(a), unsafe.Sizeof(*a), b)
 }
 func (a *EthernetAddress) Set(v []byte) {
 	copy(v, a[:])
@@ -860,7 +915,7 @@ func (a *IfAddrCacheInfo) String() string {
 	return StringOf(a)
 }
 func (a *IfAddrCacheInfo) Parse(b []byte) {
-	*a = *(*IfAddrCacheInfo)(unsafe.Pointer(&b[0]))
+	copyAttr(unsafe.Pointer(a), unsafe.Sizeof(*a), b)
 }
 func (a *IfAddrCacheInfo) WriteTo(w io.Writer) (int64, error) {
 	acc := accumulate.New(w)
@@ -875,7 +930,9 @@ func (a *IfAddrCacheInfo) WriteTo(w io.Writer) (int64, error) {
 type IfAddrFlagAttr uint32
 
 func IfAddrFlagAttrBytes(b []byte) IfAddrFlagAttr {
-	return *(*IfAddrFlagAttr)(unsafe.Pointer(&b[0]))
+	var a IfAddrFlagAttr
+	copyAttr(unsafe.Pointer(&a), unsafe.Sizeof(a), b)
+	return a
 }
 
 func (a IfAddrFlagAttr) attr() {}
@@ -945,7 +1002,9 @@ func (a Int8Attr) WriteTo(w io.Writer) (int64, error) {
 type Int16Attr int16
 
 func Int16AttrBytes(b []byte) Int16Attr {
-	return Int16Attr(*(*int16)(unsafe.Pointer(&b[0])))
+	var a Int16Attr
+	copyAttr(unsafe.Pointer(&a), unsafe.Sizeof(a), b)
+	return a
 }
 
 func (a Int16Attr) attr() {}
@@ -971,7 +1030,9 @@ func (a Int16Attr) WriteTo(w io.Writer) (int64, error) {
 type Int32Attr int32
 
 func Int32AttrBytes(b []byte) Int32Attr {
-	return Int32Attr(*(*int32)(unsafe.Pointer(&b[0])))
+	var a Int32Attr
+	copyAttr(unsafe.Pointer(&a), unsafe.Sizeof(a), b)
+	return a
 }
 
 func (a Int32Attr) attr() {}
@@ -997,7 +1058,9 @@ func (a Int32Attr) WriteTo(w io.Writer) (int64, error) {
 type Int64Attr int64
 
 func Int64AttrBytes(b []byte) Int64Attr {
-	return Int64Attr(*(*int64)(unsafe.Pointer(&b[0])))
+	var a Int64Attr
+	copyAttr(unsafe.Pointer(&a), unsafe.Sizeof(a), b)
+	return a
 }
 
 func (a Int64Attr) attr() {}
@@ -1037,7 +1100,7 @@ func (a *Ip4Address) Close() error {
 	return nil
 }
 func (a *Ip4Address) Parse(b []byte) {
-	copy(a[:], b[:4])
+	copyAttr(unsafe.Pointer(a), unsafe.Sizeof(*a), b)
 }
 func (a *Ip4Address) Set(v []byte) {
 	copy(v, a[:])
@@ -1072,7 +1135,7 @@ func (a *Ip4DevConf) Close() error {
 	return nil
 }
 func (a *Ip4DevConf) Parse(b []byte) {
-	*a = *(*Ip4DevConf)(unsafe.Pointer(&b[0]))
+	copyAttr(unsafe.Pointer(a), unsafe.Sizeof(*a), b)
 }
 func (a *Ip4DevConf) Set(v []byte) {
 	panic("not implemented")
@@ -1114,7 +1177,7 @@ func (a *Ip6Address) Close() error {
 	return nil
 }
 func (a *Ip6Address) Parse(b []byte) {
-	copy(a[:], b[:16])
+	copyAttr(unsafe.Pointer(a), unsafe.Sizeof(*a), b)
 }
 func (a *Ip6Address) Set(v []byte) {
 	copy(v, a[:])
@@ -1149,7 +1212,7 @@ func (a *Ip6DevConf) Close() error {
 	return nil
 }
 func (a *Ip6DevConf) Parse(b []byte) {
-	*a = *(*Ip6DevConf)(unsafe.Pointer(&b[0]))
+	copyAttr(unsafe.Pointer(a), unsafe.Sizeof(*a), b)
 }
 func (a *Ip6DevConf) Set(v []byte) {
 	panic("not implemented")
@@ -1177,7 +1240,9 @@ func (a *Ip6DevConf) WriteTo(w io.Writer) (int64, error) {
 type Ip6IfFlagsAttr uint32
 
 func Ip6IfFlagsAttrBytes(b []byte) Ip6IfFlagsAttr {
-	return Ip6IfFlagsAttr(*(*uint32)(unsafe.Pointer(&b[0])))
+	var a Ip6IfFlagsAttr
+	copyAttr(unsafe.Pointer(&a), unsafe.Sizeof(a), b)
+	return a
 }
 
 func (a Ip6IfFlagsAttr) attr() {}
@@ -1235,7 +1300,7 @@ func (a *LinkStats) Close() error {
 	return nil
 }
 func (a *LinkStats) Parse(b []byte) {
-	*a = *(*LinkStats)(unsafe.Pointer(&b[0]))
+	copyAttr(unsafe.Pointer(a), unsafe.Sizeof(*a), b)
 }
 func (a *LinkStats) Set(v []byte) {
 	*(*LinkStats)(unsafe.Pointer(&v[0])) = *a
@@ -1275,7 +1340,7 @@ func (a *LinkStats64) Close() error {
 	return nil
 }
 func (a *LinkStats64) Parse(b []byte) {
-	*a = *(*LinkStats64)(unsafe.Pointer(&b[0]))
+	copyAttr(unsafe.Pointer(a), unsafe.Sizeof(*a), b)
 }
 func (a *LinkStats64) Set(v []byte) {
 	*(*LinkStats64)(unsafe.Pointer(&v[0])) = *a
@@ -1320,7 +1385,7 @@ func (a *NdaCacheInfo) Close() error {
 	return nil
 }
 func (a *NdaCacheInfo) Parse(b []byte) {
-	*a = *(*NdaCacheInfo)(unsafe.Pointer(&b[0]))
+	copyAttr(unsafe.Pointer(a), unsafe.Sizeof(*a), b)
 }
 func (a *NdaCacheInfo) Set(v []byte) {
 	panic("should never be called")
@@ -1375,7 +1440,7 @@ func (a *RtaCacheInfo) String() string {
 	return StringOf(a)
 }
 func (a *RtaCacheInfo) Parse(b []byte) {
-	*a = *(*RtaCacheInfo)(unsafe.Pointer(&b[0]))
+	copyAttr(unsafe.Pointer(a), unsafe.Sizeof(*a), b)
 }
 func (a *RtaCacheInfo) WriteTo(w io.Writer) (int64, error) {
 	acc := accumulate.New(w)
@@ -1457,9 +1522,15 @@ func (a *RtaMultipath) Parse(af AddressFamily, b []byte) {
 	i := 0
 	for i < len(b) {
 		nh := RtNextHop{}
+		if i+sizeofRtNextHopHeader > len(b) {
+			panic(errMalformed)
+		}
 		nh.rtNextHopHeader = *(*rtNextHopHeader)(unsafe.Pointer(&b[i]))
 		attr_lo := i + sizeofRtNextHopHeader
 		attr_hi := i + int(nh.Len)
+		if attr_lo > attr_hi || attr_hi > len(b) {
+			panic(errMalformed)
+		}
 		for j := attr_lo; j < attr_hi; {
 			a, v, next := nextAttr(b, j)
 			j = next
@@ -1470,15 +1541,15 @@ func (a *RtaMultipath) Parse(af AddressFamily, b []byte) {
 			case RTA_TABLE, RTA_IIF, RTA_OIF, RTA_PRIORITY, RTA_FLOW:
 				nh.Attrs[k] = Uint32AttrBytes(v)
 			case RTA_ENCAP_TYPE:
-				nh.Attrs[k] = LwtunnelEncapType(v[0])
+				nh.Attrs[k] = LwtunnelEncapType(attrUint8(v))
 			case RTA_ENCAP:
 				nh.Attrs[k] = StringAttrBytes(v[:])
 			case RTA_PREF:
-				nh.Attrs[k] = Uint8Attr(v[0])
+				nh.Attrs[k] = Uint8Attr(attrUint8(v))
 			case RTA_MARK:
 				nh.Attrs[k] = Uint32AttrBytes(v[:])
 			default:
-				panic(fmt.Errorf("%#v: unexpected attr", k))
+				panic(errMalformed)
 			}
 		}
 		a.NextHops = append(a.NextHops, nh)
@@ -1503,6 +1574,65 @@ func (a *RtaMultipath) WriteTo(w io.Writer) (int64, error) {
 	return acc.Tuple()
 }
 
+// NhaGroup is the NHA_GROUP list of member nexthop ids and weights.
+type NhaGroup struct {
+	Entries []NhaGroupEntry
+}
+
+// The wire Weight is one less than the effective weight.
+type NhaGroupEntry struct {
+	Id         uint32
+	Weight     uint8
+	WeightHigh uint8
+	_          uint16
+}
+
+const sizeofNhaGroupEntry = 8
+
+func NewNhaGroupBytes(b []byte) *NhaGroup {
+	a := pool.NhaGroup.Get().(*NhaGroup)
+	a.Parse(b)
+	return a
+}
+
+func (a *NhaGroup) attr() {}
+
+func (a *NhaGroup) multiline() {}
+
+func (a *NhaGroup) Close() error {
+	repool(a)
+	return nil
+}
+func (a *NhaGroup) Set(v []byte) {
+	panic("should never be called")
+}
+func (a *NhaGroup) Size() int {
+	panic("should never be called")
+	return 0
+}
+func (a *NhaGroup) String() string {
+	return StringOf(a)
+}
+func (a *NhaGroup) Parse(b []byte) {
+	if len(b)%sizeofNhaGroupEntry != 0 {
+		panic(errMalformed)
+	}
+	for i := 0; i < len(b); i += sizeofNhaGroupEntry {
+		a.Entries = append(a.Entries,
+			*(*NhaGroupEntry)(unsafe.Pointer(&b[i])))
+	}
+}
+func (a *NhaGroup) WriteTo(w io.Writer) (int64, error) {
+	acc := accumulate.New(w)
+	defer acc.Fini()
+	for i := range a.Entries {
+		e := &a.Entries[i]
+		fmt.Fprintf(acc, "  %d: ID %d WEIGHT %d\n", i, e.Id,
+			uint16(e.WeightHigh)<<8|uint16(e.Weight)+1)
+	}
+	return acc.Tuple()
+}
+
 type StringAttr string
 
 func StringAttrBytes(b []byte) StringAttr {
@@ -1554,7 +1684,9 @@ func (a Uint8Attr) WriteTo(w io.Writer) (int64, error) {
 type Uint16Attr uint16
 
 func Uint16AttrBytes(b []byte) Uint16Attr {
-	return Uint16Attr(*(*uint16)(unsafe.Pointer(&b[0])))
+	var a Uint16Attr
+	copyAttr(unsafe.Pointer(&a), unsafe.Sizeof(a), b)
+	return a
 }
 
 func (a Uint16Attr) attr() {}
@@ -1580,7 +1712,9 @@ func (a Uint16Attr) WriteTo(w io.Writer) (int64, error) {
 type Uint32Attr uint32
 
 func Uint32AttrBytes(b []byte) Uint32Attr {
-	return Uint32Attr(*(*uint32)(unsafe.Pointer(&b[0])))
+	var a Uint32Attr
+	copyAttr(unsafe.Pointer(&a), unsafe.Sizeof(a), b)
+	return a
 }
 
 func (a Uint32Attr) attr() {}
@@ -1606,7 +1740,9 @@ func (a Uint32Attr) WriteTo(w io.Writer) (int64, error) {
 type Uint64Attr uint64
 
 func Uint64AttrBytes(b []byte) Uint64Attr {
-	return Uint64Attr(*(*uint64)(unsafe.Pointer(&b[0])))
+	var a Uint64Attr
+	copyAttr(unsafe.Pointer(&a), unsafe.Sizeof(a), b)
+	return a
 }
 
 func (a Uint64Attr) attr() {}
@@ -1731,11 +1867,11 @@ func parse_lwtunnel_ip4_encap(b []byte) *AttrArray {
 		case LWTUNNEL_IP_DST, LWTUNNEL_IP_SRC:
 			as.X[kind] = NewIp4AddressBytes(v)
 		case LWTUNNEL_IP_TTL, LWTUNNEL_IP_TOS:
-			as.X[kind] = Uint8Attr(v[0])
+			as.X[kind] = Uint8Attr(attrUint8(v))
 		case LWTUNNEL_IP_FLAGS:
-			as.X[kind] = Uint16Attr(v[0])
+			as.X[kind] = Uint16Attr(attrUint8(v))
 		default:
-			panic("unknown ip tunnel encap kind " + kind.String())
+			panic(errMalformed)
 		}
 	}
 	return as
@@ -1809,11 +1945,11 @@ func parse_lwtunnel_ip6_encap(b []byte) *AttrArray {
 		case LWTUNNEL_IP6_DST, LWTUNNEL_IP6_SRC:
 			as.X[kind] = NewIp6AddressBytes(v)
 		case LWTUNNEL_IP6_HOPLIMIT, LWTUNNEL_IP6_TC:
-			as.X[kind] = Uint8Attr(v[0])
+			as.X[kind] = Uint8Attr(attrUint8(v))
 		case LWTUNNEL_IP6_FLAGS:
-			as.X[kind] = Uint16Attr(v[0])
+			as.X[kind] = Uint16Attr(attrUint8(v))
 		default:
-			panic("unknown ip6 tunnel encap kind " + kind.String())
+			panic(errMalformed)
 		}
 	}
 	return as
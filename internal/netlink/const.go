@@ -100,6 +100,10 @@ const (
 	RTM_NEWNSID MsgType = 88
 	RTM_DELNSID MsgType = 89
 	RTM_GETNSID MsgType = 90
+
+	RTM_NEWNEXTHOP MsgType = 104
+	RTM_DELNEXTHOP MsgType = 105
+	RTM_GETNEXTHOP MsgType = 106
 )
 
 var msgTypeNames = []string{
@@ -155,6 +159,9 @@ var msgTypeNames = []string{
 	RTM_NEWNSID:      "RTM_NEWNSID",
 	RTM_DELNSID:      "RTM_DELNSID",
 	RTM_GETNSID:      "RTM_GETNSID",
+	RTM_NEWNEXTHOP:   "RTM_NEWNEXTHOP",
+	RTM_DELNEXTHOP:   "RTM_DELNEXTHOP",
+	RTM_GETNEXTHOP:   "RTM_GETNEXTHOP",
 }
 
 func (x MsgType) String() string { return elib.Stringer(msgTypeNames, int(x)) }
@@ -191,6 +198,10 @@ const (
 	RTNLGRP_MDB
 	RTNLGRP_MPLS_ROUTE
 	RTNLGRP_NSID
+	RTNLGRP_MPLS_NETCONF
+	RTNLGRP_IPV4_MROUTE_R
+	RTNLGRP_IPV6_MROUTE_R
+	RTNLGRP_NEXTHOP
 	NOOP_RTNLGRP
 )
 
@@ -412,39 +423,96 @@ const (
 	RTA_PREF
 	RTA_ENCAP_TYPE
 	RTA_ENCAP
+	RTA_EXPIRES
+	RTA_PAD
+	RTA_UID
+	RTA_TTL_PROPAGATE
+	RTA_IP_PROTO
+	RTA_SPORT
+	RTA_DPORT
+	RTA_NH_ID
 	RTA_MAX
 )
 
 var routeAttrKindNames = []string{
-	RTA_UNSPEC:     "UNSPEC",
-	RTA_DST:        "DST",
-	RTA_SRC:        "SRC",
-	RTA_IIF:        "IIF",
-	RTA_OIF:        "OIF",
-	RTA_GATEWAY:    "GATEWAY",
-	RTA_PRIORITY:   "PRIORITY",
-	RTA_PREFSRC:    "PREFSRC",
-	RTA_METRICS:    "METRICS",
-	RTA_MULTIPATH:  "MULTIPATH",
-	RTA_PROTOINFO:  "PROTOINFO",
-	RTA_FLOW:       "FLOW",
-	RTA_CACHEINFO:  "CACHEINFO",
-	RTA_SESSION:    "SESSION",
-	RTA_MP_ALGO:    "MP_ALGO",
-	RTA_TABLE:      "TABLE",
-	RTA_MARK:       "MARK",
-	RTA_MFC_STATS:  "MFC_STATS",
-	RTA_VIA:        "VIA",
-	RTA_NEWDST:     "NEWDST",
-	RTA_PREF:       "PREF",
-	RTA_ENCAP_TYPE: "ENCAP_TYPE",
-	RTA_ENCAP:      "ENCAP",
+	RTA_UNSPEC:        "UNSPEC",
+	RTA_DST:           "DST",
+	RTA_SRC:           "SRC",
+	RTA_IIF:           "IIF",
+	RTA_OIF:           "OIF",
+	RTA_GATEWAY:       "GATEWAY",
+	RTA_PRIORITY:      "PRIORITY",
+	RTA_PREFSRC:       "PREFSRC",
+	RTA_METRICS:       "METRICS",
+	RTA_MULTIPATH:     "MULTIPATH",
+	RTA_PROTOINFO:     "PROTOINFO",
+	RTA_FLOW:          "FLOW",
+	RTA_CACHEINFO:     "CACHEINFO",
+	RTA_SESSION:       "SESSION",
+	RTA_MP_ALGO:       "MP_ALGO",
+	RTA_TABLE:         "TABLE",
+	RTA_MARK:          "MARK",
+	RTA_MFC_STATS:     "MFC_STATS",
+	RTA_VIA:           "VIA",
+	RTA_NEWDST:        "NEWDST",
+	RTA_PREF:          "PREF",
+	RTA_ENCAP_TYPE:    "ENCAP_TYPE",
+	RTA_ENCAP:         "ENCAP",
+	RTA_EXPIRES:       "EXPIRES",
+	RTA_PAD:           "PAD",
+	RTA_UID:           "UID",
+	RTA_TTL_PROPAGATE: "TTL_PROPAGATE",
+	RTA_IP_PROTO:      "IP_PROTO",
+	RTA_SPORT:         "SPORT",
+	RTA_DPORT:         "DPORT",
+	RTA_NH_ID:         "NH_ID",
 }
 
 func (x RouteAttrKind) String() string {
 	return elib.Stringer(routeAttrKindNames, int(x))
 }
 
+type NexthopAttrKind int
+
+const (
+	NHA_UNSPEC NexthopAttrKind = iota
+	NHA_ID
+	NHA_GROUP
+	NHA_GROUP_TYPE
+	NHA_BLACKHOLE
+	NHA_OIF
+	NHA_GATEWAY
+	NHA_ENCAP_TYPE
+	NHA_ENCAP
+	NHA_GROUPS
+	NHA_MASTER
+	NHA_FDB
+	NHA_RES_GROUP
+	NHA_RES_BUCKET
+	NHA_MAX
+)
+
+var nexthopAttrKindNames = []string{
+	NHA_UNSPEC:     "UNSPEC",
+	NHA_ID:         "ID",
+	NHA_GROUP:      "GROUP",
+	NHA_GROUP_TYPE: "GROUP_TYPE",
+	NHA_BLACKHOLE:  "BLACKHOLE",
+	NHA_OIF:        "OIF",
+	NHA_GATEWAY:    "GATEWAY",
+	NHA_ENCAP_TYPE: "ENCAP_TYPE",
+	NHA_ENCAP:      "ENCAP",
+	NHA_GROUPS:     "GROUPS",
+	NHA_MASTER:     "MASTER",
+	NHA_FDB:        "FDB",
+	NHA_RES_GROUP:  "RES_GROUP",
+	NHA_RES_BUCKET: "RES_BUCKET",
+}
+
+func (x NexthopAttrKind) String() string {
+	return elib.Stringer(nexthopAttrKindNames, int(x))
+}
+
 type RouteTableKind uint8
 
 const (
@@ -681,7 +749,7 @@ func afAddr(af AddressFamily, b []byte) Attr {
 	case af == AF_UNSPEC || len(b) == 6:
 		return NewEthernetAddressBytes(b)
 	default:
-		panic(fmt.Errorf("unrecognized addr: %v", b))
+		panic(errMalformed)
 	}
 }
 
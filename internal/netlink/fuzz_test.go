@@ -0,0 +1,93 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package netlink
+
+import (
+	"encoding/binary"
+	"testing"
+	"unsafe"
+)
+
+// Build a message of the given type from its family header and attributes.
+type msgBuilder []byte
+
+func newMsgBuilder(t MsgType, family []byte) *msgBuilder {
+	b := make(msgBuilder, SizeofHeader, 256)
+	binary.LittleEndian.PutUint16(b[4:], uint16(t))
+	b = append(b, family...)
+	return &b
+}
+
+func (b *msgBuilder) attr(kind uint16, v []byte) *msgBuilder {
+	var h [SizeofNlAttr]byte
+	binary.LittleEndian.PutUint16(h[0:], uint16(SizeofNlAttr+len(v)))
+	binary.LittleEndian.PutUint16(h[2:], kind)
+	*b = append(*b, h[:]...)
+	*b = append(*b, v...)
+	for len(*b)%RTA_ALIGNTO != 0 {
+		*b = append(*b, 0)
+	}
+	return b
+}
+
+func (b *msgBuilder) bytes() []byte {
+	binary.LittleEndian.PutUint32((*b)[0:], uint32(len(*b)))
+	return *b
+}
+
+func le32(x uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, x)
+	return b
+}
+
+func FuzzMessage(f *testing.F) {
+	f.Add(newMsgBuilder(RTM_NEWLINK, make([]byte, SizeofIfInfomsg)).
+		attr(uint16(IFLA_IFNAME), []byte("eth0\x00")).
+		attr(uint16(IFLA_MTU), le32(1500)).
+		attr(uint16(IFLA_ADDRESS), []byte{0, 0xa0, 0xc9, 0, 0, 1}).
+		bytes())
+	f.Add(newMsgBuilder(RTM_NEWADDR, []byte{byte(AF_INET), 24, 0, 0, 2, 0, 0, 0}).
+		attr(uint16(IFA_ADDRESS), []byte{10, 0, 0, 1}).
+		attr(uint16(IFA_LABEL), []byte("eth0\x00")).
+		bytes())
+	nh := newMsgBuilder(0, nil).
+		attr(uint16(RTA_GATEWAY), []byte{10, 0, 0, 254})
+	hop := []byte{byte(len(*nh)-SizeofHeader) + 8, 0, 0, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(hop[4:], 2)
+	hop = append(hop, (*nh)[SizeofHeader:]...)
+	f.Add(newMsgBuilder(RTM_NEWROUTE, []byte{byte(AF_INET), 24, 0, 0, 254, 0, 0, 1, 0, 0, 0, 0}).
+		attr(uint16(RTA_DST), []byte{10, 1, 0, 0}).
+		attr(uint16(RTA_OIF), le32(2)).
+		attr(uint16(RTA_MULTIPATH), hop).
+		bytes())
+	f.Add(newMsgBuilder(RTM_NEWNEXTHOP, []byte{byte(AF_UNSPEC), 0, 4, 0, 0, 0, 0, 0}).
+		attr(uint16(NHA_ID), le32(10)).
+		attr(uint16(NHA_GROUP), []byte{1, 0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0, 1, 0, 0, 0}).
+		attr(uint16(NHA_GROUP_TYPE), []byte{0, 0}).
+		bytes())
+	f.Add(newMsgBuilder(RTM_NEWNEXTHOP, []byte{byte(AF_INET), 0, 4, 0, 0, 0, 0, 0}).
+		attr(uint16(NHA_ID), le32(1)).
+		attr(uint16(NHA_OIF), le32(2)).
+		attr(uint16(NHA_GATEWAY), []byte{10, 0, 0, 254}).
+		bytes())
+	f.Fuzz(func(t *testing.T, b []byte) {
+		if len(b) < SizeofHeader {
+			return
+		}
+		h := (*Header)(unsafe.Pointer(&b[0]))
+		msg := newMessage(h.Type)
+		if msg == nil {
+			return
+		}
+		defer msg.Close()
+		if _, err := msg.Write(b); err == nil {
+			_ = msg.String()
+		}
+	})
+}
@@ -398,11 +398,12 @@ func (m *IfInfoMessage) Read(b []byte) (int, error) {
 
 func (m *IfInfoMessage) String() string { return StringOf(m) }
 
-func (m *IfInfoMessage) Write(b []byte) (int, error) {
+func (m *IfInfoMessage) Write(b []byte) (n int, err error) {
+	defer recoverMalformed(&err)
 	if len(b) < SizeofIfInfoMessage {
 		return 0, syscall.EOVERFLOW
 	}
-	n, _ := m.Header.Write(b)
+	n, _ = m.Header.Write(b)
 	m.IfInfomsg = *(*IfInfomsg)(unsafe.Pointer(&b[n]))
 	n += SizeofIfInfomsg
 	for n < len(b) {
@@ -411,7 +412,7 @@ func (m *IfInfoMessage) Write(b []byte) (int, error) {
 		k := IfInfoAttrKind(a.Kind())
 		switch k {
 		case IFLA_IFNAME, IFLA_QDISC:
-			m.Attrs[k] = StringAttrBytes(v[:len(v)-1])
+			m.Attrs[k] = StringAttrBytes(attrCString(v))
 		case IFLA_MTU, IFLA_LINK, IFLA_MASTER,
 			IFLA_WEIGHT,
 			IFLA_NET_NS_PID, IFLA_NET_NS_FD, IFLA_LINK_NETNSID,
@@ -422,9 +423,9 @@ func (m *IfInfoMessage) Write(b []byte) (int, error) {
 			IFLA_GROUP:
 			m.Attrs[k] = Uint32AttrBytes(v)
 		case IFLA_CARRIER, IFLA_LINKMODE, IFLA_PROTO_DOWN:
-			m.Attrs[k] = Uint8Attr(v[0])
+			m.Attrs[k] = Uint8Attr(attrUint8(v))
 		case IFLA_OPERSTATE:
-			m.Attrs[k] = IfOperState(v[0])
+			m.Attrs[k] = IfOperState(attrUint8(v))
 		case IFLA_STATS:
 			m.Attrs[k] = NewLinkStatsBytes(v)
 		case IFLA_STATS64:
@@ -523,11 +524,12 @@ func (m *IfAddrMessage) Read(b []byte) (int, error) {
 
 func (m *IfAddrMessage) String() string { return StringOf(m) }
 
-func (m *IfAddrMessage) Write(b []byte) (int, error) {
+func (m *IfAddrMessage) Write(b []byte) (n int, err error) {
+	defer recoverMalformed(&err)
 	if len(b) < SizeofIfAddrMessage {
 		return 0, syscall.EOVERFLOW
 	}
-	n, _ := m.Header.Write(b)
+	n, _ = m.Header.Write(b)
 	m.IfAddrmsg = *(*IfAddrmsg)(unsafe.Pointer(&b[n]))
 	n += SizeofIfAddrmsg
 	for n < len(b) {
@@ -536,7 +538,7 @@ func (m *IfAddrMessage) Write(b []byte) (int, error) {
 		k := IfAddrAttrKind(a.Kind())
 		switch k {
 		case IFA_LABEL:
-			m.Attrs[k] = StringAttrBytes(v[:len(v)-1])
+			m.Attrs[k] = StringAttrBytes(attrCString(v))
 		case IFA_FLAGS:
 			m.Attrs[k] = IfAddrFlagAttrBytes(v)
 		case IFA_CACHEINFO:
@@ -626,11 +628,12 @@ func (m *RouteMessage) Read(b []byte) (int, error) {
 
 func (m *RouteMessage) String() string { return StringOf(m) }
 
-func (m *RouteMessage) Write(b []byte) (int, error) {
+func (m *RouteMessage) Write(b []byte) (n int, err error) {
+	defer recoverMalformed(&err)
 	if len(b) < SizeofRouteMessage {
 		return 0, syscall.EOVERFLOW
 	}
-	n, _ := m.Header.Write(b)
+	n, _ = m.Header.Write(b)
 	m.Rtmsg = *(*Rtmsg)(unsafe.Pointer(&b[n]))
 	n += SizeofRtmsg
 	for n < len(b) {
@@ -640,14 +643,15 @@ func (m *RouteMessage) Write(b []byte) (int, error) {
 		switch k {
 		case RTA_DST, RTA_SRC, RTA_PREFSRC, RTA_GATEWAY:
 			m.Attrs[k] = afAddr(AddressFamily(m.Family), v)
-		case RTA_TABLE, RTA_IIF, RTA_OIF, RTA_PRIORITY, RTA_FLOW:
+		case RTA_TABLE, RTA_IIF, RTA_OIF, RTA_PRIORITY, RTA_FLOW,
+			RTA_NH_ID:
 			m.Attrs[k] = Uint32AttrBytes(v)
 		case RTA_ENCAP_TYPE:
-			m.Attrs[k] = LwtunnelEncapType(v[0])
+			m.Attrs[k] = LwtunnelEncapType(attrUint8(v))
 		case RTA_ENCAP:
 			m.Attrs[k] = StringAttrBytes(v[:])
 		case RTA_PREF:
-			m.Attrs[k] = Uint8Attr(v[0])
+			m.Attrs[k] = Uint8Attr(attrUint8(v))
 		case RTA_CACHEINFO:
 			m.Attrs[k] = NewRtaCacheInfoBytes(v)
 		case RTA_MULTIPATH:
@@ -661,13 +665,17 @@ func (m *RouteMessage) Write(b []byte) (int, error) {
 		}
 	}
 	if a := m.Attrs[RTA_ENCAP_TYPE]; a != nil {
+		encap, ok := m.Attrs[RTA_ENCAP].(StringAttr)
+		if !ok {
+			return n, fmt.Errorf("%v: missing encap", a)
+		}
 		switch a.(LwtunnelEncapType) {
 		case LWTUNNEL_ENCAP_IP:
-			m.Attrs[RTA_ENCAP] = parse_lwtunnel_ip4_encap([]byte(m.Attrs[RTA_ENCAP].(StringAttr)))
+			m.Attrs[RTA_ENCAP] = parse_lwtunnel_ip4_encap([]byte(encap))
 		case LWTUNNEL_ENCAP_IP6:
-			m.Attrs[RTA_ENCAP] = parse_lwtunnel_ip6_encap([]byte(m.Attrs[RTA_ENCAP].(StringAttr)))
+			m.Attrs[RTA_ENCAP] = parse_lwtunnel_ip6_encap([]byte(encap))
 		default:
-			panic("not yet")
+			return n, fmt.Errorf("%v: unsupported encap type", a)
 		}
 	}
 	return n, nil
@@ -698,6 +706,106 @@ func (m *RouteMessage) WriteTo(w io.Writer) (int64, error) {
 	return acc.Tuple()
 }
 
+type NexthopMessage struct {
+	nsid int
+	Header
+	Nhmsg
+	Attrs [NHA_MAX]Attr
+}
+
+const SizeofNexthopMessage = SizeofHeader + SizeofNhmsg
+
+type Nhmsg struct {
+	Family   AddressFamily
+	Scope    RtScope
+	Protocol RouteProtocol
+	_        uint8
+	Flags    uint32
+}
+
+const SizeofNhmsg = 8
+
+func NewNexthopMessage() *NexthopMessage {
+	m := pool.NexthopMessage.Get().(*NexthopMessage)
+	runtime.SetFinalizer(m, (*NexthopMessage).Close)
+	m.nsid = DefaultNsid
+	return m
+}
+
+func (m *NexthopMessage) Close() error {
+	runtime.SetFinalizer(m, nil)
+	closeAttrs(m.Attrs[:])
+	repool(m)
+	return nil
+}
+
+func (m *NexthopMessage) Nsid() *int { return &m.nsid }
+
+func (m *NexthopMessage) Read(b []byte) (int, error) {
+	if len(b) < SizeofNexthopMessage {
+		return 0, syscall.EOVERFLOW
+	}
+	n, _ := m.Header.Read(b)
+	*(*Nhmsg)(unsafe.Pointer(&b[n])) = m.Nhmsg
+	n += SizeofNhmsg
+	n += AttrVec(m.Attrs[:]).Set(b[n:])
+	return n, nil
+}
+
+func (m *NexthopMessage) String() string { return StringOf(m) }
+
+func (m *NexthopMessage) Write(b []byte) (n int, err error) {
+	defer recoverMalformed(&err)
+	if len(b) < SizeofNexthopMessage {
+		return 0, syscall.EOVERFLOW
+	}
+	n, _ = m.Header.Write(b)
+	m.Nhmsg = *(*Nhmsg)(unsafe.Pointer(&b[n]))
+	n += SizeofNhmsg
+	for n < len(b) {
+		a, v, next := nextAttr(b, n)
+		n = next
+		k := NexthopAttrKind(a.Kind())
+		switch k {
+		case NHA_GATEWAY:
+			m.Attrs[k] = afAddr(AddressFamily(m.Family), v)
+		case NHA_ID, NHA_OIF, NHA_MASTER:
+			m.Attrs[k] = Uint32AttrBytes(v)
+		case NHA_GROUP_TYPE:
+			m.Attrs[k] = Uint16AttrBytes(v)
+		case NHA_GROUP:
+			m.Attrs[k] = NewNhaGroupBytes(v)
+		default:
+			if k < NHA_MAX {
+				m.Attrs[k] = NewHexStringAttrBytes(v)
+			} else {
+				return n, fmt.Errorf("%#v: unknown attr", k)
+			}
+		}
+	}
+	return n, nil
+}
+
+func (m *NexthopMessage) WriteTo(w io.Writer) (int64, error) {
+	acc := accumulate.New(w)
+	defer acc.Fini()
+	fmt.Fprint(acc, m.Header.Type, ":\n")
+	indent.Increase(acc)
+	if m.nsid != DefaultNsid {
+		fmt.Fprintln(acc, "nsid:", m.nsid)
+	}
+	m.Header.WriteTo(acc)
+	fmt.Fprintln(acc, "family:", m.Family)
+	fmt.Fprintln(acc, "scope:", m.Scope)
+	fmt.Fprintln(acc, "protocol:", m.Protocol)
+	if m.Nhmsg.Flags != 0 {
+		fmt.Fprintln(acc, "nexthop flags:", rtNextHopFlag(m.Nhmsg.Flags))
+	}
+	fprintAttrs(acc, nexthopAttrKindNames, m.Attrs[:])
+	indent.Decrease(acc)
+	return acc.Tuple()
+}
+
 type NeighborMessage struct {
 	nsid int
 	Header
@@ -751,11 +859,12 @@ func (m *NeighborMessage) Read(b []byte) (int, error) {
 
 func (m *NeighborMessage) String() string { return StringOf(m) }
 
-func (m *NeighborMessage) Write(b []byte) (int, error) {
+func (m *NeighborMessage) Write(b []byte) (n int, err error) {
+	defer recoverMalformed(&err)
 	if len(b) < SizeofNeighborMessage {
 		return 0, syscall.EOVERFLOW
 	}
-	n, _ := m.Header.Write(b)
+	n, _ = m.Header.Write(b)
 	m.Ndmsg = *(*Ndmsg)(unsafe.Pointer(&b[n]))
 	n += SizeofNdmsg
 	for n < len(b) {
@@ -868,11 +977,12 @@ func (m *NetnsMessage) Read(b []byte) (int, error) {
 
 func (m *NetnsMessage) String() string { return StringOf(m) }
 
-func (m *NetnsMessage) Write(b []byte) (int, error) {
+func (m *NetnsMessage) Write(b []byte) (n int, err error) {
+	defer recoverMalformed(&err)
 	if len(b) < SizeofNetnsMessage {
 		return 0, syscall.EOVERFLOW
 	}
-	n, _ := m.Header.Write(b)
+	n, _ = m.Header.Write(b)
 	m.Netnsmsg = *(*Netnsmsg)(unsafe.Pointer(&b[n]))
 	n += SizeofNetnsmsg
 	for n < len(b) {
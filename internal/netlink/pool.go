@@ -18,6 +18,7 @@ var pool = struct {
 	IfInfoMessage   sync.Pool
 	NeighborMessage sync.Pool
 	NetnsMessage    sync.Pool
+	NexthopMessage  sync.Pool
 	NoopMessage     sync.Pool
 	RouteMessage    sync.Pool
 	AttrArray       sync.Pool
@@ -31,6 +32,7 @@ var pool = struct {
 	IfAddrCacheInfo sync.Pool
 	RtaCacheInfo    sync.Pool
 	RtaMultipath    sync.Pool
+	NhaGroup        sync.Pool
 	NdaCacheInfo    sync.Pool
 	Bytes           sync.Pool
 	VlanFlags       sync.Pool
@@ -75,6 +77,11 @@ var pool = struct {
 			return new(NetnsMessage)
 		},
 	},
+	NexthopMessage: sync.Pool{
+		New: func() interface{} {
+			return new(NexthopMessage)
+		},
+	},
 	NoopMessage: sync.Pool{
 		New: func() interface{} {
 			return new(NoopMessage)
@@ -140,6 +147,11 @@ var pool = struct {
 			return new(RtaMultipath)
 		},
 	},
+	NhaGroup: sync.Pool{
+		New: func() interface{} {
+			return new(NhaGroup)
+		},
+	},
 	NdaCacheInfo: sync.Pool{
 		New: func() interface{} {
 			return new(NdaCacheInfo)
@@ -188,6 +200,9 @@ func repool(v interface{}) {
 	case *NetnsMessage:
 		*t = NetnsMessage{}
 		pool.NetnsMessage.Put(t)
+	case *NexthopMessage:
+		*t = NexthopMessage{}
+		pool.NexthopMessage.Put(t)
 	case *NoopMessage:
 		*t = NoopMessage{}
 		pool.NoopMessage.Put(t)
@@ -227,6 +242,9 @@ func repool(v interface{}) {
 	case *RtaMultipath:
 		*t = RtaMultipath{}
 		pool.RtaMultipath.Put(t)
+	case *NhaGroup:
+		*t = NhaGroup{}
+		pool.NhaGroup.Put(t)
 	case *NdaCacheInfo:
 		*t = NdaCacheInfo{}
 		pool.NdaCacheInfo.Put(t)
@@ -23,6 +23,7 @@ var (
 		RTNLGRP_IPV6_ROUTE,
 		RTNLGRP_IPV6_MROUTE,
 		RTNLGRP_NSID,
+		RTNLGRP_NEXTHOP,
 	}
 	LinkMulticastGroups = []MulticastGroup{
 		RTNLGRP_LINK,
@@ -36,6 +37,7 @@ var (
 		RTNLGRP_IPV6_ROUTE,
 		RTNLGRP_IPV4_MROUTE,
 		RTNLGRP_IPV6_MROUTE,
+		RTNLGRP_NEXTHOP,
 	}
 	NeighborMulticastGroups = []MulticastGroup{
 		RTNLGRP_NEIGH,
@@ -50,6 +52,7 @@ var (
 		{RTM_GETADDR, AF_INET6},
 		{RTM_GETNEIGH, AF_INET},
 		{RTM_GETNEIGH, AF_INET6},
+		{RTM_GETNEXTHOP, AF_UNSPEC},
 		{RTM_GETROUTE, AF_INET},
 		{RTM_GETROUTE, AF_INET6},
 	}
@@ -61,6 +64,7 @@ var (
 		{RTM_GETADDR, AF_INET6},
 	}
 	RouteListenReqs = []ListenReq{
+		{RTM_GETNEXTHOP, AF_UNSPEC},
 		{RTM_GETROUTE, AF_INET},
 		{RTM_GETROUTE, AF_INET6},
 	}
@@ -295,15 +299,15 @@ func (s *Socket) Listen(handler Handler, reqs ...ListenReq) (err error) {
 			continue
 		}
 		for tries := 1; true; tries++ {
-			msg := NewGenMessage()
-			msg.Type = r.MsgType
-			msg.Flags = NLM_F_REQUEST | NLM_F_DUMP
-			msg.AddressFamily = r.AddressFamily
-			s.Tx <- msg
+			s.Tx <- r.dump()
 			err := s.RxUntilDone(handler)
 			if err == nil {
 				break
 			}
+			if err == syscall.EOPNOTSUPP {
+				// e.g. RTM_GETNEXTHOP on kernels before 5.3
+				break
+			}
 			if tries >= 5 {
 				return err
 			}
@@ -312,6 +316,23 @@ func (s *Socket) Listen(handler Handler, reqs ...ListenReq) (err error) {
 	return nil
 }
 
+// The kernel validates nexthop dumps against the full nhmsg rather than the
+// rtgenmsg sufficient for the other dump requests.
+func (r ListenReq) dump() Message {
+	if r.MsgType == RTM_GETNEXTHOP {
+		msg := NewNexthopMessage()
+		msg.Type = r.MsgType
+		msg.Header.Flags = NLM_F_REQUEST | NLM_F_DUMP
+		msg.Family = r.AddressFamily
+		return msg
+	}
+	msg := NewGenMessage()
+	msg.Type = r.MsgType
+	msg.Flags = NLM_F_REQUEST | NLM_F_DUMP
+	msg.AddressFamily = r.AddressFamily
+	return msg
+}
+
 func (s *Socket) RxUntilDone(handler Handler) (err error) {
 	for msg := range s.Rx {
 		switch msg.MsgType() {
@@ -436,27 +457,7 @@ func (s *Socket) gorx() {
 			}
 			h := (*Header)(unsafe.Pointer(&buf[i]))
 			l = h.MsgLen()
-			var msg Message
-			switch h.Type {
-			case NLMSG_NOOP:
-				msg = NewNoopMessage()
-			case NLMSG_ERROR:
-				msg = NewErrorMessage()
-			case NLMSG_DONE:
-				msg = NewDoneMessage()
-			case RTM_GETLINK:
-				msg = NewGenMessage()
-			case RTM_NEWLINK, RTM_DELLINK, RTM_SETLINK:
-				msg = NewIfInfoMessage()
-			case RTM_NEWADDR, RTM_DELADDR, RTM_GETADDR:
-				msg = NewIfAddrMessage()
-			case RTM_NEWROUTE, RTM_DELROUTE, RTM_GETROUTE:
-				msg = NewRouteMessage()
-			case RTM_NEWNEIGH, RTM_DELNEIGH, RTM_GETNEIGH:
-				msg = NewNeighborMessage()
-			case RTM_NEWNSID, RTM_DELNSID, RTM_GETNSID:
-				msg = NewNetnsMessage()
-			}
+			msg := newMessage(h.Type)
 			if msg == nil {
 				continue
 			}
@@ -494,6 +495,33 @@ func (s *Socket) gorx() {
 	s.fd = -1
 }
 
+// Return a new message to decode the given type or nil if unknown.
+func newMessage(t MsgType) (msg Message) {
+	switch t {
+	case NLMSG_NOOP:
+		msg = NewNoopMessage()
+	case NLMSG_ERROR:
+		msg = NewErrorMessage()
+	case NLMSG_DONE:
+		msg = NewDoneMessage()
+	case RTM_GETLINK:
+		msg = NewGenMessage()
+	case RTM_NEWLINK, RTM_DELLINK, RTM_SETLINK:
+		msg = NewIfInfoMessage()
+	case RTM_NEWADDR, RTM_DELADDR, RTM_GETADDR:
+		msg = NewIfAddrMessage()
+	case RTM_NEWROUTE, RTM_DELROUTE, RTM_GETROUTE:
+		msg = NewRouteMessage()
+	case RTM_NEWNEIGH, RTM_DELNEIGH, RTM_GETNEIGH:
+		msg = NewNeighborMessage()
+	case RTM_NEWNSID, RTM_DELNSID, RTM_GETNSID:
+		msg = NewNetnsMessage()
+	case RTM_NEWNEXTHOP, RTM_DELNEXTHOP, RTM_GETNEXTHOP:
+		msg = NewNexthopMessage()
+	}
+	return
+}
+
 func (s *Socket) gotx() {
 	seq := uint32(1)
 	buf := make([]byte, 4*PageSize)
@@ -14,6 +14,14 @@ import (
 
 const SizeofRtAttr = syscall.SizeofRtAttr
 
+// Attribute type flags; newer families, like ethtool and devlink, require
+// NLA_F_NESTED on nested request attributes and may set either in replies.
+const (
+	NLA_F_NESTED        uint16 = 1 << 15
+	NLA_F_NET_BYTEORDER uint16 = 1 << 14
+	NLA_TYPE_MASK              = ^(NLA_F_NESTED | NLA_F_NET_BYTEORDER)
+)
+
 func ForEachAttr(b []byte, do func(uint16, []byte)) {
 	for i := 0; i <= len(b)-SizeofRtAttr; {
 		h := (*syscall.RtAttr)(unsafe.Pointer(&b[i]))
@@ -22,7 +30,7 @@ func ForEachAttr(b []byte, do func(uint16, []byte)) {
 		if l < SizeofRtAttr || n > len(b) {
 			break
 		}
-		do(h.Type, b[i+SizeofRtAttr:n])
+		do(h.Type&NLA_TYPE_MASK, b[i+SizeofRtAttr:n])
 		i = NLATTR.Align(n)
 	}
 }
@@ -0,0 +1,207 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package devlink
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+
+	"github.com/platinasystems/go/internal/nl"
+	"github.com/platinasystems/go/internal/nl/genl"
+)
+
+// A Dev is identified by its bus and device name, e.g. "pci/0000:03:00.0".
+type Dev struct {
+	Bus  string
+	Name string
+}
+
+func (dev Dev) String() string { return dev.Bus + "/" + dev.Name }
+
+// ParseDev parses a "BUS/NAME" device handle.
+func ParseDev(s string) (Dev, error) {
+	i := strings.Index(s, "/")
+	if i <= 0 || i == len(s)-1 {
+		return Dev{}, fmt.Errorf("%s: invalid, expected BUS/NAME", s)
+	}
+	return Dev{s[:i], s[i+1:]}, nil
+}
+
+func (dev Dev) attrs() []nl.Attr {
+	return []nl.Attr{
+		nl.Attr{DEVLINK_ATTR_BUS_NAME, nl.KstringAttr(dev.Bus)},
+		nl.Attr{DEVLINK_ATTR_DEV_NAME, nl.KstringAttr(dev.Name)},
+	}
+}
+
+type Port struct {
+	Dev
+	Index      uint32
+	Type       uint16
+	Flavour    uint16
+	Number     uint32
+	Netdev     string
+	Ifindex    uint32
+	SplitGroup uint32
+}
+
+func (port *Port) String() string {
+	return fmt.Sprint(port.Dev, "/", port.Index)
+}
+
+func (port *Port) attrs() []nl.Attr {
+	return append(port.Dev.attrs(),
+		nl.Attr{DEVLINK_ATTR_PORT_INDEX, nl.Uint32Attr(port.Index)})
+}
+
+type Info struct {
+	Dev
+	Driver  string
+	Serial  string
+	Fixed   map[string]string
+	Running map[string]string
+	Stored  map[string]string
+}
+
+// Parse an INFO_GET reply.
+func (info *Info) Write(b []byte) (int, error) {
+	var dea Dea
+	n, _ := dea.Write(b)
+	*info = Info{
+		Dev:     dea.Dev(),
+		Fixed:   make(map[string]string),
+		Running: make(map[string]string),
+		Stored:  make(map[string]string),
+	}
+	// Unlike other attributes, versions repeat.
+	nl.ForEachAttr(genl.Attrs(b), func(t uint16, val []byte) {
+		var m map[string]string
+		switch t {
+		case DEVLINK_ATTR_INFO_DRIVER_NAME:
+			info.Driver = nl.Kstring(val)
+		case DEVLINK_ATTR_INFO_SERIAL_NUMBER:
+			info.Serial = nl.Kstring(val)
+		case DEVLINK_ATTR_INFO_VERSION_FIXED:
+			m = info.Fixed
+		case DEVLINK_ATTR_INFO_VERSION_RUNNING:
+			m = info.Running
+		case DEVLINK_ATTR_INFO_VERSION_STORED:
+			m = info.Stored
+		}
+		if m != nil {
+			var a [N_DEVLINK_ATTR][]byte
+			nl.IndexAttrByType(a[:], val)
+			name := nl.Kstring(a[DEVLINK_ATTR_INFO_VERSION_NAME])
+			m[name] = nl.Kstring(a[DEVLINK_ATTR_INFO_VERSION_VALUE])
+		}
+	})
+	return n, nil
+}
+
+type Dea [N_DEVLINK_ATTR][]byte
+
+func (dea *Dea) Write(b []byte) (int, error) {
+	return genl.IndexAttrs(dea[:], b), nil
+}
+
+func (dea *Dea) Dev() Dev {
+	return Dev{
+		Bus:  nl.Kstring(dea[DEVLINK_ATTR_BUS_NAME]),
+		Name: nl.Kstring(dea[DEVLINK_ATTR_DEV_NAME]),
+	}
+}
+
+func (dea *Dea) Port() *Port {
+	return &Port{
+		Dev:        dea.Dev(),
+		Index:      nl.Uint32(dea[DEVLINK_ATTR_PORT_INDEX]),
+		Type:       nl.Uint16(dea[DEVLINK_ATTR_PORT_TYPE]),
+		Flavour:    nl.Uint16(dea[DEVLINK_ATTR_PORT_FLAVOUR]),
+		Number:     nl.Uint32(dea[DEVLINK_ATTR_PORT_NUMBER]),
+		Netdev:     nl.Kstring(dea[DEVLINK_ATTR_PORT_NETDEV_NAME]),
+		Ifindex:    nl.Uint32(dea[DEVLINK_ATTR_PORT_NETDEV_IFINDEX]),
+		SplitGroup: nl.Uint32(dea[DEVLINK_ATTR_PORT_SPLIT_GROUP]),
+	}
+}
+
+// Resolve the devlink family.
+func Family(sr *nl.SockReceiver) (*genl.Family, error) {
+	return genl.Lookup(sr, DEVLINK_GENL_NAME)
+}
+
+// Devs returns all devlink devices.
+func Devs(sr *nl.SockReceiver, f *genl.Family) ([]Dev, error) {
+	var devs []Dev
+	err := f.Do(sr, genl.Msg{
+		Cmd:     DEVLINK_CMD_GET,
+		Version: DEVLINK_GENL_VERSION,
+	}, nl.NLM_F_DUMP, func(b []byte) {
+		var dea Dea
+		dea.Write(b)
+		devs = append(devs, dea.Dev())
+	})
+	return devs, err
+}
+
+// Ports returns the ports of all devices.
+func Ports(sr *nl.SockReceiver, f *genl.Family) ([]*Port, error) {
+	var ports []*Port
+	err := f.Do(sr, genl.Msg{
+		Cmd:     DEVLINK_CMD_PORT_GET,
+		Version: DEVLINK_GENL_VERSION,
+	}, nl.NLM_F_DUMP, func(b []byte) {
+		var dea Dea
+		dea.Write(b)
+		ports = append(ports, dea.Port())
+	})
+	return ports, err
+}
+
+// GetInfo returns the driver, serial number and firmware versions of the
+// device.
+func GetInfo(sr *nl.SockReceiver, f *genl.Family, dev Dev) (*Info, error) {
+	var info *Info
+	err := f.Do(sr, genl.Msg{
+		Cmd:     DEVLINK_CMD_INFO_GET,
+		Version: DEVLINK_GENL_VERSION,
+	}, nl.NLM_F_ACK, func(b []byte) {
+		info = new(Info)
+		info.Write(b)
+	}, dev.attrs()...)
+	if err == nil && info == nil {
+		err = syscall.ENOMSG
+	}
+	if err != nil {
+		err = fmt.Errorf("%s: %v", dev, err)
+	}
+	return info, err
+}
+
+// SetPortType changes the port's type, e.g. DEVLINK_PORT_TYPE_ETH.
+func SetPortType(sr *nl.SockReceiver, f *genl.Family, port *Port, t uint16) error {
+	return f.Do(sr, genl.Msg{
+		Cmd:     DEVLINK_CMD_PORT_SET,
+		Version: DEVLINK_GENL_VERSION,
+	}, nl.NLM_F_ACK, nl.DoNothing, append(port.attrs(),
+		nl.Attr{DEVLINK_ATTR_PORT_TYPE, nl.Uint16Attr(t)})...)
+}
+
+// SplitPort splits the port into count subports.
+func SplitPort(sr *nl.SockReceiver, f *genl.Family, port *Port, count uint32) error {
+	return f.Do(sr, genl.Msg{
+		Cmd:     DEVLINK_CMD_PORT_SPLIT,
+		Version: DEVLINK_GENL_VERSION,
+	}, nl.NLM_F_ACK, nl.DoNothing, append(port.attrs(),
+		nl.Attr{DEVLINK_ATTR_PORT_SPLIT_COUNT, nl.Uint32Attr(count)})...)
+}
+
+// UnsplitPort rejoins the subports of a previously split port.
+func UnsplitPort(sr *nl.SockReceiver, f *genl.Family, port *Port) error {
+	return f.Do(sr, genl.Msg{
+		Cmd:     DEVLINK_CMD_PORT_UNSPLIT,
+		Version: DEVLINK_GENL_VERSION,
+	}, nl.NLM_F_ACK, nl.DoNothing, port.attrs()...)
+}
@@ -0,0 +1,171 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package devlink
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/platinasystems/go/internal/nl"
+	"github.com/platinasystems/go/internal/nl/genl"
+)
+
+// A kernel PORT_GET dump entry of the first physical port of a nic.
+var portReply = []byte{
+	0x64, 0x00, 0x00, 0x00, // len
+	0x15, 0x00, // type
+	0x02, 0x00, // MULTI
+	0x01, 0x00, 0x00, 0x00, // seq
+	0x00, 0x00, 0x00, 0x00, // pid
+	0x07, 0x01, 0x00, 0x00, // PORT_NEW, version 1
+	0x08, 0x00, 0x01, 0x00, 'p', 'c', 'i', 0, // BUS_NAME
+	0x11, 0x00, 0x02, 0x00, // DEV_NAME
+	'0', '0', '0', '0', ':', '0', '3', ':',
+	'0', '0', '.', '0', 0, 0, 0, 0,
+	0x08, 0x00, 0x03, 0x00, 0x01, 0x00, 0x00, 0x00, // PORT_INDEX
+	0x06, 0x00, 0x04, 0x00, 0x02, 0x00, 0x00, 0x00, // PORT_TYPE
+	0x08, 0x00, 0x06, 0x00, 0x05, 0x00, 0x00, 0x00, // PORT_NETDEV_IFINDEX
+	0x09, 0x00, 0x07, 0x00, 'e', 't', 'h', '1', 0, 0, 0, 0, // PORT_NETDEV_NAME
+	0x06, 0x00, 0x4d, 0x00, 0x00, 0x00, 0x00, 0x00, // PORT_FLAVOUR
+	0x08, 0x00, 0x4e, 0x00, 0x01, 0x00, 0x00, 0x00, // PORT_NUMBER
+}
+
+// A kernel INFO_GET reply with repeated running versions.
+var infoReply = []byte{
+	0xe0, 0x00, 0x00, 0x00, // len
+	0x15, 0x00, // type
+	0x00, 0x00, // flags
+	0x01, 0x00, 0x00, 0x00, // seq
+	0x00, 0x00, 0x00, 0x00, // pid
+	0x33, 0x01, 0x00, 0x00, // INFO_GET, version 1
+	0x08, 0x00, 0x01, 0x00, 'p', 'c', 'i', 0, // BUS_NAME
+	0x11, 0x00, 0x02, 0x00, // DEV_NAME
+	'0', '0', '0', '0', ':', '0', '3', ':',
+	'0', '0', '.', '0', 0, 0, 0, 0,
+	0x0e, 0x00, 0x62, 0x00, // INFO_DRIVER_NAME
+	'm', 'l', 'x', '5', '_', 'c', 'o', 'r', 'e', 0, 0, 0,
+	0x0b, 0x00, 0x63, 0x00, 'M', 'T', '1', '2', '3', '4', 0, 0, // SERIAL
+	0x28, 0x00, 0x64, 0x00, // INFO_VERSION_FIXED
+	0x0d, 0x00, 0x67, 0x00, // NAME
+	'b', 'o', 'a', 'r', 'd', '.', 'i', 'd', 0, 0, 0, 0,
+	0x12, 0x00, 0x68, 0x00, // VALUE
+	'M', 'T', '_', '0', '0', '0', '0', '0',
+	'0', '0', '0', '0', '8', 0, 0, 0,
+	0x24, 0x00, 0x65, 0x00, // INFO_VERSION_RUNNING
+	0x0f, 0x00, 0x67, 0x00, // NAME
+	'f', 'w', '.', 'v', 'e', 'r', 's', 'i', 'o', 'n', 0, 0,
+	0x0f, 0x00, 0x68, 0x00, // VALUE
+	'1', '6', '.', '2', '6', '.', '1', '0', '4', '0', 0, 0,
+	0x24, 0x00, 0x65, 0x00, // INFO_VERSION_RUNNING
+	0x0c, 0x00, 0x67, 0x00, 'f', 'w', '.', 'p', 's', 'i', 'd', 0, // NAME
+	0x12, 0x00, 0x68, 0x00, // VALUE
+	'M', 'T', '_', '0', '0', '0', '0', '0',
+	'0', '0', '0', '0', '8', 0, 0, 0,
+	0x24, 0x00, 0x66, 0x00, // INFO_VERSION_STORED
+	0x0f, 0x00, 0x67, 0x00, // NAME
+	'f', 'w', '.', 'v', 'e', 'r', 's', 'i', 'o', 'n', 0, 0,
+	0x0f, 0x00, 0x68, 0x00, // VALUE
+	'1', '6', '.', '2', '7', '.', '1', '0', '1', '6', 0, 0,
+}
+
+// A PORT_SPLIT request of the above port into 4 subports.
+var splitRequest = []byte{
+	0x40, 0x00, 0x00, 0x00, // len
+	0x15, 0x00, // type
+	0x05, 0x00, // REQUEST | ACK
+	0x00, 0x00, 0x00, 0x00, // seq
+	0x00, 0x00, 0x00, 0x00, // pid
+	0x09, 0x01, 0x00, 0x00, // PORT_SPLIT, version 1
+	0x08, 0x00, 0x01, 0x00, 'p', 'c', 'i', 0, // BUS_NAME
+	0x11, 0x00, 0x02, 0x00, // DEV_NAME
+	'0', '0', '0', '0', ':', '0', '3', ':',
+	'0', '0', '.', '0', 0, 0, 0, 0,
+	0x08, 0x00, 0x03, 0x00, 0x01, 0x00, 0x00, 0x00, // PORT_INDEX
+	0x08, 0x00, 0x09, 0x00, 0x04, 0x00, 0x00, 0x00, // PORT_SPLIT_COUNT
+}
+
+var nic = Dev{"pci", "0000:03:00.0"}
+
+func TestPort(t *testing.T) {
+	var dea Dea
+	dea.Write(portReply)
+	port := dea.Port()
+	want := &Port{
+		Dev:     nic,
+		Index:   1,
+		Type:    DEVLINK_PORT_TYPE_ETH,
+		Flavour: DEVLINK_PORT_FLAVOUR_PHYSICAL,
+		Number:  1,
+		Netdev:  "eth1",
+		Ifindex: 5,
+	}
+	if !reflect.DeepEqual(port, want) {
+		t.Fatalf("got %+v, want %+v", port, want)
+	}
+	if s := port.String(); s != "pci/0000:03:00.0/1" {
+		t.Error("string:", s)
+	}
+}
+
+func TestInfoWrite(t *testing.T) {
+	var info Info
+	info.Write(infoReply)
+	want := Info{
+		Dev:    nic,
+		Driver: "mlx5_core",
+		Serial: "MT1234",
+		Fixed: map[string]string{
+			"board.id": "MT_0000000008",
+		},
+		Running: map[string]string{
+			"fw.version": "16.26.1040",
+			"fw.psid":    "MT_0000000008",
+		},
+		Stored: map[string]string{
+			"fw.version": "16.27.1016",
+		},
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Fatalf("got %+v, want %+v", info, want)
+	}
+}
+
+func TestSplitRequest(t *testing.T) {
+	port := &Port{Dev: nic, Index: 1}
+	b, err := nl.NewMessage(nl.Hdr{
+		Type:  0x15,
+		Flags: nl.NLM_F_REQUEST | nl.NLM_F_ACK,
+	}, genl.Msg{
+		Cmd:     DEVLINK_CMD_PORT_SPLIT,
+		Version: DEVLINK_GENL_VERSION,
+	}, append(port.attrs(),
+		nl.Attr{DEVLINK_ATTR_PORT_SPLIT_COUNT, nl.Uint32Attr(4)})...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, splitRequest) {
+		t.Fatalf("got\n% x\nwant\n% x", b, splitRequest)
+	}
+}
+
+func TestParseDev(t *testing.T) {
+	for _, x := range []struct {
+		s  string
+		ok bool
+	}{
+		{"pci/0000:03:00.0", true},
+		{"pci", false},
+		{"/0000:03:00.0", false},
+		{"pci/", false},
+	} {
+		dev, err := ParseDev(x.s)
+		if (err == nil) != x.ok {
+			t.Error(x.s, err)
+		} else if x.ok && dev != nic {
+			t.Error(x.s, dev)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package devlink provides the devlink generic netlink family of device,
+// rather than netdev, objects like switch ports and firmware versions.
+package devlink
+
+const DEVLINK_GENL_NAME = "devlink"
+const DEVLINK_GENL_VERSION uint8 = 1
+
+const DEVLINK_GENL_MCGRP_CONFIG_NAME = "config"
+
+const (
+	DEVLINK_CMD_UNSPEC uint8 = iota
+	DEVLINK_CMD_GET
+	DEVLINK_CMD_SET
+	DEVLINK_CMD_NEW
+	DEVLINK_CMD_DEL
+	DEVLINK_CMD_PORT_GET
+	DEVLINK_CMD_PORT_SET
+	DEVLINK_CMD_PORT_NEW
+	DEVLINK_CMD_PORT_DEL
+	DEVLINK_CMD_PORT_SPLIT
+	DEVLINK_CMD_PORT_UNSPLIT
+	DEVLINK_CMD_SB_GET
+	DEVLINK_CMD_SB_SET
+	DEVLINK_CMD_SB_NEW
+	DEVLINK_CMD_SB_DEL
+	DEVLINK_CMD_SB_POOL_GET
+	DEVLINK_CMD_SB_POOL_SET
+	DEVLINK_CMD_SB_POOL_NEW
+	DEVLINK_CMD_SB_POOL_DEL
+	DEVLINK_CMD_SB_PORT_POOL_GET
+	DEVLINK_CMD_SB_PORT_POOL_SET
+	DEVLINK_CMD_SB_PORT_POOL_NEW
+	DEVLINK_CMD_SB_PORT_POOL_DEL
+	DEVLINK_CMD_SB_TC_POOL_BIND_GET
+	DEVLINK_CMD_SB_TC_POOL_BIND_SET
+	DEVLINK_CMD_SB_TC_POOL_BIND_NEW
+	DEVLINK_CMD_SB_TC_POOL_BIND_DEL
+	DEVLINK_CMD_SB_OCC_SNAPSHOT
+	DEVLINK_CMD_SB_OCC_MAX_CLEAR
+	DEVLINK_CMD_ESWITCH_GET
+	DEVLINK_CMD_ESWITCH_SET
+	DEVLINK_CMD_DPIPE_TABLE_GET
+	DEVLINK_CMD_DPIPE_ENTRIES_GET
+	DEVLINK_CMD_DPIPE_HEADERS_GET
+	DEVLINK_CMD_DPIPE_TABLE_COUNTERS_SET
+	DEVLINK_CMD_RESOURCE_SET
+	DEVLINK_CMD_RESOURCE_DUMP
+	DEVLINK_CMD_RELOAD
+	DEVLINK_CMD_PARAM_GET
+	DEVLINK_CMD_PARAM_SET
+	DEVLINK_CMD_PARAM_NEW
+	DEVLINK_CMD_PARAM_DEL
+	DEVLINK_CMD_REGION_GET
+	DEVLINK_CMD_REGION_SET
+	DEVLINK_CMD_REGION_NEW
+	DEVLINK_CMD_REGION_DEL
+	DEVLINK_CMD_REGION_READ
+	DEVLINK_CMD_PORT_PARAM_GET
+	DEVLINK_CMD_PORT_PARAM_SET
+	DEVLINK_CMD_PORT_PARAM_NEW
+	DEVLINK_CMD_PORT_PARAM_DEL
+	DEVLINK_CMD_INFO_GET
+
+	N_DEVLINK_CMD
+)
+
+const DEVLINK_CMD_MAX = N_DEVLINK_CMD - 1
+
+const (
+	DEVLINK_ATTR_UNSPEC                       uint16 = iota
+	DEVLINK_ATTR_BUS_NAME                            // string
+	DEVLINK_ATTR_DEV_NAME                            // string
+	DEVLINK_ATTR_PORT_INDEX                          // u32
+	DEVLINK_ATTR_PORT_TYPE                           // u16
+	DEVLINK_ATTR_PORT_DESIRED_TYPE                   // u16
+	DEVLINK_ATTR_PORT_NETDEV_IFINDEX                 // u32
+	DEVLINK_ATTR_PORT_NETDEV_NAME                    // string
+	DEVLINK_ATTR_PORT_IBDEV_NAME                     // string
+	DEVLINK_ATTR_PORT_SPLIT_COUNT                    // u32
+	DEVLINK_ATTR_PORT_SPLIT_GROUP                    // u32
+	DEVLINK_ATTR_SB_INDEX                            // u32
+	DEVLINK_ATTR_SB_SIZE                             // u32
+	DEVLINK_ATTR_SB_INGRESS_POOL_COUNT               // u16
+	DEVLINK_ATTR_SB_EGRESS_POOL_COUNT                // u16
+	DEVLINK_ATTR_SB_INGRESS_TC_COUNT                 // u16
+	DEVLINK_ATTR_SB_EGRESS_TC_COUNT                  // u16
+	DEVLINK_ATTR_SB_POOL_INDEX                       // u16
+	DEVLINK_ATTR_SB_POOL_TYPE                        // u8
+	DEVLINK_ATTR_SB_POOL_SIZE                        // u32
+	DEVLINK_ATTR_SB_POOL_THRESHOLD_TYPE              // u8
+	DEVLINK_ATTR_SB_THRESHOLD                        // u32
+	DEVLINK_ATTR_SB_TC_INDEX                         // u16
+	DEVLINK_ATTR_SB_OCC_CUR                          // u32
+	DEVLINK_ATTR_SB_OCC_MAX                          // u32
+	DEVLINK_ATTR_ESWITCH_MODE                        // u16
+	DEVLINK_ATTR_ESWITCH_INLINE_MODE                 // u8
+	DEVLINK_ATTR_DPIPE_TABLES                        // nest
+	DEVLINK_ATTR_DPIPE_TABLE                         // nest
+	DEVLINK_ATTR_DPIPE_TABLE_NAME                    // string
+	DEVLINK_ATTR_DPIPE_TABLE_SIZE                    // u64
+	DEVLINK_ATTR_DPIPE_TABLE_MATCHES                 // nest
+	DEVLINK_ATTR_DPIPE_TABLE_ACTIONS                 // nest
+	DEVLINK_ATTR_DPIPE_TABLE_COUNTERS_ENABLED        // u8
+	DEVLINK_ATTR_DPIPE_ENTRIES                       // nest
+	DEVLINK_ATTR_DPIPE_ENTRY                         // nest
+	DEVLINK_ATTR_DPIPE_ENTRY_INDEX                   // u64
+	DEVLINK_ATTR_DPIPE_ENTRY_MATCH_VALUES            // nest
+	DEVLINK_ATTR_DPIPE_ENTRY_ACTION_VALUES           // nest
+	DEVLINK_ATTR_DPIPE_ENTRY_COUNTER                 // u64
+	DEVLINK_ATTR_DPIPE_MATCH                         // nest
+	DEVLINK_ATTR_DPIPE_MATCH_VALUE                   // nest
+	DEVLINK_ATTR_DPIPE_MATCH_TYPE                    // u32
+	DEVLINK_ATTR_DPIPE_ACTION                        // nest
+	DEVLINK_ATTR_DPIPE_ACTION_VALUE                  // nest
+	DEVLINK_ATTR_DPIPE_ACTION_TYPE                   // u32
+	DEVLINK_ATTR_DPIPE_VALUE
+	DEVLINK_ATTR_DPIPE_VALUE_MASK
+	DEVLINK_ATTR_DPIPE_VALUE_MAPPING      // u32
+	DEVLINK_ATTR_DPIPE_HEADERS            // nest
+	DEVLINK_ATTR_DPIPE_HEADER             // nest
+	DEVLINK_ATTR_DPIPE_HEADER_NAME        // string
+	DEVLINK_ATTR_DPIPE_HEADER_ID          // u32
+	DEVLINK_ATTR_DPIPE_HEADER_FIELDS      // nest
+	DEVLINK_ATTR_DPIPE_HEADER_GLOBAL      // u8
+	DEVLINK_ATTR_DPIPE_HEADER_INDEX       // u32
+	DEVLINK_ATTR_DPIPE_FIELD              // nest
+	DEVLINK_ATTR_DPIPE_FIELD_NAME         // string
+	DEVLINK_ATTR_DPIPE_FIELD_ID           // u32
+	DEVLINK_ATTR_DPIPE_FIELD_BITWIDTH     // u32
+	DEVLINK_ATTR_DPIPE_FIELD_MAPPING_TYPE // u32
+	DEVLINK_ATTR_PAD
+	DEVLINK_ATTR_ESWITCH_ENCAP_MODE         // u8
+	DEVLINK_ATTR_RESOURCE_LIST              // nest
+	DEVLINK_ATTR_RESOURCE                   // nest
+	DEVLINK_ATTR_RESOURCE_NAME              // string
+	DEVLINK_ATTR_RESOURCE_ID                // u64
+	DEVLINK_ATTR_RESOURCE_SIZE              // u64
+	DEVLINK_ATTR_RESOURCE_SIZE_NEW          // u64
+	DEVLINK_ATTR_RESOURCE_SIZE_VALID        // u8
+	DEVLINK_ATTR_RESOURCE_SIZE_MIN          // u64
+	DEVLINK_ATTR_RESOURCE_SIZE_MAX          // u64
+	DEVLINK_ATTR_RESOURCE_SIZE_GRAN         // u64
+	DEVLINK_ATTR_RESOURCE_UNIT              // u8
+	DEVLINK_ATTR_RESOURCE_OCC               // u64
+	DEVLINK_ATTR_DPIPE_TABLE_RESOURCE_ID    // u64
+	DEVLINK_ATTR_DPIPE_TABLE_RESOURCE_UNITS // u64
+	DEVLINK_ATTR_PORT_FLAVOUR               // u16
+	DEVLINK_ATTR_PORT_NUMBER                // u32
+	DEVLINK_ATTR_PORT_SPLIT_SUBPORT_NUMBER  // u32
+	DEVLINK_ATTR_PARAM                      // nest
+	DEVLINK_ATTR_PARAM_NAME                 // string
+	DEVLINK_ATTR_PARAM_GENERIC              // flag
+	DEVLINK_ATTR_PARAM_TYPE                 // u8
+	DEVLINK_ATTR_PARAM_VALUES_LIST          // nest
+	DEVLINK_ATTR_PARAM_VALUE                // nest
+	DEVLINK_ATTR_PARAM_VALUE_DATA           // dynamic
+	DEVLINK_ATTR_PARAM_VALUE_CMODE          // u8
+	DEVLINK_ATTR_REGION_NAME                // string
+	DEVLINK_ATTR_REGION_SIZE                // u64
+	DEVLINK_ATTR_REGION_SNAPSHOTS           // nest
+	DEVLINK_ATTR_REGION_SNAPSHOT            // nest
+	DEVLINK_ATTR_REGION_SNAPSHOT_ID         // u32
+	DEVLINK_ATTR_REGION_CHUNKS              // nest
+	DEVLINK_ATTR_REGION_CHUNK               // nest
+	DEVLINK_ATTR_REGION_CHUNK_DATA          // binary
+	DEVLINK_ATTR_REGION_CHUNK_ADDR          // u64
+	DEVLINK_ATTR_REGION_CHUNK_LEN           // u64
+	DEVLINK_ATTR_INFO_DRIVER_NAME           // string
+	DEVLINK_ATTR_INFO_SERIAL_NUMBER         // string
+	DEVLINK_ATTR_INFO_VERSION_FIXED         // nest
+	DEVLINK_ATTR_INFO_VERSION_RUNNING       // nest
+	DEVLINK_ATTR_INFO_VERSION_STORED        // nest
+	DEVLINK_ATTR_INFO_VERSION_NAME          // string
+	DEVLINK_ATTR_INFO_VERSION_VALUE         // string
+
+	N_DEVLINK_ATTR
+)
+
+const DEVLINK_ATTR_MAX = N_DEVLINK_ATTR - 1
+
+const (
+	DEVLINK_PORT_TYPE_NOTSET uint16 = iota
+	DEVLINK_PORT_TYPE_AUTO
+	DEVLINK_PORT_TYPE_ETH
+	DEVLINK_PORT_TYPE_IB
+)
+
+const (
+	DEVLINK_PORT_FLAVOUR_PHYSICAL uint16 = iota
+	DEVLINK_PORT_FLAVOUR_CPU
+	DEVLINK_PORT_FLAVOUR_DSA
+	DEVLINK_PORT_FLAVOUR_PCI_PF
+	DEVLINK_PORT_FLAVOUR_PCI_VF
+)
+
+const (
+	DEVLINK_ESWITCH_MODE_LEGACY uint16 = iota
+	DEVLINK_ESWITCH_MODE_SWITCHDEV
+)
+
+const (
+	DEVLINK_PARAM_CMODE_RUNTIME uint8 = iota
+	DEVLINK_PARAM_CMODE_DRIVERINIT
+	DEVLINK_PARAM_CMODE_PERMANENT
+)
@@ -0,0 +1,259 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package ethtool provides the ethtool generic netlink family that replaces
+// the SIOCETHTOOL ioctl used by the ethtool program.
+package ethtool
+
+const ETHTOOL_GENL_NAME = "ethtool"
+const ETHTOOL_GENL_VERSION uint8 = 1
+
+const ETHTOOL_MCGRP_MONITOR_NAME = "monitor"
+
+// Userspace to kernel messages.
+const (
+	ETHTOOL_MSG_USER_NONE uint8 = iota
+	ETHTOOL_MSG_STRSET_GET
+	ETHTOOL_MSG_LINKINFO_GET
+	ETHTOOL_MSG_LINKINFO_SET
+	ETHTOOL_MSG_LINKMODES_GET
+	ETHTOOL_MSG_LINKMODES_SET
+	ETHTOOL_MSG_LINKSTATE_GET
+	ETHTOOL_MSG_DEBUG_GET
+	ETHTOOL_MSG_DEBUG_SET
+	ETHTOOL_MSG_WOL_GET
+	ETHTOOL_MSG_WOL_SET
+	ETHTOOL_MSG_FEATURES_GET
+	ETHTOOL_MSG_FEATURES_SET
+	ETHTOOL_MSG_PRIVFLAGS_GET
+	ETHTOOL_MSG_PRIVFLAGS_SET
+	ETHTOOL_MSG_RINGS_GET
+	ETHTOOL_MSG_RINGS_SET
+	ETHTOOL_MSG_CHANNELS_GET
+	ETHTOOL_MSG_CHANNELS_SET
+	ETHTOOL_MSG_COALESCE_GET
+	ETHTOOL_MSG_COALESCE_SET
+	ETHTOOL_MSG_PAUSE_GET
+	ETHTOOL_MSG_PAUSE_SET
+	ETHTOOL_MSG_EEE_GET
+	ETHTOOL_MSG_EEE_SET
+	ETHTOOL_MSG_TSINFO_GET
+	ETHTOOL_MSG_CABLE_TEST_ACT
+	ETHTOOL_MSG_CABLE_TEST_TDR_ACT
+	ETHTOOL_MSG_TUNNEL_INFO_GET
+
+	N_ETHTOOL_MSG_USER
+)
+
+const ETHTOOL_MSG_USER_MAX = N_ETHTOOL_MSG_USER - 1
+
+// Kernel to userspace messages.
+const (
+	ETHTOOL_MSG_KERNEL_NONE uint8 = iota
+	ETHTOOL_MSG_STRSET_GET_REPLY
+	ETHTOOL_MSG_LINKINFO_GET_REPLY
+	ETHTOOL_MSG_LINKINFO_NTF
+	ETHTOOL_MSG_LINKMODES_GET_REPLY
+	ETHTOOL_MSG_LINKMODES_NTF
+	ETHTOOL_MSG_LINKSTATE_GET_REPLY
+	ETHTOOL_MSG_DEBUG_GET_REPLY
+	ETHTOOL_MSG_DEBUG_NTF
+	ETHTOOL_MSG_WOL_GET_REPLY
+	ETHTOOL_MSG_WOL_NTF
+	ETHTOOL_MSG_FEATURES_GET_REPLY
+	ETHTOOL_MSG_FEATURES_SET_REPLY
+	ETHTOOL_MSG_FEATURES_NTF
+	ETHTOOL_MSG_PRIVFLAGS_GET_REPLY
+	ETHTOOL_MSG_PRIVFLAGS_NTF
+	ETHTOOL_MSG_RINGS_GET_REPLY
+	ETHTOOL_MSG_RINGS_NTF
+	ETHTOOL_MSG_CHANNELS_GET_REPLY
+	ETHTOOL_MSG_CHANNELS_NTF
+	ETHTOOL_MSG_COALESCE_GET_REPLY
+	ETHTOOL_MSG_COALESCE_NTF
+	ETHTOOL_MSG_PAUSE_GET_REPLY
+	ETHTOOL_MSG_PAUSE_NTF
+	ETHTOOL_MSG_EEE_GET_REPLY
+	ETHTOOL_MSG_EEE_NTF
+	ETHTOOL_MSG_TSINFO_GET_REPLY
+	ETHTOOL_MSG_CABLE_TEST_NTF
+	ETHTOOL_MSG_CABLE_TEST_TDR_NTF
+	ETHTOOL_MSG_TUNNEL_INFO_GET_REPLY
+
+	N_ETHTOOL_MSG_KERNEL
+)
+
+const ETHTOOL_MSG_KERNEL_MAX = N_ETHTOOL_MSG_KERNEL - 1
+
+// Request header flags.
+const (
+	ETHTOOL_FLAG_COMPACT_BITSETS uint32 = 1 << iota
+	ETHTOOL_FLAG_OMIT_REPLY
+	ETHTOOL_FLAG_STATS
+)
+
+const (
+	ETHTOOL_A_HEADER_UNSPEC    uint16 = iota
+	ETHTOOL_A_HEADER_DEV_INDEX        // u32
+	ETHTOOL_A_HEADER_DEV_NAME         // string
+	ETHTOOL_A_HEADER_FLAGS            // u32
+
+	N_ETHTOOL_A_HEADER
+)
+
+const ETHTOOL_A_HEADER_MAX = N_ETHTOOL_A_HEADER - 1
+
+const (
+	ETHTOOL_A_BITSET_BIT_UNSPEC uint16 = iota
+	ETHTOOL_A_BITSET_BIT_INDEX         // u32
+	ETHTOOL_A_BITSET_BIT_NAME          // string
+	ETHTOOL_A_BITSET_BIT_VALUE         // flag
+
+	N_ETHTOOL_A_BITSET_BIT
+)
+
+const ETHTOOL_A_BITSET_BIT_MAX = N_ETHTOOL_A_BITSET_BIT - 1
+
+const (
+	ETHTOOL_A_BITSET_BITS_UNSPEC uint16 = iota
+	ETHTOOL_A_BITSET_BITS_BIT           // nest
+
+	N_ETHTOOL_A_BITSET_BITS
+)
+
+const ETHTOOL_A_BITSET_BITS_MAX = N_ETHTOOL_A_BITSET_BITS - 1
+
+const (
+	ETHTOOL_A_BITSET_UNSPEC uint16 = iota
+	ETHTOOL_A_BITSET_NOMASK        // flag
+	ETHTOOL_A_BITSET_SIZE          // u32
+	ETHTOOL_A_BITSET_BITS          // nest
+	ETHTOOL_A_BITSET_VALUE         // binary
+	ETHTOOL_A_BITSET_MASK          // binary
+
+	N_ETHTOOL_A_BITSET
+)
+
+const ETHTOOL_A_BITSET_MAX = N_ETHTOOL_A_BITSET - 1
+
+const (
+	ETHTOOL_A_LINKINFO_UNSPEC       uint16 = iota
+	ETHTOOL_A_LINKINFO_HEADER              // nest
+	ETHTOOL_A_LINKINFO_PORT                // u8
+	ETHTOOL_A_LINKINFO_PHYADDR             // u8
+	ETHTOOL_A_LINKINFO_TP_MDIX             // u8
+	ETHTOOL_A_LINKINFO_TP_MDIX_CTRL        // u8
+	ETHTOOL_A_LINKINFO_TRANSCEIVER         // u8
+
+	N_ETHTOOL_A_LINKINFO
+)
+
+const ETHTOOL_A_LINKINFO_MAX = N_ETHTOOL_A_LINKINFO - 1
+
+const (
+	ETHTOOL_A_LINKMODES_UNSPEC             uint16 = iota
+	ETHTOOL_A_LINKMODES_HEADER                    // nest
+	ETHTOOL_A_LINKMODES_AUTONEG                   // u8
+	ETHTOOL_A_LINKMODES_OURS                      // bitset
+	ETHTOOL_A_LINKMODES_PEER                      // bitset
+	ETHTOOL_A_LINKMODES_SPEED                     // u32
+	ETHTOOL_A_LINKMODES_DUPLEX                    // u8
+	ETHTOOL_A_LINKMODES_MASTER_SLAVE_CFG          // u8
+	ETHTOOL_A_LINKMODES_MASTER_SLAVE_STATE        // u8
+
+	N_ETHTOOL_A_LINKMODES
+)
+
+const ETHTOOL_A_LINKMODES_MAX = N_ETHTOOL_A_LINKMODES - 1
+
+const (
+	ETHTOOL_A_LINKSTATE_UNSPEC       uint16 = iota
+	ETHTOOL_A_LINKSTATE_HEADER              // nest
+	ETHTOOL_A_LINKSTATE_LINK                // u8
+	ETHTOOL_A_LINKSTATE_SQI                 // u32
+	ETHTOOL_A_LINKSTATE_SQI_MAX             // u32
+	ETHTOOL_A_LINKSTATE_EXT_STATE           // u8
+	ETHTOOL_A_LINKSTATE_EXT_SUBSTATE        // u8
+
+	N_ETHTOOL_A_LINKSTATE
+)
+
+const ETHTOOL_A_LINKSTATE_MAX = N_ETHTOOL_A_LINKSTATE - 1
+
+const (
+	ETHTOOL_A_PRIVFLAGS_UNSPEC uint16 = iota
+	ETHTOOL_A_PRIVFLAGS_HEADER        // nest
+	ETHTOOL_A_PRIVFLAGS_FLAGS         // bitset
+
+	N_ETHTOOL_A_PRIVFLAGS
+)
+
+const ETHTOOL_A_PRIVFLAGS_MAX = N_ETHTOOL_A_PRIVFLAGS - 1
+
+const (
+	ETHTOOL_A_FEATURES_UNSPEC   uint16 = iota
+	ETHTOOL_A_FEATURES_HEADER          // nest
+	ETHTOOL_A_FEATURES_HW              // bitset
+	ETHTOOL_A_FEATURES_WANTED          // bitset
+	ETHTOOL_A_FEATURES_ACTIVE          // bitset
+	ETHTOOL_A_FEATURES_NOCHANGE        // bitset
+
+	N_ETHTOOL_A_FEATURES
+)
+
+const ETHTOOL_A_FEATURES_MAX = N_ETHTOOL_A_FEATURES - 1
+
+const (
+	ETHTOOL_A_RINGS_UNSPEC       uint16 = iota
+	ETHTOOL_A_RINGS_HEADER              // nest
+	ETHTOOL_A_RINGS_RX_MAX              // u32
+	ETHTOOL_A_RINGS_RX_MINI_MAX         // u32
+	ETHTOOL_A_RINGS_RX_JUMBO_MAX        // u32
+	ETHTOOL_A_RINGS_TX_MAX              // u32
+	ETHTOOL_A_RINGS_RX                  // u32
+	ETHTOOL_A_RINGS_RX_MINI             // u32
+	ETHTOOL_A_RINGS_RX_JUMBO            // u32
+	ETHTOOL_A_RINGS_TX                  // u32
+
+	N_ETHTOOL_A_RINGS
+)
+
+const ETHTOOL_A_RINGS_MAX = N_ETHTOOL_A_RINGS - 1
+
+const (
+	ETHTOOL_A_PAUSE_UNSPEC  uint16 = iota
+	ETHTOOL_A_PAUSE_HEADER         // nest
+	ETHTOOL_A_PAUSE_AUTONEG        // u8
+	ETHTOOL_A_PAUSE_RX             // u8
+	ETHTOOL_A_PAUSE_TX             // u8
+	ETHTOOL_A_PAUSE_STATS          // nest
+
+	N_ETHTOOL_A_PAUSE
+)
+
+const ETHTOOL_A_PAUSE_MAX = N_ETHTOOL_A_PAUSE - 1
+
+const (
+	DUPLEX_HALF    uint8 = 0x00
+	DUPLEX_FULL    uint8 = 0x01
+	DUPLEX_UNKNOWN uint8 = 0xff
+)
+
+const SPEED_UNKNOWN = ^uint32(0)
+
+const (
+	AUTONEG_DISABLE uint8 = iota
+	AUTONEG_ENABLE
+)
+
+const (
+	PORT_TP    uint8 = 0x00
+	PORT_AUI   uint8 = 0x01
+	PORT_BNC   uint8 = 0x02
+	PORT_MII   uint8 = 0x03
+	PORT_FIBRE uint8 = 0x04
+	PORT_DA    uint8 = 0x05
+	PORT_NONE  uint8 = 0xef
+	PORT_OTHER uint8 = 0xff
+)
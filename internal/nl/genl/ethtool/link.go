@@ -0,0 +1,265 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package ethtool
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"syscall"
+
+	"github.com/platinasystems/go/internal/nl"
+	"github.com/platinasystems/go/internal/nl/genl"
+)
+
+type LinkInfo struct {
+	Port        uint8
+	PhyAddr     uint8
+	Transceiver uint8
+}
+
+type LinkModes struct {
+	Autoneg bool
+	Speed   uint32 // Mb/s or SPEED_UNKNOWN
+	Duplex  uint8
+	// Supported and advertised modes, e.g. "10000baseCR_Full".
+	Ours Bitset
+	// Modes advertised by the link partner.
+	Peer Bitset
+}
+
+// A Bitset is the verbose form of an ethtool bit set.
+type Bitset []Bit
+
+type Bit struct {
+	Index uint32
+	Name  string
+	Value bool
+}
+
+// Set returns the names of the set bits.
+func (bs Bitset) Set() []string {
+	var names []string
+	for _, bit := range bs {
+		if bit.Value {
+			names = append(names, bit.Name)
+		}
+	}
+	return names
+}
+
+// Parse a verbose ETHTOOL_A_*_BITSET attribute.
+func (bs *Bitset) Write(b []byte) (int, error) {
+	var a [N_ETHTOOL_A_BITSET][]byte
+	nl.IndexAttrByType(a[:], b)
+	nomask := a[ETHTOOL_A_BITSET_NOMASK] != nil
+	*bs = (*bs)[:0]
+	nl.ForEachAttr(a[ETHTOOL_A_BITSET_BITS], func(t uint16, b []byte) {
+		var ba [N_ETHTOOL_A_BITSET_BIT][]byte
+		if t != ETHTOOL_A_BITSET_BITS_BIT {
+			return
+		}
+		nl.IndexAttrByType(ba[:], b)
+		*bs = append(*bs, Bit{
+			Index: nl.Uint32(ba[ETHTOOL_A_BITSET_BIT_INDEX]),
+			Name:  nl.Kstring(ba[ETHTOOL_A_BITSET_BIT_NAME]),
+			Value: nomask || ba[ETHTOOL_A_BITSET_BIT_VALUE] != nil,
+		})
+	})
+	return len(b), nil
+}
+
+// Parse a LINKINFO_GET reply.
+func (li *LinkInfo) Write(b []byte) (int, error) {
+	var a [N_ETHTOOL_A_LINKINFO][]byte
+	n := genl.IndexAttrs(a[:], b)
+	*li = LinkInfo{
+		Port:        nl.Uint8(a[ETHTOOL_A_LINKINFO_PORT]),
+		PhyAddr:     nl.Uint8(a[ETHTOOL_A_LINKINFO_PHYADDR]),
+		Transceiver: nl.Uint8(a[ETHTOOL_A_LINKINFO_TRANSCEIVER]),
+	}
+	return n, nil
+}
+
+// Parse a LINKMODES_GET reply.
+func (lm *LinkModes) Write(b []byte) (int, error) {
+	var a [N_ETHTOOL_A_LINKMODES][]byte
+	n := genl.IndexAttrs(a[:], b)
+	lm.Autoneg = nl.Uint8(a[ETHTOOL_A_LINKMODES_AUTONEG]) ==
+		AUTONEG_ENABLE
+	lm.Speed = SPEED_UNKNOWN
+	if val := a[ETHTOOL_A_LINKMODES_SPEED]; len(val) > 0 {
+		lm.Speed = nl.Uint32(val)
+	}
+	lm.Duplex = DUPLEX_UNKNOWN
+	if val := a[ETHTOOL_A_LINKMODES_DUPLEX]; len(val) > 0 {
+		lm.Duplex = nl.Uint8(val)
+	}
+	lm.Ours.Write(a[ETHTOOL_A_LINKMODES_OURS])
+	lm.Peer.Write(a[ETHTOOL_A_LINKMODES_PEER])
+	return n, nil
+}
+
+// Header returns the nested request header attribute of the given type.
+func Header(t uint16, ifname string, flags uint32) nl.Attr {
+	attrs := nl.Attrs{
+		nl.Attr{ETHTOOL_A_HEADER_DEV_NAME, nl.KstringAttr(ifname)},
+	}
+	if flags != 0 {
+		attrs = append(attrs, nl.Attr{ETHTOOL_A_HEADER_FLAGS,
+			nl.Uint32Attr(flags)})
+	}
+	return nl.Attr{t | nl.NLA_F_NESTED, attrs}
+}
+
+// BitsetAttr returns a nested bitset that changes the named bits w/o a mask
+// so that other bits are unchanged. Bits are listed by name.
+func BitsetAttr(t uint16, bits map[string]bool) nl.Attr {
+	var list nl.Attrs
+	names := make([]string, 0, len(bits))
+	for name := range bits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := bits[name]
+		bit := nl.Attrs{
+			nl.Attr{ETHTOOL_A_BITSET_BIT_NAME, nl.KstringAttr(name)},
+		}
+		if value {
+			bit = append(bit, nl.Attr{ETHTOOL_A_BITSET_BIT_VALUE,
+				nl.NilAttr{}})
+		}
+		list = append(list, nl.Attr{ETHTOOL_A_BITSET_BITS_BIT |
+			nl.NLA_F_NESTED, bit})
+	}
+	return nl.Attr{t | nl.NLA_F_NESTED, nl.Attrs{
+		nl.Attr{ETHTOOL_A_BITSET_BITS | nl.NLA_F_NESTED, list},
+	}}
+}
+
+// Resolve the ethtool family.
+func Family(sr *nl.SockReceiver) (*genl.Family, error) {
+	return genl.Lookup(sr, ETHTOOL_GENL_NAME)
+}
+
+func GetLinkInfo(sr *nl.SockReceiver, f *genl.Family, ifname string) (*LinkInfo, error) {
+	li := new(LinkInfo)
+	err := get(sr, f, ETHTOOL_MSG_LINKINFO_GET, ifname,
+		ETHTOOL_A_LINKINFO_HEADER, li)
+	if err != nil {
+		return nil, err
+	}
+	return li, nil
+}
+
+func GetLinkModes(sr *nl.SockReceiver, f *genl.Family, ifname string) (*LinkModes, error) {
+	lm := new(LinkModes)
+	err := get(sr, f, ETHTOOL_MSG_LINKMODES_GET, ifname,
+		ETHTOOL_A_LINKMODES_HEADER, lm)
+	if err != nil {
+		return nil, err
+	}
+	return lm, nil
+}
+
+// Change link modes with the given ETHTOOL_A_LINKMODES_* attributes, e.g.
+//
+//	SetLinkModes(sr, f, "eth1",
+//		nl.Attr{ETHTOOL_A_LINKMODES_AUTONEG,
+//			nl.Uint8Attr(AUTONEG_DISABLE)},
+//		nl.Attr{ETHTOOL_A_LINKMODES_SPEED, nl.Uint32Attr(10000)},
+//		nl.Attr{ETHTOOL_A_LINKMODES_DUPLEX,
+//			nl.Uint8Attr(DUPLEX_FULL)})
+func SetLinkModes(sr *nl.SockReceiver, f *genl.Family, ifname string,
+	attrs ...nl.Attr) error {
+	return set(sr, f, ETHTOOL_MSG_LINKMODES_SET,
+		Header(ETHTOOL_A_LINKMODES_HEADER, ifname, 0), attrs...)
+}
+
+// GetLinkState returns whether the link is up.
+func GetLinkState(sr *nl.SockReceiver, f *genl.Family, ifname string) (bool, error) {
+	var a [N_ETHTOOL_A_LINKSTATE][]byte
+	err := get(sr, f, ETHTOOL_MSG_LINKSTATE_GET, ifname,
+		ETHTOOL_A_LINKSTATE_HEADER, replyAttrs(a[:]))
+	return nl.Uint8(a[ETHTOOL_A_LINKSTATE_LINK]) != 0, err
+}
+
+// GetPrivFlags returns the driver's private flags.
+func GetPrivFlags(sr *nl.SockReceiver, f *genl.Family, ifname string) (Bitset, error) {
+	var a [N_ETHTOOL_A_PRIVFLAGS][]byte
+	var bs Bitset
+	err := get(sr, f, ETHTOOL_MSG_PRIVFLAGS_GET, ifname,
+		ETHTOOL_A_PRIVFLAGS_HEADER, replyAttrs(a[:]))
+	if err == nil {
+		bs.Write(a[ETHTOOL_A_PRIVFLAGS_FLAGS])
+	}
+	return bs, err
+}
+
+// SetPrivFlags changes the named private flags; others are unchanged.
+func SetPrivFlags(sr *nl.SockReceiver, f *genl.Family, ifname string,
+	flags map[string]bool) error {
+	return set(sr, f, ETHTOOL_MSG_PRIVFLAGS_SET,
+		Header(ETHTOOL_A_PRIVFLAGS_HEADER, ifname, 0),
+		BitsetAttr(ETHTOOL_A_PRIVFLAGS_FLAGS, flags))
+}
+
+// GetFeatures returns the active features, e.g. "rx-checksum".
+func GetFeatures(sr *nl.SockReceiver, f *genl.Family, ifname string) (Bitset, error) {
+	var a [N_ETHTOOL_A_FEATURES][]byte
+	var bs Bitset
+	err := get(sr, f, ETHTOOL_MSG_FEATURES_GET, ifname,
+		ETHTOOL_A_FEATURES_HEADER, replyAttrs(a[:]))
+	if err == nil {
+		bs.Write(a[ETHTOOL_A_FEATURES_ACTIVE])
+	}
+	return bs, err
+}
+
+// SetFeatures requests changes to the named features; the kernel may not
+// be able to change all as wanted.
+func SetFeatures(sr *nl.SockReceiver, f *genl.Family, ifname string,
+	features map[string]bool) error {
+	return set(sr, f, ETHTOOL_MSG_FEATURES_SET,
+		Header(ETHTOOL_A_FEATURES_HEADER, ifname,
+			ETHTOOL_FLAG_OMIT_REPLY),
+		BitsetAttr(ETHTOOL_A_FEATURES_WANTED, features))
+}
+
+// Index the attributes of a *_GET reply.
+type replyAttrs [][]byte
+
+func (a replyAttrs) Write(b []byte) (int, error) {
+	return genl.IndexAttrs(a, b), nil
+}
+
+// Send a *_GET request for the interface and write the reply to w.
+func get(sr *nl.SockReceiver, f *genl.Family, cmd uint8, ifname string,
+	header uint16, w io.Writer) error {
+	var replied bool
+	err := f.Do(sr, genl.Msg{
+		Cmd:     cmd,
+		Version: ETHTOOL_GENL_VERSION,
+	}, nl.NLM_F_ACK, func(b []byte) {
+		w.Write(b)
+		replied = true
+	}, Header(header, ifname, 0))
+	if err == nil && !replied {
+		err = syscall.ENOMSG
+	}
+	if err != nil {
+		err = fmt.Errorf("%s: %v", ifname, err)
+	}
+	return err
+}
+
+func set(sr *nl.SockReceiver, f *genl.Family, cmd uint8, header nl.Attr,
+	attrs ...nl.Attr) error {
+	return f.Do(sr, genl.Msg{
+		Cmd:     cmd,
+		Version: ETHTOOL_GENL_VERSION,
+	}, nl.NLM_F_ACK, nl.DoNothing, append([]nl.Attr{header}, attrs...)...)
+}
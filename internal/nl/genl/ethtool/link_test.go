@@ -0,0 +1,142 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package ethtool
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/platinasystems/go/internal/nl"
+	"github.com/platinasystems/go/internal/nl/genl"
+)
+
+// A kernel LINKMODES_GET reply of eth0 at 10G, full duplex, that supports
+// 10000baseT_Full, Autoneg and TP but advertises only the first two.
+var linkModesReply = []byte{
+	0xdc, 0x00, 0x00, 0x00, // len
+	0x14, 0x00, // type
+	0x00, 0x00, // flags
+	0x01, 0x00, 0x00, 0x00, // seq
+	0x00, 0x00, 0x00, 0x00, // pid
+	0x04, 0x01, 0x00, 0x00, // LINKMODES_GET_REPLY, version 1
+	0x18, 0x00, 0x01, 0x80, // HEADER
+	0x08, 0x00, 0x01, 0x00, 0x02, 0x00, 0x00, 0x00, // DEV_INDEX
+	0x09, 0x00, 0x02, 0x00, 'e', 't', 'h', '0', 0, 0, 0, 0, // DEV_NAME
+	0x05, 0x00, 0x02, 0x00, 0x01, 0x00, 0x00, 0x00, // AUTONEG
+	0x64, 0x00, 0x03, 0x80, // OURS
+	0x08, 0x00, 0x02, 0x00, 0x5c, 0x00, 0x00, 0x00, // SIZE
+	0x58, 0x00, 0x03, 0x80, // BITS
+	0x24, 0x00, 0x01, 0x80, // BIT
+	0x08, 0x00, 0x01, 0x00, 0x0c, 0x00, 0x00, 0x00, // INDEX
+	0x14, 0x00, 0x02, 0x00, // NAME
+	'1', '0', '0', '0', '0', 'b', 'a', 's',
+	'e', 'T', '_', 'F', 'u', 'l', 'l', 0,
+	0x04, 0x00, 0x03, 0x00, // VALUE
+	0x1c, 0x00, 0x01, 0x80, // BIT
+	0x08, 0x00, 0x01, 0x00, 0x06, 0x00, 0x00, 0x00, // INDEX
+	0x0c, 0x00, 0x02, 0x00, 'A', 'u', 't', 'o', 'n', 'e', 'g', 0, // NAME
+	0x04, 0x00, 0x03, 0x00, // VALUE
+	0x14, 0x00, 0x01, 0x80, // BIT
+	0x08, 0x00, 0x01, 0x00, 0x07, 0x00, 0x00, 0x00, // INDEX
+	0x07, 0x00, 0x02, 0x00, 'T', 'P', 0, 0, // NAME
+	0x34, 0x00, 0x04, 0x80, // PEER
+	0x04, 0x00, 0x01, 0x00, // NOMASK
+	0x08, 0x00, 0x02, 0x00, 0x5c, 0x00, 0x00, 0x00, // SIZE
+	0x24, 0x00, 0x03, 0x80, // BITS
+	0x20, 0x00, 0x01, 0x80, // BIT
+	0x08, 0x00, 0x01, 0x00, 0x0c, 0x00, 0x00, 0x00, // INDEX
+	0x14, 0x00, 0x02, 0x00, // NAME
+	'1', '0', '0', '0', '0', 'b', 'a', 's',
+	'e', 'T', '_', 'F', 'u', 'l', 'l', 0,
+	0x08, 0x00, 0x05, 0x00, 0x10, 0x27, 0x00, 0x00, // SPEED
+	0x05, 0x00, 0x06, 0x00, 0x01, 0x00, 0x00, 0x00, // DUPLEX
+}
+
+// A PRIVFLAGS_SET request of eth1 that clears disable-fw-lldp and sets
+// legacy-rx.
+var privFlagsRequest = []byte{
+	0x5c, 0x00, 0x00, 0x00, // len
+	0x14, 0x00, // type
+	0x05, 0x00, // REQUEST | ACK
+	0x00, 0x00, 0x00, 0x00, // seq
+	0x00, 0x00, 0x00, 0x00, // pid
+	0x0e, 0x01, 0x00, 0x00, // PRIVFLAGS_SET, version 1
+	0x10, 0x00, 0x01, 0x80, // HEADER
+	0x09, 0x00, 0x02, 0x00, 'e', 't', 'h', '1', 0, 0, 0, 0, // DEV_NAME
+	0x38, 0x00, 0x02, 0x80, // FLAGS
+	0x34, 0x00, 0x03, 0x80, // BITS
+	0x18, 0x00, 0x01, 0x80, // BIT
+	0x14, 0x00, 0x02, 0x00, // NAME
+	'd', 'i', 's', 'a', 'b', 'l', 'e', '-',
+	'f', 'w', '-', 'l', 'l', 'd', 'p', 0,
+	0x18, 0x00, 0x01, 0x80, // BIT
+	0x0e, 0x00, 0x02, 0x00, // NAME
+	'l', 'e', 'g', 'a', 'c', 'y', '-', 'r', 'x', 0, 0, 0,
+	0x04, 0x00, 0x03, 0x00, // VALUE
+}
+
+func TestLinkModesWrite(t *testing.T) {
+	var lm LinkModes
+	lm.Write(linkModesReply)
+	want := LinkModes{
+		Autoneg: true,
+		Speed:   10000,
+		Duplex:  DUPLEX_FULL,
+		Ours: Bitset{
+			{12, "10000baseT_Full", true},
+			{6, "Autoneg", true},
+			{7, "TP", false},
+		},
+		Peer: Bitset{
+			{12, "10000baseT_Full", true},
+		},
+	}
+	if !reflect.DeepEqual(lm, want) {
+		t.Fatalf("got %+v, want %+v", lm, want)
+	}
+	if got := lm.Ours.Set(); !reflect.DeepEqual(got,
+		[]string{"10000baseT_Full", "Autoneg"}) {
+		t.Error("ours set:", got)
+	}
+}
+
+func TestLinkModesWriteUnknown(t *testing.T) {
+	// A down link has neither speed nor duplex.
+	b, err := nl.NewMessage(nl.Hdr{Type: 0x14}, genl.Msg{
+		Cmd:     ETHTOOL_MSG_LINKMODES_GET_REPLY,
+		Version: ETHTOOL_GENL_VERSION,
+	}, nl.Attr{ETHTOOL_A_LINKMODES_AUTONEG, nl.Uint8Attr(AUTONEG_DISABLE)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lm LinkModes
+	lm.Write(b)
+	if lm.Autoneg || lm.Speed != SPEED_UNKNOWN ||
+		lm.Duplex != DUPLEX_UNKNOWN {
+		t.Errorf("got %+v", lm)
+	}
+}
+
+func TestPrivFlagsRequest(t *testing.T) {
+	b, err := nl.NewMessage(nl.Hdr{
+		Type:  0x14,
+		Flags: nl.NLM_F_REQUEST | nl.NLM_F_ACK,
+	}, genl.Msg{
+		Cmd:     ETHTOOL_MSG_PRIVFLAGS_SET,
+		Version: ETHTOOL_GENL_VERSION,
+	},
+		Header(ETHTOOL_A_PRIVFLAGS_HEADER, "eth1", 0),
+		BitsetAttr(ETHTOOL_A_PRIVFLAGS_FLAGS, map[string]bool{
+			"legacy-rx":       true,
+			"disable-fw-lldp": false,
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, privFlagsRequest) {
+		t.Fatalf("got\n% x\nwant\n% x", b, privFlagsRequest)
+	}
+}
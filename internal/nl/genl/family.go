@@ -0,0 +1,154 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package genl
+
+import (
+	"fmt"
+	"syscall"
+
+	"github.com/platinasystems/go/internal/nl"
+)
+
+// A Family is a generic netlink family as resolved by the controller.
+type Family struct {
+	Name    string
+	Id      uint16
+	Version uint32
+	HdrSize uint32
+	MaxAttr uint32
+	Ops     []Op
+	Groups  []Group
+}
+
+// An Op is a command supported by the family with its GENL_*_PERM and
+// GENL_CMD_CAP_* flags.
+type Op struct {
+	Id    uint32
+	Flags uint32
+}
+
+// A Group is a multicast group of the family.
+type Group struct {
+	Name string
+	Id   uint32
+}
+
+// Parse a CTRL_CMD_NEWFAMILY message.
+func (f *Family) Write(b []byte) (int, error) {
+	var gea Gea
+	n, _ := gea.Write(b)
+	*f = Family{
+		Name:    nl.Kstring(gea[CTRL_ATTR_FAMILY_NAME]),
+		Id:      nl.Uint16(gea[CTRL_ATTR_FAMILY_ID]),
+		Version: nl.Uint32(gea[CTRL_ATTR_VERSION]),
+		HdrSize: nl.Uint32(gea[CTRL_ATTR_HDRSIZE]),
+		MaxAttr: nl.Uint32(gea[CTRL_ATTR_MAXATTR]),
+	}
+	nl.ForEachAttr(gea[CTRL_ATTR_OPS], func(_ uint16, b []byte) {
+		var a [N_CTRL_ATTR_OP][]byte
+		nl.IndexAttrByType(a[:], b)
+		f.Ops = append(f.Ops, Op{
+			Id:    nl.Uint32(a[CTRL_ATTR_OP_ID]),
+			Flags: nl.Uint32(a[CTRL_ATTR_OP_FLAGS]),
+		})
+	})
+	nl.ForEachAttr(gea[CTRL_ATTR_MCAST_GROUPS], func(_ uint16, b []byte) {
+		var a [N_CTRL_ATTR_MCAST_GRP][]byte
+		nl.IndexAttrByType(a[:], b)
+		f.Groups = append(f.Groups, Group{
+			Name: nl.Kstring(a[CTRL_ATTR_MCAST_GRP_NAME]),
+			Id:   nl.Uint32(a[CTRL_ATTR_MCAST_GRP_ID]),
+		})
+	})
+	return n, nil
+}
+
+// Group returns the id of the named multicast group.
+func (f *Family) Group(name string) (uint32, error) {
+	for _, g := range f.Groups {
+		if g.Name == name {
+			return g.Id, nil
+		}
+	}
+	return 0, fmt.Errorf("%s: %s: group not found", f.Name, name)
+}
+
+// Send a request to the family and call the given handler for each reply
+// until DONE or ERROR. A "do" request should include NLM_F_ACK in flags
+// unless the family replies with a DONE terminated multipart message.
+func (f *Family) Do(sr *nl.SockReceiver, msg Msg, flags uint16,
+	do func([]byte), attrs ...nl.Attr) error {
+	req, err := nl.NewMessage(nl.Hdr{
+		Type:  f.Id,
+		Flags: nl.NLM_F_REQUEST | flags,
+	}, msg, attrs...)
+	if err != nil {
+		return err
+	}
+	return sr.UntilDone(req, func(b []byte) {
+		if nl.HdrPtr(b).Type == f.Id && MsgPtr(b) != nil {
+			do(b)
+		}
+	})
+}
+
+// Lookup the named family.
+func Lookup(sr *nl.SockReceiver, name string) (*Family, error) {
+	f, err := getFamily(sr, nl.Attr{CTRL_ATTR_FAMILY_NAME,
+		nl.KstringAttr(name)})
+	if err == syscall.ENOENT {
+		err = fmt.Errorf("%s: family not found", name)
+	}
+	return f, err
+}
+
+// Lookup the family with the given message type.
+func LookupId(sr *nl.SockReceiver, id uint16) (*Family, error) {
+	f, err := getFamily(sr, nl.Attr{CTRL_ATTR_FAMILY_ID,
+		nl.Uint16Attr(id)})
+	if err == syscall.ENOENT {
+		err = fmt.Errorf("%#x: family not found", id)
+	}
+	return f, err
+}
+
+// Families returns all registered families.
+func Families(sr *nl.SockReceiver) ([]*Family, error) {
+	var families []*Family
+	err := ctrl.Do(sr, Msg{
+		Cmd:     CTRL_CMD_GETFAMILY,
+		Version: CTRL_VERSION,
+	}, nl.NLM_F_DUMP, func(b []byte) {
+		if MsgPtr(b).Cmd == CTRL_CMD_NEWFAMILY {
+			f := new(Family)
+			f.Write(b)
+			families = append(families, f)
+		}
+	})
+	return families, err
+}
+
+// The controller is itself a family with a static id.
+var ctrl = &Family{
+	Name: "nlctrl",
+	Id:   GENL_ID_CTRL,
+}
+
+func getFamily(sr *nl.SockReceiver, attr nl.Attr) (*Family, error) {
+	var f *Family
+	err := ctrl.Do(sr, Msg{
+		Cmd:     CTRL_CMD_GETFAMILY,
+		Version: CTRL_VERSION,
+	}, nl.NLM_F_ACK, func(b []byte) {
+		if MsgPtr(b).Cmd == CTRL_CMD_NEWFAMILY {
+			f = new(Family)
+			f.Write(b)
+		}
+	}, attr)
+	if err == nil && f == nil {
+		err = syscall.ENOMSG
+	}
+	return f, err
+}
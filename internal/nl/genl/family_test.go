@@ -0,0 +1,66 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package genl
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/platinasystems/go/internal/nl"
+)
+
+func TestFamilyWrite(t *testing.T) {
+	op := func(id, flags uint32) nl.Attrs {
+		return nl.Attrs{
+			nl.Attr{CTRL_ATTR_OP_ID, nl.Uint32Attr(id)},
+			nl.Attr{CTRL_ATTR_OP_FLAGS, nl.Uint32Attr(flags)},
+		}
+	}
+	b, err := nl.NewMessage(nl.Hdr{
+		Type: GENL_ID_CTRL,
+	}, Msg{
+		Cmd: CTRL_CMD_NEWFAMILY,
+	},
+		nl.Attr{CTRL_ATTR_FAMILY_NAME, nl.KstringAttr("ethtool")},
+		nl.Attr{CTRL_ATTR_FAMILY_ID, nl.Uint16Attr(0x15)},
+		nl.Attr{CTRL_ATTR_VERSION, nl.Uint32Attr(1)},
+		nl.Attr{CTRL_ATTR_HDRSIZE, nl.Uint32Attr(0)},
+		nl.Attr{CTRL_ATTR_MAXATTR, nl.Uint32Attr(0)},
+		nl.Attr{CTRL_ATTR_OPS, nl.Attrs{
+			nl.Attr{1, op(1, 0xe)},
+			// Newer kernels flag nested attributes.
+			nl.Attr{2 | nl.NLA_F_NESTED, op(2, 0xa)},
+		}},
+		nl.Attr{CTRL_ATTR_MCAST_GROUPS | nl.NLA_F_NESTED, nl.Attrs{
+			nl.Attr{1, nl.Attrs{
+				nl.Attr{CTRL_ATTR_MCAST_GRP_NAME,
+					nl.KstringAttr("monitor")},
+				nl.Attr{CTRL_ATTR_MCAST_GRP_ID,
+					nl.Uint32Attr(6)},
+			}},
+		}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f Family
+	f.Write(b)
+	want := Family{
+		Name:    "ethtool",
+		Id:      0x15,
+		Version: 1,
+		Ops:     []Op{{1, 0xe}, {2, 0xa}},
+		Groups:  []Group{{"monitor", 6}},
+	}
+	if !reflect.DeepEqual(f, want) {
+		t.Fatalf("got %+v, want %+v", f, want)
+	}
+	if id, err := f.Group("monitor"); err != nil || id != 6 {
+		t.Error("monitor:", id, err)
+	}
+	if _, err := f.Group("nosuch"); err == nil {
+		t.Error("nosuch: found")
+	}
+}
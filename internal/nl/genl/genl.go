@@ -6,7 +6,6 @@ package genl
 
 import (
 	"fmt"
-	"syscall"
 	"unsafe"
 
 	"github.com/platinasystems/go/internal/nl"
@@ -57,6 +56,8 @@ const (
 )
 
 // Controller
+const CTRL_VERSION uint8 = 2
+
 const (
 	CTRL_CMD_UNSPEC uint8 = iota
 	CTRL_CMD_NEWFAMILY
@@ -89,16 +90,27 @@ const (
 
 const CTRL_ATTR_MAX = N_CTRL_ATTR - 1
 
-type Gea [N_CTRL_ATTR][]byte
-
-func (gea *Gea) Write(b []byte) (int, error) {
+// Attrs returns the attributes that follow the generic netlink header of the
+// given message; or Empty, if none.
+func Attrs(b []byte) []byte {
 	i := nl.NLMSG.Align(nl.SizeofHdr + MSG.Size())
 	if i >= len(b) {
-		nl.IndexAttrByType(gea[:], nl.Empty)
-		return 0, nil
+		return nl.Empty
 	}
-	nl.IndexAttrByType(gea[:], b[i:])
-	return len(b) - i, nil
+	return b[i:]
+}
+
+// Parse generic netlink message attributes to index by type.
+func IndexAttrs(a [][]byte, b []byte) int {
+	attrs := Attrs(b)
+	nl.IndexAttrByType(a, attrs)
+	return len(attrs)
+}
+
+type Gea [N_CTRL_ATTR][]byte
+
+func (gea *Gea) Write(b []byte) (int, error) {
+	return IndexAttrs(gea[:], b), nil
 }
 
 const (
@@ -121,57 +133,11 @@ const (
 
 const CTRL_ATTR_MCAST_GRP_MAX = N_CTRL_ATTR_MCAST_GRP - 1
 
+// GetFamily returns the message type of the named family.
 func GetFamily(sr *nl.SockReceiver, name string) (uint16, error) {
-	seq := nl.Seq()
-	req, err := nl.NewMessage(nl.Hdr{
-		Type:  GENL_ID_CTRL,
-		Flags: nl.NLM_F_REQUEST,
-		Seq:   seq,
-	}, Msg{
-		Cmd: CTRL_CMD_GETFAMILY,
-	}, nl.Attr{CTRL_ATTR_FAMILY_NAME, nl.KstringAttr(name)})
+	f, err := Lookup(sr, name)
 	if err != nil {
-		return 0, fmt.Errorf("GetFamily(%q).Request: %v", name, err)
-	}
-	if err := sr.Sock.Send(req); err != nil {
-		return 0, fmt.Errorf("GetFamily(%q).Send: %v", name, err)
-	}
-	ercv := func(err error) (uint16, error) {
-		return 0, fmt.Errorf("GetFamily(%q).Receive: %v", name, err)
-	}
-	for {
-		b, err := sr.Recv()
-		if err != nil {
-			return ercv(err)
-		}
-		if len(b) < nl.SizeofHdr {
-			return ercv(syscall.ENOMSG)
-		}
-		h := nl.HdrPtr(b)
-		if h == nil {
-			return ercv(nl.Ehdr(len(b)))
-		}
-		if int(h.Len) != len(b) {
-			return ercv(h.Elen())
-		}
-		if sr.Sock.Pid != h.Pid {
-			return ercv(h.Epid())
-		}
-		if seq != h.Seq {
-			return ercv(h.Eseq())
-		}
-		switch h.Type {
-		case nl.NLMSG_ERROR:
-			e := nl.NlmsgerrPtr(b)
-			return ercv(syscall.Errno(-e.Errno))
-		case GENL_ID_CTRL:
-			msg := MsgPtr(b)
-			if msg.Cmd == CTRL_CMD_NEWFAMILY {
-				var gea Gea
-				gea.Write(b)
-				return nl.Uint16(gea[CTRL_ATTR_FAMILY_ID]), nil
-			}
-		}
+		return 0, fmt.Errorf("GetFamily(%q): %v", name, err)
 	}
-	return ercv(syscall.ENOTRECOVERABLE)
+	return f.Id, nil
 }
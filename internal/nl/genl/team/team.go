@@ -0,0 +1,257 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package team provides the generic netlink family of the team driver that
+// teamd otherwise uses to configure link aggregation.
+package team
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/platinasystems/go/internal/nl"
+	"github.com/platinasystems/go/internal/nl/genl"
+)
+
+const TEAM_GENL_NAME = "team"
+const TEAM_GENL_VERSION uint8 = 1
+
+const TEAM_GENL_CHANGE_EVENT_MC_GRP_NAME = "change_event"
+
+const (
+	TEAM_ATTR_UNSPEC       uint16 = iota
+	TEAM_ATTR_TEAM_IFINDEX        // u32
+	TEAM_ATTR_LIST_OPTION         // nest
+	TEAM_ATTR_LIST_PORT           // nest
+
+	N_TEAM_ATTR
+)
+
+const TEAM_ATTR_MAX = N_TEAM_ATTR - 1
+
+// Nested layout of get/set/event team driver options:
+//
+//	TEAM_ATTR_LIST_OPTION
+//		TEAM_ATTR_ITEM_OPTION
+//			TEAM_ATTR_OPTION_*
+//		TEAM_ATTR_ITEM_OPTION
+//			TEAM_ATTR_OPTION_*
+const (
+	TEAM_ATTR_ITEM_OPTION_UNSPEC uint16 = iota
+	TEAM_ATTR_ITEM_OPTION               // nest
+
+	N_TEAM_ATTR_ITEM_OPTION
+)
+
+const TEAM_ATTR_ITEM_OPTION_MAX = N_TEAM_ATTR_ITEM_OPTION - 1
+
+const (
+	TEAM_ATTR_OPTION_UNSPEC       uint16 = iota
+	TEAM_ATTR_OPTION_NAME                // string
+	TEAM_ATTR_OPTION_CHANGED             // flag
+	TEAM_ATTR_OPTION_TYPE                // u8
+	TEAM_ATTR_OPTION_DATA                // dynamic
+	TEAM_ATTR_OPTION_REMOVED             // flag
+	TEAM_ATTR_OPTION_PORT_IFINDEX        // u32, for per-port options
+	TEAM_ATTR_OPTION_ARRAY_INDEX         // u32, for array options
+
+	N_TEAM_ATTR_OPTION
+)
+
+const TEAM_ATTR_OPTION_MAX = N_TEAM_ATTR_OPTION - 1
+
+// Nested layout of get/event port list:
+//
+//	TEAM_ATTR_LIST_PORT
+//		TEAM_ATTR_ITEM_PORT
+//			TEAM_ATTR_PORT_*
+//		TEAM_ATTR_ITEM_PORT
+//			TEAM_ATTR_PORT_*
+const (
+	TEAM_ATTR_ITEM_PORT_UNSPEC uint16 = iota
+	TEAM_ATTR_ITEM_PORT               // nest
+
+	N_TEAM_ATTR_ITEM_PORT
+)
+
+const TEAM_ATTR_ITEM_PORT_MAX = N_TEAM_ATTR_ITEM_PORT - 1
+
+const (
+	TEAM_ATTR_PORT_UNSPEC  uint16 = iota
+	TEAM_ATTR_PORT_IFINDEX        // u32
+	TEAM_ATTR_PORT_CHANGED        // flag
+	TEAM_ATTR_PORT_LINKUP         // flag
+	TEAM_ATTR_PORT_SPEED          // u32
+	TEAM_ATTR_PORT_DUPLEX         // u8
+	TEAM_ATTR_PORT_REMOVED        // flag
+
+	N_TEAM_ATTR_PORT
+)
+
+const TEAM_ATTR_PORT_MAX = N_TEAM_ATTR_PORT - 1
+
+const (
+	TEAM_CMD_NOOP uint8 = iota
+	TEAM_CMD_OPTIONS_SET
+	TEAM_CMD_OPTIONS_GET
+	TEAM_CMD_PORT_LIST_GET
+
+	N_TEAM_CMD
+)
+
+const TEAM_CMD_MAX = N_TEAM_CMD - 1
+
+// Option data types are the kernel's NLA_* policy types.
+const (
+	NLA_U8     uint8 = 1
+	NLA_U32    uint8 = 3
+	NLA_STRING uint8 = 5
+	NLA_FLAG   uint8 = 6
+	NLA_BINARY uint8 = 11
+	NLA_S32    uint8 = 14
+)
+
+type Option struct {
+	Name string
+	Type uint8
+	Data []byte
+	// Non-zero for per-port options.
+	Port uint32
+	// Index of array options.
+	ArrayIndex uint32
+	IsArray    bool
+}
+
+// Value formats the option data by type.
+func (opt *Option) Value() string {
+	switch opt.Type {
+	case NLA_U8:
+		return fmt.Sprint(nl.Uint8(opt.Data))
+	case NLA_U32:
+		return fmt.Sprint(nl.Uint32(opt.Data))
+	case NLA_S32:
+		return fmt.Sprint(nl.Int32(opt.Data))
+	case NLA_STRING:
+		return nl.Kstring(opt.Data)
+	case NLA_FLAG:
+		return fmt.Sprint(opt.Data != nil)
+	}
+	return fmt.Sprintf("%x", opt.Data)
+}
+
+type Port struct {
+	Ifindex uint32
+	Linkup  bool
+	Speed   uint32
+	Duplex  uint8
+}
+
+// Resolve the team family.
+func Family(sr *nl.SockReceiver) (*genl.Family, error) {
+	return genl.Lookup(sr, TEAM_GENL_NAME)
+}
+
+// Options returns the team and port options of the given team interface.
+func Options(sr *nl.SockReceiver, f *genl.Family, ifindex uint32) ([]*Option, error) {
+	var opts []*Option
+	err := f.Do(sr, genl.Msg{
+		Cmd:     TEAM_CMD_OPTIONS_GET,
+		Version: TEAM_GENL_VERSION,
+	}, 0, func(b []byte) {
+		opts = appendOptions(opts, b)
+	}, nl.Attr{TEAM_ATTR_TEAM_IFINDEX, nl.Uint32Attr(ifindex)})
+	return opts, err
+}
+
+// SetOption changes a team option or, with non-zero port, a port option,
+// e.g.
+//
+//	SetOption(sr, f, ifindex, 0, "mode", NLA_STRING,
+//		nl.KstringAttr("loadbalance"))
+//	SetOption(sr, f, ifindex, port, "enabled", NLA_FLAG, nil)
+//
+// A nil value clears a flag option.
+func SetOption(sr *nl.SockReceiver, f *genl.Family, ifindex, port uint32,
+	name string, t uint8, value io.Reader) error {
+	return f.Do(sr, genl.Msg{
+		Cmd:     TEAM_CMD_OPTIONS_SET,
+		Version: TEAM_GENL_VERSION,
+	}, nl.NLM_F_ACK, nl.DoNothing,
+		setOptionAttrs(ifindex, port, name, t, value)...)
+}
+
+func setOptionAttrs(ifindex, port uint32, name string, t uint8,
+	value io.Reader) []nl.Attr {
+	opt := nl.Attrs{
+		nl.Attr{TEAM_ATTR_OPTION_NAME, nl.KstringAttr(name)},
+		nl.Attr{TEAM_ATTR_OPTION_TYPE, nl.Uint8Attr(t)},
+	}
+	if value != nil {
+		opt = append(opt, nl.Attr{TEAM_ATTR_OPTION_DATA, value})
+	}
+	if port != 0 {
+		opt = append(opt, nl.Attr{TEAM_ATTR_OPTION_PORT_IFINDEX,
+			nl.Uint32Attr(port)})
+	}
+	return []nl.Attr{
+		nl.Attr{TEAM_ATTR_TEAM_IFINDEX, nl.Uint32Attr(ifindex)},
+		nl.Attr{TEAM_ATTR_LIST_OPTION, nl.Attrs{
+			nl.Attr{TEAM_ATTR_ITEM_OPTION, opt},
+		}},
+	}
+}
+
+// Ports returns the ports of the given team interface.
+func Ports(sr *nl.SockReceiver, f *genl.Family, ifindex uint32) ([]*Port, error) {
+	var ports []*Port
+	err := f.Do(sr, genl.Msg{
+		Cmd:     TEAM_CMD_PORT_LIST_GET,
+		Version: TEAM_GENL_VERSION,
+	}, 0, func(b []byte) {
+		ports = appendPorts(ports, b)
+	}, nl.Attr{TEAM_ATTR_TEAM_IFINDEX, nl.Uint32Attr(ifindex)})
+	return ports, err
+}
+
+// Append the options listed in an OPTIONS_GET reply or event.
+func appendOptions(opts []*Option, b []byte) []*Option {
+	var a [N_TEAM_ATTR][]byte
+	genl.IndexAttrs(a[:], b)
+	nl.ForEachAttr(a[TEAM_ATTR_LIST_OPTION], func(t uint16, b []byte) {
+		var oa [N_TEAM_ATTR_OPTION][]byte
+		if t != TEAM_ATTR_ITEM_OPTION {
+			return
+		}
+		nl.IndexAttrByType(oa[:], b)
+		opts = append(opts, &Option{
+			Name:       nl.Kstring(oa[TEAM_ATTR_OPTION_NAME]),
+			Type:       nl.Uint8(oa[TEAM_ATTR_OPTION_TYPE]),
+			Data:       oa[TEAM_ATTR_OPTION_DATA],
+			Port:       nl.Uint32(oa[TEAM_ATTR_OPTION_PORT_IFINDEX]),
+			ArrayIndex: nl.Uint32(oa[TEAM_ATTR_OPTION_ARRAY_INDEX]),
+			IsArray:    oa[TEAM_ATTR_OPTION_ARRAY_INDEX] != nil,
+		})
+	})
+	return opts
+}
+
+// Append the ports listed in a PORT_LIST_GET reply or event.
+func appendPorts(ports []*Port, b []byte) []*Port {
+	var a [N_TEAM_ATTR][]byte
+	genl.IndexAttrs(a[:], b)
+	nl.ForEachAttr(a[TEAM_ATTR_LIST_PORT], func(t uint16, b []byte) {
+		var pa [N_TEAM_ATTR_PORT][]byte
+		if t != TEAM_ATTR_ITEM_PORT {
+			return
+		}
+		nl.IndexAttrByType(pa[:], b)
+		ports = append(ports, &Port{
+			Ifindex: nl.Uint32(pa[TEAM_ATTR_PORT_IFINDEX]),
+			Linkup:  pa[TEAM_ATTR_PORT_LINKUP] != nil,
+			Speed:   nl.Uint32(pa[TEAM_ATTR_PORT_SPEED]),
+			Duplex:  nl.Uint8(pa[TEAM_ATTR_PORT_DUPLEX]),
+		})
+	})
+	return ports
+}
@@ -0,0 +1,141 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package team
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/platinasystems/go/internal/nl"
+	"github.com/platinasystems/go/internal/nl/genl"
+)
+
+// A kernel OPTIONS_GET reply with a team, a port and an array option.
+var optionsReply = []byte{
+	0xa0, 0x00, 0x00, 0x00, // len
+	0x1c, 0x00, // type
+	0x00, 0x00, // flags
+	0x01, 0x00, 0x00, 0x00, // seq
+	0x00, 0x00, 0x00, 0x00, // pid
+	0x02, 0x01, 0x00, 0x00, // OPTIONS_GET, version 1
+	0x08, 0x00, 0x01, 0x00, 0x07, 0x00, 0x00, 0x00, // TEAM_IFINDEX
+	0x84, 0x00, 0x02, 0x00, // LIST_OPTION
+	0x28, 0x00, 0x01, 0x00, // ITEM_OPTION
+	0x09, 0x00, 0x01, 0x00, 'm', 'o', 'd', 'e', 0, 0, 0, 0, // NAME
+	0x05, 0x00, 0x03, 0x00, 0x05, 0x00, 0x00, 0x00, // TYPE
+	0x10, 0x00, 0x04, 0x00, // DATA
+	'l', 'o', 'a', 'd', 'b', 'a', 'l', 'a', 'n', 'c', 'e', 0,
+	0x24, 0x00, 0x01, 0x00, // ITEM_OPTION
+	0x0c, 0x00, 0x01, 0x00, 'e', 'n', 'a', 'b', 'l', 'e', 'd', 0, // NAME
+	0x05, 0x00, 0x03, 0x00, 0x06, 0x00, 0x00, 0x00, // TYPE
+	0x04, 0x00, 0x04, 0x00, // DATA
+	0x08, 0x00, 0x06, 0x00, 0x03, 0x00, 0x00, 0x00, // PORT_IFINDEX
+	0x34, 0x00, 0x01, 0x00, // ITEM_OPTION
+	0x12, 0x00, 0x01, 0x00, // NAME
+	'l', 'b', '_', 'h', 'a', 's', 'h', '_',
+	's', 't', 'a', 't', 's', 0, 0, 0,
+	0x05, 0x00, 0x03, 0x00, 0x0b, 0x00, 0x00, 0x00, // TYPE
+	0x0c, 0x00, 0x04, 0x00, // DATA
+	0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x08, 0x00, 0x07, 0x00, 0x02, 0x00, 0x00, 0x00, // ARRAY_INDEX
+}
+
+// A kernel PORT_LIST_GET reply with an up and a down port.
+var portsReply = []byte{
+	0x5c, 0x00, 0x00, 0x00, // len
+	0x1c, 0x00, // type
+	0x00, 0x00, // flags
+	0x01, 0x00, 0x00, 0x00, // seq
+	0x00, 0x00, 0x00, 0x00, // pid
+	0x03, 0x01, 0x00, 0x00, // PORT_LIST_GET, version 1
+	0x08, 0x00, 0x01, 0x00, 0x07, 0x00, 0x00, 0x00, // TEAM_IFINDEX
+	0x40, 0x00, 0x03, 0x00, // LIST_PORT
+	0x20, 0x00, 0x01, 0x00, // ITEM_PORT
+	0x08, 0x00, 0x01, 0x00, 0x03, 0x00, 0x00, 0x00, // IFINDEX
+	0x04, 0x00, 0x03, 0x00, // LINKUP
+	0x08, 0x00, 0x04, 0x00, 0x10, 0x27, 0x00, 0x00, // SPEED
+	0x05, 0x00, 0x05, 0x00, 0x01, 0x00, 0x00, 0x00, // DUPLEX
+	0x1c, 0x00, 0x01, 0x00, // ITEM_PORT
+	0x08, 0x00, 0x01, 0x00, 0x04, 0x00, 0x00, 0x00, // IFINDEX
+	0x08, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, // SPEED
+	0x05, 0x00, 0x05, 0x00, 0x00, 0x00, 0x00, 0x00, // DUPLEX
+}
+
+// An OPTIONS_SET request that enables port 3.
+var enableRequest = []byte{
+	0x44, 0x00, 0x00, 0x00, // len
+	0x1c, 0x00, // type
+	0x05, 0x00, // REQUEST | ACK
+	0x00, 0x00, 0x00, 0x00, // seq
+	0x00, 0x00, 0x00, 0x00, // pid
+	0x01, 0x01, 0x00, 0x00, // OPTIONS_SET, version 1
+	0x08, 0x00, 0x01, 0x00, 0x07, 0x00, 0x00, 0x00, // TEAM_IFINDEX
+	0x28, 0x00, 0x02, 0x00, // LIST_OPTION
+	0x24, 0x00, 0x01, 0x00, // ITEM_OPTION
+	0x0c, 0x00, 0x01, 0x00, 'e', 'n', 'a', 'b', 'l', 'e', 'd', 0, // NAME
+	0x05, 0x00, 0x03, 0x00, 0x06, 0x00, 0x00, 0x00, // TYPE
+	0x04, 0x00, 0x04, 0x00, // DATA
+	0x08, 0x00, 0x06, 0x00, 0x03, 0x00, 0x00, 0x00, // PORT_IFINDEX
+}
+
+func TestOptions(t *testing.T) {
+	opts := appendOptions(nil, optionsReply)
+	want := []*Option{
+		{
+			Name: "mode",
+			Type: NLA_STRING,
+			Data: []byte("loadbalance\x00"),
+		},
+		{
+			Name: "enabled",
+			Type: NLA_FLAG,
+			Data: []byte{},
+			Port: 3,
+		},
+		{
+			Name:       "lb_hash_stats",
+			Type:       NLA_BINARY,
+			Data:       []byte{0x40, 0, 0, 0, 0, 0, 0, 0},
+			ArrayIndex: 2,
+			IsArray:    true,
+		},
+	}
+	if !reflect.DeepEqual(opts, want) {
+		t.Fatalf("got %+v, want %+v", opts, want)
+	}
+	for i, s := range []string{"loadbalance", "true", "4000000000000000"} {
+		if v := opts[i].Value(); v != s {
+			t.Error(opts[i].Name, "value:", v)
+		}
+	}
+}
+
+func TestPorts(t *testing.T) {
+	ports := appendPorts(nil, portsReply)
+	want := []*Port{
+		{Ifindex: 3, Linkup: true, Speed: 10000, Duplex: 1},
+		{Ifindex: 4},
+	}
+	if !reflect.DeepEqual(ports, want) {
+		t.Fatalf("got %+v, want %+v", ports, want)
+	}
+}
+
+func TestSetOptionRequest(t *testing.T) {
+	b, err := nl.NewMessage(nl.Hdr{
+		Type:  0x1c,
+		Flags: nl.NLM_F_REQUEST | nl.NLM_F_ACK,
+	}, genl.Msg{
+		Cmd:     TEAM_CMD_OPTIONS_SET,
+		Version: TEAM_GENL_VERSION,
+	}, setOptionAttrs(7, 3, "enabled", NLA_FLAG, nl.NilAttr{})...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, enableRequest) {
+		t.Fatalf("got\n% x\nwant\n% x", b, enableRequest)
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package wireguard
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/platinasystems/go/internal/nl"
+	"github.com/platinasystems/go/internal/nl/genl"
+)
+
+type Key [WG_KEY_LEN]byte
+
+// Keys are formatted in base64 like wg(8).
+func (key Key) String() string {
+	return base64.StdEncoding.EncodeToString(key[:])
+}
+
+func (key Key) IsZero() bool { return key == Key{} }
+
+func ParseKey(s string) (Key, error) {
+	var key Key
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(b) != len(key) {
+		return key, fmt.Errorf("%s: invalid key", s)
+	}
+	copy(key[:], b)
+	return key, nil
+}
+
+type Device struct {
+	Ifindex    uint32
+	Name       string
+	PrivateKey Key
+	PublicKey  Key
+	ListenPort uint16
+	Fwmark     uint32
+	Peers      []*Peer
+}
+
+type Peer struct {
+	PublicKey    Key
+	PresharedKey Key
+	Endpoint     *net.UDPAddr
+	// Seconds, or 0 if disabled.
+	Keepalive     uint16
+	LastHandshake time.Time
+	RxBytes       uint64
+	TxBytes       uint64
+	AllowedIPs    []net.IPNet
+}
+
+// Resolve the wireguard family.
+func Family(sr *nl.SockReceiver) (*genl.Family, error) {
+	return genl.Lookup(sr, WG_GENL_NAME)
+}
+
+// GetDevice returns the configuration and peer status of the interface.
+func GetDevice(sr *nl.SockReceiver, f *genl.Family, ifname string) (*Device, error) {
+	dev := new(Device)
+	err := f.Do(sr, genl.Msg{
+		Cmd:     WG_CMD_GET_DEVICE,
+		Version: WG_GENL_VERSION,
+	}, nl.NLM_F_DUMP, func(b []byte) {
+		dev.write(b)
+	}, nl.Attr{WGDEVICE_A_IFNAME, nl.KstringAttr(ifname)})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", ifname, err)
+	}
+	return dev, nil
+}
+
+// Large devices are dumped in multiple messages that repeat the device
+// attributes and may split the allowed IPs of a peer.
+func (dev *Device) write(b []byte) {
+	var a [N_WGDEVICE_A][]byte
+	genl.IndexAttrs(a[:], b)
+	dev.Ifindex = nl.Uint32(a[WGDEVICE_A_IFINDEX])
+	dev.Name = nl.Kstring(a[WGDEVICE_A_IFNAME])
+	copy(dev.PrivateKey[:], a[WGDEVICE_A_PRIVATE_KEY])
+	copy(dev.PublicKey[:], a[WGDEVICE_A_PUBLIC_KEY])
+	dev.ListenPort = nl.Uint16(a[WGDEVICE_A_LISTEN_PORT])
+	dev.Fwmark = nl.Uint32(a[WGDEVICE_A_FWMARK])
+	nl.ForEachAttr(a[WGDEVICE_A_PEERS], func(_ uint16, b []byte) {
+		var pa [N_WGPEER_A][]byte
+		nl.IndexAttrByType(pa[:], b)
+		var key Key
+		copy(key[:], pa[WGPEER_A_PUBLIC_KEY])
+		var peer *Peer
+		if n := len(dev.Peers); n > 0 && dev.Peers[n-1].PublicKey == key {
+			peer = dev.Peers[n-1]
+		} else {
+			peer = &Peer{PublicKey: key}
+			dev.Peers = append(dev.Peers, peer)
+		}
+		peer.write(&pa)
+	})
+}
+
+func (peer *Peer) write(pa *[N_WGPEER_A][]byte) {
+	if val := pa[WGPEER_A_PRESHARED_KEY]; len(val) > 0 {
+		copy(peer.PresharedKey[:], val)
+	}
+	if val := pa[WGPEER_A_ENDPOINT]; len(val) > 0 {
+		peer.Endpoint = sockaddr(val)
+	}
+	if val := pa[WGPEER_A_PERSISTENT_KEEPALIVE_INTERVAL]; len(val) > 0 {
+		peer.Keepalive = nl.Uint16(val)
+	}
+	if val := pa[WGPEER_A_LAST_HANDSHAKE_TIME]; len(val) >= 16 {
+		sec, nsec := nl.Int64(val), nl.Int64(val[8:])
+		if sec != 0 || nsec != 0 {
+			peer.LastHandshake = time.Unix(sec, nsec)
+		}
+	}
+	if val := pa[WGPEER_A_RX_BYTES]; len(val) > 0 {
+		peer.RxBytes = nl.Uint64(val)
+	}
+	if val := pa[WGPEER_A_TX_BYTES]; len(val) > 0 {
+		peer.TxBytes = nl.Uint64(val)
+	}
+	nl.ForEachAttr(pa[WGPEER_A_ALLOWEDIPS], func(_ uint16, b []byte) {
+		var ia [N_WGALLOWEDIP_A][]byte
+		nl.IndexAttrByType(ia[:], b)
+		ip := net.IP(append([]byte{}, ia[WGALLOWEDIP_A_IPADDR]...))
+		bits := 8 * len(ip)
+		if bits != 32 && bits != 128 {
+			return
+		}
+		mask := net.CIDRMask(int(nl.Uint8(ia[WGALLOWEDIP_A_CIDR_MASK])),
+			bits)
+		peer.AllowedIPs = append(peer.AllowedIPs, net.IPNet{
+			IP:   ip,
+			Mask: mask,
+		})
+	})
+}
+
+// SetDevice configures the interface and its peers. Zero keys, ports and
+// marks are unchanged; flags may include WGDEVICE_F_REPLACE_PEERS. Each
+// peer replaces its allowed IPs.
+func SetDevice(sr *nl.SockReceiver, f *genl.Family, dev *Device,
+	flags uint32) error {
+	err := f.Do(sr, genl.Msg{
+		Cmd:     WG_CMD_SET_DEVICE,
+		Version: WG_GENL_VERSION,
+	}, nl.NLM_F_ACK, nl.DoNothing, dev.attrs(flags)...)
+	if err != nil {
+		err = fmt.Errorf("%s: %v", dev.Name, err)
+	}
+	return err
+}
+
+// RemovePeer removes the peer with the given public key.
+func RemovePeer(sr *nl.SockReceiver, f *genl.Family, ifname string,
+	key Key) error {
+	return f.Do(sr, genl.Msg{
+		Cmd:     WG_CMD_SET_DEVICE,
+		Version: WG_GENL_VERSION,
+	}, nl.NLM_F_ACK, nl.DoNothing,
+		nl.Attr{WGDEVICE_A_IFNAME, nl.KstringAttr(ifname)},
+		nl.Attr{WGDEVICE_A_PEERS | nl.NLA_F_NESTED, nl.Attrs{
+			nl.Attr{nl.NLA_F_NESTED, nl.Attrs{
+				nl.Attr{WGPEER_A_PUBLIC_KEY,
+					nl.BytesAttr(key[:])},
+				nl.Attr{WGPEER_A_FLAGS,
+					nl.Uint32Attr(WGPEER_F_REMOVE_ME)},
+			}},
+		}})
+}
+
+func (dev *Device) attrs(flags uint32) []nl.Attr {
+	attrs := []nl.Attr{
+		nl.Attr{WGDEVICE_A_IFNAME, nl.KstringAttr(dev.Name)},
+	}
+	if !dev.PrivateKey.IsZero() {
+		attrs = append(attrs, nl.Attr{WGDEVICE_A_PRIVATE_KEY,
+			nl.BytesAttr(dev.PrivateKey[:])})
+	}
+	if dev.ListenPort != 0 {
+		attrs = append(attrs, nl.Attr{WGDEVICE_A_LISTEN_PORT,
+			nl.Uint16Attr(dev.ListenPort)})
+	}
+	if dev.Fwmark != 0 {
+		attrs = append(attrs, nl.Attr{WGDEVICE_A_FWMARK,
+			nl.Uint32Attr(dev.Fwmark)})
+	}
+	if flags != 0 {
+		attrs = append(attrs, nl.Attr{WGDEVICE_A_FLAGS,
+			nl.Uint32Attr(flags)})
+	}
+	if len(dev.Peers) > 0 {
+		var peers nl.Attrs
+		for i, peer := range dev.Peers {
+			peers = append(peers, nl.Attr{uint16(i) | nl.NLA_F_NESTED,
+				peer.attrs()})
+		}
+		attrs = append(attrs, nl.Attr{WGDEVICE_A_PEERS | nl.NLA_F_NESTED,
+			peers})
+	}
+	return attrs
+}
+
+func (peer *Peer) attrs() nl.Attrs {
+	attrs := nl.Attrs{
+		nl.Attr{WGPEER_A_PUBLIC_KEY, nl.BytesAttr(peer.PublicKey[:])},
+		nl.Attr{WGPEER_A_FLAGS,
+			nl.Uint32Attr(WGPEER_F_REPLACE_ALLOWEDIPS)},
+		nl.Attr{WGPEER_A_PERSISTENT_KEEPALIVE_INTERVAL,
+			nl.Uint16Attr(peer.Keepalive)},
+	}
+	if !peer.PresharedKey.IsZero() {
+		attrs = append(attrs, nl.Attr{WGPEER_A_PRESHARED_KEY,
+			nl.BytesAttr(peer.PresharedKey[:])})
+	}
+	if peer.Endpoint != nil {
+		attrs = append(attrs, nl.Attr{WGPEER_A_ENDPOINT,
+			nl.BytesAttr(sockaddrBytes(peer.Endpoint))})
+	}
+	var ips nl.Attrs
+	for i, ipnet := range peer.AllowedIPs {
+		family, ip := uint16(syscall.AF_INET), ipnet.IP.To4()
+		if ip == nil {
+			family, ip = syscall.AF_INET6, ipnet.IP.To16()
+		}
+		ones, _ := ipnet.Mask.Size()
+		ips = append(ips, nl.Attr{uint16(i) | nl.NLA_F_NESTED, nl.Attrs{
+			nl.Attr{WGALLOWEDIP_A_FAMILY, nl.Uint16Attr(family)},
+			nl.Attr{WGALLOWEDIP_A_IPADDR, nl.BytesAttr(ip)},
+			nl.Attr{WGALLOWEDIP_A_CIDR_MASK, nl.Uint8Attr(ones)},
+		}})
+	}
+	return append(attrs, nl.Attr{WGPEER_A_ALLOWEDIPS | nl.NLA_F_NESTED,
+		ips})
+}
+
+// Decode a sockaddr_in or sockaddr_in6.
+func sockaddr(b []byte) *net.UDPAddr {
+	if len(b) < 4 {
+		return nil
+	}
+	port := int(b[2])<<8 | int(b[3])
+	switch nl.Uint16(b) {
+	case syscall.AF_INET:
+		if len(b) >= 8 {
+			return &net.UDPAddr{
+				IP:   net.IP(append([]byte{}, b[4:8]...)),
+				Port: port,
+			}
+		}
+	case syscall.AF_INET6:
+		if len(b) >= 24 {
+			return &net.UDPAddr{
+				IP:   net.IP(append([]byte{}, b[8:24]...)),
+				Port: port,
+			}
+		}
+	}
+	return nil
+}
+
+// Encode a sockaddr_in or sockaddr_in6.
+func sockaddrBytes(addr *net.UDPAddr) []byte {
+	var b []byte
+	family := uint16(syscall.AF_INET)
+	if ip4 := addr.IP.To4(); ip4 != nil {
+		b = make([]byte, syscall.SizeofSockaddrInet4)
+		copy(b[4:], ip4)
+	} else {
+		family = syscall.AF_INET6
+		b = make([]byte, syscall.SizeofSockaddrInet6)
+		copy(b[8:], addr.IP.To16())
+	}
+	nl.Uint16Attr(family).Read(b)
+	nl.Be16Attr(addr.Port).Read(b[2:])
+	return b
+}
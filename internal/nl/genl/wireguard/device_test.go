@@ -0,0 +1,242 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package wireguard
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/platinasystems/go/internal/nl"
+	"github.com/platinasystems/go/internal/nl/genl"
+)
+
+func join(b ...[]byte) []byte { return bytes.Join(b, nil) }
+
+func keyBytes(c byte) []byte { return bytes.Repeat([]byte{c}, WG_KEY_LEN) }
+
+func key(c byte) (k Key) {
+	copy(k[:], keyBytes(c))
+	return
+}
+
+// Device attributes that the kernel repeats in each dump message.
+var deviceAttrs = join(
+	[]byte{0x08, 0x00, 0x01, 0x00, 0x04, 0x00, 0x00, 0x00}, // IFINDEX
+	[]byte{0x08, 0x00, 0x02, 0x00, 'w', 'g', '0', 0},       // IFNAME
+	// PRIVATE_KEY
+	[]byte{0x24, 0x00, 0x03, 0x00}, keyBytes(0x11),
+	// PUBLIC_KEY
+	[]byte{0x24, 0x00, 0x04, 0x00}, keyBytes(0x22),
+	[]byte{0x06, 0x00, 0x06, 0x00, 0x6c, 0xca, 0x00, 0x00}, // LISTEN_PORT
+	[]byte{0x08, 0x00, 0x07, 0x00, 0x00, 0x00, 0x00, 0x00}, // FWMARK
+)
+
+// A kernel GET_DEVICE dump of two messages that split the allowed IPs of the
+// first peer.
+var deviceDump = [][]byte{
+	join(
+		[]byte{
+			0x34, 0x01, 0x00, 0x00, // len
+			0x17, 0x00, // type
+			0x02, 0x00, // MULTI
+			0x01, 0x00, 0x00, 0x00, // seq
+			0x00, 0x00, 0x00, 0x00, // pid
+			0x00, 0x01, 0x00, 0x00, // GET_DEVICE, version 1
+		},
+		deviceAttrs,
+		[]byte{0xb8, 0x00, 0x08, 0x80}, // PEERS
+		[]byte{0xb4, 0x00, 0x00, 0x80}, // 0
+		// PUBLIC_KEY
+		[]byte{0x24, 0x00, 0x01, 0x00}, keyBytes(0x33),
+		// PRESHARED_KEY
+		[]byte{0x24, 0x00, 0x02, 0x00}, keyBytes(0),
+		[]byte{
+			0x14, 0x00, 0x06, 0x00, // LAST_HANDSHAKE_TIME
+			0x00, 0x10, 0x5e, 0x5f, 0x00, 0x00, 0x00, 0x00,
+			0xf4, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x06, 0x00, 0x05, 0x00, 0x19, 0x00, 0x00, 0x00, // KEEPALIVE
+			0x0c, 0x00, 0x07, 0x00, // RX_BYTES
+			0xe8, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x0c, 0x00, 0x08, 0x00, // TX_BYTES
+			0xd0, 0x07, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x14, 0x00, 0x04, 0x00, // ENDPOINT
+			0x02, 0x00, 0xca, 0x6c, 0xc0, 0x00, 0x02, 0x01,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x20, 0x00, 0x09, 0x80, // ALLOWEDIPS
+			0x1c, 0x00, 0x00, 0x80, // 0
+			0x06, 0x00, 0x01, 0x00, 0x02, 0x00, 0x00, 0x00, // FAMILY
+			0x08, 0x00, 0x02, 0x00, 0x0a, 0x01, 0x00, 0x00, // IPADDR
+			0x05, 0x00, 0x03, 0x00, 0x10, 0x00, 0x00, 0x00, // CIDR_MASK
+		},
+	),
+	join(
+		[]byte{
+			0x58, 0x01, 0x00, 0x00, // len
+			0x17, 0x00, // type
+			0x02, 0x00, // MULTI
+			0x01, 0x00, 0x00, 0x00, // seq
+			0x00, 0x00, 0x00, 0x00, // pid
+			0x00, 0x01, 0x00, 0x00, // GET_DEVICE, version 1
+		},
+		deviceAttrs,
+		[]byte{0xdc, 0x00, 0x08, 0x80}, // PEERS
+		[]byte{0x54, 0x00, 0x00, 0x80}, // 0
+		// PUBLIC_KEY
+		[]byte{0x24, 0x00, 0x01, 0x00}, keyBytes(0x33),
+		[]byte{
+			0x2c, 0x00, 0x09, 0x80, // ALLOWEDIPS
+			0x28, 0x00, 0x00, 0x80, // 0
+			0x06, 0x00, 0x01, 0x00, 0x0a, 0x00, 0x00, 0x00, // FAMILY
+			0x14, 0x00, 0x02, 0x00, // IPADDR
+			0x20, 0x01, 0x0d, 0xb8, 0x00, 0x00, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x05, 0x00, 0x03, 0x00, 0x20, 0x00, 0x00, 0x00, // CIDR_MASK
+		},
+		[]byte{0x84, 0x00, 0x01, 0x80}, // 1
+		// PUBLIC_KEY
+		[]byte{0x24, 0x00, 0x01, 0x00}, keyBytes(0x44),
+		// PRESHARED_KEY
+		[]byte{0x24, 0x00, 0x02, 0x00}, keyBytes(0x55),
+		[]byte{
+			0x14, 0x00, 0x06, 0x00, // LAST_HANDSHAKE_TIME
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x06, 0x00, 0x05, 0x00, 0x00, 0x00, 0x00, 0x00, // KEEPALIVE
+			0x0c, 0x00, 0x07, 0x00, // RX_BYTES
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x0c, 0x00, 0x08, 0x00, // TX_BYTES
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x04, 0x00, 0x09, 0x80, // ALLOWEDIPS
+		},
+	),
+}
+
+// A SET_DEVICE request that replaces all peers with one.
+var setRequest = join(
+	[]byte{
+		0xc0, 0x00, 0x00, 0x00, // len
+		0x17, 0x00, // type
+		0x05, 0x00, // REQUEST | ACK
+		0x00, 0x00, 0x00, 0x00, // seq
+		0x00, 0x00, 0x00, 0x00, // pid
+		0x01, 0x01, 0x00, 0x00, // SET_DEVICE, version 1
+		0x08, 0x00, 0x02, 0x00, 'w', 'g', '0', 0, // IFNAME
+	},
+	// PRIVATE_KEY
+	[]byte{0x24, 0x00, 0x03, 0x00}, keyBytes(0x11),
+	[]byte{
+		0x06, 0x00, 0x06, 0x00, 0x6c, 0xca, 0x00, 0x00, // LISTEN_PORT
+		0x08, 0x00, 0x05, 0x00, 0x01, 0x00, 0x00, 0x00, // FLAGS
+		0x70, 0x00, 0x08, 0x80, // PEERS
+		0x6c, 0x00, 0x00, 0x80, // 0
+	},
+	// PUBLIC_KEY
+	[]byte{0x24, 0x00, 0x01, 0x00}, keyBytes(0x33),
+	[]byte{
+		0x08, 0x00, 0x03, 0x00, 0x02, 0x00, 0x00, 0x00, // FLAGS
+		0x06, 0x00, 0x05, 0x00, 0x19, 0x00, 0x00, 0x00, // KEEPALIVE
+		0x14, 0x00, 0x04, 0x00, // ENDPOINT
+		0x02, 0x00, 0xca, 0x6c, 0xc0, 0x00, 0x02, 0x01,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x20, 0x00, 0x09, 0x80, // ALLOWEDIPS
+		0x1c, 0x00, 0x00, 0x80, // 0
+		0x06, 0x00, 0x01, 0x00, 0x02, 0x00, 0x00, 0x00, // FAMILY
+		0x08, 0x00, 0x02, 0x00, 0x0a, 0x01, 0x00, 0x00, // IPADDR
+		0x05, 0x00, 0x03, 0x00, 0x10, 0x00, 0x00, 0x00, // CIDR_MASK
+	},
+)
+
+var (
+	endpoint = &net.UDPAddr{IP: net.IP{192, 0, 2, 1}, Port: 51820}
+	net10    = net.IPNet{
+		IP:   net.IP{10, 1, 0, 0},
+		Mask: net.CIDRMask(16, 32),
+	}
+	net2001 = net.IPNet{
+		IP:   net.ParseIP("2001:db8::"),
+		Mask: net.CIDRMask(32, 128),
+	}
+)
+
+func TestDeviceWrite(t *testing.T) {
+	dev := new(Device)
+	for _, b := range deviceDump {
+		if l := int(nl.HdrPtr(b).Len); l != len(b) {
+			t.Fatalf("fixture len %d, have %d", l, len(b))
+		}
+		dev.write(b)
+	}
+	want := &Device{
+		Ifindex:    4,
+		Name:       "wg0",
+		PrivateKey: key(0x11),
+		PublicKey:  key(0x22),
+		ListenPort: 51820,
+		Peers: []*Peer{
+			{
+				PublicKey:     key(0x33),
+				Endpoint:      endpoint,
+				Keepalive:     25,
+				LastHandshake: time.Unix(1600000000, 500),
+				RxBytes:       1000,
+				TxBytes:       2000,
+				AllowedIPs:    []net.IPNet{net10, net2001},
+			},
+			{
+				PublicKey:    key(0x44),
+				PresharedKey: key(0x55),
+			},
+		},
+	}
+	if !reflect.DeepEqual(dev, want) {
+		t.Fatalf("got %+v, want %+v", dev, want)
+	}
+}
+
+func TestSetDeviceRequest(t *testing.T) {
+	dev := &Device{
+		Name:       "wg0",
+		PrivateKey: key(0x11),
+		ListenPort: 51820,
+		Peers: []*Peer{
+			{
+				PublicKey:  key(0x33),
+				Endpoint:   endpoint,
+				Keepalive:  25,
+				AllowedIPs: []net.IPNet{net10},
+			},
+		},
+	}
+	b, err := nl.NewMessage(nl.Hdr{
+		Type:  0x17,
+		Flags: nl.NLM_F_REQUEST | nl.NLM_F_ACK,
+	}, genl.Msg{
+		Cmd:     WG_CMD_SET_DEVICE,
+		Version: WG_GENL_VERSION,
+	}, dev.attrs(WGDEVICE_F_REPLACE_PEERS)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, setRequest) {
+		t.Fatalf("got\n% x\nwant\n% x", b, setRequest)
+	}
+}
+
+func TestKey(t *testing.T) {
+	k := key(0x11)
+	s := k.String()
+	if s != "ERERERERERERERERERERERERERERERERERERERERERE=" {
+		t.Error("string:", s)
+	}
+	if got, err := ParseKey(s); err != nil || got != k {
+		t.Error("parse:", got, err)
+	}
+	if _, err := ParseKey("ERER"); err == nil {
+		t.Error("short key parsed")
+	}
+}
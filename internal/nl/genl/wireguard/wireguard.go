@@ -0,0 +1,74 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package wireguard provides the generic netlink family used by wg to
+// configure wireguard interfaces.
+package wireguard
+
+const WG_GENL_NAME = "wireguard"
+const WG_GENL_VERSION uint8 = 1
+
+const WG_KEY_LEN = 32
+
+const (
+	WG_CMD_GET_DEVICE uint8 = iota
+	WG_CMD_SET_DEVICE
+
+	N_WG_CMD
+)
+
+const WG_CMD_MAX = N_WG_CMD - 1
+
+const WGDEVICE_F_REPLACE_PEERS uint32 = 1 << 0
+
+const (
+	WGDEVICE_A_UNSPEC      uint16 = iota
+	WGDEVICE_A_IFINDEX            // u32
+	WGDEVICE_A_IFNAME             // string
+	WGDEVICE_A_PRIVATE_KEY        // [WG_KEY_LEN]byte
+	WGDEVICE_A_PUBLIC_KEY         // [WG_KEY_LEN]byte
+	WGDEVICE_A_FLAGS              // u32, WGDEVICE_F_*
+	WGDEVICE_A_LISTEN_PORT        // u16
+	WGDEVICE_A_FWMARK             // u32
+	WGDEVICE_A_PEERS              // nest of nested WGPEER_A_*
+
+	N_WGDEVICE_A
+)
+
+const WGDEVICE_A_MAX = N_WGDEVICE_A - 1
+
+const (
+	WGPEER_F_REMOVE_ME uint32 = 1 << iota
+	WGPEER_F_REPLACE_ALLOWEDIPS
+	WGPEER_F_UPDATE_ONLY
+)
+
+const (
+	WGPEER_A_UNSPEC                        uint16 = iota
+	WGPEER_A_PUBLIC_KEY                           // [WG_KEY_LEN]byte
+	WGPEER_A_PRESHARED_KEY                        // [WG_KEY_LEN]byte
+	WGPEER_A_FLAGS                                // u32, WGPEER_F_*
+	WGPEER_A_ENDPOINT                             // sockaddr_in or sockaddr_in6
+	WGPEER_A_PERSISTENT_KEEPALIVE_INTERVAL        // u16
+	WGPEER_A_LAST_HANDSHAKE_TIME                  // struct __kernel_timespec
+	WGPEER_A_RX_BYTES                             // u64
+	WGPEER_A_TX_BYTES                             // u64
+	WGPEER_A_ALLOWEDIPS                           // nest of nested WGALLOWEDIP_A_*
+	WGPEER_A_PROTOCOL_VERSION                     // u32
+
+	N_WGPEER_A
+)
+
+const WGPEER_A_MAX = N_WGPEER_A - 1
+
+const (
+	WGALLOWEDIP_A_UNSPEC    uint16 = iota
+	WGALLOWEDIP_A_FAMILY           // u16
+	WGALLOWEDIP_A_IPADDR           // struct in_addr or in6_addr
+	WGALLOWEDIP_A_CIDR_MASK        // u8
+
+	N_WGALLOWEDIP_A
+)
+
+const WGALLOWEDIP_A_MAX = N_WGALLOWEDIP_A - 1
@@ -5,6 +5,8 @@
 package rtnl
 
 import (
+	"strings"
+	"syscall"
 	"unsafe"
 
 	"github.com/platinasystems/go/internal/nl"
@@ -70,6 +72,10 @@ const (
 	FRA_PAD
 	FRA_L3MDEV
 	FRA_UID_RANGE
+	FRA_PROTOCOL
+	FRA_IP_PROTO
+	FRA_SPORT_RANGE
+	FRA_DPORT_RANGE
 	N_FRA
 )
 
@@ -103,6 +109,24 @@ const (
 
 const FR_ACT_MAX = N_FR_ACT - 1
 
+// Rule types that, other than "unicast", don't lookup a table.
+var FrActByName = map[string]uint8{
+	"unicast":     FR_ACT_TO_TBL,
+	"nop":         FR_ACT_NOP,
+	"blackhole":   FR_ACT_BLACKHOLE,
+	"unreachable": FR_ACT_UNREACHABLE,
+	"prohibit":    FR_ACT_PROHIBIT,
+}
+
+func CompleteFrAct(s string) (list []string) {
+	for k := range FrActByName {
+		if len(s) == 0 || strings.HasPrefix(k, s) {
+			list = append(list, k)
+		}
+	}
+	return
+}
+
 const SizeofFibRuleUidRange = 4 + 4
 
 type FibRuleUidRange struct {
@@ -116,3 +140,42 @@ func FibRuleUidRangePtr(b []byte) *FibRuleUidRange {
 	}
 	return (*FibRuleUidRange)(unsafe.Pointer(&b[nl.SizeofHdr]))
 }
+
+// FibRuleUidRangeAttrPtr returns the FRA_UID_RANGE attribute value.
+func FibRuleUidRangeAttrPtr(b []byte) *FibRuleUidRange {
+	if len(b) < SizeofFibRuleUidRange {
+		return nil
+	}
+	return (*FibRuleUidRange)(unsafe.Pointer(&b[0]))
+}
+
+func (r FibRuleUidRange) Read(b []byte) (int, error) {
+	if len(b) < SizeofFibRuleUidRange {
+		return 0, syscall.EOVERFLOW
+	}
+	*(*FibRuleUidRange)(unsafe.Pointer(&b[0])) = r
+	return SizeofFibRuleUidRange, nil
+}
+
+const SizeofFibRulePortRange = 2 + 2
+
+// Value of FRA_SPORT_RANGE and FRA_DPORT_RANGE in host byte order.
+type FibRulePortRange struct {
+	Start uint16
+	End   uint16
+}
+
+func FibRulePortRangeAttrPtr(b []byte) *FibRulePortRange {
+	if len(b) < SizeofFibRulePortRange {
+		return nil
+	}
+	return (*FibRulePortRange)(unsafe.Pointer(&b[0]))
+}
+
+func (r FibRulePortRange) Read(b []byte) (int, error) {
+	if len(b) < SizeofFibRulePortRange {
+		return 0, syscall.EOVERFLOW
+	}
+	*(*FibRulePortRange)(unsafe.Pointer(&b[0])) = r
+	return SizeofFibRulePortRange, nil
+}
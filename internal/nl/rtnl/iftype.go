@@ -5,8 +5,12 @@
 package rtnl
 
 import (
+	"sort"
+	"strings"
 	"syscall"
 	"unsafe"
+
+	"github.com/platinasystems/go/internal/nl"
 )
 
 const (
@@ -432,3 +436,212 @@ const MACSEC_VALIDATE_MAX = N_MACSEC_VALIDATE - 1
 
 const MACSEC_DEFAULT_CIPHER_ID uint64 = 0x0080020001000001
 const MACSEC_DEFAULT_CIPHER_ALT uint64 = 0x0080C20001000001
+
+const (
+	IFLA_BOND_UNSPEC uint16 = iota
+	IFLA_BOND_MODE
+	IFLA_BOND_ACTIVE_SLAVE
+	IFLA_BOND_MIIMON
+	IFLA_BOND_UPDELAY
+	IFLA_BOND_DOWNDELAY
+	IFLA_BOND_USE_CARRIER
+	IFLA_BOND_ARP_INTERVAL
+	IFLA_BOND_ARP_IP_TARGET
+	IFLA_BOND_ARP_VALIDATE
+	IFLA_BOND_ARP_ALL_TARGETS
+	IFLA_BOND_PRIMARY
+	IFLA_BOND_PRIMARY_RESELECT
+	IFLA_BOND_FAIL_OVER_MAC
+	IFLA_BOND_XMIT_HASH_POLICY
+	IFLA_BOND_RESEND_IGMP
+	IFLA_BOND_NUM_PEER_NOTIF
+	IFLA_BOND_ALL_SLAVES_ACTIVE
+	IFLA_BOND_MIN_LINKS
+	IFLA_BOND_LP_INTERVAL
+	IFLA_BOND_PACKETS_PER_SLAVE
+	IFLA_BOND_AD_LACP_RATE
+	IFLA_BOND_AD_SELECT
+	IFLA_BOND_AD_INFO
+	IFLA_BOND_AD_ACTOR_SYS_PRIO
+	IFLA_BOND_AD_USER_PORT_KEY
+	IFLA_BOND_AD_ACTOR_SYSTEM
+	IFLA_BOND_TLB_DYNAMIC_LB
+	IFLA_BOND_PEER_NOTIF_DELAY
+	IFLA_BOND_AD_LACP_ACTIVE
+	IFLA_BOND_MISSED_MAX
+	IFLA_BOND_NS_IP6_TARGET
+	IFLA_BOND_COUPLED_CONTROL
+	N_IFLA_BOND
+)
+
+const IFLA_BOND_MAX = N_IFLA_BOND - 1
+
+const (
+	BOND_MODE_ROUNDROBIN uint8 = iota
+	BOND_MODE_ACTIVEBACKUP
+	BOND_MODE_XOR
+	BOND_MODE_BROADCAST
+	BOND_MODE_8023AD
+	BOND_MODE_TLB
+	BOND_MODE_ALB
+)
+
+var BondModeByName = map[string]uint8{
+	"balance-rr":    BOND_MODE_ROUNDROBIN,
+	"active-backup": BOND_MODE_ACTIVEBACKUP,
+	"balance-xor":   BOND_MODE_XOR,
+	"broadcast":     BOND_MODE_BROADCAST,
+	"802.3ad":       BOND_MODE_8023AD,
+	"balance-tlb":   BOND_MODE_TLB,
+	"balance-alb":   BOND_MODE_ALB,
+}
+
+const (
+	BOND_XMIT_POLICY_LAYER2 uint8 = iota
+	BOND_XMIT_POLICY_LAYER34
+	BOND_XMIT_POLICY_LAYER23
+	BOND_XMIT_POLICY_ENCAP23
+	BOND_XMIT_POLICY_ENCAP34
+	BOND_XMIT_POLICY_VLAN_SRCMAC
+)
+
+var BondXmitHashPolicyByName = map[string]uint8{
+	"layer2":      BOND_XMIT_POLICY_LAYER2,
+	"layer3+4":    BOND_XMIT_POLICY_LAYER34,
+	"layer2+3":    BOND_XMIT_POLICY_LAYER23,
+	"encap2+3":    BOND_XMIT_POLICY_ENCAP23,
+	"encap3+4":    BOND_XMIT_POLICY_ENCAP34,
+	"vlan+srcmac": BOND_XMIT_POLICY_VLAN_SRCMAC,
+}
+
+var BondLacpRateByName = map[string]uint8{
+	"slow": 0,
+	"fast": 1,
+}
+
+var BondAdSelectByName = map[string]uint8{
+	"stable":    0,
+	"bandwidth": 1,
+	"count":     2,
+}
+
+var BondArpValidateByName = map[string]uint32{
+	"none":          0,
+	"active":        1,
+	"backup":        2,
+	"all":           3,
+	"filter":        4,
+	"filter_active": 5,
+	"filter_backup": 6,
+}
+
+var BondArpAllTargetsByName = map[string]uint32{
+	"any": 0,
+	"all": 1,
+}
+
+var BondPrimaryReselectByName = map[string]uint8{
+	"always":  0,
+	"better":  1,
+	"failure": 2,
+}
+
+var BondFailOverMacByName = map[string]uint8{
+	"none":   0,
+	"active": 1,
+	"follow": 2,
+}
+
+func CompleteBondMode(s string) (list []string) {
+	for k := range BondModeByName {
+		if len(s) == 0 || strings.HasPrefix(k, s) {
+			list = append(list, k)
+		}
+	}
+	if len(list) > 0 {
+		sort.Strings(list)
+	}
+	return
+}
+
+const (
+	IFLA_BOND_SLAVE_UNSPEC uint16 = iota
+	IFLA_BOND_SLAVE_STATE
+	IFLA_BOND_SLAVE_MII_STATUS
+	IFLA_BOND_SLAVE_LINK_FAILURE_COUNT
+	IFLA_BOND_SLAVE_PERM_HWADDR
+	IFLA_BOND_SLAVE_QUEUE_ID
+	IFLA_BOND_SLAVE_AD_AGGREGATOR_ID
+	IFLA_BOND_SLAVE_AD_ACTOR_OPER_PORT_STATE
+	IFLA_BOND_SLAVE_AD_PARTNER_OPER_PORT_STATE
+	IFLA_BOND_SLAVE_PRIO
+	N_IFLA_BOND_SLAVE
+)
+
+const IFLA_BOND_SLAVE_MAX = N_IFLA_BOND_SLAVE - 1
+
+const (
+	VETH_INFO_UNSPEC uint16 = iota
+	VETH_INFO_PEER
+	N_VETH_INFO
+)
+
+const VETH_INFO_MAX = N_VETH_INFO - 1
+
+// VethPeer is the VETH_INFO_PEER value, an IfInfoMsg followed by the
+// peer's IFLA attributes.
+type VethPeer struct {
+	IfInfoMsg
+	nl.Attrs
+}
+
+func (v VethPeer) Read(b []byte) (int, error) {
+	if len(b) < SizeofIfInfoMsg {
+		return 0, syscall.EOVERFLOW
+	}
+	v.IfInfoMsg.Read(b)
+	n, err := v.Attrs.Read(b[SizeofIfInfoMsg:])
+	if err != nil {
+		return 0, err
+	}
+	return SizeofIfInfoMsg + n, nil
+}
+
+const (
+	IFLA_IPVLAN_UNSPEC uint16 = iota
+	IFLA_IPVLAN_MODE
+	IFLA_IPVLAN_FLAGS
+	N_IFLA_IPVLAN
+)
+
+const IFLA_IPVLAN_MAX = N_IFLA_IPVLAN - 1
+
+const (
+	IPVLAN_MODE_L2 uint16 = iota
+	IPVLAN_MODE_L3
+	IPVLAN_MODE_L3S
+)
+
+var IpvlanModeByName = map[string]uint16{
+	"l2":  IPVLAN_MODE_L2,
+	"l3":  IPVLAN_MODE_L3,
+	"l3s": IPVLAN_MODE_L3S,
+}
+
+const (
+	IPVLAN_F_PRIVATE uint16 = 1 << iota
+	IPVLAN_F_VEPA
+)
+
+const (
+	IFLA_VTI_UNSPEC uint16 = iota
+	IFLA_VTI_LINK
+	IFLA_VTI_IKEY
+	IFLA_VTI_OKEY
+	IFLA_VTI_LOCAL
+	IFLA_VTI_REMOTE
+	IFLA_VTI_FWMARK
+	N_IFLA_VTI
+)
+
+const IFLA_VTI_MAX = N_IFLA_VTI - 1
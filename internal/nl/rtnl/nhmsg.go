@@ -0,0 +1,123 @@
+// Copyright © 2015-2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package rtnl
+
+import (
+	"syscall"
+	"unsafe"
+
+	"github.com/platinasystems/go/internal/nl"
+)
+
+const SizeofNhMsg = 1 + 1 + 1 + 1 + 4
+
+type NhMsg struct {
+	Family   uint8
+	Scope    uint8
+	Protocol uint8
+	_        uint8
+	Flags    uint32 // RTNH_F_*
+}
+
+func NhMsgPtr(b []byte) *NhMsg {
+	if len(b) < nl.SizeofHdr+SizeofNhMsg {
+		return nil
+	}
+	return (*NhMsg)(unsafe.Pointer(&b[nl.SizeofHdr]))
+}
+
+func (msg NhMsg) Read(b []byte) (int, error) {
+	*(*NhMsg)(unsafe.Pointer(&b[0])) = msg
+	return SizeofNhMsg, nil
+}
+
+const (
+	NHA_UNSPEC uint16 = iota
+	NHA_ID
+	NHA_GROUP
+	NHA_GROUP_TYPE
+	NHA_BLACKHOLE
+	NHA_OIF
+	NHA_GATEWAY
+	NHA_ENCAP_TYPE
+	NHA_ENCAP
+	NHA_GROUPS
+	NHA_MASTER
+	NHA_FDB
+	NHA_RES_GROUP
+	NHA_RES_BUCKET
+	NHA_OP_FLAGS
+	N_NHA
+)
+
+const NHA_MAX = N_NHA - 1
+
+type Nha [N_NHA][]byte
+
+func (nha *Nha) Write(b []byte) (int, error) {
+	i := nl.NLMSG.Align(nl.SizeofHdr + SizeofNhMsg)
+	if i >= len(b) {
+		nl.IndexAttrByType(nha[:], nl.Empty)
+		return 0, nil
+	}
+	nl.IndexAttrByType(nha[:], b[i:])
+	return len(b) - i, nil
+}
+
+const (
+	NEXTHOP_GRP_TYPE_MPATH uint16 = iota
+	NEXTHOP_GRP_TYPE_RES
+)
+
+var NexthopGrpTypeByName = map[string]uint16{
+	"mpath":     NEXTHOP_GRP_TYPE_MPATH,
+	"resilient": NEXTHOP_GRP_TYPE_RES,
+}
+
+const SizeofNexthopGrp = 4 + 1 + 1 + 2
+
+// An NHA_GROUP member; the kernel's weight is one more than Weight.
+type NexthopGrp struct {
+	Id         uint32
+	Weight     uint8
+	WeightHigh uint8
+	_          uint16
+}
+
+// NexthopGrps returns the NHA_GROUP attribute value.
+func NexthopGrps(b []byte) []NexthopGrp {
+	n := len(b) / SizeofNexthopGrp
+	if n == 0 {
+		return nil
+	}
+	return (*[1 << 16]NexthopGrp)(unsafe.Pointer(&b[0]))[:n:n]
+}
+
+type NexthopGrpList []NexthopGrp
+
+func (l NexthopGrpList) Read(b []byte) (int, error) {
+	n := len(l) * SizeofNexthopGrp
+	if len(b) < n {
+		return 0, syscall.EOVERFLOW
+	}
+	for i, grp := range l {
+		*(*NexthopGrp)(unsafe.Pointer(&b[i*SizeofNexthopGrp])) = grp
+	}
+	return n, nil
+}
+
+// Nested NHA_RES_GROUP attributes; the timers are in clock_t (USER_HZ).
+const (
+	NHA_RES_GROUP_PAD uint16 = iota
+	NHA_RES_GROUP_BUCKETS
+	NHA_RES_GROUP_IDLE_TIMER
+	NHA_RES_GROUP_UNBALANCED_TIMER
+	NHA_RES_GROUP_UNBALANCED_TIME
+	N_NHA_RES_GROUP
+)
+
+const NHA_RES_GROUP_MAX = N_NHA_RES_GROUP - 1
+
+const USER_HZ = 100
@@ -73,4 +73,8 @@ const (
 	RTM_NEWNSID uint16 = 88
 	RTM_DELNSID uint16 = 89
 	RTM_GETNSID uint16 = 90
+
+	RTM_NEWNEXTHOP uint16 = 104
+	RTM_DELNEXTHOP uint16 = 105
+	RTM_GETNEXTHOP uint16 = 106
 )
@@ -55,6 +55,10 @@ const (
 	RTA_PAD
 	RTA_UID
 	RTA_TTL_PROPAGATE
+	RTA_IP_PROTO
+	RTA_SPORT
+	RTA_DPORT
+	RTA_NH_ID
 	N_RTA
 )
 
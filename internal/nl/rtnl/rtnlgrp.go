@@ -37,6 +37,9 @@ const (
 	RTNLGRP_MPLS_ROUTE
 	RTNLGRP_NSID
 	RTNLGRP_MPLS_NETCONF
+	RTNLGRP_IPV4_MROUTE_R
+	RTNLGRP_IPV6_MROUTE_R
+	RTNLGRP_NEXTHOP
 	N_RTNLGRP
 )
 
@@ -94,6 +97,8 @@ func printRtnlGrps(groups uint32) {
 		{RTNLGRP_MPLS_ROUTE, "mpls-route"},
 		{RTNLGRP_NSID, "nsid"},
 		{RTNLGRP_MPLS_NETCONF, "mpls-netconf"},
+		{RTNLGRP_IPV4_MROUTE_R, "ipv4-mroute-r"},
+		{RTNLGRP_IPV6_MROUTE_R, "ipv6-mroute-r"},
 	} {
 		if bit := x.g.Bit(); (groups & bit) == bit {
 			fmt.Print(sep, x.name)
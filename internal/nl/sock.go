@@ -196,6 +196,21 @@ func (sock *Sock) Close() error {
 	return nil
 }
 
+// Join a multicast group by id rather than the NewSock groups bitmask that
+// is limited to the first 32, e.g. generic netlink family groups.
+func (sock *Sock) Join(group uint32) error {
+	return os.NewSyscallError("NETLINK_ADD_MEMBERSHIP",
+		syscall.SetsockoptInt(sock.fd, SOL_NETLINK,
+			NETLINK_ADD_MEMBERSHIP, int(group)))
+}
+
+// Leave a multicast group joined by id.
+func (sock *Sock) Leave(group uint32) error {
+	return os.NewSyscallError("NETLINK_DROP_MEMBERSHIP",
+		syscall.SetsockoptInt(sock.fd, SOL_NETLINK,
+			NETLINK_DROP_MEMBERSHIP, int(group)))
+}
+
 func (sock *Sock) Send(b []byte) error {
 	return syscall.Sendto(sock.fd, b, 0, sock.sa)
 }
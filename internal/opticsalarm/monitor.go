@@ -0,0 +1,95 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package opticsalarm
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/platinasystems/go/internal/log"
+	"github.com/platinasystems/go/internal/redis"
+)
+
+// Monitor the alarms of a qsfp daemon's ports; logging, publishing, and
+// listing their events in redis.
+type Monitor struct {
+	mutex  sync.Mutex
+	engine *Engine
+	events chan<- string
+}
+
+// NewMonitor loads the optional DefaultFile and opens the event Channel.
+func NewMonitor() (*Monitor, error) {
+	cfg, err := Load(DefaultFile)
+	if err != nil && !os.IsNotExist(err) {
+		// monitor with the module's own thresholds rather than not at all
+		log.Print(err)
+	}
+	m := &Monitor{engine: New(cfg)}
+	m.events, err = redis.Publish(Channel)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Close the event channel; the caller must have stopped its updates.
+func (m *Monitor) Close() {
+	close(m.events)
+}
+
+// Update the port's alarms with the given fields, relative to
+// port-N.qsfp., then log, publish, and list the raised and cleared alarms
+// in port-N.qsfp.events.  This returns the alarm fields for the daemon to
+// publish with the flags of configured thresholds in place of the module's.
+func (m *Monitor) Update(port string, fields map[string]string) map[string]string {
+	m.mutex.Lock()
+	out, events := m.engine.Update(port, fields, time.Now())
+	m.mutex.Unlock()
+	for _, e := range events {
+		s := e.String()
+		if e.Raised {
+			log.Print("warning: ", s)
+		} else {
+			log.Print(s)
+		}
+		m.events <- s
+		err := redis.Log("port-"+port+".qsfp.events",
+			m.engine.Config().History, s)
+		if err != nil {
+			log.Print("qsfp events: ", err)
+		}
+	}
+	return out
+}
+
+// Remove the port's active alarms, e.g. with the removal of its module.
+func (m *Monitor) Remove(port string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.engine.Remove(port)
+}
+
+// Thresholds returns the fields, relative to port-N.qsfp., of the port's
+// configured thresholds to publish over those of its module.
+func (m *Monitor) Thresholds(port string) map[string]string {
+	return m.engine.Config().For(port).Fields()
+}
+
+// Monitors returns the monitor fields, relative to port-N.qsfp., of a
+// module and its lanes to include with the alarm fields of Update.
+func Monitors(lanes int) []string {
+	fields := []string{"temperature.units.C", "vcc.units.V"}
+	for x := 1; x <= lanes; x++ {
+		lane := strconv.Itoa(x)
+		fields = append(fields,
+			"rx"+lane+".power.units.mW",
+			"tx"+lane+".power.units.mW",
+			"tx"+lane+".bias.units.mA")
+	}
+	return fields
+}
@@ -0,0 +1,424 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package opticsalarm detects the raising and clearing of the alarms and
+// warnings of optical modules and checks the monitors against thresholds
+// configured to override those of the module.  Monitor logs these events
+// and lists the most recent of each port in redis.
+//
+// The alarm fields are those published by the qsfp daemons relative to
+// port-N.qsfp., i.e. alarms, rxN.alarms, and txN.alarms; each a comma
+// separated list of flag names or "none".  A configured threshold replaces
+// the module's flags of the same measure, e.g. TempHighWarn or
+// PowerLowAlarm, with those from comparing the monitor to the configured
+// limits.
+//
+//	history: 64
+//	thresholds:
+//	- temperature: {highAlarm: 70, highWarn: 65}
+//	- ports: [1, 2, M1]
+//	  rx.power: {lowAlarm: 0.05, lowWarn: 0.1}
+//
+// Entries without ports apply to all; later entries override the measures
+// of earlier entries.
+package opticsalarm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	DefaultFile = "/etc/goes/optics-alarms.yaml"
+	// The redis channel of events, see: goes subscribe qsfp.events
+	Channel = "qsfp.events"
+	// The default number of events listed per port.
+	DefaultHistory = 32
+)
+
+type Config struct {
+	// Events listed per port, default 32.
+	History    int          `yaml:"history"`
+	Thresholds []Thresholds `yaml:"thresholds"`
+}
+
+// Thresholds of the measures of a module, nil to use the module's own.
+type Thresholds struct {
+	// Ports, e.g. 1 or M1, default all.
+	Ports       []string `yaml:"ports"`
+	Temperature *Limits  `yaml:"temperature"`
+	Vcc         *Limits  `yaml:"vcc"`
+	RxPower     *Limits  `yaml:"rx.power"`
+	TxPower     *Limits  `yaml:"tx.power"`
+	TxBias      *Limits  `yaml:"tx.bias"`
+}
+
+// Limits, in the units of the monitored field, that are unchecked if nil.
+type Limits struct {
+	HighAlarm *float64 `yaml:"highAlarm"`
+	LowAlarm  *float64 `yaml:"lowAlarm"`
+	HighWarn  *float64 `yaml:"highWarn"`
+	LowWarn   *float64 `yaml:"lowWarn"`
+}
+
+// measure of module or lane monitors
+type measure struct {
+	name string // of thresholds
+	// Monitor and alarm fields; with %d of lane if per lane.
+	field, alarms string
+	// Prefix of flag names and the threshold fields.
+	flag, threshold string
+	limits          func(*Thresholds) **Limits
+}
+
+var measures = []measure{
+	{"temperature", "temperature.units.C", "alarms", "Temp",
+		"temperature.%sThreshold.units.C",
+		func(t *Thresholds) **Limits { return &t.Temperature }},
+	{"vcc", "vcc.units.V", "alarms", "Vcc",
+		"vcc.%sThreshold.units.V",
+		func(t *Thresholds) **Limits { return &t.Vcc }},
+	{"rx.power", "rx%d.power.units.mW", "rx%d.alarms", "Power",
+		"rx.power.%sThreshold.units.mW",
+		func(t *Thresholds) **Limits { return &t.RxPower }},
+	{"tx.power", "tx%d.power.units.mW", "tx%d.alarms", "Power",
+		"tx.power.%sThreshold.units.mW",
+		func(t *Thresholds) **Limits { return &t.TxPower }},
+	{"tx.bias", "tx%d.bias.units.mA", "tx%d.alarms", "Bias",
+		"tx.bias%sThreshold.units.mA",
+		func(t *Thresholds) **Limits { return &t.TxBias }},
+}
+
+// Lanes of QSFP-DD and OSFP.
+const maxLanes = 8
+
+// fields returns the monitor and alarm fields of the module or each lane.
+func (m *measure) fields() []struct{ field, alarms string } {
+	if !strings.Contains(m.field, "%d") {
+		return []struct{ field, alarms string }{{m.field, m.alarms}}
+	}
+	fs := make([]struct{ field, alarms string }, maxLanes)
+	for i := range fs {
+		fs[i].field = fmt.Sprintf(m.field, i+1)
+		fs[i].alarms = fmt.Sprintf(m.alarms, i+1)
+	}
+	return fs
+}
+
+// Load the named config file.
+func Load(fn string) (*Config, error) {
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := Parse(b)
+	if err != nil {
+		err = fmt.Errorf("%s: %v", fn, err)
+	}
+	return cfg, err
+}
+
+// Parse and validate a YAML config.
+func Parse(b []byte) (*Config, error) {
+	cfg := new(Config)
+	if err := yaml.UnmarshalStrict(b, cfg); err != nil {
+		return nil, err
+	}
+	if cfg.History == 0 {
+		cfg.History = DefaultHistory
+	}
+	if cfg.History < 0 {
+		return nil, fmt.Errorf("history: %d: invalid", cfg.History)
+	}
+	for i := range cfg.Thresholds {
+		for _, m := range measures {
+			l := *m.limits(&cfg.Thresholds[i])
+			if l == nil {
+				continue
+			}
+			if err := l.validate(); err != nil {
+				return nil, fmt.Errorf("thresholds[%d]: %s: %v",
+					i, m.name, err)
+			}
+		}
+	}
+	return cfg, nil
+}
+
+func (l *Limits) validate() error {
+	for _, x := range []struct {
+		lo, hi *float64
+		s      string
+	}{
+		{l.LowAlarm, l.HighAlarm, "lowAlarm exceeds highAlarm"},
+		{l.LowWarn, l.HighWarn, "lowWarn exceeds highWarn"},
+		{l.HighWarn, l.HighAlarm, "highWarn exceeds highAlarm"},
+		{l.LowAlarm, l.LowWarn, "lowAlarm exceeds lowWarn"},
+	} {
+		if x.lo != nil && x.hi != nil && *x.lo > *x.hi {
+			return fmt.Errorf("%s", x.s)
+		}
+	}
+	return nil
+}
+
+// For returns the merged thresholds of the port, e.g. "1" or "M1".
+func (cfg *Config) For(port string) *Thresholds {
+	t := new(Thresholds)
+	if cfg == nil {
+		return t
+	}
+	for i := range cfg.Thresholds {
+		x := &cfg.Thresholds[i]
+		if !x.applies(port) {
+			continue
+		}
+		for _, m := range measures {
+			if l := *m.limits(x); l != nil {
+				*m.limits(t) = l
+			}
+		}
+	}
+	return t
+}
+
+func (t *Thresholds) applies(port string) bool {
+	if len(t.Ports) == 0 {
+		return true
+	}
+	for _, p := range t.Ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
+
+// Fields returns the configured thresholds as the fields, relative to
+// port-N.qsfp., that the qsfp daemons publish of the module's thresholds.
+func (t *Thresholds) Fields() map[string]string {
+	fields := make(map[string]string)
+	for _, m := range measures {
+		l := *m.limits(t)
+		if l == nil {
+			continue
+		}
+		for _, x := range l.limits() {
+			name := x.name
+			if strings.HasPrefix(m.threshold, "tx.bias") {
+				// tx.biasHighAlarmThreshold
+				name = strings.ToUpper(name[:1]) + name[1:]
+			}
+			fields[fmt.Sprintf(m.threshold, name)] = format(*x.v)
+		}
+	}
+	return fields
+}
+
+type limit struct {
+	name string
+	v    *float64
+	high bool
+}
+
+func (l *Limits) limits() []limit {
+	var ls []limit
+	for _, x := range []limit{
+		{"highAlarm", l.HighAlarm, true},
+		{"lowAlarm", l.LowAlarm, false},
+		{"highWarn", l.HighWarn, true},
+		{"lowWarn", l.LowWarn, false},
+	} {
+		if x.v != nil {
+			ls = append(ls, x)
+		}
+	}
+	return ls
+}
+
+// Flags returns the names of the limits exceeded by v with the given
+// prefix, e.g. TempHighAlarm.
+func (l *Limits) flags(prefix string, v float64) []string {
+	var names []string
+	for _, x := range l.limits() {
+		if (x.high && v > *x.v) || (!x.high && v < *x.v) {
+			names = append(names, prefix+
+				strings.ToUpper(x.name[:1])+x.name[1:])
+		}
+	}
+	return names
+}
+
+// Event is the raising or clearing of an alarm or warning.
+type Event struct {
+	Time time.Time
+	// Port, e.g. 1 or M1.
+	Port string
+	// Alarm field relative to port-N.qsfp., e.g. rx1.alarms.
+	Field string
+	// Flag name, e.g. RxLos or TempHighWarn.
+	Alarm  string
+	Raised bool
+	// Monitor of a configured threshold or empty.
+	Value string
+}
+
+// Warning is true of warnings rather than alarms.
+func (e Event) Warning() bool { return strings.Contains(e.Alarm, "Warn") }
+
+// String formats the event like this:
+//
+//	2018-06-01T12:00:00Z port-5.qsfp.rx1.alarms: RxLos raised
+//	2018-06-01T12:00:01Z port-5.qsfp.alarms: TempHighWarn raised at 66.0
+func (e Event) String() string {
+	s := "cleared"
+	if e.Raised {
+		s = "raised"
+	}
+	if e.Value != "" {
+		s += " at " + e.Value
+	}
+	return fmt.Sprint(e.Time.UTC().Format(time.RFC3339), " port-", e.Port,
+		".qsfp.", e.Field, ": ", e.Alarm, " ", s)
+}
+
+// Engine tracks the alarms of all ports.
+type Engine struct {
+	cfg   *Config
+	ports map[string]*port
+}
+
+type port struct {
+	// active flags of each alarm field
+	active map[string]map[string]struct{}
+}
+
+func New(cfg *Config) *Engine {
+	if cfg == nil {
+		cfg = &Config{History: DefaultHistory}
+	}
+	return &Engine{cfg: cfg, ports: make(map[string]*port)}
+}
+
+func (e *Engine) Config() *Config { return e.cfg }
+
+// Update the alarms of the port with the fields, relative to port-N.qsfp.,
+// of its alarms and monitors.  This returns the alarm fields with the
+// flags of configured thresholds in place of the module's, and the events
+// since the last update in order of field and flag.
+func (e *Engine) Update(name string, fields map[string]string,
+	now time.Time) (map[string]string, []Event) {
+	flags := make(map[string][]string)
+	for k, v := range fields {
+		if k == "alarms" || strings.HasSuffix(k, ".alarms") {
+			flags[k] = split(v)
+		}
+	}
+	values := make(map[string]string)
+	th := e.cfg.For(name)
+	for _, m := range measures {
+		l := *m.limits(th)
+		if l == nil {
+			continue
+		}
+		for _, x := range m.fields() {
+			s, found := fields[x.field]
+			if !found {
+				continue
+			}
+			var names []string
+			for _, f := range flags[x.alarms] {
+				if !strings.HasPrefix(f, m.flag) {
+					names = append(names, f)
+				}
+			}
+			if v, err := strconv.ParseFloat(s, 64); err == nil {
+				for _, f := range l.flags(m.flag, v) {
+					names = append(names, f)
+					values[x.alarms+" "+f] = s
+				}
+			}
+			flags[x.alarms] = names
+		}
+	}
+
+	p := e.ports[name]
+	if p == nil {
+		p = &port{active: make(map[string]map[string]struct{})}
+		e.ports[name] = p
+	}
+	var events []Event
+	out := make(map[string]string, len(flags))
+	for k, names := range flags {
+		out[k] = join(names)
+		was := p.active[k]
+		is := make(map[string]struct{}, len(names))
+		for _, f := range names {
+			is[f] = struct{}{}
+			if _, found := was[f]; !found {
+				events = append(events, Event{
+					Time:   now,
+					Port:   name,
+					Field:  k,
+					Alarm:  f,
+					Raised: true,
+					Value:  values[k+" "+f],
+				})
+			}
+		}
+		for f := range was {
+			if _, found := is[f]; !found {
+				events = append(events, Event{
+					Time:  now,
+					Port:  name,
+					Field: k,
+					Alarm: f,
+				})
+			}
+		}
+		p.active[k] = is
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Field != events[j].Field {
+			return events[i].Field < events[j].Field
+		}
+		return events[i].Alarm < events[j].Alarm
+	})
+	return out, events
+}
+
+// Remove the port's active alarms without clearing them, e.g. with the
+// removal of its module.
+func (e *Engine) Remove(name string) {
+	if p := e.ports[name]; p != nil {
+		p.active = make(map[string]map[string]struct{})
+	}
+}
+
+func split(s string) []string {
+	var names []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" && f != "none" {
+			names = append(names, f)
+		}
+	}
+	return names
+}
+
+func join(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ",")
+}
+
+func format(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,117 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package opticsalarm
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestEdges(t *testing.T) {
+	e := New(nil)
+	out, events := e.Update("5", map[string]string{
+		"alarms":     "none",
+		"rx1.alarms": "RxLos",
+	}, t0)
+	if out["rx1.alarms"] != "RxLos" || out["alarms"] != "none" {
+		t.Error(out)
+	}
+	if len(events) != 1 || !events[0].Raised || events[0].Alarm != "RxLos" {
+		t.Fatal(events)
+	}
+	if s := events[0].String(); s !=
+		"2018-06-01T12:00:00Z port-5.qsfp.rx1.alarms: RxLos raised" {
+		t.Error(s)
+	}
+	// unchanged flags aren't events
+	if _, events = e.Update("5", map[string]string{
+		"rx1.alarms": "RxLos",
+	}, t0.Add(time.Second)); len(events) != 0 {
+		t.Error(events)
+	}
+	_, events = e.Update("5", map[string]string{
+		"rx1.alarms": "PowerLowWarn",
+	}, t0.Add(2*time.Second))
+	if len(events) != 2 || events[0].Alarm != "PowerLowWarn" ||
+		!events[0].Raised || !events[0].Warning() ||
+		events[1].Alarm != "RxLos" || events[1].Raised {
+		t.Error(events)
+	}
+}
+
+func TestOverride(t *testing.T) {
+	cfg, err := Parse([]byte(`
+thresholds:
+- temperature: {highAlarm: 70, highWarn: 65}
+- ports: ["5"]
+  rx.power: {lowAlarm: 0.05, lowWarn: 0.1}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := New(cfg)
+	out, events := e.Update("5", map[string]string{
+		"temperature.units.C": "66.5",
+		"alarms":              "TempHighAlarm,VccLowWarn",
+		"rx1.power.units.mW":  "0.080",
+		"rx1.alarms":          "RxLos,PowerHighWarn",
+		"rx2.power.units.mW":  "0.500",
+		"rx2.alarms":          "none",
+	}, t0)
+	want := map[string]string{
+		"alarms":     "VccLowWarn,TempHighWarn",
+		"rx1.alarms": "RxLos,PowerLowWarn",
+		"rx2.alarms": "none",
+	}
+	if !reflect.DeepEqual(out, want) {
+		t.Error(out)
+	}
+	if len(events) != 4 || events[0].Alarm != "TempHighWarn" ||
+		events[0].Value != "66.5" {
+		t.Error(events)
+	}
+	// port 6 has the module's rx power flags
+	out, _ = e.Update("6", map[string]string{
+		"rx1.power.units.mW": "0.080",
+		"rx1.alarms":         "PowerHighWarn",
+	}, t0)
+	if out["rx1.alarms"] != "PowerHighWarn" {
+		t.Error(out)
+	}
+	fields := cfg.For("5").Fields()
+	if fields["temperature.highAlarmThreshold.units.C"] != "70" ||
+		fields["rx.power.lowWarnThreshold.units.mW"] != "0.1" ||
+		len(fields) != 4 {
+		t.Error(fields)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	e := New(nil)
+	e.Update("M1", map[string]string{"rx1.alarms": "RxCdrLol"}, t0)
+	e.Remove("M1")
+	_, events := e.Update("M1", map[string]string{"rx1.alarms": "RxCdrLol"},
+		t0.Add(time.Minute))
+	if len(events) != 1 || !events[0].Raised {
+		t.Error(events)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	for _, s := range []string{
+		"history: -1\n",
+		"thresholds:\n- vcc: {lowAlarm: 3.5, highAlarm: 3.1}\n",
+		"thresholds:\n- tx.bias: {highWarn: 12, highAlarm: 10}\n",
+		"thresholds:\n- rx.power: {lowAlarm: 0.1, lowWarn: 0.05}\n",
+		"thresholds:\n- humidity: {highAlarm: 1}\n",
+	} {
+		if _, err := Parse([]byte(s)); err == nil {
+			t.Errorf("%q: not invalid", s)
+		}
+	}
+}
@@ -135,6 +135,20 @@ func Connect() (redis.Conn, error) {
 	return redis.NewConn(conn, rdtimeout, wrtimeout), nil
 }
 
+// Expire sets the key's time to live in seconds.
+func Expire(key string, seconds int) (i int, err error) {
+	conn, err := Connect()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	ret, err := conn.Do("EXPIRE", key, seconds)
+	if err == nil {
+		i = int(ret.(int64))
+	}
+	return
+}
+
 func Get(key string) (s string, err error) {
 	conn, err := Connect()
 	if err != nil {
@@ -187,6 +201,36 @@ func Hget(key, field string) (s string, err error) {
 	return
 }
 
+func Hgetall(key string) (m map[string]string, err error) {
+	conn, err := Connect()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	ret, err := conn.Do("HGETALL", key)
+	if ret != nil && err == nil {
+		vs := ret.([]interface{})
+		m = make(map[string]string, len(vs)/2)
+		for i := 0; i+1 < len(vs); i += 2 {
+			m[vstring(vs[i])] = vstring(vs[i+1])
+		}
+	}
+	return
+}
+
+func Hincrby(key, field string, incr int) (i int, err error) {
+	conn, err := Connect()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	ret, err := conn.Do("HINCRBY", key, field, incr)
+	if err == nil {
+		i = int(ret.(int64))
+	}
+	return
+}
+
 func Hkeys(key string) (keys []string, err error) {
 	conn, err := Connect()
 	if err != nil {
@@ -267,6 +311,45 @@ func Lrange(key string, start, stop int) (keys []string, err error) {
 	return
 }
 
+// Lpush inserts the values at the head of the list, returning its length.
+func Lpush(key string, values ...interface{}) (i int, err error) {
+	conn, err := Connect()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	ret, err := conn.Do("LPUSH", append([]interface{}{key}, values...)...)
+	if err == nil {
+		i = int(ret.(int64))
+	}
+	return
+}
+
+// Ltrim retains the list elements from start through stop inclusive.
+func Ltrim(key string, start, stop int) (err error) {
+	conn, err := Connect()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	_, err = conn.Do("LTRIM", key, start, stop)
+	return
+}
+
+// Log pushes msg to the head of the list then trims it to the most recent
+// depth entries.
+func Log(key string, depth int, msg interface{}) error {
+	conn, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.Send("LPUSH", key, msg)
+	conn.Send("LTRIM", key, 0, depth-1)
+	_, err = conn.Do("")
+	return err
+}
+
 // Publish messages to the named redis channel.  Messages sent through the
 // returned channel are forwarded to the redis server until the channel is
 // closed.
@@ -346,6 +429,52 @@ func Subscribe(channel string) (psc redis.PubSubConn, err error) {
 	return
 }
 
+// Ttl returns the key's remaining seconds to live, -1 if it doesn't expire,
+// or -2 if it doesn't exist.
+func Ttl(key string) (i int, err error) {
+	conn, err := Connect()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	ret, err := conn.Do("TTL", key)
+	if err == nil {
+		i = int(ret.(int64))
+	}
+	return
+}
+
+// Zadd inserts or updates the member of the sorted set.
+func Zadd(key string, score float64, member interface{}) (i int, err error) {
+	conn, err := Connect()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	ret, err := conn.Do("ZADD", key, score, member)
+	if err == nil {
+		i = int(ret.(int64))
+	}
+	return
+}
+
+func Zrange(key string, start, stop int) (members []string, err error) {
+	conn, err := Connect()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	ret, err := conn.Do("ZRANGE", key, start, stop)
+	if ret != nil && err == nil {
+		vs := ret.([]interface{})
+		members = make([]string, 0, len(vs))
+		for _, v := range vs {
+			members = append(members, vstring(v))
+		}
+	}
+	return
+}
+
 func vstring(v interface{}) (s string) {
 	type stringer interface {
 		String() string
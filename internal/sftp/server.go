@@ -0,0 +1,607 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package sftp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+var errBadMessage = errors.New("bad message")
+
+// Server handles the requests of one session.  Requests are served in the
+// order received.
+type Server struct {
+	rw io.ReadWriter
+	// Relative paths are from this directory.
+	dir     string
+	handles map[string]interface{}
+	nextid  uint64
+	out     []byte
+}
+
+type dir struct {
+	name  string
+	f     *os.File
+	names []string
+}
+
+// Serve the SFTP subsystem on the given SSH channel until the client closes
+// it; relative paths are from the given directory.
+func Serve(rw io.ReadWriter, dir string) error {
+	srv := &Server{
+		rw:      rw,
+		dir:     dir,
+		handles: make(map[string]interface{}),
+	}
+	defer srv.close()
+	hdr := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(rw, hdr); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return err
+		}
+		n := binary.BigEndian.Uint32(hdr)
+		if n < 1 || n > MaxPacket {
+			return fmt.Errorf("sftp: %d byte packet", n)
+		}
+		pkt := make([]byte, n)
+		if _, err := io.ReadFull(rw, pkt); err != nil {
+			return err
+		}
+		if err := srv.serve(pkt[0], &request{b: pkt[1:]}); err != nil {
+			return err
+		}
+	}
+}
+
+func (srv *Server) close() {
+	for h, v := range srv.handles {
+		switch t := v.(type) {
+		case *os.File:
+			t.Close()
+		case *dir:
+			t.f.Close()
+		}
+		delete(srv.handles, h)
+	}
+}
+
+func (srv *Server) serve(t byte, req *request) error {
+	if t == FxpInit {
+		// ignore client version and extensions
+		return srv.send(FxpVersion, uint32(Version))
+	}
+	id := req.uint32()
+	if req.err != nil {
+		return req.err
+	}
+	err := srv.dispatch(t, id, req)
+	if err == nil {
+		return nil
+	}
+	if err == io.EOF {
+		return srv.status(id, FxEOF, "EOF")
+	}
+	code := uint32(FxFailure)
+	switch {
+	case err == errBadMessage:
+		code = FxBadMessage
+	case err == errUnsupported:
+		code = FxOpUnsupported
+	case os.IsNotExist(err):
+		code = FxNoSuchFile
+	case os.IsPermission(err):
+		code = FxPermissionDenied
+	}
+	return srv.status(id, code, err.Error())
+}
+
+var errUnsupported = errors.New("unsupported")
+
+func (srv *Server) dispatch(t byte, id uint32, req *request) error {
+	switch t {
+	case FxpOpen:
+		name := srv.path(req.string())
+		pflags := req.uint32()
+		attrs := req.attrs()
+		if req.err != nil {
+			return errBadMessage
+		}
+		flags := 0
+		switch {
+		case pflags&FxfRead != 0 && pflags&FxfWrite != 0:
+			flags = os.O_RDWR
+		case pflags&FxfWrite != 0:
+			flags = os.O_WRONLY
+		}
+		if pflags&FxfAppend != 0 {
+			flags |= os.O_APPEND
+		}
+		if pflags&FxfCreat != 0 {
+			flags |= os.O_CREATE
+		}
+		if pflags&FxfTrunc != 0 {
+			flags |= os.O_TRUNC
+		}
+		if pflags&FxfExcl != 0 {
+			flags |= os.O_EXCL
+		}
+		perm := os.FileMode(0644)
+		if attrs.flags&FileXferAttrPermissions != 0 {
+			perm = os.FileMode(attrs.perm & 0777)
+		}
+		f, err := os.OpenFile(name, flags, perm)
+		if err != nil {
+			return err
+		}
+		return srv.handle(id, f)
+	case FxpOpendir:
+		name := srv.path(req.string())
+		if req.err != nil {
+			return errBadMessage
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		return srv.handle(id, &dir{name: name, f: f})
+	case FxpClose:
+		h := req.string()
+		if req.err != nil {
+			return errBadMessage
+		}
+		v, found := srv.handles[h]
+		if !found {
+			return errBadMessage
+		}
+		delete(srv.handles, h)
+		var err error
+		switch t := v.(type) {
+		case *os.File:
+			err = t.Close()
+		case *dir:
+			err = t.f.Close()
+		}
+		if err != nil {
+			return err
+		}
+		return srv.status(id, FxOk, "")
+	case FxpRead:
+		f, err := srv.file(req.string())
+		offset := req.uint64()
+		n := req.uint32()
+		if req.err != nil {
+			return errBadMessage
+		}
+		if err != nil {
+			return err
+		}
+		if n > MaxPacket-64 {
+			n = MaxPacket - 64
+		}
+		b := make([]byte, n)
+		i, err := f.ReadAt(b, int64(offset))
+		if i == 0 && err != nil {
+			return err
+		}
+		return srv.send(FxpData, id, b[:i])
+	case FxpWrite:
+		f, err := srv.file(req.string())
+		offset := req.uint64()
+		b := req.bytes()
+		if req.err != nil {
+			return errBadMessage
+		}
+		if err != nil {
+			return err
+		}
+		if _, err = f.WriteAt(b, int64(offset)); err != nil {
+			return err
+		}
+		return srv.status(id, FxOk, "")
+	case FxpReaddir:
+		h := req.string()
+		if req.err != nil {
+			return errBadMessage
+		}
+		d, found := srv.handles[h].(*dir)
+		if !found {
+			return errBadMessage
+		}
+		return srv.readdir(id, d)
+	case FxpStat, FxpLstat:
+		name := srv.path(req.string())
+		if req.err != nil {
+			return errBadMessage
+		}
+		stat := os.Stat
+		if t == FxpLstat {
+			stat = os.Lstat
+		}
+		fi, err := stat(name)
+		if err != nil {
+			return err
+		}
+		return srv.send(FxpAttrs, id, fileAttrs(fi))
+	case FxpFstat:
+		f, err := srv.file(req.string())
+		if req.err != nil {
+			return errBadMessage
+		}
+		if err != nil {
+			return err
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		return srv.send(FxpAttrs, id, fileAttrs(fi))
+	case FxpSetstat:
+		name := srv.path(req.string())
+		attrs := req.attrs()
+		if req.err != nil {
+			return errBadMessage
+		}
+		if err := attrs.set(name, nil); err != nil {
+			return err
+		}
+		return srv.status(id, FxOk, "")
+	case FxpFsetstat:
+		f, err := srv.file(req.string())
+		attrs := req.attrs()
+		if req.err != nil {
+			return errBadMessage
+		}
+		if err != nil {
+			return err
+		}
+		if err = attrs.set(f.Name(), f); err != nil {
+			return err
+		}
+		return srv.status(id, FxOk, "")
+	case FxpRemove:
+		return srv.op(id, req, os.Remove)
+	case FxpRmdir:
+		return srv.op(id, req, syscall.Rmdir)
+	case FxpMkdir:
+		name := srv.path(req.string())
+		attrs := req.attrs()
+		if req.err != nil {
+			return errBadMessage
+		}
+		perm := os.FileMode(0755)
+		if attrs.flags&FileXferAttrPermissions != 0 {
+			perm = os.FileMode(attrs.perm & 0777)
+		}
+		if err := os.Mkdir(name, perm); err != nil {
+			return err
+		}
+		return srv.status(id, FxOk, "")
+	case FxpRealpath:
+		name := srv.path(req.string())
+		if req.err != nil {
+			return errBadMessage
+		}
+		return srv.send(FxpName, id, uint32(1), name, name, attrs{})
+	case FxpRename:
+		from := srv.path(req.string())
+		to := srv.path(req.string())
+		if req.err != nil {
+			return errBadMessage
+		}
+		if err := os.Rename(from, to); err != nil {
+			return err
+		}
+		return srv.status(id, FxOk, "")
+	case FxpReadlink:
+		name := srv.path(req.string())
+		if req.err != nil {
+			return errBadMessage
+		}
+		s, err := os.Readlink(name)
+		if err != nil {
+			return err
+		}
+		return srv.send(FxpName, id, uint32(1), s, s, attrs{})
+	case FxpSymlink:
+		// OpenSSH sends these in the reverse order of the draft.
+		target := req.string()
+		link := srv.path(req.string())
+		if req.err != nil {
+			return errBadMessage
+		}
+		if err := os.Symlink(target, link); err != nil {
+			return err
+		}
+		return srv.status(id, FxOk, "")
+	}
+	return errUnsupported
+}
+
+func (srv *Server) op(id uint32, req *request, f func(string) error) error {
+	name := srv.path(req.string())
+	if req.err != nil {
+		return errBadMessage
+	}
+	if err := f(name); err != nil {
+		return err
+	}
+	return srv.status(id, FxOk, "")
+}
+
+func (srv *Server) readdir(id uint32, d *dir) error {
+	const batch = 64
+	names, err := d.f.Readdirnames(batch)
+	if len(names) == 0 {
+		if err == nil {
+			err = io.EOF
+		}
+		return err
+	}
+	args := []interface{}{id, uint32(0)}
+	n := uint32(0)
+	for _, name := range names {
+		fi, err := os.Lstat(filepath.Join(d.name, name))
+		if err != nil {
+			continue
+		}
+		args = append(args, name, longname(fi), fileAttrs(fi))
+		n++
+	}
+	args[1] = n
+	return srv.send(FxpName, args...)
+}
+
+func (srv *Server) handle(id uint32, v interface{}) error {
+	srv.nextid++
+	h := strconv.FormatUint(srv.nextid, 16)
+	srv.handles[h] = v
+	return srv.send(FxpHandle, id, h)
+}
+
+func (srv *Server) file(h string) (*os.File, error) {
+	f, found := srv.handles[h].(*os.File)
+	if !found {
+		return nil, errBadMessage
+	}
+	return f, nil
+}
+
+func (srv *Server) path(name string) string {
+	if len(name) == 0 {
+		name = "."
+	}
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(srv.dir, name)
+	}
+	return filepath.Clean(name)
+}
+
+func (srv *Server) status(id, code uint32, msg string) error {
+	return srv.send(FxpStatus, id, code, msg, "")
+}
+
+func (srv *Server) send(t byte, args ...interface{}) error {
+	b := append(srv.out[:0], 0, 0, 0, 0, t)
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case uint32:
+			b = appendUint32(b, v)
+		case string:
+			b = appendUint32(b, uint32(len(v)))
+			b = append(b, v...)
+		case []byte:
+			b = appendUint32(b, uint32(len(v)))
+			b = append(b, v...)
+		case attrs:
+			b = v.append(b)
+		default:
+			panic(fmt.Errorf("sftp: can't send %T", arg))
+		}
+	}
+	binary.BigEndian.PutUint32(b, uint32(len(b)-4))
+	srv.out = b
+	_, err := srv.rw.Write(b)
+	return err
+}
+
+type request struct {
+	b   []byte
+	err error
+}
+
+func (req *request) uint32() uint32 {
+	if len(req.b) < 4 {
+		req.err = errBadMessage
+		return 0
+	}
+	v := binary.BigEndian.Uint32(req.b)
+	req.b = req.b[4:]
+	return v
+}
+
+func (req *request) uint64() uint64 {
+	if len(req.b) < 8 {
+		req.err = errBadMessage
+		return 0
+	}
+	v := binary.BigEndian.Uint64(req.b)
+	req.b = req.b[8:]
+	return v
+}
+
+func (req *request) bytes() []byte {
+	n := req.uint32()
+	if req.err != nil || uint32(len(req.b)) < n {
+		req.err = errBadMessage
+		return nil
+	}
+	b := req.b[:n]
+	req.b = req.b[n:]
+	return b
+}
+
+func (req *request) string() string { return string(req.bytes()) }
+
+func (req *request) attrs() (a attrs) {
+	a.flags = req.uint32()
+	if a.flags&FileXferAttrSize != 0 {
+		a.size = req.uint64()
+	}
+	if a.flags&FileXferAttrUidGid != 0 {
+		a.uid = req.uint32()
+		a.gid = req.uint32()
+	}
+	if a.flags&FileXferAttrPermissions != 0 {
+		a.perm = req.uint32()
+	}
+	if a.flags&FileXferAttrAcModTime != 0 {
+		a.atime = req.uint32()
+		a.mtime = req.uint32()
+	}
+	if a.flags&FileXferAttrExtended != 0 {
+		for n := req.uint32(); n > 0 && req.err == nil; n-- {
+			req.bytes()
+			req.bytes()
+		}
+	}
+	return
+}
+
+type attrs struct {
+	flags, uid, gid, perm, atime, mtime uint32
+	size                                uint64
+}
+
+func fileAttrs(fi os.FileInfo) attrs {
+	a := attrs{
+		flags: FileXferAttrSize | FileXferAttrPermissions |
+			FileXferAttrAcModTime,
+		size:  uint64(fi.Size()),
+		perm:  mode(fi),
+		mtime: uint32(fi.ModTime().Unix()),
+	}
+	a.atime = a.mtime
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		a.flags |= FileXferAttrUidGid
+		a.uid = st.Uid
+		a.gid = st.Gid
+		a.atime = uint32(st.Atim.Sec)
+	}
+	return a
+}
+
+func (a attrs) append(b []byte) []byte {
+	b = appendUint32(b, a.flags)
+	if a.flags&FileXferAttrSize != 0 {
+		b = appendUint32(b, uint32(a.size>>32))
+		b = appendUint32(b, uint32(a.size))
+	}
+	if a.flags&FileXferAttrUidGid != 0 {
+		b = appendUint32(b, a.uid)
+		b = appendUint32(b, a.gid)
+	}
+	if a.flags&FileXferAttrPermissions != 0 {
+		b = appendUint32(b, a.perm)
+	}
+	if a.flags&FileXferAttrAcModTime != 0 {
+		b = appendUint32(b, a.atime)
+		b = appendUint32(b, a.mtime)
+	}
+	return b
+}
+
+// Apply the attributes to the named file, or f if it's open.
+func (a attrs) set(name string, f *os.File) error {
+	if a.flags&FileXferAttrSize != 0 {
+		var err error
+		if f != nil {
+			err = f.Truncate(int64(a.size))
+		} else {
+			err = os.Truncate(name, int64(a.size))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if a.flags&FileXferAttrPermissions != 0 {
+		err := os.Chmod(name, os.FileMode(a.perm&07777))
+		if err != nil {
+			return err
+		}
+	}
+	if a.flags&FileXferAttrUidGid != 0 {
+		err := os.Chown(name, int(a.uid), int(a.gid))
+		if err != nil {
+			return err
+		}
+	}
+	if a.flags&FileXferAttrAcModTime != 0 {
+		err := os.Chtimes(name, time.Unix(int64(a.atime), 0),
+			time.Unix(int64(a.mtime), 0))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the unix mode bits, including file type, of the file info.
+func mode(fi os.FileInfo) uint32 {
+	m := uint32(fi.Mode().Perm())
+	switch {
+	case fi.IsDir():
+		m |= syscall.S_IFDIR
+	case fi.Mode()&os.ModeSymlink != 0:
+		m |= syscall.S_IFLNK
+	case fi.Mode()&os.ModeNamedPipe != 0:
+		m |= syscall.S_IFIFO
+	case fi.Mode()&os.ModeSocket != 0:
+		m |= syscall.S_IFSOCK
+	case fi.Mode()&os.ModeCharDevice != 0:
+		m |= syscall.S_IFCHR
+	case fi.Mode()&os.ModeDevice != 0:
+		m |= syscall.S_IFBLK
+	default:
+		m |= syscall.S_IFREG
+	}
+	if fi.Mode()&os.ModeSetuid != 0 {
+		m |= syscall.S_ISUID
+	}
+	if fi.Mode()&os.ModeSetgid != 0 {
+		m |= syscall.S_ISGID
+	}
+	if fi.Mode()&os.ModeSticky != 0 {
+		m |= syscall.S_ISVTX
+	}
+	return m
+}
+
+// Returns an "ls -l" like line of the file.
+func longname(fi os.FileInfo) string {
+	uid, gid := uint32(0), uint32(0)
+	nlink := uint64(1)
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		uid, gid = st.Uid, st.Gid
+		nlink = uint64(st.Nlink)
+	}
+	return fmt.Sprintf("%s %4d %-8d %-8d %8d %s %s", fi.Mode(), nlink,
+		uid, gid, fi.Size(), fi.ModTime().Format("Jan _2 15:04"),
+		fi.Name())
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
@@ -0,0 +1,320 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package sftp
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	id   uint32
+	done chan error
+}
+
+func newClient(t *testing.T, dir string) *client {
+	srv, conn := net.Pipe()
+	c := &client{t: t, conn: conn, done: make(chan error, 1)}
+	go func() {
+		c.done <- Serve(srv, dir)
+		srv.Close()
+	}()
+	return c
+}
+
+// Close the client's end and return the server's error.
+func (c *client) close() error {
+	c.conn.Close()
+	return <-c.done
+}
+
+func (c *client) write(b []byte) {
+	c.t.Helper()
+	if _, err := c.conn.Write(b); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// Send a request with a new id; args are as Server.send.
+func (c *client) send(t byte, args ...interface{}) uint32 {
+	c.t.Helper()
+	c.id++
+	srv := &Server{rw: c.conn}
+	if err := srv.send(t, append([]interface{}{c.id}, args...)...); err != nil {
+		c.t.Fatal(err)
+	}
+	return c.id
+}
+
+// Receive a reply to the last request returning its type and the remainder
+// following the id.
+func (c *client) recv() (byte, *request) {
+	c.t.Helper()
+	hdr := make([]byte, 4)
+	if _, err := io.ReadFull(c.conn, hdr); err != nil {
+		c.t.Fatal(err)
+	}
+	b := make([]byte, binary.BigEndian.Uint32(hdr))
+	if _, err := io.ReadFull(c.conn, b); err != nil {
+		c.t.Fatal(err)
+	}
+	req := &request{b: b[1:]}
+	if b[0] != FxpVersion {
+		if id := req.uint32(); id != c.id {
+			c.t.Fatalf("reply id %d, want %d", id, c.id)
+		}
+	}
+	return b[0], req
+}
+
+func (c *client) status() uint32 {
+	c.t.Helper()
+	t, req := c.recv()
+	if t != FxpStatus {
+		c.t.Fatalf("reply %d, want status", t)
+	}
+	return req.uint32()
+}
+
+func (c *client) expectStatus(code uint32) {
+	c.t.Helper()
+	if got := c.status(); got != code {
+		c.t.Fatalf("status %d, want %d", got, code)
+	}
+}
+
+func (c *client) handle() string {
+	c.t.Helper()
+	t, req := c.recv()
+	if t != FxpHandle {
+		c.t.Fatalf("reply %d, want handle", t)
+	}
+	return req.string()
+}
+
+func (c *client) data() []byte {
+	c.t.Helper()
+	t, req := c.recv()
+	if t != FxpData {
+		c.t.Fatalf("reply %d, want data", t)
+	}
+	return req.bytes()
+}
+
+func (c *client) attrs() attrs {
+	c.t.Helper()
+	t, req := c.recv()
+	if t != FxpAttrs {
+		c.t.Fatalf("reply %d, want attrs", t)
+	}
+	return req.attrs()
+}
+
+func (c *client) names() []string {
+	c.t.Helper()
+	t, req := c.recv()
+	if t != FxpName {
+		c.t.Fatalf("reply %d, want name", t)
+	}
+	var names []string
+	for n := req.uint32(); n > 0; n-- {
+		names = append(names, req.string())
+		req.string()
+		req.attrs()
+	}
+	if req.err != nil {
+		c.t.Fatal(req.err)
+	}
+	return names
+}
+
+func (c *client) init() {
+	c.t.Helper()
+	c.write([]byte{0, 0, 0, 5, FxpInit, 0, 0, 0, 3})
+	t, req := c.recv()
+	if v := req.uint32(); t != FxpVersion || v != Version {
+		c.t.Fatalf("init: %d %d", t, v)
+	}
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "sftp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestFiles(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	c := newClient(t, dir)
+	c.init()
+
+	c.send(FxpOpen, "a", uint32(FxfWrite|FxfCreat|FxfTrunc),
+		attrs{flags: FileXferAttrPermissions, perm: 0600})
+	h := c.handle()
+	c.send(FxpWrite, h, uint32(0), uint32(0), []byte("hello world"))
+	c.expectStatus(FxOk)
+	c.send(FxpWrite, h, uint32(0), uint32(6), []byte("sftp!"))
+	c.expectStatus(FxOk)
+	c.send(FxpFstat, h)
+	if a := c.attrs(); a.size != 11 || a.perm&0777 != 0600 {
+		t.Errorf("fstat: size %d perm %o", a.size, a.perm)
+	}
+	c.send(FxpClose, h)
+	c.expectStatus(FxOk)
+	c.send(FxpClose, h)
+	c.expectStatus(FxBadMessage)
+
+	if b, _ := ioutil.ReadFile(filepath.Join(dir, "a")); string(b) != "hello sftp!" {
+		t.Errorf("wrote %q", b)
+	}
+
+	c.send(FxpOpen, filepath.Join(dir, "a"), uint32(FxfRead), attrs{})
+	h = c.handle()
+	c.send(FxpRead, h, uint32(0), uint32(6), uint32(100))
+	if b := c.data(); string(b) != "sftp!" {
+		t.Errorf("read %q", b)
+	}
+	c.send(FxpRead, h, uint32(0), uint32(11), uint32(100))
+	c.expectStatus(FxEOF)
+	c.send(FxpWrite, h, uint32(0), uint32(0), []byte("x"))
+	c.expectStatus(FxFailure)
+	c.send(FxpClose, h)
+	c.expectStatus(FxOk)
+
+	c.send(FxpOpen, "a", uint32(FxfWrite|FxfCreat|FxfExcl), attrs{})
+	c.expectStatus(FxFailure)
+	c.send(FxpOpen, "missing", uint32(FxfRead), attrs{})
+	c.expectStatus(FxNoSuchFile)
+
+	c.send(FxpSetstat, "a", attrs{flags: FileXferAttrSize, size: 5})
+	c.expectStatus(FxOk)
+	c.send(FxpStat, "a")
+	if a := c.attrs(); a.size != 5 {
+		t.Errorf("setstat size: %d", a.size)
+	}
+
+	c.send(FxpRename, "a", "b")
+	c.expectStatus(FxOk)
+	c.send(FxpSymlink, "b", "c")
+	c.expectStatus(FxOk)
+	c.send(FxpReadlink, "c")
+	if names := c.names(); len(names) != 1 || names[0] != "b" {
+		t.Errorf("readlink: %q", names)
+	}
+	c.send(FxpLstat, "c")
+	if a := c.attrs(); a.perm&0170000 != 0120000 {
+		t.Errorf("lstat mode %o", a.perm)
+	}
+	c.send(FxpRealpath, "x/../c")
+	if names := c.names(); len(names) != 1 ||
+		names[0] != filepath.Join(dir, "c") {
+		t.Errorf("realpath: %q", names)
+	}
+	c.send(FxpRemove, "c")
+	c.expectStatus(FxOk)
+	c.send(FxpRemove, "c")
+	c.expectStatus(FxNoSuchFile)
+
+	if err := c.close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDirs(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	c := newClient(t, dir)
+	c.init()
+
+	c.send(FxpMkdir, "d", attrs{flags: FileXferAttrPermissions, perm: 0700})
+	c.expectStatus(FxOk)
+	want := []string{"x", "y", "z"}
+	for _, name := range want {
+		c.send(FxpOpen, "d/"+name, uint32(FxfWrite|FxfCreat), attrs{})
+		h := c.handle()
+		c.send(FxpClose, h)
+		c.expectStatus(FxOk)
+	}
+	c.send(FxpStat, "d")
+	if a := c.attrs(); a.perm != 040700 {
+		t.Errorf("mkdir mode %o", a.perm)
+	}
+	c.send(FxpOpendir, "d")
+	h := c.handle()
+	c.send(FxpReaddir, h)
+	names := c.names()
+	sort.Strings(names)
+	if len(names) != len(want) {
+		t.Fatalf("readdir: %q", names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("readdir: %q", names)
+		}
+	}
+	c.send(FxpReaddir, h)
+	c.expectStatus(FxEOF)
+	c.send(FxpRead, h, uint32(0), uint32(0), uint32(1))
+	c.expectStatus(FxBadMessage)
+	c.send(FxpClose, h)
+	c.expectStatus(FxOk)
+
+	c.send(FxpRmdir, "d")
+	c.expectStatus(FxFailure)
+	for _, name := range want {
+		c.send(FxpRemove, "d/"+name)
+		c.expectStatus(FxOk)
+	}
+	c.send(FxpRmdir, "d")
+	c.expectStatus(FxOk)
+
+	if err := c.close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBadRequests(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	c := newClient(t, dir)
+	c.init()
+
+	c.send(FxpExtended, "statvfs@openssh.com", ".")
+	c.expectStatus(FxOpUnsupported)
+	// a string longer than the packet
+	c.send(FxpStat, uint32(100))
+	c.expectStatus(FxBadMessage)
+	c.send(FxpRead, "no-such-handle", uint32(0), uint32(0), uint32(1))
+	c.expectStatus(FxBadMessage)
+	// missing open flags and attributes
+	c.send(FxpOpen, "a")
+	c.expectStatus(FxBadMessage)
+	if _, err := os.Stat(filepath.Join(dir, "a")); err == nil {
+		t.Error("truncated open created file")
+	}
+
+	// A packet without its id closes the session.
+	c.write([]byte{0, 0, 0, 3, FxpStat, 0, 0})
+	if err := c.close(); err != errBadMessage {
+		t.Error("truncated id:", err)
+	}
+
+	c = newClient(t, dir)
+	c.write([]byte{0xff, 0xff, 0xff, 0xff})
+	if err := c.close(); err == nil {
+		t.Error("oversize packet accepted")
+	}
+}
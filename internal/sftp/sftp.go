@@ -0,0 +1,77 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package sftp serves version 3 of the SSH File Transfer Protocol; enough for
+// sftp(1) and scp(1) with OpenSSH 9+ to copy images to and from the machine.
+//
+// See: https://tools.ietf.org/html/draft-ietf-secsh-filexfer-02
+package sftp
+
+const Version = 3
+
+// Packet types
+const (
+	FxpInit          = 1
+	FxpVersion       = 2
+	FxpOpen          = 3
+	FxpClose         = 4
+	FxpRead          = 5
+	FxpWrite         = 6
+	FxpLstat         = 7
+	FxpFstat         = 8
+	FxpSetstat       = 9
+	FxpFsetstat      = 10
+	FxpOpendir       = 11
+	FxpReaddir       = 12
+	FxpRemove        = 13
+	FxpMkdir         = 14
+	FxpRmdir         = 15
+	FxpRealpath      = 16
+	FxpStat          = 17
+	FxpRename        = 18
+	FxpReadlink      = 19
+	FxpSymlink       = 20
+	FxpStatus        = 101
+	FxpHandle        = 102
+	FxpData          = 103
+	FxpName          = 104
+	FxpAttrs         = 105
+	FxpExtended      = 200
+	FxpExtendedReply = 201
+)
+
+// Status codes
+const (
+	FxOk               = 0
+	FxEOF              = 1
+	FxNoSuchFile       = 2
+	FxPermissionDenied = 3
+	FxFailure          = 4
+	FxBadMessage       = 5
+	FxNoConnection     = 6
+	FxConnectionLost   = 7
+	FxOpUnsupported    = 8
+)
+
+// Open flags
+const (
+	FxfRead   = 1 << 0
+	FxfWrite  = 1 << 1
+	FxfAppend = 1 << 2
+	FxfCreat  = 1 << 3
+	FxfTrunc  = 1 << 4
+	FxfExcl   = 1 << 5
+)
+
+// Attribute flags
+const (
+	FileXferAttrSize        = 1 << 0
+	FileXferAttrUidGid      = 1 << 1
+	FileXferAttrPermissions = 1 << 2
+	FileXferAttrAcModTime   = 1 << 3
+	FileXferAttrExtended    = 1 << 31
+)
+
+// MaxPacket is the largest request accepted by the server.
+const MaxPacket = 256 * 1024
@@ -6,6 +6,7 @@ package shellutils
 
 import (
 	"fmt"
+	"strings"
 )
 
 // Cmdline is a slice of Words which may be variable setting, a command,
@@ -59,3 +60,13 @@ func (c *Cmdline) Slice(getenv func(string) string) (map[string]string, []string
 	}
 	return envmap, Cmdline
 }
+
+// Quote returns the command line, without its terminator, with its Words
+// quoted for Parse.
+func (c *Cmdline) Quote(getenv func(string) string) string {
+	words := make([]string, 0, len(c.Cmds))
+	for i := range c.Cmds {
+		words = append(words, c.Cmds[i].Quote(getenv))
+	}
+	return strings.Join(words, " ")
+}
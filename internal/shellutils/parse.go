@@ -60,8 +60,8 @@ processRune:
 				s = s[1:]
 				w.addLiteral(string(r))
 			}
-			if w.String() == ";" || w.String() == "&&" ||
-				w.String() == "||" {
+			if w.String() == ";" || w.String() == "&" ||
+				w.String() == "&&" || w.String() == "||" {
 				c.Term = w
 				w = Word{}
 				cl.add(&c)
@@ -36,17 +36,15 @@ func testSlice(script []string) (*List, error) {
 }
 
 func (ls *List) print() {
-	for _, pl := range ls.Cmds {
-		for _, sl := range pl.Cmds {
-			_, cmdline := sl.Slice(os.Getenv)
-			term := sl.Term.String()
-			if term == "" {
-				term = "\n"
-			} else {
-				term = " " + term + " "
-			}
-			fmt.Print(strings.Join(cmdline, " "), term)
+	for _, cl := range ls.Cmds {
+		_, cmdline := cl.Slice(os.Getenv)
+		term := cl.Term.String()
+		if term == "" {
+			term = "\n"
+		} else {
+			term = " " + term + " "
 		}
+		fmt.Print(strings.Join(cmdline, " "), term)
 	}
 }
 
@@ -111,3 +109,40 @@ func TestDoublequote(t *testing.T) {
 
 	cmd.print()
 }
+
+func TestBackground(t *testing.T) {
+	cmd, err := testSlice([]string{"sleep 10 & echo $!; qsfp | more &"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var terms []string
+	for _, cl := range cmd.Cmds {
+		terms = append(terms, cl.Term.String())
+	}
+	if got := strings.Join(terms, ","); got != "&,;,|,&" {
+		t.Errorf("terminators: %q", got)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	script := []string{`X=1 echo "it's" $HOME '' a\ b > out`}
+	cmd, err := testSlice(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	getenv := func(k string) string { return map[string]string{"HOME": "/root dir"}[k] }
+	want := `X=1 echo 'it'\''s' '/root dir' '' 'a b' '>' out`
+	got := cmd.Cmds[0].Quote(getenv)
+	if got != want {
+		t.Errorf("got: %s\nwant: %s", got, want)
+	}
+	requoted, err := testSlice([]string{got})
+	if err != nil {
+		t.Fatal(err)
+	}
+	envmap, args := requoted.Cmds[0].Slice(getenv)
+	if envmap["X"] != "1" || strings.Join(args, "|") !=
+		"echo|it's|/root dir||a b|>|out" {
+		t.Errorf("requoted: %v %q", envmap, args)
+	}
+}
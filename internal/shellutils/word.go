@@ -80,3 +80,36 @@ func (w *Word) String() string {
 	}
 	return s
 }
+
+// Quote returns the Word with its variables expanded by getenv and its
+// literal text quoted such that Parse would return an equivalent Word.
+func (w *Word) Quote(getenv func(string) string) string {
+	if len(w.Tokens) == 0 {
+		return "''"
+	}
+	s := ""
+	for _, t := range w.Tokens {
+		switch t.T {
+		case TokenLiteral:
+			s += quote(t.V)
+		case TokenEnvget:
+			s += quote(getenv(t.V))
+		case TokenEnvset:
+			s += t.V
+		}
+	}
+	return s
+}
+
+func quote(s string) string {
+	if len(s) == 0 {
+		return "''"
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) &&
+			!strings.ContainsRune("%+,-./:@_", r) {
+			return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+		}
+	}
+	return s
+}
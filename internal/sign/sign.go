@@ -0,0 +1,222 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package sign verifies firmware images with an ed25519 signed manifest of
+// their SHA-256 hashes.
+//
+// The manifest has the format of sha256sum(1) output,
+//
+//	HASH  NAME
+//
+// and is accompanied by a detached signature, MANIFEST.sig, with the base64
+// encoded ed25519 signature of the manifest.  An image is verified if the
+// manifest signature is valid for one of the trusted keys and the image
+// hash matches the manifest entry of its base name.
+package sign
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/ed25519"
+)
+
+const (
+	ManifestName  = "MANIFEST"
+	SignatureName = ManifestName + ".sig"
+)
+
+// Keys are the base64 encoded ed25519 public keys trusted to sign images.
+// Machines add their release keys to this list at init.  With neither Keys
+// nor Key, every image fails verification.
+var Keys []string
+
+// Key is an additional trusted key that may be embedded at build time with:
+//
+//	go build -ldflags "-X github.com/platinasystems/go/internal/sign.Key=..."
+var Key string
+
+var (
+	ErrUnsigned   = errors.New("unsigned")
+	ErrBadSig     = errors.New("signature doesn't match a trusted key")
+	ErrNoKeys     = errors.New("no trusted keys")
+	ErrNotListed  = errors.New("not in manifest")
+	ErrHashDiffer = errors.New("hash doesn't match manifest")
+)
+
+// A Manifest maps image base names to their SHA-256 hash.
+type Manifest map[string][]byte
+
+// Parse the sha256sum(1) formatted manifest.
+func Parse(b []byte) (Manifest, error) {
+	m := make(Manifest)
+	for scan := bufio.NewScanner(bytes.NewReader(b)); scan.Scan(); {
+		line := strings.TrimSpace(scan.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%q: invalid manifest line", line)
+		}
+		sum, err := hex.DecodeString(fields[0])
+		if err != nil || len(sum) != sha256.Size {
+			return nil, fmt.Errorf("%s: invalid hash", fields[1])
+		}
+		// sha256sum prefixes the name with '*' in binary mode
+		name := filepath.Base(strings.TrimPrefix(fields[1], "*"))
+		m[name] = sum
+	}
+	return m, nil
+}
+
+// Bytes returns the manifest in sha256sum(1) format sorted by name.
+func (m Manifest) Bytes() []byte {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	buf := new(bytes.Buffer)
+	for _, name := range names {
+		fmt.Fprintf(buf, "%x  %s\n", m[name], name)
+	}
+	return buf.Bytes()
+}
+
+// Add the named file's hash to the manifest.
+func (m Manifest) Add(fn string) error {
+	sum, err := Hash(fn)
+	if err != nil {
+		return err
+	}
+	m[filepath.Base(fn)] = sum
+	return nil
+}
+
+// Check returns nil if the named file's hash matches the manifest entry of
+// its base name.
+func (m Manifest) Check(fn string) error {
+	want, found := m[filepath.Base(fn)]
+	if !found {
+		return fmt.Errorf("%s: %v", fn, ErrNotListed)
+	}
+	sum, err := Hash(fn)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(sum, want) {
+		return fmt.Errorf("%s: %v", fn, ErrHashDiffer)
+	}
+	return nil
+}
+
+// Hash returns the SHA-256 of the named file.
+func Hash(fn string) ([]byte, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// Sign returns the base64 encoded signature of the manifest.
+func Sign(key ed25519.PrivateKey, manifest []byte) []byte {
+	sig := ed25519.Sign(key, manifest)
+	return []byte(base64.StdEncoding.EncodeToString(sig) + "\n")
+}
+
+// Verify the base64 encoded signature of the manifest with the trusted keys
+// then return the parsed manifest.
+func Verify(manifest, sig []byte) (Manifest, error) {
+	keys, err := TrustedKeys()
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	if len(bytes.TrimSpace(sig)) == 0 {
+		return nil, ErrUnsigned
+	}
+	raw, err := base64.StdEncoding.DecodeString(
+		string(bytes.TrimSpace(sig)))
+	if err != nil || len(raw) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("%s: invalid", SignatureName)
+	}
+	for _, key := range keys {
+		if ed25519.Verify(key, manifest, raw) {
+			return Parse(manifest)
+		}
+	}
+	return nil, ErrBadSig
+}
+
+// Load and verify the manifest and signature in the given directory.
+func Load(dir string) (Manifest, error) {
+	manifest, err := ioutil.ReadFile(filepath.Join(dir, ManifestName))
+	if os.IsNotExist(err) {
+		return nil, ErrUnsigned
+	}
+	if err != nil {
+		return nil, err
+	}
+	sig, err := ioutil.ReadFile(filepath.Join(dir, SignatureName))
+	if os.IsNotExist(err) {
+		return nil, ErrUnsigned
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Verify(manifest, sig)
+}
+
+// VerifyFile checks the named file with the signed manifest in its
+// directory.
+func VerifyFile(fn string) error {
+	m, err := Load(filepath.Dir(fn))
+	if err != nil {
+		return fmt.Errorf("%s: %v", fn, err)
+	}
+	return m.Check(fn)
+}
+
+// Enabled returns true if there are trusted keys to verify images with.
+// Builds without a key refuse every image unless the user insists.
+func Enabled() bool {
+	return len(Keys) > 0 || len(Key) > 0
+}
+
+// TrustedKeys returns the decoded Keys and Key.
+func TrustedKeys() ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	s := Keys
+	if len(Key) > 0 {
+		s = append(s[:len(s):len(s)], Key)
+	}
+	for _, k := range s {
+		b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(k))
+		if err != nil || len(b) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%q: invalid public key", k)
+		}
+		keys = append(keys, ed25519.PublicKey(b))
+	}
+	return keys, nil
+}
@@ -0,0 +1,103 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package sign
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ed25519"
+)
+
+func Test(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, other, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(keys []string) { Keys = keys }(Keys)
+	Keys = []string{base64.StdEncoding.EncodeToString(pub)}
+
+	dir, err := ioutil.TempDir("", "sign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	img := filepath.Join(dir, "goes-example")
+	if err = ioutil.WriteFile(img, []byte("image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(key ed25519.PrivateKey) {
+		m := make(Manifest)
+		if err := m.Add(img); err != nil {
+			t.Fatal(err)
+		}
+		b := m.Bytes()
+		fn := filepath.Join(dir, ManifestName)
+		if err := ioutil.WriteFile(fn, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+		fn = filepath.Join(dir, SignatureName)
+		if err := ioutil.WriteFile(fn, Sign(key, b), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = VerifyFile(img); err == nil {
+		t.Error("unsigned image verified")
+	}
+	write(priv)
+	if err = VerifyFile(img); err != nil {
+		t.Error(err)
+	}
+	if err = ioutil.WriteFile(img, []byte("rootkit"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifyFile(img); err == nil {
+		t.Error("tampered image verified")
+	}
+	write(other)
+	if err = VerifyFile(img); err == nil {
+		t.Error("image signed by untrusted key verified")
+	}
+}
+
+func TestParse(t *testing.T) {
+	m, err := Parse([]byte(`# comment
+2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824 *hello
+e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  dir/empty
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 2 || m["hello"] == nil || m["empty"] == nil {
+		t.Error("unexpected:", m)
+	}
+	if _, err = Parse([]byte("1234  short\n")); err == nil {
+		t.Error("short hash parsed")
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	defer func(keys []string, key string) {
+		Keys, Key = keys, key
+	}(Keys, Key)
+	Keys, Key = nil, ""
+	if Enabled() {
+		t.Error("enabled without keys")
+	}
+	Key = "AAAA"
+	if !Enabled() {
+		t.Error("not enabled with key")
+	}
+}
@@ -5,9 +5,12 @@
 package ethtool
 
 import (
+	"fmt"
 	"io/ioutil"
-	"time"
+	"strconv"
 
+	"github.com/platinasystems/go/internal/nl"
+	nlethtool "github.com/platinasystems/go/internal/nl/genl/ethtool"
 	"github.com/platinasystems/go/internal/test"
 	"gopkg.in/yaml.v2"
 )
@@ -15,6 +18,12 @@ import (
 var Map map[string][]string
 var Map2 map[string][]string
 
+// Init sets the link modes of testdata/ethtool.yaml and the private flags of
+// testdata/ethtool_priv_flags.yaml through the ethtool netlink family, e.g.
+//
+//	xeth2: [speed, 100000, autoneg, off]
+//
+//	xeth2: [fec91, on, copper, on]
 func Init(assert test.Assert) {
 	assert.Helper()
 	Map = make(map[string][]string)
@@ -24,8 +33,18 @@ func Init(assert test.Assert) {
 	}
 	err = yaml.Unmarshal(b, Map)
 	assert.Nil(err)
+
+	sock, err := nl.NewSock(nl.NETLINK_GENERIC)
+	assert.Nil(err)
+	defer sock.Close()
+	sr := nl.NewSockReceiver(sock)
+	f, err := nlethtool.Family(sr)
+	assert.Nil(err)
+
 	for ifname, args := range Map {
-		assert.Program(2*time.Second, "ethtool", "-s", ifname, args)
+		attrs, err := linkModes(args)
+		assert.Nil(err)
+		assert.Nil(nlethtool.SetLinkModes(sr, f, ifname, attrs...))
 	}
 
 	//take care of priv-flags
@@ -37,6 +56,83 @@ func Init(assert test.Assert) {
 	err = yaml.Unmarshal(b, Map2)
 	assert.Nil(err)
 	for ifname, args := range Map2 {
-		assert.Program(2*time.Second, "ethtool", "--set-priv-flags", ifname, args)
+		flags, err := privFlags(args)
+		assert.Nil(err)
+		assert.Nil(nlethtool.SetPrivFlags(sr, f, ifname, flags))
+	}
+}
+
+// Convert "ethtool -s" arguments to LINKMODES_SET attributes.
+func linkModes(args []string) ([]nl.Attr, error) {
+	var attrs []nl.Attr
+	if len(args)%2 != 0 {
+		return nil, fmt.Errorf("%v: missing value", args)
+	}
+	for i := 0; i < len(args); i += 2 {
+		name, value := args[i], args[i+1]
+		switch name {
+		case "speed":
+			speed, err := strconv.ParseUint(value, 0, 32)
+			if err != nil {
+				return nil, fmt.Errorf("speed: %v", err)
+			}
+			attrs = append(attrs, nl.Attr{
+				nlethtool.ETHTOOL_A_LINKMODES_SPEED,
+				nl.Uint32Attr(speed)})
+		case "autoneg":
+			autoneg := nlethtool.AUTONEG_DISABLE
+			on, err := onoff(value)
+			if err != nil {
+				return nil, fmt.Errorf("autoneg: %v", err)
+			}
+			if on {
+				autoneg = nlethtool.AUTONEG_ENABLE
+			}
+			attrs = append(attrs, nl.Attr{
+				nlethtool.ETHTOOL_A_LINKMODES_AUTONEG,
+				nl.Uint8Attr(autoneg)})
+		case "duplex":
+			var duplex uint8
+			switch value {
+			case "half":
+				duplex = nlethtool.DUPLEX_HALF
+			case "full":
+				duplex = nlethtool.DUPLEX_FULL
+			default:
+				return nil, fmt.Errorf("duplex: %q invalid", value)
+			}
+			attrs = append(attrs, nl.Attr{
+				nlethtool.ETHTOOL_A_LINKMODES_DUPLEX,
+				nl.Uint8Attr(duplex)})
+		default:
+			return nil, fmt.Errorf("%s: unsupported", name)
+		}
+	}
+	return attrs, nil
+}
+
+// Convert "ethtool --set-priv-flags" arguments to named flags.
+func privFlags(args []string) (map[string]bool, error) {
+	if len(args)%2 != 0 {
+		return nil, fmt.Errorf("%v: missing value", args)
+	}
+	flags := make(map[string]bool)
+	for i := 0; i < len(args); i += 2 {
+		on, err := onoff(args[i+1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", args[i], err)
+		}
+		flags[args[i]] = on
+	}
+	return flags, nil
+}
+
+func onoff(s string) (bool, error) {
+	switch s {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
 	}
+	return false, fmt.Errorf("%q: neither on nor off", s)
 }
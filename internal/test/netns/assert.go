@@ -0,0 +1,127 @@
+// Copyright © 2015-2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package netns
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// Timeout of Ping, Route and Neighbor.
+const Timeout = 3 * time.Second
+
+// Ping asserts an ICMP or ICMPv6 echo response from the given address, in the
+// named namespace, w/in Timeout.
+func Ping(netns, addr string) error {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return fmt.Errorf("%s: invalid address", addr)
+	}
+	err := poll(Timeout, func() error {
+		return Do(netns, func() error { return echo(ip) })
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %s no response: %v", netns, addr, err)
+	}
+	return nil
+}
+
+// Send an echo request and wait a second for its reply.
+func echo(ip net.IP) error {
+	network, request, reply := "ip4:icmp", byte(8), byte(0)
+	if ip.To4() == nil {
+		network, request, reply = "ip6:ipv6-icmp", 128, 129
+	}
+	conn, err := net.ListenPacket(network, "")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	id := uint16(os.Getpid())
+	seq := uint16(time.Now().UnixNano())
+	b := []byte{request, 0, 0, 0,
+		byte(id >> 8), byte(id), byte(seq >> 8), byte(seq),
+		'n', 'e', 't', 'n', 's'}
+	if request == 8 {
+		// the kernel sums ICMPv6
+		sum := checksum(b)
+		b[2], b[3] = byte(sum>>8), byte(sum)
+	}
+	if _, err = conn.WriteTo(b, &net.IPAddr{IP: ip}); err != nil {
+		return err
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		if n < 8 || buf[0] != reply ||
+			!from.(*net.IPAddr).IP.Equal(ip) {
+			continue
+		}
+		if buf[4] == b[4] && buf[5] == b[5] &&
+			buf[6] == b[6] && buf[7] == b[7] {
+			return nil
+		}
+	}
+}
+
+func checksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return ^uint16(sum)
+}
+
+// Route asserts that the named namespace has a route to the given prefix
+// w/in Timeout; if given, the route output must also include each of the
+// other strings, e.g. "via 10.1.0.1" or "dev eth0".
+func Route(netns, prefix string, with ...string) error {
+	return match(netns, []string{"route", "show", prefix},
+		"route "+prefix, with)
+}
+
+// Neighbor asserts a reachable, stale, delay, probe, or permanent neighbor
+// entry for the given address w/in Timeout; if given, the output must also
+// include each of the other strings, e.g. "lladdr 02:00:00:00:00:01".
+func Neighbor(netns, addr string, with ...string) error {
+	return match(netns, []string{"neighbor", "show", addr},
+		"neighbor "+addr, append(with, "lladdr"))
+}
+
+func match(netns string, args []string, what string, with []string) error {
+	var out string
+	err := poll(Timeout, func() (err error) {
+		out, err = Ip(append([]string{"-n", netns}, args...)...)
+		if err != nil {
+			return
+		}
+		if len(strings.TrimSpace(out)) == 0 {
+			return fmt.Errorf("none")
+		}
+		for _, s := range with {
+			if !strings.Contains(out, s) {
+				return fmt.Errorf("%q", strings.TrimSpace(out))
+			}
+		}
+		return
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %s: %v", netns, what, err)
+	}
+	return nil
+}
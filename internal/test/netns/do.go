@@ -0,0 +1,89 @@
+// Copyright © 2015-2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package netns
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+
+	"github.com/platinasystems/go/internal/netns"
+)
+
+// Do runs f in a thread switched to the named namespace.  Sockets opened by
+// f remain in that namespace.
+func Do(name string, f func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		// The thread exits, rather than return to the pool, since
+		// it's never unlocked.
+		runtime.LockOSThread()
+		fn := filepath.Join("/var/run/netns", name)
+		fd, err := syscall.Open(fn, syscall.O_RDONLY|syscall.O_CLOEXEC,
+			0)
+		if err != nil {
+			done <- err
+			return
+		}
+		_, _, errno := syscall.Syscall(uintptr(netns.SYS_SETNS),
+			uintptr(fd), uintptr(syscall.CLONE_NEWNET), 0)
+		syscall.Close(fd)
+		if errno != 0 {
+			done <- fmt.Errorf("setns %s: %v", fn, errno)
+			return
+		}
+		done <- f()
+	}()
+	return <-done
+}
+
+const unshared = "GOES_TEST_NETNS_UNSHARED"
+
+// Unshare, if not root, re-runs this program as root of new user, mount and
+// network namespaces then exits with its status.  Call it from TestMain
+// before m.Run() so that tests may build topologies without privilege.
+// If unprivileged user namespaces are disabled, this returns and the tests
+// should skip.
+func Unshare() {
+	if os.Getenv(unshared) == "1" {
+		// The parent's /run isn't writable by the mapped root.
+		syscall.Mount("", "/", "none", syscall.MS_PRIVATE|syscall.MS_REC,
+			"")
+		syscall.Mount("none", "/run", "tmpfs", 0, "")
+		return
+	}
+	if os.Geteuid() == 0 {
+		return
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return
+	}
+	p, err := os.StartProcess(exe, os.Args, &os.ProcAttr{
+		Env:   append(os.Environ(), unshared+"=1"),
+		Files: []*os.File{os.Stdin, os.Stdout, os.Stderr},
+		Sys: &syscall.SysProcAttr{
+			Cloneflags: syscall.CLONE_NEWUSER |
+				syscall.CLONE_NEWNS |
+				syscall.CLONE_NEWNET,
+			UidMappings: []syscall.SysProcIDMap{
+				{ContainerID: 0, HostID: os.Getuid(), Size: 1},
+			},
+			GidMappings: []syscall.SysProcIDMap{
+				{ContainerID: 0, HostID: os.Getgid(), Size: 1},
+			},
+		},
+	})
+	if err != nil {
+		return
+	}
+	ps, err := p.Wait()
+	if err != nil {
+		os.Exit(1)
+	}
+	os.Exit(ps.Sys().(syscall.WaitStatus).ExitStatus())
+}
@@ -0,0 +1,50 @@
+// Copyright © 2015-2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package netns
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/platinasystems/go/goes"
+	"github.com/platinasystems/go/goes/cmd"
+	"github.com/platinasystems/go/goes/cmd/hget"
+	"github.com/platinasystems/go/goes/cmd/hwait"
+	"github.com/platinasystems/go/goes/cmd/redisd"
+	"github.com/platinasystems/go/goes/cmd/vnetd"
+	"github.com/platinasystems/go/internal/machine"
+	"github.com/platinasystems/go/vnet"
+)
+
+const testMachine = "netns-test"
+
+// The goes of the daemons and ready commands in testdata/vnet.yaml that this
+// test program runs with -test.main.
+var testGoes = &goes.Goes{
+	NAME: "goes-" + testMachine,
+	ByName: map[string]cmd.Cmd{
+		"hget":  hget.Command{},
+		"hwait": hwait.Command{},
+		"redisd": &redisd.Command{
+			Devs:    []string{"lo"},
+			Machine: testMachine,
+		},
+		"vnetd": &vnetd.Command{},
+	},
+}
+
+func goesMain() {
+	machine.Name = testMachine
+	// without a platform, vnet has just the software interfaces
+	vnetd.Hook = func(init func(), v *vnet.Vnet) error {
+		vnet.AddInit(func(*vnet.Vnet) { init() })
+		return nil
+	}
+	if err := testGoes.Main(os.Args...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,316 @@
+// Copyright © 2015-2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package netns builds test topologies of network namespaces connected by
+// veth pairs from a YAML description like this:
+//
+//	netns:
+//	- name: h1
+//	  routes:
+//	  - {prefix: 10.1.0.2/31, gw: 10.1.0.1}
+//	- name: r
+//	  router: true
+//	  daemons:
+//	  - args: [goes, redisd]
+//	    ready: [goes, hwait, platina, redis.ready, "true", "10"]
+//	  - args: [goes, vnetd]
+//	    ready: [goes, hwait, platina, vnet.ready, "true", "30"]
+//	    timeout: 32
+//	- name: h2
+//	  routes:
+//	  - {prefix: 10.1.0.0/31, gw: 10.1.0.3}
+//	links:
+//	- a: {netns: h1, ifname: eth0, address: [10.1.0.0/31]}
+//	  b: {netns: r, ifname: eth0, address: [10.1.0.1/31]}
+//	- a: {netns: h2, ifname: eth0, address: [10.1.0.2/31]}
+//	  b: {netns: r, ifname: eth1, address: [10.1.0.3/31]}
+//
+// A daemon or ready command beginning with "goes" is run by this test
+// program with -test.main, so a goes machine test may start its own redisd
+// and vnetd in a namespace.  Without a platform, vnetd only has software
+// interfaces, so the kernel of a router namespace, not vnetd, forwards
+// between its veths.
+// The unix abstract sockets of these daemons are per namespace so there may be
+// an instance in each.
+//
+// Topologies are made with iproute2, ip, as root or, with Unshare, within an
+// unprivileged user namespace.
+package netns
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/platinasystems/go/internal/prog"
+	"github.com/platinasystems/go/internal/test"
+	"gopkg.in/yaml.v2"
+)
+
+// Config of the topology.
+type Config struct {
+	Netns []Netns `yaml:"netns"`
+	Links []Link  `yaml:"links"`
+
+	daemons []*test.Program
+	added   []string
+}
+
+type Netns struct {
+	Name string `yaml:"name"`
+	// Enable IPv4 and IPv6 forwarding.
+	Router  bool          `yaml:"router"`
+	Routes  []StaticRoute `yaml:"routes"`
+	Daemons []Daemon      `yaml:"daemons"`
+}
+
+type StaticRoute struct {
+	Prefix string `yaml:"prefix"`
+	Gw     string `yaml:"gw"`
+}
+
+// Link is a veth pair.
+type Link struct {
+	A End `yaml:"a"`
+	B End `yaml:"b"`
+}
+
+type End struct {
+	Netns   string   `yaml:"netns"`
+	Ifname  string   `yaml:"ifname"`
+	Address []string `yaml:"address"`
+	Lladdr  string   `yaml:"lladdr"`
+	Mtu     int      `yaml:"mtu"`
+}
+
+// Daemon is started in the background after the links and routes are
+// configured then, if given, the ready command is repeated until it succeeds
+// or Timeout seconds (default 10).
+type Daemon struct {
+	Args    []string `yaml:"args"`
+	Ready   []string `yaml:"ready"`
+	Timeout int      `yaml:"timeout"`
+}
+
+// Parse and verify the YAML topology.
+func Parse(b []byte) (*Config, error) {
+	config := new(Config)
+	if err := yaml.UnmarshalStrict(b, config); err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for _, ns := range config.Netns {
+		if len(ns.Name) == 0 {
+			return nil, fmt.Errorf("netns: missing name")
+		}
+		if names[ns.Name] {
+			return nil, fmt.Errorf("%s: duplicate netns", ns.Name)
+		}
+		names[ns.Name] = true
+		for _, d := range ns.Daemons {
+			if len(d.Args) == 0 {
+				return nil, fmt.Errorf("%s: daemon: missing args",
+					ns.Name)
+			}
+		}
+	}
+	for _, l := range config.Links {
+		for _, end := range []End{l.A, l.B} {
+			if !names[end.Netns] {
+				return nil, fmt.Errorf("link: %q: unknown netns",
+					end.Netns)
+			}
+			if len(end.Ifname) == 0 {
+				return nil, fmt.Errorf("link: %s: missing ifname",
+					end.Netns)
+			}
+		}
+	}
+	return config, nil
+}
+
+// Up creates the namespaces, links, addresses and routes then starts the
+// daemons.  Down should be deferred even if Up fails.
+func (config *Config) Up(tb testing.TB) error {
+	tb.Helper()
+	for _, ns := range config.Netns {
+		fn := filepath.Join("/var/run/netns", ns.Name)
+		if _, err := os.Stat(fn); err == nil {
+			return fmt.Errorf("%s: netns exists", ns.Name)
+		}
+		if _, err := Ip("netns", "add", ns.Name); err != nil {
+			return err
+		}
+		config.added = append(config.added, ns.Name)
+		_, err := Ip("-n", ns.Name, "link", "set", "lo", "up")
+		if err != nil {
+			return err
+		}
+		err = Do(ns.Name, func() error {
+			// skip duplicate address detection of new links
+			for _, x := range []struct {
+				fn string
+				ok bool
+			}{
+				{"ipv6/conf/default/accept_dad", false},
+				{"ipv6/conf/all/forwarding", ns.Router},
+				{"ipv4/ip_forward", ns.Router},
+			} {
+				s := "0\n"
+				if x.ok {
+					s = "1\n"
+				}
+				fn := filepath.Join("/proc/sys/net", x.fn)
+				err := ioutil.WriteFile(fn, []byte(s), 0644)
+				if err != nil && !os.IsNotExist(err) {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, l := range config.Links {
+		args := []string{"link", "add"}
+		args = append(args, l.A.args()...)
+		args = append(args, "type", "veth", "peer")
+		args = append(args, l.B.args()...)
+		if _, err := Ip(args...); err != nil {
+			return err
+		}
+		for _, end := range []End{l.A, l.B} {
+			if err := end.up(); err != nil {
+				return err
+			}
+		}
+	}
+	for _, ns := range config.Netns {
+		for _, r := range ns.Routes {
+			_, err := Ip("-n", ns.Name, "route", "add", r.Prefix,
+				"via", r.Gw)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, ns := range config.Netns {
+		for _, d := range ns.Daemons {
+			if err := config.start(tb, ns.Name, d); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Down stops the daemons and deletes the namespaces, and with them, the
+// links.
+func (config *Config) Down(tb testing.TB) {
+	tb.Helper()
+	for i := len(config.daemons) - 1; i >= 0; i-- {
+		config.daemons[i].Quit()
+	}
+	config.daemons = config.daemons[:0]
+	for i := len(config.added) - 1; i >= 0; i-- {
+		if _, err := Ip("netns", "del", config.added[i]); err != nil {
+			tb.Log(err)
+		}
+	}
+	config.added = config.added[:0]
+}
+
+func (end End) args() []string {
+	args := []string{"name", end.Ifname, "netns", end.Netns}
+	if len(end.Lladdr) > 0 {
+		args = append(args, "address", end.Lladdr)
+	}
+	if end.Mtu > 0 {
+		args = append(args, "mtu", fmt.Sprint(end.Mtu))
+	}
+	return args
+}
+
+func (end End) up() error {
+	for _, addr := range end.Address {
+		_, err := Ip("-n", end.Netns, "address", "add", addr, "dev",
+			end.Ifname)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := Ip("-n", end.Netns, "link", "set", end.Ifname, "up")
+	return err
+}
+
+func (config *Config) start(tb testing.TB, netns string, d Daemon) error {
+	tb.Helper()
+	args := []interface{}{time.Duration(d.Timeout+3) * time.Second,
+		"ip", "netns", "exec", netns}
+	args = append(args, self(d.Args))
+	p, err := test.Begin(tb, args...)
+	if err != nil {
+		return err
+	}
+	config.daemons = append(config.daemons, p)
+	if len(d.Ready) == 0 {
+		return nil
+	}
+	timeout := time.Duration(d.Timeout) * time.Second
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	return poll(timeout, func() error {
+		_, err := Exec(netns, d.Ready...)
+		return err
+	})
+}
+
+// Replace a leading "goes" with this program's main.
+func self(args []string) []string {
+	if len(args) > 0 && args[0] == "goes" {
+		return append([]string{prog.Name(), "-test.main"}, args[1:]...)
+	}
+	return args
+}
+
+// Ip runs iproute2 with the given args.
+func Ip(args ...string) (string, error) {
+	return run(exec.Command("ip", args...))
+}
+
+// Exec runs the command in the named namespace and returns its output.
+func Exec(netns string, args ...string) (string, error) {
+	return run(exec.Command("ip",
+		append([]string{"netns", "exec", netns}, self(args)...)...))
+}
+
+func run(cmd *exec.Cmd) (string, error) {
+	b, err := cmd.CombinedOutput()
+	if err != nil {
+		s := strings.TrimSpace(string(b))
+		if len(s) == 0 {
+			s = err.Error()
+		}
+		err = fmt.Errorf("%s: %s", strings.Join(cmd.Args, " "), s)
+	}
+	return string(b), err
+}
+
+// Repeat f until it succeeds or times out.
+func poll(timeout time.Duration, f func() error) (err error) {
+	const period = 250 * time.Millisecond
+	for t := timeout / period; ; t-- {
+		if err = f(); err == nil || t <= 0 {
+			return
+		}
+		time.Sleep(period)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright © 2015-2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package netns
+
+import (
+	"flag"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/platinasystems/go/internal/test"
+)
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	test.Assert{}.Main(goesMain)
+	Unshare()
+	os.Exit(m.Run())
+}
+
+func TestParse(t *testing.T) {
+	for _, s := range []string{
+		"netns: [{name: a}, {name: a}]",
+		"netns: [{name: a}]\nlinks: [{a: {netns: a, ifname: x}, b: {netns: b, ifname: y}}]",
+		"netns: [{name: a}]\nlinks: [{a: {netns: a}, b: {netns: a, ifname: y}}]",
+		"netns: [{name: a, daemons: [{ready: [true]}]}]",
+		"netns: [{name: a, unknown: true}]",
+	} {
+		if _, err := Parse([]byte(s)); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
+
+func TestRouter(t *testing.T) {
+	assert := test.Assert{t}
+	assert.YoureRoot()
+	if _, err := exec.LookPath("ip"); err != nil {
+		t.Skip(err)
+	}
+	(&Topology{
+		Name: "router",
+		File: "testdata/router.yaml",
+		Tests: test.Tests{
+			&test.Unit{"ping", func(t *testing.T) {
+				assert := test.Assert{t}
+				assert.Nil(Ping("netns-test-h1", "10.1.0.1"))
+				assert.Nil(Ping("netns-test-h1", "10.1.0.2"))
+				assert.Nil(Ping("netns-test-h2", "2001:db8:1::2"))
+				assert.NonNil(Do("netns-test-h1", func() error {
+					return echo([]byte{10, 9, 9, 9})
+				}))
+			}},
+			&test.Unit{"route", func(t *testing.T) {
+				assert := test.Assert{t}
+				assert.Nil(Route("netns-test-h1", "10.1.0.2/31",
+					"via 10.1.0.1", "dev eth0"))
+				assert.NonNil(Route("netns-test-h2", "10.9.0.0/16"))
+			}},
+			&test.Unit{"neighbor", func(t *testing.T) {
+				assert := test.Assert{t}
+				assert.Nil(Neighbor("netns-test-h1", "10.1.0.1",
+					"lladdr 02:00:00:00:00:01"))
+				assert.Nil(Neighbor("netns-test-r", "10.1.0.2"))
+			}},
+			&test.Unit{"exec", func(t *testing.T) {
+				assert := test.Assert{t}
+				out, err := Exec("netns-test-h2", "cat",
+					"/sys/class/net/eth0/mtu")
+				assert.Nil(err)
+				assert.Equal(out, "9000\n")
+			}},
+		},
+	}).Test(t)
+	for _, ns := range []string{"netns-test-h1", "netns-test-r"} {
+		if _, err := os.Stat("/var/run/netns/" + ns); err == nil {
+			t.Error(ns, "not deleted")
+		}
+	}
+}
+
+// TestVnetdReady only checks that redisd and vnetd start in a namespace with
+// veths and that vnetd reports vnet.ready.  Without a platform, vnetd has just
+// its software interfaces so it isn't in the veths' data path.
+func TestVnetdReady(t *testing.T) {
+	assert := test.Assert{t}
+	assert.YoureRoot()
+	if _, err := exec.LookPath("ip"); err != nil {
+		t.Skip(err)
+	}
+	(&Topology{
+		Name: "vnetd",
+		File: "testdata/vnet.yaml",
+		Tests: test.Tests{
+			&test.Unit{"ready", func(t *testing.T) {
+				assert := test.Assert{t}
+				out, err := Exec("netns-test-vnet", "goes", "hget",
+					testMachine, "vnet.ready")
+				assert.Nil(err)
+				assert.Equal(out, "true\n")
+			}},
+		},
+	}).Test(t)
+}
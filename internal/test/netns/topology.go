@@ -0,0 +1,64 @@
+// Copyright © 2015-2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package netns
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/platinasystems/go/internal/test"
+)
+
+// Topology runs its Tests with the namespaces of the YAML file.
+//
+//	var Suite = test.Suite{
+//		Name: "netns",
+//		Tests: test.Tests{
+//			&netns.Topology{
+//				Name: "router",
+//				File: "testdata/netns/router.yaml",
+//				Tests: test.Tests{
+//					&test.Unit{"ping", func(t *testing.T) {
+//						test.Assert{t}.Nil(netns.Ping("h1", "10.1.0.2"))
+//					}},
+//				},
+//			},
+//		},
+//	}
+type Topology struct {
+	Name string
+	File string
+	*Config
+	test.Tests
+}
+
+func (topo *Topology) String() string { return topo.Name }
+
+func (topo *Topology) Init(t *testing.T) {
+	assert := test.Assert{t}
+	assert.Helper()
+	b, err := ioutil.ReadFile(topo.File)
+	assert.Nil(err)
+	topo.Config, err = Parse(b)
+	assert.Nil(err)
+	assert.Nil(topo.Config.Up(t))
+}
+
+func (topo *Topology) Exit(t *testing.T) {
+	if topo.Config != nil {
+		topo.Config.Down(t)
+	}
+}
+
+func (topo *Topology) Test(t *testing.T) {
+	if *test.DryRun {
+		fmt.Println(t.Name())
+	} else {
+		defer topo.Exit(t)
+		topo.Init(t)
+	}
+	topo.Tests.Test(t)
+}
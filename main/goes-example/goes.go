@@ -26,6 +26,7 @@ import (
 	"github.com/platinasystems/go/goes/cmd/femtocom"
 	"github.com/platinasystems/go/goes/cmd/ficmd"
 	"github.com/platinasystems/go/goes/cmd/function"
+	"github.com/platinasystems/go/goes/cmd/genl"
 	"github.com/platinasystems/go/goes/cmd/grub"
 	"github.com/platinasystems/go/goes/cmd/hdel"
 	"github.com/platinasystems/go/goes/cmd/hdelta"
@@ -100,6 +101,7 @@ var Goes = &goes.Goes{
 		"femtocom": femtocom.Command{},
 		"fi":       &ficmd.Command{},
 		"function": &function.Command{},
+		"genl":     genl.Goes,
 		"goes-daemons": &daemons.Command{
 			Init: [][]string{
 				[]string{"redisd"},
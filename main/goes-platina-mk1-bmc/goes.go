@@ -53,6 +53,7 @@ import (
 	"github.com/platinasystems/go/goes/cmd/kill"
 	"github.com/platinasystems/go/goes/cmd/ln"
 	"github.com/platinasystems/go/goes/cmd/log"
+	"github.com/platinasystems/go/goes/cmd/logfwdd"
 	"github.com/platinasystems/go/goes/cmd/ls"
 	"github.com/platinasystems/go/goes/cmd/lsmod"
 	"github.com/platinasystems/go/goes/cmd/mkdir"
@@ -71,6 +72,7 @@ import (
 	"github.com/platinasystems/go/goes/cmd/ps"
 	"github.com/platinasystems/go/goes/cmd/pwd"
 	"github.com/platinasystems/go/goes/cmd/reboot"
+	"github.com/platinasystems/go/goes/cmd/redfishd"
 	"github.com/platinasystems/go/goes/cmd/redisd"
 	"github.com/platinasystems/go/goes/cmd/reload"
 	"github.com/platinasystems/go/goes/cmd/restart"
@@ -79,6 +81,7 @@ import (
 	"github.com/platinasystems/go/goes/cmd/slashinit"
 	"github.com/platinasystems/go/goes/cmd/sleep"
 	"github.com/platinasystems/go/goes/cmd/source"
+	"github.com/platinasystems/go/goes/cmd/sshd"
 	"github.com/platinasystems/go/goes/cmd/start"
 	"github.com/platinasystems/go/goes/cmd/stop"
 	"github.com/platinasystems/go/goes/cmd/stty"
@@ -134,6 +137,7 @@ var Goes = &goes.Goes{
 		"goes-daemons": &daemons.Command{
 			Init: [][]string{
 				[]string{"redisd"},
+				[]string{"logfwdd"},
 				[]string{"fantrayd"},
 				[]string{"fspd"},
 				[]string{"i2cd"},
@@ -141,6 +145,7 @@ var Goes = &goes.Goes{
 				[]string{"ledgpiod"},
 				[]string{"mmclogd"},
 				[]string{"upgraded"},
+				[]string{"sshd"},
 				[]string{"uptimed"},
 				[]string{"ucd9090d"},
 				[]string{"w83795d"},
@@ -178,19 +183,21 @@ var Goes = &goes.Goes{
 		"ledgpiod": &ledgpiod.Command{
 			Init: ledgpiodInit,
 		},
-		"ln":      ln.Command{},
-		"log":     log.Command{},
-		"ls":      ls.Command{},
-		"lsmod":   lsmod.Command{},
-		"mkdir":   mkdir.Command{},
-		"mknod":   mknod.Command{},
-		"mmclog":  mmclog.Command{},
-		"mmclogd": &mmclogd.Command{},
-		"mount":   mount.Command{},
-		"ping":    ping.Command{},
-		"ps":      ps.Command{},
-		"pwd":     pwd.Command{},
-		"reboot":  reboot.Command{},
+		"ln":       ln.Command{},
+		"log":      log.Command{},
+		"logfwdd":  &logfwdd.Command{},
+		"ls":       ls.Command{},
+		"lsmod":    lsmod.Command{},
+		"mkdir":    mkdir.Command{},
+		"mknod":    mknod.Command{},
+		"mmclog":   mmclog.Command{},
+		"mmclogd":  &mmclogd.Command{},
+		"mount":    mount.Command{},
+		"ping":     ping.Command{},
+		"ps":       ps.Command{},
+		"pwd":      pwd.Command{},
+		"reboot":   reboot.Command{},
+		"redfishd": &redfishd.Command{},
 		"redisd": &redisd.Command{
 			Devs:    []string{"lo", "eth0"},
 			Machine: "platina-mk1-bmc",
@@ -225,6 +232,7 @@ var Goes = &goes.Goes{
 		"/init":  &slashinit.Command{},
 		"sleep":  sleep.Command{},
 		"source": &source.Command{},
+		"sshd":   &sshd.Command{},
 		"start": &start.Command{
 			ConfGpioHook: startConfGpioHook,
 		},
@@ -31,6 +31,7 @@ import (
 	"github.com/platinasystems/go/goes/cmd/femtocom"
 	"github.com/platinasystems/go/goes/cmd/ficmd"
 	"github.com/platinasystems/go/goes/cmd/function"
+	"github.com/platinasystems/go/goes/cmd/genl"
 	"github.com/platinasystems/go/goes/cmd/gpio"
 	"github.com/platinasystems/go/goes/cmd/hdel"
 	"github.com/platinasystems/go/goes/cmd/hdelta"
@@ -51,6 +52,8 @@ import (
 	"github.com/platinasystems/go/goes/cmd/kill"
 	"github.com/platinasystems/go/goes/cmd/ln"
 	"github.com/platinasystems/go/goes/cmd/log"
+	"github.com/platinasystems/go/goes/cmd/logfwdd"
+	"github.com/platinasystems/go/goes/cmd/lrange"
 	"github.com/platinasystems/go/goes/cmd/ls"
 	"github.com/platinasystems/go/goes/cmd/lsmod"
 	"github.com/platinasystems/go/goes/cmd/mkdir"
@@ -58,6 +61,7 @@ import (
 	"github.com/platinasystems/go/goes/cmd/mount"
 	"github.com/platinasystems/go/goes/cmd/ping"
 	"github.com/platinasystems/go/goes/cmd/platina/mk1/bootc"
+	"github.com/platinasystems/go/goes/cmd/platina/mk1/slotd"
 	"github.com/platinasystems/go/goes/cmd/platina/mk1/toggle"
 	"github.com/platinasystems/go/goes/cmd/platina/mk1/upgrade"
 	"github.com/platinasystems/go/goes/cmd/ps"
@@ -119,13 +123,16 @@ var Goes = &goes.Goes{
 		"femtocom": femtocom.Command{},
 		"fi":       &ficmd.Command{},
 		"function": &function.Command{},
+		"genl":     genl.Goes,
 		"gpio":     &gpio.Command{},
 		"goes-daemons": &daemons.Command{
 			Init: [][]string{
 				[]string{"redisd"},
+				[]string{"logfwdd"},
 				[]string{"uptimed"},
 				[]string{"tempd"},
 				[]string{"vnetd"},
+				[]string{"slotd"},
 			},
 		},
 		"hdel":    hdel.Command{},
@@ -146,6 +153,8 @@ var Goes = &goes.Goes{
 		"kill":    kill.Command{},
 		"ln":      ln.Command{},
 		"log":     log.Command{},
+		"logfwdd": &logfwdd.Command{},
+		"lrange":  lrange.Command{},
 		"ls":      ls.Command{},
 		"lsmod":   lsmod.Command{},
 		"mkdir":   mkdir.Command{},
@@ -191,6 +200,7 @@ var Goes = &goes.Goes{
 		},
 		"/init":  &slashinit.Command{},
 		"sleep":  sleep.Command{},
+		"slotd":  &slotd.Command{},
 		"source": &source.Command{},
 		"start": &start.Command{
 			ConfHook: func() error {
@@ -0,0 +1,17 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package main
+
+import "github.com/platinasystems/go/internal/sign"
+
+// Public ed25519 keys of the platina-mk1 release signers, see goes-sign.
+// The upgrade and bootc commands refuse images not signed by one of these.
+var releaseKeys = []string{
+	"ic7uhbyj/sTXXZo/RNKPTenbOAqpDyQiA5kkgCW83A0=",
+}
+
+func init() {
+	sign.Keys = append(sign.Keys, releaseKeys...)
+}
@@ -71,6 +71,7 @@ import (
 	"github.com/platinasystems/go/goes/cmd/slashinit"
 	"github.com/platinasystems/go/goes/cmd/sleep"
 	"github.com/platinasystems/go/goes/cmd/source"
+	"github.com/platinasystems/go/goes/cmd/sshd"
 	"github.com/platinasystems/go/goes/cmd/start"
 	"github.com/platinasystems/go/goes/cmd/stop"
 	"github.com/platinasystems/go/goes/cmd/stty"
@@ -135,6 +136,7 @@ var Goes = &goes.Goes{
 				[]string{"imx6d"},
 				//[]string{"ledgpiod"},
 				[]string{"upgraded"},
+				[]string{"sshd"},
 				[]string{"uptimed"},
 				//[]string{"ucd9090d"},
 				//]string{"w83795d"},
@@ -221,6 +223,7 @@ var Goes = &goes.Goes{
 		"/init":  &slashinit.Command{},
 		"sleep":  sleep.Command{},
 		"source": &source.Command{},
+		"sshd":   &sshd.Command{},
 		"start": &start.Command{
 			ConfGpioHook: startConfGpioHook,
 		},
@@ -51,6 +51,8 @@ import (
 	"github.com/platinasystems/go/goes/cmd/kill"
 	"github.com/platinasystems/go/goes/cmd/ln"
 	"github.com/platinasystems/go/goes/cmd/log"
+	"github.com/platinasystems/go/goes/cmd/logfwdd"
+	"github.com/platinasystems/go/goes/cmd/lrange"
 	"github.com/platinasystems/go/goes/cmd/ls"
 	"github.com/platinasystems/go/goes/cmd/lsmod"
 	"github.com/platinasystems/go/goes/cmd/mkdir"
@@ -66,6 +68,7 @@ import (
 	"github.com/platinasystems/go/goes/cmd/ps"
 	"github.com/platinasystems/go/goes/cmd/pwd"
 	"github.com/platinasystems/go/goes/cmd/reboot"
+	"github.com/platinasystems/go/goes/cmd/redfishd"
 	"github.com/platinasystems/go/goes/cmd/redisd"
 	"github.com/platinasystems/go/goes/cmd/reload"
 	"github.com/platinasystems/go/goes/cmd/restart"
@@ -74,6 +77,7 @@ import (
 	"github.com/platinasystems/go/goes/cmd/slashinit"
 	"github.com/platinasystems/go/goes/cmd/sleep"
 	"github.com/platinasystems/go/goes/cmd/source"
+	"github.com/platinasystems/go/goes/cmd/sshd"
 	"github.com/platinasystems/go/goes/cmd/start"
 	"github.com/platinasystems/go/goes/cmd/stop"
 	"github.com/platinasystems/go/goes/cmd/stty"
@@ -133,6 +137,7 @@ var Goes = &goes.Goes{
 			Init: [][]string{
 				//FIXME
 				[]string{"redisd"},
+				[]string{"logfwdd"},
 				//[]string{"fantrayd"},
 				//[]string{"fspd"},
 				[]string{"i2cd"},
@@ -142,6 +147,7 @@ var Goes = &goes.Goes{
 				[]string{"nct7802yd"},
 				[]string{"qsfpeventsd"},
 				[]string{"upgraded"},
+				[]string{"sshd"},
 				[]string{"uptimed"},
 				//[]string{"ucd9090d"},
 				//[]string{"w83795d"},
@@ -181,13 +187,15 @@ var Goes = &goes.Goes{
 		"lceventsd": &lceventsd.Command{
 			Init: lceventsdInit,
 		},
-		"ln":    ln.Command{},
-		"log":   log.Command{},
-		"ls":    ls.Command{},
-		"lsmod": lsmod.Command{},
-		"mkdir": mkdir.Command{},
-		"mknod": mknod.Command{},
-		"mount": mount.Command{},
+		"ln":      ln.Command{},
+		"log":     log.Command{},
+		"logfwdd": &logfwdd.Command{},
+		"lrange":  lrange.Command{},
+		"ls":      ls.Command{},
+		"lsmod":   lsmod.Command{},
+		"mkdir":   mkdir.Command{},
+		"mknod":   mknod.Command{},
+		"mount":   mount.Command{},
 		"nct7802yd": &nct7802yd.Command{
 			Init: nct7802ydInit,
 		},
@@ -197,7 +205,8 @@ var Goes = &goes.Goes{
 		"qsfpeventsd": &qsfpeventsd.Command{
 			Init: qsfpeventsdInit,
 		},
-		"reboot": reboot.Command{},
+		"reboot":   reboot.Command{},
+		"redfishd": &redfishd.Command{},
 		"redisd": &redisd.Command{
 			//FIXME
 			Devs:    []string{"lo", "eth0"},
@@ -232,6 +241,7 @@ var Goes = &goes.Goes{
 		"/init":  &slashinit.Command{},
 		"sleep":  sleep.Command{},
 		"source": &source.Command{},
+		"sshd":   &sshd.Command{},
 		"start": &start.Command{
 			ConfGpioHook: startConfGpioHook,
 		},
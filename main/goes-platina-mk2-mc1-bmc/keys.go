@@ -0,0 +1,18 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package main
+
+import "github.com/platinasystems/go/internal/sign"
+
+// Public ed25519 keys of the platina-mk2-mc1-bmc release signers,
+// see goes-sign.  The upgrade command refuses images not signed by one of
+// these.
+var releaseKeys = []string{
+	"N2+wMQ0diPgMp3kuPTpYnd11tGbkk3ZAmuOUFFqvIa0=",
+}
+
+func init() {
+	sign.Keys = append(sign.Keys, releaseKeys...)
+}
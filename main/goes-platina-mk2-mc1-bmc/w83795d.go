@@ -68,27 +68,27 @@ func w83795dInit() {
 	w83795d.WrRegFn["fan_tray.6.speed.return"] = "speed.return"
 	w83795d.WrRegFn["fan_tray.6.hwmon.target.units.C"] = "target.units.C"
 
-	w83795d.WrRegRng["fan_tray.1.speed"] = []string{"low", "med", "high", "auto", "max"}
+	w83795d.WrRegRng["fan_tray.1.speed"] = []string{"low", "med", "high", "auto", "max", "policy"}
 	w83795d.WrRegRng["fan_tray.1.control"] = []string{"local", "remote.mc1", "remote.mc2"}
 	w83795d.WrRegRng["fan_tray.1.hwmon.target.units.C"] = []string{"0", "60"}
 
-	w83795d.WrRegRng["fan_tray.2.speed"] = []string{"low", "med", "high", "auto", "max"}
+	w83795d.WrRegRng["fan_tray.2.speed"] = []string{"low", "med", "high", "auto", "max", "policy"}
 	w83795d.WrRegRng["fan_tray.2.control"] = []string{"local", "remote.mc1", "remote.mc2"}
 	w83795d.WrRegRng["fan_tray.2.hwmon.target.units.C"] = []string{"0", "60"}
 
-	w83795d.WrRegRng["fan_tray.3.speed"] = []string{"low", "med", "high", "auto", "max"}
+	w83795d.WrRegRng["fan_tray.3.speed"] = []string{"low", "med", "high", "auto", "max", "policy"}
 	w83795d.WrRegRng["fan_tray.3.control"] = []string{"local", "remote.mc1", "remote.mc2"}
 	w83795d.WrRegRng["fan_tray.3.hwmon.target.units.C"] = []string{"0", "60"}
 
-	w83795d.WrRegRng["fan_tray.4.speed"] = []string{"low", "med", "high", "auto", "max"}
+	w83795d.WrRegRng["fan_tray.4.speed"] = []string{"low", "med", "high", "auto", "max", "policy"}
 	w83795d.WrRegRng["fan_tray.4.control"] = []string{"local", "remote.mc1", "remote.mc2"}
 	w83795d.WrRegRng["fan_tray.4.hwmon.target.units.C"] = []string{"0", "60"}
 
-	w83795d.WrRegRng["fan_tray.5.speed"] = []string{"low", "med", "high", "auto", "max"}
+	w83795d.WrRegRng["fan_tray.5.speed"] = []string{"low", "med", "high", "auto", "max", "policy"}
 	w83795d.WrRegRng["fan_tray.5.control"] = []string{"local", "remote.mc1", "remote.mc2"}
 	w83795d.WrRegRng["fan_tray.5.hwmon.target.units.C"] = []string{"0", "60"}
 
-	w83795d.WrRegRng["fan_tray.6.speed"] = []string{"low", "med", "high", "auto", "max"}
+	w83795d.WrRegRng["fan_tray.6.speed"] = []string{"low", "med", "high", "auto", "max", "policy"}
 	w83795d.WrRegRng["fan_tray.6.control"] = []string{"local", "remote.mc1", "remote.mc2"}
 	w83795d.WrRegRng["fan_tray.6.hwmon.target.units.C"] = []string{"0", "60"}
 }
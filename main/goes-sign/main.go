@@ -0,0 +1,99 @@
+// Copyright © 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// sign release images for goes upgrade and bootc
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/platinasystems/go/internal/sign"
+)
+
+var (
+	genkeyFlag = flag.Bool("genkey", false,
+		"generate a new KEY and print its public key")
+	keyFlag = flag.String("key", "", "base64 encoded ed25519 private KEY")
+	oFlag   = flag.String("o", ".", "output DIR of MANIFEST and MANIFEST.sig")
+)
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if err := run(flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprint(os.Stderr, `usage:	goes-sign -genkey -key KEY
+	goes-sign -key KEY [-o DIR] IMAGE...
+
+Write a MANIFEST of the IMAGE SHA-256 hashes and its ed25519 signature,
+MANIFEST.sig.  Add the public key to the machine's releaseKeys, or embed
+it in goes with:
+
+	-ldflags "-X github.com/platinasystems/go/internal/sign.Key=PUBLIC"
+
+`)
+	flag.PrintDefaults()
+}
+
+func run(args []string) error {
+	if len(*keyFlag) == 0 {
+		return fmt.Errorf("missing -key")
+	}
+	if *genkeyFlag {
+		return genkey(*keyFlag)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("missing IMAGE")
+	}
+	b, err := ioutil.ReadFile(*keyFlag)
+	if err != nil {
+		return err
+	}
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(
+		string(b)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return fmt.Errorf("%s: invalid key", *keyFlag)
+	}
+	key := ed25519.NewKeyFromSeed(seed)
+	m := make(sign.Manifest)
+	for _, fn := range args {
+		if err = m.Add(fn); err != nil {
+			return err
+		}
+	}
+	manifest := m.Bytes()
+	fn := filepath.Join(*oFlag, sign.ManifestName)
+	if err = ioutil.WriteFile(fn, manifest, 0644); err != nil {
+		return err
+	}
+	fn = filepath.Join(*oFlag, sign.SignatureName)
+	return ioutil.WriteFile(fn, sign.Sign(key, manifest), 0644)
+}
+
+func genkey(fn string) error {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	seed := base64.StdEncoding.EncodeToString(priv.Seed())
+	if err = ioutil.WriteFile(fn, []byte(seed+"\n"), 0600); err != nil {
+		return err
+	}
+	fmt.Println(base64.StdEncoding.EncodeToString(pub))
+	return nil
+}
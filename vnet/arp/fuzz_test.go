@@ -0,0 +1,38 @@
+// Copyright 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package arp
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func FuzzHeaderEthernetIp4(f *testing.F) {
+	f.Add([]byte{
+		0, 1, 8, 0, 6, 4, 0, 1,
+		0, 0xa0, 0xc9, 0, 0, 0, 10, 0, 0, 1,
+		0, 0, 0, 0, 0, 0, 10, 0, 0, 2,
+	})
+	f.Fuzz(func(t *testing.T, b []byte) {
+		if len(b) < HeaderEthernetIp4Bytes {
+			return
+		}
+		var h HeaderEthernetIp4
+		if n := h.Len(); n != uint(unsafe.Sizeof(h)) {
+			t.Fatalf("len %d, sizeof %d", n, unsafe.Sizeof(h))
+		}
+		h = *h.Read(b).(*HeaderEthernetIp4)
+		_ = h.String()
+		b2 := make([]byte, h.Len())
+		h.Write(b2)
+		if string(b2) != string(b[:HeaderEthernetIp4Bytes]) {
+			t.Fatalf("%s: wrote %x, want %x", &h, b2,
+				b[:HeaderEthernetIp4Bytes])
+		}
+	})
+}
@@ -0,0 +1,594 @@
+// Copyright 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/platinasystems/go/elib"
+)
+
+// CMIS (Common Management Interface Specification) memory map of QSFP-DD
+// and OSFP modules.  Unlike SFF-8636, the upper 128 bytes are banked pages
+// selected by bytes 126 (bank) and 127 (page); lane monitors and flags are
+// in page 11h for up to 8 lanes per bank.
+const CmisNLane = 8
+
+// Lower page.
+const (
+	CmisId               = 0
+	CmisRevision         = 1
+	CmisMemoryModel      = 2  // [7] flat memory; only page 00h
+	CmisModuleStateReg   = 3  // [3:1] module state
+	CmisModuleFlags      = 9  // temperature and vcc flags
+	CmisTemperature      = 14 // signed 16 bit, units of degrees Celsius/256
+	CmisSupplyVoltage    = 16 // unsigned 16 bit, units of 100e-6 Volts
+	CmisModuleControl    = 26 // see CmisLowPwr*, CmisSoftwareReset
+	CmisMediaType        = 85 // media interface code table
+	CmisApplications     = 86 // 8 x 4 byte application advertisements
+	CmisNApplication     = 8  // in the lower page
+	CmisBankSelect       = 126
+	CmisPageSelect       = 127
+	CmisUpper            = 128 // first byte of the selected page
+	cmisApplicationBytes = 4
+)
+
+// Module global control bits of CmisModuleControl.
+const (
+	CmisSoftwareReset        = 1 << 3
+	CmisLowPwrRequestSW      = 1 << 4
+	CmisLowPwrAllowRequestHW = 1 << 6
+)
+
+// Page 00h offsets.
+const (
+	cmisVendorName    = 129
+	cmisVendorPN      = 148
+	cmisVendorRev     = 164
+	cmisVendorSN      = 166
+	cmisDateCode      = 182
+	cmisConnectorType = 203
+)
+
+// Page 10h and 11h offsets of lanes 1 through 8 in bank 0.
+const (
+	CmisTxDisable      = 130 // page 10h, bit per lane
+	cmisDataPathState  = 128 // 4 bits per lane
+	cmisLaneFlags      = 135 // through 152
+	cmisLaneTxPower    = 154 // unsigned 16 bit, units of 1e-7 Watts
+	cmisLaneTxBias     = 170 // unsigned 16 bit, units of 2e-6 Amps
+	cmisLaneRxPower    = 186
+	cmisThresholdsTemp = 128 // page 02h
+	cmisThresholdsVcc  = 136
+	cmisThresholdsTxP  = 176
+	cmisThresholdsBias = 184
+	cmisThresholdsRxP  = 192
+)
+
+func IsCmis(id Id) bool { return id == IdQsfpDD || id == IdOsfp }
+
+// CmisMemory is a copy of a module's lower page and the pages of bank 0
+// that we decode.  Pages are indexed by their offset less 128.
+type CmisMemory struct {
+	Lower  [128]byte
+	Page00 [128]byte
+	Page01 [128]byte
+	Page02 [128]byte
+	Page10 [128]byte
+	Page11 [128]byte
+}
+
+// CmisReadWriter accesses the module's 256 byte window, as the Access
+// method SfpReadWrite.
+type CmisReadWriter func(offset uint, p []uint8, isWrite bool) (ok bool)
+
+// ReadCmisPage selects and reads an upper page of bank 0.
+func ReadCmisPage(rw CmisReadWriter, page uint8, p *[128]byte) bool {
+	sel := []uint8{0, page}
+	if !rw(CmisBankSelect, sel, true) {
+		return false
+	}
+	return rw(CmisUpper, p[:], false)
+}
+
+// ReadCmis reads the lower page and, unless the module has flat memory,
+// the status, advertising, threshold, and lane pages.  It leaves page 00h
+// selected.
+func ReadCmis(rw CmisReadWriter) (*CmisMemory, bool) {
+	m := new(CmisMemory)
+	if !rw(0, m.Lower[:], false) {
+		return nil, false
+	}
+	if !m.Flat() {
+		for _, x := range []struct {
+			page uint8
+			p    *[128]byte
+		}{
+			{0x01, &m.Page01},
+			{0x02, &m.Page02},
+			{0x10, &m.Page10},
+			{0x11, &m.Page11},
+		} {
+			if !ReadCmisPage(rw, x.page, x.p) {
+				return nil, false
+			}
+		}
+	}
+	if !ReadCmisPage(rw, 0, &m.Page00) {
+		return nil, false
+	}
+	return m, true
+}
+
+// ReadCmisDynamic refreshes the lower page and lane page 11h monitors and
+// flags.
+func (m *CmisMemory) ReadCmisDynamic(rw CmisReadWriter) bool {
+	if !rw(0, m.Lower[:CmisModuleControl+1], false) {
+		return false
+	}
+	if m.Flat() {
+		return true
+	}
+	if !ReadCmisPage(rw, 0x11, &m.Page11) {
+		return false
+	}
+	return ReadCmisPage(rw, 0, &m.Page00)
+}
+
+// CmisModuleControlValue returns the module control byte to request,
+// or release, low power mode by software rather than the LPMode signal.
+func CmisModuleControlValue(was byte, lowPower bool) byte {
+	v := was &^ (CmisLowPwrAllowRequestHW | CmisSoftwareReset)
+	if lowPower {
+		v |= CmisLowPwrRequestSW
+	} else {
+		v &^= CmisLowPwrRequestSW
+	}
+	return v
+}
+
+func upper(p *[128]byte, offset int) []byte { return p[offset-CmisUpper:] }
+
+func be16(b []byte) uint16 { return uint16(b[0])<<8 | uint16(b[1]) }
+
+func ascii(b []byte) string {
+	if i := strings.IndexByte(string(b), 0); i >= 0 {
+		b = b[:i]
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func (m *CmisMemory) Id() Id     { return Id(m.Lower[CmisId]) }
+func (m *CmisMemory) Flat() bool { return m.Lower[CmisMemoryModel]&(1<<7) != 0 }
+
+func (m *CmisMemory) Revision() string {
+	b := m.Lower[CmisRevision]
+	return fmt.Sprint(b>>4, ".", b&0xf)
+}
+
+type CmisModuleState byte
+
+const (
+	_ CmisModuleState = iota
+	CmisModuleLowPwr
+	CmisModulePwrUp
+	CmisModuleReady
+	CmisModulePwrDn
+	CmisModuleFault
+)
+
+func (i CmisModuleState) String() string {
+	var t = [...]string{
+		CmisModuleLowPwr: "LowPwr",
+		CmisModulePwrUp:  "PwrUp",
+		CmisModuleReady:  "Ready",
+		CmisModulePwrDn:  "PwrDn",
+		CmisModuleFault:  "Fault",
+	}
+	return elib.Stringer(t[:], int(i))
+}
+
+func (m *CmisMemory) ModuleState() CmisModuleState {
+	return CmisModuleState((m.Lower[CmisModuleStateReg] >> 1) & 7)
+}
+
+// TxDisabled returns the lanes with disabled output.
+func (m *CmisMemory) TxDisabled() byte {
+	return upper(&m.Page10, CmisTxDisable)[0]
+}
+
+func (m *CmisMemory) LowPower() bool {
+	return m.Lower[CmisModuleControl]&CmisLowPwrRequestSW != 0
+}
+
+type CmisDataPathState byte
+
+func (i CmisDataPathState) String() string {
+	var t = [...]string{
+		1: "Deactivated",
+		2: "Init",
+		3: "Deinit",
+		4: "Activated",
+		5: "TxTurnOn",
+		6: "TxTurnOff",
+		7: "Initialized",
+	}
+	return elib.Stringer(t[:], int(i))
+}
+
+// DataPathStates of the first n lanes.
+func (m *CmisMemory) DataPathStates(n int) []CmisDataPathState {
+	s := make([]CmisDataPathState, n)
+	b := upper(&m.Page11, cmisDataPathState)
+	for i := range s {
+		s[i] = CmisDataPathState((b[i/2] >> (4 * uint(i%2))) & 0xf)
+	}
+	return s
+}
+
+// CmisMediaTypes of CmisMediaType.
+const (
+	CmisMediaUndefined = iota
+	CmisMediaMMF
+	CmisMediaSMF
+	CmisMediaPassiveCopper
+	CmisMediaActiveCable
+	CmisMediaBaseT
+)
+
+// A CmisApplication advertises a host and media interface pair with their
+// lane counts.
+type CmisApplication struct {
+	Host, Media           byte
+	MediaType             byte
+	HostLanes, MediaLanes int
+	// Bit mask of the first host lane of each instance.
+	HostLaneOptions byte
+}
+
+func (a CmisApplication) HostName() string {
+	if int(a.Host) < len(cmisHostInterfaces) {
+		if h := cmisHostInterfaces[a.Host]; len(h.name) > 0 {
+			return h.name
+		}
+	}
+	return fmt.Sprintf("host 0x%02x", a.Host)
+}
+
+func (a CmisApplication) MediaName() string {
+	var t []string
+	switch a.MediaType {
+	case CmisMediaMMF:
+		t = cmisMMFInterfaces[:]
+	case CmisMediaSMF:
+		t = cmisSMFInterfaces[:]
+	case CmisMediaPassiveCopper:
+		// Passive copper has the host interface rate by lanes.
+		if gbps := a.HostGbps(); gbps > 0 {
+			return fmt.Sprint(gbps, "GBASE-CR", a.HostLanes)
+		}
+		return "copper cable"
+	}
+	if int(a.Media) < len(t) && len(t[a.Media]) > 0 {
+		return t[a.Media]
+	}
+	return fmt.Sprintf("media 0x%02x", a.Media)
+}
+
+// HostGbps is the rate of all host lanes, or 0 if unknown.
+func (a CmisApplication) HostGbps() int {
+	if int(a.Host) < len(cmisHostInterfaces) {
+		return cmisHostInterfaces[a.Host].gbps
+	}
+	return 0
+}
+
+func (a CmisApplication) String() string {
+	return fmt.Sprint(a.HostName(), ", ", a.MediaName(),
+		", host lanes ", a.HostLanes, ", media lanes ", a.MediaLanes)
+}
+
+// Applications advertised in the lower page; those of page 01h, 9 through
+// 15, are optional and unsupported.
+func (m *CmisMemory) Applications() []CmisApplication {
+	var as []CmisApplication
+	for i := 0; i < CmisNApplication; i++ {
+		b := m.Lower[CmisApplications+i*cmisApplicationBytes:]
+		if b[0] == 0xff || b[0] == 0 {
+			break
+		}
+		as = append(as, CmisApplication{
+			Host:            b[0],
+			Media:           b[1],
+			MediaType:       m.Lower[CmisMediaType],
+			HostLanes:       int(b[2] >> 4),
+			MediaLanes:      int(b[2] & 0xf),
+			HostLaneOptions: b[3],
+		})
+	}
+	return as
+}
+
+// Lanes monitored, those of the first application's media or CmisNLane.
+func (m *CmisMemory) Lanes() int {
+	if as := m.Applications(); len(as) > 0 {
+		n := as[0].MediaLanes
+		if m.Lower[CmisMediaType] == CmisMediaPassiveCopper {
+			n = as[0].HostLanes
+		}
+		if n > 0 && n <= CmisNLane {
+			return n
+		}
+	}
+	return CmisNLane
+}
+
+// Compliance is the first application's media interface, e.g.
+// 400GBASE-DR4 or, with passive copper, 400GBASE-CR8.
+func (m *CmisMemory) Compliance() string {
+	if as := m.Applications(); len(as) > 0 {
+		return as[0].MediaName()
+	}
+	return ""
+}
+
+func (m *CmisMemory) Ident() QsfpIdFields {
+	p := &m.Page00
+	return QsfpIdFields{
+		Id:            m.Id().String(),
+		Vendor:        ascii(upper(p, cmisVendorName)[:16]),
+		PartNumber:    ascii(upper(p, cmisVendorPN)[:16]),
+		Revision:      ascii(upper(p, cmisVendorRev)[:2]),
+		SerialNumber:  ascii(upper(p, cmisVendorSN)[:16]),
+		Date:          ascii(upper(p, cmisDateCode)[:8]),
+		ConnectorType: ConnectorType(upper(p, cmisConnectorType)[0]).String(),
+		Compliance:    m.Compliance(),
+	}
+}
+
+func (t *QsfpThreshold) cmis(b []byte, unit float64, castInt16 bool) {
+	v := func(i int) float64 {
+		u := be16(b[2*i:])
+		if castInt16 {
+			return float64(int16(u)) * unit
+		}
+		return float64(u) * unit
+	}
+	t.Alarm.Hi, t.Alarm.Lo = v(0), v(1)
+	t.Warning.Hi, t.Warning.Lo = v(2), v(3)
+}
+
+// Thresholds of page 02h.
+func (m *CmisMemory) Thresholds() (c QsfpModuleConfig) {
+	p := &m.Page02
+	c.TemperatureInCelsius.cmis(upper(p, cmisThresholdsTemp),
+		TemperatureToCelsius, true)
+	c.SupplyVoltageInVolts.cmis(upper(p, cmisThresholdsVcc),
+		SupplyVoltageToVolts, false)
+	c.TxPowerInWatts.cmis(upper(p, cmisThresholdsTxP), TxPowerToWatts,
+		false)
+	c.TxBiasCurrentInAmps.cmis(upper(p, cmisThresholdsBias),
+		TxBiasCurrentToAmps, false)
+	c.RxPowerInWatts.cmis(upper(p, cmisThresholdsRxP), RxPowerToWatts,
+		false)
+	return
+}
+
+func format(f float64) string { return strconv.FormatFloat(f, 'f', 3, 64) }
+
+func (m *CmisMemory) Monitoring() (mon QsfpMonitoring) {
+	mon.Temperature = format(float64(int16(be16(m.Lower[CmisTemperature:]))) *
+		TemperatureToCelsius)
+	mon.Voltage = format(float64(be16(m.Lower[CmisSupplyVoltage:])) *
+		SupplyVoltageToVolts)
+	n := m.Lanes()
+	mon.RxPower = make([]string, n)
+	mon.TxPower = make([]string, n)
+	mon.TxBias = make([]string, n)
+	p := &m.Page11
+	for i := 0; i < n; i++ {
+		mon.TxPower[i] = format(float64(be16(upper(p, cmisLaneTxPower+2*i))) *
+			TxPowerToWatts)
+		mon.TxBias[i] = format(float64(be16(upper(p, cmisLaneTxBias+2*i))) *
+			TxBiasCurrentToAmps)
+		mon.RxPower[i] = format(float64(be16(upper(p, cmisLaneRxPower+2*i))) *
+			RxPowerToWatts)
+	}
+	return
+}
+
+// CmisLaneFlags are the latched page 11h flags with a bit per lane.  The
+// threshold flags are high alarm, low alarm, high warning, and low warning.
+type CmisLaneFlags struct {
+	TxFault, TxLos, TxCdrLol, TxAdaptEqFault byte
+	TxPower, TxBias                          [4]byte
+	RxLos, RxCdrLol                          byte
+	RxPower                                  [4]byte
+}
+
+func (m *CmisMemory) LaneFlags() (f CmisLaneFlags) {
+	b := upper(&m.Page11, cmisLaneFlags)
+	f.TxFault, f.TxLos, f.TxCdrLol, f.TxAdaptEqFault = b[0], b[1], b[2], b[3]
+	copy(f.TxPower[:], b[4:8])
+	copy(f.TxBias[:], b[8:12])
+	f.RxLos, f.RxCdrLol = b[12], b[13]
+	copy(f.RxPower[:], b[14:18])
+	return
+}
+
+var cmisThresholdFlags = [4]string{
+	"High Alarm", "Low Alarm", "High Warning", "Low Warning",
+}
+
+// LaneAlarms returns the names of the flags of each lane, as "L-Rx1 LOS",
+// without the lane, as "LOS".
+func (f *CmisLaneFlags) LaneAlarms(lane int, withLane bool) (rx, tx []string) {
+	bit := byte(1) << uint(lane)
+	name := func(dir, s string) string {
+		if withLane {
+			return fmt.Sprint("L-", dir, lane+1, " ", s)
+		}
+		return strings.Replace(s, " ", "", -1)
+	}
+	for _, x := range []struct {
+		flags byte
+		s     string
+	}{
+		{f.RxLos, "LOS"},
+		{f.RxCdrLol, "LOL"},
+	} {
+		if x.flags&bit != 0 {
+			rx = append(rx, name("Rx", x.s))
+		}
+	}
+	for i, s := range cmisThresholdFlags {
+		if f.RxPower[i]&bit != 0 {
+			rx = append(rx, name("Rx", "Power "+s))
+		}
+	}
+	for _, x := range []struct {
+		flags byte
+		s     string
+	}{
+		{f.TxLos, "LOS"},
+		{f.TxFault, "Fault"},
+		{f.TxAdaptEqFault, "Adapt EQ Fault"},
+		{f.TxCdrLol, "LOL"},
+	} {
+		if x.flags&bit != 0 {
+			tx = append(tx, name("Tx", x.s))
+		}
+	}
+	for i, s := range cmisThresholdFlags {
+		if f.TxBias[i]&bit != 0 {
+			tx = append(tx, name("Tx", "Bias "+s))
+		}
+		if f.TxPower[i]&bit != 0 {
+			tx = append(tx, name("Tx", "Power "+s))
+		}
+	}
+	return
+}
+
+// ModuleAlarms returns the names of the temperature and vcc flags.
+func (m *CmisMemory) ModuleAlarms() []string {
+	var alarms []string
+	b := m.Lower[CmisModuleFlags]
+	for i, s := range cmisThresholdFlags {
+		if b&(1<<uint(i)) != 0 {
+			alarms = append(alarms, "L-Temp "+s)
+		}
+	}
+	for i, s := range cmisThresholdFlags {
+		if b&(1<<uint(i+4)) != 0 {
+			alarms = append(alarms, "L-Vcc "+s)
+		}
+	}
+	return alarms
+}
+
+func (m *CmisMemory) Alarms() (a QsfpAlarms) {
+	a.Module = strings.Join(m.ModuleAlarms(), ",")
+	var channels []string
+	f := m.LaneFlags()
+	for i := 0; i < m.Lanes(); i++ {
+		rx, tx := f.LaneAlarms(i, true)
+		channels = append(channels, rx...)
+		channels = append(channels, tx...)
+	}
+	a.Channels = strings.Join(channels, ",")
+	return
+}
+
+// SFF-8024 host electrical interface codes.
+var cmisHostInterfaces = [...]struct {
+	name string
+	gbps int
+}{
+	0x01: {"1000BASE-CX", 1},
+	0x02: {"XAUI", 10},
+	0x03: {"XFI", 10},
+	0x04: {"SFI", 10},
+	0x05: {"25GAUI C2M", 25},
+	0x06: {"XLAUI C2M", 40},
+	0x07: {"XLPPI", 40},
+	0x08: {"LAUI-2 C2M", 50},
+	0x09: {"50GAUI-2 C2M", 50},
+	0x0a: {"50GAUI-1 C2M", 50},
+	0x0b: {"CAUI-4 C2M", 100},
+	0x0c: {"100GAUI-4 C2M", 100},
+	0x0d: {"100GAUI-2 C2M", 100},
+	0x0e: {"200GAUI-8 C2M", 200},
+	0x0f: {"200GAUI-4 C2M", 200},
+	0x10: {"400GAUI-16 C2M", 400},
+	0x11: {"400GAUI-8 C2M", 400},
+	0x13: {"10GBASE-CX4", 10},
+	0x14: {"25GBASE-CR CA-L", 25},
+	0x15: {"25GBASE-CR CA-S", 25},
+	0x16: {"25GBASE-CR CA-N", 25},
+	0x17: {"40GBASE-CR4", 40},
+	0x18: {"50GBASE-CR", 50},
+	0x1a: {"100GBASE-CR4", 100},
+	0x1b: {"100GBASE-CR2", 100},
+	0x1c: {"200GBASE-CR4", 200},
+	0x1d: {"400G CR8", 400},
+}
+
+// SFF-8024 multimode fiber media interface codes.
+var cmisMMFInterfaces = [...]string{
+	0x01: "10GBASE-SW",
+	0x02: "10GBASE-SR",
+	0x03: "25GBASE-SR",
+	0x04: "40GBASE-SR4",
+	0x05: "40GE SWDM4",
+	0x06: "40GE BiDi",
+	0x07: "50GBASE-SR",
+	0x08: "100GBASE-SR10",
+	0x09: "100GBASE-SR4",
+	0x0a: "100GE SWDM4",
+	0x0b: "100GE BiDi",
+	0x0c: "100GBASE-SR2",
+	0x0d: "100G-SR",
+	0x0e: "200GBASE-SR4",
+	0x0f: "400GBASE-SR16",
+	0x10: "400GBASE-SR8",
+	0x11: "400G-SR4",
+}
+
+// SFF-8024 single mode fiber media interface codes.
+var cmisSMFInterfaces = [...]string{
+	0x01: "10GBASE-LW",
+	0x02: "10GBASE-EW",
+	0x03: "10G-ZW",
+	0x04: "10GBASE-LR",
+	0x05: "10GBASE-ER",
+	0x06: "10G-ZR",
+	0x07: "25GBASE-LR",
+	0x08: "25GBASE-ER",
+	0x09: "40GBASE-LR4",
+	0x0a: "40GBASE-FR",
+	0x0b: "50GBASE-FR",
+	0x0c: "50GBASE-LR",
+	0x0d: "100GBASE-LR4",
+	0x0e: "100GBASE-ER4",
+	0x0f: "100G PSM4",
+	0x10: "100G CWDM4",
+	0x11: "100G 4WDM-10",
+	0x12: "100G 4WDM-20",
+	0x13: "100G 4WDM-40",
+	0x14: "100GBASE-DR",
+	0x15: "100G-FR",
+	0x16: "100G-LR",
+	0x17: "200GBASE-DR4",
+	0x18: "200GBASE-FR4",
+	0x19: "200GBASE-LR4",
+	0x1a: "400GBASE-FR8",
+	0x1b: "400GBASE-LR8",
+	0x1c: "400GBASE-DR4",
+	0x1d: "400G-FR4",
+	0x1e: "400G-LR4-10",
+}
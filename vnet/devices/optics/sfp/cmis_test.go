@@ -0,0 +1,160 @@
+// Copyright 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfp
+
+import (
+	"reflect"
+	"testing"
+)
+
+// cmisModule is a banked memory map behind a CmisReadWriter.
+type cmisModule struct {
+	lower [128]byte
+	pages map[uint8]*[128]byte
+}
+
+func (c *cmisModule) rw(offset uint, p []uint8, isWrite bool) bool {
+	for i := range p {
+		o := offset + uint(i)
+		b := &c.lower[o&0x7f]
+		if o >= CmisUpper {
+			pg := c.pages[c.lower[CmisPageSelect]]
+			if pg == nil {
+				return false
+			}
+			b = &pg[o-CmisUpper]
+		}
+		if isWrite {
+			*b = p[i]
+		} else {
+			p[i] = *b
+		}
+	}
+	return true
+}
+
+func put16(b []byte, v uint16) { b[0], b[1] = byte(v>>8), byte(v) }
+
+func newCmisModule() *cmisModule {
+	c := &cmisModule{pages: make(map[uint8]*[128]byte)}
+	for _, pg := range []uint8{0, 1, 2, 0x10, 0x11} {
+		c.pages[pg] = new([128]byte)
+	}
+	l := c.lower[:]
+	l[CmisId] = byte(IdQsfpDD)
+	l[CmisRevision] = 0x40
+	l[CmisModuleStateReg] = byte(CmisModuleReady) << 1
+	l[CmisModuleFlags] = 1<<2 | 1<<4 // temp high warning, vcc high alarm
+	put16(l[CmisTemperature:], 0x1980)
+	put16(l[CmisSupplyVoltage:], 33000)
+	l[CmisMediaType] = CmisMediaSMF
+	copy(l[CmisApplications:], []byte{
+		0x11, 0x1c, 0x84, 0x01, // 400GAUI-8 400GBASE-DR4
+		0x0d, 0x14, 0x21, 0x55, // 100GAUI-2 100GBASE-DR
+		0xff,
+	})
+	p0 := c.pages[0]
+	copy(p0[cmisVendorName-CmisUpper:], "PLATINA         ")
+	copy(p0[cmisVendorPN-CmisUpper:], "DD-DR4\x00")
+	copy(p0[cmisVendorSN-CmisUpper:], "SN0123          ")
+	p0[cmisConnectorType-CmisUpper] = 0x0c
+	p2 := c.pages[2]
+	for i, v := range []uint16{0x4b00, 0xfb00, 0x4600, 0} {
+		put16(p2[cmisThresholdsTemp-CmisUpper+2*i:], v)
+	}
+	p11 := c.pages[0x11]
+	p11[cmisDataPathState-CmisUpper] = 0x41  // lane 1 deactivated, 2 activated
+	p11[cmisLaneFlags-CmisUpper+12] = 1 << 1 // lane 2 rx LOS
+	p11[cmisLaneFlags-CmisUpper+4] = 1 << 3  // lane 4 tx power high alarm
+	put16(p11[cmisLaneTxPower-CmisUpper+2:], 10000)
+	put16(p11[cmisLaneTxBias-CmisUpper+2:], 3000)
+	put16(p11[cmisLaneRxPower-CmisUpper+6:], 5000)
+	return c
+}
+
+func TestCmisDecode(t *testing.T) {
+	c := newCmisModule()
+	m, ok := ReadCmis(c.rw)
+	if !ok {
+		t.Fatal("read failed")
+	}
+	if c.lower[CmisPageSelect] != 0 {
+		t.Error("page", c.lower[CmisPageSelect], "left selected")
+	}
+	if !IsCmis(m.Id()) || m.Revision() != "4.0" ||
+		m.ModuleState() != CmisModuleReady {
+		t.Error(m.Id(), m.Revision(), m.ModuleState())
+	}
+	id := m.Ident()
+	if id.Vendor != "PLATINA" || id.PartNumber != "DD-DR4" ||
+		id.SerialNumber != "SN0123" || id.Compliance != "400GBASE-DR4" ||
+		id.Id != "QSFP-DD" {
+		t.Errorf("%+v", id)
+	}
+	as := m.Applications()
+	if len(as) != 2 || as[1].String() !=
+		"100GAUI-2 C2M, 100GBASE-DR, host lanes 2, media lanes 1" {
+		t.Errorf("%v", as)
+	}
+	if n := m.Lanes(); n != 4 {
+		t.Error("lanes", n)
+	}
+	mon := m.Monitoring()
+	if mon.Temperature != "25.500" || mon.Voltage != "3.300" ||
+		len(mon.TxPower) != 4 || mon.TxPower[1] != "1.000" ||
+		mon.TxBias[1] != "6.000" || mon.RxPower[3] != "0.500" {
+		t.Errorf("%+v", mon)
+	}
+	th := m.Thresholds().TemperatureInCelsius
+	if th.Alarm.Hi != 75 || th.Alarm.Lo != -5 || th.Warning.Hi != 70 {
+		t.Errorf("%+v", th)
+	}
+	a := m.Alarms()
+	if a.Module != "L-Temp High Warning,L-Vcc High Alarm" ||
+		a.Channels != "L-Rx2 LOS,L-Tx4 Power High Alarm" {
+		t.Errorf("%+v", a)
+	}
+	f := m.LaneFlags()
+	if rx, _ := f.LaneAlarms(1, false); !reflect.DeepEqual(rx,
+		[]string{"LOS"}) {
+		t.Error(rx)
+	}
+	if s := m.DataPathStates(2); s[0].String() != "Deactivated" ||
+		s[1].String() != "Activated" {
+		t.Error(s)
+	}
+}
+
+func TestCmisCopper(t *testing.T) {
+	c := newCmisModule()
+	c.lower[CmisMediaType] = CmisMediaPassiveCopper
+	c.lower[CmisApplications+1] = 0x01
+	m, _ := ReadCmis(c.rw)
+	if s := m.Compliance(); s != "400GBASE-CR8" {
+		t.Error(s)
+	}
+	if n := m.Lanes(); n != 8 {
+		t.Error("lanes", n)
+	}
+}
+
+func TestCmisFlat(t *testing.T) {
+	c := newCmisModule()
+	c.lower[CmisMemoryModel] = 1 << 7
+	delete(c.pages, 0x11)
+	if _, ok := ReadCmis(c.rw); !ok {
+		t.Error("read of flat memory")
+	}
+}
+
+func TestCmisModuleControl(t *testing.T) {
+	was := byte(CmisLowPwrAllowRequestHW | 1<<7)
+	if v := CmisModuleControlValue(was, true); v != CmisLowPwrRequestSW|1<<7 {
+		t.Errorf("%#x", v)
+	}
+	if v := CmisModuleControlValue(CmisLowPwrRequestSW, false); v != 0 {
+		t.Errorf("%#x", v)
+	}
+}
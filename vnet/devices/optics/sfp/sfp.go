@@ -27,12 +27,13 @@ type QsfpModuleConfig struct {
 	TxPowerInWatts       QsfpThreshold
 }
 
+// Lane monitors have QsfpNChannel, or with CMIS up to CmisNLane, entries.
 type QsfpMonitoring struct {
 	Temperature string
 	Voltage     string
-	RxPower     [QsfpNChannel]string
-	TxPower     [QsfpNChannel]string
-	TxBias      [QsfpNChannel]string
+	RxPower     []string
+	TxPower     []string
+	TxBias      []string
 }
 
 type QsfpAlarms struct {
@@ -81,6 +82,9 @@ type QsfpModule struct {
 
 	Alarms QsfpAlarms
 
+	// Non-nil with QSFP-DD and OSFP modules.
+	Cmis *CmisMemory
+
 	s state
 	a Access
 }
@@ -172,6 +176,10 @@ func (m *QsfpModule) Present(is bool) {
 	if !is {
 		m.invalidateCache()
 	} else {
+		if IsCmis(Id((*reg8)(&r.id).get(m))) {
+			m.presentCmis()
+			return
+		}
 		// Wait for module to become ready.
 		start := time.Now()
 		status := r.status.get(m)
@@ -198,13 +206,38 @@ func (m *QsfpModule) Present(is bool) {
 	}
 }
 
+// Read the whole CMIS memory since there's no dataplane subset.
+func (m *QsfpModule) presentCmis() {
+	c, ok := ReadCmis(m.a.SfpReadWrite)
+	if !ok {
+		log.Print("qsfp cmis read failed")
+		return
+	}
+	m.Cmis = c
+	m.e.Id = c.Id()
+	m.Ident = c.Ident()
+	m.Config = c.Thresholds()
+	m.eepromDataplaneSubsetValid = true
+	m.AllEepromValid = true
+}
+
 func (m *QsfpModule) Monitoring() {
+	if m.Cmis != nil {
+		if m.Cmis.ReadCmisDynamic(m.a.SfpReadWrite) {
+			m.Mon = m.Cmis.Monitoring()
+			m.Alarms = m.Cmis.Alarms()
+		}
+		return
+	}
 	r := getQsfpRegs()
 	if r.upperMemoryMapPageSelect.get(m) != 0 {
 		r.upperMemoryMapPageSelect.set(m, 0)
 	}
 	m.Mon.Temperature = strconv.FormatFloat(float64(r.internallyMeasured.temperature.get(m))*TemperatureToCelsius, 'f', 3, 64)
 	m.Mon.Voltage = strconv.FormatFloat(float64(r.internallyMeasured.supplyVoltage.get(m))*SupplyVoltageToVolts, 'f', 3, 64)
+	m.Mon.RxPower = make([]string, QsfpNChannel)
+	m.Mon.TxPower = make([]string, QsfpNChannel)
+	m.Mon.TxBias = make([]string, QsfpNChannel)
 	for i := 0; i < QsfpNChannel; i++ {
 		m.Mon.RxPower[i] = strconv.FormatFloat(float64(r.internallyMeasured.rxPower[i].get(m))*RxPowerToWatts, 'f', 3, 64)
 		m.Mon.TxPower[i] = strconv.FormatFloat(float64(r.internallyMeasured.txPower[i].get(m))*TxPowerToWatts, 'f', 3, 64)
@@ -306,9 +339,13 @@ func (m *QsfpModule) validateCache(everything bool) {
 func (m *QsfpModule) invalidateCache() {
 	m.AllEepromValid = false
 	m.eepromDataplaneSubsetValid = false
+	m.Cmis = nil
 }
 
 func (m *QsfpModule) TxEnable(enableMask, laneMask uint) uint {
+	if m.Cmis != nil {
+		return m.cmisTxEnable(enableMask, laneMask)
+	}
 	r := getQsfpRegs()
 	was := m.s.txDisable
 	disableMask := byte(^enableMask)
@@ -320,6 +357,57 @@ func (m *QsfpModule) TxEnable(enableMask, laneMask uint) uint {
 	return uint(was)
 }
 
+// CMIS output disable is in page 10h for up to 8 lanes.
+func (m *QsfpModule) cmisTxEnable(enableMask, laneMask uint) uint {
+	was := m.Cmis.TxDisabled()
+	is := (was &^ byte(laneMask)) | byte(^enableMask&laneMask)
+	if is != was {
+		b := []uint8{is}
+		if ReadCmisPage(m.a.SfpReadWrite, 0x10, &m.Cmis.Page10) &&
+			m.a.SfpReadWrite(CmisTxDisable, b, true) {
+			m.Cmis.Page10[CmisTxDisable-CmisUpper] = is
+		}
+		ReadCmisPage(m.a.SfpReadWrite, 0, &m.Cmis.Page00)
+	}
+	return uint(was)
+}
+
+// CmisSetLowPower requests, or releases, low power mode of a CMIS module by
+// software rather than its LPMode signal.
+func (m *QsfpModule) CmisSetLowPower(enable bool) bool {
+	if m.Cmis == nil {
+		return false
+	}
+	b := []uint8{0}
+	if !m.a.SfpReadWrite(CmisModuleControl, b, false) {
+		return false
+	}
+	b[0] = CmisModuleControlValue(b[0], enable)
+	if !m.a.SfpReadWrite(CmisModuleControl, b, true) {
+		return false
+	}
+	m.Cmis.Lower[CmisModuleControl] = b[0]
+	return true
+}
+
+// CmisReset resets a CMIS module, which then reads as removed until it's
+// present again.
+func (m *QsfpModule) CmisReset() bool {
+	if m.Cmis == nil {
+		return false
+	}
+	b := []uint8{0}
+	if !m.a.SfpReadWrite(CmisModuleControl, b, false) {
+		return false
+	}
+	b[0] |= CmisSoftwareReset
+	if !m.a.SfpReadWrite(CmisModuleControl, b, true) {
+		return false
+	}
+	m.invalidateCache()
+	return true
+}
+
 func (m *QsfpModule) GetId() Id                       { return m.e.Id }
 func (m *QsfpModule) GetConnectorType() ConnectorType { return m.e.ConnectorType }
 func (m *QsfpModule) GetCompliance() (c Compliance, x ExtendedCompliance) {
@@ -344,6 +432,11 @@ func trim(r []reg8) string {
 }
 
 func (m *QsfpModule) String() string {
+	if c := m.Cmis; c != nil {
+		return fmt.Sprintf("Id: %s, CMIS %s, Compliance: %s, Vendor: %s, Part Number %s, Revision %s, Serial %s, Date %s, Connector Type: %s, State: %v",
+			m.Ident.Id, c.Revision(), m.Ident.Compliance, m.Ident.Vendor, m.Ident.PartNumber, m.Ident.Revision,
+			m.Ident.SerialNumber, m.Ident.Date, m.Ident.ConnectorType, c.ModuleState())
+	}
 	m.validateCache(true)
 	e := &m.e
 	var s string
@@ -37,6 +37,7 @@ const (
 	IdCdfpStyle3
 	IdMicroQsfp
 	IdQsfpDD
+	IdOsfp
 	// - 0x7F: Reserved
 	// 0x80 - 0xFF: Vendor Specific
 )
@@ -68,6 +69,7 @@ func (i Id) String() string {
 		0x16: "CDFP (Style 3)",
 		0x17: "Micro QSFP",
 		0x18: "QSFP-DD",
+		0x19: "OSFP",
 	}
 	return elib.Stringer(t[:], int(i))
 }
@@ -386,3 +388,31 @@ var DynamicMonitoringRedisFields = []string{
 	"qsfp.tx4.bias.units.mA",
 	"qsfp.tx4.power.units.mW",
 }
+
+// CmisRedisFields are those of CMIS modules in addition to the above.
+var CmisRedisFields = []string{
+	"qsfp.revision",
+	"qsfp.moduleState",
+	"qsfp.lowPower",
+	"qsfp.dataPathState",
+	"qsfp.application1",
+	"qsfp.application2",
+	"qsfp.application3",
+	"qsfp.application4",
+	"qsfp.application5",
+	"qsfp.application6",
+	"qsfp.application7",
+	"qsfp.application8",
+	"qsfp.rx5.power.units.mW",
+	"qsfp.rx6.power.units.mW",
+	"qsfp.rx7.power.units.mW",
+	"qsfp.rx8.power.units.mW",
+	"qsfp.tx5.bias.units.mA",
+	"qsfp.tx5.power.units.mW",
+	"qsfp.tx6.bias.units.mA",
+	"qsfp.tx6.power.units.mW",
+	"qsfp.tx7.bias.units.mA",
+	"qsfp.tx7.power.units.mW",
+	"qsfp.tx8.bias.units.mA",
+	"qsfp.tx8.power.units.mW",
+}
@@ -175,28 +175,36 @@ func (ns errNodes) Less(i, j int) bool {
 func (ns errNodes) Swap(i, j int) { ns[i], ns[j] = ns[j], ns[i] }
 func (ns errNodes) Len() int      { return len(ns) }
 
-func (v *Vnet) showErrors(c cli.Commander, w cli.Writer, in *cli.Input) (err error) {
+// ForeachErrorCounter calls f with the count, since last clear, of each
+// node error; zero counts are skipped unless zero is true.
+func (v *Vnet) ForeachErrorCounter(zero bool, f func(node, name string, value uint64)) {
 	en := ErrorNode
-	ns := []errNode{}
 	for i := range en.errs {
 		e := &en.errs[i]
 		c := uint64(0)
 		for _, t := range en.threads {
 			if t != nil {
-				c += t.counts[i]
+				c += atomic.LoadUint64(&t.counts[i])
 				if i < len(t.countsLastClear) {
 					c -= t.countsLastClear[i]
 				}
 			}
 		}
-		if c > 0 {
-			ns = append(ns, errNode{
-				Node:  e.nodeName,
-				Error: e.str,
-				Count: c,
-			})
+		if c > 0 || zero {
+			f(e.nodeName, e.str, c)
 		}
 	}
+}
+
+func (v *Vnet) showErrors(c cli.Commander, w cli.Writer, in *cli.Input) (err error) {
+	ns := []errNode{}
+	v.ForeachErrorCounter(false, func(node, name string, value uint64) {
+		ns = append(ns, errNode{
+			Node:  node,
+			Error: name,
+			Count: value,
+		})
+	})
 	if len(ns) > 1 {
 		sort.Sort(errNodes(ns))
 	}
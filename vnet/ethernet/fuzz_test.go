@@ -0,0 +1,91 @@
+// Copyright 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package ethernet
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/platinasystems/go/elib/parse"
+)
+
+// Parse all of s.  Parsers panic with errors, as the cli expects, but not
+// runtime errors.
+func parseAll(p parse.Parser, s string) (ok bool) {
+	defer func() {
+		if e := recover(); e != nil {
+			_, isErr := e.(error)
+			if _, isRuntime := e.(runtime.Error); !isErr || isRuntime {
+				panic(e)
+			}
+			ok = false
+		}
+	}()
+	var in parse.Input
+	in.SetString(s)
+	p.Parse(&in)
+	return in.End()
+}
+
+func FuzzHeaderParse(f *testing.F) {
+	for _, s := range []string{
+		"IP4: 00:a0:c9:00:00:00 -> fe:dc:ba:00:00:00",
+		"IP6: 1.2.3 -> 4.5.6",
+		"0x88cc: ff:ff:ff:ff:ff:ff -> 0.0.1",
+		"ARP:1.2.3->4.5.6",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		var h, h2 Header
+		if !parseAll(&h, s) {
+			return
+		}
+		if !parseAll(&h2, h.String()) {
+			t.Fatalf("%q: can't parse %q", s, h.String())
+		}
+		if h2 != h {
+			t.Fatalf("%q: got %s, want %s", s, &h2, &h)
+		}
+	})
+}
+
+func FuzzVlanHeaderParse(f *testing.F) {
+	for _, s := range []string{
+		"vlan 1",
+		"vlan { 4095 cfi priority 7 tpid 0x88a8 }",
+		"vlan { 10 pri 8 }",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		var h VlanHeader
+		if !parseAll(&h, s) {
+			return
+		}
+		if id := h.Tag.Id(); id > 0xfff {
+			t.Fatalf("%q: id %d", s, id)
+		}
+	})
+}
+
+func FuzzParseHeader(f *testing.F) {
+	f.Add([]byte{
+		1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 0x81, 0x00,
+		0, 1, 0x08, 0x00,
+	})
+	f.Fuzz(func(t *testing.T, b []byte) {
+		if len(b) < SizeofHeader {
+			return
+		}
+		h, vs, _, payload := ParseHeader(b)
+		if n := SizeofHeader + len(vs)*SizeofVlanHeader + len(payload); n != len(b) {
+			t.Fatalf("%s: parsed %d of %d bytes", h, n, len(b))
+		}
+	})
+}
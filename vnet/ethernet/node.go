@@ -19,17 +19,59 @@ type inputNode struct {
 const (
 	input_next_drop = iota
 	input_next_punt
+	input_next_ip4_input
 )
 
 func (m *Main) nodeInit(v *vnet.Vnet) {
 	n := &m.inputNode
 	n.Next = []string{
-		input_next_drop: "error",
-		input_next_punt: "punt",
+		input_next_drop:      "error",
+		input_next_punt:      "punt",
+		input_next_ip4_input: "ip4-input",
 	}
 	v.RegisterInOutNode(n, "ethernet-input")
 }
 
+// Ip4 packets sent to the address of the receive interface, or that of its
+// vlan sub interface, continue w/o ethernet header to ip4-input; all others
+// are punted unchanged.
+func (node *inputNode) input_x1(r0 *vnet.Ref) (next0 uint) {
+	next0 = input_next_punt
+	h0, ok := node.Vnet.HwIferForSupSi(r0.Si).(HwInterfacer)
+	if !ok || r0.DataLen() < SizeofHeader+SizeofVlanHeader {
+		return
+	}
+	e0 := (*Header)(r0.Data())
+	if e0.Dst != h0.GetInterface().Address {
+		return
+	}
+	t0, l0 := e0.Type, SizeofHeader
+	if t0 == TYPE_VLAN.FromHost() {
+		v0 := (*VlanHeader)(r0.DataOffset(SizeofHeader))
+		var id IfId
+		id.Set(vnet.Uint16(v0.Tag.Id()))
+		si0, ok := h0.GetHwIf().SubSi(vnet.IfId(id))
+		if !ok {
+			return
+		}
+		r0.Si = si0
+		t0, l0 = v0.Type, l0+SizeofVlanHeader
+	}
+	if t0 == TYPE_IP4.FromHost() {
+		r0.Advance(l0)
+		next0 = input_next_ip4_input
+	}
+	return
+}
+
 func (node *inputNode) NodeInput(in *vnet.RefIn, out *vnet.RefOut) {
-	node.Redirect(in, out, input_next_punt)
+	q := node.GetEnqueue(in)
+	i, n_left := in.Range()
+	for n_left >= 1 {
+		r0 := in.Get1(i)
+		x0 := node.input_x1(r0)
+		q.Put1(r0, x0)
+		n_left -= 1
+		i += 1
+	}
 }
@@ -92,7 +92,7 @@ func ParseHeader(b []byte) (h *Header, vs []*VlanHeader, payloadType Type, paylo
 	h = (*Header)(unsafe.Pointer(&b[i]))
 	i += SizeofHeader
 	payloadType = h.Type.FromHost()
-	for payloadType.IsVLAN() {
+	for payloadType.IsVLAN() && i+SizeofVlanHeader <= len(b) {
 		v := (*VlanHeader)(unsafe.Pointer(&b[i]))
 		vs = append(vs, v)
 		i += SizeofVlanHeader
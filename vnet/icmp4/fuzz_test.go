@@ -0,0 +1,75 @@
+// Copyright 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package icmp4
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/platinasystems/go/elib/parse"
+)
+
+// Parse all of s.  Parsers panic with errors, as the cli expects, but not
+// runtime errors.
+func parseAll(p parse.Parser, s string) (ok bool) {
+	defer func() {
+		if e := recover(); e != nil {
+			_, isErr := e.(error)
+			if _, isRuntime := e.(runtime.Error); !isErr || isRuntime {
+				panic(e)
+			}
+			ok = false
+		}
+	}()
+	var in parse.Input
+	in.SetString(s)
+	p.Parse(&in)
+	return in.End()
+}
+
+func FuzzHeaderParse(f *testing.F) {
+	for _, s := range []string{
+		"echo-request",
+		"destination-unreachable",
+		"0x99",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		var h, h2 Header
+		if !parseAll(&h, s) {
+			return
+		}
+		s2 := strings.TrimPrefix(h.String(), "ICMP4 ")
+		if !parseAll(&h2, s2) {
+			t.Fatalf("%q: can't parse %q", s, s2)
+		}
+		if h2 != h {
+			t.Fatalf("%q: got %s, want %s", s, &h2, &h)
+		}
+	})
+}
+
+func FuzzHeaderWrite(f *testing.F) {
+	f.Add(uint8(Echo_request), uint8(0), []byte("payload"))
+	f.Fuzz(func(t *testing.T, typ, code uint8, payload []byte) {
+		h := Header{Type: Type(typ), Code: code}
+		b := make([]byte, SizeofHeader+len(payload))
+		copy(b[SizeofHeader:], payload)
+		h.Write(b)
+		h2 := h.Read(b).(*Header)
+		if h2.checksum(b[SizeofHeader:]) != 0 {
+			t.Fatalf("%x: bad checksum", b)
+		}
+		if h2.Type != h.Type || h2.Code != h.Code {
+			t.Fatalf("%x: got %s code %d, want %s code %d", b,
+				h2, h2.Code, &h, h.Code)
+		}
+	})
+}
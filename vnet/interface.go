@@ -290,6 +290,9 @@ func (m *Vnet) NewSwSubInterface(supSi Si, id IfId) (si Si) {
 }
 func (si Si) IsSwSubInterface(v *Vnet) bool { return v.SwIf(si).kind == SwIfKindSubInterface }
 
+// Sub interface of hardware interface with given id (e.g. vlan).
+func (h *HwIf) SubSi(id IfId) (si Si, ok bool) { si, ok = h.subSiById[id]; return }
+
 func (m *interfaceMain) SwIf(i Si) *SwIf { return &m.swInterfaces.elts[i] }
 func (m *interfaceMain) SupSi(i Si) Si   { return m.SwIf(i).supSi }
 func (m *interfaceMain) SupSwIf(s *SwIf) (sup *SwIf) {
@@ -312,7 +312,9 @@ func (f *Fib) addDel(main *Main, p *Prefix, r ip.Adj, isDel bool) (oldAdj ip.Adj
 	if isDel {
 		if p.Len == 0 {
 			m.defaultLeaf = emptyLeaf
-		} else {
+		} else if ok {
+			// Remove the leaves of the deleted route's adjacency.
+			s.result = oldAdj
 			s.unset(m)
 			f.setLessSpecific(p)
 		}
@@ -522,7 +524,7 @@ func (f *Fib) setLessSpecific(pʹ *Prefix) (r mapFibResult, p Prefix, ok bool) {
 			result: r.adj,
 			keyLen: uint8(p.Len),
 		}
-		s.key = p.Address
+		s.key = p.Address.Mask(uint(p.Len))
 		s.set(&f.mtrie)
 	}
 	return
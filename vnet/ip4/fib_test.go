@@ -0,0 +1,117 @@
+// Copyright 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ip4
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/platinasystems/go/vnet/ip"
+)
+
+// Naive longest prefix match reference for a fib.
+type refFib map[Prefix]ip.Adj
+
+func (r refFib) lookup(a *Address) (adj ip.Adj) {
+	adj = ip.AdjMiss
+	best := -1
+	for p, x := range r {
+		if int(p.Len) > best && a.MatchesPrefix(&p) {
+			adj, best = x, int(p.Len)
+		}
+	}
+	return
+}
+
+// Random prefix from a small space of addresses so that prefixes overlap.
+func randPrefix(r *rand.Rand) (p Prefix) {
+	p.Len = uint32(r.Intn(33))
+	p.Address = Address{10, uint8(r.Intn(4)), uint8(r.Intn(4) << 6), uint8(r.Intn(8) << 5)}
+	p.Address = p.Address.Mask(uint(p.Len))
+	return
+}
+
+func randAddress(r *rand.Rand) Address {
+	return Address{10 + uint8(r.Intn(2)), uint8(r.Intn(4)), uint8(r.Intn(256)), uint8(r.Intn(256))}
+}
+
+// Randomly add, replace and delete routes in several fibs and compare the
+// mtrie lookups with those of a naive longest prefix match.  Replacing a
+// route's adjacency is what adding or deleting one of its multipath next hops
+// does.
+func TestFibLookup(t *testing.T) {
+	const (
+		nFib = 3
+		nOp  = 4000
+	)
+	r := rand.New(rand.NewSource(1))
+	var m Main
+	refs := make([]refFib, nFib)
+	for i := range refs {
+		refs[i] = make(refFib)
+	}
+	check := func(op int) {
+		for fi := range refs {
+			for i := 0; i < 64; i++ {
+				a := randAddress(r)
+				got := m.Lookup(&a, ip.FibIndex(fi))
+				if want := refs[fi].lookup(&a); got != want {
+					t.Fatalf("op %d: fib %d: lookup %v: got %v, want %v",
+						op, fi, &a, got, want)
+				}
+			}
+		}
+	}
+	for op := 0; op < nOp; op++ {
+		fi := r.Intn(nFib)
+		f := m.fibByIndex(ip.FibIndex(fi), true)
+		ref := refs[fi]
+		p := randPrefix(r)
+		if _, ok := ref[p]; ok && r.Intn(2) == 0 {
+			f.Del(&m, &p)
+			delete(ref, p)
+		} else {
+			adj := ip.Adj(3 + r.Intn(64))
+			f.Add(&m, &p, adj)
+			ref[p] = adj
+		}
+		if got, ok := f.Get(&p); ok != (ref[p] != 0) || (ok && got != ref[p]) {
+			t.Fatalf("op %d: fib %d: get %v: got %v %v", op, fi, &p, got, ok)
+		}
+		check(op)
+		for fi := range refs {
+			checkPlys(t, &m.fibByIndex(ip.FibIndex(fi), true).mtrie)
+		}
+	}
+	for fi := range refs {
+		f := m.fibByIndex(ip.FibIndex(fi), true)
+		for p := range refs[fi] {
+			f.Del(&m, &p)
+		}
+		if n := f.plyPool.Elts(); n != 1 {
+			t.Errorf("fib %d: %d plys left after deleting all routes", fi, n)
+		}
+	}
+}
+
+// Verify each ply's count of non-empty leaves.
+func checkPlys(t *testing.T, m *mtrie) {
+	t.Helper()
+	for i := uint(0); i < m.plyPool.Len(); i++ {
+		if m.plyPool.IsFree(i) {
+			continue
+		}
+		p := &m.plys[i]
+		n := 0
+		for _, l := range p.leaves {
+			if l != emptyLeaf {
+				n++
+			}
+		}
+		if n != p.nNonEmpty {
+			t.Fatalf("ply %d: %d non-empty leaves, counted %d", i, n, p.nNonEmpty)
+		}
+	}
+}
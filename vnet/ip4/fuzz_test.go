@@ -0,0 +1,99 @@
+// Copyright 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package ip4
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/platinasystems/go/elib/parse"
+)
+
+// Parse all of s.  Parsers panic with errors, as the cli expects, but not
+// runtime errors.
+func parseAll(p parse.Parser, s string) (ok bool) {
+	defer func() {
+		if e := recover(); e != nil {
+			_, isErr := e.(error)
+			if _, isRuntime := e.(runtime.Error); !isErr || isRuntime {
+				panic(e)
+			}
+			ok = false
+		}
+	}()
+	var in parse.Input
+	in.SetString(s)
+	p.Parse(&in)
+	return in.End()
+}
+
+func FuzzHeaderParse(f *testing.F) {
+	for _, s := range []string{
+		"UDP: 1.0.0.0 -> 1.1.1.1",
+		"TCP: 10.0.0.1 -> 10.0.0.2 ttl 1",
+		"ICMP:1.2.3.4->5.6.7.8 ttl 255 ttl 3",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		var h, h2 Header
+		if !parseAll(&h, s) {
+			return
+		}
+		h.Checksum = h.ComputeChecksum()
+		s2 := fmt.Sprintf("%s ttl %d", &h, h.Ttl)
+		if !parseAll(&h2, s2) {
+			t.Fatalf("%q: can't parse %q", s, s2)
+		}
+		h2.Checksum = h2.ComputeChecksum()
+		if h2 != h {
+			t.Fatalf("%q: got %s, want %s", s, &h2, &h)
+		}
+	})
+}
+
+func FuzzPrefixParse(f *testing.F) {
+	for _, s := range []string{
+		"0.0.0.0/0",
+		"10.1.2.0/24",
+		"255.255.255.255/32",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		var p, p2 Prefix
+		if !parseAll(&p, s) {
+			return
+		}
+		if !parseAll(&p2, p.String()) {
+			t.Fatalf("%q: can't parse %q", s, p.String())
+		}
+		if p2 != p {
+			t.Fatalf("%q: got %s, want %s", s, &p2, &p)
+		}
+	})
+}
+
+func FuzzHeaderString(f *testing.F) {
+	f.Add(make([]byte, SizeofHeader))
+	f.Add([]byte{
+		0x45, 0, 0, 20, 0, 0, 0, 0, 64, 17, 0x7a, 0xd4,
+		1, 0, 0, 0, 1, 1, 1, 1,
+	})
+	f.Fuzz(func(t *testing.T, b []byte) {
+		if len(b) < SizeofHeader {
+			return
+		}
+		h, payload := ParseHeader(b)
+		if len(payload) != len(b)-SizeofHeader {
+			t.Fatalf("%x: payload %d bytes", b, len(payload))
+		}
+		_ = h.String()
+	})
+}
@@ -115,6 +115,9 @@ func (m *mtrie) lookup(dst *Address) (a ip.Adj) {
 	for i := range dst {
 		l := p.leaves[dst[i]]
 		if l.isTerminal() {
+			if l == emptyLeaf {
+				l = m.defaultLeaf
+			}
 			a = l.ResultIndex()
 			return
 		}
@@ -130,16 +133,16 @@ func (m *mtrie) setPlyWithMoreSpecificLeaf(p *ply, l leaf, n uint8) {
 		} else if n >= p.lens[i] {
 			p.leaves[i] = l
 			p.lens[i] = n
-			if pl != emptyLeaf {
+			if pl == emptyLeaf {
 				p.nNonEmpty++
 			}
 		}
 	}
 }
 
-func (p *ply) replaceLeaf(new, old leaf, i uint8) {
+func (p *ply) replaceLeaf(new, old leaf, i uint) {
 	p.leaves[i] = new
-	if old != emptyLeaf {
+	if old == emptyLeaf {
 		p.nNonEmpty++
 	}
 }
@@ -158,7 +161,7 @@ func (s *addDelLeaf) setLeafHelper(m *mtrie, oldPlyIndex, keyByteIndex uint) {
 	// Number of bits next plies <= 0 => insert leaves this ply.
 	if nBits <= 0 {
 		nBits = -nBits
-		for i := k; i < k+1<<uint(nBits); i++ {
+		for i := uint(k); i < uint(k)+1<<uint(nBits); i++ {
 			oldLeaf := oldPly.leaves[i]
 			oldTerm := oldLeaf.isTerminal()
 
@@ -202,21 +205,25 @@ func (s *addDelLeaf) setLeafHelper(m *mtrie, oldPlyIndex, keyByteIndex uint) {
 }
 
 func (s *addDelLeaf) unsetLeafHelper(m *mtrie, oldPlyIndex, keyByteIndex uint) (oldPlyWasDeleted bool) {
-	k := s.key[keyByteIndex]
+	k, n := uint(s.key[keyByteIndex]), uint(1)
 	nBits := int(s.keyLen) - 8*int(keyByteIndex+1)
-	if nBits <= 0 {
+	isLast := nBits <= 0
+	if isLast {
 		nBits = -nBits
-		k &^= 1<<uint(nBits) - 1
 		if nBits > 8 {
 			nBits = 8
 		}
+		n = 1 << uint(nBits)
+		k &^= n - 1
 	}
 	delLeaf := setResult(s.result)
 	oldPly := &m.plys[oldPlyIndex]
-	for i := k; i < k+1<<uint(nBits); i++ {
+	for i := k; i < k+n; i++ {
 		oldLeaf := oldPly.leaves[i]
 		oldTerm := oldLeaf.isTerminal()
-		if oldLeaf == delLeaf ||
+		// Only remove leaves of this prefix; more specific prefixes
+		// may have the same result.
+		if (isLast && oldLeaf == delLeaf && oldLeaf != emptyLeaf && oldPly.lens[i] == s.keyLen) ||
 			(!oldTerm && s.unsetLeafHelper(m, oldLeaf.plyIndex(), keyByteIndex+1)) {
 			oldPly.leaves[i] = emptyLeaf
 			oldPly.lens[i] = 0
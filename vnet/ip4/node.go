@@ -6,27 +6,44 @@ package ip4
 
 import (
 	"github.com/platinasystems/go/vnet"
+	"github.com/platinasystems/go/vnet/ip"
 )
 
 func GetHeader(r *vnet.Ref) *Header { return (*Header)(r.Data()) }
 
 type nodeMain struct {
 	inputNode              inputNode
-	inputValidChecksumNode inputValidChecksumNode
-	rewriteNode            inputNode
-	arpNode                inputNode
+	inputValidChecksumNode inputNode
+	rewriteNode            puntNode
+	arpNode                puntNode
 }
 
 func (m *Main) nodeInit(v *vnet.Vnet) {
-	m.inputNode.Next = []string{
+	next := []string{
 		input_next_drop: "error",
 		input_next_punt: "punt",
 	}
+	errors := []string{
+		input_error_none:         "no error",
+		input_error_bad_checksum: "bad checksum",
+		input_error_ttl_expired:  "ttl expired",
+		input_error_drop:         "drop adjacency",
+		input_error_no_route:     "no route",
+	}
+	m.inputNode.m = m
+	m.inputNode.Next = next
+	m.inputNode.Errors = errors
 	v.RegisterInOutNode(&m.inputNode, "ip4-input")
-	m.inputValidChecksumNode.Next = m.inputNode.Next
+	m.inputValidChecksumNode.m = m
+	m.inputValidChecksumNode.validChecksum = true
+	m.inputValidChecksumNode.Next = next
+	m.inputValidChecksumNode.Errors = errors
 	v.RegisterInOutNode(&m.inputValidChecksumNode, "ip4-input-valid-checksum")
+	m.arpNode.Next = next
 	v.RegisterInOutNode(&m.arpNode, "ip4-arp")
+	m.rewriteNode.Next = next
 	v.RegisterInOutNode(&m.rewriteNode, "ip4-rewrite")
+	m.RegisterAdjAddDelHook(m.adjAddDel)
 }
 
 const (
@@ -34,14 +51,115 @@ const (
 	input_next_punt
 )
 
-type inputNode struct{ vnet.InOutNode }
+const (
+	input_error_none = iota
+	input_error_bad_checksum
+	input_error_ttl_expired
+	input_error_drop
+	input_error_no_route
+)
+
+// Software forwarding: lookup packets in the fib of their receive
+// interface then rewrite and transmit, punt or drop them by adjacency.
+type inputNode struct {
+	vnet.InOutNode
+	m             *Main
+	validChecksum bool
+	// Next index of each hardware interface transmitting rewrites.
+	txNext []uint
+}
+
+// Make the next to the interface of each of the new rewrites.
+func (m *Main) adjAddDel(_ *ip.Main, ai ip.Adj, isDel bool) {
+	if isDel {
+		return
+	}
+	as := m.GetAdj(ai)
+	for i := range as {
+		if as[i].IsRewrite() {
+			m.inputNode.addTxNext(as[i].Si)
+			m.inputValidChecksumNode.addTxNext(as[i].Si)
+		}
+	}
+}
+
+func (node *inputNode) addTxNext(si vnet.Si) {
+	v := node.Vnet
+	h := v.HwIferForSupSi(si)
+	if h == nil {
+		return
+	}
+	hi := uint(h.GetHwIf().Hi())
+	for uint(len(node.txNext)) <= hi {
+		node.txNext = append(node.txNext, input_next_drop)
+	}
+	if x, err := v.GetLoop().AddNext(node, h); err == nil {
+		node.txNext[hi] = x
+	}
+}
+
+// Multipath flow hash of source and destination address.
+func flowHash(h *Header) uint {
+	x := uint32(h.Src.AsUint32().ToHost() ^ h.Dst.AsUint32().ToHost())
+	x ^= x >> 16
+	x ^= x >> 8
+	return uint(x)
+}
+
+func (node *inputNode) forward_x1(r0 *vnet.Ref) (next0 uint) {
+	m := node.m
+	h0 := GetHeader(r0)
+	next0 = input_next_drop
+	if !node.validChecksum && h0.Checksum != h0.ComputeChecksum() {
+		node.SetError(r0, input_error_bad_checksum)
+		return
+	}
+	as0 := m.GetAdj(m.Lookup(&h0.Dst, m.ValidateFibIndexForSi(r0.Si)))
+	a0 := &as0[0]
+	if n := uint(len(as0)); n > 1 {
+		a0 = &as0[flowHash(h0)%n]
+	}
+	switch a0.LookupNextIndex {
+	case ip.LookupNextRewrite:
+		hi := uint(node.Vnet.SupHi(a0.Si))
+		if hi >= uint(len(node.txNext)) || node.txNext[hi] == input_next_drop {
+			node.SetError(r0, input_error_no_route)
+			return
+		}
+		if h0.Ttl <= 1 {
+			node.SetError(r0, input_error_ttl_expired)
+			return
+		}
+		h0.Ttl--
+		h0.Checksum = h0.ComputeChecksum()
+		vnet.PerformRewrite(r0, &a0.Rewrite)
+		r0.Si = a0.Si
+		next0 = node.txNext[hi]
+	case ip.LookupNextPunt, ip.LookupNextLocal, ip.LookupNextGlean:
+		next0 = input_next_punt
+	case ip.LookupNextDrop:
+		node.SetError(r0, input_error_drop)
+	default:
+		node.SetError(r0, input_error_no_route)
+	}
+	return
+}
 
 func (node *inputNode) NodeInput(in *vnet.RefIn, out *vnet.RefOut) {
-	node.Redirect(in, out, input_next_punt)
+	q := node.GetEnqueue(in)
+	i, n_left := in.Range()
+	for n_left >= 1 {
+		r0 := in.Get1(i)
+		x0 := node.forward_x1(r0)
+		q.Put1(r0, x0)
+		n_left -= 1
+		i += 1
+	}
 }
 
-type inputValidChecksumNode struct{ vnet.InOutNode }
+// Nodes of rewrites and arps that a platform forwards; software punts them.
+type puntNode struct{ vnet.InOutNode }
 
-func (node *inputValidChecksumNode) NodeInput(in *vnet.RefIn, out *vnet.RefOut) {
+func (node *puntNode) NodeInput(in *vnet.RefIn, out *vnet.RefOut) {
 	node.Redirect(in, out, input_next_punt)
 }
@@ -15,6 +15,18 @@ func (a *Address) String() string {
 	return (net.IP)(a[:]).String()
 }
 
+func (a *Address) Parse(in *parse.Input) {
+	var s string
+	if !in.Parse("%s", &s) {
+		in.ParseError()
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		in.ParseError()
+	}
+	copy(a[:], ip.To16())
+}
+
 func (h *Header) String() (s string) {
 	s = fmt.Sprintf("%s: %s -> %s", h.Protocol.String(), h.Src.String(), h.Dst.String())
 	return
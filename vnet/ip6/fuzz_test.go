@@ -0,0 +1,57 @@
+// Copyright 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package ip6
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/platinasystems/go/elib/parse"
+)
+
+// Parse all of s.  Parsers panic with errors, as the cli expects, but not
+// runtime errors.
+func parseAll(p parse.Parser, s string) (ok bool) {
+	defer func() {
+		if e := recover(); e != nil {
+			_, isErr := e.(error)
+			if _, isRuntime := e.(runtime.Error); !isErr || isRuntime {
+				panic(e)
+			}
+			ok = false
+		}
+	}()
+	var in parse.Input
+	in.SetString(s)
+	p.Parse(&in)
+	return in.End()
+}
+
+func FuzzHeaderParse(f *testing.F) {
+	for _, s := range []string{
+		"UDP: ::1 -> ::2",
+		"TCP: 2001:db8::1 -> fe80::1 ttl 1",
+		"ICMP:1.2.3.4->::ffff:5.6.7.8",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		var h, h2 Header
+		if !parseAll(&h, s) {
+			return
+		}
+		s2 := fmt.Sprintf("%s ttl %d", &h, h.Ttl)
+		if !parseAll(&h2, s2) {
+			t.Fatalf("%q: can't parse %q", s, s2)
+		}
+		if h2 != h {
+			t.Fatalf("%q: got %s, want %s", s, &h2, &h)
+		}
+	})
+}
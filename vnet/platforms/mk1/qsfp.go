@@ -539,10 +539,13 @@ func (m *qsfpMain) signalChange(signal sfp.QsfpSignal, changedPorts, newValues u
 						continue
 					}
 				}
+				if q.Cmis != nil {
+					publishCmis(int(port), q.Cmis)
+				}
 				// if qsfp is an optic publish static monitoring thresholds
 				if !strings.Contains(q.Ident.Compliance, "CR") && q.Ident.Compliance != "" {
-					// enable laser
-					q.TxEnable(0xf, 0xf)
+					// enable laser of all lanes
+					q.TxEnable(0xff, 0xff)
 
 					q.Monitoring()
 					for _, k := range sfp.StaticMonitoringRedisFields {
@@ -723,6 +726,14 @@ func (m *qsfpMain) signalChange(signal sfp.QsfpSignal, changedPorts, newValues u
 						lasts[f] = ""
 					}
 				}
+				// the module's CMIS memory is gone so delete what was published
+				for _, k := range sfp.CmisRedisFields {
+					f := "port-" + strconv.Itoa(int(port)+PortBase()) + "." + k
+					if lasts[f] != "" {
+						pub.Print("delete: ", f)
+						lasts[f] = ""
+					}
+				}
 				//enable reset and low power mode
 				mod.SfpReset(true)
 				mod.SfpSetLowPowerMode(true)
@@ -840,90 +851,6 @@ func (m *qsfpMain) poll() {
 									lasts[f] = s
 								}
 							}
-							if strings.Contains(k, "qsfp.rx1.power.units.mW") {
-								s := q.Mon.RxPower[0]
-								if s != lasts[f] {
-									pub.Print(f, ": ", s)
-									lasts[f] = s
-								}
-							}
-							if strings.Contains(k, "qsfp.rx2.power.units.mW") {
-								s := q.Mon.RxPower[1]
-								if s != lasts[f] {
-									pub.Print(f, ": ", s)
-									lasts[f] = s
-								}
-							}
-							if strings.Contains(k, "qsfp.rx3.power.units.mW") {
-								s := q.Mon.RxPower[2]
-								if s != lasts[f] {
-									pub.Print(f, ": ", s)
-									lasts[f] = s
-								}
-							}
-							if strings.Contains(k, "qsfp.rx4.power.units.mW") {
-								s := q.Mon.RxPower[3]
-								if s != lasts[f] {
-									pub.Print(f, ": ", s)
-									lasts[f] = s
-								}
-							}
-							if strings.Contains(k, "qsfp.tx1.power.units.mW") {
-								s := q.Mon.TxPower[0]
-								if s != lasts[f] {
-									pub.Print(f, ": ", s)
-									lasts[f] = s
-								}
-							}
-							if strings.Contains(k, "qsfp.tx2.power.units.mW") {
-								s := q.Mon.TxPower[1]
-								if s != lasts[f] {
-									pub.Print(f, ": ", s)
-									lasts[f] = s
-								}
-							}
-							if strings.Contains(k, "qsfp.tx3.power.units.mW") {
-								s := q.Mon.TxPower[2]
-								if s != lasts[f] {
-									pub.Print(f, ": ", s)
-									lasts[f] = s
-								}
-							}
-							if strings.Contains(k, "qsfp.tx4.power.units.mW") {
-								s := q.Mon.TxPower[3]
-								if s != lasts[f] {
-									pub.Print(f, ": ", s)
-									lasts[f] = s
-								}
-							}
-							if strings.Contains(k, "qsfp.tx1.bias.units.mA") {
-								s := q.Mon.TxBias[0]
-								if s != lasts[f] {
-									pub.Print(f, ": ", s)
-									lasts[f] = s
-								}
-							}
-							if strings.Contains(k, "qsfp.tx2.bias.units.mA") {
-								s := q.Mon.TxBias[1]
-								if s != lasts[f] {
-									pub.Print(f, ": ", s)
-									lasts[f] = s
-								}
-							}
-							if strings.Contains(k, "qsfp.tx3.bias.units.mA") {
-								s := q.Mon.TxBias[2]
-								if s != lasts[f] {
-									pub.Print(f, ": ", s)
-									lasts[f] = s
-								}
-							}
-							if strings.Contains(k, "qsfp.tx4.bias.units.mA") {
-								s := q.Mon.TxBias[3]
-								if s != lasts[f] {
-									pub.Print(f, ": ", s)
-									lasts[f] = s
-								}
-							}
 							if strings.Contains(k, "qsfp.alarms.module") {
 								s := q.Alarms.Module
 								if s == "" {
@@ -951,7 +878,10 @@ func (m *qsfpMain) poll() {
 								}
 							}
 						}
-
+						m.publishLanes(int(port), q)
+						if q.Cmis != nil {
+							publishCmis(int(port), q.Cmis)
+						}
 					}
 				}
 			}
@@ -961,6 +891,54 @@ func (m *qsfpMain) poll() {
 	}
 }
 
+// publish lane monitors, 4 or, with CMIS, up to 8
+func (m *qsfpMain) publishLanes(port int, q *sfp.QsfpModule) {
+	prefix := "port-" + strconv.Itoa(port+PortBase()) + ".qsfp."
+	for i := range q.Mon.RxPower {
+		lane := strconv.Itoa(i + 1)
+		for _, x := range []struct{ k, s string }{
+			{"rx" + lane + ".power.units.mW", q.Mon.RxPower[i]},
+			{"tx" + lane + ".power.units.mW", q.Mon.TxPower[i]},
+			{"tx" + lane + ".bias.units.mA", q.Mon.TxBias[i]},
+		} {
+			f := prefix + x.k
+			if x.s != lasts[f] {
+				pub.Print(f, ": ", x.s)
+				lasts[f] = x.s
+			}
+		}
+	}
+}
+
+// publish CMIS revision, state, and applications
+func publishCmis(port int, c *sfp.CmisMemory) {
+	prefix := "port-" + strconv.Itoa(port+PortBase()) + ".qsfp."
+	fields := map[string]string{
+		"revision":    c.Revision(),
+		"moduleState": c.ModuleState().String(),
+		"lowPower":    strconv.FormatBool(c.LowPower()),
+	}
+	var states []string
+	for _, s := range c.DataPathStates(c.Lanes()) {
+		states = append(states, s.String())
+	}
+	fields["dataPathState"] = strings.Join(states, ",")
+	for i, a := range c.Applications() {
+		fields["application"+strconv.Itoa(i+1)] = a.String()
+	}
+	for k, s := range fields {
+		f := prefix + k
+		if s != lasts[f] {
+			if k == "moduleState" && lasts[f] != "" {
+				log.Print("port ", port+PortBase(), " module state ",
+					lasts[f], " to ", s)
+			}
+			pub.Print(f, ": ", s)
+			lasts[f] = s
+		}
+	}
+}
+
 type qsfpModule struct {
 	// Index into m.current.* bitmaps.
 	port_index uint
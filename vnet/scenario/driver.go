@@ -0,0 +1,161 @@
+// Copyright 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scenario
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/platinasystems/go/elib/parse"
+	"github.com/platinasystems/go/vnet"
+	"github.com/platinasystems/go/vnet/ethernet"
+)
+
+// A packet sent by an interface or to the punt node.
+type packet struct {
+	out  string
+	data []byte
+}
+
+// The driver package creates the scenario interfaces and a punt node that
+// capture rather than transmit.
+type driver struct {
+	vnet.Package
+	names      []string
+	interfaces []iface
+	punt       punt_node
+
+	mu       sync.Mutex
+	captured []packet
+}
+
+type iface struct {
+	vnet.InterfaceNode
+	ethernet.Interface
+	d *driver
+}
+
+type punt_node struct {
+	vnet.OutputNode
+	d *driver
+}
+
+func (d *driver) Configure(in *parse.Input) {}
+
+func (d *driver) Init() (err error) {
+	v := d.Vnet
+	d.punt.d = d
+	v.RegisterOutputNode(&d.punt, "punt")
+	var subs []string
+	// Registered interfaces mustn't move so allocate them all up front.
+	d.interfaces = make([]iface, 0, len(d.names))
+	for _, name := range d.names {
+		if strings.Contains(name, ".") {
+			subs = append(subs, name)
+			continue
+		}
+		i := len(d.interfaces)
+		d.interfaces = d.interfaces[:i+1]
+		intf := &d.interfaces[i]
+		intf.d = d
+		config := &ethernet.InterfaceConfig{
+			Address: ethernet.Address{0xfe, 0xdc, 0xba, 0, 0, 0},
+		}
+		config.Address.Add(uint64(i))
+		ethernet.RegisterInterface(v, intf, config, "%s", name)
+		v.RegisterInterfaceNode(intf, intf.Hi(), "%s", name)
+		if err = intf.SetLinkUp(true); err != nil {
+			return
+		}
+		if err = intf.SetAdminUp(true); err != nil {
+			return
+		}
+	}
+	for _, name := range subs {
+		if err = d.newSub(name); err != nil {
+			return
+		}
+	}
+	v.RegisterSwIfAdminUpDownHook(d.swIfAdminUpDown)
+	return
+}
+
+// New vlan sub interface named as the CLI does, e.g. eth-0-0.1.
+func (d *driver) newSub(name string) (err error) {
+	v := d.Vnet
+	x := strings.LastIndex(name, ".")
+	vlan, err := strconv.ParseUint(name[x+1:], 0, 12)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	for i := range d.interfaces {
+		if intf := &d.interfaces[i]; intf.Name() == name[:x] {
+			var id ethernet.IfId
+			id.Set(vnet.Uint16(vlan))
+			si := v.NewSwSubInterface(intf.Si(), vnet.IfId(id))
+			return si.SetAdminUp(v, true)
+		}
+	}
+	return fmt.Errorf("%s: no interface %s", name, name[:x])
+}
+
+// Like a platform, transmit only while the interface is admin up so that
+// "set interface X state down" drops its packets.
+func (d *driver) swIfAdminUpDown(v *vnet.Vnet, si vnet.Si, isUp bool) (err error) {
+	for i := range d.interfaces {
+		if intf := &d.interfaces[i]; intf.Si() == si {
+			err = intf.SetAdminUp(isUp)
+		}
+	}
+	return
+}
+
+func (d *driver) capture(out string, r *vnet.Ref) {
+	b := r.DataSlice()
+	p := packet{out: out, data: make([]byte, len(b))}
+	copy(p.data, b)
+	d.mu.Lock()
+	d.captured = append(d.captured, p)
+	d.mu.Unlock()
+}
+
+// Return packets captured since the given count.
+func (d *driver) since(n int) []packet {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.captured[n:]
+}
+
+func (d *driver) n_captured() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.captured)
+}
+
+func (i *iface) DriverName() string { return "scenario" }
+
+func (i *iface) GetHwInterfaceCounterNames() (nm vnet.InterfaceCounterNames) { return }
+func (i *iface) GetHwInterfaceCounterValues(t *vnet.InterfaceThread)         {}
+func (i *iface) ValidateSpeed(speed vnet.Bandwidth) (err error)              { return }
+
+// Packets are only received from the packet generator.
+func (i *iface) InterfaceInput(o *vnet.RefOut) {}
+
+func (i *iface) InterfaceOutput(in *vnet.TxRefVecIn) {
+	for j := range in.Refs {
+		i.d.capture(i.Name(), &in.Refs[j])
+	}
+	i.Vnet.FreeTxRefIn(in)
+}
+
+func (n *punt_node) NodeOutput(in *vnet.RefIn) {
+	l := in.InLen()
+	for i := uint(0); i < l; i++ {
+		n.d.capture(n.Name(), &in.Refs[i])
+	}
+	in.FreeRefs(l)
+}
@@ -0,0 +1,386 @@
+// Copyright 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scenario
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/platinasystems/go/elib/cli"
+	"github.com/platinasystems/go/elib/parse"
+	"github.com/platinasystems/go/internal/prog"
+	"github.com/platinasystems/go/vnet"
+	"github.com/platinasystems/go/vnet/ethernet"
+	ipcli "github.com/platinasystems/go/vnet/ip/cli"
+	"github.com/platinasystems/go/vnet/ip4"
+	"github.com/platinasystems/go/vnet/ip6"
+	"github.com/platinasystems/go/vnet/pg"
+)
+
+// Timeout for the packets of a case to be generated and settle.
+const Timeout = 5 * time.Second
+
+// A vnet may only run once per process so each scenario is run by a child
+// test process with this set to its file name.
+const env = "GOES_VNET_SCENARIO"
+
+// Test runs each scenario file matching the pattern as a subtest named by
+// the file's base name w/o extension, e.g.
+//
+//	func TestScenarios(t *testing.T) {
+//		scenario.Test(t, "testdata/*.yaml")
+//	}
+func Test(t *testing.T, pattern string) {
+	fns, err := filepath.Glob(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fns) == 0 {
+		t.Fatal(pattern, "no match")
+	}
+	for _, fn := range fns {
+		fn := fn
+		name := strings.TrimSuffix(filepath.Base(fn), filepath.Ext(fn))
+		t.Run(name, func(t *testing.T) {
+			if os.Getenv(env) == fn {
+				Run(t, fn)
+			} else {
+				child(t, fn)
+			}
+		})
+	}
+}
+
+// Re-run this test in a child process to run the scenario.
+func child(t *testing.T, fn string) {
+	var run []string
+	for _, s := range strings.Split(t.Name(), "/") {
+		run = append(run, "^"+regexp.QuoteMeta(s)+"$")
+	}
+	cmd := exec.Command(prog.Name(), "-test.v",
+		"-test.run="+strings.Join(run, "/"))
+	cmd.Env = append(os.Environ(), env+"="+fn)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Errorf("%s: %v\n%s", fn, err, out)
+	} else if testing.Verbose() {
+		t.Logf("%s", out)
+	}
+}
+
+// Run the scenario file in this process.
+func Run(t *testing.T, fn string) {
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Parse(b)
+	if err != nil {
+		t.Fatal(fn, err)
+	}
+	r := start(t, s.Interfaces)
+	defer r.quit(t)
+	if out, err := r.exec(s.Setup); err != nil {
+		t.Fatalf("setup: %v\n%s", err, out)
+	}
+	for i := range s.Cases {
+		c := &s.Cases[i]
+		t.Run(c.Name, func(t *testing.T) {
+			r.test(t, i, c)
+		})
+	}
+}
+
+type runner struct {
+	v      vnet.Vnet
+	d      driver
+	ready  chan struct{}
+	exited chan error
+}
+
+// Run f in the vnet event loop.
+type call struct {
+	vnet.Event
+	f    func()
+	done chan struct{}
+}
+
+func (c *call) EventAction() {
+	defer close(c.done)
+	c.f()
+}
+
+func (c *call) String() string { return "scenario call" }
+
+func start(t *testing.T, interfaces []string) *runner {
+	r := &runner{
+		ready:  make(chan struct{}),
+		exited: make(chan error, 1),
+	}
+	v := &r.v
+	m4 := ip4.Init(v)
+	m6 := ip6.Init(v)
+	ethernet.Init(v, m4, m6)
+	pg.Init(v)
+	ipcli.Init(v)
+	r.d.names = interfaces
+	v.AddPackage("scenario", &r.d)
+	vnet.AddInit(func(v *vnet.Vnet) {
+		v.SignalEvent(&call{
+			f:    func() { close(r.ready) },
+			done: make(chan struct{}),
+		})
+	})
+	go func() {
+		var in parse.Input
+		r.exited <- v.Run(&in)
+	}()
+	select {
+	case <-r.ready:
+	case err := <-r.exited:
+		t.Fatal("vnet exited:", err)
+	case <-time.After(Timeout):
+		t.Fatal("vnet not ready")
+	}
+	return r
+}
+
+func (r *runner) quit(t *testing.T) {
+	r.v.Quit()
+	select {
+	case err := <-r.exited:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(Timeout):
+		t.Error("vnet didn't quit")
+	}
+}
+
+func (r *runner) do(f func()) {
+	c := &call{f: f, done: make(chan struct{})}
+	r.v.SignalEvent(c)
+	<-c.done
+}
+
+// Execute vnet CLI commands like the "exec" command does a file.
+func (r *runner) exec(script string) (out string, err error) {
+	var b bytes.Buffer
+	r.do(func() {
+		var i [2]cli.Input
+		i[0].Init(strings.NewReader(script))
+		for !i[0].End() {
+			i[1].Init(nil)
+			if !i[0].Parse("%l", &i[1].Input) {
+				err = i[0].Error()
+				return
+			}
+			err = r.v.GetLoop().Cli.ExecInput(&b, &i[1])
+			if err != nil {
+				return
+			}
+		}
+	})
+	return b.String(), err
+}
+
+type counters map[string]map[string]uint64
+
+func (c counters) add(what, name string, value uint64) {
+	if c[what] == nil {
+		c[what] = make(map[string]uint64)
+	}
+	c[what][name] += value
+}
+
+func (r *runner) counters() counters {
+	c := make(counters)
+	r.do(func() {
+		v := &r.v
+		v.ForeachSwIfCounter(false,
+			func(si vnet.Si, name string, value uint64) {
+				c.add(si.Name(v), name, value)
+			})
+		v.ForeachErrorCounter(false,
+			func(node, name string, value uint64) {
+				c.add(node, name, value)
+			})
+	})
+	return c
+}
+
+func (c counters) total() (n uint64) {
+	for _, m := range c {
+		for _, value := range m {
+			n += value
+		}
+	}
+	return
+}
+
+func (r *runner) test(t *testing.T, i int, c *Case) {
+	if out, err := r.exec(c.Setup); err != nil {
+		t.Fatalf("setup: %v\n%s", err, out)
+	}
+	n := r.d.n_captured()
+	before := r.counters()
+	out, err := r.exec(fmt.Sprintf(`packet-generator {
+name scenario%d count %d interface %s next %s
+%s
+}`, i, c.Count, c.In, c.Next, c.Stream))
+	if err != nil {
+		t.Fatalf("stream: %v\n%s", err, out)
+	}
+	after, err := r.settle(before, c.Count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	captured := r.d.since(n)
+	matched := make([]bool, len(captured))
+	for _, x := range c.Expect {
+		x.check(t, captured, matched)
+	}
+	for j, p := range captured {
+		if !matched[j] {
+			t.Errorf("unexpected %s: %x", p.out, p.data)
+		}
+	}
+	for what, m := range c.Counters {
+		for name, want := range m {
+			got := after[what][name] - before[what][name]
+			if got != want {
+				t.Errorf("%s %s: got %d, want %d", what, name,
+					got, want)
+			}
+		}
+	}
+}
+
+// Wait for the packet generator to send count packets and for the packet
+// counters to stop changing; return the final counters.
+func (r *runner) settle(before counters, count uint64) (after counters, err error) {
+	const period = 10 * time.Millisecond
+	var last uint64
+	stable := 0
+	for t := Timeout / period; t > 0; t-- {
+		time.Sleep(period)
+		after = r.counters()
+		sent := after["pg0"]["rx packets"] - before["pg0"]["rx packets"]
+		if sent < count {
+			continue
+		}
+		total := after.total() + uint64(r.d.n_captured())
+		if total == last {
+			if stable++; stable == 3 {
+				return
+			}
+		} else {
+			last, stable = total, 0
+		}
+	}
+	err = fmt.Errorf("packets didn't settle w/in %v", Timeout)
+	return
+}
+
+func (x *Expect) check(t *testing.T, captured []packet, matched []bool) {
+	t.Helper()
+	what := strings.Join(x.Out, "|")
+	var eh ethernet.HeaderParser
+	var ih ip4.Header
+	var eb []byte
+	if len(x.Ethernet) > 0 {
+		if !parseHeader(t, x.Ethernet, func(in *parse.Input) {
+			eh.Parse(in)
+		}) {
+			return
+		}
+		eb = make([]byte, eh.Sizeof())
+		eh.Write(eb)
+	}
+	if len(x.Ip4) > 0 && !parseHeader(t, x.Ip4, ih.Parse) {
+		return
+	}
+	n := uint64(0)
+	for j, p := range captured {
+		if !x.isOut(p.out) {
+			continue
+		}
+		matched[j] = true
+		n++
+		o := uint(0)
+		if p.out != "punt" {
+			o = ethernet.SizeofHeader
+		}
+		if len(x.Ethernet) > 0 {
+			o = uint(len(eb))
+			if uint(len(p.data)) < o {
+				t.Errorf("%s: no ethernet header: %x", p.out,
+					p.data)
+				continue
+			}
+			if !bytes.Equal(p.data[:o], eb) {
+				h := (*ethernet.Header)(unsafe.Pointer(&p.data[0]))
+				t.Errorf("%s: ethernet: got %s %x, want %s",
+					p.out, h, p.data[ethernet.SizeofHeader:o],
+					x.Ethernet)
+				continue
+			}
+		}
+		if len(x.Ip4) > 0 {
+			if uint(len(p.data)) < o+ip4.SizeofHeader {
+				t.Errorf("%s: no ip4 header: %x", p.out, p.data)
+				continue
+			}
+			h := (*ip4.Header)(unsafe.Pointer(&p.data[o]))
+			if h.Protocol != ih.Protocol || h.Src != ih.Src ||
+				h.Dst != ih.Dst || h.Ttl != ih.Ttl ||
+				h.Checksum != h.ComputeChecksum() {
+				t.Errorf("%s: ip4: got %s ttl %d, want %s ttl %d",
+					p.out, h, h.Ttl, &ih, ih.Ttl)
+			}
+		}
+	}
+	if x.Packets != nil && n != *x.Packets {
+		t.Errorf("%s: got %d packets, want %d", what, n, *x.Packets)
+	}
+	if n < x.Min {
+		t.Errorf("%s: got %d packets, want at least %d", what, n, x.Min)
+	}
+}
+
+func (x *Expect) isOut(out string) bool {
+	for _, s := range x.Out {
+		if s == out {
+			return true
+		}
+	}
+	return false
+}
+
+func parseHeader(t *testing.T, s string, f func(*parse.Input)) (ok bool) {
+	t.Helper()
+	defer func() {
+		if e := recover(); e != nil {
+			t.Errorf("%q: %v", s, e)
+		}
+	}()
+	var in parse.Input
+	in.SetString(s)
+	f(&in)
+	if !in.End() {
+		t.Errorf("%q: junk at end: %s", s, &in)
+		return
+	}
+	return true
+}
@@ -0,0 +1,151 @@
+// Copyright 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package scenario runs declarative forwarding tests of vnet.  A scenario
+// file names the ethernet interfaces to create, the vnet CLI commands that
+// configure them, then cases of packet generator streams and where and how
+// those packets should leave.
+//
+//	interfaces: [eth-0-0, eth-1-0]
+//	setup: |
+//	  ip route add 2.2.2.2/32 rewrite eth-1-0 IP4: 1.2.3 -> 4.5.6
+//	cases:
+//	- name: ttl1
+//	  count: 10
+//	  stream: |
+//	    ethernet {
+//	      IP4: 00:a0:c9:00:00:00 -> fe:dc:ba:00:00:00
+//	      UDP: 1.0.0.0 -> 2.2.2.2 ttl 1
+//	    }
+//	    size 100
+//	  counters:
+//	    ip4-input: {ttl expired: 10}
+//	- name: rewrite
+//	  stream: |
+//	    ethernet {
+//	      IP4: 00:a0:c9:00:00:00 -> fe:dc:ba:00:00:00
+//	      UDP: 1.0.0.0 -> 2.2.2.2
+//	    }
+//	  expect:
+//	  - out: [eth-1-0]
+//	    packets: 1
+//	    ethernet: "IP4: 1.2.3 -> 4.5.6"
+//	    ip4: "UDP: 1.0.0.0 -> 2.2.2.2 ttl 63"
+//	  counters:
+//	    eth-1-0: {tx packets: 1}
+//
+// Generated packets appear to be received by the case's interface and start
+// at the given node, by default, ethernet-input.  The Nth interface has the
+// ethernet address fe:dc:ba:00:00:0N; ip4 packets sent to it, or tagged for
+// one of its vlan sub interfaces, are looked up in the receive interface's
+// fib by ip4-input then rewritten and sent by the route's interface, punted
+// at their ip4 header, or dropped.  Other packets are punted whole.  A
+// platform added with vnet.AddInit before Test may forward with its own
+// nodes instead, e.g. fe1-cpu.
+//
+// Packets sent by an interface or to the punt node are captured and every
+// captured packet must match an expectation.  Packets sent to the error node
+// aren't captured but may be checked by the counters of the node that dropped
+// them, e.g. "eth-1-0: {tx down drops: 1}".  Counters are the difference of
+// each named software interface or node error counter over the case.
+package scenario
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+type Scenario struct {
+	// Ethernet interfaces to create; those named as a vlan sub interface,
+	// e.g. eth-0-0.1, follow their super interface.
+	Interfaces []string `yaml:"interfaces"`
+	// Vnet CLI commands run before the cases.
+	Setup string `yaml:"setup"`
+	Cases []Case `yaml:"cases"`
+}
+
+type Case struct {
+	Name string `yaml:"name"`
+	// Receive interface of the generated packets, default, the first.
+	In string `yaml:"in"`
+	// First node of the generated packets, default, ethernet-input.
+	Next string `yaml:"next"`
+	// Number of packets to generate, default, 1.
+	Count uint64 `yaml:"count"`
+	// Body of a packet-generator command, e.g.
+	//	ethernet { IP4: 1.2.3 -> 4.5.6 UDP: 1.0.0.0 -> 1.1.1.1 } size 100
+	Stream string `yaml:"stream"`
+	// Vnet CLI commands run before the stream.
+	Setup  string   `yaml:"setup"`
+	Expect []Expect `yaml:"expect"`
+	// Counter deltas by interface or node then counter name.
+	Counters map[string]map[string]uint64 `yaml:"counters"`
+}
+
+// Expect the given number of packets to leave through any of the named
+// interfaces or nodes, each with the given headers.  The ethernet and ip4
+// headers are in the packet-generator syntax.  An ethernet header, with any
+// vlan tags, e.g. "IP4: 1.2.3 -> 4.5.6 vlan 1", is matched at the start of
+// the packet.  An ip4 header is matched after the ethernet header, if given,
+// or that of packets sent by an interface and otherwise at the start of
+// packets sent to the punt node.
+type Expect struct {
+	Out      []string `yaml:"out"`
+	Packets  *uint64  `yaml:"packets"`
+	Min      uint64   `yaml:"min"`
+	Ethernet string   `yaml:"ethernet"`
+	Ip4      string   `yaml:"ip4"`
+}
+
+// Parse and verify a YAML scenario.
+func Parse(b []byte) (*Scenario, error) {
+	s := new(Scenario)
+	if err := yaml.UnmarshalStrict(b, s); err != nil {
+		return nil, err
+	}
+	if len(s.Interfaces) == 0 {
+		return nil, fmt.Errorf("interfaces: none")
+	}
+	names := make(map[string]bool)
+	for _, name := range s.Interfaces {
+		if names[name] {
+			return nil, fmt.Errorf("%s: duplicate interface", name)
+		}
+		if x := strings.LastIndex(name, "."); x >= 0 && !names[name[:x]] {
+			return nil, fmt.Errorf("%s: no interface %s", name,
+				name[:x])
+		}
+		names[name] = true
+	}
+	for i := range s.Cases {
+		c := &s.Cases[i]
+		if len(c.Name) == 0 {
+			c.Name = fmt.Sprint("case", i)
+		}
+		if len(c.In) == 0 {
+			c.In = s.Interfaces[0]
+		} else if !names[c.In] {
+			return nil, fmt.Errorf("%s: in: %s: unknown interface",
+				c.Name, c.In)
+		}
+		if len(c.Next) == 0 {
+			c.Next = "ethernet-input"
+		}
+		if c.Count == 0 {
+			c.Count = 1
+		}
+		if len(c.Stream) == 0 {
+			return nil, fmt.Errorf("%s: stream: missing", c.Name)
+		}
+		for _, x := range c.Expect {
+			if len(x.Out) == 0 {
+				return nil, fmt.Errorf("%s: expect: missing out",
+					c.Name)
+			}
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,27 @@
+// Copyright 2018 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scenario
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	for _, s := range []string{
+		"cases: [{stream: x}]",
+		"interfaces: [a, a]",
+		"interfaces: [a.1, a]",
+		"interfaces: [a]\ncases: [{in: b, stream: x}]",
+		"interfaces: [a]\ncases: [{name: x}]",
+		"interfaces: [a]\ncases: [{stream: x, expect: [{packets: 1}]}]",
+		"interfaces: [a]\nunknown: true",
+	} {
+		if _, err := Parse([]byte(s)); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
+
+func TestScenarios(t *testing.T) {
+	Test(t, "testdata/*.yaml")
+}
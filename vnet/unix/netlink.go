@@ -42,6 +42,9 @@ type netlink_namespace struct {
 	netlink_socket_fds [2]int
 	netlink_socket_pair
 	ip4_next_hops []ip4_next_hop
+	nexthop_by_id map[uint32]*netlink_nexthop
+	// Nexthop id of each ip4 route added with RTA_NH_ID.
+	nexthop_id_by_ip4_prefix map[ip4.Prefix]uint32
 }
 
 type netlink_socket_pair struct {
@@ -150,6 +153,12 @@ func (ns *net_namespace) knownInterface(i uint32) (ok bool) {
 }
 
 func (ns *net_namespace) route_msg_for_vnet_interface(v *netlink.RouteMessage) (intf *net_namespace_interface, ok bool) {
+	// Track all routes using known nexthop objects since the nexthop may
+	// later change to one via vnet interfaces.
+	if a := v.Attrs[netlink.RTA_NH_ID]; a != nil {
+		_, ok = ns.nexthop_by_id[a.(netlink.Uint32Attr).Uint()]
+		return
+	}
 	if a := v.Attrs[netlink.RTA_OIF]; a != nil {
 		intf, ok = ns.interface_by_index[a.(netlink.Uint32Attr).Uint()]
 		if !ok {
@@ -196,6 +205,8 @@ func (ns *net_namespace) msg_for_vnet_interface(msg netlink.Message) (ok bool) {
 		}
 	case *netlink.NeighborMessage:
 		ok = ns.knownInterface(v.Index)
+	case *netlink.NexthopMessage:
+		// Keep all nexthops since groups may reference any of them.
 	case *netlink.DoneMessage, *netlink.NetnsMessage, *netlink.ErrorMessage:
 	default:
 		panic(fmt.Errorf("unknown message %s", msg))
@@ -509,6 +520,11 @@ func (e *netlinkEvent) EventAction() {
 				known = true
 				err = e.netnsMessage(v)
 			}
+		case *netlink.NexthopMessage:
+			if !FdbOn {
+				known = true
+				err = e.nexthopMsg(v)
+			}
 		}
 		if !known && !FdbOn {
 			err = fmt.Errorf("unkown")
@@ -725,6 +741,15 @@ func (e *netlinkEvent) ip4RouteMsg(v *netlink.RouteMessage, isLastInEvent bool)
 	p := ip4Prefix(v.Attrs[netlink.RTA_DST], v.DstLen)
 	isDel := v.Header.Type == netlink.RTM_DELROUTE
 
+	if a := v.Attrs[netlink.RTA_NH_ID]; a != nil {
+		return e.ip4NexthopIdRouteMsg(&p, a.(netlink.Uint32Attr).Uint(), isDel, isReplace)
+	}
+
+	// A gateway route replaces any route via nexthop id.
+	if _, err = e.ip4UntrackNexthopRoute(&p); err != nil {
+		return
+	}
+
 	nhs := e.ns.parse_ip4_next_hops(v)
 	if isReplace && false { //debug print
 		fmt.Printf("netlink NEWROUTE Flags=Replace GATEWAY=%v DST=%v\n", v.Attrs[netlink.RTA_GATEWAY], v.Attrs[netlink.RTA_DST])
@@ -764,6 +789,203 @@ func (e *netlinkEvent) ip4RouteMsg(v *netlink.RouteMessage, isLastInEvent bool)
 	return
 }
 
+// Kernel nexthop object; either a gateway via interface or a group of other
+// nexthops by id and weight.
+type netlink_nexthop struct {
+	family    netlink.AddressFamily
+	ifindex   uint32
+	gateway   ip4.Address
+	blackhole bool
+	group     []netlink.NhaGroupEntry
+
+	// Prefixes of routes using this nexthop.
+	ip4_prefixes map[ip4.Prefix]struct{}
+}
+
+func (e *netlinkEvent) nexthopMsg(v *netlink.NexthopMessage) (err error) {
+	ns := e.ns
+	a, ok := v.Attrs[netlink.NHA_ID].(netlink.Uint32Attr)
+	if !ok {
+		return fmt.Errorf("nexthop without id")
+	}
+	id := a.Uint()
+	isDel := v.Header.Type == netlink.RTM_DELNEXTHOP
+
+	// Withdraw routes resolved through this nexthop before it changes.
+	users := ns.nexthop_users(id)
+	if err = e.ip4NexthopRoutes(users, true); err != nil {
+		return
+	}
+
+	if isDel {
+		// The kernel removes routes using a deleted nexthop without
+		// notification; groups are updated with RTM_NEWNEXTHOP.
+		if nh, found := ns.nexthop_by_id[id]; found {
+			for p := range nh.ip4_prefixes {
+				delete(ns.nexthop_id_by_ip4_prefix, p)
+			}
+		}
+		delete(ns.nexthop_by_id, id)
+	} else {
+		if ns.nexthop_by_id == nil {
+			ns.nexthop_by_id = make(map[uint32]*netlink_nexthop)
+		}
+		nh, found := ns.nexthop_by_id[id]
+		if !found {
+			nh = &netlink_nexthop{
+				ip4_prefixes: make(map[ip4.Prefix]struct{}),
+			}
+			ns.nexthop_by_id[id] = nh
+		}
+		nh.family = v.Family
+		nh.ifindex = 0
+		nh.gateway = ip4.Address{}
+		nh.blackhole = v.Attrs[netlink.NHA_BLACKHOLE] != nil
+		nh.group = nh.group[:0]
+		if a := v.Attrs[netlink.NHA_OIF]; a != nil {
+			nh.ifindex = a.(netlink.Uint32Attr).Uint()
+		}
+		if a, ok := v.Attrs[netlink.NHA_GATEWAY].(*netlink.Ip4Address); ok {
+			copy(nh.gateway[:], a[:])
+		}
+		if a := v.Attrs[netlink.NHA_GROUP]; a != nil {
+			nh.group = append(nh.group, a.(*netlink.NhaGroup).Entries...)
+		}
+	}
+
+	return e.ip4NexthopRoutes(users, false)
+}
+
+// Ids of nexthops using the given nexthop either directly or as a group member.
+func (ns *net_namespace) nexthop_users(id uint32) (ids []uint32) {
+	for nid, nh := range ns.nexthop_by_id {
+		if nid == id {
+			ids = append(ids, nid)
+			continue
+		}
+		for i := range nh.group {
+			if nh.group[i].Id == id {
+				ids = append(ids, nid)
+				break
+			}
+		}
+	}
+	return
+}
+
+// Resolve nexthop id to the ip4 gateway next hops of known vnet interfaces.
+func (ns *net_namespace) nexthop_ip4_next_hops(id uint32) (nhs []ip4.NextHop) {
+	nh, ok := ns.nexthop_by_id[id]
+	if !ok {
+		return
+	}
+	add := func(x *netlink_nexthop, w ip.NextHopWeight) {
+		if x.family != netlink.AF_INET || x.blackhole ||
+			x.gateway == (ip4.Address{}) {
+			return
+		}
+		if !ns.knownInterface(x.ifindex) {
+			return
+		}
+		intf, ok := ns.interface_by_index[x.ifindex]
+		if !ok {
+			return
+		}
+		n := ip4.NextHop{Address: x.gateway}
+		n.Si = intf.si
+		n.Weight = w
+		nhs = append(nhs, n)
+	}
+	if len(nh.group) == 0 {
+		add(nh, 1)
+		return
+	}
+	for i := range nh.group {
+		g := &nh.group[i]
+		if x, ok := ns.nexthop_by_id[g.Id]; ok {
+			w := uint32(g.WeightHigh)<<8 | uint32(g.Weight)
+			add(x, ip.NextHopWeight(w+1))
+		}
+	}
+	return
+}
+
+// Track prefix as a route via the given nexthop id; id 0 forgets the prefix.
+func (ns *net_namespace) set_ip4_prefix_nexthop(p *ip4.Prefix, id uint32) {
+	if old, ok := ns.nexthop_id_by_ip4_prefix[*p]; ok {
+		if nh, ok := ns.nexthop_by_id[old]; ok {
+			delete(nh.ip4_prefixes, *p)
+		}
+		delete(ns.nexthop_id_by_ip4_prefix, *p)
+	}
+	nh, ok := ns.nexthop_by_id[id]
+	if !ok {
+		return
+	}
+	if ns.nexthop_id_by_ip4_prefix == nil {
+		ns.nexthop_id_by_ip4_prefix = make(map[ip4.Prefix]uint32)
+	}
+	ns.nexthop_id_by_ip4_prefix[*p] = id
+	nh.ip4_prefixes[*p] = struct{}{}
+}
+
+// Withdraw the next hops of a route via nexthop id and forget its prefix.
+// Returns false if prefix was not tracked.
+func (e *netlinkEvent) ip4UntrackNexthopRoute(p *ip4.Prefix) (ok bool, err error) {
+	old, ok := e.ns.nexthop_id_by_ip4_prefix[*p]
+	if !ok {
+		return
+	}
+	e.ns.set_ip4_prefix_nexthop(p, 0)
+	err = e.ip4NexthopRoute(p, old, true, false)
+	return
+}
+
+func (e *netlinkEvent) ip4NexthopIdRouteMsg(p *ip4.Prefix, id uint32, isDel, isReplace bool) (err error) {
+	if _, ok := e.ns.nexthop_by_id[id]; !ok {
+		return fmt.Errorf("nexthop id %d unknown", id)
+	}
+	// Withdraw exactly the next hops of the previous nexthop id rather
+	// than rely on replace of the first new next hop, which only clears
+	// the fib of that next hop's interface.
+	tracked, err := e.ip4UntrackNexthopRoute(p)
+	if err != nil || isDel {
+		return
+	}
+	if tracked {
+		isReplace = false
+	}
+	e.ns.set_ip4_prefix_nexthop(p, id)
+	return e.ip4NexthopRoute(p, id, false, isReplace)
+}
+
+func (e *netlinkEvent) ip4NexthopRoute(p *ip4.Prefix, id uint32, isDel, isReplace bool) (err error) {
+	m4 := ip4.GetMain(e.m.v)
+	nhs := e.ns.nexthop_ip4_next_hops(id)
+	for i := range nhs {
+		if err = m4.AddDelRouteNextHop(p, &nhs[i], isDel, isReplace); err != nil {
+			return
+		}
+		isReplace = false
+	}
+	return
+}
+
+func (e *netlinkEvent) ip4NexthopRoutes(ids []uint32, isDel bool) (err error) {
+	for _, id := range ids {
+		nh, ok := e.ns.nexthop_by_id[id]
+		if !ok {
+			continue
+		}
+		for p := range nh.ip4_prefixes {
+			if err = e.ip4NexthopRoute(&p, id, isDel, false); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
 func (e *netlinkEvent) ip4_in_ip4_route(p *ip4.Prefix, as *netlink.AttrArray, intf *net_namespace_interface, isDel bool) (err error) {
 	switch intf.kind {
 	case netlink.InterfaceKindIp4GRE, netlink.InterfaceKindIpip:
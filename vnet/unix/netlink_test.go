@@ -0,0 +1,274 @@
+// Copyright 2016 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package unix
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/platinasystems/go/elib/parse"
+	"github.com/platinasystems/go/internal/netlink"
+	"github.com/platinasystems/go/vnet"
+	"github.com/platinasystems/go/vnet/ethernet"
+	"github.com/platinasystems/go/vnet/ip4"
+	"github.com/platinasystems/go/vnet/ip6"
+	"github.com/platinasystems/go/vnet/pg"
+)
+
+// Ethernet interfaces and a punt node that drop their packets.
+type testDriver struct {
+	vnet.Package
+	interfaces [3]testInterface
+	punt       testPuntNode
+}
+
+type testInterface struct {
+	vnet.InterfaceNode
+	ethernet.Interface
+}
+
+type testPuntNode struct{ vnet.OutputNode }
+
+func (n *testPuntNode) NodeOutput(in *vnet.RefIn) { in.FreeRefs(in.InLen()) }
+
+func (d *testDriver) Init() (err error) {
+	v := d.Vnet
+	v.RegisterOutputNode(&d.punt, "punt")
+	for i := range d.interfaces {
+		intf := &d.interfaces[i]
+		config := &ethernet.InterfaceConfig{
+			Address: ethernet.Address{0xfe, 0xdc, 0xba, 0, 0, byte(i)},
+		}
+		ethernet.RegisterInterface(v, intf, config, "eth%d", i)
+		v.RegisterInterfaceNode(intf, intf.Hi(), "eth%d", i)
+		if err = intf.SetLinkUp(true); err != nil {
+			return
+		}
+		if err = intf.SetAdminUp(true); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (i *testInterface) DriverName() string { return "test" }
+
+func (i *testInterface) GetHwInterfaceCounterNames() (nm vnet.InterfaceCounterNames) { return }
+func (i *testInterface) GetHwInterfaceCounterValues(t *vnet.InterfaceThread)         {}
+func (i *testInterface) ValidateSpeed(speed vnet.Bandwidth) (err error)              { return }
+func (i *testInterface) InterfaceInput(o *vnet.RefOut)                               {}
+func (i *testInterface) InterfaceOutput(in *vnet.TxRefVecIn)                         { i.Vnet.FreeTxRefIn(in) }
+
+// Run f in the vnet event loop.
+type testCall struct {
+	vnet.Event
+	f    func()
+	done chan struct{}
+}
+
+func (c *testCall) EventAction() {
+	defer close(c.done)
+	c.f()
+}
+
+func (c *testCall) String() string { return "test call" }
+
+func do(v *vnet.Vnet, f func()) {
+	c := &testCall{f: f, done: make(chan struct{})}
+	v.SignalEvent(c)
+	<-c.done
+}
+
+func nexthop(id uint32, ifindex uint32, gw ip4.Address) *netlink.NexthopMessage {
+	v := &netlink.NexthopMessage{}
+	v.Header.Type = netlink.RTM_NEWNEXTHOP
+	v.Family = netlink.AF_INET
+	v.Attrs[netlink.NHA_ID] = netlink.Uint32Attr(id)
+	v.Attrs[netlink.NHA_OIF] = netlink.Uint32Attr(ifindex)
+	a := netlink.Ip4Address(gw)
+	v.Attrs[netlink.NHA_GATEWAY] = &a
+	return v
+}
+
+func nexthopGroup(id uint32, members ...uint32) *netlink.NexthopMessage {
+	v := &netlink.NexthopMessage{}
+	v.Header.Type = netlink.RTM_NEWNEXTHOP
+	v.Family = netlink.AF_UNSPEC
+	v.Attrs[netlink.NHA_ID] = netlink.Uint32Attr(id)
+	g := &netlink.NhaGroup{}
+	for _, m := range members {
+		g.Entries = append(g.Entries, netlink.NhaGroupEntry{Id: m})
+	}
+	v.Attrs[netlink.NHA_GROUP] = g
+	return v
+}
+
+func nexthopDel(id uint32) *netlink.NexthopMessage {
+	v := &netlink.NexthopMessage{}
+	v.Header.Type = netlink.RTM_DELNEXTHOP
+	v.Attrs[netlink.NHA_ID] = netlink.Uint32Attr(id)
+	return v
+}
+
+var testPrefix = ip4.Prefix{Address: ip4.Address{1, 1, 1, 0}, Len: 24}
+
+func route(t netlink.MsgType, flags netlink.HeaderFlags, id uint32) *netlink.RouteMessage {
+	v := &netlink.RouteMessage{}
+	v.Header.Type = t
+	v.Header.Flags = flags
+	v.Rtmsg.Family = netlink.AF_INET
+	v.DstLen = uint8(testPrefix.Len)
+	v.Table = netlink.RT_TABLE_MAIN
+	v.Protocol = netlink.RTPROT_STATIC
+	v.RouteType = netlink.RTN_UNICAST
+	dst := netlink.Ip4Address(testPrefix.Address)
+	v.Attrs[netlink.RTA_DST] = &dst
+	v.Attrs[netlink.RTA_NH_ID] = netlink.Uint32Attr(id)
+	return v
+}
+
+func TestNexthopRoutes(t *testing.T) {
+	v := &vnet.Vnet{}
+	ethernet.Init(v, ip4.Init(v), ip6.Init(v))
+	pg.Init(v)
+	m4 := ip4.GetMain(v)
+	d := &testDriver{}
+	v.AddPackage("test", d)
+	ready := make(chan struct{})
+	exited := make(chan error, 1)
+	vnet.AddInit(func(v *vnet.Vnet) {
+		v.SignalEvent(&testCall{
+			f:    func() { close(ready) },
+			done: make(chan struct{}),
+		})
+	})
+	go func() {
+		var in parse.Input
+		exited <- v.Run(&in)
+	}()
+	select {
+	case <-ready:
+	case err := <-exited:
+		t.Fatal("vnet exited:", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("vnet not ready")
+	}
+	defer func() {
+		v.Quit()
+		<-exited
+	}()
+
+	// Interface i has ifindex i+1, address 10.0.i.1/24 and a neighbor
+	// 10.0.i.2 that is the gateway of nexthop i+1.
+	ns := &net_namespace{
+		interface_by_index: make(map[uint32]*net_namespace_interface),
+	}
+	ns.si_by_ifindex.m = make(map[uint32]vnet.Si)
+	e := &netlinkEvent{m: &Main{v: v}, ns: ns}
+	gw := func(i int) ip4.Address { return ip4.Address{10, 0, byte(i), 2} }
+	do(v, func() {
+		em := ethernet.GetMain(v)
+		for i := range d.interfaces {
+			si := d.interfaces[i].Si()
+			ifindex := uint32(i + 1)
+			ns.si_by_ifindex.set(ifindex, si)
+			ns.interface_by_index[ifindex] = &net_namespace_interface{
+				ifindex: ifindex,
+				si:      si,
+			}
+			p := ip4.Prefix{Address: ip4.Address{10, 0, byte(i), 1}, Len: 24}
+			if err := m4.AddDelInterfaceAddress(si, &p, false); err != nil {
+				t.Error(err)
+			}
+			a := gw(i)
+			nbr := ethernet.IpNeighbor{
+				Si:       si,
+				Ethernet: ethernet.Address{0, 0xa0, 0xc9, 0, 0, byte(i)},
+				Ip:       a.ToIp(),
+			}
+			if _, err := em.AddDelIpNeighbor(&m4.Main, &nbr, false); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+
+	// Names of the interfaces of the test route's adjacencies.
+	routeInterfaces := func() (names []string) {
+		si := d.interfaces[0].Si()
+		ai, ok := m4.GetRoute(&testPrefix, si)
+		if !ok {
+			return
+		}
+		seen := make(map[string]bool)
+		for _, a := range m4.GetAdj(ai) {
+			if !a.IsRewrite() {
+				continue
+			}
+			if name := a.Rewrite.Si.Name(v); !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		return
+	}
+
+	for _, x := range []struct {
+		name string
+		msgs []netlink.Message
+		want []string
+	}{
+		{"add", []netlink.Message{
+			nexthop(1, 1, gw(0)),
+			nexthop(2, 2, gw(1)),
+			nexthop(3, 3, gw(2)),
+			nexthopGroup(10, 1, 2),
+			route(netlink.RTM_NEWROUTE, 0, 10),
+		}, []string{"eth0", "eth1"}},
+		{"group change", []netlink.Message{
+			nexthopGroup(10, 1, 3),
+		}, []string{"eth0", "eth2"}},
+		{"member delete", []netlink.Message{
+			nexthopDel(3),
+		}, []string{"eth0"}},
+		{"route replace", []netlink.Message{
+			route(netlink.RTM_NEWROUTE, netlink.NLM_F_REPLACE, 2),
+		}, []string{"eth1"}},
+		{"old group change", []netlink.Message{
+			nexthopGroup(10, 1),
+		}, []string{"eth1"}},
+		{"route delete", []netlink.Message{
+			route(netlink.RTM_DELROUTE, 0, 2),
+		}, nil},
+	} {
+		var got []string
+		do(v, func() {
+			for _, msg := range x.msgs {
+				var err error
+				switch msg := msg.(type) {
+				case *netlink.NexthopMessage:
+					err = e.nexthopMsg(msg)
+				case *netlink.RouteMessage:
+					err = e.ip4RouteMsg(msg, true)
+				}
+				if err != nil {
+					t.Error(x.name, err)
+				}
+			}
+			got = routeInterfaces()
+		})
+		if !reflect.DeepEqual(got, x.want) {
+			t.Errorf("%s: got %v, want %v", x.name, got, x.want)
+		}
+	}
+	if _, ok := ns.nexthop_by_id[10].ip4_prefixes[testPrefix]; ok {
+		t.Error("group still has replaced route")
+	}
+	if len(ns.nexthop_id_by_ip4_prefix) != 0 {
+		t.Error("deleted route still tracked")
+	}
+}